@@ -0,0 +1,94 @@
+// Package cli implements the `new-api admin` subcommands for headless
+// management, e.g. provisioning users and tokens or keeping channels in a
+// git repository, without going through the web UI.
+//
+// Commands either call the admin HTTP API of a running instance (default) or,
+// with --direct, open the database configured by SQL_DSN and use the model
+// layer like the server does. `admin migrate` always works on the databases
+// directly.
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+type adminOptions struct {
+	server      string
+	accessToken string
+	userId      int
+	direct      bool
+
+	backend backend
+}
+
+// Execute runs the admin command tree with args (without the leading
+// "admin") and returns the process exit code.
+func Execute(args []string) int {
+	root := newAdminCommand()
+	root.SetArgs(args)
+	if err := root.Execute(); err != nil {
+		return 1
+	}
+	return 0
+}
+
+func newAdminCommand() *cobra.Command {
+	opts := &adminOptions{}
+	root := &cobra.Command{
+		Use:          "admin",
+		Short:        "Manage a new-api deployment from the command line",
+		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if opts.direct {
+				b, err := newDBBackend()
+				if err != nil {
+					return err
+				}
+				opts.backend = b
+				return nil
+			}
+			if opts.server == "" || opts.accessToken == "" || opts.userId == 0 {
+				return errors.New("--server, --access-token and --user-id are required unless --direct is set")
+			}
+			opts.backend = newHTTPBackend(opts.server, opts.accessToken, opts.userId)
+			return nil
+		},
+	}
+	userId, _ := strconv.Atoi(os.Getenv("NEW_API_USER_ID"))
+	flags := root.PersistentFlags()
+	flags.StringVar(&opts.server, "server", os.Getenv("NEW_API_SERVER"), "base URL of the new-api instance (env NEW_API_SERVER)")
+	flags.StringVar(&opts.accessToken, "access-token", os.Getenv("NEW_API_ACCESS_TOKEN"), "system access token of an admin user (env NEW_API_ACCESS_TOKEN)")
+	flags.IntVar(&opts.userId, "user-id", userId, "id of the user owning the access token (env NEW_API_USER_ID)")
+	flags.BoolVar(&opts.direct, "direct", false, "operate on the database configured by SQL_DSN instead of the HTTP API")
+
+	root.AddCommand(
+		newUserCommand(opts),
+		newTokenCommand(opts),
+		newQuotaCommand(opts),
+		newChannelCommand(opts),
+		newUsageCommand(opts),
+		newMigrateCommand(),
+	)
+	return root
+}
+
+// parseReportTime accepts a date (2006-01-02, local time), an RFC 3339
+// timestamp or unix seconds.
+func parseReportTime(value string) (int64, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return seconds, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t.Unix(), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.Unix(), nil
+	}
+	return 0, fmt.Errorf("invalid time %q, expected 2006-01-02, RFC 3339 or unix seconds", value)
+}
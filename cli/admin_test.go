@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelFileRoundTrip(t *testing.T) {
+	org := "org-1"
+	tests := []struct {
+		name    string
+		input   string
+		want    channelFile
+		wantErr string
+	}{
+		{
+			name: "full channel",
+			input: `channels:
+  - id: 3
+    name: openai-main
+    type: 1
+    models: gpt-4o,gpt-4o-mini
+    group: default
+    priority: 10
+    weight: 5
+    auto_ban: 1
+    model_mapping: '{"gpt-4":"gpt-4o"}'
+    openai_organization: org-1
+`,
+			want: channelFile{Channels: []channelSpec{{
+				Id: 3, Name: "openai-main", Type: 1, Models: "gpt-4o,gpt-4o-mini", Group: "default",
+				Priority: 10, Weight: 5, AutoBan: 1, ModelMapping: `{"gpt-4":"gpt-4o"}`, OpenAIOrg: &org,
+			}}},
+		},
+		{name: "empty document", input: ""},
+		{name: "unknown field", input: "channels:\n  - name: a\n    modles: gpt-4o\n", wantErr: "modles"},
+		{name: "missing name", input: "channels:\n  - type: 1\n", wantErr: "name is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := readChannelFile(strings.NewReader(tt.input))
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, file)
+
+			var buf bytes.Buffer
+			require.NoError(t, writeChannelFile(&buf, file))
+			again, err := readChannelFile(&buf)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, file.Channels, again.Channels)
+		})
+	}
+}
+
+func TestChannelSpecApplyKeepsKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		specKey string
+		wantKey string
+	}{
+		{name: "key omitted", wantKey: "sk-stored"},
+		{name: "key replaced", specKey: "sk-new", wantKey: "sk-new"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			channel := &model.Channel{Id: 1, Key: "sk-stored", Status: 2}
+			channelSpec{Id: 1, Name: "c", Key: tt.specKey}.applyTo(channel)
+			assert.Equal(t, tt.wantKey, channel.Key)
+			assert.Equal(t, 2, channel.Status)
+			assert.Equal(t, "c", channel.Name)
+		})
+	}
+}
+
+func TestSummarizeUsage(t *testing.T) {
+	data := []*model.QuotaData{
+		{ModelName: "gpt-4o", Count: 2, TokenUsed: 100, Quota: 50},
+		{ModelName: "gpt-4o-mini", Count: 10, TokenUsed: 1000, Quota: 20},
+		{ModelName: "gpt-4o", Count: 1, TokenUsed: 40, Quota: 30},
+	}
+	assert.Equal(t, []usageRow{
+		{ModelName: "gpt-4o", Requests: 3, Tokens: 140, Quota: 80},
+		{ModelName: "gpt-4o-mini", Requests: 10, Tokens: 1000, Quota: 20},
+		{ModelName: "TOTAL", Requests: 13, Tokens: 1140, Quota: 100},
+	}, summarizeUsage(data))
+}
+
+func TestHTTPBackendCall(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		response string
+		wantErr  string
+		wantKey  string
+	}{
+		{name: "success", status: http.StatusOK, response: `{"success":true,"message":"","data":{"key":"sk-abc"}}`, wantKey: "sk-abc"},
+		{name: "api error", status: http.StatusOK, response: `{"success":false,"message":"无权进行此操作"}`, wantErr: "无权进行此操作"},
+		{name: "not json", status: http.StatusBadGateway, response: `bad gateway`, wantErr: "status 502"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "access-token", r.Header.Get("Authorization"))
+				assert.Equal(t, "7", r.Header.Get("New-Api-User"))
+				assert.Equal(t, "/api/token/5/rotate", r.URL.Path)
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			b := newHTTPBackend(server.URL+"/", "access-token", 7)
+			key, err := b.RotateToken(0, 5, nil)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantKey, key)
+		})
+	}
+
+	_, err := newHTTPBackend("http://127.0.0.1:0", "access-token", 7).RotateToken(8, 5, nil)
+	require.ErrorContains(t, err, "--direct")
+}
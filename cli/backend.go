@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+)
+
+// backend performs the admin operations against either the HTTP API or the
+// database. Token operations act on tokens of userId; the HTTP backend can
+// only manage tokens of the authenticated user and rejects other ids.
+type backend interface {
+	CreateUser(user userSpec) error
+	CreateToken(userId int, token tokenSpec) (string, error)
+	RotateToken(userId int, tokenId int, graceSeconds *int64) (string, error)
+	GrantQuota(userId int, quota int) error
+	ListChannels(withKeys bool) ([]channelSpec, error)
+	// ApplyChannel updates the channel with channel.Id, or creates it when the
+	// id is 0.
+	ApplyChannel(channel channelSpec) error
+	// ExportChannelBundle and ImportChannelBundle have the semantics of the
+	// channel export/import API, see service.ExportChannelBundle.
+	ExportChannelBundle(includeKeys bool, passphrase string) (*service.ChannelBundle, error)
+	ImportChannelBundle(bundle *service.ChannelBundle, passphrase string, dryRun bool) ([]service.ChannelImportChange, error)
+	QuotaData(startTime int64, endTime int64, username string) ([]*model.QuotaData, error)
+}
+
+type userSpec struct {
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	DisplayName string `json:"display_name"`
+	Role        int    `json:"role"`
+}
+
+type tokenSpec struct {
+	Name           string `json:"name"`
+	RemainQuota    int    `json:"remain_quota"`
+	UnlimitedQuota bool   `json:"unlimited_quota"`
+	ExpiredTime    int64  `json:"expired_time"`
+	Group          string `json:"group"`
+}
+
+// channelSpec is the YAML form of a channel used by export/import. Runtime
+// state such as balance, used quota and test results is left out, and the key
+// is only exported on request so files can be committed without secrets.
+type channelSpec struct {
+	Id                int     `yaml:"id,omitempty" json:"id,omitempty"`
+	Name              string  `yaml:"name" json:"name"`
+	Type              int     `yaml:"type" json:"type"`
+	Key               string  `yaml:"key,omitempty" json:"key,omitempty"`
+	Status            int     `yaml:"status,omitempty" json:"status,omitempty"`
+	BaseURL           string  `yaml:"base_url,omitempty" json:"base_url"`
+	Models            string  `yaml:"models" json:"models"`
+	Group             string  `yaml:"group" json:"group"`
+	Tag               string  `yaml:"tag,omitempty" json:"tag"`
+	Priority          int64   `yaml:"priority" json:"priority"`
+	Weight            uint    `yaml:"weight" json:"weight"`
+	AutoBan           int     `yaml:"auto_ban" json:"auto_ban"`
+	TestModel         string  `yaml:"test_model,omitempty" json:"test_model"`
+	ModelMapping      string  `yaml:"model_mapping,omitempty" json:"model_mapping"`
+	StatusCodeMapping string  `yaml:"status_code_mapping,omitempty" json:"status_code_mapping"`
+	ParamOverride     string  `yaml:"param_override,omitempty" json:"param_override"`
+	HeaderOverride    string  `yaml:"header_override,omitempty" json:"header_override"`
+	Setting           string  `yaml:"setting,omitempty" json:"setting"`
+	Settings          string  `yaml:"settings,omitempty" json:"settings"`
+	Other             string  `yaml:"other,omitempty" json:"other"`
+	Remark            string  `yaml:"remark,omitempty" json:"remark"`
+	OpenAIOrg         *string `yaml:"openai_organization,omitempty" json:"openai_organization,omitempty"`
+}
+
+func channelSpecFromModel(channel *model.Channel) channelSpec {
+	deref := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	}
+	spec := channelSpec{
+		Id:                channel.Id,
+		Name:              channel.Name,
+		Type:              channel.Type,
+		Key:               channel.Key,
+		Status:            channel.Status,
+		BaseURL:           deref(channel.BaseURL),
+		Models:            channel.Models,
+		Group:             channel.Group,
+		Tag:               deref(channel.Tag),
+		Priority:          channel.GetPriority(),
+		Weight:            uint(channel.GetWeight()),
+		AutoBan:           1,
+		TestModel:         deref(channel.TestModel),
+		ModelMapping:      deref(channel.ModelMapping),
+		StatusCodeMapping: deref(channel.StatusCodeMapping),
+		ParamOverride:     deref(channel.ParamOverride),
+		HeaderOverride:    deref(channel.HeaderOverride),
+		Setting:           deref(channel.Setting),
+		Settings:          channel.OtherSettings,
+		Other:             channel.Other,
+		Remark:            deref(channel.Remark),
+		OpenAIOrg:         channel.OpenAIOrganization,
+	}
+	if channel.AutoBan != nil {
+		spec.AutoBan = *channel.AutoBan
+	}
+	return spec
+}
+
+// applyTo copies the spec onto channel. The key is only replaced when the
+// spec carries one, so importing an export without keys keeps existing keys.
+func (spec channelSpec) applyTo(channel *model.Channel) {
+	channel.Name = spec.Name
+	channel.Type = spec.Type
+	if spec.Key != "" {
+		channel.Key = spec.Key
+	}
+	if spec.Status != 0 {
+		channel.Status = spec.Status
+	}
+	channel.BaseURL = &spec.BaseURL
+	channel.Models = spec.Models
+	channel.Group = spec.Group
+	channel.Tag = &spec.Tag
+	channel.Priority = &spec.Priority
+	channel.Weight = &spec.Weight
+	channel.AutoBan = &spec.AutoBan
+	channel.TestModel = &spec.TestModel
+	channel.ModelMapping = &spec.ModelMapping
+	channel.StatusCodeMapping = &spec.StatusCodeMapping
+	channel.ParamOverride = &spec.ParamOverride
+	channel.HeaderOverride = &spec.HeaderOverride
+	channel.Setting = &spec.Setting
+	channel.OtherSettings = spec.Settings
+	channel.Other = spec.Other
+	channel.Remark = &spec.Remark
+	channel.OpenAIOrganization = spec.OpenAIOrg
+}
@@ -0,0 +1,184 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+	"github.com/joho/godotenv"
+)
+
+// dbBackend writes through the model layer. With REDIS_CONN_STRING set it
+// also keeps the shared caches of running nodes consistent; channel changes
+// are picked up by the nodes on their next channel sync.
+type dbBackend struct{}
+
+// loadServerEnv reads the same .env and environment variables as the server.
+func loadServerEnv() {
+	_ = godotenv.Load(".env")
+	// keep stdout clean for exports and keys
+	gin.DefaultWriter = os.Stderr
+	common.InitEnv()
+}
+
+func newDBBackend() (*dbBackend, error) {
+	loadServerEnv()
+	if err := model.InitDB(); err != nil {
+		return nil, err
+	}
+	model.InitOptionMap()
+	if err := model.InitLogDB(); err != nil {
+		return nil, err
+	}
+	if err := common.InitRedisClient(); err != nil {
+		return nil, err
+	}
+	return &dbBackend{}, nil
+}
+
+func (b *dbBackend) CreateUser(user userSpec) error {
+	if user.DisplayName == "" {
+		user.DisplayName = user.Username
+	}
+	if user.Role >= common.RoleRootUser {
+		return errors.New("cannot create a root user")
+	}
+	newUser := model.User{
+		Username:    user.Username,
+		Password:    user.Password,
+		DisplayName: user.DisplayName,
+		Role:        user.Role,
+	}
+	if err := common.Validate.Struct(&newUser); err != nil {
+		return err
+	}
+	return newUser.Insert(0)
+}
+
+func (b *dbBackend) CreateToken(userId int, token tokenSpec) (string, error) {
+	if userId == 0 {
+		return "", errors.New("--user is required with --direct")
+	}
+	if _, err := model.GetUserById(userId, false); err != nil {
+		return "", err
+	}
+	count, err := model.CountUserTokens(userId)
+	if err != nil {
+		return "", err
+	}
+	if maxTokens := operation_setting.GetMaxUserTokens(); int(count) >= maxTokens {
+		return "", fmt.Errorf("user %d already has the maximum number of tokens (%d)", userId, maxTokens)
+	}
+	key, err := common.GenerateKey()
+	if err != nil {
+		return "", err
+	}
+	newToken := model.Token{
+		UserId:         userId,
+		Name:           token.Name,
+		Key:            key,
+		CreatedTime:    common.GetTimestamp(),
+		AccessedTime:   common.GetTimestamp(),
+		ExpiredTime:    token.ExpiredTime,
+		RemainQuota:    token.RemainQuota,
+		UnlimitedQuota: token.UnlimitedQuota,
+		Group:          token.Group,
+	}
+	if err := newToken.Insert(); err != nil {
+		return "", err
+	}
+	return newToken.GetFullKey(), nil
+}
+
+func (b *dbBackend) RotateToken(userId int, tokenId int, graceSeconds *int64) (string, error) {
+	if userId == 0 {
+		return "", errors.New("--user is required with --direct")
+	}
+	grace := int64(operation_setting.GetTokenSetting().RotationGraceSeconds)
+	if graceSeconds != nil {
+		grace = *graceSeconds
+	}
+	if grace < 0 || grace > operation_setting.MaxTokenRotationGraceSeconds {
+		return "", fmt.Errorf("grace seconds must be between 0 and %d", operation_setting.MaxTokenRotationGraceSeconds)
+	}
+	key, err := common.GenerateKey()
+	if err != nil {
+		return "", err
+	}
+	token, err := model.RotateTokenKey(tokenId, userId, key, grace)
+	if err != nil {
+		return "", err
+	}
+	model.RecordLog(userId, model.LogTypeSystem, fmt.Sprintf("通过命令行轮换令牌「%s」的密钥", token.Name))
+	return token.GetFullKey(), nil
+}
+
+func (b *dbBackend) GrantQuota(userId int, quota int) error {
+	if quota <= 0 {
+		return errors.New("quota must be positive")
+	}
+	if _, err := model.GetUserById(userId, false); err != nil {
+		return err
+	}
+	if err := model.IncreaseUserQuota(userId, quota, true); err != nil {
+		return err
+	}
+	model.RecordLog(userId, model.LogTypeManage, fmt.Sprintf("管理员通过命令行增加用户额度 %s", logger.LogQuota(quota)))
+	return nil
+}
+
+func (b *dbBackend) ListChannels(withKeys bool) ([]channelSpec, error) {
+	channels, err := model.GetAllChannels(0, 0, true, true)
+	if err != nil {
+		return nil, err
+	}
+	specs := make([]channelSpec, 0, len(channels))
+	for _, channel := range channels {
+		spec := channelSpecFromModel(channel)
+		if !withKeys {
+			spec.Key = ""
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+func (b *dbBackend) ApplyChannel(spec channelSpec) error {
+	if spec.Id == 0 {
+		if strings.TrimSpace(spec.Key) == "" {
+			return fmt.Errorf("channel %q: key is required to create a channel", spec.Name)
+		}
+		channel := &model.Channel{Status: common.ChannelStatusEnabled, CreatedTime: common.GetTimestamp()}
+		spec.applyTo(channel)
+		return channel.Insert()
+	}
+	channel, err := model.GetChannelById(spec.Id, true)
+	if err != nil {
+		return fmt.Errorf("channel %d: %w", spec.Id, err)
+	}
+	spec.applyTo(channel)
+	return channel.Update()
+}
+
+func (b *dbBackend) ExportChannelBundle(includeKeys bool, passphrase string) (*service.ChannelBundle, error) {
+	return service.ExportChannelBundle(includeKeys, passphrase)
+}
+
+func (b *dbBackend) ImportChannelBundle(bundle *service.ChannelBundle, passphrase string, dryRun bool) ([]service.ChannelImportChange, error) {
+	if err := service.DecryptChannelBundleKeys(bundle, passphrase); err != nil {
+		return nil, err
+	}
+	return service.ImportChannelBundle(bundle, dryRun)
+}
+
+func (b *dbBackend) QuotaData(startTime int64, endTime int64, username string) ([]*model.QuotaData, error) {
+	return model.GetAllQuotaDates(startTime, endTime, username)
+}
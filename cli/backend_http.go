@@ -0,0 +1,259 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+)
+
+const httpPageSize = 100
+
+type httpBackend struct {
+	server      string
+	accessToken string
+	userId      int
+	client      *http.Client
+}
+
+type apiResponse struct {
+	Success bool            `json:"success"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+type pageResponse[T any] struct {
+	Items []T `json:"items"`
+	Total int `json:"total"`
+}
+
+func newHTTPBackend(server string, accessToken string, userId int) *httpBackend {
+	return &httpBackend{
+		server:      strings.TrimRight(server, "/"),
+		accessToken: accessToken,
+		userId:      userId,
+		client:      &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// send sends an authenticated request to the admin API and returns the raw
+// response body.
+func (b *httpBackend) send(method string, path string, body any) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		payload, err := common.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequest(method, b.server+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", b.accessToken)
+	req.Header.Set("New-Api-User", strconv.Itoa(b.userId))
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest && !json.Valid(raw) {
+		return nil, fmt.Errorf("%s %s: unexpected response (status %d): %s", method, path, resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	return raw, nil
+}
+
+// call sends a request and decodes the data field of the standard
+// {success, message, data} envelope into out when it is not nil.
+func (b *httpBackend) call(method string, path string, body any, out any) error {
+	raw, err := b.send(method, path, body)
+	if err != nil {
+		return err
+	}
+	var result apiResponse
+	if err := common.Unmarshal(raw, &result); err != nil {
+		return fmt.Errorf("%s %s: unexpected response: %s", method, path, strings.TrimSpace(string(raw)))
+	}
+	if !result.Success {
+		return fmt.Errorf("%s %s: %s", method, path, result.Message)
+	}
+	if out == nil || len(result.Data) == 0 {
+		return nil
+	}
+	return common.Unmarshal(result.Data, out)
+}
+
+func (b *httpBackend) checkTokenOwner(userId int) error {
+	if userId != 0 && userId != b.userId {
+		return errors.New("the HTTP API only manages tokens of the authenticated user, use --direct to manage tokens of other users")
+	}
+	return nil
+}
+
+func (b *httpBackend) CreateUser(user userSpec) error {
+	return b.call(http.MethodPost, "/api/user/", user, nil)
+}
+
+// CreateToken creates the token, then looks up the newest token with the same
+// name to read its key since the create endpoint does not return it.
+func (b *httpBackend) CreateToken(userId int, token tokenSpec) (string, error) {
+	if err := b.checkTokenOwner(userId); err != nil {
+		return "", err
+	}
+	if err := b.call(http.MethodPost, "/api/token/", token, nil); err != nil {
+		return "", err
+	}
+	var page pageResponse[model.Token]
+	query := url.Values{"keyword": {token.Name}, "p": {"1"}, "page_size": {strconv.Itoa(httpPageSize)}}
+	if err := b.call(http.MethodGet, "/api/token/search?"+query.Encode(), nil, &page); err != nil {
+		return "", err
+	}
+	tokenId := 0
+	for _, item := range page.Items {
+		if item.Name == token.Name && item.Id > tokenId {
+			tokenId = item.Id
+		}
+	}
+	if tokenId == 0 {
+		return "", fmt.Errorf("token %q was created but could not be found", token.Name)
+	}
+	var key struct {
+		Key string `json:"key"`
+	}
+	if err := b.call(http.MethodPost, fmt.Sprintf("/api/token/%d/key", tokenId), nil, &key); err != nil {
+		return "", err
+	}
+	return key.Key, nil
+}
+
+func (b *httpBackend) RotateToken(userId int, tokenId int, graceSeconds *int64) (string, error) {
+	if err := b.checkTokenOwner(userId); err != nil {
+		return "", err
+	}
+	var rotated struct {
+		Key string `json:"key"`
+	}
+	body := map[string]any{}
+	if graceSeconds != nil {
+		body["grace_seconds"] = *graceSeconds
+	}
+	if err := b.call(http.MethodPost, fmt.Sprintf("/api/token/%d/rotate", tokenId), body, &rotated); err != nil {
+		return "", err
+	}
+	return rotated.Key, nil
+}
+
+func (b *httpBackend) GrantQuota(userId int, quota int) error {
+	return b.call(http.MethodPost, "/api/user/manage", map[string]any{
+		"id":     userId,
+		"action": "add_quota",
+		"mode":   "add",
+		"value":  quota,
+	}, nil)
+}
+
+// ListChannels pages through the channel list. The list endpoint never
+// returns keys, so withKeys is only honoured by the database backend.
+func (b *httpBackend) ListChannels(withKeys bool) ([]channelSpec, error) {
+	if withKeys {
+		return nil, errors.New("exporting channel keys requires --direct")
+	}
+	specs := make([]channelSpec, 0)
+	for page := 1; ; page++ {
+		var result pageResponse[*model.Channel]
+		path := fmt.Sprintf("/api/channel/?p=%d&page_size=%d&id_sort=true", page, httpPageSize)
+		if err := b.call(http.MethodGet, path, nil, &result); err != nil {
+			return nil, err
+		}
+		for _, channel := range result.Items {
+			specs = append(specs, channelSpecFromModel(channel))
+		}
+		if len(result.Items) < httpPageSize || len(specs) >= result.Total {
+			return specs, nil
+		}
+	}
+}
+
+// ApplyChannel uses the add and update endpoints. The update endpoint does
+// not accept a status, so a status change is sent to the status endpoint.
+func (b *httpBackend) ApplyChannel(spec channelSpec) error {
+	if spec.Id == 0 {
+		channel := &model.Channel{Status: common.ChannelStatusEnabled}
+		spec.applyTo(channel)
+		return b.call(http.MethodPost, "/api/channel/", map[string]any{"mode": "single", "channel": channel}, nil)
+	}
+	status := spec.Status
+	spec.Status = 0
+	if err := b.call(http.MethodPut, "/api/channel/", spec, nil); err != nil {
+		return err
+	}
+	if status == 0 {
+		return nil
+	}
+	return b.call(http.MethodPost, fmt.Sprintf("/api/channel/%d/status", spec.Id), map[string]int{"status": status}, nil)
+}
+
+// ExportChannelBundle posts to the export endpoint, which answers with the bundle
+// itself rather than the usual envelope unless it fails. Key exports need a
+// secure-verified browser session, so keys are only exported with --direct.
+func (b *httpBackend) ExportChannelBundle(includeKeys bool, passphrase string) (*service.ChannelBundle, error) {
+	if includeKeys {
+		return nil, errors.New("exporting channel keys requires --direct")
+	}
+	raw, err := b.send(http.MethodPost, "/api/channel/export", map[string]any{
+		"format": "json",
+	})
+	if err != nil {
+		return nil, err
+	}
+	var failure apiResponse
+	if err := common.Unmarshal(raw, &failure); err == nil && !failure.Success && failure.Message != "" {
+		return nil, fmt.Errorf("POST /api/channel/export: %s", failure.Message)
+	}
+	return service.ParseChannelBundle(raw)
+}
+
+func (b *httpBackend) ImportChannelBundle(bundle *service.ChannelBundle, passphrase string, dryRun bool) ([]service.ChannelImportChange, error) {
+	data, err := service.MarshalChannelBundle(bundle, "json")
+	if err != nil {
+		return nil, err
+	}
+	var changes []service.ChannelImportChange
+	err = b.call(http.MethodPost, "/api/channel/import", map[string]any{
+		"data":       string(data),
+		"passphrase": passphrase,
+		"dry_run":    dryRun,
+	}, &changes)
+	return changes, err
+}
+
+func (b *httpBackend) QuotaData(startTime int64, endTime int64, username string) ([]*model.QuotaData, error) {
+	query := url.Values{
+		"start_timestamp": {strconv.FormatInt(startTime, 10)},
+		"end_timestamp":   {strconv.FormatInt(endTime, 10)},
+		"username":        {username},
+	}
+	var data []*model.QuotaData
+	if err := b.call(http.MethodGet, "/api/data/?"+query.Encode(), nil, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
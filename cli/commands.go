@@ -0,0 +1,424 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+func newUserCommand(opts *adminOptions) *cobra.Command {
+	cmd := &cobra.Command{Use: "user", Short: "Manage users"}
+
+	var user userSpec
+	var role string
+	create := &cobra.Command{
+		Use:   "create",
+		Short: "Create a user",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch role {
+			case "common":
+				user.Role = common.RoleCommonUser
+			case "admin":
+				user.Role = common.RoleAdminUser
+			default:
+				return fmt.Errorf("invalid role %q, expected common or admin", role)
+			}
+			if err := opts.backend.CreateUser(user); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "created user %s\n", user.Username)
+			return nil
+		},
+	}
+	create.Flags().StringVar(&user.Username, "username", "", "username")
+	create.Flags().StringVar(&user.Password, "password", "", "password")
+	create.Flags().StringVar(&user.DisplayName, "display-name", "", "display name, defaults to the username")
+	create.Flags().StringVar(&role, "role", "common", "role: common or admin")
+	_ = create.MarkFlagRequired("username")
+	_ = create.MarkFlagRequired("password")
+
+	cmd.AddCommand(create)
+	return cmd
+}
+
+func newTokenCommand(opts *adminOptions) *cobra.Command {
+	cmd := &cobra.Command{Use: "token", Short: "Manage API tokens"}
+
+	var userId int
+	var token tokenSpec
+	var expiresIn time.Duration
+	create := &cobra.Command{
+		Use:   "create",
+		Short: "Create a token and print its key (sk-...)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			token.ExpiredTime = -1
+			if expiresIn > 0 {
+				token.ExpiredTime = time.Now().Add(expiresIn).Unix()
+			}
+			key, err := opts.backend.CreateToken(userId, token)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "sk-"+key)
+			return nil
+		},
+	}
+	create.Flags().IntVar(&userId, "user", 0, "owner of the token, only with --direct (defaults to the authenticated user)")
+	create.Flags().StringVar(&token.Name, "name", "", "token name")
+	create.Flags().IntVar(&token.RemainQuota, "quota", 0, "token quota")
+	create.Flags().BoolVar(&token.UnlimitedQuota, "unlimited", false, "do not limit the token quota")
+	create.Flags().DurationVar(&expiresIn, "expires-in", 0, "lifetime of the token, e.g. 720h (never expires by default)")
+	create.Flags().StringVar(&token.Group, "group", "", "group of the token")
+	_ = create.MarkFlagRequired("name")
+
+	var graceSeconds int64
+	rotate := &cobra.Command{
+		Use:   "rotate <token-id>",
+		Short: "Rotate the key of a token and print the new key",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tokenId, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid token id %q", args[0])
+			}
+			var grace *int64
+			if cmd.Flags().Changed("grace-seconds") {
+				grace = &graceSeconds
+			}
+			key, err := opts.backend.RotateToken(userId, tokenId, grace)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "sk-"+key)
+			return nil
+		},
+	}
+	rotate.Flags().IntVar(&userId, "user", 0, "owner of the token, only with --direct (defaults to the authenticated user)")
+	rotate.Flags().Int64Var(&graceSeconds, "grace-seconds", 0, "how long the old key keeps working (defaults to the server setting)")
+
+	cmd.AddCommand(create, rotate)
+	return cmd
+}
+
+func newQuotaCommand(opts *adminOptions) *cobra.Command {
+	cmd := &cobra.Command{Use: "quota", Short: "Manage user quota"}
+
+	var userId, quota int
+	grant := &cobra.Command{
+		Use:   "grant",
+		Short: "Add quota to a user",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if quota <= 0 {
+				return errors.New("--quota must be positive")
+			}
+			if err := opts.backend.GrantQuota(userId, quota); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "granted %d quota to user %d\n", quota, userId)
+			return nil
+		},
+	}
+	grant.Flags().IntVar(&userId, "user", 0, "user id")
+	grant.Flags().IntVar(&quota, "quota", 0, "quota to add")
+	_ = grant.MarkFlagRequired("user")
+	_ = grant.MarkFlagRequired("quota")
+
+	cmd.AddCommand(grant)
+	return cmd
+}
+
+// channelFile is the YAML document read and written by channel import/export.
+type channelFile struct {
+	Channels []channelSpec `yaml:"channels"`
+}
+
+func newChannelCommand(opts *adminOptions) *cobra.Command {
+	cmd := &cobra.Command{Use: "channel", Short: "Import and export channels as YAML"}
+
+	var output string
+	var withKeys bool
+	export := &cobra.Command{
+		Use:   "export",
+		Short: "Write all channels as YAML",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			channels, err := opts.backend.ListChannels(withKeys)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			if output != "" && output != "-" {
+				file, err := os.Create(output)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				out = file
+			}
+			return writeChannelFile(out, channelFile{Channels: channels})
+		},
+	}
+	export.Flags().StringVarP(&output, "output", "o", "-", "output file, - for stdout")
+	export.Flags().BoolVar(&withKeys, "with-keys", false, "include channel keys, only with --direct")
+
+	var input string
+	var dryRun bool
+	importCmd := &cobra.Command{
+		Use:   "import",
+		Short: "Create or update channels from YAML",
+		Long:  "Channels with an id are updated in place, channels without one are created. Omitted keys leave the stored key unchanged.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var in io.Reader = cmd.InOrStdin()
+			if input != "-" {
+				file, err := os.Open(input)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				in = file
+			}
+			channels, err := readChannelFile(in)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			for _, channel := range channels.Channels {
+				action := "create"
+				if channel.Id != 0 {
+					action = fmt.Sprintf("update #%d", channel.Id)
+				}
+				if !dryRun {
+					if err := opts.backend.ApplyChannel(channel); err != nil {
+						return fmt.Errorf("%s %q: %w", action, channel.Name, err)
+					}
+				}
+				fmt.Fprintf(out, "%s %q\n", action, channel.Name)
+			}
+			return nil
+		},
+	}
+	importCmd.Flags().StringVarP(&input, "file", "f", "-", "input file, - for stdin")
+	importCmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the changes without applying them")
+
+	cmd.AddCommand(export, importCmd, newChannelBundleCommand(opts))
+	return cmd
+}
+
+func newChannelBundleCommand(opts *adminOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bundle",
+		Short: "Import and export channel bundles",
+		Long:  "Bundles match channels by name rather than id, so they can be moved between instances.",
+	}
+
+	var passphrase string
+	cmd.PersistentFlags().StringVar(&passphrase, "passphrase", os.Getenv("NEW_API_BUNDLE_PASSPHRASE"), "passphrase for the channel keys (env NEW_API_BUNDLE_PASSPHRASE)")
+
+	var output, format string
+	var withKeys bool
+	export := &cobra.Command{
+		Use:   "export",
+		Short: "Write all channels as a bundle",
+		Long:  "Keys are only exported with --with-keys and --direct, and are always encrypted with the passphrase.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bundle, err := opts.backend.ExportChannelBundle(withKeys, passphrase)
+			if err != nil {
+				return err
+			}
+			data, err := service.MarshalChannelBundle(bundle, format)
+			if err != nil {
+				return err
+			}
+			if output == "-" {
+				_, err = cmd.OutOrStdout().Write(data)
+				return err
+			}
+			return os.WriteFile(output, data, 0600)
+		},
+	}
+	export.Flags().StringVarP(&output, "output", "o", "-", "output file, - for stdout")
+	export.Flags().StringVar(&format, "format", "yaml", "bundle format: yaml or json")
+	export.Flags().BoolVar(&withKeys, "with-keys", false, "include channel keys, only with --direct and a passphrase")
+
+	var input string
+	var dryRun bool
+	importCmd := &cobra.Command{
+		Use:   "import",
+		Short: "Create or update channels from a bundle",
+		Long:  "Channels are matched by name: missing channels are created, existing ones updated. Omitted keys leave the stored key unchanged.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var data []byte
+			var err error
+			if input == "-" {
+				data, err = io.ReadAll(cmd.InOrStdin())
+			} else {
+				data, err = os.ReadFile(input)
+			}
+			if err != nil {
+				return err
+			}
+			bundle, err := service.ParseChannelBundle(data)
+			if err != nil {
+				return err
+			}
+			changes, err := opts.backend.ImportChannelBundle(bundle, passphrase, dryRun)
+			if err != nil {
+				return err
+			}
+			printChannelChanges(cmd.OutOrStdout(), changes)
+			return nil
+		},
+	}
+	importCmd.Flags().StringVarP(&input, "file", "f", "-", "bundle file (JSON or YAML), - for stdin")
+	importCmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the changes without applying them")
+
+	cmd.AddCommand(export, importCmd)
+	return cmd
+}
+
+func printChannelChanges(w io.Writer, changes []service.ChannelImportChange) {
+	for _, change := range changes {
+		if change.ChannelId != 0 {
+			fmt.Fprintf(w, "%-9s %q (#%d)\n", change.Action, change.Name, change.ChannelId)
+		} else {
+			fmt.Fprintf(w, "%-9s %q\n", change.Action, change.Name)
+		}
+		for _, field := range change.Fields {
+			if field.Field == "key" {
+				fmt.Fprintf(w, "    key: changed\n")
+				continue
+			}
+			fmt.Fprintf(w, "    %s: %v -> %v\n", field.Field, field.Before, field.After)
+		}
+	}
+}
+
+func writeChannelFile(w io.Writer, file channelFile) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(file); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+func readChannelFile(r io.Reader) (channelFile, error) {
+	var file channelFile
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return file, err
+	}
+	for i, channel := range file.Channels {
+		if channel.Name == "" {
+			return file, fmt.Errorf("channel #%d: name is required", i+1)
+		}
+	}
+	return file, nil
+}
+
+// usageRow is one line of the usage report, aggregated per model.
+type usageRow struct {
+	ModelName string `json:"model_name"`
+	Requests  int    `json:"requests"`
+	Tokens    int    `json:"tokens"`
+	Quota     int    `json:"quota"`
+}
+
+func newUsageCommand(opts *adminOptions) *cobra.Command {
+	cmd := &cobra.Command{Use: "usage", Short: "Usage reports"}
+
+	var start, end, username, format string
+	report := &cobra.Command{
+		Use:   "report",
+		Short: "Summarize requests, tokens and quota per model",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			endTime := time.Now().Unix()
+			startTime := endTime - 7*24*3600
+			var err error
+			if start != "" {
+				if startTime, err = parseReportTime(start); err != nil {
+					return err
+				}
+			}
+			if end != "" {
+				if endTime, err = parseReportTime(end); err != nil {
+					return err
+				}
+			}
+			data, err := opts.backend.QuotaData(startTime, endTime, username)
+			if err != nil {
+				return err
+			}
+			rows := summarizeUsage(data)
+			out := cmd.OutOrStdout()
+			switch format {
+			case "json":
+				payload, err := common.Marshal(rows)
+				if err != nil {
+					return err
+				}
+				_, err = fmt.Fprintln(out, string(payload))
+				return err
+			case "table":
+				writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+				fmt.Fprintln(writer, "MODEL\tREQUESTS\tTOKENS\tQUOTA")
+				for _, row := range rows {
+					fmt.Fprintf(writer, "%s\t%d\t%d\t%d\n", row.ModelName, row.Requests, row.Tokens, row.Quota)
+				}
+				return writer.Flush()
+			default:
+				return fmt.Errorf("invalid format %q, expected table or json", format)
+			}
+		},
+	}
+	report.Flags().StringVar(&start, "start", "", "start time, 2006-01-02, RFC 3339 or unix seconds (defaults to 7 days ago)")
+	report.Flags().StringVar(&end, "end", "", "end time (defaults to now)")
+	report.Flags().StringVar(&username, "username", "", "only report this user")
+	report.Flags().StringVar(&format, "format", "table", "output format: table or json")
+
+	cmd.AddCommand(report)
+	return cmd
+}
+
+// summarizeUsage merges the hourly quota data into one row per model, with
+// the busiest model first and a trailing total row.
+func summarizeUsage(data []*model.QuotaData) []usageRow {
+	byModel := make(map[string]*usageRow)
+	total := usageRow{ModelName: "TOTAL"}
+	for _, item := range data {
+		row, ok := byModel[item.ModelName]
+		if !ok {
+			row = &usageRow{ModelName: item.ModelName}
+			byModel[item.ModelName] = row
+		}
+		row.Requests += item.Count
+		row.Tokens += item.TokenUsed
+		row.Quota += item.Quota
+		total.Requests += item.Count
+		total.Tokens += item.TokenUsed
+		total.Quota += item.Quota
+	}
+	rows := make([]usageRow, 0, len(byModel)+1)
+	for _, row := range byModel {
+		rows = append(rows, *row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Quota != rows[j].Quota {
+			return rows[i].Quota > rows[j].Quota
+		}
+		return rows[i].ModelName < rows[j].ModelName
+	})
+	return append(rows, total)
+}
@@ -0,0 +1,92 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// cacheInvalidationChannel is the Redis pub/sub channel every replica listens
+// on so in-process caches drop stale entries as soon as another node mutates
+// the underlying rows, instead of waiting for the next periodic sync.
+const cacheInvalidationChannel = "new_api:cache_invalidation"
+
+const (
+	CacheInvalidationKindChannels = "channels"
+	CacheInvalidationKindToken    = "token"
+	CacheInvalidationKindUser     = "user"
+	CacheInvalidationKindOptions  = "options"
+)
+
+type CacheInvalidationEvent struct {
+	Kind   string `json:"kind"`
+	Key    string `json:"key,omitempty"`
+	Origin string `json:"origin"`
+}
+
+var (
+	cacheInvalidationOrigin     = GetRandomString(16)
+	cacheInvalidationHandlersMu sync.RWMutex
+	cacheInvalidationHandlers   = map[string][]func(event CacheInvalidationEvent){}
+	cacheInvalidationListenOnce sync.Once
+)
+
+// RegisterCacheInvalidationHandler subscribes fn to events of the given kind
+// published by other nodes. Events published by this process are not
+// delivered back to it: the publisher has already updated its own cache.
+func RegisterCacheInvalidationHandler(kind string, fn func(event CacheInvalidationEvent)) {
+	cacheInvalidationHandlersMu.Lock()
+	defer cacheInvalidationHandlersMu.Unlock()
+	cacheInvalidationHandlers[kind] = append(cacheInvalidationHandlers[kind], fn)
+}
+
+// PublishCacheInvalidation broadcasts an invalidation to the other replicas.
+// It is a no-op without Redis, where each node only relies on its periodic sync.
+func PublishCacheInvalidation(kind string, key string) {
+	if !RedisEnabled || RDB == nil {
+		return
+	}
+	payload, err := Marshal(CacheInvalidationEvent{Kind: kind, Key: key, Origin: cacheInvalidationOrigin})
+	if err != nil {
+		SysError(fmt.Sprintf("failed to marshal cache invalidation: %v", err))
+		return
+	}
+	if err := RDB.Publish(context.Background(), cacheInvalidationChannel, payload).Err(); err != nil {
+		SysError(fmt.Sprintf("failed to publish cache invalidation kind=%s: %v", kind, err))
+	}
+}
+
+// StartCacheInvalidationListener subscribes to the invalidation channel and
+// dispatches events to the registered handlers. go-redis re-subscribes
+// automatically after reconnects; events missed during an outage are covered
+// by the periodic sync.
+func StartCacheInvalidationListener() {
+	if !RedisEnabled || RDB == nil {
+		return
+	}
+	cacheInvalidationListenOnce.Do(func() {
+		pubsub := RDB.Subscribe(context.Background(), cacheInvalidationChannel)
+		SysLog("cache invalidation listener started")
+		go func() {
+			for msg := range pubsub.Channel(redis.WithChannelHealthCheckInterval(30 * time.Second)) {
+				var event CacheInvalidationEvent
+				if err := UnmarshalJsonStr(msg.Payload, &event); err != nil {
+					SysError(fmt.Sprintf("invalid cache invalidation payload: %v", err))
+					continue
+				}
+				if event.Origin == cacheInvalidationOrigin {
+					continue
+				}
+				cacheInvalidationHandlersMu.RLock()
+				handlers := cacheInvalidationHandlers[event.Kind]
+				cacheInvalidationHandlersMu.RUnlock()
+				for _, handler := range handlers {
+					handler(event)
+				}
+			}
+		}()
+	})
+}
@@ -77,6 +77,10 @@ var CryptoSecret = uuid.New().String()
 var SessionCookieSecure = false
 var SessionCookieTrustedURLs []string
 
+// CryptoSecretConfigured reports whether CryptoSecret comes from the environment
+// and therefore stays stable across restarts.
+var CryptoSecretConfigured = false
+
 var OptionMap map[string]string
 var OptionMapRWMutex sync.RWMutex
 
@@ -183,6 +187,9 @@ var SyncFrequency int // unit is second
 var BatchUpdateEnabled = false
 var BatchUpdateInterval int
 
+// RedisQuotaEnabled 开启后用户额度以 Redis 为权威值，原子扣减并定期写回数据库
+var RedisQuotaEnabled = false
+
 var RelayTimeout int // unit is second
 
 var RelayIdleConnTimeout int // unit is second
@@ -242,6 +249,11 @@ var (
 	SearchRateLimitEnable         = true
 	SearchRateLimitNum            = 10
 	SearchRateLimitDuration int64 = 60
+
+	// Per-user rate limit for token-authenticated check-in endpoints
+	CheckinRateLimitEnable         = true
+	CheckinRateLimitNum            = 10
+	CheckinRateLimitDuration int64 = 60
 )
 
 var RateLimitKeyExpirationDuration = 20 * time.Minute
@@ -262,6 +274,7 @@ const (
 	RedemptionCodeStatusEnabled  = 1 // don't use 0, 0 is the default value!
 	RedemptionCodeStatusDisabled = 2 // also don't use 0
 	RedemptionCodeStatusUsed     = 3 // also don't use 0
+	RedemptionCodeStatusExpired  = 4 // marked by the cleanup task once expired_time has passed
 )
 
 const (
@@ -272,8 +285,9 @@ const (
 )
 
 const (
-	TopUpStatusPending = "pending"
-	TopUpStatusSuccess = "success"
-	TopUpStatusFailed  = "failed"
-	TopUpStatusExpired = "expired"
+	TopUpStatusPending  = "pending"
+	TopUpStatusSuccess  = "success"
+	TopUpStatusFailed   = "failed"
+	TopUpStatusExpired  = "expired"
+	TopUpStatusRefunded = "refunded"
 )
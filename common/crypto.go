@@ -1,11 +1,18 @@
 package common
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
 	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
+	"errors"
+	"strings"
 
 	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/scrypt"
 )
 
 func GenerateHMACWithKey(key []byte, data string) string {
@@ -30,3 +37,93 @@ func ValidatePasswordAndHash(password string, hash string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
 }
+
+// encryptedValuePrefix marks values produced by EncryptString so that legacy
+// plaintext values can still be read.
+const encryptedValuePrefix = "enc:v1:"
+
+func newCryptoAEAD() (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte(CryptoSecret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptString encrypts plaintext with AES-GCM using a key derived from
+// CryptoSecret. Values that are already encrypted are returned unchanged.
+func EncryptString(plaintext string) (string, error) {
+	if plaintext == "" || IsEncryptedString(plaintext) {
+		return plaintext, nil
+	}
+	aead, err := newCryptoAEAD()
+	if err != nil {
+		return "", err
+	}
+	sealed, err := SealString(aead, plaintext)
+	if err != nil {
+		return "", err
+	}
+	return encryptedValuePrefix + sealed, nil
+}
+
+// DecryptString reverses EncryptString. Values without the encryption prefix
+// are treated as legacy plaintext and returned unchanged.
+func DecryptString(value string) (string, error) {
+	if !IsEncryptedString(value) {
+		return value, nil
+	}
+	aead, err := newCryptoAEAD()
+	if err != nil {
+		return "", err
+	}
+	return OpenString(aead, strings.TrimPrefix(value, encryptedValuePrefix))
+}
+
+func IsEncryptedString(value string) bool {
+	return strings.HasPrefix(value, encryptedValuePrefix)
+}
+
+// NewPassphraseAEAD derives an AES-256-GCM cipher from a user supplied
+// passphrase with scrypt, for data that leaves this instance (e.g. exported
+// channel keys) and therefore cannot depend on CryptoSecret.
+func NewPassphraseAEAD(passphrase string, salt []byte) (cipher.AEAD, error) {
+	if passphrase == "" {
+		return nil, errors.New("passphrase is empty")
+	}
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// SealString encrypts plaintext with aead and returns base64(nonce|ciphertext).
+func SealString(aead cipher.AEAD, plaintext string) (string, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(plaintext), nil)), nil
+}
+
+// OpenString reverses SealString.
+func OpenString(aead cipher.AEAD, value string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", errors.New("encrypted value is too short")
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
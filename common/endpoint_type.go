@@ -5,6 +5,10 @@ import "github.com/QuantumNous/new-api/constant"
 // GetEndpointTypesByChannelType 获取渠道最优先端点类型（所有的渠道都支持 OpenAI 端点）
 func GetEndpointTypesByChannelType(channelType int, modelName string) []constant.EndpointType {
 	var endpointTypes []constant.EndpointType
+	// 重排序模型在任何渠道上都只支持 /v1/rerank
+	if IsRerankModel(modelName) {
+		return []constant.EndpointType{constant.EndpointTypeJinaRerank}
+	}
 	switch channelType {
 	case constant.ChannelTypeJina:
 		endpointTypes = []constant.EndpointType{constant.EndpointTypeJinaRerank}
@@ -0,0 +1,27 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetEndpointTypesByChannelTypeRerank(t *testing.T) {
+	tests := []struct {
+		name        string
+		channelType int
+		modelName   string
+		want        []constant.EndpointType
+	}{
+		{"jina reranker", constant.ChannelTypeJina, "jina-reranker-m0", []constant.EndpointType{constant.EndpointTypeJinaRerank}},
+		{"cohere rerank", constant.ChannelTypeCohere, "rerank-english-v3.0", []constant.EndpointType{constant.EndpointTypeJinaRerank}},
+		{"vllm style on openai channel", constant.ChannelTypeOpenAI, "BAAI/bge-reranker-v2-m3", []constant.EndpointType{constant.EndpointTypeJinaRerank}},
+		{"chat model on openai channel", constant.ChannelTypeOpenAI, "gpt-4o", []constant.EndpointType{constant.EndpointTypeOpenAI}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, GetEndpointTypesByChannelType(tt.channelType, tt.modelName))
+		})
+	}
+}
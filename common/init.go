@@ -26,6 +26,7 @@ func printHelp() {
 	fmt.Println("Original Project: OneAPI by JustSong - https://github.com/songquanpeng/one-api")
 	fmt.Println("Maintainer: QuantumNous - https://github.com/QuantumNous/new-api")
 	fmt.Println("Usage: newapi [--port <port>] [--log-dir <log directory>] [--version] [--help]")
+	fmt.Println("       newapi admin <command> [--help]")
 }
 
 func InitEnv() {
@@ -58,8 +59,10 @@ func InitEnv() {
 	}
 	if os.Getenv("CRYPTO_SECRET") != "" {
 		CryptoSecret = os.Getenv("CRYPTO_SECRET")
+		CryptoSecretConfigured = true
 	} else {
 		CryptoSecret = SessionSecret
+		CryptoSecretConfigured = os.Getenv("SESSION_SECRET") != ""
 	}
 	if err := InitSessionCookieSettings(); err != nil {
 		log.Fatal(err)
@@ -131,6 +134,9 @@ func InitEnv() {
 	SearchRateLimitEnable = GetEnvOrDefaultBool("SEARCH_RATE_LIMIT_ENABLE", true)
 	SearchRateLimitNum = GetEnvOrDefault("SEARCH_RATE_LIMIT", 10)
 	SearchRateLimitDuration = int64(GetEnvOrDefault("SEARCH_RATE_LIMIT_DURATION", 60))
+	CheckinRateLimitEnable = GetEnvOrDefaultBool("CHECKIN_RATE_LIMIT_ENABLE", true)
+	CheckinRateLimitNum = GetEnvOrDefault("CHECKIN_RATE_LIMIT", 10)
+	CheckinRateLimitDuration = int64(GetEnvOrDefault("CHECKIN_RATE_LIMIT_DURATION", 60))
 	initConstantEnv()
 }
 
@@ -49,3 +49,43 @@ func IsIpInCIDRList(ip net.IP, cidrList []string) bool {
 	}
 	return false
 }
+
+// IPAllowlist is a pre-parsed list of IPs and CIDR ranges. Single IPs are
+// stored as host-sized networks so matching is a plain Contains per entry.
+type IPAllowlist struct {
+	networks []*net.IPNet
+}
+
+// ParseIPAllowlist parses IP and CIDR entries, returning the entries that are
+// neither so callers can reject them on input or skip them on enforcement.
+func ParseIPAllowlist(entries []string) (*IPAllowlist, []string) {
+	list := &IPAllowlist{networks: make([]*net.IPNet, 0, len(entries))}
+	var invalid []string
+	for _, entry := range entries {
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			list.networks = append(list.networks, network)
+			continue
+		}
+		ip := net.ParseIP(entry)
+		if ip == nil {
+			invalid = append(invalid, entry)
+			continue
+		}
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+			bits = 8 * net.IPv4len
+		}
+		list.networks = append(list.networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+	return list, invalid
+}
+
+func (l *IPAllowlist) Contains(ip net.IP) bool {
+	for _, network := range l.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
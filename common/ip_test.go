@@ -0,0 +1,32 @@
+package common
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIPAllowlist(t *testing.T) {
+	list, invalid := ParseIPAllowlist([]string{"10.0.0.0/8", "203.0.113.7", "2001:db8::/32", "::1", "not-an-ip", "300.1.1.1"})
+	assert.Equal(t, []string{"not-an-ip", "300.1.1.1"}, invalid)
+
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "10.1.2.3", want: true},
+		{ip: "11.0.0.1", want: false},
+		{ip: "203.0.113.7", want: true},
+		{ip: "203.0.113.8", want: false},
+		{ip: "::ffff:203.0.113.7", want: true},
+		{ip: "2001:db8:1::1", want: true},
+		{ip: "::1", want: true},
+		{ip: "::2", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			assert.Equal(t, tt.want, list.Contains(net.ParseIP(tt.ip)))
+		})
+	}
+}
@@ -17,6 +17,10 @@ var (
 		"flux-",
 		"flux.1-",
 	}
+	// RerankModels 名称包含这些关键字的模型按重排序模型处理，如 jina-reranker、rerank-english、bge-reranker
+	RerankModels = []string{
+		"rerank",
+	}
 	OpenAITextModels = []string{
 		"gpt-",
 		"o1",
@@ -35,6 +39,16 @@ func IsOpenAIResponseOnlyModel(modelName string) bool {
 	return false
 }
 
+func IsRerankModel(modelName string) bool {
+	modelName = strings.ToLower(modelName)
+	for _, m := range RerankModels {
+		if strings.Contains(modelName, m) {
+			return true
+		}
+	}
+	return false
+}
+
 func IsImageGenerationModel(modelName string) bool {
 	modelName = strings.ToLower(modelName)
 	for _, m := range ImageGenerationModels {
@@ -6,6 +6,9 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// MaxPageSize 分页接口单页最多返回的条数
+const MaxPageSize = 100
+
 type PageInfo struct {
 	Page     int `json:"page"`      // page num 页码
 	PageSize int `json:"page_size"` // page size 页大小
@@ -74,8 +77,8 @@ func GetPageQuery(c *gin.Context) *PageInfo {
 		}
 	}
 
-	if pageInfo.PageSize > 100 {
-		pageInfo.PageSize = 100
+	if pageInfo.PageSize > MaxPageSize {
+		pageInfo.PageSize = MaxPageSize
 	}
 
 	return pageInfo
@@ -124,6 +124,11 @@ func RedisHSetObj(key string, obj interface{}, expiration time.Duration) error {
 			continue
 		}
 
+		// Skip fields tagged redis:"-"
+		if field.Tag.Get("redis") == "-" {
+			continue
+		}
+
 		// 处理指针类型
 		if value.Kind() == reflect.Ptr {
 			if value.IsNil() {
@@ -0,0 +1,53 @@
+package common
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+var shuttingDown atomic.Bool
+
+// pendingWrites tracks background goroutines that persist billing or metrics
+// state, so shutdown can wait for them before flushing and exiting.
+var pendingWrites sync.WaitGroup
+
+// SetShuttingDown marks the process as draining. New requests are rejected
+// from this point on while in-flight ones, including long SSE streams, are
+// allowed to finish.
+func SetShuttingDown() {
+	shuttingDown.Store(true)
+}
+
+func IsShuttingDown() bool {
+	return shuttingDown.Load()
+}
+
+// GoPendingWrite runs fn in the background like gopool.Go, but shutdown waits
+// for it in WaitPendingWrites. Use it for asynchronous writes that must not be
+// lost on restart, such as quota refunds.
+func GoPendingWrite(fn func()) {
+	pendingWrites.Add(1)
+	gopool.Go(func() {
+		defer pendingWrites.Done()
+		fn()
+	})
+}
+
+// WaitPendingWrites blocks until every write started with GoPendingWrite has
+// finished. It returns false if ctx is done first.
+func WaitPendingWrites(ctx context.Context) bool {
+	done := make(chan struct{})
+	go func() {
+		pendingWrites.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
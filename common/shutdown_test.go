@@ -0,0 +1,26 @@
+package common
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitPendingWrites(t *testing.T) {
+	release := make(chan struct{})
+	finished := false
+	GoPendingWrite(func() {
+		<-release
+		finished = true
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.False(t, WaitPendingWrites(ctx), "write still running")
+
+	close(release)
+	assert.True(t, WaitPendingWrites(context.Background()))
+	assert.True(t, finished)
+}
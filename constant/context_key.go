@@ -18,7 +18,14 @@ const (
 	ContextKeyTokenSpecificChannelId ContextKey = "specific_channel_id"
 	ContextKeyTokenModelLimitEnabled ContextKey = "token_model_limit_enabled"
 	ContextKeyTokenModelLimit        ContextKey = "token_model_limit"
+	ContextKeyTokenModelMapping      ContextKey = "token_model_mapping"
 	ContextKeyTokenCrossGroupRetry   ContextKey = "token_cross_group_retry"
+	ContextKeyTokenRpmLimit          ContextKey = "token_rpm_limit"
+	ContextKeyTokenTpmLimit          ContextKey = "token_tpm_limit"
+	ContextKeyTokenMaxConcurrency    ContextKey = "token_max_concurrency"
+	ContextKeyTokenBodyLogEnabled    ContextKey = "token_body_log_enabled"
+	// ContextKeyTokenUsedTokens accumulates the tokens consumed by the request for the token TPM limiter
+	ContextKeyTokenUsedTokens ContextKey = "token_used_tokens"
 
 	/* channel related keys */
 	ContextKeyChannelId                ContextKey = "channel_id"
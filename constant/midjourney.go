@@ -3,6 +3,7 @@ package constant
 const (
 	MjErrorUnknown = 5
 	MjRequestError = 4
+	MjQueueFull    = 23
 )
 
 const (
@@ -3,6 +3,7 @@ package controller
 import (
 	"fmt"
 	"os"
+	"strconv"
 
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/constant"
@@ -28,6 +29,7 @@ var auditContentTemplates = map[string]string{
 	"user.passkey_register": "Registered a passkey",
 	"user.passkey_delete":   "Deleted a passkey",
 	"user.reset_passkey":    "Reset the user passkey",
+	"user.force_logout":     "Logged out user ${username} (ID: ${id}) from ${count} sessions",
 	"option.update":         "Updated system setting ${key}",
 
 	"channel.create":             "Created channel ${name} (type ${type}, count ${count})",
@@ -44,8 +46,28 @@ var auditContentTemplates = map[string]string{
 	"channel.multi_key_manage":   "Multi-key management ${action} on channel (ID: ${id})",
 	"channel.upstream_apply":     "Applied upstream model changes to channel (ID: ${id})",
 	"channel.upstream_apply_all": "Applied upstream model changes to ${count} channels",
+	"channel.mock_load_test":     "Ran mock load test on ${model} (${requests} requests, concurrency ${concurrency})",
 
 	"redemption.create": "Created ${count} redemption codes named ${name} (${quota} each)",
+	"redemption.export": "Exported redemption codes (keyword: ${keyword}, status: ${status})",
+
+	"webhook.create": "Created webhook endpoint ${name} (ID: ${id})",
+	"webhook.update": "Updated webhook endpoint ${name} (ID: ${id})",
+	"webhook.delete": "Deleted webhook endpoint ${name} (ID: ${id})",
+
+	"checkin.grant":  "Granted check-in for ${date} to user ${user_id} (quota ${quota})",
+	"checkin.revoke": "Revoked check-in for ${date} from user ${user_id} (quota ${quota})",
+
+	"statement.generate": "Generated ${count} statements for ${period} (user: ${user_id})",
+
+	"notification_template.update": "Updated notification template ${key} (${language})",
+	"notification_template.delete": "Reset notification template ${key} (${language}) to default",
+
+	"token.create": "Created token ${name} (ID: ${id})",
+	"token.delete": "Deleted token (ID: ${id})",
+
+	"midjourney.cancel":       "Cancelled Midjourney task ${mj_id} (ID: ${id}) and refunded ${quota}",
+	"midjourney.queue_cancel": "Cancelled queued Midjourney submission ${id}",
 
 	"subscription.plan_reset":      "Reset active subscriptions for plan ${plan_id}",
 	"subscription.user_plan_reset": "Reset active plan ${plan_id} subscriptions for user ${target_user_id}",
@@ -97,6 +119,12 @@ func recordManageAudit(c *gin.Context, action string, params map[string]interfac
 // recordManageAuditFor 记录一条管理审计日志，日志归属于操作者；targetUserId
 // 只表示被操作用户，用于在结构化参数中保留目标上下文。
 func recordManageAuditFor(c *gin.Context, targetUserId int, action string, params map[string]interface{}) {
+	recordManageAuditChanges(c, targetUserId, action, params, nil)
+}
+
+// recordManageAuditChanges 在 recordManageAuditFor 的基础上附带变更前后差异
+// （由 model.BuildAuditChanges 生成），写入 audit_info.changes，仅管理员可见。
+func recordManageAuditChanges(c *gin.Context, targetUserId int, action string, params map[string]interface{}, changes map[string]model.AuditChange) {
 	if params == nil {
 		params = map[string]interface{}{}
 	}
@@ -104,7 +132,14 @@ func recordManageAuditFor(c *gin.Context, targetUserId int, action string, param
 	if _, ok := params["target_user_id"]; !ok && targetUserId > 0 && targetUserId != operatorUserId {
 		params["target_user_id"] = targetUserId
 	}
-	model.RecordOperationAuditLog(operatorUserId, auditContentEN(action, params), c.ClientIP(), action, params, auditOperatorInfo(c), nil)
+	auditInfo := map[string]interface{}{
+		"method": c.Request.Method,
+		"route":  c.FullPath(),
+	}
+	if len(changes) > 0 {
+		auditInfo["changes"] = changes
+	}
+	model.RecordOperationAuditLog(operatorUserId, auditContentEN(action, params), c.ClientIP(), action, params, auditOperatorInfo(c), auditInfo)
 	markAuditLogged(c)
 }
 
@@ -113,3 +148,25 @@ func recordManageAuditFor(c *gin.Context, targetUserId int, action string, param
 func recordUserSecurityAudit(c *gin.Context, userId int, action string, params map[string]interface{}) {
 	model.RecordOperationAuditLog(userId, auditContentEN(action, params), c.ClientIP(), action, params, nil, nil)
 }
+
+// GetAuditLogs 分页查询管理操作审计日志，支持按操作者、操作、目标和时间范围过滤
+func GetAuditLogs(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	query := model.AuditLogQuery{
+		ActorName:  c.Query("actor_name"),
+		Action:     c.Query("action"),
+		TargetType: c.Query("target_type"),
+		TargetId:   c.Query("target_id"),
+	}
+	query.ActorId, _ = strconv.Atoi(c.Query("actor_id"))
+	query.StartTimestamp, _ = strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	query.EndTimestamp, _ = strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	logs, total, err := model.GetAuditLogs(query, pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(logs)
+	common.ApiSuccess(c, pageInfo)
+}
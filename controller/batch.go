@@ -0,0 +1,439 @@
+package controller
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+// OpenAI Batch / Files API 转发。
+// 文件和批量任务只存在于创建时所选渠道的上游账号上，因此上传时选定渠道和密钥后
+// 记录到本地，后续所有请求都按记录发往同一渠道；计费在任务结束后由轮询统一按折扣结算。
+
+const batchListDefaultLimit = 20
+
+// batchEnabled 功能未开启时按未实现接口返回
+func batchEnabled(c *gin.Context) bool {
+	if !operation_setting.GetBatchSetting().Enabled {
+		RelayNotImplemented(c)
+		return false
+	}
+	return true
+}
+
+func respondBatchError(c *gin.Context, status int, err error) {
+	c.JSON(status, gin.H{
+		"error": types.NewErrorWithStatusCode(err, types.ErrorCodeInvalidRequest, status).ToOpenAIError(),
+	})
+}
+
+// respondBatchUpstream 原样返回上游响应
+func respondBatchUpstream(c *gin.Context, resp *http.Response, body []byte) {
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	c.Data(resp.StatusCode, contentType, body)
+}
+
+// batchUsingGroup 返回本次请求使用的分组，auto 分组无法固定渠道，回退为用户分组
+func batchUsingGroup(c *gin.Context) string {
+	group := common.GetContextKeyString(c, constant.ContextKeyUsingGroup)
+	if group == "" || group == "auto" {
+		group = common.GetContextKeyString(c, constant.ContextKeyUserGroup)
+	}
+	return group
+}
+
+// batchModelAllowed 校验令牌的模型限制
+func batchModelAllowed(c *gin.Context, modelName string) bool {
+	if !common.GetContextKeyBool(c, constant.ContextKeyTokenModelLimitEnabled) {
+		return true
+	}
+	limits, _ := common.GetContextKeyType[map[string]bool](c, constant.ContextKeyTokenModelLimit)
+	_, ok := limits[ratio_setting.FormatMatchingModelName(modelName)]
+	return ok
+}
+
+func batchFileObject(file *model.BatchFile) gin.H {
+	return gin.H{
+		"id":         file.FileId,
+		"object":     "file",
+		"bytes":      file.Bytes,
+		"created_at": file.CreatedAt,
+		"filename":   file.Filename,
+		"purpose":    file.Purpose,
+	}
+}
+
+// UploadBatchFile POST /v1/files，仅支持 purpose=batch，模型取自 JSONL 首行请求体
+func UploadBatchFile(c *gin.Context) {
+	if !batchEnabled(c) {
+		return
+	}
+	purpose := c.PostForm("purpose")
+	if purpose != "batch" {
+		respondBatchError(c, http.StatusBadRequest, errors.New("only purpose=batch is supported"))
+		return
+	}
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		respondBatchError(c, http.StatusBadRequest, err)
+		return
+	}
+	src, err := fileHeader.Open()
+	if err != nil {
+		respondBatchError(c, http.StatusBadRequest, err)
+		return
+	}
+	defer src.Close()
+	content, err := io.ReadAll(src)
+	if err != nil {
+		respondBatchError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	firstLine, _, _ := bufio.NewReader(bytes.NewReader(content)).ReadLine()
+	modelName := gjson.GetBytes(firstLine, "body.model").String()
+	if modelName == "" {
+		respondBatchError(c, http.StatusBadRequest, errors.New("the first line of the batch file must contain body.model"))
+		return
+	}
+	if !batchModelAllowed(c, modelName) {
+		respondBatchError(c, http.StatusForbidden, fmt.Errorf("token is not allowed to use model %s", modelName))
+		return
+	}
+	channel, keyIndex, err := service.SelectBatchChannel(batchUsingGroup(c), modelName)
+	if err != nil {
+		respondBatchError(c, http.StatusServiceUnavailable, err)
+		return
+	}
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	if err := writer.WriteField("purpose", purpose); err != nil {
+		respondBatchError(c, http.StatusInternalServerError, err)
+		return
+	}
+	part, err := writer.CreateFormFile("file", fileHeader.Filename)
+	if err != nil {
+		respondBatchError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if _, err := part.Write(content); err != nil {
+		respondBatchError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if err := writer.Close(); err != nil {
+		respondBatchError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp, err := service.DoBatchUpstreamRequest(c.Request.Context(), channel.Id, keyIndex, http.MethodPost, "/v1/files", body, writer.FormDataContentType())
+	if err != nil {
+		respondBatchError(c, http.StatusBadGateway, err)
+		return
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		respondBatchError(c, http.StatusBadGateway, err)
+		return
+	}
+	if resp.StatusCode == http.StatusOK {
+		file := &model.BatchFile{
+			FileId:    gjson.GetBytes(respBody, "id").String(),
+			UserId:    c.GetInt("id"),
+			ChannelId: channel.Id,
+			KeyIndex:  keyIndex,
+			Purpose:   purpose,
+			Filename:  fileHeader.Filename,
+			Bytes:     int64(len(content)),
+			Model:     modelName,
+		}
+		if err := file.Insert(); err != nil {
+			common.SysLog(fmt.Sprintf("保存 batch 文件 %s 失败: %s", file.FileId, err.Error()))
+			respondBatchError(c, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	respondBatchUpstream(c, resp, respBody)
+}
+
+// ListBatchFiles GET /v1/files，只返回当前用户通过网关上传的文件
+func ListBatchFiles(c *gin.Context) {
+	if !batchEnabled(c) {
+		return
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	if limit <= 0 || limit > 100 {
+		limit = 100
+	}
+	files, err := model.GetUserBatchFiles(c.GetInt("id"), c.Query("purpose"), limit)
+	if err != nil {
+		respondBatchError(c, http.StatusInternalServerError, err)
+		return
+	}
+	data := make([]gin.H, 0, len(files))
+	for _, file := range files {
+		data = append(data, batchFileObject(file))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"object":   "list",
+		"data":     data,
+		"has_more": false,
+	})
+}
+
+// resolveBatchFileLocation 查找文件所在渠道：上传的输入文件记录在 BatchFile，
+// 上游生成的输出/错误文件通过所属任务定位
+func resolveBatchFileLocation(userId int, fileId string) (file *model.BatchFile, channelId int, keyIndex int, err error) {
+	file, err = model.GetUserBatchFile(userId, fileId)
+	if err == nil {
+		return file, file.ChannelId, file.KeyIndex, nil
+	}
+	job, err := model.GetUserBatchJobByFileId(userId, fileId)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return nil, job.ChannelId, job.KeyIndex, nil
+}
+
+// forwardBatchFileRequest 将文件相关请求转发到文件所在渠道
+func forwardBatchFileRequest(c *gin.Context, method string, suffix string) {
+	if !batchEnabled(c) {
+		return
+	}
+	fileId := c.Param("id")
+	file, channelId, keyIndex, err := resolveBatchFileLocation(c.GetInt("id"), fileId)
+	if err != nil {
+		respondBatchError(c, http.StatusNotFound, fmt.Errorf("no such file: %s", fileId))
+		return
+	}
+	resp, err := service.DoBatchUpstreamRequest(c.Request.Context(), channelId, keyIndex, method, "/v1/files/"+fileId+suffix, nil, "")
+	if err != nil {
+		respondBatchError(c, http.StatusBadGateway, err)
+		return
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		respondBatchError(c, http.StatusBadGateway, err)
+		return
+	}
+	if method == http.MethodDelete && resp.StatusCode == http.StatusOK && file != nil {
+		if err := model.DeleteUserBatchFile(file.UserId, file.FileId); err != nil {
+			common.SysLog(fmt.Sprintf("删除 batch 文件记录 %s 失败: %s", file.FileId, err.Error()))
+		}
+	}
+	respondBatchUpstream(c, resp, respBody)
+}
+
+// RetrieveBatchFile GET /v1/files/:id
+func RetrieveBatchFile(c *gin.Context) {
+	forwardBatchFileRequest(c, http.MethodGet, "")
+}
+
+// RetrieveBatchFileContent GET /v1/files/:id/content
+func RetrieveBatchFileContent(c *gin.Context) {
+	forwardBatchFileRequest(c, http.MethodGet, "/content")
+}
+
+// DeleteBatchFile DELETE /v1/files/:id
+func DeleteBatchFile(c *gin.Context) {
+	forwardBatchFileRequest(c, http.MethodDelete, "")
+}
+
+// CreateBatch POST /v1/batches，发往输入文件所在渠道，任务结束后再按折扣结算
+func CreateBatch(c *gin.Context) {
+	if !batchEnabled(c) {
+		return
+	}
+	requestBody, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		respondBatchError(c, http.StatusBadRequest, err)
+		return
+	}
+	inputFileId := gjson.GetBytes(requestBody, "input_file_id").String()
+	endpoint := gjson.GetBytes(requestBody, "endpoint").String()
+	if inputFileId == "" || endpoint == "" {
+		respondBatchError(c, http.StatusBadRequest, errors.New("input_file_id and endpoint are required"))
+		return
+	}
+	userId := c.GetInt("id")
+	file, err := model.GetUserBatchFile(userId, inputFileId)
+	if err != nil || file.Purpose != "batch" {
+		respondBatchError(c, http.StatusNotFound, fmt.Errorf("no such batch input file: %s", inputFileId))
+		return
+	}
+	userQuota, err := model.GetUserQuota(userId, false)
+	if err != nil {
+		respondBatchError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if userQuota <= 0 {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": types.NewErrorWithStatusCode(errors.New("user quota is not enough"), types.ErrorCodeInsufficientUserQuota, http.StatusForbidden).ToOpenAIError(),
+		})
+		return
+	}
+	settleUsageCaps, capErr := service.CheckUsageCaps(c, &relaycommon.RelayInfo{
+		UserId:          userId,
+		UserGroup:       common.GetContextKeyString(c, constant.ContextKeyUserGroup),
+		OriginModelName: file.Model,
+	})
+	if capErr != nil {
+		c.JSON(capErr.StatusCode, gin.H{"error": capErr.ToOpenAIError()})
+		return
+	}
+	// 用量在任务结束后才结算，这里只计次数；任务未创建成功时退还
+	created := false
+	defer func() {
+		var batchErr *types.NewAPIError
+		if !created {
+			batchErr = types.NewError(errors.New("batch was not created"), types.ErrorCodeBadResponseStatusCode)
+		}
+		settleUsageCaps(batchErr)
+	}()
+
+	resp, err := service.DoBatchUpstreamRequest(c.Request.Context(), file.ChannelId, file.KeyIndex, http.MethodPost, "/v1/batches", bytes.NewReader(requestBody), "application/json")
+	if err != nil {
+		respondBatchError(c, http.StatusBadGateway, err)
+		return
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		respondBatchError(c, http.StatusBadGateway, err)
+		return
+	}
+	if resp.StatusCode == http.StatusOK {
+		job := &model.BatchJob{
+			BatchId:     gjson.GetBytes(respBody, "id").String(),
+			UserId:      userId,
+			TokenId:     c.GetInt("token_id"),
+			ChannelId:   file.ChannelId,
+			KeyIndex:    file.KeyIndex,
+			Group:       batchUsingGroup(c),
+			Model:       file.Model,
+			Endpoint:    endpoint,
+			InputFileId: inputFileId,
+		}
+		service.ApplyUpstreamBatch(job, respBody)
+		if err := job.Insert(); err != nil {
+			common.SysLog(fmt.Sprintf("保存 batch 任务 %s 失败: %s", job.BatchId, err.Error()))
+			respondBatchError(c, http.StatusInternalServerError, err)
+			return
+		}
+		created = true
+	}
+	respondBatchUpstream(c, resp, respBody)
+}
+
+// RetrieveBatch GET /v1/batches/:id，顺带刷新本地状态，上游不可用时返回最近一次快照
+func RetrieveBatch(c *gin.Context) {
+	if !batchEnabled(c) {
+		return
+	}
+	job, err := model.GetUserBatchJob(c.GetInt("id"), c.Param("id"))
+	if err != nil {
+		respondBatchError(c, http.StatusNotFound, fmt.Errorf("no such batch: %s", c.Param("id")))
+		return
+	}
+	if _, err := service.RefreshBatchJob(c.Request.Context(), job); err != nil {
+		common.SysLog(fmt.Sprintf("刷新 batch 任务 %s 失败: %s", job.BatchId, err.Error()))
+	}
+	if job.Data == "" {
+		respondBatchError(c, http.StatusBadGateway, fmt.Errorf("batch %s is not available from upstream", job.BatchId))
+		return
+	}
+	c.Data(http.StatusOK, "application/json", []byte(job.Data))
+}
+
+// CancelBatch POST /v1/batches/:id/cancel
+func CancelBatch(c *gin.Context) {
+	if !batchEnabled(c) {
+		return
+	}
+	job, err := model.GetUserBatchJob(c.GetInt("id"), c.Param("id"))
+	if err != nil {
+		respondBatchError(c, http.StatusNotFound, fmt.Errorf("no such batch: %s", c.Param("id")))
+		return
+	}
+	resp, err := service.DoBatchUpstreamRequest(c.Request.Context(), job.ChannelId, job.KeyIndex, http.MethodPost, "/v1/batches/"+job.BatchId+"/cancel", nil, "")
+	if err != nil {
+		respondBatchError(c, http.StatusBadGateway, err)
+		return
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		respondBatchError(c, http.StatusBadGateway, err)
+		return
+	}
+	if resp.StatusCode == http.StatusOK {
+		service.ApplyUpstreamBatch(job, respBody)
+		if err := job.UpdateFromUpstream(); err != nil {
+			common.SysLog(fmt.Sprintf("更新 batch 任务 %s 失败: %s", job.BatchId, err.Error()))
+		}
+	}
+	respondBatchUpstream(c, resp, respBody)
+}
+
+// ListBatches GET /v1/batches，返回本地记录的最近一次上游快照
+func ListBatches(c *gin.Context) {
+	if !batchEnabled(c) {
+		return
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	if limit <= 0 || limit > 100 {
+		limit = batchListDefaultLimit
+	}
+	jobs, total, err := model.GetUserBatchJobs(c.GetInt("id"), 0, limit)
+	if err != nil {
+		respondBatchError(c, http.StatusInternalServerError, err)
+		return
+	}
+	data := make([]json.RawMessage, 0, len(jobs))
+	for _, job := range jobs {
+		if job.Data != "" {
+			data = append(data, json.RawMessage(job.Data))
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"object":   "list",
+		"data":     data,
+		"has_more": total > int64(len(jobs)),
+	})
+}
+
+// GetUserBatchJobs 控制台查询当前用户的批量任务
+func GetUserBatchJobs(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	jobs, total, err := model.GetUserBatchJobs(c.GetInt("id"), pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(jobs)
+	common.ApiSuccess(c, pageInfo)
+}
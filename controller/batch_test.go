@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateBatchEnforcesUsageCap(t *testing.T) {
+	db := openTokenControllerTestDB(t)
+	require.NoError(t, db.AutoMigrate(&model.User{}, &model.Channel{}, &model.BatchFile{}))
+	require.NoError(t, db.Create(&model.User{Id: 4101, Username: "batch_cap", Quota: 1000, Group: "free"}).Error)
+	require.NoError(t, db.Create(&model.BatchFile{FileId: "file-cap", UserId: 4101, ChannelId: 999, Purpose: "batch", Model: "gpt-4o-mini"}).Error)
+
+	originalBatch := *operation_setting.GetBatchSetting()
+	originalCap := *operation_setting.GetUsageCapSetting()
+	t.Cleanup(func() {
+		*operation_setting.GetBatchSetting() = originalBatch
+		*operation_setting.GetUsageCapSetting() = originalCap
+	})
+	operation_setting.GetBatchSetting().Enabled = true
+	*operation_setting.GetUsageCapSetting() = operation_setting.UsageCapSetting{
+		Enabled: true,
+		Rules: []operation_setting.UsageCapRule{
+			{Group: "free", Model: "gpt-4o-mini", Period: operation_setting.UsageCapPeriodDay, MaxRequests: 1},
+		},
+	}
+
+	createBatch := func() *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		c.Request = httptest.NewRequest(http.MethodPost, "/v1/batches",
+			strings.NewReader(`{"input_file_id":"file-cap","endpoint":"/v1/chat/completions"}`))
+		c.Set("id", 4101)
+		common.SetContextKey(c, constant.ContextKeyUserGroup, "free")
+		CreateBatch(c)
+		return recorder
+	}
+
+	// 上游不可用时任务未创建，退还次数
+	assert.Equal(t, http.StatusBadGateway, createBatch().Code)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	settle, apiErr := service.CheckUsageCaps(c, &relaycommon.RelayInfo{UserId: 4101, UserGroup: "free", OriginModelName: "gpt-4o-mini"})
+	require.Nil(t, apiErr, "the failed batch should have given its request back")
+	settle(nil)
+
+	recorder := createBatch()
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "daily request cap of 1 reached")
+}
@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RunMockLoadTest enqueues a mock_load_test system task that drives chat
+// completions through a master node's own relay path with one of the caller's
+// tokens. It only runs against models served exclusively by mock channels, so
+// it measures gateway overhead without touching real upstreams. Progress and
+// the result are read from /api/system-task/:task_id.
+func RunMockLoadTest(c *gin.Context) {
+	var req service.LoadTestRequest
+	if err := common.DecodeJson(c.Request.Body, &req); err != nil {
+		common.ApiError(c, fmt.Errorf("参数错误: %v", err))
+		return
+	}
+	if err := service.ValidateLoadTestRequest(&req); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if _, err := model.GetTokenByIds(req.TokenId, c.GetInt("id")); err != nil {
+		common.ApiError(c, fmt.Errorf("令牌不存在: %v", err))
+		return
+	}
+
+	task, created, err := service.StartLoadTest(req, c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if !created {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": "已有压测任务正在运行或等待中",
+			"data":    task.ToResponse(),
+		})
+		return
+	}
+	recordManageAudit(c, "channel.mock_load_test", map[string]interface{}{
+		"model":       req.Model,
+		"requests":    req.Requests,
+		"concurrency": req.Concurrency,
+		"stream":      req.Stream,
+	})
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    task.ToResponse(),
+	})
+}
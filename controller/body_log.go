@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetBodyLogs 分页查询请求/响应体记录的元数据，仅 root 可用
+func GetBodyLogs(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	query := model.BodyLogQuery{
+		RequestId: c.Query("request_id"),
+	}
+	query.UserId, _ = strconv.Atoi(c.Query("user_id"))
+	query.TokenId, _ = strconv.Atoi(c.Query("token_id"))
+	query.ChannelId, _ = strconv.Atoi(c.Query("channel_id"))
+	query.StartTimestamp, _ = strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	query.EndTimestamp, _ = strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	logs, total, err := model.GetBodyLogs(query, pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(logs)
+	common.ApiSuccess(c, pageInfo)
+}
+
+// GetBodyLog 返回单条记录解压后的请求体和响应体，仅 root 可用
+func GetBodyLog(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiErrorMsg(c, "无效的记录 ID")
+		return
+	}
+	log, err := model.GetBodyLogById(id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	requestBody, err := service.DecompressBody(log.RequestBody)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	responseBody, err := service.DecompressBody(log.ResponseBody)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"log":           log,
+		"request_body":  string(requestBody),
+		"response_body": string(responseBody),
+	})
+}
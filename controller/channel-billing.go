@@ -495,9 +495,13 @@ func UpdateAllChannelsBalance(c *gin.Context) {
 	return
 }
 
+// AutomaticallyUpdateChannels 定时更新渠道余额，只在后台任务主节点执行
 func AutomaticallyUpdateChannels(frequency int) {
 	for {
 		time.Sleep(time.Duration(frequency) * time.Minute)
+		if !service.IsBackgroundLeader() {
+			continue
+		}
 		common.SysLog("updating all channels")
 		_ = updateAllChannelsBalance()
 		common.SysLog("channels update done")
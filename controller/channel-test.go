@@ -898,6 +898,9 @@ type channelTestSummary struct {
 	Failed    int `json:"failed"`
 	Disabled  int `json:"disabled"`
 	Enabled   int `json:"enabled"`
+	// Deferred counts failing channels kept enabled because they have not yet
+	// reached the monitor FailureThreshold of consecutive failed probes.
+	Deferred int `json:"deferred"`
 }
 
 // performChannelTests runs the channel test loop synchronously, honoring ctx
@@ -910,6 +913,7 @@ func performChannelTests(ctx context.Context, channels []*model.Channel, testUse
 	if disableThreshold == 0 {
 		disableThreshold = 10000000 // a impossible value
 	}
+	failureThreshold := max(operation_setting.GetMonitorSetting().FailureThreshold, 1)
 
 	total := len(channels)
 	for index, channel := range channels {
@@ -949,10 +953,31 @@ func performChannelTests(ctx context.Context, channels []*model.Channel, testUse
 			}
 		}
 
+		health := &model.ChannelHealth{
+			ChannelId: channel.Id,
+			Success:   newAPIError == nil,
+			LatencyMs: milliseconds,
+		}
 		if newAPIError == nil {
 			summary.Succeeded++
 		} else {
 			summary.Failed++
+			health.StatusCode = newAPIError.StatusCode
+			health.ErrorMessage = newAPIError.Error()
+		}
+		model.RecordChannelHealth(health)
+
+		// a single failed probe only disables the channel once the consecutive
+		// failures, including this one, reach the configured threshold
+		if allowDisable && isChannelEnabled && shouldBanChannel && channel.GetAutoBan() && failureThreshold > 1 {
+			failures, err := model.CountChannelConsecutiveFailures(channel.Id, failureThreshold)
+			if err != nil {
+				common.SysLog(fmt.Sprintf("failed to count channel failures: channel_id=%d, error=%v", channel.Id, err))
+			}
+			if err == nil && failures < failureThreshold {
+				shouldBanChannel = false
+				summary.Deferred++
+			}
 		}
 
 		// disable channel
@@ -1009,6 +1034,11 @@ func runChannelTestTask(ctx context.Context, mode string, notify bool, report fu
 	selected := selectChannelsForAutomaticTest(channels, mode)
 	allowDisable := mode != operation_setting.ChannelTestModePassiveRecovery
 	summary := performChannelTests(ctx, selected, testUserID, allowDisable, report)
+	if days := operation_setting.GetMonitorSetting().HealthRetentionDays; days > 0 {
+		if err := model.DeleteChannelHealthBefore(common.GetTimestamp() - int64(days)*24*3600); err != nil {
+			common.SysLog("failed to cleanup channel health history: " + err.Error())
+		}
+	}
 	if notify && (ctx == nil || ctx.Err() == nil) {
 		service.NotifyRootUser(dto.NotifyTypeChannelTest, "通道测试完成", "所有通道测试已完成")
 	}
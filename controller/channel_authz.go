@@ -130,6 +130,7 @@ var channelNonSensitiveFields = map[string]struct{}{
 	"auto_ban":            {},
 	"other_info":          {},
 	"tag":                 {},
+	"tags":                {},
 	"remark":              {},
 	"channel_info":        {},
 	"multi_key_mode":      {},
@@ -0,0 +1,122 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ChannelExportRequest struct {
+	Format      string `json:"format"`       // json 或 yaml
+	IncludeKeys bool   `json:"include_keys"` // 是否导出密钥，仅 POST /export/keys 支持
+	Passphrase  string `json:"passphrase"`   // 导出密钥时必填，用于加密密钥
+}
+
+type ChannelImportRequest struct {
+	Data       string `json:"data"`       // JSON 或 YAML 格式的渠道配置包
+	Passphrase string `json:"passphrase"` // 配置包中的密钥已加密时必填
+	DryRun     bool   `json:"dry_run"`    // 仅返回差异，不写入
+}
+
+// ExportChannels 导出不含密钥的渠道配置包。GET 通过查询参数指定 format；
+// 导出密钥只能走 POST /export/keys，避免密钥请求出现在 URL、代理日志和缓存中
+func ExportChannels(c *gin.Context) {
+	req := ChannelExportRequest{Format: c.Query("format")}
+	if c.Request.Method == http.MethodPost {
+		if err := common.DecodeJson(c.Request.Body, &req); err != nil {
+			common.ApiError(c, err)
+			return
+		}
+	} else {
+		req.IncludeKeys, _ = strconv.ParseBool(c.Query("include_keys"))
+	}
+	if req.IncludeKeys {
+		common.ApiErrorMsg(c, "导出渠道密钥请使用 POST /api/channel/export/keys")
+		return
+	}
+	writeChannelBundle(c, req)
+}
+
+// ExportChannelsWithKeys 导出包含密钥的渠道配置包，仅超级管理员可用且需要安全验证，
+// 密钥必须使用口令加密
+func ExportChannelsWithKeys(c *gin.Context) {
+	var req ChannelExportRequest
+	if err := common.DecodeJson(c.Request.Body, &req); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if req.Passphrase == "" {
+		common.ApiErrorMsg(c, "导出渠道密钥时必须提供加密口令")
+		return
+	}
+	req.IncludeKeys = true
+	writeChannelBundle(c, req)
+}
+
+func writeChannelBundle(c *gin.Context, req ChannelExportRequest) {
+	if req.Format == "" {
+		req.Format = "json"
+	}
+	bundle, err := service.ExportChannelBundle(req.IncludeKeys, req.Passphrase)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	data, err := service.MarshalChannelBundle(bundle, req.Format)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	recordManageAudit(c, "channel.export", map[string]interface{}{
+		"count":        len(bundle.Channels),
+		"include_keys": req.IncludeKeys,
+	})
+	contentType := "application/json"
+	if req.Format != "json" {
+		contentType = "application/yaml"
+	}
+	filename := fmt.Sprintf("channels-%s.%s", time.Now().Format("20060102-150405"), req.Format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// ImportChannels 导入渠道配置包，按名称匹配已有渠道：不存在则创建，存在则更新。
+// dry_run 为 true 时只返回差异
+func ImportChannels(c *gin.Context) {
+	var req ChannelImportRequest
+	if err := common.DecodeJson(c.Request.Body, &req); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	bundle, err := service.ParseChannelBundle([]byte(req.Data))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := service.DecryptChannelBundleKeys(bundle, req.Passphrase); err != nil {
+		if errors.Is(err, service.ErrChannelBundlePassphrase) {
+			common.ApiErrorMsg(c, "配置包中的密钥已加密，请提供口令")
+			return
+		}
+		common.ApiError(c, err)
+		return
+	}
+	changes, err := service.ImportChannelBundle(bundle, req.DryRun)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if !req.DryRun {
+		recordManageAudit(c, "channel.import", map[string]interface{}{
+			"count": len(bundle.Channels),
+		})
+	}
+	common.ApiSuccess(c, changes)
+}
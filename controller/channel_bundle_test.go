@@ -0,0 +1,45 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportChannelsRefusesKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		target  string
+		body    string
+		handler gin.HandlerFunc
+		wantMsg string
+	}{
+		{name: "get with include_keys", method: http.MethodGet, target: "/api/channel/export?include_keys=true", handler: ExportChannels, wantMsg: "/api/channel/export/keys"},
+		{name: "post with include_keys", method: http.MethodPost, target: "/api/channel/export", body: `{"include_keys":true,"passphrase":"p"}`, handler: ExportChannels, wantMsg: "/api/channel/export/keys"},
+		{name: "key export without passphrase", method: http.MethodPost, target: "/api/channel/export/keys", body: `{"format":"yaml"}`, handler: ExportChannelsWithKeys, wantMsg: "口令"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(recorder)
+			ctx.Request = httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+
+			tt.handler(ctx)
+
+			var body struct {
+				Success bool   `json:"success"`
+				Message string `json:"message"`
+			}
+			require.NoError(t, common.Unmarshal(recorder.Body.Bytes(), &body))
+			assert.False(t, body.Success)
+			assert.Contains(t, body.Message, tt.wantMsg)
+		})
+	}
+}
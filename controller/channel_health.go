@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	channelHealthDefaultHours = 24
+	channelHealthMaxHours     = 24 * 30
+	channelHealthDefaultLimit = 100
+	channelHealthMaxLimit     = 1000
+)
+
+// channelHealthSince parses the ?hours= window of the health endpoints.
+func channelHealthSince(c *gin.Context) int64 {
+	hours, err := strconv.Atoi(c.Query("hours"))
+	if err != nil || hours <= 0 {
+		hours = channelHealthDefaultHours
+	}
+	hours = min(hours, channelHealthMaxHours)
+	return time.Now().Add(-time.Duration(hours) * time.Hour).Unix()
+}
+
+// GetChannelHealthSummaries returns per-channel probe success and latency over
+// the requested window together with the scheduler settings, which are tuned
+// through the monitor_setting.* options.
+func GetChannelHealthSummaries(c *gin.Context) {
+	summaries, err := model.GetChannelHealthSummaries(channelHealthSince(c))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"setting":   operation_setting.GetMonitorSetting(),
+		"summaries": summaries,
+	})
+}
+
+// GetChannelHealthHistory returns the latest probes of one channel.
+func GetChannelHealthHistory(c *gin.Context) {
+	channelId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || limit <= 0 {
+		limit = channelHealthDefaultLimit
+	}
+	history, err := model.GetChannelHealthHistory(channelId, channelHealthSince(c), min(limit, channelHealthMaxLimit))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, history)
+}
+
+// GetModelStatuses returns the availability of every advertised model over
+// the requested window: probe success rate, p95 latency of successful probes
+// and an overall status. Channel details are never exposed, so the endpoint
+// can back a public status page when monitor_setting.model_status_public is
+// enabled.
+func GetModelStatuses(c *gin.Context) {
+	hours, err := strconv.Atoi(c.Query("hours"))
+	if err != nil || hours <= 0 {
+		hours = channelHealthDefaultHours
+	}
+	hours = min(hours, channelHealthMaxHours)
+	statuses, err := service.GetModelStatuses(hours)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"hours":  hours,
+		"models": statuses,
+	})
+}
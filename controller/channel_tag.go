@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ChannelTagsSelector 按路由标签选择渠道
+type ChannelTagsSelector struct {
+	Tags     []string `json:"tags"`
+	MatchAll bool     `json:"match_all"` // 为 true 时要求渠道同时带有全部标签，否则带有任一标签即可
+}
+
+type ChannelTagsStatusRequest struct {
+	ChannelTagsSelector
+	Enabled bool `json:"enabled"`
+}
+
+type ChannelTagsEditRequest struct {
+	ChannelTagsSelector
+	model.ChannelTagBatchEdit
+}
+
+type ChannelTagsBatchRequest struct {
+	Ids    []int    `json:"ids"`
+	Add    []string `json:"add"`
+	Remove []string `json:"remove"`
+}
+
+// GetChannelTags 返回所有路由标签及其渠道数
+func GetChannelTags(c *gin.Context) {
+	stats, err := model.GetChannelTagStats()
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, stats)
+}
+
+// GetChannelsByTags 按路由标签查询渠道，tags 以逗号分隔
+func GetChannelsByTags(c *gin.Context) {
+	matchAll, _ := strconv.ParseBool(c.Query("match_all"))
+	channels, err := model.GetChannelsByTags(strings.Split(c.Query("tags"), ","), matchAll)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, channels)
+}
+
+// BatchUpdateChannelTags 为指定渠道批量追加或移除路由标签
+func BatchUpdateChannelTags(c *gin.Context) {
+	var req ChannelTagsBatchRequest
+	if err := common.DecodeJson(c.Request.Body, &req); err != nil || len(req.Ids) == 0 {
+		common.ApiErrorMsg(c, "参数错误")
+		return
+	}
+	if len(req.Add) == 0 && len(req.Remove) == 0 {
+		common.ApiErrorMsg(c, "请指定要添加或移除的标签")
+		return
+	}
+	for _, tag := range req.Add {
+		if strings.Contains(tag, ",") {
+			common.ApiErrorMsg(c, "标签不能包含逗号")
+			return
+		}
+	}
+	if err := model.BatchUpdateChannelTags(req.Ids, req.Add, req.Remove); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	model.RefreshChannelCache()
+	recordManageAudit(c, "channel.tags_batch_update", map[string]interface{}{
+		"count":  len(req.Ids),
+		"add":    req.Add,
+		"remove": req.Remove,
+	})
+	common.ApiSuccess(c, len(req.Ids))
+}
+
+// UpdateChannelsStatusByTags 按路由标签批量启用或禁用渠道
+func UpdateChannelsStatusByTags(c *gin.Context) {
+	var req ChannelTagsStatusRequest
+	if err := common.DecodeJson(c.Request.Body, &req); err != nil {
+		common.ApiErrorMsg(c, "参数错误")
+		return
+	}
+	status := common.ChannelStatusManuallyDisabled
+	if req.Enabled {
+		status = common.ChannelStatusEnabled
+	}
+	ids, err := model.BatchEditChannelsByTags(req.Tags, req.MatchAll, model.ChannelTagBatchEdit{Status: &status})
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	model.RefreshChannelCache()
+	recordManageAudit(c, "channel.tags_status", map[string]interface{}{
+		"tags":        req.Tags,
+		"match_all":   req.MatchAll,
+		"enabled":     req.Enabled,
+		"channel_ids": ids,
+	})
+	common.ApiSuccess(c, ids)
+}
+
+// EditChannelsByTags 按路由标签批量修改模型、模型价格、优先级与权重
+func EditChannelsByTags(c *gin.Context) {
+	var req ChannelTagsEditRequest
+	if err := common.DecodeJson(c.Request.Body, &req); err != nil {
+		common.ApiErrorMsg(c, "参数错误")
+		return
+	}
+	// 启用或禁用需要渠道操作权限，使用单独的接口
+	req.Status = nil
+	ids, err := model.BatchEditChannelsByTags(req.Tags, req.MatchAll, req.ChannelTagBatchEdit)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	model.RefreshChannelCache()
+	recordManageAudit(c, "channel.tags_edit", map[string]interface{}{
+		"tags":        req.Tags,
+		"match_all":   req.MatchAll,
+		"channel_ids": ids,
+	})
+	common.ApiSuccess(c, ids)
+}
@@ -410,7 +410,7 @@ func refreshChannelRuntimeCache() {
 					common.SysLog(fmt.Sprintf("InitChannelCache panic: %v", r))
 				}
 			}()
-			model.InitChannelCache()
+			model.RefreshChannelCache()
 		}()
 	}
 	service.ResetProxyClientCache()
@@ -1,72 +1,463 @@
 package controller
 
 import (
+	"encoding/csv"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/i18n"
 	"github.com/QuantumNous/new-api/logger"
 	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
 	"github.com/QuantumNous/new-api/setting/operation_setting"
 	"github.com/gin-gonic/gin"
 )
 
+// checkinErrorKeys 模型层签到错误对应的 i18n 消息键，消息键同时作为响应中的 code
+var checkinErrorKeys = []struct {
+	err error
+	key string
+}{
+	{model.ErrCheckinDisabled, i18n.MsgCheckinDisabled},
+	{model.ErrAlreadyCheckedIn, i18n.MsgCheckinAlreadyToday},
+	{model.ErrCheckinIpLimited, i18n.MsgCheckinIpLimited},
+	{model.ErrCheckinTaskIncomplete, i18n.MsgCheckinTaskIncomplete},
+	{model.ErrCheckinInvalidDate, i18n.MsgCheckinInvalidDate},
+	{model.ErrCheckinDateTaken, i18n.MsgCheckinDateTaken},
+	{model.ErrCheckinMakeupDisabled, i18n.MsgCheckinMakeupDisabled},
+	{model.ErrCheckinMakeupNotPast, i18n.MsgCheckinMakeupNotPast},
+	{model.ErrCheckinMakeupExpired, i18n.MsgCheckinMakeupExpired},
+	{model.ErrCheckinMakeupLimit, i18n.MsgCheckinMakeupLimit},
+	{model.ErrCheckinMakeupQuota, i18n.MsgCheckinMakeupQuota},
+	{model.ErrCheckinUserNotFound, i18n.MsgUserNotExists},
+	{model.ErrCheckinFailed, i18n.MsgCheckinFailed},
+	{model.ErrCheckinMakeupFailed, i18n.MsgCheckinMakeupFailed},
+}
+
+// checkinApiError 按请求语言返回签到错误消息，并附带机器可读的 code
+func checkinApiError(c *gin.Context, key string, args ...map[string]any) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": false,
+		"code":    key,
+		"message": i18n.T(c, key, args...),
+	})
+}
+
+// checkinModelError 把模型层错误转换为本地化响应，无法识别的错误记录日志后按 fallback 返回
+func checkinModelError(c *gin.Context, err error, fallback string) {
+	for _, mapping := range checkinErrorKeys {
+		if errors.Is(err, mapping.err) {
+			if mapping.key == i18n.MsgCheckinMakeupExpired {
+				checkinApiError(c, mapping.key, map[string]any{"Days": operation_setting.GetCheckinSetting().MakeupDays})
+				return
+			}
+			checkinApiError(c, mapping.key)
+			return
+		}
+	}
+	common.SysError(fmt.Sprintf("checkin request failed: %v", err))
+	checkinApiError(c, fallback)
+}
+
+// parseCheckinMonth 解析签到月份参数，支持 month=YYYY-MM 或 year=YYYY&month=MM，默认为当前月份
+func parseCheckinMonth(c *gin.Context) (string, error) {
+	month := c.Query("month")
+	if year := c.Query("year"); year != "" {
+		month = year + "-" + month
+	}
+	if month == "" {
+		return operation_setting.CheckinNow().Format("2006-01"), nil
+	}
+	parsed, err := time.Parse("2006-01", month)
+	if err != nil {
+		return "", errors.New("月份格式错误，应为 YYYY-MM")
+	}
+	return parsed.Format("2006-01"), nil
+}
+
 // GetCheckinStatus 获取用户签到状态和历史记录
 func GetCheckinStatus(c *gin.Context) {
 	setting := operation_setting.GetCheckinSetting()
 	if !setting.Enabled {
-		common.ApiErrorMsg(c, "签到功能未启用")
+		checkinApiError(c, i18n.MsgCheckinDisabled)
 		return
 	}
 	userId := c.GetInt("id")
-	// 获取月份参数，默认为当前月份
-	month := c.DefaultQuery("month", time.Now().Format("2006-01"))
+	month, err := parseCheckinMonth(c)
+	if err != nil {
+		checkinApiError(c, i18n.MsgCheckinInvalidMonth)
+		return
+	}
+
+	group, err := model.GetUserGroup(userId, false)
+	if err != nil {
+		checkinModelError(c, err, i18n.MsgDatabaseError)
+		return
+	}
+	minQuota, maxQuota := operation_setting.GetCheckinQuotaRange(group)
+
+	// 转盘只需要展示奖品，不暴露权重和库存
+	prizes := make([]gin.H, 0, len(setting.Prizes))
+	if setting.LotteryEnabled {
+		for i, prize := range setting.Prizes {
+			prizes = append(prizes, gin.H{
+				"index": i,
+				"id":    prize.Id,
+				"name":  prize.Name,
+				"type":  prize.Type,
+				"quota": prize.Quota,
+			})
+		}
+	}
 
 	stats, err := model.GetUserCheckinStats(userId, month)
 	if err != nil {
-		c.JSON(http.StatusOK, gin.H{
-			"success": false,
-			"message": err.Error(),
-		})
+		checkinModelError(c, err, i18n.MsgDatabaseError)
+		return
+	}
+	taskProgress, err := model.GetCheckinTaskProgress(userId)
+	if err != nil {
+		checkinModelError(c, err, i18n.MsgDatabaseError)
+		return
+	}
+	referral, err := model.GetCheckinReferralBoost(userId)
+	if err != nil {
+		checkinModelError(c, err, i18n.MsgDatabaseError)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data": gin.H{
-			"enabled":   setting.Enabled,
-			"min_quota": setting.MinQuota,
-			"max_quota": setting.MaxQuota,
-			"stats":     stats,
+			"enabled":         setting.Enabled,
+			"min_quota":       minQuota,
+			"max_quota":       maxQuota,
+			"require_captcha": setting.RequireCaptcha,
+			"makeup": gin.H{
+				"enabled":       setting.MakeupEnabled,
+				"cost":          setting.MakeupCost,
+				"days":          setting.MakeupDays,
+				"monthly_limit": setting.MakeupMonthlyLimit,
+			},
+			"current_promotion": operation_setting.GetActiveCheckinPromotion(operation_setting.CheckinNow().Format("2006-01-02")),
+			"lottery": gin.H{
+				"enabled": setting.LotteryEnabled,
+				"prizes":  prizes,
+			},
+			"task":     taskProgress,
+			"referral": referral,
+			"stats":    stats,
 		},
 	})
 }
 
+// GetCheckinCalendar 获取用户单月签到日历，只返回该月记录
+func GetCheckinCalendar(c *gin.Context) {
+	if !operation_setting.IsCheckinEnabled() {
+		checkinApiError(c, i18n.MsgCheckinDisabled)
+		return
+	}
+	month, err := parseCheckinMonth(c)
+	if err != nil {
+		checkinApiError(c, i18n.MsgCheckinInvalidMonth)
+		return
+	}
+
+	records, err := model.GetUserCheckinCalendar(c.GetInt("id"), month)
+	if err != nil {
+		checkinModelError(c, err, i18n.MsgDatabaseError)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"month":   month,
+		"records": records,
+	})
+}
+
 // DoCheckin 执行用户签到
 func DoCheckin(c *gin.Context) {
 	setting := operation_setting.GetCheckinSetting()
 	if !setting.Enabled {
-		common.ApiErrorMsg(c, "签到功能未启用")
+		checkinApiError(c, i18n.MsgCheckinDisabled)
 		return
 	}
 
 	userId := c.GetInt("id")
 
-	checkin, err := model.UserCheckin(userId)
+	checkin, err := model.UserCheckin(userId, c.ClientIP())
 	if err != nil {
-		c.JSON(http.StatusOK, gin.H{
-			"success": false,
-			"message": err.Error(),
-		})
+		checkinModelError(c, err, i18n.MsgCheckinFailed)
 		return
 	}
-	model.RecordLog(userId, model.LogTypeSystem, fmt.Sprintf("用户签到，获得额度 %s", logger.LogQuota(checkin.QuotaAwarded)))
+	content := fmt.Sprintf("用户签到，获得额度 %s，连续签到 %d 天", logger.LogQuota(checkin.QuotaAwarded), checkin.Streak)
+	if checkin.Prize != nil {
+		content += fmt.Sprintf("，抽中奖品「%s」", checkin.Prize.Name)
+	}
+	if checkin.Promotion != nil {
+		content += fmt.Sprintf("，参与促销活动「%s」", checkin.Promotion.Name)
+	}
+	if checkin.Flagged {
+		content += "，同 IP 签到账号过多，已标记待复核"
+	}
+	if checkin.StreakMultiplier > 1 {
+		content += fmt.Sprintf("，连签奖励 %.2f 倍", checkin.StreakMultiplier)
+	}
+	if checkin.ReferralMultiplier > 1 {
+		content += fmt.Sprintf("，邀请加成 %.2f 倍", checkin.ReferralMultiplier)
+	}
+	if checkin.BonusQuota > 0 {
+		content += fmt.Sprintf("，含累计签到奖励 %s", logger.LogQuota(checkin.BonusQuota))
+	}
+	model.RecordLog(userId, model.LogTypeSystem, content)
+	if checkin.InviterKickback > 0 {
+		model.RecordLog(checkin.InviterId, model.LogTypeSystem, fmt.Sprintf("邀请的用户 %d 签到，获得邀请额度 %s", userId, logger.LogQuota(checkin.InviterKickback)))
+	}
+	service.PublishCheckinEvents(checkin)
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"message": "签到成功",
+		"code":    i18n.MsgCheckinSuccess,
+		"message": i18n.T(c, i18n.MsgCheckinSuccess),
+		"data": gin.H{
+			"quota_awarded":     checkin.QuotaAwarded,
+			"checkin_date":      checkin.CheckinDate,
+			"streak":            checkin.Streak,
+			"streak_multiplier": checkin.StreakMultiplier,
+			"bonus_quota":       checkin.BonusQuota,
+			"prize":             checkin.Prize,
+			"promotion":         checkin.Promotion,
+		},
+	})
+}
+
+// DoTokenCheckin 供 API 令牌调用的签到接口，与网页签到共用同一套资格校验。
+// 开启签到人机验证时令牌客户端无法完成验证，直接拒绝，避免脚本绕过
+func DoTokenCheckin(c *gin.Context) {
+	if operation_setting.GetCheckinSetting().RequireCaptcha {
+		checkinApiError(c, i18n.MsgCheckinCaptchaNeeded)
+		return
+	}
+	DoCheckin(c)
+}
+
+type checkinMakeupRequest struct {
+	Date string `json:"date"`
+}
+
+// DoCheckinMakeup 补签指定日期
+func DoCheckinMakeup(c *gin.Context) {
+	var req checkinMakeupRequest
+	if err := common.DecodeJson(c.Request.Body, &req); err != nil || req.Date == "" {
+		checkinApiError(c, i18n.MsgInvalidParams)
+		return
+	}
+
+	userId := c.GetInt("id")
+	checkin, err := model.UserMakeupCheckin(userId, req.Date)
+	if err != nil {
+		checkinModelError(c, err, i18n.MsgCheckinMakeupFailed)
+		return
+	}
+	cost := max(operation_setting.GetCheckinSetting().MakeupCost, 0)
+	model.RecordLog(userId, model.LogTypeSystem, fmt.Sprintf("用户补签 %s，获得额度 %s，扣除补签费用 %s",
+		checkin.CheckinDate, logger.LogQuota(checkin.QuotaAwarded), logger.LogQuota(cost)))
+	service.PublishCheckinEvents(checkin)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"code":    i18n.MsgCheckinMakeupSuccess,
+		"message": i18n.T(c, i18n.MsgCheckinMakeupSuccess),
 		"data": gin.H{
 			"quota_awarded": checkin.QuotaAwarded,
-			"checkin_date":  checkin.CheckinDate},
+			"checkin_date":  checkin.CheckinDate,
+			"streak":        checkin.Streak,
+			"makeup_cost":   cost,
+		},
 	})
 }
+
+// GetCheckinAdminStats 管理员查看签到统计，默认最近 30 天
+func GetCheckinAdminStats(c *gin.Context) {
+	startDate, endDate, err := parseCheckinDateRange(c, 30)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	topN, _ := strconv.Atoi(c.DefaultQuery("top", "10"))
+	if topN <= 0 || topN > 100 {
+		topN = 10
+	}
+
+	stats, err := model.GetCheckinAdminStats(startDate, endDate, topN)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, stats)
+}
+
+// GetCheckinIpClusters 管理员查看同一 IP 下签到的多账号聚集情况，默认最近 7 天
+func GetCheckinIpClusters(c *gin.Context) {
+	startDate, endDate, err := parseCheckinDateRange(c, 7)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	minAccounts, _ := strconv.Atoi(c.Query("min_accounts"))
+	if minAccounts < 2 {
+		minAccounts = max(operation_setting.GetCheckinSetting().MaxAccountsPerIp, 2)
+	}
+
+	clusters, err := model.GetCheckinIpClusters(startDate, endDate, minAccounts, 100)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, clusters)
+}
+
+// parseCheckinDateRange 解析 start_date / end_date 参数，默认截至今天的最近 defaultDays 天，最长一年
+func parseCheckinDateRange(c *gin.Context, defaultDays int) (string, string, error) {
+	now := operation_setting.CheckinNow()
+	startDate := c.DefaultQuery("start_date", now.AddDate(0, 0, 1-defaultDays).Format("2006-01-02"))
+	endDate := c.DefaultQuery("end_date", now.Format("2006-01-02"))
+	start, startErr := time.Parse("2006-01-02", startDate)
+	end, endErr := time.Parse("2006-01-02", endDate)
+	if startErr != nil || endErr != nil || end.Before(start) {
+		return "", "", errors.New("日期范围错误，格式应为 YYYY-MM-DD")
+	}
+	if end.Sub(start) > 366*24*time.Hour {
+		return "", "", errors.New("日期范围不能超过一年")
+	}
+	return startDate, endDate, nil
+}
+
+// exportCheckinCSV 以 CSV 流式输出签到记录，便于核对签到发放的额度；admin 导出额外包含 IP 和复核标记
+func exportCheckinCSV(c *gin.Context, userId int, admin bool) {
+	startDate, endDate, err := parseCheckinDateRange(c, 30)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=checkins_%s_%s.csv", startDate, endDate))
+	// UTF-8 BOM，避免 Excel 打开时乱码
+	_, _ = c.Writer.WriteString("\xEF\xBB\xBF")
+	writer := csv.NewWriter(c.Writer)
+	header := []string{"id", "user_id", "checkin_date", "quota_awarded", "bonus_quota", "streak", "is_makeup", "prize_id", "created_at"}
+	if admin {
+		header = append(header, "ip", "flagged")
+	}
+	_ = writer.Write(header)
+
+	loc := operation_setting.GetCheckinLocation()
+	err = model.ExportCheckins(userId, startDate, endDate, func(batch []model.Checkin) error {
+		for _, checkin := range batch {
+			row := []string{
+				strconv.Itoa(checkin.Id),
+				strconv.Itoa(checkin.UserId),
+				checkin.CheckinDate,
+				strconv.Itoa(checkin.QuotaAwarded),
+				strconv.Itoa(checkin.BonusQuota),
+				strconv.Itoa(checkin.Streak),
+				strconv.FormatBool(checkin.IsMakeup),
+				checkin.PrizeId,
+				time.Unix(checkin.CreatedAt, 0).In(loc).Format("2006-01-02 15:04:05"),
+			}
+			if admin {
+				row = append(row, checkin.Ip, strconv.FormatBool(checkin.Flagged))
+			}
+			if err := writer.Write(row); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	})
+	writer.Flush()
+	if err != nil {
+		// 响应已经开始输出，只能记录错误并中断
+		common.SysError(fmt.Sprintf("failed to export checkins: %v", err))
+		c.Abort()
+	}
+}
+
+// ExportSelfCheckins 导出当前用户的签到记录
+func ExportSelfCheckins(c *gin.Context) {
+	exportCheckinCSV(c, c.GetInt("id"), false)
+}
+
+// AdminExportCheckins 管理员按用户和日期范围导出签到记录，不指定 user_id 时导出全部用户
+func AdminExportCheckins(c *gin.Context) {
+	userId, _ := strconv.Atoi(c.Query("user_id"))
+	exportCheckinCSV(c, userId, true)
+}
+
+type checkinAdminRequest struct {
+	UserId int    `json:"user_id"`
+	Date   string `json:"date"`
+	Quota  *int   `json:"quota,omitempty"`
+}
+
+// AdminGrantCheckin 管理员为用户补录签到记录
+func AdminGrantCheckin(c *gin.Context) {
+	var req checkinAdminRequest
+	if err := common.DecodeJson(c.Request.Body, &req); err != nil || req.UserId <= 0 || req.Date == "" {
+		common.ApiErrorMsg(c, "参数错误")
+		return
+	}
+	quota := -1
+	if req.Quota != nil {
+		quota = *req.Quota
+		if quota < 0 {
+			common.ApiErrorMsg(c, "额度不能为负数")
+			return
+		}
+	}
+
+	checkin, err := model.AdminGrantCheckin(req.UserId, req.Date, quota)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	recordManageAuditFor(c, req.UserId, "checkin.grant", map[string]interface{}{
+		"user_id": req.UserId,
+		"date":    checkin.CheckinDate,
+		"quota":   checkin.QuotaAwarded,
+	})
+	common.ApiSuccess(c, checkin)
+}
+
+// AdminRevokeCheckin 管理员撤销用户签到记录并扣回额度
+func AdminRevokeCheckin(c *gin.Context) {
+	var req checkinAdminRequest
+	if err := common.DecodeJson(c.Request.Body, &req); err != nil || req.UserId <= 0 || req.Date == "" {
+		common.ApiErrorMsg(c, "参数错误")
+		return
+	}
+
+	checkin, err := model.AdminRevokeCheckin(req.UserId, req.Date)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	recordManageAuditFor(c, req.UserId, "checkin.revoke", map[string]interface{}{
+		"user_id": req.UserId,
+		"date":    checkin.CheckinDate,
+		"quota":   checkin.QuotaAwarded,
+	})
+	common.ApiSuccess(c, checkin)
+}
+
+// GetSelfQuotaGrants 获取当前用户仍然有效的限时额度
+func GetSelfQuotaGrants(c *gin.Context) {
+	summary, err := model.GetUserQuotaGrantSummary(c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, summary)
+}
@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/i18n"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCheckinMonth(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    string
+		wantErr bool
+	}{
+		{name: "month param", query: "month=2025-06", want: "2025-06"},
+		{name: "year and month params", query: "year=2025&month=06", want: "2025-06"},
+		{name: "single digit month", query: "year=2025&month=6", wantErr: true},
+		{name: "garbage", query: "month=abc", wantErr: true},
+		{name: "sql-ish input rejected", query: "month=2025-06'--", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest(http.MethodGet, "/api/user/checkin/list?"+tt.query, nil)
+
+			got, err := parseCheckinMonth(ctx)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCheckinModelErrorLocalized(t *testing.T) {
+	require.NoError(t, i18n.Init())
+	setting := operation_setting.GetCheckinSetting()
+	savedDays := setting.MakeupDays
+	setting.MakeupDays = 5
+	t.Cleanup(func() { setting.MakeupDays = savedDays })
+
+	tests := []struct {
+		name     string
+		lang     string
+		err      error
+		wantCode string
+		wantMsg  string
+	}{
+		{name: "english", lang: "en-US", err: model.ErrAlreadyCheckedIn, wantCode: i18n.MsgCheckinAlreadyToday, wantMsg: "Already checked in today"},
+		{name: "simplified chinese", lang: "zh-CN", err: model.ErrAlreadyCheckedIn, wantCode: i18n.MsgCheckinAlreadyToday, wantMsg: "今日已签到"},
+		{name: "template args", lang: "en", err: model.ErrCheckinMakeupExpired, wantCode: i18n.MsgCheckinMakeupExpired, wantMsg: "Only dates within the last 5 days can be made up"},
+		{name: "unknown error falls back", lang: "en", err: errors.New("db down"), wantCode: i18n.MsgCheckinFailed, wantMsg: "Check-in failed, please try again later"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(recorder)
+			ctx.Request = httptest.NewRequest(http.MethodPost, "/api/user/checkin", nil)
+			ctx.Request.Header.Set("Accept-Language", tt.lang)
+
+			checkinModelError(ctx, tt.err, i18n.MsgCheckinFailed)
+
+			var body struct {
+				Success bool   `json:"success"`
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			}
+			require.NoError(t, common.Unmarshal(recorder.Body.Bytes(), &body))
+			assert.False(t, body.Success)
+			assert.Equal(t, tt.wantCode, body.Code)
+			assert.Equal(t, tt.wantMsg, body.Message)
+		})
+	}
+}
+
+func TestDoTokenCheckinRejectsWhenCaptchaRequired(t *testing.T) {
+	require.NoError(t, i18n.Init())
+	setting := operation_setting.GetCheckinSetting()
+	savedCaptcha := setting.RequireCaptcha
+	setting.RequireCaptcha = true
+	t.Cleanup(func() { setting.RequireCaptcha = savedCaptcha })
+
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/v1/dashboard/checkin", nil)
+	ctx.Set("id", 1)
+
+	DoTokenCheckin(ctx)
+
+	var body struct {
+		Success bool   `json:"success"`
+		Code    string `json:"code"`
+	}
+	require.NoError(t, common.Unmarshal(recorder.Body.Bytes(), &body))
+	assert.False(t, body.Success)
+	assert.Equal(t, i18n.MsgCheckinCaptchaNeeded, body.Code)
+}
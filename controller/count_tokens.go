@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CountClaudeTokens 兼容 Anthropic /v1/messages/count_tokens
+// 在本地估算输入 token 数，不选择渠道、不请求上游、不计费
+func CountClaudeTokens(c *gin.Context) {
+	request := &dto.ClaudeRequest{}
+	if err := common.UnmarshalBodyReusable(c, request); err != nil {
+		respondClaudeCountTokensError(c, types.NewErrorWithStatusCode(err, types.ErrorCodeInvalidRequest, http.StatusBadRequest))
+		return
+	}
+	if request.Model == "" {
+		respondClaudeCountTokensError(c, types.NewErrorWithStatusCode(errors.New("model is required"), types.ErrorCodeInvalidRequest, http.StatusBadRequest))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"input_tokens": estimateLocalInputTokens(request.GetTokenCountMeta(), request.Model),
+	})
+}
+
+func respondClaudeCountTokensError(c *gin.Context, newAPIError *types.NewAPIError) {
+	c.JSON(newAPIError.StatusCode, gin.H{
+		"type":  "error",
+		"error": newAPIError.ToClaudeError(),
+	})
+}
+
+// CountGeminiTokens 兼容 Gemini /v1beta/models/{model}:countTokens
+// 请求体可以是 generateContent 请求本身，也可以包裹在 generateContentRequest 中
+func CountGeminiTokens(c *gin.Context) {
+	modelName := strings.TrimPrefix(c.Param("path"), "/")
+	modelName, _, _ = strings.Cut(modelName, ":")
+
+	var wrapped struct {
+		GenerateContentRequest *dto.GeminiChatRequest `json:"generateContentRequest"`
+	}
+	if err := common.UnmarshalBodyReusable(c, &wrapped); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": types.NewErrorWithStatusCode(err, types.ErrorCodeInvalidRequest, http.StatusBadRequest).ToOpenAIError(),
+		})
+		return
+	}
+	request := wrapped.GenerateContentRequest
+	if request == nil {
+		request = &dto.GeminiChatRequest{}
+		if err := common.UnmarshalBodyReusable(c, request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": types.NewErrorWithStatusCode(err, types.ErrorCodeInvalidRequest, http.StatusBadRequest).ToOpenAIError(),
+			})
+			return
+		}
+	}
+
+	meta := request.GetTokenCountMeta()
+	if request.SystemInstructions != nil {
+		for _, part := range request.SystemInstructions.Parts {
+			meta.CombineText += "\n" + part.Text
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"totalTokens": estimateLocalInputTokens(meta, modelName),
+	})
+}
+
+// estimateLocalInputTokens 与请求预估保持一致：文本按模型分词，媒体文件按类型估算固定值
+func estimateLocalInputTokens(meta *types.TokenCountMeta, modelName string) int {
+	inputTokens := service.CountTextToken(meta.CombineText, modelName)
+	for _, file := range meta.Files {
+		switch file.FileType {
+		case types.FileTypeImage:
+			inputTokens += 520
+		case types.FileTypeAudio:
+			inputTokens += 256
+		case types.FileTypeVideo:
+			inputTokens += 4096 * 2
+		default:
+			inputTokens += 4096
+		}
+	}
+	return inputTokens
+}
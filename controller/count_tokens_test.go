@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountClaudeTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{
+			name:       "counts messages and system",
+			body:       `{"model":"claude-sonnet-4-5","system":"You are terse.","messages":[{"role":"user","content":"Hello, how many tokens is this?"}]}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "missing model",
+			body:       `{"messages":[{"role":"user","content":"hi"}]}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid json",
+			body:       `{"model":`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = httptest.NewRequest(http.MethodPost, "/v1/messages/count_tokens", strings.NewReader(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			CountClaudeTokens(c)
+
+			require.Equal(t, tt.wantStatus, recorder.Code)
+			var response map[string]any
+			require.NoError(t, common.Unmarshal(recorder.Body.Bytes(), &response))
+			if tt.wantStatus == http.StatusOK {
+				require.Greater(t, response["input_tokens"], float64(0))
+			} else {
+				require.Equal(t, "error", response["type"])
+			}
+		})
+	}
+}
+
+func TestCountGeminiTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{
+			name:       "plain generateContent body",
+			body:       `{"contents":[{"role":"user","parts":[{"text":"Hello, how many tokens is this?"}]}]}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "wrapped generateContentRequest",
+			body:       `{"generateContentRequest":{"contents":[{"role":"user","parts":[{"text":"Hello"}]}],"systemInstruction":{"parts":[{"text":"Be terse."}]}}}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "invalid json",
+			body:       `{"contents":`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = httptest.NewRequest(http.MethodPost, "/v1beta/models/gemini-2.5-flash:countTokens", strings.NewReader(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Params = gin.Params{{Key: "path", Value: "/gemini-2.5-flash:countTokens"}}
+
+			CountGeminiTokens(c)
+
+			require.Equal(t, tt.wantStatus, recorder.Code)
+			var response map[string]any
+			require.NoError(t, common.Unmarshal(recorder.Body.Bytes(), &response))
+			if tt.wantStatus == http.StatusOK {
+				require.Greater(t, response["totalTokens"], float64(0))
+			} else {
+				require.Contains(t, response, "error")
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+// validateGroupUpgradeRules 校验自动升级规则：门槛不能为负，目标分组必须存在且不能重复
+func validateGroupUpgradeRules(rules []operation_setting.GroupUpgradeRule) error {
+	seen := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		if rule.MinSpend < 0 {
+			return errors.New("消费门槛不能为负数")
+		}
+		if !ratio_setting.ContainsGroupRatio(rule.Group) {
+			return fmt.Errorf("分组 %s 不存在", rule.Group)
+		}
+		if seen[rule.Group] {
+			return fmt.Errorf("分组 %s 重复配置", rule.Group)
+		}
+		seen[rule.Group] = true
+	}
+	return nil
+}
+
+// PreviewGroupUpgrades 预览按累计消费规则将被调整分组的用户，不会实际修改。
+// 请求体可传入尚未保存的配置，留空则使用当前配置
+func PreviewGroupUpgrades(c *gin.Context) {
+	setting := *operation_setting.GetGroupUpgradeSetting()
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&setting); err != nil {
+			common.ApiError(c, err)
+			return
+		}
+	}
+	if err := validateGroupUpgradeRules(setting.Rules); err != nil {
+		common.ApiErrorMsg(c, err.Error())
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+	changes, err := service.PreviewGroupUpgrades(c.Request.Context(), &setting, limit)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"changes":   changes,
+		"truncated": len(changes) >= limit,
+	})
+}
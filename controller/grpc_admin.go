@@ -0,0 +1,431 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/pkg/adminpb"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// GrpcAdminServer 以 gRPC 提供的管理接口，调用方身份由 mTLS 客户端证书确定，
+// 拥有与超级管理员相同的权限，但不能创建管理员
+type GrpcAdminServer struct {
+	adminpb.UnimplementedAdminServiceServer
+}
+
+type grpcOperatorKey struct{}
+
+// WithGrpcOperator 在 ctx 中记录调用方的证书标识，写入审计日志
+func WithGrpcOperator(ctx context.Context, operator string) context.Context {
+	return context.WithValue(ctx, grpcOperatorKey{}, operator)
+}
+
+// recordGrpcAudit 记录 gRPC 管理操作的审计日志，操作者为客户端证书而非站内用户
+func recordGrpcAudit(ctx context.Context, targetUserId int, action string, params map[string]interface{}) {
+	if targetUserId > 0 {
+		params["target_user_id"] = targetUserId
+	}
+	operator, _ := ctx.Value(grpcOperatorKey{}).(string)
+	method, _ := grpc.Method(ctx)
+	ip := ""
+	if p, ok := peer.FromContext(ctx); ok {
+		ip, _, _ = net.SplitHostPort(p.Addr.String())
+	}
+	adminInfo := map[string]interface{}{
+		"admin_id":       0,
+		"admin_username": operator,
+		"admin_role":     common.RoleRootUser,
+		"auth_method":    "mtls",
+	}
+	auditInfo := map[string]interface{}{
+		"method": "gRPC",
+		"route":  method,
+	}
+	model.RecordOperationAuditLog(0, auditContentEN(action, params), ip, action, params, adminInfo, auditInfo)
+}
+
+// grpcError 将模型层错误转换为 gRPC 状态码。返回给调用方的只有通用描述，
+// 原始错误（可能包含 SQL 或内部细节）仅记录到系统日志
+func grpcError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return status.Error(codes.NotFound, "record not found")
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return status.Error(codes.AlreadyExists, "record already exists")
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, "request canceled")
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "request deadline exceeded")
+	}
+	common.SysError("grpc admin api error: " + err.Error())
+	return status.Error(codes.Internal, "internal error")
+}
+
+// grpcPageInfo 与 HTTP 接口的分页规则一致，页大小不超过 common.MaxPageSize
+func grpcPageInfo(page int32, pageSize int32) *common.PageInfo {
+	pageInfo := &common.PageInfo{Page: int(page), PageSize: int(pageSize)}
+	if pageInfo.Page < 1 {
+		pageInfo.Page = 1
+	}
+	if pageInfo.PageSize <= 0 {
+		pageInfo.PageSize = common.ItemsPerPage
+	}
+	if pageInfo.PageSize > common.MaxPageSize {
+		pageInfo.PageSize = common.MaxPageSize
+	}
+	return pageInfo
+}
+
+func grpcUser(user *model.User) *adminpb.User {
+	return &adminpb.User{
+		Id:           int64(user.Id),
+		Username:     user.Username,
+		DisplayName:  user.DisplayName,
+		Email:        user.Email,
+		Role:         int32(user.Role),
+		Status:       int32(user.Status),
+		Group:        user.Group,
+		Quota:        int64(user.Quota),
+		UsedQuota:    int64(user.UsedQuota),
+		RequestCount: int64(user.RequestCount),
+		CreatedAt:    user.CreatedAt,
+	}
+}
+
+func grpcToken(token *model.Token, key string) *adminpb.Token {
+	return &adminpb.Token{
+		Id:             int64(token.Id),
+		UserId:         int64(token.UserId),
+		Name:           token.Name,
+		Key:            key,
+		Status:         int32(token.Status),
+		RemainQuota:    int64(token.RemainQuota),
+		UnlimitedQuota: token.UnlimitedQuota,
+		UsedQuota:      int64(token.UsedQuota),
+		ExpiredTime:    token.ExpiredTime,
+		CreatedTime:    token.CreatedTime,
+		Group:          token.Group,
+	}
+}
+
+func grpcChannel(channel *model.Channel) *adminpb.Channel {
+	return &adminpb.Channel{
+		Id:           int64(channel.Id),
+		Name:         channel.Name,
+		Type:         int32(channel.Type),
+		Status:       int32(channel.Status),
+		Group:        channel.Group,
+		Models:       channel.Models,
+		Priority:     channel.GetPriority(),
+		Weight:       int64(channel.GetWeight()),
+		UsedQuota:    channel.UsedQuota,
+		ResponseTime: int64(channel.ResponseTime),
+		Balance:      channel.Balance,
+		Tag:          channel.GetTag(),
+	}
+}
+
+func (s *GrpcAdminServer) ListUsers(ctx context.Context, req *adminpb.ListUsersRequest) (*adminpb.ListUsersResponse, error) {
+	pageInfo := grpcPageInfo(req.Page, req.PageSize)
+	var users []*model.User
+	var total int64
+	var err error
+	if keyword := strings.TrimSpace(req.Keyword); keyword != "" {
+		users, total, err = model.SearchUsers(keyword, "", nil, nil, pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	} else {
+		users, total, err = model.GetAllUsers(pageInfo)
+	}
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	resp := &adminpb.ListUsersResponse{Total: total}
+	for _, user := range users {
+		resp.Users = append(resp.Users, grpcUser(user))
+	}
+	return resp, nil
+}
+
+func (s *GrpcAdminServer) GetUser(ctx context.Context, req *adminpb.GetUserRequest) (*adminpb.User, error) {
+	if req.Id <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+	user, err := model.GetUserById(int(req.Id), false)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return grpcUser(user), nil
+}
+
+func (s *GrpcAdminServer) CreateUser(ctx context.Context, req *adminpb.CreateUserRequest) (*adminpb.User, error) {
+	user := model.User{
+		Username:    strings.TrimSpace(req.Username),
+		Password:    req.Password,
+		DisplayName: req.DisplayName,
+		Role:        common.RoleCommonUser,
+		Group:       req.Group,
+	}
+	if user.Username == "" || user.Password == "" {
+		return nil, status.Error(codes.InvalidArgument, "username and password are required")
+	}
+	if err := common.Validate.Struct(&user); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if user.Group != "" && !ratio_setting.ContainsGroupRatio(user.Group) {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown group %q", user.Group)
+	}
+	if user.DisplayName == "" {
+		user.DisplayName = user.Username
+	}
+	exist, err := model.CheckUserExistOrDeleted(user.Username, "")
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if exist {
+		return nil, status.Errorf(codes.AlreadyExists, "username %q is taken", user.Username)
+	}
+	if err := user.Insert(0); err != nil {
+		return nil, grpcError(err)
+	}
+	recordGrpcAudit(ctx, user.Id, "user.create", map[string]interface{}{
+		"username": user.Username,
+		"role":     user.Role,
+	})
+	return grpcUser(&user), nil
+}
+
+func (s *GrpcAdminServer) SetUserStatus(ctx context.Context, req *adminpb.SetUserStatusRequest) (*adminpb.User, error) {
+	if req.Id <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+	user, err := model.GetUserById(int(req.Id), false)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	action := "enable"
+	user.Status = common.UserStatusEnabled
+	if !req.Enabled {
+		if user.Role == common.RoleRootUser {
+			return nil, status.Error(codes.FailedPrecondition, "the root user cannot be disabled")
+		}
+		action = "disable"
+		user.Status = common.UserStatusDisabled
+	}
+	if err := user.Update(false); err != nil {
+		return nil, grpcError(err)
+	}
+	if !req.Enabled {
+		// 与控制台一致，禁用后立即失效用户与令牌缓存
+		if err := model.InvalidateUserCache(user.Id); err != nil {
+			common.SysLog("failed to invalidate user cache: " + err.Error())
+		}
+		if err := model.InvalidateUserTokensCache(user.Id); err != nil {
+			common.SysLog("failed to invalidate tokens cache: " + err.Error())
+		}
+	}
+	recordGrpcAudit(ctx, user.Id, "user.manage", map[string]interface{}{
+		"action":   action,
+		"username": user.Username,
+		"id":       user.Id,
+	})
+	return grpcUser(user), nil
+}
+
+func (s *GrpcAdminServer) ListTokens(ctx context.Context, req *adminpb.ListTokensRequest) (*adminpb.ListTokensResponse, error) {
+	if req.UserId <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	pageInfo := grpcPageInfo(req.Page, req.PageSize)
+	tokens, err := model.GetAllUserTokens(int(req.UserId), pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	total, err := model.CountUserTokens(int(req.UserId))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	resp := &adminpb.ListTokensResponse{Total: total}
+	for _, token := range tokens {
+		resp.Tokens = append(resp.Tokens, grpcToken(token, token.GetMaskedKey()))
+	}
+	return resp, nil
+}
+
+func (s *GrpcAdminServer) CreateToken(ctx context.Context, req *adminpb.CreateTokenRequest) (*adminpb.Token, error) {
+	if req.UserId <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	if len(req.Name) > 50 {
+		return nil, status.Error(codes.InvalidArgument, "name must be at most 50 characters")
+	}
+	if !req.UnlimitedQuota {
+		maxQuotaValue := int64(1000000000 * common.QuotaPerUnit)
+		if req.RemainQuota < 0 || req.RemainQuota > maxQuotaValue {
+			return nil, status.Errorf(codes.InvalidArgument, "remain_quota must be between 0 and %d", maxQuotaValue)
+		}
+	}
+	if _, err := model.GetUserById(int(req.UserId), false); err != nil {
+		return nil, grpcError(err)
+	}
+	count, err := model.CountUserTokens(int(req.UserId))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if maxTokens := operation_setting.GetMaxUserTokens(); int(count) >= maxTokens {
+		return nil, status.Errorf(codes.ResourceExhausted, "the user already has the maximum of %d tokens", maxTokens)
+	}
+	key, err := common.GenerateKey()
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	expiredTime := req.ExpiredTime
+	if expiredTime == 0 {
+		expiredTime = -1
+	}
+	token := model.Token{
+		UserId:         int(req.UserId),
+		Name:           req.Name,
+		Key:            key,
+		CreatedTime:    common.GetTimestamp(),
+		AccessedTime:   common.GetTimestamp(),
+		ExpiredTime:    expiredTime,
+		RemainQuota:    int(req.RemainQuota),
+		UnlimitedQuota: req.UnlimitedQuota,
+		Group:          req.Group,
+	}
+	if err := token.Insert(); err != nil {
+		return nil, grpcError(err)
+	}
+	recordGrpcAudit(ctx, token.UserId, "token.create", map[string]interface{}{
+		"id":   token.Id,
+		"name": token.Name,
+	})
+	return grpcToken(&token, token.GetFullKey()), nil
+}
+
+func (s *GrpcAdminServer) DeleteToken(ctx context.Context, req *adminpb.DeleteTokenRequest) (*adminpb.DeleteTokenResponse, error) {
+	if req.Id <= 0 || req.UserId <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "id and user_id are required")
+	}
+	if err := model.DeleteTokenById(int(req.Id), int(req.UserId)); err != nil {
+		return nil, grpcError(err)
+	}
+	recordGrpcAudit(ctx, int(req.UserId), "token.delete", map[string]interface{}{
+		"id": req.Id,
+	})
+	return &adminpb.DeleteTokenResponse{}, nil
+}
+
+func (s *GrpcAdminServer) ListChannels(ctx context.Context, req *adminpb.ListChannelsRequest) (*adminpb.ListChannelsResponse, error) {
+	pageInfo := grpcPageInfo(req.Page, req.PageSize)
+	channels, err := model.GetAllChannels(pageInfo.GetStartIdx(), pageInfo.GetPageSize(), false, false)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	total, err := model.CountAllChannels()
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	resp := &adminpb.ListChannelsResponse{Total: total}
+	for _, channel := range channels {
+		resp.Channels = append(resp.Channels, grpcChannel(channel))
+	}
+	return resp, nil
+}
+
+func (s *GrpcAdminServer) SetChannelStatus(ctx context.Context, req *adminpb.SetChannelStatusRequest) (*adminpb.Channel, error) {
+	if req.Id <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+	id := int(req.Id)
+	if _, err := model.GetChannelById(id, false); err != nil {
+		return nil, grpcError(err)
+	}
+	channelStatus := common.ChannelStatusManuallyDisabled
+	if req.Enabled {
+		channelStatus = common.ChannelStatusEnabled
+	}
+	reason := req.Reason
+	if reason == "" {
+		reason = "manual operation"
+	}
+	changed := model.UpdateChannelStatus(id, "", channelStatus, reason)
+	if changed {
+		model.RefreshChannelCache()
+		service.ResetProxyClientCache()
+	}
+	recordGrpcAudit(ctx, 0, "channel.status_update", map[string]interface{}{
+		"id":      id,
+		"status":  channelStatus,
+		"changed": changed,
+	})
+	channel, err := model.GetChannelById(id, false)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return grpcChannel(channel), nil
+}
+
+func (s *GrpcAdminServer) GrantQuota(ctx context.Context, req *adminpb.GrantQuotaRequest) (*adminpb.GrantQuotaResponse, error) {
+	if req.UserId <= 0 || req.Quota <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id and a positive quota are required")
+	}
+	if req.ExpiresAt != 0 && req.ExpiresAt <= common.GetTimestamp() {
+		return nil, status.Error(codes.InvalidArgument, "expires_at must be in the future")
+	}
+	userId := int(req.UserId)
+	if err := model.GrantUserQuota(userId, int(req.Quota), req.ExpiresAt); err != nil {
+		return nil, grpcError(err)
+	}
+	recordGrpcAudit(ctx, userId, "user.quota_add", map[string]interface{}{
+		"quota":      logger.LogQuota(int(req.Quota)),
+		"expires_at": req.ExpiresAt,
+	})
+	quota, err := model.GetUserQuota(userId, true)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &adminpb.GrantQuotaResponse{Quota: int64(quota)}, nil
+}
+
+func (s *GrpcAdminServer) GetUsage(ctx context.Context, req *adminpb.GetUsageRequest) (*adminpb.GetUsageResponse, error) {
+	endTimestamp := req.EndTimestamp
+	if endTimestamp == 0 {
+		endTimestamp = common.GetTimestamp()
+	}
+	if req.StartTimestamp > endTimestamp {
+		return nil, status.Error(codes.InvalidArgument, "start_timestamp must not be after end_timestamp")
+	}
+	rows, err := model.GetModelUsage(int(req.UserId), req.StartTimestamp, endTimestamp)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	resp := &adminpb.GetUsageResponse{}
+	for _, row := range rows {
+		resp.Models = append(resp.Models, &adminpb.ModelUsage{
+			ModelName:        row.ModelName,
+			Requests:         row.Requests,
+			ErrorCount:       row.ErrorCount,
+			Quota:            row.Quota,
+			PromptTokens:     row.PromptTokens,
+			CompletionTokens: row.CompletionTokens,
+			TotalTokens:      row.TotalTokens,
+		})
+		resp.Quota += row.Quota
+		resp.Requests += row.Requests
+	}
+	return resp, nil
+}
@@ -0,0 +1,173 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/pkg/adminpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+func TestGrpcAdminServerUserLifecycle(t *testing.T) {
+	db := openTokenControllerTestDB(t)
+	require.NoError(t, db.AutoMigrate(&model.User{}, &model.Token{}, &model.Log{}, &model.AuditLog{}, &model.QuotaGrant{}))
+	server := &GrpcAdminServer{}
+	ctx := WithGrpcOperator(context.Background(), "provisioner")
+
+	user, err := server.CreateUser(ctx, &adminpb.CreateUserRequest{Username: "grpc_user", Password: "password123"})
+	require.NoError(t, err)
+	assert.Equal(t, "grpc_user", user.DisplayName)
+	assert.Equal(t, int32(common.RoleCommonUser), user.Role)
+	_, err = server.CreateUser(ctx, &adminpb.CreateUserRequest{Username: "grpc_user", Password: "password123"})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	granted, err := server.GrantQuota(ctx, &adminpb.GrantQuotaRequest{UserId: user.Id, Quota: 1000})
+	require.NoError(t, err)
+	assert.Equal(t, user.Quota+1000, granted.Quota)
+	_, err = server.GrantQuota(ctx, &adminpb.GrantQuotaRequest{UserId: user.Id + 100, Quota: 1000})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	token, err := server.CreateToken(ctx, &adminpb.CreateTokenRequest{UserId: user.Id, Name: "provisioned", RemainQuota: 500})
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), token.ExpiredTime)
+	tokens, err := server.ListTokens(ctx, &adminpb.ListTokensRequest{UserId: user.Id})
+	require.NoError(t, err)
+	require.Len(t, tokens.Tokens, 1)
+	assert.NotEqual(t, token.Key, tokens.Tokens[0].Key, "listed keys are masked")
+
+	disabled, err := server.SetUserStatus(ctx, &adminpb.SetUserStatusRequest{Id: user.Id, Enabled: false})
+	require.NoError(t, err)
+	assert.Equal(t, int32(common.UserStatusDisabled), disabled.Status)
+
+	_, err = server.DeleteToken(ctx, &adminpb.DeleteTokenRequest{Id: token.Id, UserId: user.Id})
+	require.NoError(t, err)
+	_, err = server.DeleteToken(ctx, &adminpb.DeleteTokenRequest{Id: token.Id, UserId: user.Id})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	var audits []model.AuditLog
+	require.NoError(t, db.Order("id").Find(&audits).Error)
+	actions := make([]string, 0, len(audits))
+	for _, audit := range audits {
+		assert.Equal(t, "provisioner", audit.ActorName)
+		actions = append(actions, audit.Action)
+	}
+	assert.Equal(t, []string{"user.create", "user.quota_add", "token.create", "user.manage", "token.delete"}, actions)
+}
+
+func TestGrpcAdminServerRejectsInvalidRequests(t *testing.T) {
+	db := openTokenControllerTestDB(t)
+	require.NoError(t, db.AutoMigrate(&model.User{}, &model.Token{}))
+	require.NoError(t, db.Create(&model.User{Id: 1, Username: "root", Role: common.RoleRootUser, Status: common.UserStatusEnabled, AffCode: "root"}).Error)
+	server := &GrpcAdminServer{}
+	ctx := context.Background()
+
+	tests := []struct {
+		name         string
+		call         func() error
+		expectedCode codes.Code
+	}{
+		{
+			name: "disable root user",
+			call: func() error {
+				_, err := server.SetUserStatus(ctx, &adminpb.SetUserStatusRequest{Id: 1, Enabled: false})
+				return err
+			},
+			expectedCode: codes.FailedPrecondition,
+		},
+		{
+			name: "create user without password",
+			call: func() error {
+				_, err := server.CreateUser(ctx, &adminpb.CreateUserRequest{Username: "someone"})
+				return err
+			},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name: "grant non-positive quota",
+			call: func() error {
+				_, err := server.GrantQuota(ctx, &adminpb.GrantQuotaRequest{UserId: 1, Quota: 0})
+				return err
+			},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name: "grant quota expiring in the past",
+			call: func() error {
+				_, err := server.GrantQuota(ctx, &adminpb.GrantQuotaRequest{UserId: 1, Quota: 10, ExpiresAt: 1})
+				return err
+			},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name: "token with negative quota",
+			call: func() error {
+				_, err := server.CreateToken(ctx, &adminpb.CreateTokenRequest{UserId: 1, RemainQuota: -1})
+				return err
+			},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name: "unknown user",
+			call: func() error {
+				_, err := server.GetUser(ctx, &adminpb.GetUserRequest{Id: 42})
+				return err
+			},
+			expectedCode: codes.NotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedCode, status.Code(tt.call()))
+		})
+	}
+}
+
+func TestGrpcErrorHidesInternalDetails(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantCode    codes.Code
+		wantMessage string
+	}{
+		{name: "not found", err: fmt.Errorf("lookup: %w", gorm.ErrRecordNotFound), wantCode: codes.NotFound, wantMessage: "record not found"},
+		{name: "duplicate", err: gorm.ErrDuplicatedKey, wantCode: codes.AlreadyExists, wantMessage: "record already exists"},
+		{name: "canceled", err: context.Canceled, wantCode: codes.Canceled, wantMessage: "request canceled"},
+		{name: "internal", err: errors.New("Error 1054: Unknown column 'secret' in 'users'"), wantCode: codes.Internal, wantMessage: "internal error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := status.Convert(grpcError(tt.err))
+			assert.Equal(t, tt.wantCode, st.Code())
+			assert.Equal(t, tt.wantMessage, st.Message())
+		})
+	}
+}
+
+func TestGrpcPageInfoClampsPageSize(t *testing.T) {
+	tests := []struct {
+		name         string
+		page         int32
+		pageSize     int32
+		wantPage     int
+		wantPageSize int
+	}{
+		{name: "defaults", wantPage: 1, wantPageSize: common.ItemsPerPage},
+		{name: "within limit", page: 3, pageSize: 50, wantPage: 3, wantPageSize: 50},
+		{name: "over limit", page: 1, pageSize: 100000, wantPage: 1, wantPageSize: common.MaxPageSize},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pageInfo := grpcPageInfo(tt.page, tt.pageSize)
+			assert.Equal(t, tt.wantPage, pageInfo.Page)
+			assert.Equal(t, tt.wantPageSize, pageInfo.PageSize)
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/QuantumNous/new-api/common"
@@ -14,6 +15,7 @@ import (
 	"github.com/QuantumNous/new-api/model"
 	"github.com/QuantumNous/new-api/service"
 	"github.com/QuantumNous/new-api/setting"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
 	"github.com/QuantumNous/new-api/setting/system_setting"
 
 	"github.com/gin-gonic/gin"
@@ -22,9 +24,10 @@ import (
 // midjourneyPollSummary is the result recorded on a midjourney_poll system task
 // row, summarizing one polling pass.
 type midjourneyPollSummary struct {
-	UnfinishedTasks int `json:"unfinished_tasks"`
-	ChannelsScanned int `json:"channels_scanned"`
-	NullTasksFailed int `json:"null_tasks_failed"`
+	UnfinishedTasks  int `json:"unfinished_tasks"`
+	ChannelsScanned  int `json:"channels_scanned"`
+	NullTasksFailed  int `json:"null_tasks_failed"`
+	StuckTasksReaped int `json:"stuck_tasks_reaped"`
 }
 
 // runMidjourneyTaskUpdateOnce performs one Midjourney polling pass synchronously.
@@ -37,6 +40,8 @@ func runMidjourneyTaskUpdateOnce(ctx context.Context, report func(processed, tot
 		ctx = context.Background()
 	}
 
+	summary.StuckTasksReaped = reapStuckMidjourneyTasks(ctx)
+
 	tasks := model.GetAllUnFinishTasks()
 	if len(tasks) == 0 {
 		return summary
@@ -213,22 +218,7 @@ func runMidjourneyTaskUpdateOnce(ctx context.Context, report func(processed, tot
 			if err != nil {
 				logger.LogError(ctx, "UpdateMidjourneyTask task error: "+err.Error())
 			} else if won && shouldReturnQuota {
-				err = model.IncreaseUserQuota(task.UserId, task.Quota, false)
-				if err != nil {
-					logger.LogError(ctx, "fail to increase user quota: "+err.Error())
-				}
-				model.RecordTaskBillingLog(model.RecordTaskBillingLogParams{
-					UserId:    task.UserId,
-					LogType:   model.LogTypeRefund,
-					Content:   "",
-					ChannelId: task.ChannelId,
-					ModelName: service.CovertMjpActionToModelName(task.Action),
-					Quota:     task.Quota,
-					Other: map[string]interface{}{
-						"task_id": task.MjId,
-						"reason":  "构图失败",
-					},
-				})
+				refundMidjourneyTask(ctx, task, "构图失败")
 			}
 		}
 	}
@@ -238,6 +228,70 @@ func runMidjourneyTaskUpdateOnce(ctx context.Context, report func(processed, tot
 	return summary
 }
 
+// refundMidjourneyTask 退还任务预扣的额度并记录退款日志
+func refundMidjourneyTask(ctx context.Context, task *model.Midjourney, reason string) {
+	err := model.IncreaseUserQuota(task.UserId, task.Quota, false)
+	if err != nil {
+		logger.LogError(ctx, "fail to increase user quota: "+err.Error())
+	}
+	model.RecordTaskBillingLog(model.RecordTaskBillingLogParams{
+		UserId:    task.UserId,
+		LogType:   model.LogTypeRefund,
+		Content:   "",
+		ChannelId: task.ChannelId,
+		ModelName: service.CovertMjpActionToModelName(task.Action),
+		Quota:     task.Quota,
+		Other: map[string]interface{}{
+			"task_id": task.MjId,
+			"reason":  reason,
+		},
+	})
+}
+
+// failMidjourneyTask 将未完成的任务判定为失败并退款，任务已被其他流程更新时返回 false
+func failMidjourneyTask(ctx context.Context, task *model.Midjourney, reason string) (bool, error) {
+	preStatus := task.Status
+	task.Status = "FAILURE"
+	task.Progress = "100%"
+	task.FailReason = reason
+	won, err := task.UpdateWithStatus(preStatus)
+	if err != nil || !won {
+		return false, err
+	}
+	if task.Quota != 0 {
+		refundMidjourneyTask(ctx, task, reason)
+	}
+	return true, nil
+}
+
+// reapStuckMidjourneyTasks 回收提交后长时间未完成的任务，上游丢失任务时轮询无法将其结束
+func reapStuckMidjourneyTasks(ctx context.Context) int {
+	queueSetting := operation_setting.GetMidjourneyQueueSetting()
+	if !queueSetting.Enabled || queueSetting.StuckTaskTimeoutMinutes <= 0 {
+		return 0
+	}
+	timeout := time.Duration(queueSetting.StuckTaskTimeoutMinutes) * time.Minute
+	tasks, err := model.GetStuckMidjourneyTasks(time.Now().Add(-timeout).UnixMilli())
+	if err != nil {
+		logger.LogError(ctx, fmt.Sprintf("Get stuck mj task error: %v", err))
+		return 0
+	}
+	reaped := 0
+	reason := fmt.Sprintf("任务超过 %d 分钟未完成，已自动判定失败", queueSetting.StuckTaskTimeoutMinutes)
+	for _, task := range tasks {
+		won, err := failMidjourneyTask(ctx, task, reason)
+		if err != nil {
+			logger.LogError(ctx, "UpdateMidjourneyTask task error: "+err.Error())
+			continue
+		}
+		if won {
+			reaped++
+			logger.LogInfo(ctx, fmt.Sprintf("Reap stuck mj task: %s", task.MjId))
+		}
+	}
+	return reaped
+}
+
 func checkMjTaskNeedUpdate(oldTask *model.Midjourney, newTask dto.MidjourneyDto) bool {
 	if oldTask.Code != 1 {
 		return true
@@ -342,3 +396,81 @@ func GetUserMidjourney(c *gin.Context) {
 	pageInfo.SetItems(items)
 	common.ApiSuccess(c, pageInfo)
 }
+
+// GetMidjourneyQueue 查看排队中的提交与各用户进行中的任务数
+func GetMidjourneyQueue(c *gin.Context) {
+	queued, err := service.GetMidjourneyQueue()
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	running, err := model.CountRunningMidjourneyTasks()
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"queued":  queued,
+		"running": running,
+	})
+}
+
+// GetUserMidjourneyQueue 查看当前用户排队中的提交及其位置
+func GetUserMidjourneyQueue(c *gin.Context) {
+	queued, err := service.GetMidjourneyQueue()
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	userId := c.GetInt("id")
+	items := make([]service.MidjourneyQueueEntry, 0)
+	for _, entry := range queued {
+		if entry.UserId == userId {
+			items = append(items, entry)
+		}
+	}
+	common.ApiSuccess(c, items)
+}
+
+// CancelMidjourneyQueueEntry 取消一个排队中的提交，提交尚未扣费
+func CancelMidjourneyQueueEntry(c *gin.Context) {
+	id := c.Param("id")
+	if !service.CancelMidjourneyQueueEntry(id) {
+		common.ApiErrorMsg(c, "排队任务不存在或已开始执行")
+		return
+	}
+	recordManageAudit(c, "midjourney.queue_cancel", map[string]interface{}{
+		"id": id,
+	})
+	common.ApiSuccess(c, nil)
+}
+
+// CancelMidjourney 将进行中的任务判定为失败并退款，上游任务不会被中止
+func CancelMidjourney(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	task := model.GetMjByuId(id)
+	if task == nil {
+		common.ApiErrorMsg(c, "任务不存在")
+		return
+	}
+	if task.Progress == "100%" {
+		common.ApiErrorMsg(c, "任务已结束")
+		return
+	}
+	won, err := failMidjourneyTask(c.Request.Context(), task, "管理员取消任务")
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if !won {
+		common.ApiErrorMsg(c, "任务状态已变化，请刷新后重试")
+		return
+	}
+	recordManageAuditFor(c, task.UserId, "midjourney.cancel", map[string]interface{}{
+		"id":      task.Id,
+		"mj_id":   task.MjId,
+		"user_id": task.UserId,
+		"quota":   task.Quota,
+	})
+	common.ApiSuccess(c, nil)
+}
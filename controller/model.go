@@ -205,7 +205,8 @@ func getModelListGroups(c *gin.Context) (modelListGroups, error) {
 	}, nil
 }
 
-func ListModels(c *gin.Context, modelType int) {
+// getUserModelNames 返回调用方令牌在其分组下可用的模型
+func getUserModelNames(c *gin.Context, groups modelListGroups) []string {
 	acceptUnsetRatioModel := operation_setting.SelfUseModeEnabled
 	if !acceptUnsetRatioModel {
 		userId := c.GetInt("id")
@@ -218,14 +219,6 @@ func ListModels(c *gin.Context, modelType int) {
 	}
 
 	userModelNames := make([]string, 0)
-	groups, err := getModelListGroups(c)
-	if err != nil {
-		c.JSON(http.StatusOK, gin.H{
-			"success": false,
-			"message": "get user group failed",
-		})
-		return
-	}
 	ownerGroups := groups.ownerGroups
 	modelLimitEnable := common.GetContextKeyBool(c, constant.ContextKeyTokenModelLimitEnabled)
 	if modelLimitEnable {
@@ -267,6 +260,59 @@ func ListModels(c *gin.Context, modelType int) {
 			userModelNames = append(userModelNames, modelName)
 		}
 	}
+	return userModelNames
+}
+
+// attachModelPricing 为模型列表补充调用方分组下的实际价格与渠道可用情况
+func attachModelPricing(models []dto.OpenAIModels, groups modelListGroups) error {
+	if len(groups.ownerGroups) == 0 {
+		return nil
+	}
+	availability, err := model.GetModelAvailability(groups.ownerGroups)
+	if err != nil {
+		return err
+	}
+	// auto 分组按顺序使用第一个启用了该模型的分组计费
+	billingGroups := make(map[string]string)
+	for _, group := range groups.ownerGroups {
+		for _, modelName := range model.GetGroupEnabledModels(group) {
+			if _, ok := billingGroups[modelName]; !ok {
+				billingGroups[modelName] = group
+			}
+		}
+	}
+	pricingByModel := make(map[string]model.Pricing)
+	for _, pricing := range model.GetPricing() {
+		pricingByModel[pricing.ModelName] = pricing
+	}
+	for i := range models {
+		modelAvailability := availability[models[i].Id]
+		models[i].Availability = &modelAvailability
+		pricing, ok := pricingByModel[models[i].Id]
+		if !ok {
+			continue
+		}
+		group, ok := billingGroups[models[i].Id]
+		if !ok {
+			group = groups.ownerGroups[0]
+		}
+		price := service.GetModelEffectivePrice(pricing, groups.userGroup, group)
+		models[i].Pricing = &price
+	}
+	return nil
+}
+
+func ListModels(c *gin.Context, modelType int) {
+	groups, err := getModelListGroups(c)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "get user group failed",
+		})
+		return
+	}
+	ownerGroups := groups.ownerGroups
+	userModelNames := getUserModelNames(c, groups)
 
 	ownerByModel := map[string]string{}
 	if len(ownerGroups) > 0 {
@@ -307,6 +353,13 @@ func ListModels(c *gin.Context, modelType int) {
 			"nextPageToken": nil,
 		})
 	default:
+		// include=pricing 时附带实际价格与可用情况，便于集成方构建模型选择器
+		if c.Query("include") == "pricing" {
+			if err := attachModelPricing(userOpenAiModels, groups); err != nil {
+				common.ApiError(c, err)
+				return
+			}
+		}
 		c.JSON(200, gin.H{
 			"success": true,
 			"data":    userOpenAiModels,
@@ -315,6 +368,34 @@ func ListModels(c *gin.Context, modelType int) {
 	}
 }
 
+// GetTokenModelPricing 返回调用方令牌可用的模型及其实际价格与可用情况
+func GetTokenModelPricing(c *gin.Context) {
+	groups, err := getModelListGroups(c)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	userModelNames := getUserModelNames(c, groups)
+	ownerByModel := map[string]string{}
+	if len(groups.ownerGroups) > 0 {
+		ownerByModel = getPreferredModelOwners(userModelNames, groups.ownerGroups)
+	}
+	models := make([]dto.OpenAIModels, 0, len(userModelNames))
+	for _, modelName := range userModelNames {
+		models = append(models, buildOpenAIModel(modelName, ownerByModel))
+	}
+	if err := attachModelPricing(models, groups); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"data":       models,
+		"groups":     groups.ownerGroups,
+		"user_group": groups.userGroup,
+	})
+}
+
 func ChannelListModels(c *gin.Context) {
 	c.JSON(200, gin.H{
 		"success": true,
@@ -0,0 +1,45 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func parseModerationHitQuery(c *gin.Context) model.ModerationHitQuery {
+	query := model.ModerationHitQuery{
+		Group:  c.Query("group"),
+		Source: c.Query("source"),
+		Action: c.Query("action"),
+	}
+	query.UserId, _ = strconv.Atoi(c.Query("user_id"))
+	query.StartTimestamp, _ = strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	query.EndTimestamp, _ = strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	return query
+}
+
+// GetModerationHits 分页查询内容审核命中记录，支持按用户、分组、来源、动作和时间范围过滤
+func GetModerationHits(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	hits, total, err := model.GetModerationHits(parseModerationHitQuery(c), pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(hits)
+	common.ApiSuccess(c, pageInfo)
+}
+
+// GetModerationHitStats 内容审核命中报表：按来源和动作汇总，并列出命中最多的用户
+func GetModerationHitStats(c *gin.Context) {
+	stats, err := model.GetModerationHitStats(parseModerationHitQuery(c), 10)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, stats)
+}
@@ -0,0 +1,170 @@
+package controller
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/i18n"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type notificationTemplateRequest struct {
+	Key      string `json:"key"`
+	Language string `json:"language"`
+	Subject  string `json:"subject"`
+	Content  string `json:"content"`
+	Email    string `json:"email"` // 仅测试发送使用，为空时发送到当前管理员的邮箱
+}
+
+// validateNotificationTemplate 校验模板键、语言，并用示例变量试渲染模板
+func validateNotificationTemplate(req *notificationTemplateRequest) (*service.NotificationTemplateDefinition, error) {
+	definition, ok := service.GetNotificationTemplateDefinition(req.Key)
+	if !ok {
+		return nil, errors.New("不支持的模板: " + req.Key)
+	}
+	if !slices.Contains(i18n.SupportedLanguages(), req.Language) {
+		return nil, errors.New("不支持的语言: " + req.Language)
+	}
+	req.Subject = strings.TrimSpace(req.Subject)
+	if req.Subject == "" || utf8.RuneCountInString(req.Subject) > 255 {
+		return nil, errors.New("标题长度应在 1-255 之间")
+	}
+	if strings.TrimSpace(req.Content) == "" {
+		return nil, errors.New("内容不能为空")
+	}
+	if _, err := service.RenderNotificationTemplate(req.Subject, req.Content, definition.Sample); err != nil {
+		return nil, err
+	}
+	return definition, nil
+}
+
+// GetNotificationTemplates 返回全部模板定义（变量、示例值、内置默认文本）和已自定义的模板
+func GetNotificationTemplates(c *gin.Context) {
+	templates, err := model.GetAllNotificationTemplates()
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"definitions":      service.NotificationTemplateDefinitions,
+		"common_variables": service.NotificationCommonVariables,
+		"languages":        i18n.SupportedLanguages(),
+		"templates":        templates,
+	})
+}
+
+// UpdateNotificationTemplate 保存某个模板键在某种语言下的自定义模板
+func UpdateNotificationTemplate(c *gin.Context) {
+	req := notificationTemplateRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if _, err := validateNotificationTemplate(&req); err != nil {
+		common.ApiErrorMsg(c, err.Error())
+		return
+	}
+	template := &model.NotificationTemplate{
+		TemplateKey: req.Key,
+		Language:    req.Language,
+		Subject:     req.Subject,
+		Content:     req.Content,
+	}
+	if err := model.UpsertNotificationTemplate(template); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	recordManageAudit(c, "notification_template.update", map[string]interface{}{
+		"key":      template.TemplateKey,
+		"language": template.Language,
+	})
+	common.ApiSuccess(c, template)
+}
+
+// DeleteNotificationTemplate 删除自定义模板，恢复使用内置默认模板
+func DeleteNotificationTemplate(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	template, err := model.GetNotificationTemplateById(id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := model.DeleteNotificationTemplateById(id); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	recordManageAudit(c, "notification_template.delete", map[string]interface{}{
+		"key":      template.TemplateKey,
+		"language": template.Language,
+	})
+	common.ApiSuccess(c, nil)
+}
+
+// TestNotificationTemplate 使用示例变量渲染模板并发送测试邮件。
+// 请求中带有标题和内容时测试未保存的草稿，否则测试该语言当前生效的模板
+func TestNotificationTemplate(c *gin.Context) {
+	req := notificationTemplateRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	var definition *service.NotificationTemplateDefinition
+	if req.Subject == "" && req.Content == "" {
+		var ok bool
+		definition, ok = service.GetNotificationTemplateDefinition(req.Key)
+		if !ok {
+			common.ApiErrorMsg(c, "不支持的模板: "+req.Key)
+			return
+		}
+		raw, _, err := service.ResolveNotificationTemplate(req.Key, req.Language)
+		if err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		req.Subject, req.Content = raw.Subject, raw.Content
+	} else {
+		var err error
+		if definition, err = validateNotificationTemplate(&req); err != nil {
+			common.ApiErrorMsg(c, err.Error())
+			return
+		}
+	}
+	message, err := service.RenderNotificationTemplate(req.Subject, req.Content, definition.Sample)
+	if err != nil {
+		common.ApiErrorMsg(c, err.Error())
+		return
+	}
+	email := model.NormalizeEmail(req.Email)
+	if email == "" {
+		user, err := model.GetUserById(c.GetInt("id"), false)
+		if err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		email = user.Email
+	}
+	if err := common.Validate.Var(email, "required,email"); err != nil {
+		common.ApiErrorMsg(c, "请先绑定邮箱或填写有效的收件邮箱")
+		return
+	}
+	if err := common.SendEmail(message.Subject, email, message.Content); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"email":   email,
+		"subject": message.Subject,
+		"content": message.Content,
+	})
+}
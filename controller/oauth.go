@@ -239,13 +239,29 @@ func findOrCreateOAuthUser(c *gin.Context, provider oauth.Provider, oauthUser *o
 		}
 	}
 
+	// Link to an existing account when the provider allows it (e.g. OIDC by verified email)
+	provisioning, _ := provider.(oauth.ProvisioningProvider)
+	if provisioning != nil {
+		if existing := provisioning.LinkableUser(oauthUser); existing != nil {
+			provider.SetProviderUserID(existing, oauthUser.ProviderUserID)
+			if err := existing.Update(false); err != nil {
+				return nil, err
+			}
+			common.SysLog(fmt.Sprintf("[OAuth] Linked %s account %s to existing user %d", provider.GetName(), oauthUser.ProviderUserID, existing.Id))
+			return existing, nil
+		}
+	}
+
 	// User doesn't exist, create new user if registration is enabled
-	if !common.RegisterEnabled {
+	if !common.RegisterEnabled && (provisioning == nil || !provisioning.AutoProvisionEnabled()) {
 		return nil, &OAuthRegistrationDisabledError{}
 	}
 
 	// Set up new user
 	user.Username = provider.GetProviderPrefix() + strconv.Itoa(model.GetMaxUserId()+1)
+	if provisioning != nil {
+		user.Group = provisioning.DefaultGroup()
+	}
 
 	if oauthUser.Username != "" {
 		if exists, err := model.CheckUserExistOrDeleted(oauthUser.Username, ""); err == nil && !exists {
@@ -3,8 +3,11 @@ package controller
 import (
 	"fmt"
 	"net/http"
+	"net/url"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/i18n"
@@ -24,6 +27,7 @@ var completionRatioMetaOptionKeys = []string{
 	"CompletionRatio",
 	"CacheRatio",
 	"CreateCacheRatio",
+	"CreateCache1hRatio",
 	"ImageRatio",
 	"AudioRatio",
 	"AudioCompletionRatio",
@@ -150,6 +154,374 @@ func UpdateOption(c *gin.Context) {
 		}
 	}
 	switch option.Key {
+	case "checkin_setting.min_quota", "checkin_setting.max_quota", "checkin_setting.makeup_cost",
+		"checkin_setting.makeup_days", "checkin_setting.makeup_monthly_limit", "checkin_setting.expire_days",
+		"checkin_setting.max_accounts_per_ip", "checkin_setting.reminder_batch_size",
+		"checkin_setting.required_requests", "checkin_setting.required_tokens",
+		"checkin_setting.referral_min_invitees", "checkin_setting.inviter_kickback_quota":
+		value, err := strconv.Atoi(strings.TrimSpace(option.Value.(string)))
+		if err != nil || value < 0 {
+			common.ApiErrorMsg(c, "签到配置必须为非负整数")
+			return
+		}
+	case "checkin_setting.total_milestones":
+		var milestones []operation_setting.CheckinTotalMilestone
+		if err := common.UnmarshalJsonStr(option.Value.(string), &milestones); err != nil {
+			common.ApiErrorMsg(c, "累计签到奖励配置格式错误: "+err.Error())
+			return
+		}
+	case "checkin_setting.group_rewards":
+		var rewards map[string]operation_setting.CheckinGroupReward
+		if err := common.UnmarshalJsonStr(option.Value.(string), &rewards); err != nil {
+			common.ApiErrorMsg(c, "分组签到奖励配置格式错误: "+err.Error())
+			return
+		}
+	case "checkin_setting.ip_limit_action":
+		if option.Value != operation_setting.CheckinIpLimitActionDeny && option.Value != operation_setting.CheckinIpLimitActionFlag {
+			common.ApiErrorMsg(c, "签到 IP 超限处理方式只能是 deny 或 flag")
+			return
+		}
+	case "usage_cap_setting.timezone":
+		if _, err := time.LoadLocation(strings.TrimSpace(option.Value.(string))); err != nil {
+			common.ApiErrorMsg(c, "无效的时区: "+err.Error())
+			return
+		}
+	case "usage_cap_setting.rules":
+		var rules []operation_setting.UsageCapRule
+		if err := common.UnmarshalJsonStr(option.Value.(string), &rules); err != nil {
+			common.ApiErrorMsg(c, "用量上限规则格式错误: "+err.Error())
+			return
+		}
+		if err := operation_setting.ValidateUsageCapRules(rules); err != nil {
+			common.ApiError(c, err)
+			return
+		}
+	case "checkin_setting.timezone":
+		if _, err := time.LoadLocation(strings.TrimSpace(option.Value.(string))); err != nil {
+			common.ApiErrorMsg(c, "无效的时区: "+err.Error())
+			return
+		}
+	case "checkin_setting.streak_milestones":
+		var milestones []operation_setting.CheckinStreakMilestone
+		if err := common.UnmarshalJsonStr(option.Value.(string), &milestones); err != nil {
+			common.ApiErrorMsg(c, "连签奖励配置格式错误: "+err.Error())
+			return
+		}
+	case "checkin_setting.prizes":
+		var prizes []operation_setting.CheckinPrize
+		if err := common.UnmarshalJsonStr(option.Value.(string), &prizes); err != nil {
+			common.ApiErrorMsg(c, "签到奖品配置格式错误: "+err.Error())
+			return
+		}
+		prizeIds := make(map[string]bool, len(prizes))
+		for _, prize := range prizes {
+			if prize.Id == "" || len(prize.Id) > 64 || prizeIds[prize.Id] {
+				common.ApiErrorMsg(c, "签到奖品 id 不能为空、不能重复且不超过 64 个字符")
+				return
+			}
+			prizeIds[prize.Id] = true
+			switch prize.Type {
+			case operation_setting.CheckinPrizeTypeQuota, operation_setting.CheckinPrizeTypeRedemption, operation_setting.CheckinPrizeTypeNone:
+			default:
+				common.ApiErrorMsg(c, "签到奖品类型只能是 quota、redemption 或 none")
+				return
+			}
+			if prize.Quota < 0 || prize.Weight < 0 || prize.DailyStock < 0 {
+				common.ApiErrorMsg(c, "签到奖品的额度、权重和库存不能为负数")
+				return
+			}
+		}
+	case "checkin_setting.promotions":
+		var promotions []operation_setting.CheckinPromotion
+		if err := common.UnmarshalJsonStr(option.Value.(string), &promotions); err != nil {
+			common.ApiErrorMsg(c, "签到促销活动配置格式错误: "+err.Error())
+			return
+		}
+		if err := operation_setting.ValidateCheckinPromotions(promotions); err != nil {
+			common.ApiError(c, err)
+			return
+		}
+	case "checkin_setting.reminder_hour":
+		value, err := strconv.Atoi(strings.TrimSpace(option.Value.(string)))
+		if err != nil || value < 0 || value > 23 {
+			common.ApiErrorMsg(c, "签到提醒时间必须为 0-23 之间的整点")
+			return
+		}
+	case "monitor_setting.failure_threshold", "monitor_setting.health_retention_days":
+		value, err := strconv.Atoi(strings.TrimSpace(option.Value.(string)))
+		if err != nil || value < 0 {
+			common.ApiErrorMsg(c, "通道监控配置必须为非负整数")
+			return
+		}
+	case "general_setting.currency_decimal_places":
+		value, err := strconv.Atoi(strings.TrimSpace(option.Value.(string)))
+		if err != nil || value < 0 || value > operation_setting.MaxCurrencyDecimalPlaces {
+			common.ApiErrorMsg(c, fmt.Sprintf("货币小数位数必须为 0-%d 之间的整数", operation_setting.MaxCurrencyDecimalPlaces))
+			return
+		}
+	case "general_setting.custom_currency_exchange_rate":
+		value, err := strconv.ParseFloat(strings.TrimSpace(option.Value.(string)), 64)
+		if err != nil || value <= 0 {
+			common.ApiErrorMsg(c, "自定义货币汇率必须为正数")
+			return
+		}
+	case "audit_setting.retention_days":
+		value, err := strconv.Atoi(strings.TrimSpace(option.Value.(string)))
+		if err != nil || value < 0 {
+			common.ApiErrorMsg(c, "审计日志保留天数必须为非负整数")
+			return
+		}
+	case "log_retention_setting.consume_retention_days", "log_retention_setting.error_retention_days":
+		value, err := strconv.Atoi(strings.TrimSpace(option.Value.(string)))
+		if err != nil || value < 0 {
+			common.ApiErrorMsg(c, "日志保留天数必须为非负整数")
+			return
+		}
+	case "body_log_setting.retention_days", "body_log_setting.max_body_bytes":
+		value, err := strconv.Atoi(strings.TrimSpace(option.Value.(string)))
+		if err != nil || value < 0 {
+			common.ApiErrorMsg(c, "请求体记录配置必须为非负整数")
+			return
+		}
+	case "body_log_setting.mask_patterns":
+		var patterns []string
+		if err := common.UnmarshalJsonStr(option.Value.(string), &patterns); err != nil {
+			common.ApiErrorMsg(c, "脱敏规则必须为正则表达式字符串数组")
+			return
+		}
+		for _, pattern := range patterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				common.ApiErrorMsg(c, fmt.Sprintf("脱敏规则 %s 不是合法的正则表达式: %s", pattern, err.Error()))
+				return
+			}
+		}
+	case "embedding_cache_setting.ttl_seconds", "embedding_cache_setting.max_entries", "embedding_cache_setting.max_entry_kb":
+		value, err := strconv.Atoi(strings.TrimSpace(option.Value.(string)))
+		if err != nil || value <= 0 {
+			common.ApiErrorMsg(c, "嵌入缓存配置必须为正整数")
+			return
+		}
+	case "embedding_cache_setting.hit_price_ratio":
+		value, err := strconv.ParseFloat(strings.TrimSpace(option.Value.(string)), 64)
+		if err != nil || value < 0 || value > 1 {
+			common.ApiErrorMsg(c, "嵌入缓存命中计费倍率应在 0-1 之间")
+			return
+		}
+	case "moderation_setting.action":
+		if !operation_setting.IsValidModerationAction(strings.TrimSpace(option.Value.(string))) {
+			common.ApiErrorMsg(c, "内容审核动作必须为 off、block、log 或 redact")
+			return
+		}
+	case "moderation_setting.group_actions":
+		var actions map[string]string
+		if err := common.UnmarshalJsonStr(option.Value.(string), &actions); err != nil {
+			common.ApiErrorMsg(c, "分组审核动作格式错误: "+err.Error())
+			return
+		}
+		for group, action := range actions {
+			if !operation_setting.IsValidModerationAction(action) {
+				common.ApiErrorMsg(c, "分组 "+group+" 的审核动作必须为 off、block、log 或 redact")
+				return
+			}
+		}
+	case "moderation_setting.regex_rules":
+		var rules []string
+		if err := common.UnmarshalJsonStr(option.Value.(string), &rules); err != nil {
+			common.ApiErrorMsg(c, "正则规则格式错误: "+err.Error())
+			return
+		}
+		if err := operation_setting.ValidateModerationRegexRules(rules); err != nil {
+			common.ApiError(c, err)
+			return
+		}
+	case "moderation_setting.model_timeout_seconds", "moderation_setting.hit_retention_days":
+		value, err := strconv.Atoi(strings.TrimSpace(option.Value.(string)))
+		if err != nil || value < 0 {
+			common.ApiErrorMsg(c, "内容审核配置必须为非负整数")
+			return
+		}
+	case "account_deletion_setting.cool_off_days":
+		value, err := strconv.Atoi(strings.TrimSpace(option.Value.(string)))
+		if err != nil || value < 0 || value > operation_setting.MaxAccountDeletionCoolOffDays {
+			common.ApiErrorMsg(c, fmt.Sprintf("注销冷静期天数应在 0-%d 之间", operation_setting.MaxAccountDeletionCoolOffDays))
+			return
+		}
+	case "quota_transfer_setting.min_amount", "quota_transfer_setting.max_amount",
+		"quota_transfer_setting.daily_limit", "quota_transfer_setting.daily_count":
+		value, err := strconv.Atoi(strings.TrimSpace(option.Value.(string)))
+		if err != nil || value < 0 {
+			common.ApiErrorMsg(c, "转账额度限制必须为非负整数")
+			return
+		}
+	case "quota_transfer_setting.fee_percent":
+		value, err := strconv.ParseFloat(strings.TrimSpace(option.Value.(string)), 64)
+		if err != nil || value < 0 || value >= 100 {
+			common.ApiErrorMsg(c, "转账手续费百分比应在 0-100 之间")
+			return
+		}
+	case "chat_cache_setting.ttl_seconds", "chat_cache_setting.max_entries", "chat_cache_setting.max_entry_kb":
+		value, err := strconv.Atoi(strings.TrimSpace(option.Value.(string)))
+		if err != nil || value <= 0 {
+			common.ApiErrorMsg(c, "对话缓存配置必须为正整数")
+			return
+		}
+	case "chat_cache_setting.hit_price_ratio":
+		value, err := strconv.ParseFloat(strings.TrimSpace(option.Value.(string)), 64)
+		if err != nil || value < 0 || value > 1 {
+			common.ApiErrorMsg(c, "对话缓存命中计费倍率应在 0-1 之间")
+			return
+		}
+	case "channel_tag_routing_setting.groups":
+		var groups map[string]operation_setting.ChannelTagRoutingRule
+		if err := common.UnmarshalJsonStr(option.Value.(string), &groups); err != nil {
+			common.ApiErrorMsg(c, "渠道标签路由配置格式错误: "+err.Error())
+			return
+		}
+	case "token_setting.rotation_grace_seconds":
+		value, err := strconv.Atoi(strings.TrimSpace(option.Value.(string)))
+		if err != nil || value < 0 || value > operation_setting.MaxTokenRotationGraceSeconds {
+			common.ApiErrorMsg(c, fmt.Sprintf("令牌轮换宽限期应在 0-%d 秒之间", operation_setting.MaxTokenRotationGraceSeconds))
+			return
+		}
+	case "concurrency_setting.default_user_limit", "concurrency_setting.queue_timeout_seconds":
+		value, err := strconv.Atoi(strings.TrimSpace(option.Value.(string)))
+		if err != nil || value < 0 {
+			common.ApiErrorMsg(c, "并发限制配置必须为非负整数")
+			return
+		}
+	case "midjourney_queue_setting.max_user_running_tasks", "midjourney_queue_setting.max_running_tasks",
+		"midjourney_queue_setting.max_user_queued_tasks", "midjourney_queue_setting.queue_timeout_seconds",
+		"midjourney_queue_setting.stuck_task_timeout_minutes":
+		value, err := strconv.Atoi(strings.TrimSpace(option.Value.(string)))
+		if err != nil || value < 0 {
+			common.ApiErrorMsg(c, "Midjourney 排队配置必须为非负整数")
+			return
+		}
+	case "concurrency_setting.mode":
+		mode := strings.TrimSpace(option.Value.(string))
+		if mode != operation_setting.ConcurrencyModeReject && mode != operation_setting.ConcurrencyModeQueue {
+			common.ApiErrorMsg(c, "并发限制模式只能为 reject 或 queue")
+			return
+		}
+	case "concurrency_setting.group_limits":
+		var limits map[string]int
+		if err := common.UnmarshalJsonStr(option.Value.(string), &limits); err != nil {
+			common.ApiErrorMsg(c, "分组并发上限格式错误: "+err.Error())
+			return
+		}
+		for group, limit := range limits {
+			if limit < 0 {
+				common.ApiErrorMsg(c, "分组 "+group+" 的并发上限不能为负数")
+				return
+			}
+		}
+	case "channel_queue_setting.max_wait_seconds", "channel_queue_setting.max_queue_length":
+		value, err := strconv.Atoi(strings.TrimSpace(option.Value.(string)))
+		if err != nil || value < 0 {
+			common.ApiErrorMsg(c, "渠道排队配置必须为非负整数")
+			return
+		}
+	case "channel_queue_setting.group_priorities", "channel_queue_setting.group_max_wait_seconds":
+		var values map[string]int
+		if err := common.UnmarshalJsonStr(option.Value.(string), &values); err != nil {
+			common.ApiErrorMsg(c, "分组排队配置格式错误: "+err.Error())
+			return
+		}
+		if option.Key == "channel_queue_setting.group_max_wait_seconds" {
+			for group, seconds := range values {
+				if seconds < 0 {
+					common.ApiErrorMsg(c, "分组 "+group+" 的最长排队时间不能为负数")
+					return
+				}
+			}
+		}
+	case "channel_select_setting.default_strategy":
+		if !operation_setting.IsValidChannelSelectStrategy(strings.TrimSpace(option.Value.(string))) {
+			common.ApiErrorMsg(c, "渠道选择策略只能为 weighted、random 或 least_latency")
+			return
+		}
+	case "channel_select_setting.rules":
+		var rules []operation_setting.ChannelSelectRule
+		if err := common.UnmarshalJsonStr(option.Value.(string), &rules); err != nil {
+			common.ApiErrorMsg(c, "渠道选择规则格式错误: "+err.Error())
+			return
+		}
+		for _, rule := range rules {
+			if !operation_setting.IsValidChannelSelectStrategy(rule.Strategy) {
+				common.ApiErrorMsg(c, "不支持的渠道选择策略: "+rule.Strategy)
+				return
+			}
+		}
+	case "channel_select_setting.explore_percent":
+		value, err := strconv.Atoi(strings.TrimSpace(option.Value.(string)))
+		if err != nil || value < 0 || value > 100 {
+			common.ApiErrorMsg(c, "探索比例应在 0-100 之间")
+			return
+		}
+	case "channel_breaker_setting.window_seconds", "channel_breaker_setting.min_requests", "channel_breaker_setting.open_seconds":
+		value, err := strconv.Atoi(strings.TrimSpace(option.Value.(string)))
+		if err != nil || value < 1 {
+			common.ApiErrorMsg(c, "熔断统计窗口、最小请求数和熔断时长必须为正整数")
+			return
+		}
+	case "realtime_setting.max_sessions_per_user", "realtime_setting.idle_timeout_seconds":
+		value, err := strconv.Atoi(strings.TrimSpace(option.Value.(string)))
+		if err != nil || value < 0 {
+			common.ApiErrorMsg(c, "Realtime 会话上限和空闲超时必须为非负整数")
+			return
+		}
+	case "batch_setting.discount_percent":
+		value, err := strconv.Atoi(strings.TrimSpace(option.Value.(string)))
+		if err != nil || value < 0 || value > 100 {
+			common.ApiErrorMsg(c, "Batch 计费折扣应在 0-100 之间")
+			return
+		}
+	case "batch_setting.poll_interval_seconds":
+		value, err := strconv.Atoi(strings.TrimSpace(option.Value.(string)))
+		if err != nil || value < 1 {
+			common.ApiErrorMsg(c, "Batch 轮询间隔必须为正整数")
+			return
+		}
+	case "channel_breaker_setting.error_rate_percent":
+		value, err := strconv.Atoi(strings.TrimSpace(option.Value.(string)))
+		if err != nil || value < 1 || value > 100 {
+			common.ApiErrorMsg(c, "熔断错误率应在 1-100 之间")
+			return
+		}
+	case "group_upgrade_setting.rules":
+		var rules []operation_setting.GroupUpgradeRule
+		if err := common.UnmarshalJsonStr(option.Value.(string), &rules); err != nil {
+			common.ApiErrorMsg(c, "分组升级规则格式错误: "+err.Error())
+			return
+		}
+		if err := validateGroupUpgradeRules(rules); err != nil {
+			common.ApiErrorMsg(c, err.Error())
+			return
+		}
+	case "group_upgrade_setting.base_group":
+		if group := strings.TrimSpace(option.Value.(string)); group != "" && !ratio_setting.ContainsGroupRatio(group) {
+			common.ApiErrorMsg(c, "分组 "+group+" 不存在")
+			return
+		}
+	case "checkin_setting.referral_multiplier":
+		value, err := strconv.ParseFloat(strings.TrimSpace(option.Value.(string)), 64)
+		if err != nil || value < 0 || value > 100 {
+			common.ApiErrorMsg(c, "邀请签到加成倍率应在 0-100 之间")
+			return
+		}
+	case "checkin_setting.webhook_url":
+		if value := strings.TrimSpace(option.Value.(string)); value != "" {
+			parsed, err := url.Parse(value)
+			if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+				common.ApiErrorMsg(c, "签到事件推送地址必须是有效的 http(s) URL")
+				return
+			}
+		}
+	case "checkin_setting.require_captcha":
+		if option.Value == "true" && !common.TurnstileCheckEnabled {
+			common.ApiErrorMsg(c, "无法启用签到人机验证，请先启用并配置 Turnstile！")
+			return
+		}
 	case "GitHubOAuthEnabled":
 		if option.Value == "true" && common.GitHubClientId == "" {
 			c.JSON(http.StatusOK, gin.H{
@@ -174,6 +546,11 @@ func UpdateOption(c *gin.Context) {
 			})
 			return
 		}
+	case "oidc.default_group":
+		if group := strings.TrimSpace(option.Value.(string)); group != "" && !ratio_setting.ContainsGroupRatio(group) {
+			common.ApiErrorMsg(c, "分组 "+group+" 不存在")
+			return
+		}
 	case "LinuxDOOAuthEnabled":
 		if option.Value == "true" && common.LinuxDOClientId == "" {
 			c.JSON(http.StatusOK, gin.H{
@@ -259,6 +636,24 @@ func UpdateOption(c *gin.Context) {
 			})
 			return
 		}
+	case "AudioSecondPrice":
+		err = ratio_setting.UpdateAudioSecondPriceByJSONString(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "音频按秒价格设置失败: " + err.Error(),
+			})
+			return
+		}
+	case "AudioCharacterPrice":
+		err = ratio_setting.UpdateAudioCharacterPriceByJSONString(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "语音合成按字符价格设置失败: " + err.Error(),
+			})
+			return
+		}
 	case "CreateCacheRatio":
 		err = ratio_setting.UpdateCreateCacheRatioByJSONString(option.Value.(string))
 		if err != nil {
@@ -268,6 +663,15 @@ func UpdateOption(c *gin.Context) {
 			})
 			return
 		}
+	case "CreateCache1hRatio":
+		err = ratio_setting.UpdateCreateCache1hRatioByJSONString(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "1小时缓存创建倍率设置失败: " + err.Error(),
+			})
+			return
+		}
 	case "ModelRequestRateLimitGroup":
 		err = setting.CheckModelRequestRateLimitGroup(option.Value.(string))
 		if err != nil {
@@ -332,17 +736,33 @@ func UpdateOption(c *gin.Context) {
 			return
 		}
 	}
+	common.OptionMapRWMutex.RLock()
+	oldValue := common.OptionMap[option.Key]
+	common.OptionMapRWMutex.RUnlock()
 	err = model.UpdateOption(option.Key, option.Value.(string))
 	if err != nil {
 		common.ApiError(c, err)
 		return
 	}
-	// 出于安全考虑只记录被修改的配置项名称，不记录配置值（可能含密钥等敏感信息）。
-	recordManageAudit(c, "option.update", map[string]interface{}{
+	// 出于安全考虑操作日志只记录被修改的配置项名称；审计表记录前后值，密钥类配置只记录是否变更。
+	recordManageAuditChanges(c, c.GetInt("id"), "option.update", map[string]interface{}{
 		"key": option.Key,
-	})
+	}, model.BuildAuditChanges(
+		map[string]interface{}{option.Key: oldValue},
+		map[string]interface{}{option.Key: option.Value},
+	))
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
 	})
 }
+
+// ReloadOptions 从数据库重新加载本节点配置，并通知其他节点同步重新加载
+func ReloadOptions(c *gin.Context) {
+	if err := model.ReloadOptions(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	model.BroadcastOptionsReload()
+	common.ApiSuccess(c, nil)
+}
@@ -17,7 +17,7 @@ func isStripeTopUpEnabled() bool {
 	}
 	return strings.TrimSpace(setting.StripeApiSecret) != "" &&
 		strings.TrimSpace(setting.StripeWebhookSecret) != "" &&
-		strings.TrimSpace(setting.StripePriceId) != ""
+		(strings.TrimSpace(setting.StripePriceId) != "" || strings.TrimSpace(setting.StripeCurrency) != "")
 }
 
 func isStripeWebhookConfigured() bool {
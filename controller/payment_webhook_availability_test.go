@@ -26,10 +26,12 @@ func TestStripeWebhookEnabledRequiresTopUpAndWebhookConfig(t *testing.T) {
 	originalAPISecret := setting.StripeApiSecret
 	originalWebhookSecret := setting.StripeWebhookSecret
 	originalPriceID := setting.StripePriceId
+	originalCurrency := setting.StripeCurrency
 	t.Cleanup(func() {
 		setting.StripeApiSecret = originalAPISecret
 		setting.StripeWebhookSecret = originalWebhookSecret
 		setting.StripePriceId = originalPriceID
+		setting.StripeCurrency = originalCurrency
 	})
 
 	setting.StripeWebhookSecret = ""
@@ -41,6 +43,10 @@ func TestStripeWebhookEnabledRequiresTopUpAndWebhookConfig(t *testing.T) {
 	require.True(t, isStripeWebhookEnabled())
 
 	setting.StripePriceId = ""
+	setting.StripeCurrency = "usd"
+	require.True(t, isStripeWebhookEnabled())
+
+	setting.StripeCurrency = ""
 	require.False(t, isStripeWebhookEnabled())
 }
 
@@ -13,6 +13,8 @@ import (
 
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
 	"github.com/gin-gonic/gin"
 )
 
@@ -28,6 +30,10 @@ type PerformanceStats struct {
 	DiskSpaceInfo common.DiskSpaceInfo `json:"disk_space_info"`
 	// 配置信息
 	Config PerformanceConfig `json:"config"`
+	// 令牌/用户本地缓存命中统计
+	AuthCacheStats model.AuthCacheStats `json:"auth_cache_stats"`
+	// 渠道并发与排队统计（本节点）
+	ChannelQueueStats service.ChannelQueueStats `json:"channel_queue_stats"`
 }
 
 // MemoryStats 内存统计
@@ -128,9 +134,11 @@ func GetPerformanceStats(c *gin.Context) {
 			NumGC:        memStats.NumGC,
 			NumGoroutine: runtime.NumGoroutine(),
 		},
-		DiskCacheInfo: diskCacheInfo,
-		DiskSpaceInfo: diskSpaceInfo,
-		Config:        config,
+		DiskCacheInfo:     diskCacheInfo,
+		DiskSpaceInfo:     diskSpaceInfo,
+		Config:            config,
+		AuthCacheStats:    model.GetAuthCacheStats(),
+		ChannelQueueStats: service.GetChannelQueueStats(),
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -158,6 +166,8 @@ func ClearDiskCache(c *gin.Context) {
 // ResetPerformanceStats 重置性能统计
 func ResetPerformanceStats(c *gin.Context) {
 	common.ResetDiskCacheStats()
+	model.ResetAuthCacheStats()
+	service.ResetChannelQueueStats()
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
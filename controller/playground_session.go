@@ -0,0 +1,343 @@
+package controller
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+type playgroundSessionRequest struct {
+	Title        string `json:"title"`
+	Model        string `json:"model"`
+	TokenId      int    `json:"token_id"`
+	SystemPrompt string `json:"system_prompt"`
+}
+
+type playgroundMessageRequest struct {
+	Content string `json:"content"`
+}
+
+// validatePlaygroundSessionRequest 校验会话参数，指定的令牌必须属于当前用户
+func validatePlaygroundSessionRequest(req *playgroundSessionRequest, userId int) string {
+	req.Title = strings.TrimSpace(req.Title)
+	req.Model = strings.TrimSpace(req.Model)
+	if req.Model == "" {
+		return "请选择模型"
+	}
+	if req.Title == "" {
+		req.Title = req.Model
+	}
+	if utf8.RuneCountInString(req.Title) > 128 {
+		return "会话标题不能超过 128 个字符"
+	}
+	if req.TokenId != 0 {
+		if _, err := model.GetTokenByIds(req.TokenId, userId); err != nil {
+			return "令牌不存在"
+		}
+	}
+	return ""
+}
+
+// resolvePlaygroundToken 会话指定了令牌时使用该令牌，否则使用用户的默认令牌
+func resolvePlaygroundToken(session *model.PlaygroundSession) (*model.Token, error) {
+	if session.TokenId != 0 {
+		return model.GetTokenByIds(session.TokenId, session.UserId)
+	}
+	return model.GetUserDefaultToken(session.UserId)
+}
+
+func getPlaygroundSessionParam(c *gin.Context) (*model.PlaygroundSession, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiErrorMsg(c, "参数错误")
+		return nil, false
+	}
+	session, err := model.GetUserPlaygroundSession(id, c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return nil, false
+	}
+	return session, true
+}
+
+func GetPlaygroundSessions(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	sessions, total, err := model.GetUserPlaygroundSessions(c.GetInt("id"), pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(sessions)
+	common.ApiSuccess(c, pageInfo)
+}
+
+func CreatePlaygroundSession(c *gin.Context) {
+	var req playgroundSessionRequest
+	if err := common.DecodeJson(c.Request.Body, &req); err != nil {
+		common.ApiErrorMsg(c, "参数错误")
+		return
+	}
+	userId := c.GetInt("id")
+	if msg := validatePlaygroundSessionRequest(&req, userId); msg != "" {
+		common.ApiErrorMsg(c, msg)
+		return
+	}
+	session := &model.PlaygroundSession{
+		UserId:       userId,
+		Title:        req.Title,
+		Model:        req.Model,
+		TokenId:      req.TokenId,
+		SystemPrompt: req.SystemPrompt,
+	}
+	if err := model.CreatePlaygroundSession(session); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, session)
+}
+
+// GetPlaygroundSession 获取会话及其全部消息，并汇总会话累计的 token 用量和估算额度
+func GetPlaygroundSession(c *gin.Context) {
+	session, ok := getPlaygroundSessionParam(c)
+	if !ok {
+		return
+	}
+	messages, err := model.GetPlaygroundMessages(session.Id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	var promptTokens, completionTokens, quota int
+	for _, message := range messages {
+		promptTokens += message.PromptTokens
+		completionTokens += message.CompletionTokens
+		quota += message.Quota
+	}
+	common.ApiSuccess(c, gin.H{
+		"session":           session,
+		"messages":          messages,
+		"prompt_tokens":     promptTokens,
+		"completion_tokens": completionTokens,
+		"quota":             quota,
+	})
+}
+
+func UpdatePlaygroundSession(c *gin.Context) {
+	session, ok := getPlaygroundSessionParam(c)
+	if !ok {
+		return
+	}
+	var req playgroundSessionRequest
+	if err := common.DecodeJson(c.Request.Body, &req); err != nil {
+		common.ApiErrorMsg(c, "参数错误")
+		return
+	}
+	if msg := validatePlaygroundSessionRequest(&req, session.UserId); msg != "" {
+		common.ApiErrorMsg(c, msg)
+		return
+	}
+	session.Title = req.Title
+	session.Model = req.Model
+	session.TokenId = req.TokenId
+	session.SystemPrompt = req.SystemPrompt
+	if err := model.UpdatePlaygroundSession(session); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, session)
+}
+
+func DeletePlaygroundSession(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiErrorMsg(c, "参数错误")
+		return
+	}
+	if err := model.DeletePlaygroundSession(id, c.GetInt("id")); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
+
+// ExportPlaygroundSession 以 OpenAI 格式的 messages 数组导出会话
+func ExportPlaygroundSession(c *gin.Context) {
+	session, ok := getPlaygroundSessionParam(c)
+	if !ok {
+		return
+	}
+	history, err := model.GetPlaygroundMessages(session.Id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"model":    session.Model,
+		"messages": service.BuildPlaygroundMessages(session, history),
+	})
+}
+
+// PreviewPlaygroundMessage 发送前预估本轮的输入 token 数和费用（不含输出部分）
+func PreviewPlaygroundMessage(c *gin.Context) {
+	session, ok := getPlaygroundSessionParam(c)
+	if !ok {
+		return
+	}
+	var req playgroundMessageRequest
+	if err := common.DecodeJson(c.Request.Body, &req); err != nil {
+		common.ApiErrorMsg(c, "参数错误")
+		return
+	}
+	token, err := resolvePlaygroundToken(session)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	history, err := model.GetPlaygroundMessages(session.Id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	messages := service.BuildPlaygroundMessages(session, history)
+	messages = append(messages, dto.Message{Role: "user", Content: req.Content})
+	promptTokens := service.CountPlaygroundPromptTokens(messages, session.Model)
+	userGroup, err := model.GetUserGroup(session.UserId, false)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	usingGroup := common.GetStringIfEmpty(token.Group, userGroup)
+	common.ApiSuccess(c, gin.H{
+		"token_id":      token.Id,
+		"token_name":    token.Name,
+		"prompt_tokens": promptTokens,
+		"quota":         service.EstimatePlaygroundQuota(session.Model, userGroup, usingGroup, promptTokens, 0),
+	})
+}
+
+// PreparePlaygroundSessionChat 将会话历史和新消息组装为流式 chat completions 请求，
+// 并改写为使用会话令牌请求 /v1/chat/completions，后续由令牌鉴权、限流和分发中间件按正常 API 请求处理，
+// 消耗计入该令牌
+func PreparePlaygroundSessionChat(c *gin.Context) {
+	if c.GetBool("use_access_token") {
+		common.ApiErrorMsg(c, "暂不支持使用 access token")
+		c.Abort()
+		return
+	}
+	session, ok := getPlaygroundSessionParam(c)
+	if !ok {
+		c.Abort()
+		return
+	}
+	var req playgroundMessageRequest
+	if err := common.DecodeJson(c.Request.Body, &req); err != nil || strings.TrimSpace(req.Content) == "" {
+		common.ApiErrorMsg(c, "消息内容不能为空")
+		c.Abort()
+		return
+	}
+	token, err := resolvePlaygroundToken(session)
+	if err != nil {
+		common.ApiError(c, err)
+		c.Abort()
+		return
+	}
+	history, err := model.GetPlaygroundMessages(session.Id)
+	if err != nil {
+		common.ApiError(c, err)
+		c.Abort()
+		return
+	}
+	messages := service.BuildPlaygroundMessages(session, history)
+	messages = append(messages, dto.Message{Role: "user", Content: req.Content})
+	body, err := common.Marshal(gin.H{
+		"model":          session.Model,
+		"messages":       messages,
+		"stream":         true,
+		"stream_options": gin.H{"include_usage": true},
+	})
+	if err != nil {
+		common.ApiError(c, err)
+		c.Abort()
+		return
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	c.Request.ContentLength = int64(len(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Request.Header.Set("Authorization", "Bearer sk-"+token.Key)
+	c.Request.URL.Path = "/v1/chat/completions"
+	c.Set("playground_session", session)
+	c.Set("playground_messages", messages)
+	c.Next()
+}
+
+// PlaygroundSessionChat 流式转发会话消息，结束后保存本轮消息，
+// 并追加 playground_usage 事件返回本轮的 token 用量和估算额度
+func PlaygroundSessionChat(c *gin.Context) {
+	session := c.MustGet("playground_session").(*model.PlaygroundSession)
+	messages := c.MustGet("playground_messages").([]dto.Message)
+
+	writer := service.NewResponseCaptureWriter(c.Writer, 0)
+	c.Writer = writer
+	Relay(c, types.RelayFormatOpenAI)
+	if c.Writer.Status() >= http.StatusBadRequest {
+		return
+	}
+	captured, _ := writer.Body()
+	reply, usage := service.ParsePlaygroundStream(captured)
+	if reply == "" {
+		return
+	}
+	var promptTokens, completionTokens int
+	if usage != nil {
+		promptTokens, completionTokens = usage.PromptTokens, usage.CompletionTokens
+	} else {
+		promptTokens = service.CountPlaygroundPromptTokens(messages, session.Model)
+		completionTokens = service.CountTextToken(reply, session.Model)
+	}
+	quota := service.EstimatePlaygroundQuota(session.Model,
+		common.GetContextKeyString(c, constant.ContextKeyUserGroup),
+		common.GetContextKeyString(c, constant.ContextKeyUsingGroup),
+		promptTokens, completionTokens)
+	userMessage := &model.PlaygroundMessage{
+		Role:         "user",
+		Content:      messages[len(messages)-1].StringContent(),
+		Model:        session.Model,
+		PromptTokens: promptTokens,
+	}
+	assistantMessage := &model.PlaygroundMessage{
+		Role:             "assistant",
+		Content:          reply,
+		Model:            session.Model,
+		CompletionTokens: completionTokens,
+		Quota:            quota,
+	}
+	if err := model.AddPlaygroundMessages(session.Id, userMessage, assistantMessage); err != nil {
+		common.SysError("failed to save playground messages: " + err.Error())
+		return
+	}
+	data, err := common.Marshal(gin.H{
+		"session_id":        session.Id,
+		"message_id":        assistantMessage.Id,
+		"prompt_tokens":     promptTokens,
+		"completion_tokens": completionTokens,
+		"quota":             quota,
+	})
+	if err != nil {
+		return
+	}
+	_, _ = c.Writer.WriteString("event: playground_usage\ndata: " + string(data) + "\n\n")
+	c.Writer.Flush()
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/model"
 	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
 	"github.com/QuantumNous/new-api/setting/ratio_setting"
 
 	"github.com/gin-gonic/gin"
@@ -73,6 +74,14 @@ func GetPricing(c *gin.Context) {
 		"supported_endpoint": model.GetSupportedEndpointMap(),
 		"auto_groups":        service.GetUserAutoGroup(group),
 		"pricing_version":    "a42d372ccf0b5dd13ecf71203521f9d2",
+		// 价格按额度单位返回，前端据此换算为站点展示货币
+		"currency": gin.H{
+			"display_type":   operation_setting.GetQuotaDisplayType(),
+			"symbol":         operation_setting.GetCurrencySymbol(),
+			"exchange_rate":  operation_setting.GetUsdToCurrencyRate(operation_setting.USDExchangeRate),
+			"decimal_places": operation_setting.GetCurrencyDecimalPlaces(),
+			"quota_per_unit": common.QuotaPerUnit,
+		},
 	})
 }
 
@@ -0,0 +1,35 @@
+package controller
+
+import (
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetQuotaSyncConsistency 检查 Redis 权威额度与“数据库额度 + 待写回增量”是否一致，仅 root 可用
+func GetQuotaSyncConsistency(c *gin.Context) {
+	respondQuotaSyncConsistency(c, false)
+}
+
+// ReconcileQuotaSync 检查并删除不一致的 Redis 权威额度，使其按数据库重新加载
+func ReconcileQuotaSync(c *gin.Context) {
+	respondQuotaSyncConsistency(c, true)
+}
+
+func respondQuotaSyncConsistency(c *gin.Context, fix bool) {
+	if !common.RedisQuotaEnabled {
+		common.ApiErrorMsg(c, "未启用 Redis 权威额度模式")
+		return
+	}
+	drifts, err := model.CheckUserQuotaConsistency(fix)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"consistent": len(drifts) == 0,
+		"fixed":      fix,
+		"drifts":     drifts,
+	})
+}
@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+type quotaTransferRequest struct {
+	Recipient string `json:"recipient"` // 收款用户名
+	Amount    int    `json:"amount"`
+	Message   string `json:"message"`
+}
+
+// TransferUserQuota 将自己的额度转给其他用户
+func TransferUserQuota(c *gin.Context) {
+	setting := operation_setting.GetQuotaTransferSetting()
+	if !setting.Enabled {
+		common.ApiErrorMsg(c, "管理员未开启额度转账")
+		return
+	}
+	if !requirePaymentCompliance(c) {
+		return
+	}
+	var req quotaTransferRequest
+	if err := common.DecodeJson(c.Request.Body, &req); err != nil {
+		common.ApiErrorMsg(c, "参数错误")
+		return
+	}
+	req.Recipient = strings.TrimSpace(req.Recipient)
+	req.Message = strings.TrimSpace(req.Message)
+	if req.Recipient == "" {
+		common.ApiErrorMsg(c, "请输入收款用户名")
+		return
+	}
+	if req.Amount <= 0 || req.Amount < setting.MinAmount {
+		common.ApiErrorMsg(c, fmt.Sprintf("单笔转账额度不能少于 %s", logger.LogQuota(setting.MinAmount)))
+		return
+	}
+	if setting.MaxAmount > 0 && req.Amount > setting.MaxAmount {
+		common.ApiErrorMsg(c, fmt.Sprintf("单笔转账额度不能超过 %s", logger.LogQuota(setting.MaxAmount)))
+		return
+	}
+	if utf8.RuneCountInString(req.Message) > 255 {
+		common.ApiErrorMsg(c, "留言不能超过 255 个字符")
+		return
+	}
+	transfer, err := model.TransferUserQuota(c.GetInt("id"), req.Recipient, req.Amount, req.Message)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrInsufficientUserQuota):
+			common.ApiErrorMsg(c, "额度不足")
+		case errors.Is(err, model.ErrQuotaTransferRecipientNotFound),
+			errors.Is(err, model.ErrQuotaTransferToSelf),
+			errors.Is(err, model.ErrQuotaTransferDailyLimit),
+			errors.Is(err, model.ErrQuotaTransferDailyCount),
+			errors.Is(err, model.ErrQuotaTransferAmountTooSmall):
+			common.ApiErrorMsg(c, err.Error())
+		default:
+			common.ApiError(c, err)
+		}
+		return
+	}
+	common.ApiSuccess(c, transfer)
+}
+
+// GetSelfQuotaTransfers 查询自己转出和收到的转账记录
+func GetSelfQuotaTransfers(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	transfers, total, err := model.GetUserQuotaTransfers(c.GetInt("id"), pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(transfers)
+	common.ApiSuccess(c, pageInfo)
+}
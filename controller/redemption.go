@@ -1,7 +1,10 @@
 package controller
 
 import (
+	"encoding/csv"
+	"fmt"
 	"net/http"
+	"regexp"
 	"strconv"
 	"unicode/utf8"
 
@@ -10,10 +13,14 @@ import (
 	"github.com/QuantumNous/new-api/logger"
 	"github.com/QuantumNous/new-api/model"
 	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
 
 	"github.com/gin-gonic/gin"
 )
 
+// redemptionPrefixPattern 兑换码前缀，为空表示不加前缀
+var redemptionPrefixPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{0,16}$`)
+
 func GetAllRedemptions(c *gin.Context) {
 	pageInfo := common.GetPageQuery(c)
 	redemptions, total, err := model.GetAllRedemptions(pageInfo.GetStartIdx(), pageInfo.GetPageSize())
@@ -89,33 +96,42 @@ func AddRedemption(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"success": false, "message": msg})
 		return
 	}
-	var keys []string
+	if !redemptionPrefixPattern.MatchString(redemption.Prefix) {
+		common.ApiErrorI18n(c, i18n.MsgRedemptionPrefixInvalid)
+		return
+	}
+	if valid, msg := validateRedemptionLimits(c, &redemption); !valid {
+		c.JSON(http.StatusOK, gin.H{"success": false, "message": msg})
+		return
+	}
+	redemptions := make([]*model.Redemption, 0, redemption.Count)
+	keys := make([]string, 0, redemption.Count)
 	for i := 0; i < redemption.Count; i++ {
-		key := common.GetUUID()
-		cleanRedemption := model.Redemption{
+		// 兑换码固定 32 位，前缀占用随机部分的长度
+		key := redemption.Prefix + common.GetUUID()[len(redemption.Prefix):]
+		redemptions = append(redemptions, &model.Redemption{
 			UserId:      c.GetInt("id"),
 			Name:        redemption.Name,
 			Key:         key,
 			CreatedTime: common.GetTimestamp(),
 			Quota:       redemption.Quota,
 			ExpiredTime: redemption.ExpiredTime,
-		}
-		err = cleanRedemption.Insert()
-		if err != nil {
-			common.SysError("failed to insert redemption: " + err.Error())
-			c.JSON(http.StatusOK, gin.H{
-				"success": false,
-				"message": i18n.T(c, i18n.MsgRedemptionCreateFailed),
-				"data":    keys,
-			})
-			return
-		}
+			MaxUses:     redemption.MaxUses,
+			Group:       redemption.Group,
+		})
 		keys = append(keys, key)
 	}
+	if err = model.CreateRedemptions(redemptions); err != nil {
+		common.SysError("failed to insert redemption: " + err.Error())
+		common.ApiErrorI18n(c, i18n.MsgRedemptionCreateFailed)
+		return
+	}
 	recordManageAudit(c, "redemption.create", map[string]interface{}{
-		"name":  redemption.Name,
-		"count": redemption.Count,
-		"quota": logger.LogQuota(redemption.Quota),
+		"name":     redemption.Name,
+		"count":    redemption.Count,
+		"quota":    logger.LogQuota(redemption.Quota),
+		"max_uses": redemption.MaxUses,
+		"group":    redemption.Group,
 	})
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -157,10 +173,16 @@ func UpdateRedemption(c *gin.Context) {
 			c.JSON(http.StatusOK, gin.H{"success": false, "message": msg})
 			return
 		}
+		if valid, msg := validateRedemptionLimits(c, &redemption); !valid {
+			c.JSON(http.StatusOK, gin.H{"success": false, "message": msg})
+			return
+		}
 		// If you add more fields, please also update redemption.Update()
 		cleanRedemption.Name = redemption.Name
 		cleanRedemption.Quota = redemption.Quota
 		cleanRedemption.ExpiredTime = redemption.ExpiredTime
+		cleanRedemption.MaxUses = redemption.MaxUses
+		cleanRedemption.Group = redemption.Group
 	}
 	if statusOnly != "" {
 		cleanRedemption.Status = redemption.Status
@@ -192,6 +214,63 @@ func DeleteInvalidRedemption(c *gin.Context) {
 	return
 }
 
+// ExportRedemptions 按搜索条件导出兑换码为 CSV，用于批量分发
+func ExportRedemptions(c *gin.Context) {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=redemptions_%d.csv", common.GetTimestamp()))
+	// UTF-8 BOM，避免 Excel 打开时乱码
+	_, _ = c.Writer.WriteString("\xEF\xBB\xBF")
+	writer := csv.NewWriter(c.Writer)
+	_ = writer.Write([]string{"id", "name", "key", "quota", "status", "max_uses", "used_count", "group", "expired_time", "created_time"})
+
+	err := model.ExportRedemptions(c.Query("keyword"), c.Query("status"), func(batch []model.Redemption) error {
+		for _, redemption := range batch {
+			err := writer.Write([]string{
+				strconv.Itoa(redemption.Id),
+				redemption.Name,
+				redemption.Key,
+				strconv.Itoa(redemption.Quota),
+				strconv.Itoa(redemption.Status),
+				strconv.Itoa(max(redemption.MaxUses, 1)),
+				strconv.Itoa(redemption.UsedCount),
+				redemption.Group,
+				strconv.FormatInt(redemption.ExpiredTime, 10),
+				strconv.FormatInt(redemption.CreatedTime, 10),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	})
+	writer.Flush()
+	if err != nil {
+		// 响应已经开始输出，只能记录错误并中断
+		common.SysError(fmt.Sprintf("failed to export redemptions: %v", err))
+		c.Abort()
+		return
+	}
+	recordManageAudit(c, "redemption.export", map[string]interface{}{
+		"keyword": c.Query("keyword"),
+		"status":  c.Query("status"),
+	})
+}
+
+// validateRedemptionLimits 校验可使用次数和限定分组，max_uses 未填写时视为 1
+func validateRedemptionLimits(c *gin.Context, redemption *model.Redemption) (bool, string) {
+	if redemption.MaxUses == 0 {
+		redemption.MaxUses = 1
+	}
+	if redemption.MaxUses < 1 || redemption.MaxUses > 10000 {
+		return false, i18n.T(c, i18n.MsgRedemptionMaxUsesInvalid)
+	}
+	if redemption.Group != "" && !ratio_setting.ContainsGroupRatio(redemption.Group) {
+		return false, i18n.T(c, i18n.MsgRedemptionGroupInvalid)
+	}
+	return true, ""
+}
+
 func validateExpiredTime(c *gin.Context, expired int64) (bool, string) {
 	if expired != 0 && expired < common.GetTimestamp() {
 		return false, i18n.T(c, i18n.MsgRedemptionExpireTimeInvalid)
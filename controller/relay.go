@@ -89,6 +89,8 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 	defer func() {
 		if newAPIError != nil {
 			logger.LogError(c, fmt.Sprintf("relay error: %s", common.LocalLogPreview(newAPIError.Error())))
+			// 日志保留原始错误，返回给用户前按渠道规则改写并去除渠道信息
+			service.NormalizeUpstreamError(c, newAPIError)
 			newAPIError.SetMessage(common.MessageWithRequestId(newAPIError.Error(), requestId))
 			switch relayFormat {
 			case types.RelayFormatOpenAIRealtime:
@@ -123,11 +125,34 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 		return
 	}
 
+	settleUsageCaps, newAPIError := service.CheckUsageCaps(c, relayInfo)
+	if newAPIError != nil {
+		return
+	}
+	// 失败的请求退还次数，成功的请求按实际用量累计 token
+	defer func() {
+		settleUsageCaps(newAPIError)
+	}()
+
+	releaseConcurrency, newAPIError := service.AcquireStreamConcurrency(c, relayInfo)
+	if newAPIError != nil {
+		return
+	}
+	defer releaseConcurrency()
+
+	releaseRealtimeSession, newAPIError := service.AcquireRealtimeSession(c, relayInfo)
+	if newAPIError != nil {
+		return
+	}
+	defer releaseRealtimeSession()
+
 	needSensitiveCheck := setting.ShouldCheckPromptSensitive()
 	needCountToken := constant.CountToken
+	moderationAction := operation_setting.GetModerationAction(relayInfo.UsingGroup)
+	needModeration := moderationAction != operation_setting.ModerationActionOff
 	// Avoid building huge CombineText (strings.Join) when token counting and sensitive check are both disabled.
 	var meta *types.TokenCountMeta
-	if needSensitiveCheck || needCountToken {
+	if needSensitiveCheck || needCountToken || needModeration {
 		meta = request.GetTokenCountMeta()
 	} else {
 		meta = fastTokenCountMetaForPricing(request)
@@ -142,6 +167,24 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 		}
 	}
 
+	// 按分组进行内容审核，redact 动作会改写请求体，需要重新解析请求
+	if needModeration && meta != nil {
+		redacted, moderationErr := service.ModerateRequest(c, relayInfo, moderationAction, meta.CombineText)
+		if moderationErr != nil {
+			newAPIError = moderationErr
+			return
+		}
+		if redacted {
+			request, err = helper.GetAndValidateRequest(c, relayFormat)
+			if err != nil {
+				newAPIError = types.NewError(err, types.ErrorCodeInvalidRequest, types.ErrOptionWithSkipRetry())
+				return
+			}
+			relayInfo.Request = request
+			meta = request.GetTokenCountMeta()
+		}
+	}
+
 	tokens, err := service.EstimateRequestToken(c, meta, relayInfo)
 	if err != nil {
 		newAPIError = types.NewError(err, types.ErrorCodeCountTokenFailed)
@@ -188,7 +231,9 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 	relayInfo.RetryIndex = 0
 	relayInfo.LastError = nil
 
-	for ; retryParam.GetRetry() <= common.RetryTimes; retryParam.IncreaseRetry() {
+	// 重试上限取自最近一次失败渠道的重试策略，未配置时使用全局重试次数
+	retryLimit := common.RetryTimes
+	for ; retryParam.GetRetry() <= retryLimit; retryParam.IncreaseRetry() {
 		relayInfo.RetryIndex = retryParam.GetRetry()
 		channel, channelErr := getChannel(c, relayInfo, retryParam)
 		if channelErr != nil {
@@ -198,6 +243,12 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 		}
 
 		addUsedChannel(c, channel.Id)
+		// 重试换到的渠道可能有不同的价格覆盖，重新计算价格，结算时按新价格多退少补
+		if _, hasChannelPrice := helper.ChannelModelPrice(c, relayInfo.OriginModelName); retryParam.GetRetry() > 0 && (hasChannelPrice || relayInfo.PriceData.ChannelPriceOverride) {
+			if _, priceErr := helper.ModelPriceHelper(c, relayInfo, tokens, meta); priceErr != nil {
+				logger.LogWarn(c, fmt.Sprintf("重新计算渠道 #%d 价格失败，沿用原价格: %s", channel.Id, priceErr.Error()))
+			}
+		}
 		bodyStorage, bodyErr := common.GetBodyStorage(c)
 		if bodyErr != nil {
 			// Ensure consistent 413 for oversized bodies even when error occurs later (e.g., retry path)
@@ -210,28 +261,51 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 		}
 		c.Request.Body = io.NopCloser(bodyStorage)
 
-		switch relayFormat {
-		case types.RelayFormatOpenAIRealtime:
-			newAPIError = relay.WssHelper(c, relayInfo)
-		case types.RelayFormatClaude:
-			newAPIError = relay.ClaudeHelper(c, relayInfo)
-		case types.RelayFormatGemini:
-			newAPIError = geminiRelayHandler(c, relayInfo)
-		default:
-			newAPIError = relayHandler(c, relayInfo)
+		// 渠道达到并发上限时排队等待空闲位置，未开启排队则返回 429 交给重试切换渠道
+		releaseChannelSlot, slotErr := service.AcquireChannelSlot(c, relayInfo, channel.Id)
+		if slotErr != nil {
+			newAPIError = slotErr
+			relayInfo.LastError = slotErr
+			if !shouldRetry(c, slotErr, retryLimit-retryParam.GetRetry()) {
+				break
+			}
+			continue
 		}
 
+		// 在闭包内 defer 释放并发位置，适配器 panic 被 gin recovery 捕获时也不会泄漏
+		newAPIError = func() *types.NewAPIError {
+			defer releaseChannelSlot()
+			switch relayFormat {
+			case types.RelayFormatOpenAIRealtime:
+				return relay.WssHelper(c, relayInfo)
+			case types.RelayFormatClaude:
+				return relay.ClaudeHelper(c, relayInfo)
+			case types.RelayFormatGemini:
+				return geminiRelayHandler(c, relayInfo)
+			default:
+				return relayHandler(c, relayInfo)
+			}
+		}()
+
 		if newAPIError == nil {
 			relayInfo.LastError = nil
+			model.RecordChannelBreakerResult(channel.Id, true)
 			return
 		}
 
 		newAPIError = service.NormalizeViolationFeeError(newAPIError)
 		relayInfo.LastError = newAPIError
+		if service.IsChannelBreakerFailure(newAPIError) {
+			model.RecordChannelBreakerResult(channel.Id, false)
+		}
 
 		processChannelError(c, *types.NewChannelError(channel.Id, channel.Type, channel.Name, channel.ChannelInfo.IsMultiKey, common.GetContextKeyString(c, constant.ContextKeyChannelKey), channel.GetAutoBan()), newAPIError)
 
-		if !shouldRetry(c, newAPIError, common.RetryTimes-retryParam.GetRetry()) {
+		retryLimit = service.GetChannelRetryTimes(c)
+		if !shouldRetry(c, newAPIError, retryLimit-retryParam.GetRetry()) {
+			break
+		}
+		if !service.WaitChannelRetryBackoff(c, retryParam.GetRetry()+1) {
 			break
 		}
 	}
@@ -242,7 +316,7 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 		logger.LogInfo(c, retryLogStr)
 	}
 	if newAPIError != nil {
-		gopool.Go(func() {
+		common.GoPendingWrite(func() {
 			perfmetrics.RecordRelaySample(relayInfo, false, 0)
 		})
 	}
@@ -351,7 +425,7 @@ func shouldRetry(c *gin.Context, openaiErr *types.NewAPIError, retryTimes int) b
 	if operation_setting.IsAlwaysSkipRetryCode(openaiErr.GetErrorCode()) {
 		return false
 	}
-	return operation_setting.ShouldRetryByStatusCode(code)
+	return service.ShouldRetryChannelStatusCode(c, code)
 }
 
 func processChannelError(c *gin.Context, channelError types.ChannelError, err *types.NewAPIError) {
@@ -364,6 +438,21 @@ func processChannelError(c *gin.Context, channelError types.ChannelError, err *t
 		})
 	}
 
+	if common.DataExportEnabled && types.IsRecordErrorLog(err) {
+		// 失败请求计入数据看板，用于用量分析中的错误率统计
+		model.LogQuotaData(model.QuotaDataLogParams{
+			UserID:    c.GetInt("id"),
+			Username:  c.GetString("username"),
+			ModelName: c.GetString("original_model"),
+			CreatedAt: common.GetTimestamp(),
+			UseGroup:  c.GetString("group"),
+			TokenID:   c.GetInt("token_id"),
+			ChannelID: channelError.ChannelId,
+			NodeName:  common.NodeName,
+			IsError:   true,
+		})
+	}
+
 	if constant.ErrorLogEnabled && types.IsRecordErrorLog(err) {
 		// 保存错误日志到mysql中
 		userId := c.GetInt("id")
@@ -421,10 +510,27 @@ func RelayMidjourney(c *gin.Context) {
 		mjErr = relay.RelayMidjourneyTask(c, relayInfo.RelayMode)
 	case relayconstant.RelayModeMidjourneyTaskImageSeed:
 		mjErr = relay.RelayMidjourneyTaskImageSeed(c)
-	case relayconstant.RelayModeSwapFace:
-		mjErr = relay.RelaySwapFace(c, relayInfo)
 	default:
-		mjErr = relay.RelayMidjourneySubmit(c, relayInfo)
+		// 只有提交类请求计入用量上限，查询与回调不计
+		settleUsageCaps, capErr := service.CheckUsageCaps(c, relayInfo)
+		if capErr != nil {
+			c.JSON(capErr.StatusCode, gin.H{
+				"description": capErr.Error(),
+				"type":        "new_api_error",
+				"code":        constant.MjRequestError,
+			})
+			return
+		}
+		if relayInfo.RelayMode == relayconstant.RelayModeSwapFace {
+			mjErr = relay.RelaySwapFace(c, relayInfo)
+		} else {
+			mjErr = relay.RelayMidjourneySubmit(c, relayInfo)
+		}
+		var submitErr *types.NewAPIError
+		if mjErr != nil {
+			submitErr = types.NewError(errors.New(mjErr.Description), types.ErrorCodeBadResponse)
+		}
+		settleUsageCaps(submitErr)
 	}
 	//err = relayMidjourneySubmit(c, relayMode)
 	log.Println(mjErr)
@@ -433,6 +539,8 @@ func RelayMidjourney(c *gin.Context) {
 		if mjErr.Code == 30 {
 			mjErr.Result = "当前分组负载已饱和，请稍后再试，或升级账户以提升服务质量。"
 			statusCode = http.StatusTooManyRequests
+		} else if mjErr.Code == constant.MjQueueFull {
+			statusCode = http.StatusTooManyRequests
 		}
 		c.JSON(statusCode, gin.H{
 			"description": fmt.Sprintf("%s %s", mjErr.Description, mjErr.Result),
@@ -499,12 +607,23 @@ func RelayTask(c *gin.Context) {
 		return
 	}
 
+	settleUsageCaps, capErr := service.CheckUsageCaps(c, relayInfo)
+	if capErr != nil {
+		c.JSON(capErr.StatusCode, service.TaskErrorWrapperLocal(capErr.Err, string(capErr.GetErrorCode()), capErr.StatusCode))
+		return
+	}
+
 	var result *relay.TaskSubmitResult
 	var taskErr *dto.TaskError
 	defer func() {
-		if taskErr != nil && relayInfo.Billing != nil {
-			relayInfo.Billing.Refund(c)
+		var submitErr *types.NewAPIError
+		if taskErr != nil {
+			submitErr = types.NewError(taskErr.Error, types.ErrorCodeBadResponse)
+			if relayInfo.Billing != nil {
+				relayInfo.Billing.Refund(c)
+			}
 		}
+		settleUsageCaps(submitErr)
 	}()
 
 	retryParam := &service.RetryParam{
@@ -550,10 +669,14 @@ func RelayTask(c *gin.Context) {
 
 		result, taskErr = relay.RelayTaskSubmit(c, relayInfo)
 		if taskErr == nil {
+			model.RecordChannelBreakerResult(channel.Id, true)
 			break
 		}
 
 		if !taskErr.LocalError {
+			if taskErr.StatusCode == http.StatusTooManyRequests || taskErr.StatusCode >= 500 {
+				model.RecordChannelBreakerResult(channel.Id, false)
+			}
 			processChannelError(c,
 				*types.NewChannelError(channel.Id, channel.Type, channel.Name, channel.ChannelInfo.IsMultiKey,
 					common.GetContextKeyString(c, constant.ContextKeyChannelKey), channel.GetAutoBan()),
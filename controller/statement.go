@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSelfStatements 分页获取当前用户的月度账单，可按 period（YYYY-MM）过滤
+func GetSelfStatements(c *gin.Context) {
+	getStatements(c, c.GetInt("id"))
+}
+
+// GetAllStatements 管理员分页获取账单，不指定 user_id 时返回全部用户
+func GetAllStatements(c *gin.Context) {
+	userId, _ := strconv.Atoi(c.Query("user_id"))
+	getStatements(c, userId)
+}
+
+func getStatements(c *gin.Context, userId int) {
+	pageInfo := common.GetPageQuery(c)
+	period := c.Query("period")
+	if period != "" {
+		if _, _, err := model.StatementPeriodRange(period); err != nil {
+			common.ApiError(c, err)
+			return
+		}
+	}
+	statements, total, err := model.GetUserStatements(userId, period, pageInfo)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(statements)
+	common.ApiSuccess(c, pageInfo)
+}
+
+// ExportSelfStatement 导出当前用户的账单，format 为 csv（默认）或 pdf
+func ExportSelfStatement(c *gin.Context) {
+	exportStatement(c, c.GetInt("id"))
+}
+
+// AdminExportStatement 管理员导出任意用户的账单
+func AdminExportStatement(c *gin.Context) {
+	exportStatement(c, 0)
+}
+
+func exportStatement(c *gin.Context, userId int) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiErrorMsg(c, "无效的账单 ID")
+		return
+	}
+	statement, err := model.GetUserStatementById(id, userId)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	// 先写入缓冲区，生成失败时仍可返回 JSON 错误
+	var buf bytes.Buffer
+	var contentType string
+	format := c.DefaultQuery("format", "csv")
+	switch format {
+	case "csv":
+		contentType = "text/csv; charset=utf-8"
+		// UTF-8 BOM，避免 Excel 打开时乱码
+		buf.WriteString("\xEF\xBB\xBF")
+		err = service.WriteStatementCSV(&buf, statement)
+	case "pdf":
+		contentType = "application/pdf"
+		err = service.WriteStatementPDF(&buf, statement)
+	default:
+		common.ApiErrorMsg(c, "不支持的导出格式，仅支持 csv 和 pdf")
+		return
+	}
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=statement_%d_%s.%s", statement.UserId, statement.Period, format))
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+type generateStatementRequest struct {
+	Period string `json:"period"`
+	UserId int    `json:"user_id"`
+}
+
+// AdminGenerateStatements 管理员手动生成账单：指定 user_id 时重新生成该用户的账单，
+// 否则为该周期内有活动但尚未生成账单的用户补生成
+func AdminGenerateStatements(c *gin.Context) {
+	var req generateStatementRequest
+	if err := common.DecodeJson(c.Request.Body, &req); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if _, _, err := model.StatementPeriodRange(req.Period); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if req.UserId < 0 {
+		common.ApiErrorMsg(c, "无效的用户 ID")
+		return
+	}
+	count := 0
+	if req.UserId > 0 {
+		if _, err := model.GenerateUserStatement(req.UserId, req.Period); err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		count = 1
+	} else {
+		n, err := model.GenerateMissingStatements(req.Period)
+		if err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		count = n
+	}
+	recordManageAudit(c, "statement.generate", map[string]interface{}{
+		"period":  req.Period,
+		"user_id": req.UserId,
+		"count":   count,
+	})
+	common.ApiSuccess(c, gin.H{"count": count})
+}
@@ -300,6 +300,7 @@ func AdminUpdateSubscriptionPlan(c *gin.Context) {
 			"downgrade_group":            req.Plan.DowngradeGroup,
 			"quota_reset_period":         req.Plan.QuotaResetPeriod,
 			"quota_reset_custom_seconds": req.Plan.QuotaResetCustomSeconds,
+			"prorate_cycles":             req.Plan.ProrateCycles,
 			"updated_at":                 common.GetTimestamp(),
 		}
 		if req.Plan.AllowBalancePay != nil {
@@ -391,6 +392,33 @@ func AdminListUserSubscriptions(c *gin.Context) {
 	common.ApiSuccess(c, subs)
 }
 
+// listSubscriptionGrants returns the grant history of the user subscription in
+// the :id param; userId > 0 limits it to that user's own subscriptions.
+func listSubscriptionGrants(c *gin.Context, userId int) {
+	userSubscriptionId, _ := strconv.Atoi(c.Param("id"))
+	if userSubscriptionId <= 0 {
+		common.ApiErrorMsg(c, "无效的订阅ID")
+		return
+	}
+	pageInfo := common.GetPageQuery(c)
+	grants, total, err := model.GetSubscriptionGrants(userSubscriptionId, userId, pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(grants)
+	common.ApiSuccess(c, pageInfo)
+}
+
+func GetSubscriptionSelfGrants(c *gin.Context) {
+	listSubscriptionGrants(c, c.GetInt("id"))
+}
+
+func AdminListSubscriptionGrants(c *gin.Context) {
+	listSubscriptionGrants(c, 0)
+}
+
 type AdminCreateUserSubscriptionRequest struct {
 	PlanId int `json:"plan_id"`
 }
@@ -6,6 +6,7 @@ import (
 
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
 
 	"github.com/gin-gonic/gin"
 )
@@ -63,3 +64,12 @@ func DeleteStaleSystemInstance(c *gin.Context) {
 		"deleted_count": 1,
 	})
 }
+
+func GetSystemLeader(c *gin.Context) {
+	status, err := service.GetLeaderStatus()
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, status)
+}
@@ -34,6 +34,36 @@ func CreateLogCleanupSystemTask(c *gin.Context) {
 	})
 }
 
+// CreateLogRetentionSystemTask 手动触发一次日志保留清理/归档，进度通过 /system-task/current?type=log_retention 查询
+func CreateLogRetentionSystemTask(c *gin.Context) {
+	payload := service.NewLogRetentionPayload()
+	if payload.ConsumeCutoff == 0 && payload.ErrorCutoff == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "未配置日志保留天数",
+		})
+		return
+	}
+	task, created, err := service.EnqueueSystemTask(model.SystemTaskTypeLogRetention, payload)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if !created {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": "已有日志保留任务正在运行或等待中",
+			"data":    task.ToResponse(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    task.ToResponse(),
+	})
+}
+
 func GetCurrentSystemTask(c *gin.Context) {
 	taskType := c.Query("type")
 	if taskType == "" {
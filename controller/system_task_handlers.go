@@ -22,6 +22,7 @@ func RegisterScheduledSystemTasks() {
 	service.RegisterSystemTaskHandler(modelUpdateHandler{})
 	service.RegisterSystemTaskHandler(midjourneyPollHandler{})
 	service.RegisterSystemTaskHandler(asyncTaskPollHandler{})
+	service.RegisterSystemTaskHandler(batchPollHandler{})
 }
 
 // channelTestHandler runs the scheduled "test all channels" job. Enablement and
@@ -152,6 +153,32 @@ func (asyncTaskPollHandler) Run(ctx context.Context, task *model.SystemTask, run
 	finishSystemTaskHandler(task, runnerID, model.SystemTaskStatusSucceeded, summary, nil)
 }
 
+// batchPollHandler refreshes tracked OpenAI batch jobs and settles the ones
+// that finished. Like the other poll handlers, Enabled() folds in the
+// unsettled job check so an idle system schedules no rows.
+type batchPollHandler struct{}
+
+func (batchPollHandler) Type() string { return model.SystemTaskTypeBatchPoll }
+
+func (batchPollHandler) Enabled() bool {
+	return operation_setting.GetBatchSetting().Enabled && model.HasUnsettledBatchJobs()
+}
+
+func (batchPollHandler) Interval() time.Duration {
+	seconds := operation_setting.GetBatchSetting().PollIntervalSeconds
+	if seconds <= 0 {
+		seconds = 60
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func (batchPollHandler) NewPayload() any { return nil }
+
+func (batchPollHandler) Run(ctx context.Context, task *model.SystemTask, runnerID string) {
+	summary := service.RunBatchPollingOnce(ctx, service.NewSystemTaskProgressReporter(task, runnerID))
+	finishSystemTaskHandler(task, runnerID, model.SystemTaskStatusSucceeded, summary, nil)
+}
+
 func finishSystemTaskHandler(task *model.SystemTask, runnerID string, status model.SystemTaskStatus, result any, runErr error) {
 	errorMessage := ""
 	if runErr != nil {
@@ -1,10 +1,13 @@
 package controller
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/i18n"
@@ -14,12 +17,36 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// validateTokenModelMapping 校验令牌级模型别名：必须是 JSON 对象，键和值均为非空模型名
+func validateTokenModelMapping(modelMapping string) bool {
+	if modelMapping == "" {
+		return true
+	}
+	mapping := make(map[string]string)
+	if err := common.UnmarshalJsonStr(modelMapping, &mapping); err != nil {
+		return false
+	}
+	for alias, target := range mapping {
+		if strings.TrimSpace(alias) == "" || strings.TrimSpace(target) == "" {
+			return false
+		}
+	}
+	return true
+}
+
 func buildMaskedTokenResponse(token *model.Token) *model.Token {
 	if token == nil {
 		return nil
 	}
 	maskedToken := *token
 	maskedToken.Key = token.GetMaskedKey()
+	// 宽限期内的旧密钥以掩码形式返回，列表据此展示“即将失效”
+	if token.PreviousKeyActive() {
+		maskedToken.PreviousKey = model.MaskTokenKey(token.PreviousKey)
+	} else {
+		maskedToken.PreviousKey = ""
+		maskedToken.PreviousKeyExpiresAt = 0
+	}
 	return &maskedToken
 }
 
@@ -175,6 +202,18 @@ func AddToken(c *gin.Context) {
 		common.ApiErrorI18n(c, i18n.MsgTokenNameTooLong)
 		return
 	}
+	if token.RpmLimit < 0 || token.TpmLimit < 0 || token.MaxConcurrency < 0 {
+		common.ApiErrorI18n(c, i18n.MsgInvalidParams)
+		return
+	}
+	if _, invalid := common.ParseIPAllowlist(token.GetIpLimits()); len(invalid) > 0 {
+		common.ApiErrorI18n(c, i18n.MsgTokenAllowIpsInvalid, map[string]any{"Entries": strings.Join(invalid, ", ")})
+		return
+	}
+	if !validateTokenModelMapping(token.ModelMapping) {
+		common.ApiErrorI18n(c, i18n.MsgTokenModelMappingInvalid)
+		return
+	}
 	// 非无限额度时，检查额度值是否超出有效范围
 	if !token.UnlimitedQuota {
 		if token.RemainQuota < 0 {
@@ -218,9 +257,14 @@ func AddToken(c *gin.Context) {
 		UnlimitedQuota:     token.UnlimitedQuota,
 		ModelLimitsEnabled: token.ModelLimitsEnabled,
 		ModelLimits:        token.ModelLimits,
+		ModelMapping:       token.ModelMapping,
 		AllowIps:           token.AllowIps,
 		Group:              token.Group,
 		CrossGroupRetry:    token.CrossGroupRetry,
+		RpmLimit:           token.RpmLimit,
+		TpmLimit:           token.TpmLimit,
+		MaxConcurrency:     token.MaxConcurrency,
+		BodyLogEnabled:     token.BodyLogEnabled,
 	}
 	err = cleanToken.Insert()
 	if err != nil {
@@ -260,6 +304,18 @@ func UpdateToken(c *gin.Context) {
 		common.ApiErrorI18n(c, i18n.MsgTokenNameTooLong)
 		return
 	}
+	if token.RpmLimit < 0 || token.TpmLimit < 0 || token.MaxConcurrency < 0 {
+		common.ApiErrorI18n(c, i18n.MsgInvalidParams)
+		return
+	}
+	if _, invalid := common.ParseIPAllowlist(token.GetIpLimits()); statusOnly == "" && len(invalid) > 0 {
+		common.ApiErrorI18n(c, i18n.MsgTokenAllowIpsInvalid, map[string]any{"Entries": strings.Join(invalid, ", ")})
+		return
+	}
+	if statusOnly == "" && !validateTokenModelMapping(token.ModelMapping) {
+		common.ApiErrorI18n(c, i18n.MsgTokenModelMappingInvalid)
+		return
+	}
 	if !token.UnlimitedQuota {
 		if token.RemainQuota < 0 {
 			common.ApiErrorI18n(c, i18n.MsgTokenQuotaNegative)
@@ -296,9 +352,14 @@ func UpdateToken(c *gin.Context) {
 		cleanToken.UnlimitedQuota = token.UnlimitedQuota
 		cleanToken.ModelLimitsEnabled = token.ModelLimitsEnabled
 		cleanToken.ModelLimits = token.ModelLimits
+		cleanToken.ModelMapping = token.ModelMapping
 		cleanToken.AllowIps = token.AllowIps
 		cleanToken.Group = token.Group
 		cleanToken.CrossGroupRetry = token.CrossGroupRetry
+		cleanToken.RpmLimit = token.RpmLimit
+		cleanToken.TpmLimit = token.TpmLimit
+		cleanToken.MaxConcurrency = token.MaxConcurrency
+		cleanToken.BodyLogEnabled = token.BodyLogEnabled
 	}
 	err = cleanToken.Update()
 	if err != nil {
@@ -312,6 +373,57 @@ func UpdateToken(c *gin.Context) {
 	})
 }
 
+type RotateTokenRequest struct {
+	// GraceSeconds 旧密钥保留秒数，为空时使用 token_setting.rotation_grace_seconds
+	GraceSeconds *int64 `json:"grace_seconds"`
+}
+
+// RotateToken 为令牌签发新密钥，旧密钥在宽限期内继续有效，便于无中断切换
+func RotateToken(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	userId := c.GetInt("id")
+	var req RotateTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		common.ApiErrorI18n(c, i18n.MsgInvalidParams)
+		return
+	}
+	graceSeconds := int64(operation_setting.GetTokenSetting().RotationGraceSeconds)
+	if req.GraceSeconds != nil {
+		graceSeconds = *req.GraceSeconds
+	}
+	if graceSeconds < 0 || graceSeconds > operation_setting.MaxTokenRotationGraceSeconds {
+		common.ApiErrorMsg(c, fmt.Sprintf("宽限期应在 0-%d 秒之间", operation_setting.MaxTokenRotationGraceSeconds))
+		return
+	}
+	key, err := common.GenerateKey()
+	if err != nil {
+		common.ApiErrorI18n(c, i18n.MsgTokenGenerateFailed)
+		common.SysLog("failed to generate token key: " + err.Error())
+		return
+	}
+	token, err := model.RotateTokenKey(id, userId, key, graceSeconds)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	content := fmt.Sprintf("轮换令牌「%s」的密钥，旧密钥已立即失效", token.Name)
+	if token.PreviousKeyActive() {
+		content = fmt.Sprintf("轮换令牌「%s」的密钥，旧密钥有效期至 %s", token.Name,
+			time.Unix(token.PreviousKeyExpiresAt, 0).Format("2006-01-02 15:04:05"))
+	}
+	model.RecordLog(userId, model.LogTypeSystem, content)
+	common.ApiSuccess(c, gin.H{
+		"id":                      token.Id,
+		"key":                     token.GetFullKey(),
+		"previous_key":            model.MaskTokenKey(token.PreviousKey),
+		"previous_key_expires_at": token.PreviousKeyExpiresAt,
+	})
+}
+
 type TokenBatch struct {
 	Ids []int `json:"ids"`
 }
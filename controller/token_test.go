@@ -538,3 +538,148 @@ func TestGetTokenKeyRequiresOwnershipAndReturnsFullKey(t *testing.T) {
 		t.Fatalf("unauthorized key response leaked raw token key: %s", unauthorizedRecorder.Body.String())
 	}
 }
+
+func TestRotateTokenKeepsPreviousKeyDuringGracePeriod(t *testing.T) {
+	db := setupTokenControllerTestDB(t)
+	if err := db.AutoMigrate(&model.User{}, &model.Log{}); err != nil {
+		t.Fatalf("failed to migrate user and log tables: %v", err)
+	}
+	token := seedToken(t, db, 1, "rotating-token", "rotate1234token5678")
+	oldKey := token.Key
+
+	rotate := func(userID int, body any) (tokenAPIResponse, string) {
+		ctx, recorder := newAuthenticatedContext(t, http.MethodPost, "/api/token/"+strconv.Itoa(token.Id)+"/rotate", body, userID)
+		ctx.Params = gin.Params{{Key: "id", Value: strconv.Itoa(token.Id)}}
+		RotateToken(ctx)
+		return decodeAPIResponse(t, recorder), recorder.Body.String()
+	}
+
+	if response, _ := rotate(2, map[string]any{"grace_seconds": 3600}); response.Success {
+		t.Fatalf("expected rotating another user's token to fail")
+	}
+	if response, _ := rotate(1, map[string]any{"grace_seconds": -1}); response.Success {
+		t.Fatalf("expected negative grace period to be rejected")
+	}
+
+	response, _ := rotate(1, map[string]any{"grace_seconds": 3600})
+	if !response.Success {
+		t.Fatalf("expected rotation to succeed, got message: %s", response.Message)
+	}
+	var rotated struct {
+		Key                  string `json:"key"`
+		PreviousKeyExpiresAt int64  `json:"previous_key_expires_at"`
+	}
+	if err := common.Unmarshal(response.Data, &rotated); err != nil {
+		t.Fatalf("failed to decode rotate response: %v", err)
+	}
+	if rotated.Key == "" || rotated.Key == oldKey {
+		t.Fatalf("expected a new key, got %q", rotated.Key)
+	}
+	if rotated.PreviousKeyExpiresAt <= common.GetTimestamp() {
+		t.Fatalf("expected previous key cutoff in the future, got %d", rotated.PreviousKeyExpiresAt)
+	}
+
+	for _, key := range []string{oldKey, rotated.Key} {
+		validated, err := model.ValidateUserToken(key)
+		if err != nil {
+			t.Fatalf("expected key %q to stay valid during grace period: %v", key, err)
+		}
+		if validated.Id != token.Id || validated.Key != rotated.Key {
+			t.Fatalf("expected key %q to resolve to token %d with the new key, got %d", key, token.Id, validated.Id)
+		}
+	}
+
+	detailCtx, detailRecorder := newAuthenticatedContext(t, http.MethodGet, "/api/token/"+strconv.Itoa(token.Id), nil, 1)
+	detailCtx.Params = gin.Params{{Key: "id", Value: strconv.Itoa(token.Id)}}
+	GetToken(detailCtx)
+	if strings.Contains(detailRecorder.Body.String(), oldKey) || !strings.Contains(detailRecorder.Body.String(), model.MaskTokenKey(oldKey)) {
+		t.Fatalf("expected token detail to expose only the masked previous key: %s", detailRecorder.Body.String())
+	}
+
+	// 不保留宽限期时，之前的所有密钥立即失效
+	response, _ = rotate(1, map[string]any{"grace_seconds": 0})
+	if !response.Success {
+		t.Fatalf("expected second rotation to succeed, got message: %s", response.Message)
+	}
+	for _, key := range []string{oldKey, rotated.Key} {
+		if _, err := model.ValidateUserToken(key); err == nil {
+			t.Fatalf("expected key %q to be revoked after rotation without grace period", key)
+		}
+	}
+}
+
+func TestUpdateTokenRejectsInvalidAllowIps(t *testing.T) {
+	db := setupTokenControllerTestDB(t)
+	token := seedToken(t, db, 1, "ip-token", "iplimit1234token5678")
+
+	tests := []struct {
+		name      string
+		allowIps  string
+		wantValid bool
+	}{
+		{name: "ips and cidrs", allowIps: "10.0.0.0/8\n203.0.113.7, 2001:db8::/32", wantValid: true},
+		{name: "invalid entry", allowIps: "10.0.0.0/8\n10.0.0.300", wantValid: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := map[string]any{
+				"id":              token.Id,
+				"name":            token.Name,
+				"expired_time":    -1,
+				"unlimited_quota": true,
+				"allow_ips":       tt.allowIps,
+			}
+			ctx, recorder := newAuthenticatedContext(t, http.MethodPut, "/api/token/", body, 1)
+			UpdateToken(ctx)
+
+			response := decodeAPIResponse(t, recorder)
+			if response.Success != tt.wantValid {
+				t.Fatalf("expected success=%v, got message: %s", tt.wantValid, response.Message)
+			}
+		})
+	}
+}
+
+func TestUpdateTokenValidatesModelMapping(t *testing.T) {
+	db := setupTokenControllerTestDB(t)
+	token := seedToken(t, db, 1, "alias-token", "alias1234token5678")
+
+	tests := []struct {
+		name         string
+		modelMapping string
+		wantValid    bool
+	}{
+		{name: "empty", modelMapping: "", wantValid: true},
+		{name: "alias", modelMapping: `{"gpt-4o":"my-channel-specific-model"}`, wantValid: true},
+		{name: "not an object", modelMapping: `["gpt-4o"]`, wantValid: false},
+		{name: "empty target", modelMapping: `{"gpt-4o":" "}`, wantValid: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := map[string]any{
+				"id":              token.Id,
+				"name":            token.Name,
+				"expired_time":    -1,
+				"unlimited_quota": true,
+				"model_mapping":   tt.modelMapping,
+			}
+			ctx, recorder := newAuthenticatedContext(t, http.MethodPut, "/api/token/", body, 1)
+			UpdateToken(ctx)
+
+			response := decodeAPIResponse(t, recorder)
+			if response.Success != tt.wantValid {
+				t.Fatalf("expected success=%v, got message: %s", tt.wantValid, response.Message)
+			}
+			if !tt.wantValid {
+				return
+			}
+			stored, err := model.GetTokenById(token.Id)
+			if err != nil {
+				t.Fatalf("failed to reload token: %v", err)
+			}
+			if stored.ModelMapping != tt.modelMapping {
+				t.Fatalf("expected stored model mapping %q, got %q", tt.modelMapping, stored.ModelMapping)
+			}
+		})
+	}
+}
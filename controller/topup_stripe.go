@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -17,6 +18,7 @@ import (
 	"github.com/QuantumNous/new-api/setting/operation_setting"
 
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
 	"github.com/stripe/stripe-go/v81"
 	"github.com/stripe/stripe-go/v81/checkout/session"
 	"github.com/stripe/stripe-go/v81/webhook"
@@ -25,6 +27,13 @@ import (
 
 var stripeAdaptor = &StripeAdaptor{}
 
+// stripeZeroDecimalCurrencies are charged in whole units rather than cents.
+// https://docs.stripe.com/currencies#zero-decimal
+var stripeZeroDecimalCurrencies = []string{
+	"bif", "clp", "djf", "gnf", "jpy", "kmf", "krw", "mga",
+	"pyg", "rwf", "ugx", "vnd", "vuv", "xaf", "xof", "xpf",
+}
+
 // StripePayRequest represents a payment request for Stripe checkout.
 type StripePayRequest struct {
 	// Amount is the quantity of units to purchase.
@@ -92,7 +101,8 @@ func (*StripeAdaptor) RequestPay(c *gin.Context, req *StripePayRequest) {
 	reference := fmt.Sprintf("new-api-ref-%d-%d-%s", user.Id, time.Now().UnixMilli(), randstr.String(4))
 	referenceId := "ref_" + common.Sha1([]byte(reference))
 
-	payLink, err := genStripeLink(referenceId, user.StripeCustomer, user.Email, req.Amount, req.SuccessURL, req.CancelURL)
+	payMoney := getStripePayMoney(float64(req.Amount), user.Group)
+	payLink, err := genStripeLink(referenceId, user.StripeCustomer, user.Email, req.Amount, payMoney, req.SuccessURL, req.CancelURL)
 	if err != nil {
 		logger.LogError(c.Request.Context(), fmt.Sprintf("Stripe 创建 Checkout Session 失败 user_id=%d trade_no=%s amount=%d error=%q", id, referenceId, req.Amount, err.Error()))
 		c.JSON(http.StatusOK, gin.H{"message": "error", "data": "拉起支付失败"})
@@ -182,6 +192,8 @@ func StripeWebhook(c *gin.Context) {
 		sessionAsyncPaymentSucceeded(ctx, event, callerIp)
 	case stripe.EventTypeCheckoutSessionAsyncPaymentFailed:
 		sessionAsyncPaymentFailed(ctx, event, callerIp)
+	case stripe.EventTypeChargeRefunded:
+		chargeRefunded(ctx, event, callerIp)
 	default:
 		logger.LogInfo(ctx, fmt.Sprintf("Stripe webhook 忽略事件 event_type=%s client_ip=%s", string(event.Type), callerIp))
 	}
@@ -278,7 +290,7 @@ func fulfillOrder(ctx context.Context, event stripe.Event, referenceId string, c
 		return
 	}
 
-	err := model.Recharge(referenceId, customerId, callerIp)
+	err := model.Recharge(referenceId, customerId, event.GetObjectValue("payment_intent"), callerIp)
 	if err != nil {
 		logger.LogError(ctx, fmt.Sprintf("Stripe 充值处理失败 trade_no=%s event_type=%s client_ip=%s error=%q", referenceId, string(event.Type), callerIp, err.Error()))
 		return
@@ -289,6 +301,30 @@ func fulfillOrder(ctx context.Context, event stripe.Event, referenceId string, c
 	logger.LogInfo(ctx, fmt.Sprintf("Stripe 充值成功 trade_no=%s amount_total=%.2f currency=%s event_type=%s client_ip=%s", referenceId, total/100, currency, string(event.Type), callerIp))
 }
 
+// chargeRefunded deducts quota for refunded top-up payments. Stripe reports the
+// cumulative refunded amount, so partial and repeated refunds are handled by
+// the model layer against what has already been deducted.
+func chargeRefunded(ctx context.Context, event stripe.Event, callerIp string) {
+	paymentId := event.GetObjectValue("payment_intent")
+	if paymentId == "" {
+		logger.LogWarn(ctx, fmt.Sprintf("Stripe charge.refunded 缺少 payment_intent client_ip=%s", callerIp))
+		return
+	}
+	chargeAmount, _ := strconv.ParseInt(event.GetObjectValue("amount"), 10, 64)
+	amountRefunded, _ := strconv.ParseInt(event.GetObjectValue("amount_refunded"), 10, 64)
+
+	quota, err := model.RefundStripeTopUp(paymentId, chargeAmount, amountRefunded, callerIp)
+	if errors.Is(err, model.ErrTopUpNotFound) {
+		logger.LogInfo(ctx, fmt.Sprintf("Stripe 退款未匹配到充值订单，忽略处理 payment_intent=%s client_ip=%s", paymentId, callerIp))
+		return
+	}
+	if err != nil {
+		logger.LogError(ctx, fmt.Sprintf("Stripe 退款处理失败 payment_intent=%s amount=%d amount_refunded=%d client_ip=%s error=%q", paymentId, chargeAmount, amountRefunded, callerIp, err.Error()))
+		return
+	}
+	logger.LogInfo(ctx, fmt.Sprintf("Stripe 退款处理成功 payment_intent=%s amount=%d amount_refunded=%d deducted_quota=%d client_ip=%s", paymentId, chargeAmount, amountRefunded, quota, callerIp))
+}
+
 func sessionExpired(ctx context.Context, event stripe.Event) {
 	referenceId := event.GetObjectValue("client_reference_id")
 	status := event.GetObjectValue("status")
@@ -334,11 +370,12 @@ func sessionExpired(ctx context.Context, event stripe.Event) {
 //   - customerId: existing Stripe customer ID (empty string if new customer)
 //   - email: customer email address for new customer creation
 //   - amount: quantity of units to purchase
+//   - payMoney: amount to charge, used when no StripePriceId is configured
 //   - successURL: custom URL to redirect after successful payment (empty for default)
 //   - cancelURL: custom URL to redirect when payment is canceled (empty for default)
 //
 // Returns the checkout session URL or an error if the session creation fails.
-func genStripeLink(referenceId string, customerId string, email string, amount int64, successURL string, cancelURL string) (string, error) {
+func genStripeLink(referenceId string, customerId string, email string, amount int64, payMoney float64, successURL string, cancelURL string) (string, error) {
 	if !strings.HasPrefix(setting.StripeApiSecret, "sk_") && !strings.HasPrefix(setting.StripeApiSecret, "rk_") {
 		return "", fmt.Errorf("无效的Stripe API密钥")
 	}
@@ -353,16 +390,35 @@ func genStripeLink(referenceId string, customerId string, email string, amount i
 		cancelURL = paymentReturnPath("/console/topup")
 	}
 
-	params := &stripe.CheckoutSessionParams{
-		ClientReferenceID: stripe.String(referenceId),
-		SuccessURL:        stripe.String(successURL),
-		CancelURL:         stripe.String(cancelURL),
-		LineItems: []*stripe.CheckoutSessionLineItemParams{
-			{
-				Price:    stripe.String(setting.StripePriceId),
-				Quantity: stripe.Int64(amount),
+	lineItem := &stripe.CheckoutSessionLineItemParams{
+		Price:    stripe.String(setting.StripePriceId),
+		Quantity: stripe.Int64(amount),
+	}
+	if setting.StripePriceId == "" {
+		// Without a preconfigured price, charge the computed amount in the
+		// configured currency. Stripe expects the smallest currency unit.
+		currency := strings.ToLower(setting.StripeCurrency)
+		unitAmount := decimal.NewFromFloat(payMoney)
+		if !slices.Contains(stripeZeroDecimalCurrencies, currency) {
+			unitAmount = unitAmount.Mul(decimal.NewFromInt(100))
+		}
+		lineItem = &stripe.CheckoutSessionLineItemParams{
+			PriceData: &stripe.CheckoutSessionLineItemPriceDataParams{
+				Currency:   stripe.String(currency),
+				UnitAmount: stripe.Int64(unitAmount.Round(0).IntPart()),
+				ProductData: &stripe.CheckoutSessionLineItemPriceDataProductDataParams{
+					Name: stripe.String(fmt.Sprintf("Top-up %d", amount)),
+				},
 			},
-		},
+			Quantity: stripe.Int64(1),
+		}
+	}
+
+	params := &stripe.CheckoutSessionParams{
+		ClientReferenceID:   stripe.String(referenceId),
+		SuccessURL:          stripe.String(successURL),
+		CancelURL:           stripe.String(cancelURL),
+		LineItems:           []*stripe.CheckoutSessionLineItemParams{lineItem},
 		Mode:                stripe.String(string(stripe.CheckoutSessionModePayment)),
 		AllowPromotionCodes: stripe.Bool(setting.StripePromotionCodesEnabled),
 	}
@@ -6,7 +6,9 @@ import (
 	"strconv"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/i18n"
 	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/system_setting"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
@@ -212,6 +214,10 @@ func Disable2FA(c *gin.Context) {
 	}
 
 	userId := c.GetInt("id")
+	if c.GetInt("role") >= common.RoleAdminUser && system_setting.GetTwoFASettings().RequireForAdmin {
+		common.ApiErrorI18n(c, i18n.MsgTwoFARequired)
+		return
+	}
 
 	// 获取2FA记录
 	twoFA, err := model.GetTwoFAByUserId(userId)
@@ -129,3 +129,36 @@ func GetUserFlowQuotaDates(c *gin.Context) {
 	})
 	return
 }
+
+// GetUsageAnalytics 按时间桶（hour/day）和维度（model/channel/user/group）汇总用量
+func GetUsageAnalytics(c *gin.Context) {
+	startTimestamp, endTimestamp, ok := parseFlowQuotaTimeRange(c)
+	if !ok {
+		return
+	}
+	groupBy := c.DefaultQuery("group_by", model.UsageAnalyticsGroupByModel)
+	if !model.IsValidUsageAnalyticsGroupBy(groupBy) {
+		common.ApiErrorMsg(c, "invalid group_by")
+		return
+	}
+	var bucketSize int64
+	switch c.DefaultQuery("bucket", "day") {
+	case "hour":
+		bucketSize = model.UsageAnalyticsBucketHour
+	case "day":
+		bucketSize = model.UsageAnalyticsBucketDay
+	default:
+		common.ApiErrorMsg(c, "invalid bucket")
+		return
+	}
+	rows, err := model.GetUsageAnalytics(startTimestamp, endTimestamp, groupBy, bucketSize)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    rows,
+	})
+}
@@ -9,6 +9,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/dto"
@@ -19,6 +20,7 @@ import (
 	"github.com/QuantumNous/new-api/service/authz"
 	"github.com/QuantumNous/new-api/setting"
 	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/setting/system_setting"
 
 	"github.com/QuantumNous/new-api/constant"
 
@@ -149,7 +151,24 @@ func setupLogin(user *model.User, c *gin.Context) {
 	session.Set("role", user.Role)
 	session.Set("status", user.Status)
 	session.Set("group", user.Group)
-	err := session.Save()
+	// 登记会话，用户可在设备管理中查看和注销
+	userSession, err := model.CreateUserSession(user.Id, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	session.Set("sid", userSession.Id)
+	// 要求管理员启用 2FA 但尚未启用时，只能访问 2FA 设置接口（由鉴权中间件按用户记录拦截），
+	// 这里仅提示前端跳转到 2FA 设置
+	twoFASetupRequired := false
+	if user.Role >= common.RoleAdminUser && system_setting.GetTwoFASettings().RequireForAdmin {
+		enabled, err := model.IsTwoFAEnabled(user.Id)
+		if err != nil {
+			common.SysLog(fmt.Sprintf("setupLogin failed to load 2FA status for user %d: %v", user.Id, err))
+		}
+		twoFASetupRequired = !enabled
+	}
+	err = session.Save()
 	if err != nil {
 		common.ApiErrorI18n(c, i18n.MsgUserSessionSaveFailed)
 		return
@@ -159,18 +178,24 @@ func setupLogin(user *model.User, c *gin.Context) {
 		"message": "",
 		"success": true,
 		"data": map[string]any{
-			"id":           user.Id,
-			"username":     user.Username,
-			"display_name": user.DisplayName,
-			"role":         user.Role,
-			"status":       user.Status,
-			"group":        user.Group,
+			"id":                user.Id,
+			"username":          user.Username,
+			"display_name":      user.DisplayName,
+			"role":              user.Role,
+			"status":            user.Status,
+			"group":             user.Group,
+			"require_2fa_setup": twoFASetupRequired,
 		},
 	})
 }
 
 func Logout(c *gin.Context) {
 	session := sessions.Default(c)
+	if sessionId, ok := session.Get("sid").(string); ok && sessionId != "" {
+		if userId, ok := session.Get("id").(int); ok {
+			_ = model.RevokeUserSession(userId, sessionId)
+		}
+	}
 	session.Clear()
 	err := session.Save()
 	if err != nil {
@@ -716,10 +741,20 @@ func UpdateUser(c *gin.Context) {
 	if err := model.InvalidateUserCache(updatedUser.Id); err != nil {
 		common.SysLog(fmt.Sprintf("failed to invalidate user cache for user %d: %s", updatedUser.Id, err.Error()))
 	}
-	recordManageAuditFor(c, updatedUser.Id, "user.update", map[string]interface{}{
+	var changes map[string]model.AuditChange
+	if reloadedUser, err := model.GetUserById(updatedUser.Id, false); err == nil {
+		changes = model.BuildAuditChanges(originUser, reloadedUser)
+	}
+	if updatePassword {
+		if changes == nil {
+			changes = map[string]model.AuditChange{}
+		}
+		changes["password"] = model.AuditChange{Before: "***", After: "***"}
+	}
+	recordManageAuditChanges(c, updatedUser.Id, "user.update", map[string]interface{}{
 		"username": originUser.Username,
 		"id":       updatedUser.Id,
-	})
+	}, changes)
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -946,11 +981,29 @@ func DeleteSelf(c *gin.Context) {
 		return
 	}
 
-	err := model.DeleteUserById(id)
+	// 注销第二步：需先提交注销申请，冷静期结束后才能确认
+	request, err := model.GetAccountDeletionRequest(id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if request == nil {
+		common.ApiErrorMsg(c, "请先提交注销申请")
+		return
+	}
+	if common.GetTimestamp() < request.EffectiveAt {
+		common.ApiErrorMsg(c, fmt.Sprintf("注销冷静期未结束，请于 %s 后再确认", time.Unix(request.EffectiveAt, 0).Format("2006-01-02 15:04:05")))
+		return
+	}
+
+	err = model.AnonymizeAndDeleteUser(id)
 	if err != nil {
 		common.ApiError(c, err)
 		return
 	}
+	session := sessions.Default(c)
+	session.Clear()
+	_ = session.Save()
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -1063,6 +1116,20 @@ func ManageUser(c *gin.Context) {
 		return
 	}
 	switch req.Action {
+	case "force_logout":
+		// 注销用户在所有设备上的登录会话，不影响其访问令牌
+		count, err := model.RevokeUserSessions(user.Id, "")
+		if err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		recordManageAuditFor(c, user.Id, "user.force_logout", map[string]interface{}{
+			"username": user.Username,
+			"id":       user.Id,
+			"count":    count,
+		})
+		common.ApiSuccess(c, gin.H{"count": count})
+		return
 	case "disable":
 		user.Status = common.UserStatusDisabled
 		if user.Role == common.RoleRootUser {
@@ -1140,10 +1207,10 @@ func ManageUser(c *gin.Context) {
 				common.ApiError(c, err)
 				return
 			}
-			recordManageAuditFor(c, user.Id, "user.quota_override", map[string]interface{}{
+			recordManageAuditChanges(c, user.Id, "user.quota_override", map[string]interface{}{
 				"from": logger.LogQuota(oldQuota),
 				"to":   logger.LogQuota(req.Value),
-			})
+			}, map[string]model.AuditChange{"quota": {Before: oldQuota, After: req.Value}})
 		default:
 			common.ApiErrorI18n(c, i18n.MsgInvalidParams)
 			return
@@ -1183,6 +1250,12 @@ func ManageUser(c *gin.Context) {
 	// 避免在 Redis TTL 过期前仍使用旧状态（尤其是禁用后仍可发起请求的问题）。
 	// InvalidateUserCache 会让下一次 GetUserCache 从数据库重新加载，
 	// InvalidateUserTokensCache 则确保令牌侧的缓存也同步刷新。
+	// 会话 cookie 中保存了状态和角色，禁用、删除或调整角色后注销其全部会话，要求重新登录
+	if req.Action == "disable" || req.Action == "delete" || req.Action == "promote" || req.Action == "demote" {
+		if _, err := model.RevokeUserSessions(user.Id, ""); err != nil {
+			common.SysLog(fmt.Sprintf("failed to revoke sessions for user %d: %s", user.Id, err.Error()))
+		}
+	}
 	if req.Action == "disable" || req.Action == "promote" || req.Action == "demote" {
 		if err := model.InvalidateUserCache(user.Id); err != nil {
 			common.SysLog(fmt.Sprintf("failed to invalidate user cache for user %d: %s", user.Id, err.Error()))
@@ -1322,6 +1395,10 @@ func TopUp(c *gin.Context) {
 		logger.LogError(c, fmt.Sprintf("failed to redeem key %s for user %d: %s", req.Key, id, err.Error()))
 		return
 	}
+	service.PublishWebhookEvent(service.WebhookEventRedemptionUsed, map[string]any{
+		"user_id": id,
+		"quota":   quota,
+	})
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -1342,6 +1419,7 @@ type UpdateUserSettingRequest struct {
 	UpstreamModelUpdateNotifyEnabled *bool   `json:"upstream_model_update_notify_enabled,omitempty"`
 	AcceptUnsetModelRatioModel       bool    `json:"accept_unset_model_ratio_model"`
 	RecordIpLog                      bool    `json:"record_ip_log"`
+	CheckinReminderDisabled          *bool   `json:"checkin_reminder_disabled,omitempty"`
 }
 
 func UpdateUserSetting(c *gin.Context) {
@@ -1436,6 +1514,10 @@ func UpdateUserSetting(c *gin.Context) {
 	if user.Role >= common.RoleAdminUser && req.UpstreamModelUpdateNotifyEnabled != nil {
 		upstreamModelUpdateNotifyEnabled = *req.UpstreamModelUpdateNotifyEnabled
 	}
+	checkinReminderDisabled := existingSettings.CheckinReminderDisabled
+	if req.CheckinReminderDisabled != nil {
+		checkinReminderDisabled = *req.CheckinReminderDisabled
+	}
 
 	// 构建设置
 	settings := dto.UserSetting{
@@ -1444,6 +1526,7 @@ func UpdateUserSetting(c *gin.Context) {
 		UpstreamModelUpdateNotifyEnabled: upstreamModelUpdateNotifyEnabled,
 		AcceptUnsetRatioModel:            req.AcceptUnsetModelRatioModel,
 		RecordIpLog:                      req.RecordIpLog,
+		CheckinReminderDisabled:          checkinReminderDisabled,
 	}
 
 	// 如果是webhook类型,添加webhook相关设置
@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/i18n"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportSelfData 导出当前用户的个人数据归档（zip），包含资料、令牌（密钥脱敏）、
+// 消费日志、登录日志、签到记录、充值记录和登录会话
+func ExportSelfData(c *gin.Context) {
+	user, err := model.GetUserById(c.GetInt("id"), false)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=user_data_%d_%s.zip", user.Id, time.Now().Format("20060102")))
+	if err := service.WriteUserDataExport(c.Request.Context(), c.Writer, user); err != nil {
+		// 响应已经开始输出，只能记录错误并中断
+		common.SysError(fmt.Sprintf("failed to export data of user %d: %v", user.Id, err))
+		c.Abort()
+	}
+}
+
+// GetSelfDeletionRequest 查询当前用户的注销申请状态
+func GetSelfDeletionRequest(c *gin.Context) {
+	request, err := model.GetAccountDeletionRequest(c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"request":       request,
+		"cool_off_days": operation_setting.GetAccountDeletionSetting().CoolOffDays,
+	})
+}
+
+type selfDeletionRequest struct {
+	Username string `json:"username"` // 需输入自己的用户名确认
+}
+
+// RequestSelfDeletion 注销第一步：提交注销申请，进入冷静期
+func RequestSelfDeletion(c *gin.Context) {
+	var req selfDeletionRequest
+	if err := common.DecodeJson(c.Request.Body, &req); err != nil {
+		common.ApiErrorMsg(c, "参数错误")
+		return
+	}
+	user, err := model.GetUserById(c.GetInt("id"), false)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if user.Role == common.RoleRootUser {
+		common.ApiErrorI18n(c, i18n.MsgUserCannotDeleteRootUser)
+		return
+	}
+	if req.Username != user.Username {
+		common.ApiErrorMsg(c, "用户名不匹配")
+		return
+	}
+	coolOffSeconds := int64(operation_setting.GetAccountDeletionSetting().CoolOffDays) * 24 * 3600
+	request, err := model.CreateAccountDeletionRequest(user.Id, coolOffSeconds)
+	if err != nil {
+		if errors.Is(err, model.ErrAccountDeletionRequestExists) {
+			common.ApiErrorMsg(c, err.Error())
+			return
+		}
+		common.ApiError(c, err)
+		return
+	}
+	model.RecordLog(user.Id, model.LogTypeSystem, fmt.Sprintf("申请注销账号，冷静期至 %s", time.Unix(request.EffectiveAt, 0).Format("2006-01-02 15:04:05")))
+	common.ApiSuccess(c, request)
+}
+
+// CancelSelfDeletion 冷静期内撤销注销申请
+func CancelSelfDeletion(c *gin.Context) {
+	userId := c.GetInt("id")
+	if err := model.CancelAccountDeletionRequest(userId); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	model.RecordLog(userId, model.LogTypeSystem, "撤销注销账号申请")
+	common.ApiSuccess(c, nil)
+}
@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+type userSessionItem struct {
+	*model.UserSession
+	Current bool `json:"current"`
+}
+
+func listUserSessions(c *gin.Context, userId int, currentSessionId string) {
+	sessions, err := model.GetUserSessions(userId)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	items := make([]userSessionItem, 0, len(sessions))
+	for _, session := range sessions {
+		items = append(items, userSessionItem{
+			UserSession: session,
+			Current:     currentSessionId != "" && session.Id == currentSessionId,
+		})
+	}
+	common.ApiSuccess(c, items)
+}
+
+// GetSelfSessions 列出当前用户已登录的设备（会话），标记当前会话
+func GetSelfSessions(c *gin.Context) {
+	listUserSessions(c, c.GetInt("id"), c.GetString("session_id"))
+}
+
+// RevokeSelfSession 注销当前用户的指定会话，对应设备下次请求时需要重新登录
+func RevokeSelfSession(c *gin.Context) {
+	if err := model.RevokeUserSession(c.GetInt("id"), c.Param("id")); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
+
+// RevokeOtherSelfSessions 注销当前用户除当前会话外的全部会话
+func RevokeOtherSelfSessions(c *gin.Context) {
+	currentSessionId := c.GetString("session_id")
+	if currentSessionId == "" {
+		// 通过访问令牌调用时没有当前会话，避免误注销全部会话
+		common.ApiErrorMsg(c, "请通过网页登录后操作")
+		return
+	}
+	count, err := model.RevokeUserSessions(c.GetInt("id"), currentSessionId)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{"count": count})
+}
+
+// GetUserSessionsByAdmin 管理员查看用户已登录的设备，强制下线通过 /api/user/manage 的 force_logout 操作
+func GetUserSessionsByAdmin(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiErrorMsg(c, "无效的用户 ID")
+		return
+	}
+	user := &model.User{Id: id}
+	if err := user.FillUserById(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if !canManageTargetRole(c.GetInt("role"), user.Role) {
+		common.ApiErrorMsg(c, "no permission")
+		return
+	}
+	listUserSessions(c, id, "")
+}
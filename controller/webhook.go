@@ -0,0 +1,172 @@
+package controller
+
+import (
+	"errors"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// validateWebhookEndpoint 校验推送地址的名称、URL 和订阅事件，并规范化事件列表
+func validateWebhookEndpoint(endpoint *model.WebhookEndpoint) error {
+	if utf8.RuneCountInString(endpoint.Name) == 0 || utf8.RuneCountInString(endpoint.Name) > 64 {
+		return errors.New("名称长度应在 1-64 之间")
+	}
+	parsed, err := url.Parse(strings.TrimSpace(endpoint.Url))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return errors.New("推送地址必须是有效的 http(s) URL")
+	}
+	endpoint.Url = parsed.String()
+	events := endpoint.EventList()
+	for _, event := range events {
+		if !slices.Contains(service.WebhookEvents, event) {
+			return errors.New("不支持的事件: " + event)
+		}
+	}
+	endpoint.Events = strings.Join(events, ",")
+	if endpoint.Status != model.WebhookEndpointStatusDisabled {
+		endpoint.Status = model.WebhookEndpointStatusEnabled
+	}
+	return nil
+}
+
+// GetWebhookEndpoints 返回全部推送地址和可订阅的事件，不返回签名密钥
+func GetWebhookEndpoints(c *gin.Context) {
+	endpoints, err := model.GetAllWebhookEndpoints()
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	for _, endpoint := range endpoints {
+		endpoint.Secret = ""
+	}
+	common.ApiSuccess(c, gin.H{
+		"endpoints": endpoints,
+		"events":    service.WebhookEvents,
+	})
+}
+
+func CreateWebhookEndpoint(c *gin.Context) {
+	endpoint := model.WebhookEndpoint{}
+	if err := c.ShouldBindJSON(&endpoint); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := validateWebhookEndpoint(&endpoint); err != nil {
+		common.ApiErrorMsg(c, err.Error())
+		return
+	}
+	endpoint.Id = 0
+	if err := endpoint.Insert(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	recordManageAudit(c, "webhook.create", map[string]interface{}{
+		"id":     endpoint.Id,
+		"name":   endpoint.Name,
+		"events": endpoint.Events,
+	})
+	endpoint.Secret = ""
+	common.ApiSuccess(c, endpoint)
+}
+
+// UpdateWebhookEndpoint 更新推送地址，secret 留空表示保留原密钥
+func UpdateWebhookEndpoint(c *gin.Context) {
+	endpoint := model.WebhookEndpoint{}
+	if err := c.ShouldBindJSON(&endpoint); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	origin, err := model.GetWebhookEndpointById(endpoint.Id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := validateWebhookEndpoint(&endpoint); err != nil {
+		common.ApiErrorMsg(c, err.Error())
+		return
+	}
+	if err := endpoint.Update(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	updated, err := model.GetWebhookEndpointById(endpoint.Id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	recordManageAuditChanges(c, 0, "webhook.update", map[string]interface{}{
+		"id":   updated.Id,
+		"name": updated.Name,
+	}, model.BuildAuditChanges(origin, updated))
+	updated.Secret = ""
+	common.ApiSuccess(c, updated)
+}
+
+func DeleteWebhookEndpoint(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	endpoint, err := model.GetWebhookEndpointById(id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := model.DeleteWebhookEndpointById(id); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	recordManageAudit(c, "webhook.delete", map[string]interface{}{
+		"id":   endpoint.Id,
+		"name": endpoint.Name,
+	})
+	common.ApiSuccess(c, nil)
+}
+
+// TestWebhookEndpoint 同步发送一条测试事件（不重试），返回本次推送记录
+func TestWebhookEndpoint(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	endpoint, err := model.GetWebhookEndpointById(id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	delivery := service.DeliverWebhookEvent(endpoint, service.WebhookEvent{
+		Id:        common.GetUUID(),
+		Event:     service.WebhookEventTest,
+		Timestamp: common.GetTimestamp(),
+		Data: map[string]any{
+			"endpoint_id":   endpoint.Id,
+			"endpoint_name": endpoint.Name,
+		},
+	}, 1)
+	common.ApiSuccess(c, delivery)
+}
+
+// GetWebhookDeliveries 分页查询推送记录，支持按 endpoint_id 和 event 过滤
+func GetWebhookDeliveries(c *gin.Context) {
+	endpointId, _ := strconv.Atoi(c.Query("endpoint_id"))
+	pageInfo := common.GetPageQuery(c)
+	deliveries, total, err := model.GetWebhookDeliveries(endpointId, c.Query("event"), pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(deliveries)
+	common.ApiSuccess(c, pageInfo)
+}
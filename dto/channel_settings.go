@@ -33,26 +33,84 @@ const (
 	AwsKeyTypeApiKey AwsKeyType = "api_key"
 )
 
+// StreamOptionsMode 控制流式请求是否注入 stream_options.include_usage，留空时按渠道类型判断
+type StreamOptionsMode string
+
+const (
+	StreamOptionsModeEnabled  StreamOptionsMode = "enabled"  // 强制注入，适用于支持 usage 块的 OpenAI 兼容上游
+	StreamOptionsModeDisabled StreamOptionsMode = "disabled" // 不注入，适用于拒绝该参数的上游，用量改为本地计算
+)
+
+// JsonSchemaShimMode 控制是否为不支持 response_format json_schema 的上游模拟结构化输出，留空时按渠道类型判断
+type JsonSchemaShimMode string
+
+const (
+	JsonSchemaShimModeEnabled  JsonSchemaShimMode = "enabled"  // 强制模拟，适用于忽略 json_schema 的 OpenAI 兼容上游
+	JsonSchemaShimModeDisabled JsonSchemaShimMode = "disabled" // 不模拟，原样转发
+)
+
 type ChannelOtherSettings struct {
-	AzureResponsesVersion                 string                `json:"azure_responses_version,omitempty"`
-	VertexKeyType                         VertexKeyType         `json:"vertex_key_type,omitempty"` // "json" or "api_key"
-	OpenRouterEnterprise                  *bool                 `json:"openrouter_enterprise,omitempty"`
-	ClaudeBetaQuery                       bool                  `json:"claude_beta_query,omitempty"`          // Claude 渠道是否强制追加 ?beta=true
-	AllowServiceTier                      bool                  `json:"allow_service_tier,omitempty"`         // 是否允许 service_tier 透传（默认过滤以避免额外计费）
-	AllowInferenceGeo                     bool                  `json:"allow_inference_geo,omitempty"`        // 是否允许 inference_geo 透传（仅 Claude，默认过滤以满足数据驻留合规
-	AllowSpeed                            bool                  `json:"allow_speed,omitempty"`                // 是否允许 speed 透传（仅 Claude，默认过滤以避免意外切换推理速度模式）
-	AllowSafetyIdentifier                 bool                  `json:"allow_safety_identifier,omitempty"`    // 是否允许 safety_identifier 透传（默认过滤以保护用户隐私）
-	DisableStore                          bool                  `json:"disable_store,omitempty"`              // 是否禁用 store 透传（默认允许透传，禁用后可能导致 Codex 无法使用）
-	AllowIncludeObfuscation               bool                  `json:"allow_include_obfuscation,omitempty"`  // 是否允许 stream_options.include_obfuscation 透传（默认过滤以避免关闭流混淆保护）
-	DisableTaskPollingSleep               bool                  `json:"disable_task_polling_sleep,omitempty"` // 是否跳过异步任务轮询间隔
-	AwsKeyType                            AwsKeyType            `json:"aws_key_type,omitempty"`
-	UpstreamModelUpdateCheckEnabled       bool                  `json:"upstream_model_update_check_enabled,omitempty"`        // 是否检测上游模型更新
-	UpstreamModelUpdateAutoSyncEnabled    bool                  `json:"upstream_model_update_auto_sync_enabled,omitempty"`    // 是否自动同步上游模型更新
-	UpstreamModelUpdateLastCheckTime      int64                 `json:"upstream_model_update_last_check_time,omitempty"`      // 上次检测时间
-	UpstreamModelUpdateLastDetectedModels []string              `json:"upstream_model_update_last_detected_models,omitempty"` // 上次检测到的可加入模型
-	UpstreamModelUpdateLastRemovedModels  []string              `json:"upstream_model_update_last_removed_models,omitempty"`  // 上次检测到的可删除模型
-	UpstreamModelUpdateIgnoredModels      []string              `json:"upstream_model_update_ignored_models,omitempty"`       // 手动忽略的模型
-	AdvancedCustom                        *AdvancedCustomConfig `json:"advanced_custom,omitempty"`
+	AzureResponsesVersion                 string                       `json:"azure_responses_version,omitempty"`
+	VertexKeyType                         VertexKeyType                `json:"vertex_key_type,omitempty"` // "json" or "api_key"
+	OpenRouterEnterprise                  *bool                        `json:"openrouter_enterprise,omitempty"`
+	ClaudeBetaQuery                       bool                         `json:"claude_beta_query,omitempty"`          // Claude 渠道是否强制追加 ?beta=true
+	AllowServiceTier                      bool                         `json:"allow_service_tier,omitempty"`         // 是否允许 service_tier 透传（默认过滤以避免额外计费）
+	AllowInferenceGeo                     bool                         `json:"allow_inference_geo,omitempty"`        // 是否允许 inference_geo 透传（仅 Claude，默认过滤以满足数据驻留合规
+	AllowSpeed                            bool                         `json:"allow_speed,omitempty"`                // 是否允许 speed 透传（仅 Claude，默认过滤以避免意外切换推理速度模式）
+	AllowSafetyIdentifier                 bool                         `json:"allow_safety_identifier,omitempty"`    // 是否允许 safety_identifier 透传（默认过滤以保护用户隐私）
+	DisableStore                          bool                         `json:"disable_store,omitempty"`              // 是否禁用 store 透传（默认允许透传，禁用后可能导致 Codex 无法使用）
+	AllowIncludeObfuscation               bool                         `json:"allow_include_obfuscation,omitempty"`  // 是否允许 stream_options.include_obfuscation 透传（默认过滤以避免关闭流混淆保护）
+	DisableTaskPollingSleep               bool                         `json:"disable_task_polling_sleep,omitempty"` // 是否跳过异步任务轮询间隔
+	AwsKeyType                            AwsKeyType                   `json:"aws_key_type,omitempty"`
+	UpstreamModelUpdateCheckEnabled       bool                         `json:"upstream_model_update_check_enabled,omitempty"`        // 是否检测上游模型更新
+	UpstreamModelUpdateAutoSyncEnabled    bool                         `json:"upstream_model_update_auto_sync_enabled,omitempty"`    // 是否自动同步上游模型更新
+	UpstreamModelUpdateLastCheckTime      int64                        `json:"upstream_model_update_last_check_time,omitempty"`      // 上次检测时间
+	UpstreamModelUpdateLastDetectedModels []string                     `json:"upstream_model_update_last_detected_models,omitempty"` // 上次检测到的可加入模型
+	UpstreamModelUpdateLastRemovedModels  []string                     `json:"upstream_model_update_last_removed_models,omitempty"`  // 上次检测到的可删除模型
+	UpstreamModelUpdateIgnoredModels      []string                     `json:"upstream_model_update_ignored_models,omitempty"`       // 手动忽略的模型
+	AdvancedCustom                        *AdvancedCustomConfig        `json:"advanced_custom,omitempty"`
+	Mock                                  *MockChannelConfig           `json:"mock,omitempty"`
+	RetryPolicy                           *ChannelRetryPolicy          `json:"retry_policy,omitempty"` // 渠道级重试策略，未设置时使用全局重试配置
+	StreamOptionsMode                     StreamOptionsMode            `json:"stream_options_mode,omitempty"`
+	ModelPrices                           map[string]ChannelModelPrice `json:"model_prices,omitempty"`     // 渠道级模型价格覆盖，键为用户请求的模型名
+	BodyLogEnabled                        bool                         `json:"body_log_enabled,omitempty"` // 记录该渠道的请求/响应体，需开启 body_log_setting.enabled
+	ErrorOverrides                        []ChannelErrorOverride       `json:"error_overrides,omitempty"`  // 上游错误信息改写规则，按顺序匹配第一条
+	JsonSchemaShimMode                    JsonSchemaShimMode           `json:"json_schema_shim_mode,omitempty"`
+	JsonSchemaShimRetry                   bool                         `json:"json_schema_shim_retry,omitempty"` // 模拟结构化输出时，校验失败后带上错误信息重试一次
+	MaxConcurrency                        int                          `json:"max_concurrency,omitempty"`        // 单节点同时转发到该渠道的请求数上限，0 表示不限制
+}
+
+// ChannelErrorOverride 按正则匹配上游错误信息，替换返回给用户的错误
+type ChannelErrorOverride struct {
+	Pattern    string `json:"pattern"`               // 匹配上游错误信息的正则表达式
+	Message    string `json:"message"`               // 替换后的错误信息，可用 $1 引用捕获组
+	Code       string `json:"code,omitempty"`        // 替换后的错误码，为空时按状态码归一化
+	StatusCode int    `json:"status_code,omitempty"` // 替换后的状态码，为空时保持不变
+}
+
+// ChannelModelPrice 渠道级模型价格覆盖，设置 ModelPrice 时按次计费，否则按倍率计费；
+// 未设置的字段使用全局配置
+type ChannelModelPrice struct {
+	ModelPrice      *float64 `json:"model_price,omitempty"`
+	ModelRatio      *float64 `json:"model_ratio,omitempty"`
+	CompletionRatio *float64 `json:"completion_ratio,omitempty"`
+}
+
+// ChannelRetryPolicy overrides the global retry behaviour after a request
+// fails on this channel. Zero values fall back to the global settings.
+type ChannelRetryPolicy struct {
+	RetryTimes   *int   `json:"retry_times,omitempty"`    // 在该渠道失败后允许的最大重试次数
+	StatusCodes  string `json:"status_codes,omitempty"`   // 可重试的状态码范围，如 "429,500-599"
+	BackoffMs    int    `json:"backoff_ms,omitempty"`     // 首次重试前的等待时间，之后每次翻倍
+	MaxBackoffMs int    `json:"max_backoff_ms,omitempty"` // 单次等待上限
+}
+
+// MockChannelConfig shapes the synthetic responses of the benchmark (mock)
+// channel type. Zero values fall back to the adaptor defaults.
+type MockChannelConfig struct {
+	FirstTokenLatencyMs int `json:"first_token_latency_ms,omitempty"`
+	TokensPerSecond     int `json:"tokens_per_second,omitempty"`
+	CompletionTokens    int `json:"completion_tokens,omitempty"`
 }
 
 func (s *ChannelOtherSettings) IsOpenRouterEnterprise() bool {
@@ -13,6 +13,9 @@ const (
 	NotifyTypeQuotaExceed   = "quota_exceed"
 	NotifyTypeChannelUpdate = "channel_update"
 	NotifyTypeChannelTest   = "channel_test"
+	NotifyTypeCheckin       = "checkin"
+	NotifyTypeGroupChange   = "group_change"
+	NotifyTypeTopUp         = "topup"
 )
 
 func NewNotify(t string, title string, content string, values []interface{}) Notify {
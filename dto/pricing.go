@@ -9,6 +9,27 @@ type OpenAIModels struct {
 	Created                int                     `json:"created"`
 	OwnedBy                string                  `json:"owned_by"`
 	SupportedEndpointTypes []constant.EndpointType `json:"supported_endpoint_types"`
+	Pricing                *ModelEffectivePrice    `json:"pricing,omitempty"`
+	Availability           *ModelAvailability      `json:"availability,omitempty"`
+}
+
+// ModelEffectivePrice 模型在调用方分组下的实际价格（已乘分组倍率），价格单位为美元
+type ModelEffectivePrice struct {
+	Group          string   `json:"group"` // 计费使用的分组
+	GroupRatio     float64  `json:"group_ratio"`
+	QuotaType      int      `json:"quota_type"` // 0 按量计费，1 按次计费
+	BillingMode    string   `json:"billing_mode,omitempty"`
+	InputPrice     *float64 `json:"input_price,omitempty"`      // 每百万输入 tokens
+	OutputPrice    *float64 `json:"output_price,omitempty"`     // 每百万输出 tokens
+	CacheReadPrice *float64 `json:"cache_read_price,omitempty"` // 每百万缓存命中 tokens
+	RequestPrice   *float64 `json:"request_price,omitempty"`    // 每次请求
+}
+
+// ModelAvailability 模型在调用方分组下的渠道可用情况
+type ModelAvailability struct {
+	Available       bool `json:"available"`
+	Channels        int  `json:"channels"`         // 已启用的渠道数
+	HealthyChannels int  `json:"healthy_channels"` // 熔断器未打开的渠道数
 }
 
 type AnthropicModel struct {
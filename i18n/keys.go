@@ -55,6 +55,9 @@ const (
 	MsgTokenExhausted            = "token.exhausted"
 	MsgTokenStatusUnavailable    = "token.status_unavailable"
 	MsgTokenDbError              = "token.db_error"
+	MsgTokenIpNotAllowed         = "token.ip_not_allowed"
+	MsgTokenAllowIpsInvalid      = "token.allow_ips_invalid"
+	MsgTokenModelMappingInvalid  = "token.model_mapping_invalid"
 )
 
 // Redemption related messages
@@ -69,6 +72,9 @@ const (
 	MsgRedemptionFailed            = "redemption.failed"
 	MsgRedemptionNotProvided       = "redemption.not_provided"
 	MsgRedemptionExpireTimeInvalid = "redemption.expire_time_invalid"
+	MsgRedemptionPrefixInvalid     = "redemption.prefix_invalid"
+	MsgRedemptionMaxUsesInvalid    = "redemption.max_uses_invalid"
+	MsgRedemptionGroupInvalid      = "redemption.group_invalid"
 )
 
 // User related messages
@@ -208,10 +214,24 @@ const (
 
 // Checkin related messages
 const (
-	MsgCheckinDisabled     = "checkin.disabled"
-	MsgCheckinAlreadyToday = "checkin.already_today"
-	MsgCheckinFailed       = "checkin.failed"
-	MsgCheckinQuotaFailed  = "checkin.quota_failed"
+	MsgCheckinDisabled       = "checkin.disabled"
+	MsgCheckinAlreadyToday   = "checkin.already_today"
+	MsgCheckinFailed         = "checkin.failed"
+	MsgCheckinQuotaFailed    = "checkin.quota_failed"
+	MsgCheckinSuccess        = "checkin.success"
+	MsgCheckinInvalidDate    = "checkin.invalid_date"
+	MsgCheckinInvalidMonth   = "checkin.invalid_month"
+	MsgCheckinIpLimited      = "checkin.ip_limited"
+	MsgCheckinDateTaken      = "checkin.date_taken"
+	MsgCheckinMakeupSuccess  = "checkin.makeup_success"
+	MsgCheckinMakeupDisabled = "checkin.makeup_disabled"
+	MsgCheckinMakeupNotPast  = "checkin.makeup_not_past"
+	MsgCheckinMakeupExpired  = "checkin.makeup_expired"
+	MsgCheckinMakeupLimit    = "checkin.makeup_limit"
+	MsgCheckinMakeupQuota    = "checkin.makeup_quota"
+	MsgCheckinMakeupFailed   = "checkin.makeup_failed"
+	MsgCheckinCaptchaNeeded  = "checkin.captcha_needed"
+	MsgCheckinTaskIncomplete = "checkin.task_incomplete"
 )
 
 // Passkey related messages
@@ -230,6 +250,8 @@ const (
 	MsgTwoFAAlreadyExists = "twofa.already_exists"
 	MsgTwoFARecordIdEmpty = "twofa.record_id_empty"
 	MsgTwoFACodeInvalid   = "twofa.code_invalid"
+	MsgTwoFASetupRequired = "twofa.setup_required"
+	MsgTwoFARequired      = "twofa.required"
 )
 
 // Rate limit related messages
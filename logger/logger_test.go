@@ -0,0 +1,49 @@
+package logger
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatQuota(t *testing.T) {
+	setting := operation_setting.GetGeneralSetting()
+	original := *setting
+	originalRate := operation_setting.USDExchangeRate
+	t.Cleanup(func() {
+		*setting = original
+		operation_setting.USDExchangeRate = originalRate
+	})
+	operation_setting.USDExchangeRate = 7
+	quota := int(common.QuotaPerUnit * 1.5)
+
+	tests := []struct {
+		name        string
+		displayType string
+		symbol      string
+		rate        float64
+		decimals    int
+		wantFormat  string
+		wantLog     string
+	}{
+		{name: "usd keeps legacy precision", displayType: operation_setting.QuotaDisplayTypeUSD, decimals: 6, wantFormat: "＄1.500000", wantLog: "＄1.500000 额度"},
+		{name: "cny uses exchange rate", displayType: operation_setting.QuotaDisplayTypeCNY, decimals: 2, wantFormat: "¥10.50", wantLog: "¥10.50 额度"},
+		{name: "custom currency", displayType: operation_setting.QuotaDisplayTypeCustom, symbol: "€", rate: 0.9, decimals: 3, wantFormat: "€1.350", wantLog: "€1.350 额度"},
+		{name: "custom currency without rate falls back to 1", displayType: operation_setting.QuotaDisplayTypeCustom, symbol: "€", decimals: 1, wantFormat: "€1.5", wantLog: "€1.5 额度"},
+		{name: "decimal places are clamped", displayType: operation_setting.QuotaDisplayTypeUSD, decimals: -1, wantFormat: "＄2", wantLog: "＄2 额度"},
+		{name: "tokens ignore currency settings", displayType: operation_setting.QuotaDisplayTypeTokens, decimals: 2, wantFormat: "750000", wantLog: "750000 点额度"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setting.QuotaDisplayType = tt.displayType
+			setting.CustomCurrencySymbol = tt.symbol
+			setting.CustomCurrencyExchangeRate = tt.rate
+			setting.CurrencyDecimalPlaces = tt.decimals
+			assert.Equal(t, tt.wantFormat, FormatQuota(quota))
+			assert.Equal(t, tt.wantLog, LogQuota(quota))
+		})
+	}
+}
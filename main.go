@@ -15,6 +15,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/QuantumNous/new-api/cli"
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/controller"
@@ -24,6 +25,7 @@ import (
 	"github.com/QuantumNous/new-api/model"
 	"github.com/QuantumNous/new-api/oauth"
 	perfmetrics "github.com/QuantumNous/new-api/pkg/perf_metrics"
+	"github.com/QuantumNous/new-api/pkg/tracing"
 	"github.com/QuantumNous/new-api/relay"
 	"github.com/QuantumNous/new-api/router"
 	"github.com/QuantumNous/new-api/service"
@@ -53,6 +55,10 @@ var classicBuildFS embed.FS
 var classicIndexPage []byte
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "admin" {
+		os.Exit(cli.Execute(os.Args[2:]))
+	}
+
 	startTime := time.Now()
 
 	err := InitResources()
@@ -99,6 +105,9 @@ func main() {
 			model.InitChannelCache()
 		}()
 
+		// Reload immediately when another replica mutates channels; the
+		// periodic sync remains the fallback for missed events.
+		common.StartCacheInvalidationListener()
 		go model.SyncChannelCache(common.SyncFrequency)
 	}
 
@@ -112,8 +121,12 @@ func main() {
 	// 周期性重载授权策略，保证多节点/多 master 部署下权限变更能传播到每个实例
 	go authz.StartPolicySync(common.SyncFrequency)
 
+	// Elect a single master to run cron-style background jobs; the jobs below
+	// check leadership on every tick so failover needs no restart.
+	service.StartLeaderElection()
+
 	// 数据看板
-	go model.UpdateQuotaData()
+	go model.UpdateQuotaData(service.IsBackgroundLeader)
 
 	if os.Getenv("CHANNEL_UPDATE_FREQUENCY") != "" {
 		frequency, err := strconv.Atoi(os.Getenv("CHANNEL_UPDATE_FREQUENCY"))
@@ -129,6 +142,12 @@ func main() {
 	// Subscription quota reset task (daily/weekly/monthly/custom)
 	service.StartSubscriptionQuotaResetTask()
 
+	// Expiring quota grants (e.g. time-limited check-in rewards)
+	service.StartQuotaGrantTask()
+
+	// Monthly per-user statements for the previous month
+	service.StartStatementTask()
+
 	// Report this process as a system instance so the System Info page can show
 	// all currently alive nodes in multi-instance deployments.
 	service.StartSystemInstanceReporter()
@@ -158,6 +177,15 @@ func main() {
 		model.InitBatchUpdater()
 	}
 
+	if os.Getenv("REDIS_QUOTA_ENABLED") == "true" {
+		if common.RedisEnabled {
+			common.RedisQuotaEnabled = true
+			model.InitRedisQuotaSync()
+		} else {
+			common.SysError("REDIS_QUOTA_ENABLED requires REDIS_CONN_STRING, falling back to database quota")
+		}
+	}
+
 	if os.Getenv("ENABLE_PPROF") == "true" {
 		gopool.Go(func() {
 			log.Println(http.ListenAndServe("0.0.0.0:8005", nil))
@@ -185,6 +213,7 @@ func main() {
 	// This will cause SSE not to work!!!
 	//server.Use(gzip.Gzip(gzip.DefaultCompression))
 	server.Use(middleware.RequestId())
+	server.Use(middleware.RejectWhenDraining())
 	server.Use(middleware.Version())
 	server.Use(middleware.I18n())
 	middleware.SetUpLogger(server)
@@ -192,7 +221,7 @@ func main() {
 	store := cookie.NewStore([]byte(common.SessionSecret))
 	store.Options(sessions.Options{
 		Path:     "/",
-		MaxAge:   2592000, // 30 days
+		MaxAge:   model.UserSessionMaxAgeSeconds, // 30 days
 		HttpOnly: true,
 		Secure:   common.SessionCookieSecure,
 		SameSite: http.SameSiteStrictMode,
@@ -225,6 +254,11 @@ func main() {
 		}
 	}()
 
+	grpcServer, err := router.StartAdminGrpcServer()
+	if err != nil {
+		common.FatalLog("failed to start gRPC admin server: " + err.Error())
+	}
+
 	time.Sleep(100 * time.Millisecond)
 
 	common.LogStartupSuccess(startTime, port)
@@ -234,13 +268,44 @@ func main() {
 	sig := <-quit
 	common.SysLog(fmt.Sprintf("received signal: %v, shutting down...", sig))
 
-	// SSE streams may run for minutes; give them time to finish before forced exit
+	// Drain: reject new requests (so load balancers fail over) and stop
+	// keep-alives, then let in-flight requests finish. SSE streams may run for
+	// minutes, so the drain window is configurable.
+	common.SetShuttingDown()
+	srv.SetKeepAlivesEnabled(false)
+	common.SysLog(fmt.Sprintf("draining %d in-flight relay requests", middleware.GetStats().ActiveConnections))
+
 	shutdownTimeout := time.Duration(common.GetEnvOrDefault("SHUTDOWN_TIMEOUT_SECONDS", 120)) * time.Second
 	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 	defer cancel()
 	if err := srv.Shutdown(ctx); err != nil {
-		common.SysError(fmt.Sprintf("server forced to shutdown: %v", err))
+		common.SysError(fmt.Sprintf("server forced to shutdown with %d relay requests still active: %v", middleware.GetStats().ActiveConnections, err))
+	}
+	if grpcServer != nil {
+		grpcServer.GracefulStop()
 	}
+	service.ResignLeadership()
+
+	// Requests that finished during the drain, or were cut off by it, may
+	// still be writing refunds and metric samples in the background. Give
+	// them a short window of their own so the flushes below include them.
+	writesCtx, writesCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	if !common.WaitPendingWrites(writesCtx) {
+		common.SysError("shutdown timed out waiting for background billing writes")
+	}
+	writesCancel()
+
+	// Flush everything buffered in memory so billing and metrics survive the
+	// restart.
+	if common.BatchUpdateEnabled {
+		model.FlushBatchUpdates()
+	}
+	model.FlushUserQuotaWriteBehind()
+	perfmetrics.FlushAll()
+	model.FlushLogSink()
+	tracingCtx, tracingCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	tracing.Shutdown(tracingCtx)
+	tracingCancel()
 	// 内存中的看板数据保存入库，避免重启丢失未落库数据 (issue #5679)
 	if common.DataExportEnabled {
 		model.SaveQuotaDataCache()
@@ -339,6 +404,7 @@ func InitResources() error {
 	if err != nil {
 		return err
 	}
+	model.InitLogSink()
 
 	// Initialize Redis
 	err = common.InitRedisClient()
@@ -348,6 +414,10 @@ func InitResources() error {
 
 	perfmetrics.Init()
 
+	if err = tracing.Init(); err != nil {
+		return err
+	}
+
 	// 启动系统监控
 	common.StartSystemMonitor()
 
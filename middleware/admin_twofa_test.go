@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/i18n"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthRequiresAdminTwoFAOnEveryRequest(t *testing.T) {
+	require.NoError(t, i18n.Init())
+	gin.SetMode(gin.TestMode)
+	settings := system_setting.GetTwoFASettings()
+	t.Cleanup(func() {
+		settings.RequireForAdmin = false
+		model.DB.Exec("DELETE FROM user_sessions")
+		model.DB.Exec("DELETE FROM users")
+		model.DB.Exec("DELETE FROM two_fas")
+	})
+	admin := &model.User{Id: 1, Username: "admin", Password: "password", Role: common.RoleAdminUser, Status: common.UserStatusEnabled, AccessToken: common.GetPointer("admin-access-token")}
+	require.NoError(t, model.DB.Create(admin).Error)
+
+	router := gin.New()
+	router.Use(sessions.Sessions("session", cookie.NewStore([]byte("admin-twofa-test"))))
+	// 模拟在开启 2FA 要求之前登录的会话
+	router.GET("/login", func(c *gin.Context) {
+		userSession, err := model.CreateUserSession(admin.Id, c.ClientIP(), c.Request.UserAgent())
+		require.NoError(t, err)
+		session := sessions.Default(c)
+		session.Set("username", admin.Username)
+		session.Set("role", admin.Role)
+		session.Set("id", admin.Id)
+		session.Set("status", admin.Status)
+		session.Set("sid", userSession.Id)
+		require.NoError(t, session.Save())
+	})
+	router.GET("/api/test", UserAuth(), func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/api/user/self/2fa/status", UserAuth(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/login", nil))
+	cookies := recorder.Result().Cookies()
+
+	request := func(path string, useAccessToken bool) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("New-Api-User", "1")
+		if useAccessToken {
+			req.Header.Set("Authorization", "admin-access-token")
+		} else {
+			for _, c := range cookies {
+				req.AddCookie(c)
+			}
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request("/api/test", false), "not required yet")
+
+	settings.RequireForAdmin = true
+	for _, useAccessToken := range []bool{false, true} {
+		assert.Equal(t, http.StatusForbidden, request("/api/test", useAccessToken), "access token: %v", useAccessToken)
+		assert.Equal(t, http.StatusOK, request("/api/user/self/2fa/status", useAccessToken), "setup paths stay reachable")
+	}
+
+	require.NoError(t, model.DB.Create(&model.TwoFA{UserId: admin.Id, Secret: "secret", IsEnabled: true}).Error)
+	for _, useAccessToken := range []bool{false, true} {
+		assert.Equal(t, http.StatusOK, request("/api/test", useAccessToken), "access token: %v", useAccessToken)
+	}
+}
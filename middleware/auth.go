@@ -13,13 +13,18 @@ import (
 	"github.com/QuantumNous/new-api/i18n"
 	"github.com/QuantumNous/new-api/logger"
 	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/pkg/tracing"
 	"github.com/QuantumNous/new-api/service"
 	"github.com/QuantumNous/new-api/service/authz"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
 	"github.com/QuantumNous/new-api/setting/ratio_setting"
+	"github.com/QuantumNous/new-api/setting/system_setting"
 	"github.com/QuantumNous/new-api/types"
 
+	"github.com/bytedance/gopkg/util/gopool"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
 	"gorm.io/gorm"
 )
 
@@ -34,6 +39,44 @@ func validUserInfo(username string, role int) bool {
 	return true
 }
 
+// twoFASetupPaths 半认证会话（需先启用 2FA）仍可访问的接口
+var twoFASetupPaths = map[string]bool{
+	"GET /api/user/self":             true,
+	"GET /api/user/self/2fa/status":  true,
+	"POST /api/user/self/2fa/setup":  true,
+	"POST /api/user/self/2fa/enable": true,
+}
+
+// checkUserSession 校验会话是否仍登记在会话表中，已被注销的会话会被清除。
+// 旧版本签发的 cookie 没有会话 id，无法被强制下线或随封禁、角色变更注销，一律要求重新登录
+func checkUserSession(c *gin.Context, session sessions.Session, userId int) bool {
+	sessionId, _ := session.Get("sid").(string)
+	if sessionId == "" {
+		session.Clear()
+		_ = session.Save()
+		return false
+	}
+	valid, err := model.IsUserSessionValid(sessionId, userId)
+	if err != nil {
+		// 数据库异常时放行，避免所有用户被登出
+		common.SysLog(fmt.Sprintf("failed to validate session for user %d: %v", userId, err))
+		return true
+	}
+	if !valid {
+		session.Clear()
+		_ = session.Save()
+		return false
+	}
+	ip := c.ClientIP()
+	gopool.Go(func() {
+		if err := model.TouchUserSession(sessionId, ip); err != nil {
+			common.SysLog("failed to update session activity: " + err.Error())
+		}
+	})
+	c.Set("session_id", sessionId)
+	return true
+}
+
 func authHelper(c *gin.Context, minRole int) {
 	session := sessions.Default(c)
 	username := session.Get("username")
@@ -41,6 +84,14 @@ func authHelper(c *gin.Context, minRole int) {
 	id := session.Get("id")
 	status := session.Get("status")
 	useAccessToken := false
+	if username != nil && !checkUserSession(c, session, id.(int)) {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": common.TranslateMessage(c, i18n.MsgAuthNotLoggedIn),
+		})
+		c.Abort()
+		return
+	}
 	if username == nil {
 		// Check access token
 		accessToken := c.Request.Header.Get("Authorization")
@@ -145,6 +196,29 @@ func authHelper(c *gin.Context, minRole int) {
 		c.Abort()
 		return
 	}
+	// 管理员被要求启用 2FA 但尚未启用时，只允许访问 2FA 设置相关接口。
+	// 每次请求都按用户记录检查，会话 cookie 与访问令牌一视同仁，设置开启前签发的会话同样受限
+	if role.(int) >= common.RoleAdminUser && system_setting.GetTwoFASettings().RequireForAdmin &&
+		!twoFASetupPaths[c.Request.Method+" "+c.FullPath()] {
+		enabled, err := model.IsTwoFAEnabled(id.(int))
+		if err != nil {
+			common.SysLog(fmt.Sprintf("failed to load 2FA status for user %d: %v", id.(int), err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": common.TranslateMessage(c, i18n.MsgDatabaseError),
+			})
+			c.Abort()
+			return
+		}
+		if !enabled {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"message": common.TranslateMessage(c, i18n.MsgTwoFASetupRequired),
+			})
+			c.Abort()
+			return
+		}
+	}
 	// 防止不同newapi版本冲突，导致数据不通用
 	c.Header("Auth-Version", "864b7076dbcd0a3c01b5520316720ebf")
 	c.Set("username", username)
@@ -171,7 +245,7 @@ func TryUserAuth() func(c *gin.Context) {
 	return func(c *gin.Context) {
 		session := sessions.Default(c)
 		id := session.Get("id")
-		if id != nil {
+		if id != nil && checkUserSession(c, session, id.(int)) {
 			c.Set("id", id)
 		}
 		c.Next()
@@ -222,7 +296,7 @@ func TokenOrUserAuth() func(c *gin.Context) {
 	return func(c *gin.Context) {
 		// Try session auth first (dashboard users)
 		session := sessions.Default(c)
-		if id := session.Get("id"); id != nil {
+		if id := session.Get("id"); id != nil && checkUserSession(c, session, id.(int)) {
 			if status, ok := session.Get("status").(int); ok && status == common.UserStatusEnabled {
 				c.Set("id", id)
 				c.Next()
@@ -313,6 +387,9 @@ func TokenAuthReadOnly() func(c *gin.Context) {
 
 func TokenAuth() func(c *gin.Context) {
 	return func(c *gin.Context) {
+		// 鉴权 span 在进入后续处理前结束，提前返回时由 defer 结束（End 可重复调用）
+		span := tracing.StartSpan(c, "auth.token")
+		defer span.End()
 		// 先检测是否为ws
 		if c.Request.Header.Get("Sec-WebSocket-Protocol") != "" {
 			// Sec-WebSocket-Protocol: realtime, openai-insecure-api-key.sk-xxx, openai-beta.realtime-v1
@@ -386,17 +463,14 @@ func TokenAuth() func(c *gin.Context) {
 			return
 		}
 
-		allowIps := token.GetIpLimits()
-		if len(allowIps) > 0 {
+		if allowlist := token.GetIpAllowlist(); allowlist != nil {
 			clientIp := c.ClientIP()
 			logger.LogDebug(c, "Token has IP restrictions, checking client IP %s", clientIp)
 			ip := net.ParseIP(clientIp)
-			if ip == nil {
-				abortWithOpenAiMessage(c, http.StatusForbidden, "无法解析客户端 IP 地址")
-				return
-			}
-			if common.IsIpInCIDRList(ip, allowIps) == false {
-				abortWithOpenAiMessage(c, http.StatusForbidden, "您的 IP 不在令牌允许访问的列表中", types.ErrorCodeAccessDenied)
+			if ip == nil || !allowlist.Contains(ip) {
+				logger.LogWarn(c, fmt.Sprintf("token %d (user %d) rejected client IP %q: not in token IP allowlist", token.Id, token.UserId, clientIp))
+				abortWithOpenAiMessage(c, http.StatusForbidden,
+					common.TranslateMessage(c, i18n.MsgTokenIpNotAllowed, map[string]any{"Ip": clientIp}), types.ErrorCodeAccessDenied)
 				return
 			}
 			logger.LogDebug(c, "Client IP %s passed the token IP restrictions check", clientIp)
@@ -440,6 +514,8 @@ func TokenAuth() func(c *gin.Context) {
 		if err != nil {
 			return
 		}
+		span.SetAttributes(attribute.Int("user.id", token.UserId), attribute.Int("token.id", token.Id))
+		span.End()
 		c.Next()
 	}
 }
@@ -462,8 +538,13 @@ func SetupContextForToken(c *gin.Context, token *model.Token, parts ...string) e
 	} else {
 		c.Set("token_model_limit_enabled", false)
 	}
+	common.SetContextKey(c, constant.ContextKeyTokenModelMapping, token.GetModelMapping())
 	common.SetContextKey(c, constant.ContextKeyTokenGroup, token.Group)
 	common.SetContextKey(c, constant.ContextKeyTokenCrossGroupRetry, token.CrossGroupRetry)
+	common.SetContextKey(c, constant.ContextKeyTokenRpmLimit, token.RpmLimit)
+	common.SetContextKey(c, constant.ContextKeyTokenTpmLimit, token.TpmLimit)
+	common.SetContextKey(c, constant.ContextKeyTokenMaxConcurrency, token.MaxConcurrency)
+	common.SetContextKey(c, constant.ContextKeyTokenBodyLogEnabled, token.BodyLogEnabled)
 	if len(parts) > 1 {
 		if model.IsAdmin(token.UserId) {
 			c.Set("specific_channel_id", parts[1])
@@ -475,3 +556,14 @@ func SetupContextForToken(c *gin.Context, token *model.Token, parts ...string) e
 	}
 	return nil
 }
+
+// ModelStatusAuth 模型状态页：开启公开访问时无需鉴权，否则需要使用令牌访问
+func ModelStatusAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if operation_setting.GetMonitorSetting().ModelStatusPublic {
+			c.Next()
+			return
+		}
+		TokenAuth()(c)
+	}
+}
@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+)
+
+// bodyLogResponseWriter 将响应体复制到有限大小的缓冲区，超出 maxSize 的部分丢弃并标记截断
+type bodyLogResponseWriter struct {
+	gin.ResponseWriter
+	body      *bytes.Buffer
+	maxSize   int
+	truncated bool
+}
+
+func (w *bodyLogResponseWriter) Write(b []byte) (int, error) {
+	if remain := w.maxSize - w.body.Len(); remain >= len(b) {
+		w.body.Write(b)
+	} else {
+		if remain > 0 {
+			w.body.Write(b[:remain])
+		}
+		w.truncated = true
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyLogResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// BodyLog 在全局开关开启且令牌或所选渠道选择记录时，保存脱敏压缩后的请求/响应体，
+// 需挂在 Distribute 之后以便读取渠道设置
+func BodyLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setting := operation_setting.GetBodyLogSetting()
+		if !setting.Enabled || !shouldCaptureBody(c) {
+			c.Next()
+			return
+		}
+		maxSize := setting.MaxBodyBytes
+		if maxSize <= 0 {
+			maxSize = 256 * 1024
+		}
+		writer := &bodyLogResponseWriter{
+			ResponseWriter: c.Writer,
+			body:           bytes.NewBuffer(nil),
+			maxSize:        maxSize,
+		}
+		c.Writer = writer
+
+		c.Next()
+
+		var requestBody []byte
+		requestTruncated := false
+		if storage, err := common.GetBodyStorage(c); err == nil {
+			requestBody, _ = io.ReadAll(io.LimitReader(storage, int64(maxSize)+1))
+			if len(requestBody) > maxSize {
+				requestBody = requestBody[:maxSize]
+				requestTruncated = true
+			}
+		}
+		log := &model.BodyLog{
+			CreatedAt:         common.GetTimestamp(),
+			RequestId:         c.GetString(common.RequestIdKey),
+			UserId:            c.GetInt("id"),
+			TokenId:           c.GetInt("token_id"),
+			ChannelId:         c.GetInt("channel_id"),
+			ModelName:         c.GetString("original_model"),
+			Path:              c.Request.URL.Path,
+			StatusCode:        writer.Status(),
+			RequestTruncated:  requestTruncated,
+			ResponseTruncated: writer.truncated,
+		}
+		responseBody := writer.body.Bytes()
+		gopool.Go(func() {
+			service.RecordBodyLog(log, requestBody, responseBody)
+		})
+	}
+}
+
+func shouldCaptureBody(c *gin.Context) bool {
+	if common.GetContextKeyBool(c, constant.ContextKeyTokenBodyLogEnabled) {
+		return true
+	}
+	channelSetting, ok := common.GetContextKeyType[dto.ChannelOtherSettings](c, constant.ContextKeyChannelOtherSetting)
+	return ok && channelSetting.BodyLogEnabled
+}
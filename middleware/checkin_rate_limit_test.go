@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckinRateLimit_PerUser(t *testing.T) {
+	originalRedis := common.RedisEnabled
+	originalEnable := common.CheckinRateLimitEnable
+	originalNum := common.CheckinRateLimitNum
+	t.Cleanup(func() {
+		common.RedisEnabled = originalRedis
+		common.CheckinRateLimitEnable = originalEnable
+		common.CheckinRateLimitNum = originalNum
+	})
+	common.RedisEnabled = false
+	common.CheckinRateLimitNum = 2
+
+	tests := []struct {
+		name      string
+		enabled   bool
+		userId    int
+		wantCodes []int
+	}{
+		{name: "limited", enabled: true, userId: 9101, wantCodes: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}},
+		{name: "other user has its own window", enabled: true, userId: 9102, wantCodes: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}},
+		{name: "anonymous", enabled: true, wantCodes: []int{http.StatusUnauthorized}},
+		{name: "disabled", enabled: false, userId: 9103, wantCodes: []int{http.StatusOK, http.StatusOK, http.StatusOK}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			common.CheckinRateLimitEnable = tt.enabled
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				if tt.userId != 0 {
+					c.Set("id", tt.userId)
+				}
+			})
+			router.POST("/v1/dashboard/checkin", CheckinRateLimit(), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			for i, want := range tt.wantCodes {
+				recorder := httptest.NewRecorder()
+				router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/v1/dashboard/checkin", nil))
+				require.Equal(t, want, recorder.Code, "request %d", i+1)
+			}
+		})
+	}
+}
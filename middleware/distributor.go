@@ -55,6 +55,13 @@ func Distribute() func(c *gin.Context) {
 			}
 		} else {
 			// Select a channel for the user
+			// resolve token model alias; the model limit applies to the name the client requested
+			requestedModel := modelRequest.Model
+			if tokenModelMapping, ok := common.GetContextKeyType[map[string]string](c, constant.ContextKeyTokenModelMapping); ok {
+				if target := tokenModelMapping[modelRequest.Model]; target != "" {
+					modelRequest.Model = target
+				}
+			}
 			// check token model mapping
 			modelLimitEnable := common.GetContextKeyBool(c, constant.ContextKeyTokenModelLimitEnabled)
 			if modelLimitEnable {
@@ -69,9 +76,9 @@ func Distribute() func(c *gin.Context) {
 				if !ok {
 					tokenModelLimit = map[string]bool{}
 				}
-				matchName := ratio_setting.FormatMatchingModelName(modelRequest.Model) // match gpts & thinking-*
+				matchName := ratio_setting.FormatMatchingModelName(requestedModel) // match gpts & thinking-*
 				if _, ok := tokenModelLimit[matchName]; !ok {
-					abortWithOpenAiMessage(c, http.StatusForbidden, i18n.T(c, i18n.MsgDistributorTokenModelForbidden, map[string]any{"Model": modelRequest.Model}))
+					abortWithOpenAiMessage(c, http.StatusForbidden, i18n.T(c, i18n.MsgDistributorTokenModelForbidden, map[string]any{"Model": requestedModel}))
 					return
 				}
 			}
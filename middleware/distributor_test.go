@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/i18n"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDistribute_ModelLimitChecksRequestedAlias(t *testing.T) {
+	require.NoError(t, i18n.Init())
+
+	tests := []struct {
+		name      string
+		modelName string
+		limit     map[string]bool
+	}{
+		{name: "alias target listed but alias not listed", modelName: "gpt-4o", limit: map[string]bool{"upstream-model": true}},
+		{name: "neither alias nor target listed", modelName: "gpt-4o", limit: map[string]bool{"claude-3": true}},
+		{name: "unaliased model not listed", modelName: "gpt-3.5-turbo", limit: map[string]bool{"upstream-model": true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				common.SetContextKey(c, constant.ContextKeyTokenModelMapping, map[string]string{"gpt-4o": "upstream-model"})
+				common.SetContextKey(c, constant.ContextKeyTokenModelLimitEnabled, true)
+				common.SetContextKey(c, constant.ContextKeyTokenModelLimit, tt.limit)
+			})
+			router.POST("/v1/chat/completions", Distribute(), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			body := `{"model":"` + tt.modelName + `","messages":[]}`
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+			request.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(recorder, request)
+
+			assert.Equal(t, http.StatusForbidden, recorder.Code)
+			assert.Contains(t, recorder.Body.String(), tt.modelName)
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/gin-gonic/gin"
+)
+
+// RejectWhenDraining turns new requests away with 503 once shutdown has begun,
+// so load balancers and health checks move traffic to another node while
+// in-flight streams on this one finish.
+func RejectWhenDraining() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !common.IsShuttingDown() {
+			c.Next()
+			return
+		}
+		c.Header("Connection", "close")
+		c.Header("Retry-After", "5")
+		abortWithOpenAiMessage(c, http.StatusServiceUnavailable, "server is shutting down, please retry")
+	}
+}
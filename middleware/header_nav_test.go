@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-contrib/sessions/cookie"
 	"github.com/gin-gonic/gin"
@@ -41,7 +42,10 @@ func performHeaderNavRequest(t *testing.T, handler gin.HandlerFunc, authenticate
 	router := gin.New()
 	router.Use(sessions.Sessions("session", cookie.NewStore([]byte("header-nav-test"))))
 	router.GET("/login", func(c *gin.Context) {
+		userSession, err := model.CreateUserSession(1, c.ClientIP(), c.Request.UserAgent())
+		require.NoError(t, err)
 		session := sessions.Default(c)
+		session.Set("sid", userSession.Id)
 		session.Set("username", "tester")
 		session.Set("role", common.RoleCommonUser)
 		session.Set("id", 1)
@@ -203,3 +203,13 @@ func SearchRateLimit() func(c *gin.Context) {
 	}
 	return userRateLimitFactory(common.SearchRateLimitNum, common.SearchRateLimitDuration, "SR")
 }
+
+// CheckinRateLimit returns a per-user rate limiter for the token-authenticated
+// check-in endpoints, so scripted clients cannot hammer them.
+// Configurable via CHECKIN_RATE_LIMIT_ENABLE / CHECKIN_RATE_LIMIT / CHECKIN_RATE_LIMIT_DURATION.
+func CheckinRateLimit() func(c *gin.Context) {
+	if !common.CheckinRateLimitEnable {
+		return defNext
+	}
+	return userRateLimitFactory(common.CheckinRateLimitNum, common.CheckinRateLimitDuration, "CI")
+}
@@ -0,0 +1,243 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+const tokenRateLimitWindow = time.Minute
+
+// tokenRpmScript 滑动窗口请求计数：有序集合按请求时间（毫秒）记分，
+// 超限时返回最早一条记录移出窗口还需等待的毫秒数，未超限时记录本次请求并返回 0
+var tokenRpmScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], 0, now - window)
+if redis.call('ZCARD', KEYS[1]) >= limit then
+	local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+	return tonumber(oldest[2]) + window - now
+end
+redis.call('ZADD', KEYS[1], now, ARGV[4])
+redis.call('PEXPIRE', KEYS[1], window)
+return 0
+`)
+
+// tokenTpmScript 滑动窗口 token 计数：哈希表按秒累计消耗的 token 数，
+// 窗口内合计达到上限时返回最早一秒移出窗口还需等待的毫秒数，否则返回 0
+var tokenTpmScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+local fields = redis.call('HGETALL', KEYS[1])
+local total = 0
+local oldest = nil
+for i = 1, #fields, 2 do
+	local second = tonumber(fields[i])
+	if second <= now - window then
+		redis.call('HDEL', KEYS[1], fields[i])
+	else
+		total = total + tonumber(fields[i + 1])
+		if oldest == nil or second < oldest then
+			oldest = second
+		end
+	end
+end
+if total >= limit and oldest ~= nil then
+	return (oldest + window - now) * 1000
+end
+return 0
+`)
+
+// tokenRateWindow 未启用 Redis 时单个令牌在本机的滑动窗口
+type tokenRateWindow struct {
+	requests []int64    // 请求时间（毫秒）
+	tokens   [][2]int64 // [时间（毫秒）, token 数]
+}
+
+var (
+	tokenRateWindows     = map[int]*tokenRateWindow{}
+	tokenRateWindowsMu   sync.Mutex
+	tokenRateJanitorOnce sync.Once
+)
+
+// pruneTokenRateWindow 移除窗口外的记录，返回窗口内的 token 合计
+func pruneTokenRateWindow(w *tokenRateWindow, now int64) int64 {
+	cutoff := now - tokenRateLimitWindow.Milliseconds()
+	i := 0
+	for i < len(w.requests) && w.requests[i] <= cutoff {
+		i++
+	}
+	w.requests = w.requests[i:]
+	j := 0
+	for j < len(w.tokens) && w.tokens[j][0] <= cutoff {
+		j++
+	}
+	w.tokens = w.tokens[j:]
+	var total int64
+	for _, usage := range w.tokens {
+		total += usage[1]
+	}
+	return total
+}
+
+// startTokenRateJanitor 定期清理已空的本机窗口，避免不再使用的令牌常驻内存
+func startTokenRateJanitor() {
+	tokenRateJanitorOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(tokenRateLimitWindow)
+			defer ticker.Stop()
+			for range ticker.C {
+				now := time.Now().UnixMilli()
+				tokenRateWindowsMu.Lock()
+				for tokenId, w := range tokenRateWindows {
+					pruneTokenRateWindow(w, now)
+					if len(w.requests) == 0 && len(w.tokens) == 0 {
+						delete(tokenRateWindows, tokenId)
+					}
+				}
+				tokenRateWindowsMu.Unlock()
+			}
+		}()
+	})
+}
+
+// checkMemoryTokenRateLimit 检查本机窗口，返回需等待的毫秒数和超限类型；未超限时记录本次请求
+func checkMemoryTokenRateLimit(tokenId int, rpm int, tpm int) (int64, string) {
+	startTokenRateJanitor()
+	now := time.Now().UnixMilli()
+	window := tokenRateLimitWindow.Milliseconds()
+	tokenRateWindowsMu.Lock()
+	defer tokenRateWindowsMu.Unlock()
+	w, ok := tokenRateWindows[tokenId]
+	if !ok {
+		w = &tokenRateWindow{}
+		tokenRateWindows[tokenId] = w
+	}
+	total := pruneTokenRateWindow(w, now)
+	if rpm > 0 && len(w.requests) >= rpm {
+		return w.requests[0] + window - now, "requests"
+	}
+	if tpm > 0 && total >= int64(tpm) && len(w.tokens) > 0 {
+		return w.tokens[0][0] + window - now, "tokens"
+	}
+	if rpm > 0 {
+		w.requests = append(w.requests, now)
+	}
+	return 0, ""
+}
+
+func recordMemoryTokenUsage(tokenId int, used int) {
+	tokenRateWindowsMu.Lock()
+	defer tokenRateWindowsMu.Unlock()
+	w, ok := tokenRateWindows[tokenId]
+	if !ok {
+		w = &tokenRateWindow{}
+		tokenRateWindows[tokenId] = w
+	}
+	w.tokens = append(w.tokens, [2]int64{time.Now().UnixMilli(), int64(used)})
+}
+
+// checkRedisTokenRateLimit 与 checkMemoryTokenRateLimit 相同，基于 Redis 在多节点间共享窗口
+func checkRedisTokenRateLimit(ctx context.Context, tokenId int, rpm int, tpm int) (int64, string, error) {
+	now := time.Now()
+	if tpm > 0 {
+		wait, err := tokenTpmScript.Run(ctx, common.RDB, []string{fmt.Sprintf("tokenRateLimit:tpm:%d", tokenId)},
+			now.Unix(), int64(tokenRateLimitWindow.Seconds()), tpm).Int64()
+		if err != nil {
+			return 0, "", err
+		}
+		if wait > 0 {
+			return wait, "tokens", nil
+		}
+	}
+	if rpm > 0 {
+		wait, err := tokenRpmScript.Run(ctx, common.RDB, []string{fmt.Sprintf("tokenRateLimit:rpm:%d", tokenId)},
+			now.UnixMilli(), tokenRateLimitWindow.Milliseconds(), rpm, common.GetUUID()).Int64()
+		if err != nil {
+			return 0, "", err
+		}
+		if wait > 0 {
+			return wait, "requests", nil
+		}
+	}
+	return 0, "", nil
+}
+
+func recordRedisTokenUsage(ctx context.Context, tokenId int, used int) error {
+	key := fmt.Sprintf("tokenRateLimit:tpm:%d", tokenId)
+	pipe := common.RDB.TxPipeline()
+	pipe.HIncrBy(ctx, key, strconv.FormatInt(time.Now().Unix(), 10), int64(used))
+	pipe.Expire(ctx, key, tokenRateLimitWindow+time.Second)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// TokenRateLimit 令牌级 RPM/TPM 限流中间件，需放在 TokenAuth 之后。
+// RPM 在请求进入时计数；TPM 在请求进入时检查窗口内已消耗的 token 数，
+// 请求结束后再按 RecordConsumeLog 累计的实际用量记账，因此单个请求可能使窗口略微超出上限。
+// 超限时返回 OpenAI 兼容的 429 响应并带上 Retry-After
+func TokenRateLimit() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		rpm := common.GetContextKeyInt(c, constant.ContextKeyTokenRpmLimit)
+		tpm := common.GetContextKeyInt(c, constant.ContextKeyTokenTpmLimit)
+		tokenId := common.GetContextKeyInt(c, constant.ContextKeyTokenId)
+		if (rpm <= 0 && tpm <= 0) || tokenId == 0 {
+			c.Next()
+			return
+		}
+
+		ctx := context.Background()
+		var wait int64
+		var limitType string
+		if common.RedisEnabled {
+			var err error
+			wait, limitType, err = checkRedisTokenRateLimit(ctx, tokenId, rpm, tpm)
+			if err != nil {
+				common.SysError("token rate limit check failed: " + err.Error())
+				abortWithOpenAiMessage(c, http.StatusInternalServerError, "rate_limit_check_failed")
+				return
+			}
+		} else {
+			wait, limitType = checkMemoryTokenRateLimit(tokenId, rpm, tpm)
+		}
+		if wait > 0 {
+			retryAfter := int(math.Ceil(float64(wait) / 1000))
+			c.Header("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+			limit := rpm
+			if limitType == "tokens" {
+				limit = tpm
+			}
+			abortWithOpenAiMessage(c, http.StatusTooManyRequests,
+				fmt.Sprintf("Rate limit reached for %s per minute on this token: limit %d, please try again in %ds", limitType, limit, max(retryAfter, 1)),
+				types.ErrorCodeRateLimitExceeded)
+			return
+		}
+
+		c.Next()
+
+		used := common.GetContextKeyInt(c, constant.ContextKeyTokenUsedTokens)
+		if tpm <= 0 || used <= 0 {
+			return
+		}
+		if common.RedisEnabled {
+			if err := recordRedisTokenUsage(ctx, tokenId, used); err != nil {
+				common.SysError("failed to record token usage for rate limit: " + err.Error())
+			}
+		} else {
+			recordMemoryTokenUsage(tokenId, used)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenRateLimit_MemoryWindow(t *testing.T) {
+	originalRedis := common.RedisEnabled
+	t.Cleanup(func() {
+		common.RedisEnabled = originalRedis
+	})
+	common.RedisEnabled = false
+
+	tests := []struct {
+		name      string
+		tokenId   int
+		rpm       int
+		tpm       int
+		used      int
+		wantCodes []int
+	}{
+		{name: "unlimited", tokenId: 9001, used: 100, wantCodes: []int{http.StatusOK, http.StatusOK, http.StatusOK}},
+		{name: "rpm", tokenId: 9002, rpm: 2, wantCodes: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}},
+		{name: "tpm", tokenId: 9003, tpm: 150, used: 100, wantCodes: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				common.SetContextKey(c, constant.ContextKeyTokenId, tt.tokenId)
+				common.SetContextKey(c, constant.ContextKeyTokenRpmLimit, tt.rpm)
+				common.SetContextKey(c, constant.ContextKeyTokenTpmLimit, tt.tpm)
+			})
+			router.POST("/v1/chat/completions", TokenRateLimit(), func(c *gin.Context) {
+				common.SetContextKey(c, constant.ContextKeyTokenUsedTokens, tt.used)
+				c.Status(http.StatusOK)
+			})
+
+			for i, want := range tt.wantCodes {
+				recorder := httptest.NewRecorder()
+				router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil))
+				require.Equal(t, want, recorder.Code, "request %d", i+1)
+				if want == http.StatusTooManyRequests {
+					assert.NotEmpty(t, recorder.Header().Get("Retry-After"))
+					assert.Contains(t, recorder.Body.String(), "rate_limit_exceeded")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/pkg/tracing"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracing_NestsChildSpansUnderRequestSpan(t *testing.T) {
+	originalProvider := otel.GetTracerProvider()
+	originalPropagator := otel.GetTextMapPropagator()
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(originalProvider)
+		otel.SetTextMapPropagator(originalPropagator)
+	})
+
+	const parentTraceId = "4bf92f3577b34da6a3ce929d0e0e4736"
+	tests := []struct {
+		name        string
+		traceparent string
+		status      int
+		wantError   bool
+	}{
+		{name: "new trace", status: http.StatusOK},
+		{name: "propagated parent", traceparent: "00-" + parentTraceId + "-00f067aa0ba902b7-01", status: http.StatusOK},
+		{name: "server error", status: http.StatusBadGateway, wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(Tracing())
+			router.POST("/v1/chat/completions", func(c *gin.Context) {
+				tracing.StartSpan(c, "auth.token").End()
+				tracing.StartSpan(c, "upstream.request").End()
+				c.Status(tt.status)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+			if tt.traceparent != "" {
+				req.Header.Set("traceparent", tt.traceparent)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			spans := recorder.Ended()
+			t.Cleanup(recorder.Reset)
+			require.Len(t, spans, 3)
+			root := spans[2]
+			assert.Equal(t, "POST /v1/chat/completions", root.Name())
+			for _, child := range spans[:2] {
+				assert.Equal(t, root.SpanContext().SpanID(), child.Parent().SpanID(), child.Name())
+				assert.Equal(t, root.SpanContext().TraceID(), child.SpanContext().TraceID(), child.Name())
+			}
+			if tt.traceparent != "" {
+				assert.Equal(t, parentTraceId, root.SpanContext().TraceID().String())
+			}
+			if tt.wantError {
+				assert.Equal(t, codes.Error, root.Status().Code)
+			} else {
+				assert.Equal(t, codes.Unset, root.Status().Code)
+			}
+		})
+	}
+}
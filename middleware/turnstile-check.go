@@ -6,6 +6,7 @@ import (
 	"net/url"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 )
@@ -15,13 +16,38 @@ type turnstileCheckResponse struct {
 }
 
 func TurnstileCheck() gin.HandlerFunc {
+	return turnstileCheck(false)
+}
+
+// CheckinTurnstileCheck guards the check-in endpoints. With
+// CheckinSetting.RequireCaptcha on, every check-in must carry a fresh
+// Turnstile token: the per-session pass used elsewhere would let a script
+// solve one challenge and then check in daily from the same session.
+func CheckinTurnstileCheck() gin.HandlerFunc {
+	sessionCheck := turnstileCheck(false)
+	strictCheck := turnstileCheck(true)
+	return func(c *gin.Context) {
+		if operation_setting.GetCheckinSetting().RequireCaptcha {
+			strictCheck(c)
+			return
+		}
+		sessionCheck(c)
+	}
+}
+
+// turnstileCheck verifies the Turnstile token in the "turnstile" query
+// parameter. Unless perRequest is set, a successful verification is remembered
+// in the session and later requests skip the challenge.
+func turnstileCheck(perRequest bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if common.TurnstileCheckEnabled {
 			session := sessions.Default(c)
-			turnstileChecked := session.Get("turnstile")
-			if turnstileChecked != nil {
-				c.Next()
-				return
+			if !perRequest {
+				turnstileChecked := session.Get("turnstile")
+				if turnstileChecked != nil {
+					c.Next()
+					return
+				}
 			}
 			response := c.Query("turnstile")
 			if response == "" {
@@ -66,14 +92,16 @@ func TurnstileCheck() gin.HandlerFunc {
 				c.Abort()
 				return
 			}
-			session.Set("turnstile", true)
-			err = session.Save()
-			if err != nil {
-				c.JSON(http.StatusOK, gin.H{
-					"message": "无法保存会话信息，请重试",
-					"success": false,
-				})
-				return
+			if !perRequest {
+				session.Set("turnstile", true)
+				err = session.Save()
+				if err != nil {
+					c.JSON(http.StatusOK, gin.H{
+						"message": "无法保存会话信息，请重试",
+						"success": false,
+					})
+					return
+				}
 			}
 		}
 		c.Next()
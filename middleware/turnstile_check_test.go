@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckinTurnstileCheck_RequireCaptchaIgnoresSessionPass(t *testing.T) {
+	originalEnabled := common.TurnstileCheckEnabled
+	setting := operation_setting.GetCheckinSetting()
+	originalRequire := setting.RequireCaptcha
+	t.Cleanup(func() {
+		common.TurnstileCheckEnabled = originalEnabled
+		setting.RequireCaptcha = originalRequire
+	})
+	common.TurnstileCheckEnabled = true
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(sessions.Sessions("session", cookie.NewStore([]byte("turnstile-test"))))
+	router.GET("/pass", func(c *gin.Context) {
+		session := sessions.Default(c)
+		session.Set("turnstile", true)
+		require.NoError(t, session.Save())
+		c.Status(http.StatusNoContent)
+	})
+	router.POST("/checkin", CheckinTurnstileCheck(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	})
+
+	passRecorder := httptest.NewRecorder()
+	router.ServeHTTP(passRecorder, httptest.NewRequest(http.MethodGet, "/pass", nil))
+	cookies := passRecorder.Result().Cookies()
+
+	tests := []struct {
+		name           string
+		requireCaptcha bool
+		wantSuccess    bool
+	}{
+		{name: "session pass is reused by default", requireCaptcha: false, wantSuccess: true},
+		{name: "require captcha demands a fresh token", requireCaptcha: true, wantSuccess: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setting.RequireCaptcha = tt.requireCaptcha
+			req := httptest.NewRequest(http.MethodPost, "/checkin", nil)
+			for _, cookie := range cookies {
+				req.AddCookie(cookie)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			var body struct {
+				Success bool `json:"success"`
+			}
+			require.NoError(t, common.Unmarshal(recorder.Body.Bytes(), &body))
+			assert.Equal(t, tt.wantSuccess, body.Success)
+		})
+	}
+}
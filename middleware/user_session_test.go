@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		panic("failed to open test db: " + err.Error())
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		panic("failed to get sql.DB: " + err.Error())
+	}
+	sqlDB.SetMaxOpenConns(1)
+	model.DB = db
+	model.LOG_DB = db
+	common.SetDatabaseTypes(common.DatabaseTypeSQLite, common.DatabaseTypeSQLite)
+	common.RedisEnabled = false
+	if err := db.AutoMigrate(&model.UserSession{}, &model.User{}, &model.TwoFA{}); err != nil {
+		panic("failed to migrate: " + err.Error())
+	}
+	os.Exit(m.Run())
+}
+
+func TestUserAuthRejectsRevokedSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Cleanup(func() { model.DB.Exec("DELETE FROM user_sessions") })
+	router := gin.New()
+	router.Use(sessions.Sessions("session", cookie.NewStore([]byte("user-session-test"))))
+	router.GET("/login", func(c *gin.Context) {
+		userSession, err := model.CreateUserSession(1, c.ClientIP(), c.Request.UserAgent())
+		require.NoError(t, err)
+		session := sessions.Default(c)
+		session.Set("username", "tester")
+		session.Set("role", common.RoleCommonUser)
+		session.Set("id", 1)
+		session.Set("status", common.UserStatusEnabled)
+		session.Set("sid", userSession.Id)
+		if c.Query("legacy") != "" {
+			session.Delete("sid")
+		}
+		require.NoError(t, session.Save())
+		c.String(http.StatusOK, userSession.Id)
+	})
+	router.GET("/api/test", UserAuth(), func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("session_id"))
+	})
+
+	login := func(query string) ([]*http.Cookie, string) {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/login"+query, nil))
+		require.Equal(t, http.StatusOK, recorder.Code)
+		return recorder.Result().Cookies(), recorder.Body.String()
+	}
+	request := func(cookies []*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/test", nil)
+		req.Header.Set("New-Api-User", "1")
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	cookies, sessionId := login("")
+	recorder := request(cookies)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, sessionId, recorder.Body.String())
+
+	require.NoError(t, model.RevokeUserSession(1, sessionId))
+	assert.Equal(t, http.StatusUnauthorized, request(cookies).Code, "revoked session is logged out")
+
+	// cookies issued before session registration cannot be revoked, so they must log in again
+	cookies, _ = login("?legacy=1")
+	assert.Equal(t, http.StatusUnauthorized, request(cookies).Code)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
 
 	"github.com/samber/lo"
 	"gorm.io/gorm"
@@ -40,6 +41,18 @@ func GetAllEnableAbilityWithChannels() ([]AbilityWithChannel, error) {
 	return abilities, err
 }
 
+// GetModelEnabledChannelTypes returns the distinct channel types that have an
+// enabled ability for the model, across all groups.
+func GetModelEnabledChannelTypes(modelName string) ([]int, error) {
+	var channelTypes []int
+	err := DB.Table("abilities").
+		Joins("join channels on abilities.channel_id = channels.id").
+		Where("abilities.model = ? and abilities.enabled = ?", modelName, true).
+		Distinct("channels.type").
+		Pluck("channels.type", &channelTypes).Error
+	return channelTypes, err
+}
+
 func GetGroupEnabledModels(group string) []string {
 	var models []string
 	// Find distinct models
@@ -122,8 +135,34 @@ func GetChannel(group string, model string, retry int, requestPath string) (*Cha
 		return nil, err
 	}
 	abilities = filterAbilitiesByRequestPathAndModel(abilities, requestPath, model)
+	abilities = filterAbilitiesByTagRouting(group, abilities)
+	if len(abilities) > 1 {
+		channelIds := make([]int, 0, len(abilities))
+		for _, ability_ := range abilities {
+			channelIds = append(channelIds, ability_.ChannelId)
+		}
+		if available := filterChannelIdsByBreaker(channelIds); len(available) < len(channelIds) {
+			allowed := make(map[int]bool, len(available))
+			for _, channelId := range available {
+				allowed[channelId] = true
+			}
+			filtered := make([]Ability, 0, len(available))
+			for _, ability_ := range abilities {
+				if allowed[ability_.ChannelId] {
+					filtered = append(filtered, ability_)
+				}
+			}
+			abilities = filtered
+		}
+	}
 	channel := Channel{}
-	if len(abilities) > 0 {
+	if strategy := operation_setting.GetChannelSelectStrategy(group, model); len(abilities) > 0 && strategy != operation_setting.ChannelSelectStrategyWeighted {
+		candidates := make([]*Channel, 0, len(abilities))
+		for _, ability_ := range abilities {
+			candidates = append(candidates, &Channel{Id: ability_.ChannelId, Weight: &ability_.Weight})
+		}
+		channel.Id = selectChannelInTier(strategy, candidates).Id
+	} else if len(abilities) > 0 {
 		// Randomly choose one
 		weightSum := uint(0)
 		for _, ability_ := range abilities {
@@ -386,6 +425,6 @@ func FixAbility() (int, int, error) {
 			}
 		}
 	}
-	InitChannelCache()
+	RefreshChannelCache()
 	return successCount, failCount, nil
 }
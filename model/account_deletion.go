@@ -0,0 +1,160 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"gorm.io/gorm"
+)
+
+var ErrAccountDeletionRequestExists = errors.New("已提交注销申请")
+
+// AccountDeletionRequest 用户的账号注销申请。申请后进入冷静期，冷静期内可撤销，
+// 冷静期结束后由用户再次确认才真正注销
+type AccountDeletionRequest struct {
+	UserId      int   `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	RequestedAt int64 `json:"requested_at" gorm:"bigint"`
+	EffectiveAt int64 `json:"effective_at" gorm:"bigint"` // 冷静期结束时间，此后可确认注销
+}
+
+func (AccountDeletionRequest) TableName() string {
+	return "account_deletion_requests"
+}
+
+// GetAccountDeletionRequest 获取用户的注销申请，不存在时返回 nil
+func GetAccountDeletionRequest(userId int) (*AccountDeletionRequest, error) {
+	var request AccountDeletionRequest
+	err := DB.Where("user_id = ?", userId).First(&request).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// CreateAccountDeletionRequest 提交注销申请，冷静期从当前时间开始计算
+func CreateAccountDeletionRequest(userId int, coolOffSeconds int64) (*AccountDeletionRequest, error) {
+	existing, err := GetAccountDeletionRequest(userId)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrAccountDeletionRequestExists
+	}
+	now := common.GetTimestamp()
+	request := &AccountDeletionRequest{
+		UserId:      userId,
+		RequestedAt: now,
+		EffectiveAt: now + coolOffSeconds,
+	}
+	if err := DB.Create(request).Error; err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// CancelAccountDeletionRequest 撤销注销申请
+func CancelAccountDeletionRequest(userId int) error {
+	result := DB.Where("user_id = ?", userId).Delete(&AccountDeletionRequest{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("没有待处理的注销申请")
+	}
+	return nil
+}
+
+// AnonymizedUsername 注销后用户名替换为该值，原用户名即可被重新注册
+func AnonymizedUsername(userId int) string {
+	return fmt.Sprintf("deleted_%d", userId)
+}
+
+// AnonymizeAndDeleteUser 注销账号：清除个人信息和第三方绑定、释放用户名、删除令牌、登录会话和
+// 记录的请求/响应正文，再软删除用户；日志中的用户名和 IP 被匿名化，消费记录本身保留用于对账
+func AnonymizeAndDeleteUser(userId int) error {
+	if userId == 0 {
+		return errors.New("id 为空！")
+	}
+	var tokens []Token
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if common.RedisEnabled {
+			if err := tx.Unscoped().Select("id", commonKeyCol).Where("user_id = ?", userId).Find(&tokens).Error; err != nil {
+				return err
+			}
+		}
+		if err := deleteUserAuthenticationData(tx, userId); err != nil {
+			return err
+		}
+		err := tx.Model(&User{}).Where("id = ?", userId).Updates(map[string]interface{}{
+			"username":        AnonymizedUsername(userId),
+			"password":        "",
+			"display_name":    "",
+			"email":           "",
+			"github_id":       "",
+			"discord_id":      "",
+			"oidc_id":         "",
+			"wechat_id":       "",
+			"telegram_id":     "",
+			"linux_do_id":     "",
+			"access_token":    nil,
+			"setting":         "",
+			"remark":          "",
+			"stripe_customer": "",
+		}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userId).Delete(&AccountDeletionRequest{}).Error; err != nil {
+			return err
+		}
+		// 请求/响应正文可能含有用户的对话内容，无法匿名化，直接删除
+		if err := tx.Where("user_id = ?", userId).Delete(&BodyLog{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&User{Id: userId}).Error
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := RevokeUserSessions(userId, ""); err != nil {
+		common.SysError(fmt.Sprintf("failed to revoke sessions of deleted user %d: %v", userId, err))
+	}
+	if err := anonymizeUserLogs(userId); err != nil {
+		common.SysError(fmt.Sprintf("failed to anonymize logs of deleted user %d: %v", userId, err))
+	}
+	if err := invalidateTokensCache(tokens); err != nil {
+		common.SysError(fmt.Sprintf("failed to invalidate token cache after deleting user %d: %v", userId, err))
+	}
+	if err := invalidateUserCache(userId); err != nil {
+		common.SysError(fmt.Sprintf("failed to invalidate user cache after deleting user %d: %v", userId, err))
+	}
+	return nil
+}
+
+// anonymizeUserLogs 将用户日志中的用户名替换为匿名用户名并清空 IP，
+// 登录日志的附加信息含有 User-Agent，一并清空
+func anonymizeUserLogs(userId int) error {
+	username := AnonymizedUsername(userId)
+	if common.UsingLogDatabase(common.DatabaseTypeClickHouse) {
+		if err := LOG_DB.Exec(
+			"ALTER TABLE logs UPDATE username = ?, ip = '' WHERE user_id = ? SETTINGS mutations_sync = 1",
+			username, userId,
+		).Error; err != nil {
+			return err
+		}
+		return LOG_DB.Exec(
+			"ALTER TABLE logs UPDATE other = '' WHERE user_id = ? AND type = ? SETTINGS mutations_sync = 1",
+			userId, LogTypeLogin,
+		).Error
+	}
+	if err := LOG_DB.Model(&Log{}).Where("user_id = ?", userId).
+		Updates(map[string]interface{}{"username": username, "ip": ""}).Error; err != nil {
+		return err
+	}
+	return LOG_DB.Model(&Log{}).Where("user_id = ? AND type = ?", userId, LogTypeLogin).Update("other", "").Error
+}
@@ -0,0 +1,91 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountDeletionRequestLifecycle(t *testing.T) {
+	t.Cleanup(func() { DB.Exec("DELETE FROM account_deletion_requests") })
+
+	request, err := CreateAccountDeletionRequest(1, 3600)
+	require.NoError(t, err)
+	assert.Equal(t, request.RequestedAt+3600, request.EffectiveAt)
+
+	_, err = CreateAccountDeletionRequest(1, 3600)
+	assert.ErrorIs(t, err, ErrAccountDeletionRequestExists)
+
+	found, err := GetAccountDeletionRequest(1)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, request.EffectiveAt, found.EffectiveAt)
+
+	require.NoError(t, CancelAccountDeletionRequest(1))
+	assert.Error(t, CancelAccountDeletionRequest(1))
+	found, err = GetAccountDeletionRequest(1)
+	require.NoError(t, err)
+	assert.Nil(t, found)
+}
+
+func TestAnonymizeAndDeleteUser(t *testing.T) {
+	t.Cleanup(func() {
+		DB.Exec("DELETE FROM users")
+		DB.Exec("DELETE FROM tokens")
+		DB.Exec("DELETE FROM logs")
+		DB.Exec("DELETE FROM user_sessions")
+		DB.Exec("DELETE FROM account_deletion_requests")
+		DB.Exec("DELETE FROM body_logs")
+	})
+
+	user := &User{Id: 501, Username: "gdpr_user", Password: "hashed", DisplayName: "GDPR", Email: "gdpr@example.com", GitHubId: "gh-501", AffCode: "a501", Status: common.UserStatusEnabled}
+	require.NoError(t, DB.Create(user).Error)
+	require.NoError(t, DB.Create(&Token{UserId: user.Id, Key: "gdpr-token-key", Name: "t"}).Error)
+	require.NoError(t, DB.Create(&Log{UserId: user.Id, Username: user.Username, Type: LogTypeConsume, Ip: "10.0.0.1", Quota: 100}).Error)
+	require.NoError(t, DB.Create(&Log{UserId: user.Id, Username: user.Username, Type: LogTypeLogin, Ip: "10.0.0.1", Other: `{"user_agent":"browser"}`}).Error)
+	require.NoError(t, CreateBodyLog(&BodyLog{UserId: user.Id, RequestId: "gdpr-req", RequestBody: []byte("secret prompt")}))
+	require.NoError(t, CreateBodyLog(&BodyLog{UserId: 777, RequestId: "other-req", RequestBody: []byte("other prompt")}))
+	_, err := CreateUserSession(user.Id, "10.0.0.1", "browser")
+	require.NoError(t, err)
+	_, err = CreateAccountDeletionRequest(user.Id, 0)
+	require.NoError(t, err)
+
+	require.NoError(t, AnonymizeAndDeleteUser(user.Id))
+
+	var deleted User
+	require.NoError(t, DB.Unscoped().First(&deleted, "id = ?", user.Id).Error)
+	assert.True(t, deleted.DeletedAt.Valid)
+	assert.Equal(t, AnonymizedUsername(user.Id), deleted.Username)
+	assert.Empty(t, deleted.Email)
+	assert.Empty(t, deleted.DisplayName)
+	assert.Empty(t, deleted.GitHubId)
+	assert.Empty(t, deleted.Password)
+
+	var tokenCount, sessionCount, requestCount, bodyLogCount, otherBodyLogCount int64
+	DB.Unscoped().Model(&Token{}).Where("user_id = ?", user.Id).Count(&tokenCount)
+	DB.Model(&UserSession{}).Where("user_id = ?", user.Id).Count(&sessionCount)
+	DB.Model(&AccountDeletionRequest{}).Where("user_id = ?", user.Id).Count(&requestCount)
+	DB.Model(&BodyLog{}).Where("user_id = ?", user.Id).Count(&bodyLogCount)
+	DB.Model(&BodyLog{}).Where("user_id = ?", 777).Count(&otherBodyLogCount)
+	assert.Zero(t, tokenCount)
+	assert.Zero(t, sessionCount)
+	assert.Zero(t, requestCount)
+	assert.Zero(t, bodyLogCount)
+	assert.EqualValues(t, 1, otherBodyLogCount)
+
+	var logs []Log
+	require.NoError(t, DB.Where("user_id = ?", user.Id).Order("type").Find(&logs).Error)
+	require.Len(t, logs, 2)
+	for _, log := range logs {
+		assert.Equal(t, AnonymizedUsername(user.Id), log.Username)
+		assert.Empty(t, log.Ip)
+	}
+	assert.Equal(t, 100, logs[0].Quota, "consumption records are kept")
+	assert.Empty(t, logs[1].Other)
+
+	// the username is released for new registrations
+	require.NoError(t, DB.Create(&User{Id: 502, Username: "gdpr_user", Password: "hashed", AffCode: "a502"}).Error)
+}
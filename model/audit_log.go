@@ -0,0 +1,212 @@
+package model
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+)
+
+// AuditLog 管理员/root 写操作的审计记录。
+// 与 logs 表中 type=LogTypeManage 的操作日志不同，审计表只供 root 查询，
+// 保存操作者、目标资源和变更前后差异，并按 audit_setting.retention_days 单独清理，
+// 不受普通日志清理影响。
+type AuditLog struct {
+	Id         int    `json:"id" gorm:"primaryKey;autoIncrement"`
+	CreatedAt  int64  `json:"created_at" gorm:"bigint;index"`
+	ActorId    int    `json:"actor_id" gorm:"index"`
+	ActorName  string `json:"actor_name" gorm:"type:varchar(64)"`
+	ActorRole  int    `json:"actor_role"`
+	AuthMethod string `json:"auth_method" gorm:"type:varchar(32)"`
+	Action     string `json:"action" gorm:"type:varchar(64);index"`
+	TargetType string `json:"target_type" gorm:"type:varchar(32);index:idx_audit_target"`
+	TargetId   string `json:"target_id" gorm:"type:varchar(128);index:idx_audit_target"`
+	Method     string `json:"method" gorm:"type:varchar(10)"`
+	Route      string `json:"route" gorm:"type:varchar(255)"`
+	Ip         string `json:"ip" gorm:"type:varchar(64)"`
+	Success    bool   `json:"success"`
+	Params     string `json:"params" gorm:"type:text"`
+	Changes    string `json:"changes" gorm:"type:text"` // JSON: {字段: {before, after}}
+}
+
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
+
+// AuditChange 单个字段的变更前后值
+type AuditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// auditTargetParamKeys 按优先级从 action params 中提取目标资源标识
+var auditTargetParamKeys = []string{"id", "target_user_id", "user_id", "key", "plan_id", "tag"}
+
+// isSensitiveAuditField 密钥、密码类字段只记录是否变更，不记录内容
+func isSensitiveAuditField(field string) bool {
+	field = strings.ToLower(field)
+	return strings.HasSuffix(field, "key") ||
+		strings.HasSuffix(field, "secret") ||
+		strings.HasSuffix(field, "token") ||
+		strings.HasSuffix(field, "password")
+}
+
+// BuildAuditChanges 比较 before/after 序列化后的顶层字段，返回有变化的字段；
+// 敏感字段的值以 "***" 代替。before/after 可以是结构体或 map
+func BuildAuditChanges(before, after interface{}) map[string]AuditChange {
+	beforeMap := auditFieldMap(before)
+	afterMap := auditFieldMap(after)
+	changes := map[string]AuditChange{}
+	for field, value := range afterMap {
+		if old, ok := beforeMap[field]; !ok || !reflect.DeepEqual(old, value) {
+			changes[field] = AuditChange{Before: beforeMap[field], After: value}
+		}
+	}
+	for field, old := range beforeMap {
+		if _, ok := afterMap[field]; !ok {
+			changes[field] = AuditChange{Before: old}
+		}
+	}
+	for field, change := range changes {
+		if isSensitiveAuditField(field) {
+			changes[field] = AuditChange{Before: maskAuditValue(change.Before), After: maskAuditValue(change.After)}
+		}
+	}
+	return changes
+}
+
+func auditFieldMap(value interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	if value == nil {
+		return fields
+	}
+	data, err := common.Marshal(value)
+	if err != nil {
+		return fields
+	}
+	_ = common.Unmarshal(data, &fields)
+	return fields
+}
+
+func maskAuditValue(value interface{}) interface{} {
+	if value == nil || value == "" {
+		return value
+	}
+	return "***"
+}
+
+// recordAuditLog 由 RecordOperationAuditLog 在管理员操作时调用，写入审计表。
+// 目标类型取 action 的前缀（兜底记录的 generic 操作取路由的资源段），目标标识按
+// auditTargetParamKeys 从 params 或路由参数中提取
+func recordAuditLog(ip string, action string, params map[string]interface{}, adminInfo map[string]interface{}, auditInfo map[string]interface{}) {
+	if !operation_setting.GetAuditSetting().Enabled {
+		return
+	}
+	entry := &AuditLog{
+		CreatedAt: common.GetTimestamp(),
+		Action:    action,
+		Ip:        ip,
+		Success:   true,
+	}
+	entry.ActorId, _ = adminInfo["admin_id"].(int)
+	entry.ActorName, _ = adminInfo["admin_username"].(string)
+	entry.ActorRole, _ = adminInfo["admin_role"].(int)
+	entry.AuthMethod, _ = adminInfo["auth_method"].(string)
+	entry.Method, _ = auditInfo["method"].(string)
+	entry.Route, _ = auditInfo["route"].(string)
+	if success, ok := auditInfo["success"].(bool); ok {
+		entry.Success = success
+	}
+
+	entry.TargetType, _, _ = strings.Cut(action, ".")
+	if action == "generic" {
+		segments := strings.Split(strings.TrimPrefix(entry.Route, "/api/"), "/")
+		entry.TargetType = segments[0]
+	}
+	targetParams := map[string]interface{}{}
+	for k, v := range params {
+		targetParams[k] = v
+	}
+	if routeParams, ok := auditInfo["params"].(map[string]string); ok {
+		for k, v := range routeParams {
+			if _, exists := targetParams[k]; !exists {
+				targetParams[k] = v
+			}
+		}
+	}
+	for _, key := range auditTargetParamKeys {
+		if value, ok := targetParams[key]; ok {
+			entry.TargetId = common.Interface2String(value)
+			break
+		}
+	}
+
+	if len(params) > 0 {
+		entry.Params = common.MapToJsonStr(params)
+	}
+	if changes, ok := auditInfo["changes"].(map[string]AuditChange); ok && len(changes) > 0 {
+		if data, err := common.Marshal(changes); err == nil {
+			entry.Changes = string(data)
+		}
+	}
+	if err := DB.Create(entry).Error; err != nil {
+		common.SysLog("failed to record audit log: " + err.Error())
+	}
+}
+
+// AuditLogQuery 审计日志查询条件，零值字段不参与过滤
+type AuditLogQuery struct {
+	ActorId        int
+	ActorName      string
+	Action         string
+	TargetType     string
+	TargetId       string
+	StartTimestamp int64
+	EndTimestamp   int64
+}
+
+// GetAuditLogs 按条件分页查询审计日志，按时间倒序
+func GetAuditLogs(query AuditLogQuery, startIdx int, num int) (logs []*AuditLog, total int64, err error) {
+	tx := DB.Model(&AuditLog{})
+	if query.ActorId != 0 {
+		tx = tx.Where("actor_id = ?", query.ActorId)
+	}
+	if query.ActorName != "" {
+		tx = tx.Where("actor_name = ?", query.ActorName)
+	}
+	if query.Action != "" {
+		tx = tx.Where("action = ?", query.Action)
+	}
+	if query.TargetType != "" {
+		tx = tx.Where("target_type = ?", query.TargetType)
+	}
+	if query.TargetId != "" {
+		tx = tx.Where("target_id = ?", query.TargetId)
+	}
+	if query.StartTimestamp != 0 {
+		tx = tx.Where("created_at >= ?", query.StartTimestamp)
+	}
+	if query.EndTimestamp != 0 {
+		tx = tx.Where("created_at <= ?", query.EndTimestamp)
+	}
+	if err = tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = tx.Order("id DESC").Limit(num).Offset(startIdx).Find(&logs).Error
+	return logs, total, err
+}
+
+// DeleteAuditLogsBefore 删除一批 cutoff 之前的审计日志，返回删除条数。
+// 先查出 id 再按 id 删除，避免依赖 DELETE ... LIMIT 等非通用语法
+func DeleteAuditLogsBefore(cutoff int64, limit int) (int64, error) {
+	var ids []int
+	if err := DB.Model(&AuditLog{}).Where("created_at < ?", cutoff).Order("id").Limit(limit).Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	result := DB.Where("id IN ?", ids).Delete(&AuditLog{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,101 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildAuditChanges(t *testing.T) {
+	tests := []struct {
+		name   string
+		before interface{}
+		after  interface{}
+		want   map[string]AuditChange
+	}{
+		{
+			name:   "unchanged",
+			before: map[string]interface{}{"name": "a"},
+			after:  map[string]interface{}{"name": "a"},
+			want:   map[string]AuditChange{},
+		},
+		{
+			name:   "changed and added",
+			before: map[string]interface{}{"name": "a", "weight": 1},
+			after:  map[string]interface{}{"name": "b", "weight": 1, "priority": 2},
+			want: map[string]AuditChange{
+				"name":     {Before: "a", After: "b"},
+				"priority": {Before: nil, After: float64(2)},
+			},
+		},
+		{
+			name:   "removed",
+			before: map[string]interface{}{"tag": "x"},
+			after:  map[string]interface{}{},
+			want:   map[string]AuditChange{"tag": {Before: "x"}},
+		},
+		{
+			name:   "sensitive fields masked",
+			before: map[string]interface{}{"key": "sk-old", "GitHubClientSecret": ""},
+			after:  map[string]interface{}{"key": "sk-new", "GitHubClientSecret": "s3cret"},
+			want: map[string]AuditChange{
+				"key":                {Before: "***", After: "***"},
+				"GitHubClientSecret": {Before: "", After: "***"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, BuildAuditChanges(tt.before, tt.after))
+		})
+	}
+}
+
+func TestRecordOperationAuditLog_WritesAuditTable(t *testing.T) {
+	truncateTables(t)
+	require.NoError(t, DB.Create(&User{Id: 1, Username: "audit_root", AffCode: "audit_root", Status: common.UserStatusEnabled}).Error)
+
+	adminInfo := map[string]interface{}{"admin_id": 1, "admin_username": "audit_root", "admin_role": common.RoleRootUser, "auth_method": "session"}
+	RecordOperationAuditLog(1, "Updated channel", "10.0.0.1", "channel.update",
+		map[string]interface{}{"id": 7, "name": "c7"}, adminInfo,
+		map[string]interface{}{
+			"method":  "PUT",
+			"route":   "/api/channel/",
+			"changes": BuildAuditChanges(map[string]interface{}{"weight": 1}, map[string]interface{}{"weight": 5}),
+		})
+	RecordOperationAuditLog(1, "DELETE /api/vendors/:id", "10.0.0.1", "generic", nil, adminInfo,
+		map[string]interface{}{
+			"method":  "DELETE",
+			"route":   "/api/vendors/:id",
+			"success": false,
+			"params":  map[string]string{"id": "3"},
+		})
+	// 普通用户自己的操作没有 adminInfo，不进入审计表
+	RecordOperationAuditLog(1, "Registered a passkey", "10.0.0.1", "user.passkey_register", nil, nil, nil)
+
+	logs, total, err := GetAuditLogs(AuditLogQuery{}, 0, 10)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, total)
+	assert.Equal(t, "vendors", logs[0].TargetType)
+	assert.Equal(t, "3", logs[0].TargetId)
+	assert.False(t, logs[0].Success)
+	assert.Equal(t, "channel", logs[1].TargetType)
+	assert.Equal(t, "7", logs[1].TargetId)
+	assert.Equal(t, "audit_root", logs[1].ActorName)
+	assert.JSONEq(t, `{"weight":{"before":1,"after":5}}`, logs[1].Changes)
+
+	logs, total, err = GetAuditLogs(AuditLogQuery{TargetType: "channel", TargetId: "7"}, 0, 10)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, total)
+	assert.Equal(t, "channel.update", logs[0].Action)
+
+	require.NoError(t, DB.Model(&AuditLog{}).Where("id = ?", logs[0].Id).Update("created_at", 100).Error)
+	deleted, err := DeleteAuditLogsBefore(1000, 10)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, deleted)
+	_, total, err = GetAuditLogs(AuditLogQuery{}, 0, 10)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+}
@@ -0,0 +1,195 @@
+package model
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"github.com/samber/hot"
+)
+
+// The auth local cache is an in-process LRU in front of the Redis token/user
+// hashes that every relay request reads. It is opt-in via
+// AUTH_LOCAL_CACHE_TTL_SECONDS because quota fields can lag behind Redis by up
+// to one TTL on other nodes; structural changes (status, group, deletion) are
+// broadcast over the cache invalidation bus and take effect immediately.
+
+const (
+	authLocalCacheDefaultCapacity = 100000
+)
+
+type AuthCacheStats struct {
+	Enabled      bool  `json:"enabled"`
+	TTLSeconds   int   `json:"ttl_seconds"`
+	TokenHits    int64 `json:"token_hits"`
+	TokenMisses  int64 `json:"token_misses"`
+	TokenEntries int   `json:"token_entries"`
+	UserHits     int64 `json:"user_hits"`
+	UserMisses   int64 `json:"user_misses"`
+	UserEntries  int   `json:"user_entries"`
+}
+
+var (
+	authLocalCacheOnce sync.Once
+	tokenLocalCache    *hot.HotCache[string, Token]
+	userLocalCache     *hot.HotCache[int, UserBase]
+
+	tokenLocalHits   atomic.Int64
+	tokenLocalMisses atomic.Int64
+	userLocalHits    atomic.Int64
+	userLocalMisses  atomic.Int64
+)
+
+func init() {
+	common.RegisterCacheInvalidationHandler(common.CacheInvalidationKindToken, func(event common.CacheInvalidationEvent) {
+		if authLocalCacheEnabled() {
+			getTokenLocalCache().Delete(event.Key)
+		}
+	})
+	common.RegisterCacheInvalidationHandler(common.CacheInvalidationKindUser, func(event common.CacheInvalidationEvent) {
+		userId, err := strconv.Atoi(event.Key)
+		if err != nil || !authLocalCacheEnabled() {
+			return
+		}
+		getUserLocalCache().Delete(userId)
+	})
+}
+
+func authLocalCacheTTLSeconds() int {
+	ttl := common.GetEnvOrDefault("AUTH_LOCAL_CACHE_TTL_SECONDS", 0)
+	if ttl < 0 {
+		return 0
+	}
+	return ttl
+}
+
+// authLocalCacheEnabled requires Redis: without it there is no shared tier to
+// front and no bus to deliver invalidations, so lookups keep going to the DB.
+func authLocalCacheEnabled() bool {
+	return common.RedisEnabled && authLocalCacheTTLSeconds() > 0
+}
+
+func initAuthLocalCaches() {
+	authLocalCacheOnce.Do(func() {
+		ttl := time.Duration(authLocalCacheTTLSeconds()) * time.Second
+		capacity := common.GetEnvOrDefault("AUTH_LOCAL_CACHE_CAP", authLocalCacheDefaultCapacity)
+		if capacity <= 0 {
+			capacity = authLocalCacheDefaultCapacity
+		}
+		tokenLocalCache = hot.NewHotCache[string, Token](hot.LRU, capacity).
+			WithTTL(ttl).
+			WithJanitor().
+			Build()
+		userLocalCache = hot.NewHotCache[int, UserBase](hot.LRU, capacity).
+			WithTTL(ttl).
+			WithJanitor().
+			Build()
+	})
+}
+
+func getTokenLocalCache() *hot.HotCache[string, Token] {
+	initAuthLocalCaches()
+	return tokenLocalCache
+}
+
+func getUserLocalCache() *hot.HotCache[int, UserBase] {
+	initAuthLocalCaches()
+	return userLocalCache
+}
+
+func localGetToken(hmacKey string) (*Token, bool) {
+	if !authLocalCacheEnabled() {
+		return nil, false
+	}
+	token, found, _ := getTokenLocalCache().Get(hmacKey)
+	if !found {
+		tokenLocalMisses.Add(1)
+		return nil, false
+	}
+	tokenLocalHits.Add(1)
+	return &token, true
+}
+
+func localSetToken(hmacKey string, token Token) {
+	if !authLocalCacheEnabled() {
+		return
+	}
+	getTokenLocalCache().Set(hmacKey, token)
+}
+
+// localDropToken evicts the token on this node only. Used on the quota hot
+// path, where broadcasting every decrement would flood the bus.
+func localDropToken(hmacKey string) {
+	if !authLocalCacheEnabled() {
+		return
+	}
+	getTokenLocalCache().Delete(hmacKey)
+}
+
+// invalidateLocalToken evicts the token on every node.
+func invalidateLocalToken(hmacKey string) {
+	localDropToken(hmacKey)
+	if authLocalCacheEnabled() {
+		common.PublishCacheInvalidation(common.CacheInvalidationKindToken, hmacKey)
+	}
+}
+
+func localGetUser(userId int) (*UserBase, bool) {
+	if !authLocalCacheEnabled() {
+		return nil, false
+	}
+	user, found, _ := getUserLocalCache().Get(userId)
+	if !found {
+		userLocalMisses.Add(1)
+		return nil, false
+	}
+	userLocalHits.Add(1)
+	return &user, true
+}
+
+func localSetUser(user UserBase) {
+	if !authLocalCacheEnabled() {
+		return
+	}
+	getUserLocalCache().Set(user.Id, user)
+}
+
+func localDropUser(userId int) {
+	if !authLocalCacheEnabled() {
+		return
+	}
+	getUserLocalCache().Delete(userId)
+}
+
+func invalidateLocalUser(userId int) {
+	localDropUser(userId)
+	if authLocalCacheEnabled() {
+		common.PublishCacheInvalidation(common.CacheInvalidationKindUser, strconv.Itoa(userId))
+	}
+}
+
+func GetAuthCacheStats() AuthCacheStats {
+	stats := AuthCacheStats{
+		Enabled:     authLocalCacheEnabled(),
+		TTLSeconds:  authLocalCacheTTLSeconds(),
+		TokenHits:   tokenLocalHits.Load(),
+		TokenMisses: tokenLocalMisses.Load(),
+		UserHits:    userLocalHits.Load(),
+		UserMisses:  userLocalMisses.Load(),
+	}
+	if stats.Enabled {
+		stats.TokenEntries = getTokenLocalCache().Len()
+		stats.UserEntries = getUserLocalCache().Len()
+	}
+	return stats
+}
+
+func ResetAuthCacheStats() {
+	tokenLocalHits.Store(0)
+	tokenLocalMisses.Store(0)
+	userLocalHits.Store(0)
+	userLocalMisses.Store(0)
+}
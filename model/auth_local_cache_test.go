@@ -0,0 +1,117 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func enableAuthLocalCacheForTest(t *testing.T) *miniredis.Miniredis {
+	t.Helper()
+	stub := useMiniRedis(t)
+	t.Setenv("AUTH_LOCAL_CACHE_TTL_SECONDS", "60")
+	reset := func() {
+		getTokenLocalCache().Purge()
+		getUserLocalCache().Purge()
+		ResetAuthCacheStats()
+	}
+	reset()
+	t.Cleanup(reset)
+	return stub
+}
+
+func TestAuthLocalCache_Token(t *testing.T) {
+	stub := enableAuthLocalCacheForTest(t)
+	token := Token{Id: 7, Key: "local-cache-token", PreviousKey: "local-cache-old", Name: "first", Status: common.TokenStatusEnabled, RemainQuota: 100}
+	hmacKey := common.GenerateHMAC(token.Key)
+
+	require.NoError(t, cacheSetToken(token))
+	fields, err := stub.HKeys("token:" + hmacKey)
+	require.NoError(t, err)
+	assert.NotContains(t, fields, "PreviousKey", "the previous key must not be cached")
+
+	cached, err := cacheGetTokenByKey(token.Key)
+	require.NoError(t, err)
+	assert.Equal(t, "first", cached.Name)
+	assert.Equal(t, token.Key, cached.Key)
+	cached, err = cacheGetTokenByKey(token.Key)
+	require.NoError(t, err)
+	assert.Equal(t, "first", cached.Name)
+	stats := GetAuthCacheStats()
+	assert.Equal(t, int64(1), stats.TokenMisses)
+	assert.Equal(t, int64(1), stats.TokenHits)
+	assert.Equal(t, 1, stats.TokenEntries)
+
+	// 写回 Redis 后本地条目必须失效，下一次读取拿到新值
+	token.Name = "second"
+	require.NoError(t, cacheSetToken(token))
+	assert.False(t, getTokenLocalCache().Has(hmacKey))
+	cached, err = cacheGetTokenByKey(token.Key)
+	require.NoError(t, err)
+	assert.Equal(t, "second", cached.Name)
+
+	require.NoError(t, cacheSetTokenField(token.Key, "Status", "2"))
+	assert.False(t, getTokenLocalCache().Has(hmacKey))
+	cached, err = cacheGetTokenByKey(token.Key)
+	require.NoError(t, err)
+	assert.Equal(t, 2, cached.Status)
+
+	require.NoError(t, cacheDecrTokenQuota(token.Key, 30))
+	assert.False(t, getTokenLocalCache().Has(hmacKey))
+	cached, err = cacheGetTokenByKey(token.Key)
+	require.NoError(t, err)
+	assert.Equal(t, 70, cached.RemainQuota)
+
+	require.NoError(t, cacheDeleteToken(token.Key))
+	assert.False(t, getTokenLocalCache().Has(hmacKey))
+	_, err = cacheGetTokenByKey(token.Key)
+	assert.Error(t, err)
+}
+
+func TestAuthLocalCache_User(t *testing.T) {
+	enableAuthLocalCacheForTest(t)
+	user := User{Id: 9, Username: "local-cache-user", Group: "default", Status: common.UserStatusEnabled, Quota: 500}
+	require.NoError(t, populateUserCache(user))
+
+	cached, err := cacheGetUserBase(user.Id)
+	require.NoError(t, err)
+	assert.Equal(t, common.UserStatusEnabled, cached.Status)
+	_, err = cacheGetUserBase(user.Id)
+	require.NoError(t, err)
+	stats := GetAuthCacheStats()
+	assert.Equal(t, int64(1), stats.UserMisses)
+	assert.Equal(t, int64(1), stats.UserHits)
+	assert.Equal(t, 1, stats.UserEntries)
+
+	require.NoError(t, updateUserStatusCache(user.Id, false))
+	assert.False(t, getUserLocalCache().Has(user.Id))
+	cached, err = cacheGetUserBase(user.Id)
+	require.NoError(t, err)
+	assert.Equal(t, common.UserStatusDisabled, cached.Status)
+
+	require.NoError(t, cacheIncrUserQuota(user.Id, 25))
+	assert.False(t, getUserLocalCache().Has(user.Id))
+	cached, err = cacheGetUserBase(user.Id)
+	require.NoError(t, err)
+	assert.Equal(t, 525, cached.Quota)
+}
+
+func TestAuthLocalCache_DisabledWithoutTTL(t *testing.T) {
+	enableAuthLocalCacheForTest(t)
+	t.Setenv("AUTH_LOCAL_CACHE_TTL_SECONDS", "0")
+	token := Token{Id: 8, Key: "local-cache-disabled", Name: "n", Status: common.TokenStatusEnabled}
+	require.NoError(t, cacheSetToken(token))
+
+	for i := 0; i < 2; i++ {
+		_, err := cacheGetTokenByKey(token.Key)
+		require.NoError(t, err)
+	}
+	stats := GetAuthCacheStats()
+	assert.False(t, stats.Enabled)
+	assert.Zero(t, stats.TokenHits)
+	assert.Zero(t, stats.TokenMisses)
+}
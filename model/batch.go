@@ -0,0 +1,158 @@
+package model
+
+import (
+	"github.com/QuantumNous/new-api/common"
+)
+
+// 上游 Batch 对象的状态，见 https://platform.openai.com/docs/api-reference/batch/object
+const (
+	BatchStatusValidating = "validating"
+	BatchStatusFailed     = "failed"
+	BatchStatusInProgress = "in_progress"
+	BatchStatusFinalizing = "finalizing"
+	BatchStatusCompleted  = "completed"
+	BatchStatusExpired    = "expired"
+	BatchStatusCancelling = "cancelling"
+	BatchStatusCancelled  = "cancelled"
+)
+
+// batchTerminalStatuses 上游不会再变化的状态，到达后即可结算
+var batchTerminalStatuses = []string{BatchStatusFailed, BatchStatusCompleted, BatchStatusExpired, BatchStatusCancelled}
+
+func IsBatchTerminalStatus(status string) bool {
+	for _, s := range batchTerminalStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// BatchFile 通过网关上传到上游的文件。文件只存在于上传时选中的渠道和密钥上，
+// 后续的文件和批量任务请求都必须发往同一渠道
+type BatchFile struct {
+	Id        int    `json:"id"`
+	FileId    string `json:"file_id" gorm:"type:varchar(128);uniqueIndex"` // 上游文件 ID
+	UserId    int    `json:"user_id" gorm:"index"`
+	ChannelId int    `json:"channel_id"`
+	KeyIndex  int    `json:"-"` // 多密钥渠道上传时使用的密钥下标
+	Purpose   string `json:"purpose" gorm:"type:varchar(32)"`
+	Filename  string `json:"filename" gorm:"type:varchar(255)"`
+	Bytes     int64  `json:"bytes"`
+	Model     string `json:"model" gorm:"type:varchar(128)"` // batch 输入文件首行请求的模型
+	CreatedAt int64  `json:"created_at" gorm:"bigint"`
+}
+
+// BatchJob 用户提交的批量任务，Data 保存最近一次从上游获取的 Batch 对象
+type BatchJob struct {
+	Id           int    `json:"id"`
+	BatchId      string `json:"batch_id" gorm:"type:varchar(128);uniqueIndex"` // 上游 Batch ID
+	UserId       int    `json:"user_id" gorm:"index"`
+	TokenId      int    `json:"token_id"`
+	ChannelId    int    `json:"channel_id"`
+	KeyIndex     int    `json:"-"`
+	Group        string `json:"group" gorm:"type:varchar(64)"`
+	Model        string `json:"model" gorm:"type:varchar(128)"`
+	Endpoint     string `json:"endpoint" gorm:"type:varchar(64)"`
+	InputFileId  string `json:"input_file_id" gorm:"type:varchar(128)"`
+	OutputFileId string `json:"output_file_id" gorm:"type:varchar(128)"`
+	ErrorFileId  string `json:"error_file_id" gorm:"type:varchar(128)"`
+	Status       string `json:"status" gorm:"type:varchar(32);index"`
+	Settled      bool   `json:"settled" gorm:"index"`
+	Quota        int    `json:"quota"` // 结算时实际扣除的额度
+	Data         string `json:"-" gorm:"type:text"`
+	CreatedAt    int64  `json:"created_at" gorm:"bigint"`
+	UpdatedAt    int64  `json:"updated_at" gorm:"bigint"`
+}
+
+func (file *BatchFile) Insert() error {
+	file.CreatedAt = common.GetTimestamp()
+	return DB.Create(file).Error
+}
+
+func GetUserBatchFile(userId int, fileId string) (*BatchFile, error) {
+	file := &BatchFile{}
+	err := DB.Where("user_id = ? AND file_id = ?", userId, fileId).First(file).Error
+	return file, err
+}
+
+func GetUserBatchFiles(userId int, purpose string, limit int) ([]*BatchFile, error) {
+	var files []*BatchFile
+	query := DB.Where("user_id = ?", userId)
+	if purpose != "" {
+		query = query.Where("purpose = ?", purpose)
+	}
+	err := query.Order("id desc").Limit(limit).Find(&files).Error
+	return files, err
+}
+
+func DeleteUserBatchFile(userId int, fileId string) error {
+	return DB.Where("user_id = ? AND file_id = ?", userId, fileId).Delete(&BatchFile{}).Error
+}
+
+func (job *BatchJob) Insert() error {
+	now := common.GetTimestamp()
+	job.CreatedAt = now
+	job.UpdatedAt = now
+	return DB.Create(job).Error
+}
+
+// UpdateFromUpstream 保存上游最新的状态和文件信息，已结算的任务不会被回退为未结算
+func (job *BatchJob) UpdateFromUpstream() error {
+	job.UpdatedAt = common.GetTimestamp()
+	return DB.Model(&BatchJob{}).Where("id = ?", job.Id).Updates(map[string]any{
+		"status":         job.Status,
+		"output_file_id": job.OutputFileId,
+		"error_file_id":  job.ErrorFileId,
+		"data":           job.Data,
+		"updated_at":     job.UpdatedAt,
+	}).Error
+}
+
+func GetUserBatchJob(userId int, batchId string) (*BatchJob, error) {
+	job := &BatchJob{}
+	err := DB.Where("user_id = ? AND batch_id = ?", userId, batchId).First(job).Error
+	return job, err
+}
+
+func GetUserBatchJobs(userId int, startIdx int, num int) ([]*BatchJob, int64, error) {
+	var jobs []*BatchJob
+	var total int64
+	query := DB.Model(&BatchJob{}).Where("user_id = ?", userId)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("id desc").Offset(startIdx).Limit(num).Find(&jobs).Error
+	return jobs, total, err
+}
+
+// GetUserBatchJobByFileId 按输出文件或错误文件 ID 查找任务，这两类文件由上游生成，本地没有 BatchFile 记录
+func GetUserBatchJobByFileId(userId int, fileId string) (*BatchJob, error) {
+	job := &BatchJob{}
+	err := DB.Where("user_id = ? AND (output_file_id = ? OR error_file_id = ?)", userId, fileId, fileId).First(job).Error
+	return job, err
+}
+
+// GetUnsettledBatchJobs 返回尚未结算的任务，包括仍在运行和已结束待结算的
+func GetUnsettledBatchJobs(limit int) ([]*BatchJob, error) {
+	var jobs []*BatchJob
+	err := DB.Where("settled = ?", false).Order("updated_at asc").Limit(limit).Find(&jobs).Error
+	return jobs, err
+}
+
+func HasUnsettledBatchJobs() bool {
+	var job BatchJob
+	err := DB.Select("id").Where("settled = ?", false).Take(&job).Error
+	return err == nil
+}
+
+// MarkBatchJobSettled 以 CAS 方式标记任务已结算，返回是否由本次调用完成标记，
+// 保证多个节点同时轮询时只结算一次
+func MarkBatchJobSettled(id int, quota int) (bool, error) {
+	result := DB.Model(&BatchJob{}).Where("id = ? AND settled = ?", id, false).Updates(map[string]any{
+		"settled":    true,
+		"quota":      quota,
+		"updated_at": common.GetTimestamp(),
+	})
+	return result.RowsAffected == 1, result.Error
+}
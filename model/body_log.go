@@ -0,0 +1,84 @@
+package model
+
+// BodyLog 调试用的请求/响应体记录，仅在全局开关开启且渠道或令牌选择记录时写入。
+// 正文经脱敏后 gzip 压缩保存，只有 root 可以查看，并按 body_log_setting.retention_days 单独清理。
+type BodyLog struct {
+	Id           int    `json:"id" gorm:"primaryKey;autoIncrement"`
+	CreatedAt    int64  `json:"created_at" gorm:"bigint;index"`
+	RequestId    string `json:"request_id" gorm:"type:varchar(64);index"`
+	UserId       int    `json:"user_id" gorm:"index"`
+	TokenId      int    `json:"token_id" gorm:"index"`
+	ChannelId    int    `json:"channel_id" gorm:"index"`
+	ModelName    string `json:"model_name" gorm:"type:varchar(128)"`
+	Path         string `json:"path" gorm:"type:varchar(255)"`
+	StatusCode   int    `json:"status_code"`
+	RequestBody  []byte `json:"-"`
+	ResponseBody []byte `json:"-"`
+	// 正文超过 MaxBodyBytes 时只保存前 MaxBodyBytes 字节
+	RequestTruncated  bool `json:"request_truncated"`
+	ResponseTruncated bool `json:"response_truncated"`
+}
+
+func (BodyLog) TableName() string {
+	return "body_logs"
+}
+
+type BodyLogQuery struct {
+	RequestId      string
+	UserId         int
+	TokenId        int
+	ChannelId      int
+	StartTimestamp int64
+	EndTimestamp   int64
+}
+
+func CreateBodyLog(log *BodyLog) error {
+	return DB.Create(log).Error
+}
+
+// GetBodyLogs 分页查询请求体记录的元数据，不加载正文
+func GetBodyLogs(query BodyLogQuery, startIdx int, num int) (logs []*BodyLog, total int64, err error) {
+	tx := DB.Model(&BodyLog{})
+	if query.RequestId != "" {
+		tx = tx.Where("request_id = ?", query.RequestId)
+	}
+	if query.UserId != 0 {
+		tx = tx.Where("user_id = ?", query.UserId)
+	}
+	if query.TokenId != 0 {
+		tx = tx.Where("token_id = ?", query.TokenId)
+	}
+	if query.ChannelId != 0 {
+		tx = tx.Where("channel_id = ?", query.ChannelId)
+	}
+	if query.StartTimestamp != 0 {
+		tx = tx.Where("created_at >= ?", query.StartTimestamp)
+	}
+	if query.EndTimestamp != 0 {
+		tx = tx.Where("created_at <= ?", query.EndTimestamp)
+	}
+	if err = tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = tx.Omit("request_body", "response_body").Order("id DESC").Limit(num).Offset(startIdx).Find(&logs).Error
+	return logs, total, err
+}
+
+func GetBodyLogById(id int) (*BodyLog, error) {
+	log := &BodyLog{}
+	err := DB.First(log, "id = ?", id).Error
+	return log, err
+}
+
+// DeleteBodyLogsBefore 删除一批 cutoff 之前的请求体记录，返回删除条数。
+func DeleteBodyLogsBefore(cutoff int64, limit int) (int64, error) {
+	var ids []int
+	if err := DB.Model(&BodyLog{}).Where("created_at < ?", cutoff).Order("id").Limit(limit).Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	result := DB.Where("id IN ?", ids).Delete(&BodyLog{})
+	return result.RowsAffected, result.Error
+}
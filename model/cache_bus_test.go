@@ -0,0 +1,115 @@
+package model
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// publishRemoteInvalidation publishes an event as if it came from another
+// node, retrying until done reports true since the subscription is set up
+// asynchronously.
+func publishRemoteInvalidation(t *testing.T, kind string, key string, done func() bool) {
+	t.Helper()
+	payload, err := common.Marshal(common.CacheInvalidationEvent{Kind: kind, Key: key, Origin: "another-node"})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		common.RDB.Publish(context.Background(), "new_api:cache_invalidation", string(payload))
+		return done()
+	}, 3*time.Second, 50*time.Millisecond)
+}
+
+func TestCacheBus_RemoteAuthInvalidation(t *testing.T) {
+	enableAuthLocalCacheForTest(t)
+	common.StartCacheInvalidationListener()
+
+	token := Token{Id: 10, Key: "cache-bus-token", Name: "n", Status: common.TokenStatusEnabled}
+	hmacKey := common.GenerateHMAC(token.Key)
+	require.NoError(t, cacheSetToken(token))
+	require.NoError(t, populateUserCache(User{Id: 11, Username: "cache-bus-user", Status: common.UserStatusEnabled}))
+
+	tests := []struct {
+		name   string
+		kind   string
+		key    string
+		load   func() error
+		cached func() bool
+	}{
+		{
+			name:   "token",
+			kind:   common.CacheInvalidationKindToken,
+			key:    hmacKey,
+			load:   func() error { _, err := cacheGetTokenByKey(token.Key); return err },
+			cached: func() bool { return getTokenLocalCache().Has(hmacKey) },
+		},
+		{
+			name:   "user",
+			kind:   common.CacheInvalidationKindUser,
+			key:    "11",
+			load:   func() error { _, err := cacheGetUserBase(11); return err },
+			cached: func() bool { return getUserLocalCache().Has(11) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, tt.load())
+			require.True(t, tt.cached())
+			publishRemoteInvalidation(t, tt.kind, tt.key, func() bool { return !tt.cached() })
+		})
+	}
+}
+
+func TestCacheBus_RemoteChannelInvalidation(t *testing.T) {
+	truncateTables(t)
+	useMiniRedis(t)
+	common.StartCacheInvalidationListener()
+
+	useChannelCacheForTest(t)
+	InitChannelCache()
+
+	// 另一个节点写入的渠道，本节点只能通过失效消息得知
+	channel := &Channel{Name: "cache-bus-channel", Type: 1, Key: "sk-bus", Status: common.ChannelStatusEnabled, Models: "gpt-4o", Group: "default"}
+	require.NoError(t, channel.Insert())
+	_, err := CacheGetChannel(channel.Id)
+	require.Error(t, err)
+
+	publishRemoteInvalidation(t, common.CacheInvalidationKindChannels, strconv.Itoa(channel.Id), func() bool {
+		_, err := CacheGetChannel(channel.Id)
+		return err == nil
+	})
+}
+
+func TestRefreshChannelCachePublishesInvalidation(t *testing.T) {
+	truncateTables(t)
+	useMiniRedis(t)
+	ctx := context.Background()
+	pubsub := common.RDB.Subscribe(ctx, "new_api:cache_invalidation")
+	t.Cleanup(func() { _ = pubsub.Close() })
+	_, err := pubsub.Receive(ctx)
+	require.NoError(t, err)
+	messages := pubsub.Channel()
+
+	// 启动加载只刷新本节点，不通知其他节点
+	InitChannelCache()
+	select {
+	case msg := <-messages:
+		t.Fatalf("unexpected invalidation on startup load: %s", msg.Payload)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	RefreshChannelCache()
+	select {
+	case msg := <-messages:
+		var event common.CacheInvalidationEvent
+		require.NoError(t, common.UnmarshalJsonStr(msg.Payload, &event))
+		assert.Equal(t, common.CacheInvalidationKindChannels, event.Kind)
+	case <-time.After(3 * time.Second):
+		t.Fatal("channel mutation did not publish an invalidation")
+	}
+}
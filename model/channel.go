@@ -6,6 +6,8 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -13,6 +15,7 @@ import (
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/dto"
 	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
 	"github.com/QuantumNous/new-api/types"
 
 	"github.com/samber/lo"
@@ -46,7 +49,8 @@ type Channel struct {
 	AutoBan           *int    `json:"auto_ban" gorm:"default:1"`
 	OtherInfo         string  `json:"other_info"`
 	Tag               *string `json:"tag" gorm:"index"`
-	Setting           *string `json:"setting" gorm:"type:text"` // 渠道额外设置
+	Tags              *string `json:"tags" gorm:"type:varchar(255)"` // 路由标签，逗号分隔，用于按标签批量操作和分组路由，与聚合展示用的 tag 相互独立
+	Setting           *string `json:"setting" gorm:"type:text"`      // 渠道额外设置
 	ParamOverride     *string `json:"param_override" gorm:"type:text"`
 	HeaderOverride    *string `json:"header_override" gorm:"type:text"`
 	Remark            *string `json:"remark" gorm:"type:varchar(255)" validate:"max=255"`
@@ -523,7 +527,8 @@ func (channel *Channel) Insert() error {
 	return err
 }
 
-func (channel *Channel) Update() error {
+// syncMultiKeySize 按当前密钥列表重新计算多密钥渠道的 MultiKeySize，并清理超出范围的状态记录
+func (channel *Channel) syncMultiKeySize() {
 	// If this is a multi-key channel, recalculate MultiKeySize based on the current key list to avoid inconsistency after editing keys
 	if channel.ChannelInfo.IsMultiKey {
 		var keyStr string
@@ -562,6 +567,10 @@ func (channel *Channel) Update() error {
 			}
 		}
 	}
+}
+
+func (channel *Channel) Update() error {
+	channel.syncMultiKeySize()
 	var err error
 	err = DB.Model(channel).Updates(channel).Error
 	if err != nil {
@@ -572,6 +581,33 @@ func (channel *Channel) Update() error {
 	return err
 }
 
+// SaveImportedChannels 在同一事务中创建和更新导入的渠道，任一渠道失败则全部回滚
+func SaveImportedChannels(creates []*Channel, updates []*Channel) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		for _, channel := range creates {
+			if err := tx.Create(channel).Error; err != nil {
+				return fmt.Errorf("channel %q: %w", channel.Name, err)
+			}
+			if err := channel.AddAbilities(tx); err != nil {
+				return fmt.Errorf("channel %q: %w", channel.Name, err)
+			}
+		}
+		for _, channel := range updates {
+			channel.syncMultiKeySize()
+			if err := tx.Model(channel).Updates(channel).Error; err != nil {
+				return fmt.Errorf("channel %q: %w", channel.Name, err)
+			}
+			if err := tx.First(channel, "id = ?", channel.Id).Error; err != nil {
+				return fmt.Errorf("channel %q: %w", channel.Name, err)
+			}
+			if err := channel.UpdateAbilities(tx); err != nil {
+				return fmt.Errorf("channel %q: %w", channel.Name, err)
+			}
+		}
+		return nil
+	})
+}
+
 func (channel *Channel) UpdateResponseTime(responseTime int64) {
 	err := DB.Model(channel).Select("response_time", "test_time").Updates(Channel{
 		TestTime:     common.GetTimestamp(),
@@ -774,6 +810,7 @@ func UpdateChannelStatus(channelId int, usingKey string, status int, reason stri
 			common.SysLog(fmt.Sprintf("failed to update channel status: channel_id=%d, status=%d, error=%v", channel.Id, status, err))
 			return false
 		}
+		common.PublishCacheInvalidation(common.CacheInvalidationKindChannels, strconv.Itoa(channelId))
 	}
 	return true
 }
@@ -962,6 +999,46 @@ func (channel *Channel) ValidateSettings() error {
 			return err
 		}
 	}
+	if policy := channelOtherSettings.RetryPolicy; policy != nil {
+		if policy.RetryTimes != nil && (*policy.RetryTimes < 0 || *policy.RetryTimes > operation_setting.MaxChannelRetryTimes) {
+			return fmt.Errorf("retry_policy.retry_times must be between 0 and %d", operation_setting.MaxChannelRetryTimes)
+		}
+		if _, err := operation_setting.ParseHTTPStatusCodeRanges(policy.StatusCodes); err != nil {
+			return fmt.Errorf("retry_policy.status_codes: %w", err)
+		}
+		if policy.BackoffMs < 0 || policy.BackoffMs > operation_setting.MaxChannelRetryBackoffMs || policy.MaxBackoffMs < 0 || policy.MaxBackoffMs > operation_setting.MaxChannelRetryBackoffMs {
+			return fmt.Errorf("retry_policy backoff must be between 0 and %d ms", operation_setting.MaxChannelRetryBackoffMs)
+		}
+	}
+	if channelOtherSettings.MaxConcurrency < 0 {
+		return fmt.Errorf("max_concurrency must not be negative")
+	}
+	for i, override := range channelOtherSettings.ErrorOverrides {
+		if _, err := regexp.Compile(override.Pattern); err != nil || override.Pattern == "" {
+			return fmt.Errorf("error_overrides[%d].pattern is not a valid regular expression", i)
+		}
+		if override.Message == "" {
+			return fmt.Errorf("error_overrides[%d].message is required", i)
+		}
+		if override.StatusCode != 0 && (override.StatusCode < 400 || override.StatusCode > 599) {
+			return fmt.Errorf("error_overrides[%d].status_code must be between 400 and 599", i)
+		}
+	}
+	for modelName, price := range channelOtherSettings.ModelPrices {
+		if (price.ModelPrice != nil && *price.ModelPrice < 0) || (price.ModelRatio != nil && *price.ModelRatio < 0) || (price.CompletionRatio != nil && *price.CompletionRatio < 0) {
+			return fmt.Errorf("model_prices.%s must not be negative", modelName)
+		}
+	}
+	switch channelOtherSettings.StreamOptionsMode {
+	case "", dto.StreamOptionsModeEnabled, dto.StreamOptionsModeDisabled:
+	default:
+		return fmt.Errorf("invalid stream_options_mode: %s", channelOtherSettings.StreamOptionsMode)
+	}
+	switch channelOtherSettings.JsonSchemaShimMode {
+	case "", dto.JsonSchemaShimModeEnabled, dto.JsonSchemaShimModeDisabled:
+	default:
+		return fmt.Errorf("invalid json_schema_shim_mode: %s", channelOtherSettings.JsonSchemaShimMode)
+	}
 	return nil
 }
 
@@ -0,0 +1,174 @@
+package model
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+)
+
+// channelBreakerNow is the clock used by the breaker, replaceable in tests.
+var channelBreakerNow = common.GetTimestamp
+
+type channelBreakerState struct {
+	mu          sync.Mutex
+	windowStart int64
+	requests    int
+	failures    int
+	// openUntil is zero while the breaker is closed. Once it has passed the
+	// breaker is half-open and lets a single probe request through.
+	openUntil int64
+	probeAt   int64
+}
+
+// channelBreakers holds per-node breaker state keyed by channel id. Like the
+// latency stats it is kept in memory, so every node trips independently.
+var channelBreakers sync.Map
+
+func getChannelBreakerState(channelId int) *channelBreakerState {
+	value, ok := channelBreakers.Load(channelId)
+	if !ok {
+		return nil
+	}
+	return value.(*channelBreakerState)
+}
+
+// RecordChannelBreakerResult feeds the outcome of an upstream request into the
+// channel's breaker. Only upstream failures (5xx, 429, network errors) should
+// be reported as unsuccessful; client errors say nothing about channel health.
+func RecordChannelBreakerResult(channelId int, success bool) {
+	setting := operation_setting.GetChannelBreakerSetting()
+	if !setting.Enabled || channelId <= 0 {
+		return
+	}
+	value, _ := channelBreakers.LoadOrStore(channelId, &channelBreakerState{})
+	state := value.(*channelBreakerState)
+	now := channelBreakerNow()
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if state.openUntil > 0 {
+		if now < state.openUntil {
+			// late results of requests sent before the breaker opened
+			return
+		}
+		if success {
+			state.windowStart, state.requests, state.failures = now, 0, 0
+			state.openUntil, state.probeAt = 0, 0
+			common.SysLog(fmt.Sprintf("channel #%d circuit breaker closed after a successful probe", channelId))
+		} else {
+			state.openUntil = now + int64(setting.OpenSeconds)
+			state.probeAt = 0
+			common.SysLog(fmt.Sprintf("channel #%d circuit breaker probe failed, reopened for %ds", channelId, setting.OpenSeconds))
+		}
+		return
+	}
+	if now-state.windowStart >= int64(setting.WindowSeconds) {
+		state.windowStart, state.requests, state.failures = now, 0, 0
+	}
+	state.requests++
+	if !success {
+		state.failures++
+	}
+	if state.requests >= setting.MinRequests && state.failures*100 >= setting.ErrorRatePercent*state.requests {
+		state.openUntil = now + int64(setting.OpenSeconds)
+		common.SysLog(fmt.Sprintf("channel #%d circuit breaker opened for %ds: %d/%d upstream failures",
+			channelId, setting.OpenSeconds, state.failures, state.requests))
+	}
+}
+
+// channelBreakerAvailable reports whether a channel may be selected: its
+// breaker is closed, or half-open with no probe in flight. A probe that never
+// reported back is considered lost after another open period.
+func channelBreakerAvailable(channelId int, now int64) bool {
+	state := getChannelBreakerState(channelId)
+	if state == nil {
+		return true
+	}
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if state.openUntil == 0 {
+		return true
+	}
+	if now < state.openUntil {
+		return false
+	}
+	return state.probeAt == 0 || now-state.probeAt >= int64(operation_setting.GetChannelBreakerSetting().OpenSeconds)
+}
+
+// markChannelBreakerSelected claims the half-open probe slot when a half-open
+// channel is picked, so concurrent requests keep routing around it.
+func markChannelBreakerSelected(channelId int) {
+	if !operation_setting.GetChannelBreakerSetting().Enabled {
+		return
+	}
+	state := getChannelBreakerState(channelId)
+	if state == nil {
+		return
+	}
+	now := channelBreakerNow()
+	state.mu.Lock()
+	if state.openUntil > 0 && now >= state.openUntil {
+		state.probeAt = now
+	}
+	state.mu.Unlock()
+}
+
+// filterChannelIdsByBreaker drops channels whose breaker is open. When every
+// candidate is open the original list is returned, so a fully tripped model
+// still gets served instead of failing outright.
+func filterChannelIdsByBreaker(channelIds []int) []int {
+	if !operation_setting.GetChannelBreakerSetting().Enabled || len(channelIds) == 0 {
+		return channelIds
+	}
+	now := channelBreakerNow()
+	available := make([]int, 0, len(channelIds))
+	for _, channelId := range channelIds {
+		if channelBreakerAvailable(channelId, now) {
+			available = append(available, channelId)
+		}
+	}
+	if len(available) == 0 {
+		return channelIds
+	}
+	return available
+}
+
+// GetModelAvailability counts, per model, the enabled channels serving it in
+// any of groups and how many of them currently have a closed (or half-open)
+// breaker. Channels auto-disabled by health checks have no enabled ability and
+// are not counted.
+func GetModelAvailability(groups []string) (map[string]dto.ModelAvailability, error) {
+	result := make(map[string]dto.ModelAvailability)
+	if len(groups) == 0 {
+		return result, nil
+	}
+	var abilities []Ability
+	err := DB.Model(&Ability{}).Select("model", "channel_id").
+		Where(commonGroupCol+" IN ? AND enabled = ?", groups, true).
+		Find(&abilities).Error
+	if err != nil {
+		return nil, err
+	}
+	breakerEnabled := operation_setting.GetChannelBreakerSetting().Enabled
+	now := channelBreakerNow()
+	seen := make(map[string]bool, len(abilities))
+	for _, ability := range abilities {
+		// a channel in several of the groups has one ability per group
+		key := fmt.Sprintf("%s|%d", ability.Model, ability.ChannelId)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		availability := result[ability.Model]
+		availability.Channels++
+		if !breakerEnabled || channelBreakerAvailable(ability.ChannelId, now) {
+			availability.HealthyChannels++
+			availability.Available = true
+		}
+		result[ability.Model] = availability
+	}
+	return result, nil
+}
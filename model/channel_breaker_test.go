@@ -0,0 +1,115 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func enableChannelBreakerForTest(t *testing.T, now *int64) {
+	t.Helper()
+	overrideForTest(t, operation_setting.GetChannelBreakerSetting(), operation_setting.ChannelBreakerSetting{
+		Enabled:          true,
+		WindowSeconds:    60,
+		MinRequests:      4,
+		ErrorRatePercent: 50,
+		OpenSeconds:      30,
+	})
+	overrideForTest(t, &channelBreakerNow, func() int64 { return *now })
+	t.Cleanup(func() {
+		channelBreakers.Range(func(key, _ any) bool {
+			channelBreakers.Delete(key)
+			return true
+		})
+	})
+}
+
+func TestChannelBreakerTransitions(t *testing.T) {
+	now := int64(1000)
+	enableChannelBreakerForTest(t, &now)
+	const channelId = 9401
+
+	steps := []struct {
+		name      string
+		advance   int64
+		results   []bool
+		selected  bool
+		available bool
+	}{
+		{name: "below min requests stays closed", results: []bool{false, false, false}, available: true},
+		{name: "error rate reached opens", results: []bool{true}, available: false},
+		{name: "still open before cooldown", advance: 29, available: false},
+		{name: "half-open lets one probe through", advance: 1, available: true},
+		{name: "probe in flight blocks others", selected: true, available: false},
+		{name: "failed probe reopens", results: []bool{false}, available: false},
+		{name: "half-open again after cooldown", advance: 30, selected: true, available: false},
+		{name: "successful probe closes", results: []bool{true}, available: true},
+		{name: "window reset after close", results: []bool{false, false, false}, available: true},
+	}
+	for _, step := range steps {
+		now += step.advance
+		if step.selected {
+			require.True(t, channelBreakerAvailable(channelId, now), step.name)
+			markChannelBreakerSelected(channelId)
+		}
+		for _, success := range step.results {
+			RecordChannelBreakerResult(channelId, success)
+		}
+		assert.Equal(t, step.available, channelBreakerAvailable(channelId, now), step.name)
+	}
+}
+
+func TestChannelSelectSkipsOpenBreakers(t *testing.T) {
+	now := int64(1000)
+	enableChannelBreakerForTest(t, &now)
+	setChannelSelectCacheForTest(t,
+		newSelectTestChannel(9411, 10, 1),
+		newSelectTestChannel(9412, 10, 1),
+		newSelectTestChannel(9413, 5, 1),
+	)
+	trip := func(channelId int) {
+		for i := 0; i < 4; i++ {
+			RecordChannelBreakerResult(channelId, false)
+		}
+	}
+
+	trip(9411)
+	assert.Equal(t, map[int]int{9412: 100}, countChannelPicks(t, 0, 100))
+
+	// an open top tier falls through to the next priority
+	trip(9412)
+	assert.Equal(t, map[int]int{9413: 100}, countChannelPicks(t, 0, 100))
+
+	// with every candidate open the breaker fails open
+	trip(9413)
+	assert.Len(t, countChannelPicks(t, 0, 100), 2)
+}
+
+func TestGetModelAvailability(t *testing.T) {
+	truncateTables(t)
+	now := int64(1000)
+	enableChannelBreakerForTest(t, &now)
+	channels := []*Channel{
+		{Id: 9421, Name: "a", Key: "k", Models: "gpt-4o,o3", Group: "default,vip", Status: common.ChannelStatusEnabled},
+		{Id: 9422, Name: "b", Key: "k", Models: "gpt-4o", Group: "vip", Status: common.ChannelStatusEnabled},
+		{Id: 9423, Name: "c", Key: "k", Models: "o3", Group: "default", Status: common.ChannelStatusManuallyDisabled},
+	}
+	for _, channel := range channels {
+		require.NoError(t, DB.Create(channel).Error)
+		require.NoError(t, channel.AddAbilities(nil))
+	}
+	for i := 0; i < 4; i++ {
+		RecordChannelBreakerResult(9421, false)
+	}
+
+	availability, err := GetModelAvailability([]string{"default", "vip"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]dto.ModelAvailability{
+		"gpt-4o": {Available: true, Channels: 2, HealthyChannels: 1},
+		"o3":     {Available: false, Channels: 1, HealthyChannels: 0},
+	}, availability)
+}
@@ -7,12 +7,14 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/dto"
 	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
 	"github.com/QuantumNous/new-api/setting/ratio_setting"
 )
 
@@ -23,6 +25,34 @@ var channelsIDM map[int]*Channel                     // all channels include dis
 var channel2advancedCustomConfig map[int]*dto.AdvancedCustomConfig
 var channelSyncLock sync.RWMutex
 
+// RefreshChannelCache reloads the routing cache after a channel mutation and
+// tells the other replicas to do the same, so the change is routed
+// consistently cluster-wide without waiting for SyncChannelCache.
+func RefreshChannelCache() {
+	InitChannelCache()
+	common.PublishCacheInvalidation(common.CacheInvalidationKindChannels, "")
+}
+
+var channelCacheReloadPending atomic.Bool
+
+func init() {
+	common.RegisterCacheInvalidationHandler(common.CacheInvalidationKindChannels, func(common.CacheInvalidationEvent) {
+		scheduleChannelCacheReload()
+	})
+}
+
+// scheduleChannelCacheReload coalesces bursts of remote invalidations (e.g. a
+// batch edit publishing once per channel) into a single reload.
+func scheduleChannelCacheReload() {
+	if !channelCacheReloadPending.CompareAndSwap(false, true) {
+		return
+	}
+	time.AfterFunc(500*time.Millisecond, func() {
+		channelCacheReloadPending.Store(false)
+		InitChannelCache()
+	})
+}
+
 func InitChannelCache() {
 	if !common.MemoryCacheEnabled {
 		InvalidatePricingCache()
@@ -30,15 +60,20 @@ func InitChannelCache() {
 	}
 	newChannelId2channel := make(map[int]*Channel)
 	newChannel2advancedCustomConfig := make(map[int]*dto.AdvancedCustomConfig)
+	newChannel2maxConcurrency := make(map[int]int)
 	var channels []*Channel
 	DB.Find(&channels)
 	for _, channel := range channels {
 		newChannelId2channel[channel.Id] = channel
+		otherSettings := channel.GetOtherSettings()
 		if channel.Type == constant.ChannelTypeAdvancedCustom {
-			if config := channel.GetOtherSettings().AdvancedCustom; config != nil {
+			if config := otherSettings.AdvancedCustom; config != nil {
 				newChannel2advancedCustomConfig[channel.Id] = config
 			}
 		}
+		if otherSettings.MaxConcurrency > 0 {
+			newChannel2maxConcurrency[channel.Id] = otherSettings.MaxConcurrency
+		}
 	}
 	var abilities []*Ability
 	DB.Find(&abilities)
@@ -94,6 +129,7 @@ func InitChannelCache() {
 	}
 	channelsIDM = newChannelId2channel
 	channel2advancedCustomConfig = newChannel2advancedCustomConfig
+	channel2maxConcurrency = newChannel2maxConcurrency
 	channelSyncLock.Unlock()
 	// Lock ordering: InvalidatePricingCache acquires updatePricingLock, and
 	// GetPricing (holding updatePricingLock) nests channelSyncLock.RLock via
@@ -112,6 +148,14 @@ func SyncChannelCache(frequency int) {
 }
 
 func GetRandomSatisfiedChannel(group string, model string, retry int, requestPath string) (*Channel, error) {
+	channel, err := getRandomSatisfiedChannel(group, model, retry, requestPath)
+	if channel != nil {
+		markChannelBreakerSelected(channel.Id)
+	}
+	return channel, err
+}
+
+func getRandomSatisfiedChannel(group string, model string, retry int, requestPath string) (*Channel, error) {
 	// if memory cache is disabled, get channel directly from database
 	if !common.MemoryCacheEnabled {
 		return GetChannel(group, model, retry, requestPath)
@@ -133,6 +177,23 @@ func GetRandomSatisfiedChannel(group string, model string, retry int, requestPat
 		return nil, nil
 	}
 
+	if rule, ok := operation_setting.GetChannelTagRoutingRule(group); ok {
+		channels = applyChannelTagRouting(rule, channels, func(channelId int) []string {
+			if channel, ok := channelsIDM[channelId]; ok {
+				return channel.GetTags()
+			}
+			return nil
+		})
+		if len(channels) == 0 {
+			return nil, nil
+		}
+	}
+
+	// route around channels whose circuit breaker is open
+	channels = filterChannelIdsByBreaker(channels)
+	// and around channels already at their max_concurrency
+	channels = filterChannelIdsBySaturation(channels)
+
 	if len(channels) == 1 {
 		if channel, ok := channelsIDM[channels[0]]; ok {
 			return channel, nil
@@ -160,12 +221,10 @@ func GetRandomSatisfiedChannel(group string, model string, retry int, requestPat
 	targetPriority := int64(sortedUniquePriorities[retry])
 
 	// get the priority for the given retry number
-	var sumWeight = 0
 	var targetChannels []*Channel
 	for _, channelId := range channels {
 		if channel, ok := channelsIDM[channelId]; ok {
 			if channel.GetPriority() == targetPriority {
-				sumWeight += channel.GetWeight()
 				targetChannels = append(targetChannels, channel)
 			}
 		} else {
@@ -177,6 +236,50 @@ func GetRandomSatisfiedChannel(group string, model string, retry int, requestPat
 		return nil, errors.New(fmt.Sprintf("no channel found, group: %s, model: %s, priority: %d", group, model, targetPriority))
 	}
 
+	return selectChannelInTier(operation_setting.GetChannelSelectStrategy(group, model), targetChannels), nil
+}
+
+// selectChannelInTier picks one of the channels sharing the target priority
+// according to the channel select strategy. Falling back to the next priority
+// is driven by the retry count, independent of the strategy.
+func selectChannelInTier(strategy string, channels []*Channel) *Channel {
+	switch strategy {
+	case operation_setting.ChannelSelectStrategyRandom:
+		return channels[rand.Intn(len(channels))]
+	case operation_setting.ChannelSelectStrategyLeastLatency:
+		if channel := pickLeastLatencyChannel(channels); channel != nil {
+			return channel
+		}
+	}
+	return pickWeightedChannel(channels)
+}
+
+// pickLeastLatencyChannel returns the measured channel with the lowest rolling
+// latency. It returns nil, i.e. weighted selection, when no channel has been
+// measured yet and for ExplorePercent of requests, so that unmeasured and
+// slower channels keep receiving samples.
+func pickLeastLatencyChannel(channels []*Channel) *Channel {
+	var fastest *Channel
+	fastestMs := 0.0
+	for _, channel := range channels {
+		latencyMs, ok := GetChannelLatency(channel.Id)
+		if ok && (fastest == nil || latencyMs < fastestMs) {
+			fastest = channel
+			fastestMs = latencyMs
+		}
+	}
+	if fastest == nil || rand.Intn(100) < operation_setting.GetChannelSelectSetting().ExplorePercent {
+		return nil
+	}
+	return fastest
+}
+
+func pickWeightedChannel(channels []*Channel) *Channel {
+	sumWeight := 0
+	for _, channel := range channels {
+		sumWeight += channel.GetWeight()
+	}
+
 	// smoothing factor and adjustment
 	smoothingFactor := 1
 	smoothingAdjustment := 0
@@ -184,9 +287,9 @@ func GetRandomSatisfiedChannel(group string, model string, retry int, requestPat
 	if sumWeight == 0 {
 		// when all channels have weight 0, set sumWeight to the number of channels and set smoothing adjustment to 100
 		// each channel's effective weight = 100
-		sumWeight = len(targetChannels) * 100
+		sumWeight = len(channels) * 100
 		smoothingAdjustment = 100
-	} else if sumWeight/len(targetChannels) < 10 {
+	} else if sumWeight/len(channels) < 10 {
 		// when the average weight is less than 10, set smoothing factor to 100
 		smoothingFactor = 100
 	}
@@ -198,14 +301,13 @@ func GetRandomSatisfiedChannel(group string, model string, retry int, requestPat
 	randomWeight := rand.Intn(totalWeight)
 
 	// Find a channel based on its weight
-	for _, channel := range targetChannels {
+	for _, channel := range channels {
 		randomWeight -= channel.GetWeight()*smoothingFactor + smoothingAdjustment
 		if randomWeight < 0 {
-			return channel, nil
+			return channel
 		}
 	}
-	// return null if no channel is not found
-	return nil, errors.New("channel not found")
+	return channels[len(channels)-1]
 }
 
 // filterChannelsByRequestPathAndModel restricts candidates by request path and
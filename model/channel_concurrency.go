@@ -0,0 +1,75 @@
+package model
+
+import "sync"
+
+// channelInflight counts requests currently relayed to each channel that has
+// a max_concurrency cap. Like the breaker state it is kept in memory, so caps
+// apply per node.
+var (
+	channelInflightMu sync.Mutex
+	channelInflight   = map[int]int{}
+)
+
+// channel2maxConcurrency caches the max_concurrency of capped channels so
+// selection can skip saturated ones without parsing settings per request.
+// Refreshed on full sync, guarded by channelSyncLock.
+var channel2maxConcurrency map[int]int
+
+// TryAcquireChannelSlot takes one in-flight slot of a channel when it is below
+// limit. A limit of 0 or less means uncapped and always succeeds untracked.
+func TryAcquireChannelSlot(channelId int, limit int) bool {
+	if limit <= 0 {
+		return true
+	}
+	channelInflightMu.Lock()
+	defer channelInflightMu.Unlock()
+	if channelInflight[channelId] >= limit {
+		return false
+	}
+	channelInflight[channelId]++
+	return true
+}
+
+// ReleaseChannelSlot gives back a slot taken by TryAcquireChannelSlot.
+func ReleaseChannelSlot(channelId int) {
+	channelInflightMu.Lock()
+	defer channelInflightMu.Unlock()
+	channelInflight[channelId]--
+	if channelInflight[channelId] <= 0 {
+		delete(channelInflight, channelId)
+	}
+}
+
+// GetChannelInflight returns the number of in-flight requests per capped
+// channel.
+func GetChannelInflight() map[int]int {
+	channelInflightMu.Lock()
+	defer channelInflightMu.Unlock()
+	inflight := make(map[int]int, len(channelInflight))
+	for channelId, count := range channelInflight {
+		inflight[channelId] = count
+	}
+	return inflight
+}
+
+// filterChannelIdsBySaturation drops channels that have reached their
+// max_concurrency. When every candidate is saturated the original list is
+// returned, so the request is assigned a channel and waits in its queue.
+// Caller must hold channelSyncLock (read lock).
+func filterChannelIdsBySaturation(channelIds []int) []int {
+	if len(channel2maxConcurrency) == 0 || len(channelIds) == 0 {
+		return channelIds
+	}
+	channelInflightMu.Lock()
+	defer channelInflightMu.Unlock()
+	available := make([]int, 0, len(channelIds))
+	for _, channelId := range channelIds {
+		if limit, ok := channel2maxConcurrency[channelId]; !ok || channelInflight[channelId] < limit {
+			available = append(available, channelId)
+		}
+	}
+	if len(available) == 0 {
+		return channelIds
+	}
+	return available
+}
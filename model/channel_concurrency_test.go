@@ -0,0 +1,41 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelSelectSkipsSaturatedChannels(t *testing.T) {
+	setChannelSelectCacheForTest(t,
+		newSelectTestChannel(9511, 10, 1),
+		newSelectTestChannel(9512, 10, 1),
+		newSelectTestChannel(9513, 5, 1),
+	)
+	channelSyncLock.Lock()
+	oldLimits := channel2maxConcurrency
+	channel2maxConcurrency = map[int]int{9511: 1, 9512: 1, 9513: 1}
+	channelSyncLock.Unlock()
+	t.Cleanup(func() {
+		channelSyncLock.Lock()
+		channel2maxConcurrency = oldLimits
+		channelSyncLock.Unlock()
+	})
+
+	require.True(t, TryAcquireChannelSlot(9511, 1))
+	defer ReleaseChannelSlot(9511)
+	assert.False(t, TryAcquireChannelSlot(9511, 1))
+	assert.Equal(t, map[int]int{9512: 100}, countChannelPicks(t, 0, 100))
+
+	// a saturated top tier falls through to the next priority
+	require.True(t, TryAcquireChannelSlot(9512, 1))
+	defer ReleaseChannelSlot(9512)
+	assert.Equal(t, map[int]int{9513: 100}, countChannelPicks(t, 0, 100))
+
+	// with every candidate saturated selection still assigns one to queue on
+	require.True(t, TryAcquireChannelSlot(9513, 1))
+	defer ReleaseChannelSlot(9513)
+	assert.Len(t, countChannelPicks(t, 0, 100), 2)
+	assert.Equal(t, map[int]int{9511: 1, 9512: 1, 9513: 1}, GetChannelInflight())
+}
@@ -0,0 +1,124 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+const channelHealthErrorMaxLength = 512
+
+// ChannelHealth is one automatic health probe of a channel. Rows are written by
+// the scheduled channel test and pruned after the monitor retention window.
+type ChannelHealth struct {
+	Id           int    `json:"id" gorm:"primaryKey;autoIncrement"`
+	ChannelId    int    `json:"channel_id" gorm:"index:idx_channel_health_channel_time,priority:1"`
+	CreatedAt    int64  `json:"created_at" gorm:"bigint;index:idx_channel_health_channel_time,priority:2;index"`
+	Success      bool   `json:"success"`
+	LatencyMs    int64  `json:"latency_ms"`
+	StatusCode   int    `json:"status_code"`
+	ErrorMessage string `json:"error_message" gorm:"type:varchar(512)"`
+}
+
+func (ChannelHealth) TableName() string {
+	return "channel_health"
+}
+
+// ChannelHealthSummary aggregates the probes of one channel over a window.
+type ChannelHealthSummary struct {
+	ChannelId    int     `json:"channel_id"`
+	Total        int64   `json:"total"`
+	Succeeded    int64   `json:"succeeded"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+	LastProbeAt  int64   `json:"last_probe_at"`
+}
+
+func RecordChannelHealth(health *ChannelHealth) {
+	if health.CreatedAt == 0 {
+		health.CreatedAt = common.GetTimestamp()
+	}
+	if runes := []rune(health.ErrorMessage); len(runes) > channelHealthErrorMaxLength {
+		health.ErrorMessage = string(runes[:channelHealthErrorMaxLength])
+	}
+	if err := DB.Create(health).Error; err != nil {
+		common.SysLog(fmt.Sprintf("failed to record channel health: channel_id=%d, error=%v", health.ChannelId, err))
+	}
+}
+
+// CountChannelConsecutiveFailures returns how many of the latest probes of the
+// channel failed in a row, looking at no more than limit probes.
+func CountChannelConsecutiveFailures(channelId int, limit int) (int, error) {
+	var results []bool
+	err := DB.Model(&ChannelHealth{}).
+		Where("channel_id = ?", channelId).
+		Order("id DESC").
+		Limit(limit).
+		Pluck("success", &results).Error
+	if err != nil {
+		return 0, err
+	}
+	failures := 0
+	for _, success := range results {
+		if success {
+			break
+		}
+		failures++
+	}
+	return failures, nil
+}
+
+// GetChannelHealthHistory returns the probes of a channel since the given
+// timestamp, newest first.
+func GetChannelHealthHistory(channelId int, since int64, limit int) ([]*ChannelHealth, error) {
+	var history []*ChannelHealth
+	err := DB.Where("channel_id = ? AND created_at >= ?", channelId, since).
+		Order("id DESC").
+		Limit(limit).
+		Find(&history).Error
+	return history, err
+}
+
+// GetChannelHealthSummaries aggregates the probes of every channel since the
+// given timestamp.
+func GetChannelHealthSummaries(since int64) ([]*ChannelHealthSummary, error) {
+	var summaries []*ChannelHealthSummary
+	err := DB.Model(&ChannelHealth{}).
+		Select("channel_id, COUNT(*) AS total, SUM(CASE WHEN success = ? THEN 1 ELSE 0 END) AS succeeded, AVG(latency_ms) AS avg_latency_ms, MAX(created_at) AS last_probe_at", true).
+		Where("created_at >= ?", since).
+		Group("channel_id").
+		Order("channel_id").
+		Scan(&summaries).Error
+	return summaries, err
+}
+
+// ModelHealthBucket counts the probes of the enabled channels serving a model
+// that share an outcome and, for successful probes, a latency. Failed probes
+// are collapsed into one bucket with zero latency.
+type ModelHealthBucket struct {
+	Model       string `json:"model"`
+	Success     bool   `json:"success"`
+	LatencyMs   int64  `json:"latency_ms" gorm:"column:bucket_latency_ms"`
+	Probes      int64  `json:"probes"`
+	LastProbeAt int64  `json:"last_probe_at"`
+}
+
+// GetModelHealthBuckets aggregates the probes since the given timestamp per
+// model. A channel counts once per model however many groups it serves it in.
+func GetModelHealthBuckets(since int64) ([]ModelHealthBucket, error) {
+	var buckets []ModelHealthBucket
+	modelChannels := DB.Model(&Ability{}).Distinct("model", "channel_id").Where("enabled = ?", true)
+	err := DB.Table("channel_health AS h").
+		Joins("JOIN (?) AS a ON a.channel_id = h.channel_id", modelChannels).
+		Select("a.model AS model, h.success AS success, CASE WHEN h.success = ? THEN h.latency_ms ELSE 0 END AS bucket_latency_ms, COUNT(*) AS probes, MAX(h.created_at) AS last_probe_at", true).
+		Where("h.created_at >= ?", since).
+		Group("a.model, h.success, bucket_latency_ms").
+		Scan(&buckets).Error
+	return buckets, err
+}
+
+func DeleteChannelHealthBefore(cutoff int64) error {
+	if cutoff <= 0 {
+		return nil
+	}
+	return DB.Where("created_at < ?", cutoff).Delete(&ChannelHealth{}).Error
+}
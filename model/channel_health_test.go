@@ -0,0 +1,67 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelHealth_ConsecutiveFailuresAndSummary(t *testing.T) {
+	truncateTables(t)
+
+	probes := []bool{true, false, true, false, false}
+	for i, success := range probes {
+		RecordChannelHealth(&ChannelHealth{ChannelId: 1, Success: success, LatencyMs: int64(100 * (i + 1)), CreatedAt: int64(1000 + i)})
+	}
+	RecordChannelHealth(&ChannelHealth{ChannelId: 2, Success: true, LatencyMs: 50, CreatedAt: 1000})
+
+	tests := []struct {
+		name  string
+		limit int
+		want  int
+	}{
+		{name: "stops at last success", limit: 5, want: 2},
+		{name: "bounded by limit", limit: 1, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failures, err := CountChannelConsecutiveFailures(1, tt.limit)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, failures)
+		})
+	}
+
+	summaries, err := GetChannelHealthSummaries(1002)
+	require.NoError(t, err)
+	require.Len(t, summaries, 1)
+	assert.Equal(t, 1, summaries[0].ChannelId)
+	assert.EqualValues(t, 3, summaries[0].Total)
+	assert.EqualValues(t, 1, summaries[0].Succeeded)
+	assert.Equal(t, 400.0, summaries[0].AvgLatencyMs)
+	assert.EqualValues(t, 1004, summaries[0].LastProbeAt)
+
+	// 渠道 1 在两个分组中提供 gpt 也只计一次；已禁用的 other 不参与统计
+	require.NoError(t, DB.Create(&[]Ability{
+		{Group: "default", Model: "gpt", ChannelId: 1, Enabled: true},
+		{Group: "vip", Model: "gpt", ChannelId: 1, Enabled: true},
+		{Group: "default", Model: "gpt", ChannelId: 2, Enabled: true},
+		{Group: "default", Model: "other", ChannelId: 1, Enabled: false},
+	}).Error)
+	buckets, err := GetModelHealthBuckets(1002)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []ModelHealthBucket{
+		{Model: "gpt", Success: true, LatencyMs: 300, Probes: 1, LastProbeAt: 1002},
+		{Model: "gpt", Success: false, Probes: 2, LastProbeAt: 1004},
+	}, buckets)
+
+	history, err := GetChannelHealthHistory(1, 0, 2)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.EqualValues(t, 1004, history[0].CreatedAt)
+
+	require.NoError(t, DeleteChannelHealthBefore(1003))
+	history, err = GetChannelHealthHistory(1, 0, 10)
+	require.NoError(t, err)
+	assert.Len(t, history, 2)
+}
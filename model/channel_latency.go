@@ -0,0 +1,48 @@
+package model
+
+import (
+	"sync"
+)
+
+// channelLatencyAlpha is the weight of the newest sample in the rolling
+// (exponentially weighted) channel latency average.
+const channelLatencyAlpha = 0.2
+
+type channelLatencyStat struct {
+	mu     sync.Mutex
+	ewmaMs float64
+}
+
+// channelLatencyStats holds per-node rolling latency of successful relays,
+// keyed by channel id. It only feeds least-latency channel selection, so it is
+// kept in memory and starts empty after a restart.
+var channelLatencyStats sync.Map
+
+// RecordChannelLatency folds a successful relay latency into the channel's
+// rolling average.
+func RecordChannelLatency(channelId int, latencyMs int64) {
+	if channelId <= 0 || latencyMs < 0 {
+		return
+	}
+	value, loaded := channelLatencyStats.LoadOrStore(channelId, &channelLatencyStat{ewmaMs: float64(latencyMs)})
+	if !loaded {
+		return
+	}
+	stat := value.(*channelLatencyStat)
+	stat.mu.Lock()
+	stat.ewmaMs += channelLatencyAlpha * (float64(latencyMs) - stat.ewmaMs)
+	stat.mu.Unlock()
+}
+
+// GetChannelLatency returns the rolling latency of a channel in milliseconds
+// and whether any sample has been recorded.
+func GetChannelLatency(channelId int) (float64, bool) {
+	value, ok := channelLatencyStats.Load(channelId)
+	if !ok {
+		return 0, false
+	}
+	stat := value.(*channelLatencyStat)
+	stat.mu.Lock()
+	defer stat.mu.Unlock()
+	return stat.ewmaMs, true
+}
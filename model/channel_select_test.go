@@ -0,0 +1,162 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useChannelCacheForTest enables the in-memory channel cache and restores the
+// previous cache contents after the test.
+func useChannelCacheForTest(t *testing.T) {
+	t.Helper()
+	channelSyncLock.Lock()
+	oldGroups, oldChannels, oldMemoryCache := group2model2channels, channelsIDM, common.MemoryCacheEnabled
+	common.MemoryCacheEnabled = true
+	channelSyncLock.Unlock()
+	t.Cleanup(func() {
+		// 等待可能仍在排队的重新加载完成后再恢复
+		require.Eventually(t, func() bool { return !channelCacheReloadPending.Load() }, 3*time.Second, 20*time.Millisecond)
+		channelSyncLock.Lock()
+		group2model2channels, channelsIDM, common.MemoryCacheEnabled = oldGroups, oldChannels, oldMemoryCache
+		channelSyncLock.Unlock()
+	})
+}
+
+func setChannelSelectCacheForTest(t *testing.T, channels ...*Channel) {
+	t.Helper()
+	useChannelCacheForTest(t)
+	setting := operation_setting.GetChannelSelectSetting()
+	overrideForTest(t, setting, *setting)
+
+	channelSyncLock.Lock()
+	defer channelSyncLock.Unlock()
+	ids := make([]int, 0, len(channels))
+	channelsIDM = make(map[int]*Channel, len(channels))
+	for _, channel := range channels {
+		ids = append(ids, channel.Id)
+		channelsIDM[channel.Id] = channel
+	}
+	group2model2channels = map[string]map[string][]int{"default": {"gpt-test": ids}}
+}
+
+func newSelectTestChannel(id int, priority int64, weight uint) *Channel {
+	return &Channel{Id: id, Priority: &priority, Weight: &weight, Status: common.ChannelStatusEnabled}
+}
+
+func countChannelPicks(t *testing.T, retry int, rounds int) map[int]int {
+	t.Helper()
+	counts := make(map[int]int)
+	for i := 0; i < rounds; i++ {
+		channel, err := GetRandomSatisfiedChannel("default", "gpt-test", retry, "")
+		require.NoError(t, err)
+		require.NotNil(t, channel)
+		counts[channel.Id]++
+	}
+	return counts
+}
+
+func TestChannelSelectStrategyDistribution(t *testing.T) {
+	const rounds = 20000
+	tests := []struct {
+		name      string
+		strategy  string
+		channels  []*Channel
+		latencies map[int]int64
+		explore   int
+		want      map[int]float64 // expected share of picks per channel
+	}{
+		{
+			name:     "weighted follows channel weights",
+			strategy: operation_setting.ChannelSelectStrategyWeighted,
+			channels: []*Channel{newSelectTestChannel(9101, 0, 30), newSelectTestChannel(9102, 0, 70)},
+			want:     map[int]float64{9101: 0.3, 9102: 0.7},
+		},
+		{
+			name:     "random ignores weights",
+			strategy: operation_setting.ChannelSelectStrategyRandom,
+			channels: []*Channel{newSelectTestChannel(9111, 0, 1), newSelectTestChannel(9112, 0, 99)},
+			want:     map[int]float64{9111: 0.5, 9112: 0.5},
+		},
+		{
+			name:      "least latency picks the fastest channel",
+			strategy:  operation_setting.ChannelSelectStrategyLeastLatency,
+			channels:  []*Channel{newSelectTestChannel(9121, 0, 50), newSelectTestChannel(9122, 0, 50)},
+			latencies: map[int]int64{9121: 900, 9122: 300},
+			want:      map[int]float64{9122: 1},
+		},
+		{
+			name:      "least latency explores by weight",
+			strategy:  operation_setting.ChannelSelectStrategyLeastLatency,
+			channels:  []*Channel{newSelectTestChannel(9131, 0, 50), newSelectTestChannel(9132, 0, 50)},
+			latencies: map[int]int64{9131: 900, 9132: 300},
+			explore:   20,
+			want:      map[int]float64{9131: 0.1, 9132: 0.9},
+		},
+		{
+			name:     "least latency without samples falls back to weights",
+			strategy: operation_setting.ChannelSelectStrategyLeastLatency,
+			channels: []*Channel{newSelectTestChannel(9141, 0, 25), newSelectTestChannel(9142, 0, 75)},
+			want:     map[int]float64{9141: 0.25, 9142: 0.75},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setChannelSelectCacheForTest(t, tt.channels...)
+			setting := operation_setting.GetChannelSelectSetting()
+			setting.DefaultStrategy = tt.strategy
+			setting.ExplorePercent = tt.explore
+			for channelId, latencyMs := range tt.latencies {
+				RecordChannelLatency(channelId, latencyMs)
+			}
+
+			counts := countChannelPicks(t, 0, rounds)
+			for channelId, share := range tt.want {
+				assert.InDelta(t, share, float64(counts[channelId])/rounds, 0.03, "channel %d", channelId)
+			}
+		})
+	}
+}
+
+func TestChannelSelectPriorityTierFallback(t *testing.T) {
+	setChannelSelectCacheForTest(t,
+		newSelectTestChannel(9201, 10, 1),
+		newSelectTestChannel(9202, 10, 1),
+		newSelectTestChannel(9203, 5, 100),
+	)
+	operation_setting.GetChannelSelectSetting().Rules = []operation_setting.ChannelSelectRule{
+		{Group: "default", Model: "gpt-test", Strategy: operation_setting.ChannelSelectStrategyRandom},
+	}
+
+	tests := []struct {
+		retry int
+		want  []int
+	}{
+		{retry: 0, want: []int{9201, 9202}},
+		{retry: 1, want: []int{9203}},
+		{retry: 5, want: []int{9203}},
+	}
+	for _, tt := range tests {
+		counts := countChannelPicks(t, tt.retry, 200)
+		picked := make([]int, 0, len(counts))
+		for channelId := range counts {
+			picked = append(picked, channelId)
+		}
+		assert.ElementsMatch(t, tt.want, picked, "retry %d", tt.retry)
+	}
+}
+
+func TestRecordChannelLatencyRollingAverage(t *testing.T) {
+	RecordChannelLatency(9301, 1000)
+	RecordChannelLatency(9301, 500)
+	latencyMs, ok := GetChannelLatency(9301)
+	require.True(t, ok)
+	assert.InDelta(t, 900, latencyMs, 0.001)
+
+	_, ok = GetChannelLatency(9302)
+	assert.False(t, ok)
+}
@@ -0,0 +1,270 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"gorm.io/gorm"
+)
+
+// ChannelTagStat 路由标签的渠道统计
+type ChannelTagStat struct {
+	Tag      string `json:"tag"`
+	Channels int    `json:"channels"`
+	Enabled  int    `json:"enabled"`
+}
+
+// ChannelTagBatchEdit 按标签批量修改渠道，未设置的字段保持不变
+type ChannelTagBatchEdit struct {
+	Status       *int                              `json:"status,omitempty"`        // 1 启用，2 手动禁用
+	AddModels    []string                          `json:"add_models,omitempty"`    // 追加的模型
+	RemoveModels []string                          `json:"remove_models,omitempty"` // 移除的模型
+	ModelPrices  map[string]*dto.ChannelModelPrice `json:"model_prices,omitempty"`  // 合并到渠道级模型价格，值为 null 时删除该模型的覆盖
+	Priority     *int64                            `json:"priority,omitempty"`
+	Weight       *uint                             `json:"weight,omitempty"`
+}
+
+// NormalizeChannelTags 去除空白与重复的标签并排序
+func NormalizeChannelTags(tags []string) []string {
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !slices.Contains(result, tag) {
+			result = append(result, tag)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func (channel *Channel) GetTags() []string {
+	if channel.Tags == nil || *channel.Tags == "" {
+		return nil
+	}
+	return NormalizeChannelTags(strings.Split(*channel.Tags, ","))
+}
+
+func (channel *Channel) SetTags(tags []string) {
+	joined := strings.Join(NormalizeChannelTags(tags), ",")
+	channel.Tags = &joined
+}
+
+// matchChannelTags 判断渠道标签是否包含 want 中的任一标签，matchAll 为 true 时要求全部包含
+func matchChannelTags(tags []string, want []string, matchAll bool) bool {
+	if len(want) == 0 {
+		return false
+	}
+	for _, tag := range want {
+		has := slices.Contains(tags, tag)
+		if matchAll && !has {
+			return false
+		}
+		if !matchAll && has {
+			return true
+		}
+	}
+	return matchAll
+}
+
+// applyChannelTagRouting 按标签路由规则过滤候选渠道：先排除带有排除标签的渠道，
+// 剩余渠道中存在带有优先标签的渠道时只保留这些渠道
+func applyChannelTagRouting(rule operation_setting.ChannelTagRoutingRule, channelIds []int, tagsOf func(channelId int) []string) []int {
+	candidates := make([]int, 0, len(channelIds))
+	var preferred []int
+	for _, channelId := range channelIds {
+		tags := tagsOf(channelId)
+		if matchChannelTags(tags, rule.Exclude, false) {
+			continue
+		}
+		candidates = append(candidates, channelId)
+		if matchChannelTags(tags, rule.Prefer, false) {
+			preferred = append(preferred, channelId)
+		}
+	}
+	if len(preferred) > 0 {
+		return preferred
+	}
+	return candidates
+}
+
+// filterAbilitiesByTagRouting 数据库选路路径下按分组的标签路由规则过滤候选
+func filterAbilitiesByTagRouting(group string, abilities []Ability) []Ability {
+	rule, ok := operation_setting.GetChannelTagRoutingRule(group)
+	if !ok || len(abilities) == 0 {
+		return abilities
+	}
+	channelIds := make([]int, 0, len(abilities))
+	for _, ability := range abilities {
+		channelIds = append(channelIds, ability.ChannelId)
+	}
+	var channels []*Channel
+	if err := DB.Select("id", "tags").Where("id IN ?", channelIds).Find(&channels).Error; err != nil {
+		// 查询失败时不过滤，避免阻塞选路
+		return abilities
+	}
+	tagsById := make(map[int][]string, len(channels))
+	for _, channel := range channels {
+		tagsById[channel.Id] = channel.GetTags()
+	}
+	allowed := applyChannelTagRouting(rule, channelIds, func(channelId int) []string {
+		return tagsById[channelId]
+	})
+	filtered := make([]Ability, 0, len(allowed))
+	for _, ability := range abilities {
+		if slices.Contains(allowed, ability.ChannelId) {
+			filtered = append(filtered, ability)
+		}
+	}
+	return filtered
+}
+
+// GetChannelsByTags 返回带有任一（matchAll 时为全部）路由标签的渠道，不含密钥
+func GetChannelsByTags(tags []string, matchAll bool) ([]*Channel, error) {
+	tags = NormalizeChannelTags(tags)
+	if len(tags) == 0 {
+		return nil, errors.New("tags is empty")
+	}
+	var channels []*Channel
+	if err := DB.Omit("key").Where("tags IS NOT NULL AND tags <> ''").Order("id").Find(&channels).Error; err != nil {
+		return nil, err
+	}
+	result := make([]*Channel, 0, len(channels))
+	for _, channel := range channels {
+		if matchChannelTags(channel.GetTags(), tags, matchAll) {
+			result = append(result, channel)
+		}
+	}
+	return result, nil
+}
+
+// GetChannelTagStats 统计每个路由标签下的渠道数与启用数
+func GetChannelTagStats() ([]ChannelTagStat, error) {
+	var channels []*Channel
+	if err := DB.Select("id", "status", "tags").Where("tags IS NOT NULL AND tags <> ''").Find(&channels).Error; err != nil {
+		return nil, err
+	}
+	byTag := make(map[string]*ChannelTagStat)
+	for _, channel := range channels {
+		for _, tag := range channel.GetTags() {
+			stat, ok := byTag[tag]
+			if !ok {
+				stat = &ChannelTagStat{Tag: tag}
+				byTag[tag] = stat
+			}
+			stat.Channels++
+			if channel.Status == common.ChannelStatusEnabled {
+				stat.Enabled++
+			}
+		}
+	}
+	stats := make([]ChannelTagStat, 0, len(byTag))
+	for _, stat := range byTag {
+		stats = append(stats, *stat)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Tag < stats[j].Tag
+	})
+	return stats, nil
+}
+
+// BatchUpdateChannelTags 为指定渠道追加与移除路由标签
+func BatchUpdateChannelTags(ids []int, add []string, remove []string) error {
+	var channels []*Channel
+	if err := DB.Select("id", "tags").Where("id IN ?", ids).Find(&channels).Error; err != nil {
+		return err
+	}
+	add = NormalizeChannelTags(add)
+	remove = NormalizeChannelTags(remove)
+	return DB.Transaction(func(tx *gorm.DB) error {
+		for _, channel := range channels {
+			tags := slices.DeleteFunc(append(channel.GetTags(), add...), func(tag string) bool {
+				return slices.Contains(remove, tag)
+			})
+			channel.SetTags(tags)
+			if err := tx.Model(&Channel{}).Where("id = ?", channel.Id).Update("tags", *channel.Tags).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// BatchEditChannelsByTags 按路由标签批量修改渠道并重建能力表，返回被修改的渠道 ID
+func BatchEditChannelsByTags(tags []string, matchAll bool, edit ChannelTagBatchEdit) ([]int, error) {
+	if edit.Status != nil && *edit.Status != common.ChannelStatusEnabled && *edit.Status != common.ChannelStatusManuallyDisabled {
+		return nil, errors.New("status must be 1 (enabled) or 2 (disabled)")
+	}
+	channels, err := GetChannelsByTags(tags, matchAll)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int, 0, len(channels))
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		for _, channel := range channels {
+			columns := []string{}
+			if edit.Status != nil {
+				channel.Status = *edit.Status
+				columns = append(columns, "status")
+			}
+			if len(edit.AddModels) > 0 || len(edit.RemoveModels) > 0 {
+				var models []string
+				for _, model := range append(channel.GetModels(), edit.AddModels...) {
+					model = strings.TrimSpace(model)
+					if model != "" && !slices.Contains(models, model) && !slices.Contains(edit.RemoveModels, model) {
+						models = append(models, model)
+					}
+				}
+				channel.Models = strings.Join(models, ",")
+				columns = append(columns, "models")
+			}
+			if edit.ModelPrices != nil {
+				otherSettings := channel.GetOtherSettings()
+				if otherSettings.ModelPrices == nil {
+					otherSettings.ModelPrices = make(map[string]dto.ChannelModelPrice)
+				}
+				for modelName, price := range edit.ModelPrices {
+					if price == nil {
+						delete(otherSettings.ModelPrices, modelName)
+					} else {
+						otherSettings.ModelPrices[modelName] = *price
+					}
+				}
+				channel.SetOtherSettings(otherSettings)
+				if err := channel.ValidateSettings(); err != nil {
+					return fmt.Errorf("channel #%d: %w", channel.Id, err)
+				}
+				columns = append(columns, "settings")
+			}
+			if edit.Priority != nil {
+				channel.Priority = edit.Priority
+				columns = append(columns, "priority")
+			}
+			if edit.Weight != nil {
+				channel.Weight = edit.Weight
+				columns = append(columns, "weight")
+			}
+			if len(columns) == 0 {
+				return errors.New("nothing to update")
+			}
+			if err := tx.Model(channel).Select(columns).Updates(channel).Error; err != nil {
+				return err
+			}
+			if err := channel.UpdateAbilities(tx); err != nil {
+				return err
+			}
+			ids = append(ids, channel.Id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
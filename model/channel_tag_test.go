@@ -0,0 +1,138 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTaggedSelectTestChannel(id int, priority int64, tags ...string) *Channel {
+	channel := newSelectTestChannel(id, priority, 10)
+	channel.SetTags(tags)
+	return channel
+}
+
+func TestChannelTagRouting(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     operation_setting.ChannelTagRoutingRule
+		channels []*Channel
+		want     []int
+	}{
+		{
+			name:     "no rule keeps priority order",
+			channels: []*Channel{newTaggedSelectTestChannel(9301, 10, "cheap"), newTaggedSelectTestChannel(9302, 0, "gpt4-capable")},
+			want:     []int{9301},
+		},
+		{
+			name:     "prefer beats higher priority",
+			rule:     operation_setting.ChannelTagRoutingRule{Prefer: []string{"cheap"}},
+			channels: []*Channel{newTaggedSelectTestChannel(9311, 10, "premium"), newTaggedSelectTestChannel(9312, 0, "cheap", "gpt4-capable")},
+			want:     []int{9312},
+		},
+		{
+			name:     "prefer falls back when no channel has the tag",
+			rule:     operation_setting.ChannelTagRoutingRule{Prefer: []string{"cheap"}},
+			channels: []*Channel{newTaggedSelectTestChannel(9321, 10, "premium"), newTaggedSelectTestChannel(9322, 0)},
+			want:     []int{9321},
+		},
+		{
+			name:     "exclude wins over prefer",
+			rule:     operation_setting.ChannelTagRoutingRule{Prefer: []string{"cheap"}, Exclude: []string{"beta"}},
+			channels: []*Channel{newTaggedSelectTestChannel(9331, 10, "cheap", "beta"), newTaggedSelectTestChannel(9332, 0, "premium")},
+			want:     []int{9332},
+		},
+		{
+			name:     "everything excluded",
+			rule:     operation_setting.ChannelTagRoutingRule{Exclude: []string{"beta"}},
+			channels: []*Channel{newTaggedSelectTestChannel(9341, 0, "beta")},
+			want:     nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setChannelSelectCacheForTest(t, tt.channels...)
+			routing := operation_setting.GetChannelTagRoutingSetting()
+			overrideForTest(t, &routing.Groups, map[string]operation_setting.ChannelTagRoutingRule{"default": tt.rule})
+
+			picked := make(map[int]bool)
+			for i := 0; i < 50; i++ {
+				channel, err := GetRandomSatisfiedChannel("default", "gpt-test", 0, "")
+				require.NoError(t, err)
+				if channel != nil {
+					picked[channel.Id] = true
+				}
+			}
+			var got []int
+			for _, channel := range tt.channels {
+				if picked[channel.Id] {
+					got = append(got, channel.Id)
+				}
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestBatchEditChannelsByTags(t *testing.T) {
+	truncateTables(t)
+	price := 2.5
+	channels := []*Channel{
+		{Id: 9401, Name: "a", Key: "k", Models: "gpt-4o,gpt-4o-mini", Group: "default", Status: common.ChannelStatusEnabled},
+		{Id: 9402, Name: "b", Key: "k", Models: "gpt-4o", Group: "default", Status: common.ChannelStatusEnabled},
+		{Id: 9403, Name: "c", Key: "k", Models: "gpt-4o", Group: "default", Status: common.ChannelStatusEnabled},
+	}
+	channels[0].SetTags([]string{" cheap", "gpt4-capable", "cheap"})
+	channels[1].SetTags([]string{"cheap"})
+	for _, channel := range channels {
+		require.NoError(t, DB.Create(channel).Error)
+		require.NoError(t, channel.AddAbilities(nil))
+	}
+	assert.Equal(t, "cheap,gpt4-capable", *channels[0].Tags)
+
+	require.NoError(t, BatchUpdateChannelTags([]int{9402, 9403}, []string{"gpt4-capable"}, []string{"cheap"}))
+	matched, err := GetChannelsByTags([]string{"cheap", "gpt4-capable"}, true)
+	require.NoError(t, err)
+	require.Len(t, matched, 1)
+	assert.Equal(t, 9401, matched[0].Id)
+
+	disabled := common.ChannelStatusManuallyDisabled
+	ids, err := BatchEditChannelsByTags([]string{"gpt4-capable"}, false, ChannelTagBatchEdit{
+		Status:       &disabled,
+		AddModels:    []string{"o3"},
+		RemoveModels: []string{"gpt-4o-mini"},
+		ModelPrices:  map[string]*dto.ChannelModelPrice{"o3": {ModelRatio: &price}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{9401, 9402, 9403}, ids)
+
+	channel, err := GetChannelById(9401, true)
+	require.NoError(t, err)
+	assert.Equal(t, common.ChannelStatusManuallyDisabled, channel.Status)
+	assert.Equal(t, "gpt-4o,o3", channel.Models)
+	assert.Equal(t, price, *channel.GetOtherSettings().ModelPrices["o3"].ModelRatio)
+	var enabledAbilities int64
+	require.NoError(t, DB.Model(&Ability{}).Where("channel_id IN ? AND enabled = ?", ids, true).Count(&enabledAbilities).Error)
+	assert.Zero(t, enabledAbilities)
+	var o3Abilities int64
+	require.NoError(t, DB.Model(&Ability{}).Where("model = ?", "o3").Count(&o3Abilities).Error)
+	assert.Equal(t, int64(3), o3Abilities)
+
+	negative := -1.0
+	_, err = BatchEditChannelsByTags([]string{"gpt4-capable"}, false, ChannelTagBatchEdit{
+		ModelPrices: map[string]*dto.ChannelModelPrice{"o3": {ModelRatio: &negative}},
+	})
+	require.Error(t, err)
+
+	stats, err := GetChannelTagStats()
+	require.NoError(t, err)
+	assert.Equal(t, []ChannelTagStat{
+		{Tag: "cheap", Channels: 1},
+		{Tag: "gpt4-capable", Channels: 3},
+	}, stats)
+}
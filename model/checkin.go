@@ -16,13 +16,33 @@ type Checkin struct {
 	UserId       int    `json:"user_id" gorm:"not null;uniqueIndex:idx_user_checkin_date"`
 	CheckinDate  string `json:"checkin_date" gorm:"type:varchar(10);not null;uniqueIndex:idx_user_checkin_date"` // 格式: YYYY-MM-DD
 	QuotaAwarded int    `json:"quota_awarded" gorm:"not null"`
+	Streak       int    `json:"streak" gorm:"not null;default:0"` // 截至当天的连续签到天数，旧记录为 0
+	IsMakeup     bool   `json:"is_makeup"`
+	BonusQuota   int    `json:"bonus_quota" gorm:"not null;default:0"` // QuotaAwarded 中累计签到里程碑奖励的部分
+	Ip           string `json:"ip" gorm:"type:varchar(64);index"`
+	Flagged      bool   `json:"flagged"`                          // 同一 IP 当日签到账号数超限时标记，供管理员复核
+	PrizeId      string `json:"prize_id" gorm:"type:varchar(64)"` // 抽奖模式下抽中的奖品 id
 	CreatedAt    int64  `json:"created_at" gorm:"bigint"`
+
+	StreakMultiplier float64             `json:"streak_multiplier" gorm:"-"` // 本次签到应用的连签倍率，仅用于返回
+	TotalCheckins    int                 `json:"total_checkins" gorm:"-"`    // 本次签到后的累计签到天数，仅用于返回
+	Prize            *CheckinPrizeResult `json:"prize,omitempty" gorm:"-"`   // 抽奖结果，仅用于返回
+
+	Promotion *operation_setting.CheckinPromotion `json:"promotion,omitempty" gorm:"-"` // 本次签到生效的促销活动，仅用于返回
+
+	InviterId       int `json:"-" gorm:"not null;default:0"` // 获得邀请签到奖励的邀请人，撤销签到时据此扣回
+	InviterKickback int `json:"-" gorm:"not null;default:0"` // 发放给邀请人的邀请额度
+
+	ReferralMultiplier float64 `json:"referral_multiplier,omitempty" gorm:"-"` // 本次签到应用的邀请加成倍率，仅用于返回
 }
 
 // CheckinRecord 用于API返回的签到记录（不包含敏感字段）
 type CheckinRecord struct {
 	CheckinDate  string `json:"checkin_date"`
 	QuotaAwarded int    `json:"quota_awarded"`
+	Streak       int    `json:"streak"`
+	IsMakeup     bool   `json:"is_makeup"`
+	BonusQuota   int    `json:"bonus_quota"`
 }
 
 func (Checkin) TableName() string {
@@ -41,7 +61,7 @@ func GetUserCheckinRecords(userId int, startDate, endDate string) ([]Checkin, er
 
 // HasCheckedInToday 检查用户今天是否已签到
 func HasCheckedInToday(userId int) (bool, error) {
-	today := time.Now().Format("2006-01-02")
+	today := operation_setting.CheckinNow().Format("2006-01-02")
 	var count int64
 	err := DB.Model(&Checkin{}).
 		Where("user_id = ? AND checkin_date = ?", userId, today).
@@ -49,104 +69,571 @@ func HasCheckedInToday(userId int) (bool, error) {
 	return count > 0, err
 }
 
+var (
+	// ErrCheckinDisabled 签到功能未启用
+	ErrCheckinDisabled = errors.New("签到功能未启用")
+	// ErrCheckinMakeupDisabled 补签功能未启用
+	ErrCheckinMakeupDisabled = errors.New("补签功能未启用")
+	// ErrCheckinInvalidDate 日期参数格式错误
+	ErrCheckinInvalidDate = errors.New("日期格式错误，应为 YYYY-MM-DD")
+	// ErrAlreadyCheckedIn 今日已签到
+	ErrAlreadyCheckedIn = errors.New("今日已签到")
+	// ErrCheckinDateTaken 补签或补录的日期已有签到记录
+	ErrCheckinDateTaken = errors.New("该日期已签到")
+	// ErrCheckinMakeupNotPast 补签日期不早于今天
+	ErrCheckinMakeupNotPast = errors.New("只能补签今天之前的日期")
+	// ErrCheckinMakeupExpired 补签日期超出 MakeupDays 窗口
+	ErrCheckinMakeupExpired = errors.New("超出可补签的日期范围")
+	// ErrCheckinMakeupLimit 本月补签次数已用完
+	ErrCheckinMakeupLimit = errors.New("本月补签次数已用完")
+	// ErrCheckinMakeupQuota 额度不足以支付补签费用
+	ErrCheckinMakeupQuota = errors.New("额度不足，无法补签")
+	// ErrCheckinIpLimited 同一 IP 当日签到账号数已达上限
+	ErrCheckinIpLimited = errors.New("当前网络今日签到账号过多，请稍后再试")
+	// ErrCheckinTaskIncomplete 未完成当天的调用任务
+	ErrCheckinTaskIncomplete = errors.New("请先完成今日的调用任务再签到")
+	// ErrCheckinUserNotFound 签到用户不存在
+	ErrCheckinUserNotFound = errors.New("用户不存在")
+	// ErrCheckinFailed 签到事务失败
+	ErrCheckinFailed = errors.New("签到失败，请稍后重试")
+	// ErrCheckinMakeupFailed 补签事务失败
+	ErrCheckinMakeupFailed = errors.New("补签失败，请稍后重试")
+)
+
+// randomCheckinQuota 在用户分组生效的额度范围内随机生成基础签到奖励
+func randomCheckinQuota(group string) int {
+	minQuota, maxQuota := operation_setting.GetCheckinQuotaRange(group)
+	if maxQuota > minQuota {
+		return minQuota + rand.Intn(maxQuota-minQuota+1)
+	}
+	return minQuota
+}
+
+// grantExpiringCheckinQuota 配置了签到额度有效期时，把本次奖励登记为限时额度
+func grantExpiringCheckinQuota(tx *gorm.DB, checkin *Checkin) error {
+	expireDays := operation_setting.GetCheckinSetting().ExpireDays
+	if expireDays <= 0 {
+		return nil
+	}
+	expiresAt := operation_setting.CheckinNow().AddDate(0, 0, expireDays).Unix()
+	return createQuotaGrant(tx, checkin.UserId, QuotaGrantSourceCheckin, checkin.Id, checkin.QuotaAwarded, expiresAt)
+}
+
+// checkinStreakBefore 返回 date 前一天的连签天数，前一天未签到时为 0
+func checkinStreakBefore(tx *gorm.DB, userId int, date time.Time) (int, error) {
+	var previous Checkin
+	err := tx.Where("user_id = ? AND checkin_date = ?", userId, date.AddDate(0, 0, -1).Format("2006-01-02")).
+		Limit(1).Find(&previous).Error
+	if err != nil || previous.Id == 0 {
+		return 0, err
+	}
+	return max(previous.Streak, 1), nil
+}
+
+// CheckinTaskProgress 任务门槛签到的当日进度，未开启门槛时 Completed 恒为 true
+type CheckinTaskProgress struct {
+	Requests         int64 `json:"requests"`
+	RequiredRequests int   `json:"required_requests"`
+	Tokens           int64 `json:"tokens"`
+	RequiredTokens   int   `json:"required_tokens"`
+	Completed        bool  `json:"completed"`
+}
+
+// GetCheckinTaskProgress 统计用户在签到时区的今天内成功调用的次数和消耗的 token 数
+func GetCheckinTaskProgress(userId int) (*CheckinTaskProgress, error) {
+	setting := operation_setting.GetCheckinSetting()
+	progress := &CheckinTaskProgress{
+		RequiredRequests: max(setting.RequiredRequests, 0),
+		RequiredTokens:   max(setting.RequiredTokens, 0),
+	}
+	if progress.RequiredRequests == 0 && progress.RequiredTokens == 0 {
+		progress.Completed = true
+		return progress, nil
+	}
+
+	now := operation_setting.CheckinNow()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	// 通过日志写入后端统计，批量写入（ClickHouse 默认）时尚未落库的调用也计入进度
+	var err error
+	progress.Requests, progress.Tokens, err = logSink.ConsumeStats(userId, dayStart.Unix(), dayStart.AddDate(0, 0, 1).Unix())
+	if err != nil {
+		return nil, err
+	}
+	progress.Completed = progress.Requests >= int64(progress.RequiredRequests) &&
+		progress.Tokens >= int64(progress.RequiredTokens)
+	return progress, nil
+}
+
+// CheckinReferralBoost 邀请加成状态，未开启邀请加成时 Enabled 为 false
+type CheckinReferralBoost struct {
+	Enabled          bool    `json:"enabled"`
+	ActiveInvitees   int64   `json:"active_invitees"`
+	RequiredInvitees int     `json:"required_invitees"`
+	Multiplier       float64 `json:"multiplier"`
+	Active           bool    `json:"active"`
+}
+
+// countActiveInvitees 统计用户邀请的活跃用户数：未被禁用且产生过消费
+func countActiveInvitees(db *gorm.DB, userId int) (int64, error) {
+	var count int64
+	err := db.Model(&User{}).
+		Where("inviter_id = ? AND status = ? AND used_quota > 0", userId, common.UserStatusEnabled).
+		Count(&count).Error
+	return count, err
+}
+
+// GetCheckinReferralBoost 获取用户当前的邀请签到加成状态
+func GetCheckinReferralBoost(userId int) (*CheckinReferralBoost, error) {
+	setting := operation_setting.GetCheckinSetting()
+	boost := &CheckinReferralBoost{
+		Enabled:          setting.ReferralMinInvitees > 0,
+		RequiredInvitees: max(setting.ReferralMinInvitees, 0),
+		Multiplier:       1,
+	}
+	if !boost.Enabled {
+		return boost, nil
+	}
+	count, err := countActiveInvitees(DB, userId)
+	if err != nil {
+		return nil, err
+	}
+	boost.ActiveInvitees = count
+	boost.Multiplier = operation_setting.GetCheckinReferralMultiplier(int(count))
+	boost.Active = count >= int64(boost.RequiredInvitees)
+	return boost, nil
+}
+
 // UserCheckin 执行用户签到
-// MySQL 和 PostgreSQL 使用事务保证原子性
-// SQLite 不支持嵌套事务，使用顺序操作 + 手动回滚
-func UserCheckin(userId int) (*Checkin, error) {
+// 签到记录与额度发放在同一事务中完成：先锁定用户行串行化同一用户的并发签到，
+// 再在事务内复查当天记录；(user_id, checkin_date) 唯一约束兜底，
+// 保证并发请求最多只有一个能发放奖励。
+func UserCheckin(userId int, ip string) (*Checkin, error) {
 	setting := operation_setting.GetCheckinSetting()
 	if !setting.Enabled {
-		return nil, errors.New("签到功能未启用")
+		return nil, ErrCheckinDisabled
 	}
 
-	// 检查今天是否已签到
+	// 快速路径：已签到直接返回，避免无谓地开启事务
 	hasChecked, err := HasCheckedInToday(userId)
 	if err != nil {
 		return nil, err
 	}
 	if hasChecked {
-		return nil, errors.New("今日已签到")
+		return nil, ErrAlreadyCheckedIn
 	}
 
-	// 计算随机额度奖励
-	quotaAwarded := setting.MinQuota
-	if setting.MaxQuota > setting.MinQuota {
-		quotaAwarded = setting.MinQuota + rand.Intn(setting.MaxQuota-setting.MinQuota+1)
+	progress, err := GetCheckinTaskProgress(userId)
+	if err != nil {
+		return nil, err
+	}
+	if !progress.Completed {
+		return nil, ErrCheckinTaskIncomplete
 	}
 
-	today := time.Now().Format("2006-01-02")
+	now := operation_setting.CheckinNow()
 	checkin := &Checkin{
-		UserId:       userId,
-		CheckinDate:  today,
-		QuotaAwarded: quotaAwarded,
-		CreatedAt:    time.Now().Unix(),
+		UserId:      userId,
+		CheckinDate: now.Format("2006-01-02"),
+		Ip:          ip,
+		CreatedAt:   now.Unix(),
 	}
 
-	// 根据数据库类型选择不同的策略
-	if common.UsingMainDatabase(common.DatabaseTypeSQLite) {
-		// SQLite 不支持嵌套事务，使用顺序操作 + 手动回滚
-		return userCheckinWithoutTransaction(checkin, userId, quotaAwarded)
-	}
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		// 锁定用户行，同一用户的并发签到在此排队
+		var user User
+		if err := lockForUpdate(tx).Select("id", commonGroupCol, "inviter_id").Where("id = ?", userId).First(&user).Error; err != nil {
+			return err
+		}
 
-	// MySQL 和 PostgreSQL 支持事务，使用事务保证原子性
-	return userCheckinWithTransaction(checkin, userId, quotaAwarded)
-}
+		var count int64
+		if err := tx.Model(&Checkin{}).
+			Where("user_id = ? AND checkin_date = ?", userId, checkin.CheckinDate).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrAlreadyCheckedIn
+		}
+
+		// 同一 IP 当日已签到的其他账号数达到上限时拒绝或标记。不同用户的签到不互相加锁，
+		// 并发时可能略微超出上限，对风控场景可以接受
+		if setting.MaxAccountsPerIp > 0 && ip != "" {
+			var ipAccounts int64
+			if err := tx.Model(&Checkin{}).
+				Where("checkin_date = ? AND ip = ? AND user_id <> ?", checkin.CheckinDate, ip, userId).
+				Distinct("user_id").
+				Count(&ipAccounts).Error; err != nil {
+				return err
+			}
+			if ipAccounts >= int64(setting.MaxAccountsPerIp) {
+				if setting.IpLimitAction != operation_setting.CheckinIpLimitActionFlag {
+					return ErrCheckinIpLimited
+				}
+				checkin.Flagged = true
+			}
+		}
 
-// userCheckinWithTransaction 使用事务执行签到（适用于 MySQL 和 PostgreSQL）
-func userCheckinWithTransaction(checkin *Checkin, userId int, quotaAwarded int) (*Checkin, error) {
-	err := DB.Transaction(func(tx *gorm.DB) error {
-		// 步骤1: 创建签到记录
-		// 数据库有唯一约束 (user_id, checkin_date)，可以防止并发重复签到
+		// 昨天有签到则延续连签天数，否则从 1 重新开始
+		previousStreak, err := checkinStreakBefore(tx, userId, now)
+		if err != nil {
+			return err
+		}
+		checkin.Streak = previousStreak + 1
+		checkin.StreakMultiplier = operation_setting.GetCheckinStreakMultiplier(checkin.Streak)
+		// 抽奖模式下额度类奖品代替区间随机额度，兑换码和谢谢参与不直接发放额度
+		baseQuota := 0
+		if setting.LotteryEnabled {
+			prize, err := drawCheckinPrize(tx, userId, checkin.CheckinDate, setting.Prizes)
+			if err != nil {
+				return err
+			}
+			checkin.Prize = prize
+			checkin.PrizeId = prize.Id
+			if prize.Type == operation_setting.CheckinPrizeTypeQuota {
+				baseQuota = prize.Quota
+			}
+		} else {
+			baseQuota = randomCheckinQuota(user.Group)
+		}
+		multiplier := checkin.StreakMultiplier
+		// 促销活动期间倍率与连签倍率相乘，额外奖励直接计入
+		promotionBonus := 0
+		if promotion := operation_setting.GetActiveCheckinPromotion(checkin.CheckinDate); promotion != nil {
+			checkin.Promotion = promotion
+			multiplier *= promotion.Multiplier
+			promotionBonus = promotion.BonusQuota
+		}
+		// 邀请的活跃用户达标时再叠加邀请加成倍率
+		if setting.ReferralMinInvitees > 0 {
+			invitees, err := countActiveInvitees(tx, userId)
+			if err != nil {
+				return err
+			}
+			checkin.ReferralMultiplier = operation_setting.GetCheckinReferralMultiplier(int(invitees))
+			multiplier *= checkin.ReferralMultiplier
+		}
+		checkin.QuotaAwarded = common.QuotaFromFloat(float64(baseQuota)*multiplier) + promotionBonus
+
+		// 被邀请用户签到时给邀请人发放邀请额度，与注册邀请奖励一样计入 aff_quota，由邀请人自行划转；
+		// 发放情况随签到记录保存，撤销签到时扣回
+		if setting.InviterKickbackQuota > 0 && user.InviterId > 0 {
+			result := tx.Model(&User{}).Where("id = ?", user.InviterId).Updates(map[string]interface{}{
+				"aff_quota":   gorm.Expr("aff_quota + ?", setting.InviterKickbackQuota),
+				"aff_history": gorm.Expr("aff_history + ?", setting.InviterKickbackQuota),
+			})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				checkin.InviterId = user.InviterId
+				checkin.InviterKickback = setting.InviterKickbackQuota
+			}
+		}
+
+		// 唯一约束 (user_id, checkin_date) 兜底：SQLite 无行锁时由它拒绝重复记录
 		if err := tx.Create(checkin).Error; err != nil {
-			return errors.New("签到失败，请稍后重试")
+			return ErrAlreadyCheckedIn
+		}
+		// 累计天数达到的里程碑奖励随当天奖励一并发放
+		if err := payCheckinMilestones(tx, checkin); err != nil {
+			return err
+		}
+		if err := grantExpiringCheckinQuota(tx, checkin); err != nil {
+			return err
 		}
 
-		// 步骤2: 在事务中增加用户额度
 		if err := tx.Model(&User{}).Where("id = ?", userId).
-			Update("quota", gorm.Expr("quota + ?", quotaAwarded)).Error; err != nil {
+			Update("quota", gorm.Expr("quota + ?", checkin.QuotaAwarded)).Error; err != nil {
 			return errors.New("签到失败：更新额度出错")
 		}
 
 		return nil
 	})
+	if err != nil {
+		if errors.Is(err, ErrAlreadyCheckedIn) || errors.Is(err, ErrCheckinIpLimited) {
+			return nil, err
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCheckinUserNotFound
+		}
+		return nil, ErrCheckinFailed
+	}
 
+	invalidateCheckinCache(userId, checkin.CheckinDate)
+	invalidateUserQuotaAfterCommit(userId)
+
+	return checkin, nil
+}
+
+// relinkCheckinStreaks 在 day 的签到记录新增或删除后，重新计算其后连续签到记录的连签天数。
+// streak 为 day 当天的连签天数（day 无签到时为 0），遇到断签即停止。
+func relinkCheckinStreaks(tx *gorm.DB, userId int, day time.Time, streak int) error {
+	var following []Checkin
+	if err := tx.Select("id", "checkin_date", "streak").
+		Where("user_id = ? AND checkin_date > ?", userId, day.Format("2006-01-02")).
+		Order("checkin_date ASC").
+		Find(&following).Error; err != nil {
+		return err
+	}
+	expected := day
+	for _, record := range following {
+		expected = expected.AddDate(0, 0, 1)
+		if record.CheckinDate != expected.Format("2006-01-02") {
+			break
+		}
+		streak++
+		if record.Streak == streak {
+			continue
+		}
+		if err := tx.Model(&Checkin{}).Where("id = ?", record.Id).Update("streak", streak).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UserMakeupCheckin 为用户补签过去的某一天
+// 补签扣除 MakeupCost 额度并发放不含连签倍率的基础奖励；补上的日期会与后续
+// 已签到的日期重新连成连签，因此在同一事务内顺延更新其后记录的连签天数。
+func UserMakeupCheckin(userId int, date string) (*Checkin, error) {
+	setting := operation_setting.GetCheckinSetting()
+	if !setting.Enabled || !setting.MakeupEnabled {
+		return nil, ErrCheckinMakeupDisabled
+	}
+
+	now := operation_setting.CheckinNow()
+	day, err := time.ParseInLocation("2006-01-02", date, now.Location())
 	if err != nil {
-		return nil, err
+		return nil, ErrCheckinInvalidDate
+	}
+	today, _ := time.ParseInLocation("2006-01-02", now.Format("2006-01-02"), now.Location())
+	if !day.Before(today) {
+		return nil, ErrCheckinMakeupNotPast
+	}
+	if day.Before(today.AddDate(0, 0, -setting.MakeupDays)) {
+		return nil, ErrCheckinMakeupExpired
+	}
+
+	cost := max(setting.MakeupCost, 0)
+	checkin := &Checkin{
+		UserId:      userId,
+		CheckinDate: day.Format("2006-01-02"),
+		IsMakeup:    true,
+		CreatedAt:   now.Unix(),
+	}
+
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		var user User
+		if err := lockForUpdate(tx).Select("id", "quota", commonGroupCol).Where("id = ?", userId).First(&user).Error; err != nil {
+			return err
+		}
+		checkin.QuotaAwarded = randomCheckinQuota(user.Group)
+
+		var count int64
+		if err := tx.Model(&Checkin{}).
+			Where("user_id = ? AND checkin_date = ?", userId, checkin.CheckinDate).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrAlreadyCheckedIn
+		}
+
+		if setting.MakeupMonthlyLimit > 0 {
+			monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).Unix()
+			var used int64
+			if err := tx.Model(&Checkin{}).
+				Where("user_id = ? AND is_makeup = ? AND created_at >= ?", userId, true, monthStart).
+				Count(&used).Error; err != nil {
+				return err
+			}
+			if used >= int64(setting.MakeupMonthlyLimit) {
+				return ErrCheckinMakeupLimit
+			}
+		}
+
+		if user.Quota < cost {
+			return ErrCheckinMakeupQuota
+		}
+
+		previousStreak, err := checkinStreakBefore(tx, userId, day)
+		if err != nil {
+			return err
+		}
+		checkin.Streak = previousStreak + 1
+		if err := tx.Create(checkin).Error; err != nil {
+			return ErrAlreadyCheckedIn
+		}
+		if err := payCheckinMilestones(tx, checkin); err != nil {
+			return err
+		}
+		if err := grantExpiringCheckinQuota(tx, checkin); err != nil {
+			return err
+		}
+
+		if err := relinkCheckinStreaks(tx, userId, day, checkin.Streak); err != nil {
+			return err
+		}
+
+		return tx.Model(&User{}).Where("id = ?", userId).
+			Update("quota", gorm.Expr("quota + ?", checkin.QuotaAwarded-cost)).Error
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrAlreadyCheckedIn):
+			return nil, ErrCheckinDateTaken
+		case errors.Is(err, ErrCheckinMakeupLimit), errors.Is(err, ErrCheckinMakeupQuota):
+			return nil, err
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return nil, ErrCheckinUserNotFound
+		}
+		return nil, ErrCheckinMakeupFailed
 	}
 
-	// 事务成功后，异步更新缓存
-	go func() {
-		_ = cacheIncrUserQuota(userId, int64(quotaAwarded))
-	}()
+	invalidateCheckinCache(userId, checkin.CheckinDate)
+	invalidateUserQuotaAfterCommit(userId)
 
 	return checkin, nil
 }
 
-// userCheckinWithoutTransaction 不使用事务执行签到（适用于 SQLite）
-func userCheckinWithoutTransaction(checkin *Checkin, userId int, quotaAwarded int) (*Checkin, error) {
-	// 步骤1: 创建签到记录
-	// 数据库有唯一约束 (user_id, checkin_date)，可以防止并发重复签到
-	if err := DB.Create(checkin).Error; err != nil {
-		return nil, errors.New("签到失败，请稍后重试")
+// AdminGrantCheckin 管理员为用户补录某天的签到记录并发放 quota 额度
+// quota 小于 0 时按配置范围随机发放；不受补签开关、窗口和次数限制，也不扣费。
+func AdminGrantCheckin(userId int, date string, quota int) (*Checkin, error) {
+	now := operation_setting.CheckinNow()
+	day, err := time.ParseInLocation("2006-01-02", date, now.Location())
+	if err != nil {
+		return nil, ErrCheckinInvalidDate
+	}
+	if day.After(now) {
+		return nil, errors.New("不能补录未来日期的签到")
+	}
+	checkin := &Checkin{
+		UserId:       userId,
+		CheckinDate:  day.Format("2006-01-02"),
+		QuotaAwarded: quota,
+		CreatedAt:    now.Unix(),
 	}
 
-	// 步骤2: 增加用户额度
-	// 使用 db=true 强制直接写入数据库，不使用批量更新
-	if err := IncreaseUserQuota(userId, quotaAwarded, true); err != nil {
-		// 如果增加额度失败，需要回滚签到记录
-		DB.Delete(checkin)
-		return nil, errors.New("签到失败：更新额度出错")
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		var user User
+		if err := lockForUpdate(tx).Select("id", commonGroupCol).Where("id = ?", userId).First(&user).Error; err != nil {
+			return err
+		}
+		if quota < 0 {
+			checkin.QuotaAwarded = randomCheckinQuota(user.Group)
+		}
+		previousStreak, err := checkinStreakBefore(tx, userId, day)
+		if err != nil {
+			return err
+		}
+		checkin.Streak = previousStreak + 1
+		if err := tx.Create(checkin).Error; err != nil {
+			return ErrAlreadyCheckedIn
+		}
+		if err := payCheckinMilestones(tx, checkin); err != nil {
+			return err
+		}
+		if err := grantExpiringCheckinQuota(tx, checkin); err != nil {
+			return err
+		}
+		if err := relinkCheckinStreaks(tx, userId, day, checkin.Streak); err != nil {
+			return err
+		}
+		return tx.Model(&User{}).Where("id = ?", userId).
+			Update("quota", gorm.Expr("quota + ?", checkin.QuotaAwarded)).Error
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrAlreadyCheckedIn):
+			return nil, ErrCheckinDateTaken
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return nil, ErrCheckinUserNotFound
+		}
+		return nil, err
 	}
 
+	invalidateCheckinCache(userId, checkin.CheckinDate)
+	invalidateUserQuotaAfterCommit(userId)
 	return checkin, nil
 }
 
-// GetUserCheckinStats 获取用户签到统计信息
-func GetUserCheckinStats(userId int, month string) (map[string]interface{}, error) {
-	// 获取指定月份的所有签到记录
-	startDate := month + "-01"
-	endDate := month + "-31"
+// AdminRevokeCheckin 管理员撤销用户某天的签到记录，并扣回该记录发放且尚未因到期扣回的额度；
+// 随该记录发放的里程碑奖励包含在扣回的额度中，对应的里程碑记录一并删除，再次达到时重新发放；
+// 随该记录发放给邀请人的邀请额度在同一事务中扣回
+func AdminRevokeCheckin(userId int, date string) (*Checkin, error) {
+	day, err := time.ParseInLocation("2006-01-02", date, operation_setting.GetCheckinLocation())
+	if err != nil {
+		return nil, ErrCheckinInvalidDate
+	}
 
-	records, err := GetUserCheckinRecords(userId, startDate, endDate)
+	var checkin Checkin
+	var revokedQuota int
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		var user User
+		if err := lockForUpdate(tx).Select("id").Where("id = ?", userId).First(&user).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ? AND checkin_date = ?", userId, date).First(&checkin).Error; err != nil {
+			return err
+		}
+		// 限时奖励到期时已扣回过未使用的部分，撤销时不能重复扣减
+		var expiredQuota int64
+		if err := tx.Model(&QuotaGrant{}).
+			Where("source = ? AND source_id = ?", QuotaGrantSourceCheckin, checkin.Id).
+			Select("COALESCE(SUM(expired_quota), 0)").Scan(&expiredQuota).Error; err != nil {
+			return err
+		}
+		revokedQuota = max(checkin.QuotaAwarded-int(expiredQuota), 0)
+		if err := tx.Delete(&checkin).Error; err != nil {
+			return err
+		}
+		if err := deleteQuotaGrantsBySource(tx, QuotaGrantSourceCheckin, checkin.Id); err != nil {
+			return err
+		}
+		if err := deleteCheckinMilestoneRewards(tx, checkin.Id); err != nil {
+			return err
+		}
+		if err := relinkCheckinStreaks(tx, userId, day, 0); err != nil {
+			return err
+		}
+		// 扣回该签到发放给邀请人的邀请额度；邀请人已划转的部分无法追回，aff_quota 最多扣到 0
+		if checkin.InviterId > 0 && checkin.InviterKickback > 0 {
+			if err := tx.Model(&User{}).Where("id = ?", checkin.InviterId).Updates(map[string]interface{}{
+				"aff_quota":   gorm.Expr("CASE WHEN aff_quota > ? THEN aff_quota - ? ELSE 0 END", checkin.InviterKickback, checkin.InviterKickback),
+				"aff_history": gorm.Expr("aff_history - ?", checkin.InviterKickback),
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&User{}).Where("id = ?", userId).
+			Update("quota", gorm.Expr("quota - ?", revokedQuota)).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("用户或签到记录不存在")
+		}
+		return nil, err
+	}
+
+	invalidateCheckinCache(userId, checkin.CheckinDate)
+	invalidateUserQuotaAfterCommit(userId)
+	return &checkin, nil
+}
+
+// GetUserCheckinCalendar 获取用户某个月（格式 YYYY-MM）的签到记录，按日期倒序
+// 开启 Redis 时按月缓存，签到记录变更时由 invalidateCheckinCache 清理
+func GetUserCheckinCalendar(userId int, month string) ([]CheckinRecord, error) {
+	cacheKey := getCheckinCalendarCacheKey(userId, month)
+	if common.RedisEnabled {
+		if cached, err := common.RedisGet(cacheKey); err == nil {
+			var checkinRecords []CheckinRecord
+			if err := common.UnmarshalJsonStr(cached, &checkinRecords); err == nil {
+				return checkinRecords, nil
+			}
+		}
+	}
+
+	records, err := GetUserCheckinRecords(userId, month+"-01", month+"-31")
 	if err != nil {
 		return nil, err
 	}
@@ -157,23 +644,247 @@ func GetUserCheckinStats(userId int, month string) (map[string]interface{}, erro
 		checkinRecords[i] = CheckinRecord{
 			CheckinDate:  r.CheckinDate,
 			QuotaAwarded: r.QuotaAwarded,
+			Streak:       r.Streak,
+			IsMakeup:     r.IsMakeup,
+			BonusQuota:   r.BonusQuota,
 		}
 	}
+	if common.RedisEnabled {
+		if payload, err := common.Marshal(checkinRecords); err == nil {
+			if err := common.RedisSet(cacheKey, string(payload), time.Duration(common.RedisKeyCacheSeconds())*time.Second); err != nil {
+				common.SysLog("failed to cache checkin calendar: " + err.Error())
+			}
+		}
+	}
+	return checkinRecords, nil
+}
 
-	// 检查今天是否已签到
-	hasCheckedToday, _ := HasCheckedInToday(userId)
+// GetUserCheckinStats 获取用户签到统计信息
+func GetUserCheckinStats(userId int, month string) (map[string]interface{}, error) {
+	checkinRecords, err := GetUserCheckinCalendar(userId, month)
+	if err != nil {
+		return nil, err
+	}
+
+	summary, err := getCheckinSummary(userId)
+	if err != nil {
+		return nil, err
+	}
+	now := operation_setting.CheckinNow()
+	totalCheckins := summary.TotalCheckins
 
-	// 获取用户所有时间的签到统计
-	var totalCheckins int64
-	var totalQuota int64
-	DB.Model(&Checkin{}).Where("user_id = ?", userId).Count(&totalCheckins)
-	DB.Model(&Checkin{}).Where("user_id = ?", userId).Select("COALESCE(SUM(quota_awarded), 0)").Scan(&totalQuota)
+	var nextMilestone map[string]interface{}
+	if milestone := operation_setting.GetNextCheckinTotalMilestone(int(totalCheckins)); milestone != nil {
+		nextMilestone = map[string]interface{}{
+			"days":      milestone.Days,
+			"quota":     milestone.Quota,
+			"remaining": milestone.Days - int(totalCheckins),
+		}
+	}
 
 	return map[string]interface{}{
-		"total_quota":      totalQuota,      // 所有时间累计获得的额度
-		"total_checkins":   totalCheckins,   // 所有时间累计签到次数
-		"checkin_count":    len(records),    // 本月签到次数
-		"checked_in_today": hasCheckedToday, // 今天是否已签到
-		"records":          checkinRecords,  // 本月签到记录详情（不含id和user_id）
+		"next_milestone":   nextMilestone,                                 // 下一个累计签到里程碑，没有时为 null
+		"total_quota":      summary.TotalQuota,                            // 所有时间累计获得的额度
+		"total_checkins":   totalCheckins,                                 // 所有时间累计签到次数
+		"current_streak":   summary.currentStreak(now),                    // 当前连续签到天数
+		"longest_streak":   summary.LongestStreak,                         // 历史最长连续签到天数
+		"checkin_count":    len(checkinRecords),                           // 本月签到次数
+		"checked_in_today": summary.checkedInOn(now.Format("2006-01-02")), // 今天是否已签到
+		"records":          checkinRecords,                                // 本月签到记录详情（不含id和user_id）
 	}, nil
 }
+
+// GetUserCheckinStreak 获取用户当前连续签到天数
+// 最近一次签到是今天或昨天时连签仍然有效，否则视为已中断
+func GetUserCheckinStreak(userId int) (int, error) {
+	summary, err := getCheckinSummary(userId)
+	if err != nil {
+		return 0, err
+	}
+	return summary.currentStreak(operation_setting.CheckinNow()), nil
+}
+
+// CheckinDailyStat 单日签到汇总
+type CheckinDailyStat struct {
+	CheckinDate string `json:"checkin_date"`
+	Count       int64  `json:"count"`
+	Quota       int64  `json:"quota"`
+}
+
+// CheckinGroupStat 分组签到参与情况
+type CheckinGroupStat struct {
+	Group             string  `json:"group"`
+	Users             int64   `json:"users"`
+	Participants      int64   `json:"participants"`
+	ParticipationRate float64 `json:"participation_rate"`
+}
+
+// CheckinTopUser 签到次数排行
+type CheckinTopUser struct {
+	UserId   int    `json:"user_id"`
+	Username string `json:"username"`
+	Count    int64  `json:"count"`
+	Quota    int64  `json:"quota"`
+}
+
+// CheckinAdminStats 管理员签到统计
+type CheckinAdminStats struct {
+	StartDate    string             `json:"start_date"`
+	EndDate      string             `json:"end_date"`
+	TotalCount   int64              `json:"total_count"`
+	TotalQuota   int64              `json:"total_quota"`
+	Participants int64              `json:"participants"`
+	Daily        []CheckinDailyStat `json:"daily"`
+	Groups       []CheckinGroupStat `json:"groups"`
+	TopUsers     []CheckinTopUser   `json:"top_users"`
+}
+
+// GetCheckinAdminStats 统计日期范围内的签到情况，全部在数据库侧聚合
+func GetCheckinAdminStats(startDate, endDate string, topN int) (*CheckinAdminStats, error) {
+	stats := &CheckinAdminStats{
+		StartDate: startDate,
+		EndDate:   endDate,
+		Daily:     []CheckinDailyStat{},
+		Groups:    []CheckinGroupStat{},
+		TopUsers:  []CheckinTopUser{},
+	}
+	inRange := func() *gorm.DB {
+		return DB.Model(&Checkin{}).Where("checkin_date >= ? AND checkin_date <= ?", startDate, endDate)
+	}
+
+	if err := inRange().
+		Select("checkin_date, COUNT(*) AS count, COALESCE(SUM(quota_awarded), 0) AS quota").
+		Group("checkin_date").
+		Order("checkin_date ASC").
+		Scan(&stats.Daily).Error; err != nil {
+		return nil, err
+	}
+	for _, day := range stats.Daily {
+		stats.TotalCount += day.Count
+		stats.TotalQuota += day.Quota
+	}
+	if err := inRange().Distinct("user_id").Count(&stats.Participants).Error; err != nil {
+		return nil, err
+	}
+
+	groupCol := "users." + commonGroupCol
+	var participants []CheckinGroupStat
+	if err := inRange().
+		Select(groupCol + " AS " + commonGroupCol + ", COUNT(DISTINCT checkins.user_id) AS participants").
+		Joins("JOIN users ON users.id = checkins.user_id").
+		Group(groupCol).
+		Scan(&participants).Error; err != nil {
+		return nil, err
+	}
+	var groupUsers []CheckinGroupStat
+	if err := DB.Model(&User{}).
+		Select(groupCol+" AS "+commonGroupCol+", COUNT(*) AS users").
+		Where("status = ?", common.UserStatusEnabled).
+		Group(groupCol).
+		Scan(&groupUsers).Error; err != nil {
+		return nil, err
+	}
+	participantsByGroup := make(map[string]int64, len(participants))
+	for _, p := range participants {
+		participantsByGroup[p.Group] = p.Participants
+	}
+	for _, g := range groupUsers {
+		g.Participants = participantsByGroup[g.Group]
+		if g.Users > 0 {
+			g.ParticipationRate = float64(g.Participants) / float64(g.Users)
+		}
+		stats.Groups = append(stats.Groups, g)
+	}
+
+	if err := inRange().
+		Select("checkins.user_id, users.username, COUNT(*) AS count, COALESCE(SUM(checkins.quota_awarded), 0) AS quota").
+		Joins("JOIN users ON users.id = checkins.user_id").
+		Group("checkins.user_id, users.username").
+		Order("count DESC, quota DESC").
+		Limit(topN).
+		Scan(&stats.TopUsers).Error; err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// CheckinIpCluster 同一 IP 在日期范围内签到的多个账号
+type CheckinIpCluster struct {
+	Ip       string           `json:"ip"`
+	Accounts int64            `json:"accounts"`
+	Flagged  int64            `json:"flagged"`
+	Users    []CheckinTopUser `json:"users" gorm:"-"`
+}
+
+// GetCheckinIpClusters 找出日期范围内签到账号数不少于 minAccounts 的 IP，按账号数倒序
+func GetCheckinIpClusters(startDate, endDate string, minAccounts int, limit int) ([]CheckinIpCluster, error) {
+	clusters := []CheckinIpCluster{}
+	if err := DB.Model(&Checkin{}).
+		Select("ip, COUNT(DISTINCT user_id) AS accounts, SUM(CASE WHEN flagged = ? THEN 1 ELSE 0 END) AS flagged", true).
+		Where("checkin_date >= ? AND checkin_date <= ? AND ip <> ''", startDate, endDate).
+		Group("ip").
+		Having("COUNT(DISTINCT user_id) >= ?", minAccounts).
+		Order("accounts DESC").
+		Limit(limit).
+		Scan(&clusters).Error; err != nil {
+		return nil, err
+	}
+	for i := range clusters {
+		clusters[i].Users = []CheckinTopUser{}
+		if err := DB.Model(&Checkin{}).
+			Select("checkins.user_id, users.username, COUNT(*) AS count, COALESCE(SUM(checkins.quota_awarded), 0) AS quota").
+			Joins("JOIN users ON users.id = checkins.user_id").
+			Where("checkins.checkin_date >= ? AND checkins.checkin_date <= ? AND checkins.ip = ?", startDate, endDate, clusters[i].Ip).
+			Group("checkins.user_id, users.username").
+			Order("count DESC").
+			Scan(&clusters[i].Users).Error; err != nil {
+			return nil, err
+		}
+	}
+	return clusters, nil
+}
+
+// GetCheckinReminderCandidates 获取 sinceDate 到 today 之前签到过、但 today 尚未签到的启用用户，
+// 按 id 升序分页，afterId 为上一页最后一个用户 id
+func GetCheckinReminderCandidates(today, sinceDate string, afterId int, limit int) ([]User, error) {
+	var users []User
+	err := DB.Select("id", "email", "setting").
+		Where("status = ? AND id > ?", common.UserStatusEnabled, afterId).
+		Where("id IN (?)", DB.Model(&Checkin{}).Select("user_id").
+			Where("checkin_date >= ? AND checkin_date < ?", sinceDate, today)).
+		Where("id NOT IN (?)", DB.Model(&Checkin{}).Select("user_id").
+			Where("checkin_date = ?", today)).
+		Order("id asc").
+		Limit(limit).
+		Find(&users).Error
+	return users, err
+}
+
+// checkinExportBatchSize 导出签到记录时每批读取的条数
+const checkinExportBatchSize = 500
+
+// ExportCheckins 按 id 顺序分批读取日期范围内的签到记录并交给 fn 处理，避免一次性加载全部记录；
+// userId 为 0 时导出所有用户
+func ExportCheckins(userId int, startDate, endDate string, fn func(batch []Checkin) error) error {
+	lastId := 0
+	for {
+		query := DB.Where("id > ? AND checkin_date >= ? AND checkin_date <= ?", lastId, startDate, endDate)
+		if userId > 0 {
+			query = query.Where("user_id = ?", userId)
+		}
+		var batch []Checkin
+		if err := query.Order("id asc").Limit(checkinExportBatchSize).Find(&batch).Error; err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := fn(batch); err != nil {
+			return err
+		}
+		if len(batch) < checkinExportBatchSize {
+			return nil
+		}
+		lastId = batch[len(batch)-1].Id
+	}
+}
@@ -0,0 +1,100 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+)
+
+// checkinSummary 用户签到汇总，开启 Redis 时按用户缓存。
+// 今天是否已签到、当前连签天数在读取时由 LastDate 推导，跨天不需要清理缓存
+type checkinSummary struct {
+	LastDate      string `json:"last_date"`
+	LastStreak    int    `json:"last_streak"`
+	TotalCheckins int64  `json:"total_checkins"`
+	TotalQuota    int64  `json:"total_quota"`
+	LongestStreak int    `json:"longest_streak"`
+}
+
+func getCheckinSummaryCacheKey(userId int) string {
+	return fmt.Sprintf("checkin:summary:%d", userId)
+}
+
+func getCheckinCalendarCacheKey(userId int, month string) string {
+	return fmt.Sprintf("checkin:calendar:%d:%s", userId, month)
+}
+
+// checkedInOn 最近一次签到是否就是 date
+func (s *checkinSummary) checkedInOn(date string) bool {
+	return s.LastDate != "" && s.LastDate == date
+}
+
+// currentStreak 最近一次签到是今天或昨天时连签仍然有效，否则视为已中断
+func (s *checkinSummary) currentStreak(now time.Time) int {
+	if s.LastDate != now.Format("2006-01-02") && s.LastDate != now.AddDate(0, 0, -1).Format("2006-01-02") {
+		return 0
+	}
+	return max(s.LastStreak, 1)
+}
+
+// getCheckinSummary 读取用户签到汇总，优先使用 Redis 缓存，未命中或未开启 Redis 时查询数据库
+func getCheckinSummary(userId int) (*checkinSummary, error) {
+	if common.RedisEnabled {
+		if cached, err := common.RedisGet(getCheckinSummaryCacheKey(userId)); err == nil {
+			var summary checkinSummary
+			if err := common.UnmarshalJsonStr(cached, &summary); err == nil {
+				return &summary, nil
+			}
+		}
+	}
+
+	summary := &checkinSummary{}
+	var latest Checkin
+	if err := DB.Where("user_id = ?", userId).Order("checkin_date DESC").Limit(1).Find(&latest).Error; err != nil {
+		return nil, err
+	}
+	if latest.Id != 0 {
+		summary.LastDate = latest.CheckinDate
+		summary.LastStreak = latest.Streak
+		err := DB.Model(&Checkin{}).Where("user_id = ?", userId).
+			Select("COUNT(*) AS total_checkins, COALESCE(SUM(quota_awarded), 0) AS total_quota, COALESCE(MAX(streak), 0) AS longest_streak").
+			Scan(summary).Error
+		if err != nil {
+			return nil, err
+		}
+		// 旧记录没有连签天数，至少算 1 天
+		summary.LongestStreak = max(summary.LongestStreak, 1)
+	}
+
+	if common.RedisEnabled {
+		if payload, err := common.Marshal(summary); err == nil {
+			if err := common.RedisSet(getCheckinSummaryCacheKey(userId), string(payload), time.Duration(common.RedisKeyCacheSeconds())*time.Second); err != nil {
+				common.SysLog("failed to cache checkin summary: " + err.Error())
+			}
+		}
+	}
+	return summary, nil
+}
+
+// invalidateCheckinCache 在 date 的签到记录变更后清理用户签到缓存。
+// 补签和撤销会顺延更新之后记录的连签天数，因此从 date 所在月份起到当前月份的日历都要清理
+func invalidateCheckinCache(userId int, date string) {
+	if !common.RedisEnabled {
+		return
+	}
+	if err := common.RedisDel(getCheckinSummaryCacheKey(userId)); err != nil {
+		common.SysLog("failed to invalidate checkin summary cache: " + err.Error())
+	}
+	month, err := time.Parse("2006-01", date[:min(len(date), 7)])
+	if err != nil {
+		return
+	}
+	current := operation_setting.CheckinNow().Format("2006-01")
+	for ; month.Format("2006-01") <= current; month = month.AddDate(0, 1, 0) {
+		if err := common.RedisDel(getCheckinCalendarCacheKey(userId, month.Format("2006-01"))); err != nil {
+			common.SysLog("failed to invalidate checkin calendar cache: " + err.Error())
+		}
+	}
+}
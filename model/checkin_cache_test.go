@@ -0,0 +1,77 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckinCache_StatsServedFromCacheUntilCheckin(t *testing.T) {
+	truncateTables(t)
+	stub := useMiniRedis(t)
+	enableCheckinForTest(t, 100, 100)
+	require.NoError(t, DB.Create(&User{Id: 71, Username: "checkin_cache", Status: common.UserStatusEnabled}).Error)
+
+	now := time.Now()
+	month := now.Format("2006-01")
+	yesterday := now.AddDate(0, 0, -1).Format("2006-01-02")
+	require.NoError(t, DB.Create(&Checkin{UserId: 71, CheckinDate: yesterday, QuotaAwarded: 100, Streak: 3}).Error)
+
+	stats, err := GetUserCheckinStats(71, month)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, stats["total_checkins"])
+	assert.Equal(t, 3, stats["current_streak"])
+	assert.Equal(t, false, stats["checked_in_today"])
+	assert.True(t, stub.Exists(getCheckinSummaryCacheKey(71)))
+	assert.True(t, stub.Exists(getCheckinCalendarCacheKey(71, month)))
+
+	// 绕过签到流程直接写库时缓存不会失效，读取仍是缓存中的旧值
+	require.NoError(t, DB.Create(&Checkin{UserId: 71, CheckinDate: now.AddDate(0, 0, -5).Format("2006-01-02"), QuotaAwarded: 100, Streak: 1}).Error)
+	stats, err = GetUserCheckinStats(71, month)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, stats["total_checkins"])
+
+	_, err = UserCheckin(71, "")
+	require.NoError(t, err)
+	assert.False(t, stub.Exists(getCheckinSummaryCacheKey(71)))
+	assert.False(t, stub.Exists(getCheckinCalendarCacheKey(71, month)))
+
+	stats, err = GetUserCheckinStats(71, month)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, stats["total_checkins"])
+	assert.Equal(t, 4, stats["current_streak"])
+	assert.Equal(t, true, stats["checked_in_today"])
+}
+
+func TestCheckinCache_RevokeInvalidatesLaterMonths(t *testing.T) {
+	truncateTables(t)
+	stub := useMiniRedis(t)
+	require.NoError(t, DB.Create(&User{Id: 72, Username: "checkin_cache_revoke", Status: common.UserStatusEnabled, Quota: 1000}).Error)
+
+	now := time.Now()
+	earlier := now.AddDate(0, -1, 0)
+	revokedDate := earlier.Format("2006-01-02")
+	require.NoError(t, DB.Create(&Checkin{UserId: 72, CheckinDate: revokedDate, QuotaAwarded: 10, Streak: 1}).Error)
+
+	months := []string{earlier.Format("2006-01"), now.Format("2006-01")}
+	for _, month := range months {
+		_, err := GetUserCheckinStats(72, month)
+		require.NoError(t, err)
+		require.True(t, stub.Exists(getCheckinCalendarCacheKey(72, month)))
+	}
+
+	_, err := AdminRevokeCheckin(72, revokedDate)
+	require.NoError(t, err)
+	assert.False(t, stub.Exists(getCheckinSummaryCacheKey(72)))
+	for _, month := range months {
+		assert.False(t, stub.Exists(getCheckinCalendarCacheKey(72, month)), month)
+	}
+
+	records, err := GetUserCheckinCalendar(72, months[0])
+	require.NoError(t, err)
+	assert.Empty(t, records)
+}
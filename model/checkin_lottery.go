@@ -0,0 +1,110 @@
+package model
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CheckinPrizeStock 抽奖奖品每日已发放数量
+// (stock_date, prize_id) 唯一，库存通过带上限条件的 UPDATE 原子扣减，不同用户并发抽奖也不会超发
+type CheckinPrizeStock struct {
+	Id        int    `json:"id" gorm:"primaryKey;autoIncrement"`
+	StockDate string `json:"stock_date" gorm:"type:varchar(10);not null;uniqueIndex:idx_checkin_prize_stock"`
+	PrizeId   string `json:"prize_id" gorm:"type:varchar(64);not null;uniqueIndex:idx_checkin_prize_stock"`
+	Used      int    `json:"used" gorm:"not null;default:0"`
+}
+
+func (CheckinPrizeStock) TableName() string {
+	return "checkin_prize_stocks"
+}
+
+// CheckinPrizeResult 抽奖结果，Index 为奖品在奖品表中的位置，供前端转盘定位
+type CheckinPrizeResult struct {
+	Index      int    `json:"index"`
+	Id         string `json:"id"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Quota      int    `json:"quota"`
+	RedeemCode string `json:"redeem_code,omitempty"`
+}
+
+// claimCheckinPrizeStock 扣减奖品当日库存，库存已用完时返回 false
+func claimCheckinPrizeStock(tx *gorm.DB, date string, prize operation_setting.CheckinPrize) (bool, error) {
+	stock := &CheckinPrizeStock{StockDate: date, PrizeId: prize.Id}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(stock).Error; err != nil {
+		return false, err
+	}
+	result := tx.Model(&CheckinPrizeStock{}).
+		Where("stock_date = ? AND prize_id = ? AND used < ?", date, prize.Id, prize.DailyStock).
+		Update("used", gorm.Expr("used + 1"))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// drawCheckinPrize 按权重从奖品表抽取一个奖品；抽中的奖品当日库存用完时剔除后重抽，
+// 全部抽空时视为谢谢参与。兑换码奖品在同一事务内生成
+func drawCheckinPrize(tx *gorm.DB, userId int, date string, prizes []operation_setting.CheckinPrize) (*CheckinPrizeResult, error) {
+	candidates := make([]int, 0, len(prizes))
+	totalWeight := 0
+	for i, prize := range prizes {
+		if prize.Weight > 0 {
+			candidates = append(candidates, i)
+			totalWeight += prize.Weight
+		}
+	}
+
+	for len(candidates) > 0 {
+		pick := rand.Intn(totalWeight)
+		pos := 0
+		for pick >= prizes[candidates[pos]].Weight {
+			pick -= prizes[candidates[pos]].Weight
+			pos++
+		}
+		index := candidates[pos]
+		prize := prizes[index]
+
+		if prize.DailyStock > 0 {
+			ok, err := claimCheckinPrizeStock(tx, date, prize)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				candidates = append(candidates[:pos], candidates[pos+1:]...)
+				totalWeight -= prize.Weight
+				continue
+			}
+		}
+
+		result := &CheckinPrizeResult{
+			Index: index,
+			Id:    prize.Id,
+			Name:  prize.Name,
+			Type:  prize.Type,
+			Quota: max(prize.Quota, 0),
+		}
+		if prize.Type == operation_setting.CheckinPrizeTypeRedemption && result.Quota > 0 {
+			redemption := &Redemption{
+				UserId:      userId,
+				Key:         common.GetUUID(),
+				Status:      common.RedemptionCodeStatusEnabled,
+				Name:        fmt.Sprintf("签到奖品 %s", prize.Id),
+				Quota:       result.Quota,
+				CreatedTime: common.GetTimestamp(),
+			}
+			if err := tx.Create(redemption).Error; err != nil {
+				return nil, err
+			}
+			result.RedeemCode = redemption.Key
+		}
+		return result, nil
+	}
+
+	return &CheckinPrizeResult{Index: -1, Type: operation_setting.CheckinPrizeTypeNone, Name: "谢谢参与"}, nil
+}
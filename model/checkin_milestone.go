@@ -0,0 +1,102 @@
+package model
+
+import (
+	"sort"
+
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CheckinMilestoneReward 已发放的累计签到里程碑奖励
+// (user_id, milestone_days) 唯一，每个里程碑对每个用户只发放一次；CheckinId 为随之发放奖励的签到记录，
+// 撤销该签到时一并删除，之后再次达到时重新发放。MilestoneDays 为 0 的记录是用户的基线标记，见 ensureCheckinMilestoneBaseline
+type CheckinMilestoneReward struct {
+	Id            int   `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserId        int   `json:"user_id" gorm:"not null;uniqueIndex:idx_checkin_milestone_user_days"`
+	MilestoneDays int   `json:"milestone_days" gorm:"not null;uniqueIndex:idx_checkin_milestone_user_days"`
+	CheckinId     int   `json:"checkin_id" gorm:"not null;index"`
+	Quota         int   `json:"quota" gorm:"not null"`
+	CreatedAt     int64 `json:"created_at" gorm:"bigint"`
+}
+
+func (CheckinMilestoneReward) TableName() string {
+	return "checkin_milestone_rewards"
+}
+
+// payCheckinMilestones 在签到记录写入后发放累计天数已达到但尚未发放的所有里程碑奖励，
+// 补签、管理员补录跨过里程碑时同样补发。奖励计入 checkin.BonusQuota 与 QuotaAwarded 并回写记录，
+// 调用方负责把 QuotaAwarded 加到用户额度上
+func payCheckinMilestones(tx *gorm.DB, checkin *Checkin) error {
+	var total int64
+	if err := tx.Model(&Checkin{}).Where("user_id = ?", checkin.UserId).Count(&total).Error; err != nil {
+		return err
+	}
+	checkin.TotalCheckins = int(total)
+	if err := ensureCheckinMilestoneBaseline(tx, checkin.UserId, checkin.TotalCheckins-1); err != nil {
+		return err
+	}
+
+	milestones := make([]operation_setting.CheckinTotalMilestone, 0)
+	for _, milestone := range operation_setting.GetCheckinSetting().TotalMilestones {
+		if milestone.Days > 0 && milestone.Days <= checkin.TotalCheckins && milestone.Quota > 0 {
+			milestones = append(milestones, milestone)
+		}
+	}
+	if len(milestones) == 0 {
+		return nil
+	}
+	sort.Slice(milestones, func(i, j int) bool { return milestones[i].Days < milestones[j].Days })
+
+	bonus := 0
+	for _, milestone := range milestones {
+		reward := &CheckinMilestoneReward{
+			UserId:        checkin.UserId,
+			MilestoneDays: milestone.Days,
+			CheckinId:     checkin.Id,
+			Quota:         milestone.Quota,
+			CreatedAt:     checkin.CreatedAt,
+		}
+		// 唯一约束保证并发签到时同一里程碑只会有一次插入成功
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(reward)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			bonus += milestone.Quota
+		}
+	}
+	if bonus == 0 {
+		return nil
+	}
+	checkin.BonusQuota += bonus
+	checkin.QuotaAwarded += bonus
+	return tx.Model(&Checkin{}).Where("id = ?", checkin.Id).Updates(map[string]interface{}{
+		"bonus_quota":   checkin.BonusQuota,
+		"quota_awarded": checkin.QuotaAwarded,
+	}).Error
+}
+
+// ensureCheckinMilestoneBaseline 用户首次经过里程碑记录逻辑时写入基线：升级前按“恰好达到”规则
+// 已经历过的里程碑（天数不超过 previousTotal）视为已发放，避免升级后重复发放
+func ensureCheckinMilestoneBaseline(tx *gorm.DB, userId int, previousTotal int) error {
+	var count int64
+	if err := tx.Model(&CheckinMilestoneReward{}).Where("user_id = ?", userId).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	rewards := []CheckinMilestoneReward{{UserId: userId}}
+	for _, milestone := range operation_setting.GetCheckinSetting().TotalMilestones {
+		if milestone.Days > 0 && milestone.Days <= previousTotal {
+			rewards = append(rewards, CheckinMilestoneReward{UserId: userId, MilestoneDays: milestone.Days})
+		}
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rewards).Error
+}
+
+// deleteCheckinMilestoneRewards 撤销签到时删除随其发放的里程碑记录，奖励已包含在撤销扣回的额度中
+func deleteCheckinMilestoneRewards(tx *gorm.DB, checkinId int) error {
+	return tx.Where("checkin_id = ?", checkinId).Delete(&CheckinMilestoneReward{}).Error
+}
@@ -0,0 +1,625 @@
+package model
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func enableCheckinForTest(t *testing.T, minQuota int, maxQuota int) {
+	t.Helper()
+	setting := operation_setting.GetCheckinSetting()
+	enabled := *setting
+	enabled.Enabled = true
+	enabled.MinQuota = minQuota
+	enabled.MaxQuota = maxQuota
+	overrideForTest(t, setting, enabled)
+}
+
+func TestUserCheckin_ConcurrentRequestsAwardOnce(t *testing.T) {
+	truncateTables(t)
+	enableCheckinForTest(t, 500, 500)
+	require.NoError(t, DB.Create(&User{Id: 1, Username: "checkin_user", Status: common.UserStatusEnabled, Quota: 100}).Error)
+
+	const attempts = 20
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := UserCheckin(1, ""); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, succeeded)
+
+	var count int64
+	require.NoError(t, DB.Model(&Checkin{}).Where("user_id = ?", 1).Count(&count).Error)
+	assert.EqualValues(t, 1, count)
+
+	var user User
+	require.NoError(t, DB.Select("quota").Where("id = ?", 1).First(&user).Error)
+	assert.Equal(t, 600, user.Quota)
+}
+
+func TestUserCheckin_SecondCheckinSameDayRejected(t *testing.T) {
+	truncateTables(t)
+	enableCheckinForTest(t, 100, 200)
+	require.NoError(t, DB.Create(&User{Id: 2, Username: "checkin_again", Status: common.UserStatusEnabled}).Error)
+
+	checkin, err := UserCheckin(2, "")
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, checkin.QuotaAwarded, 100)
+	assert.LessOrEqual(t, checkin.QuotaAwarded, 200)
+
+	_, err = UserCheckin(2, "")
+	assert.ErrorIs(t, err, ErrAlreadyCheckedIn)
+}
+
+func TestUserCheckin_StreakContinuesFromYesterday(t *testing.T) {
+	truncateTables(t)
+	enableCheckinForTest(t, 100, 100)
+	require.NoError(t, DB.Create(&User{Id: 3, Username: "checkin_streak", Status: common.UserStatusEnabled}).Error)
+
+	now := time.Now()
+	require.NoError(t, DB.Create(&Checkin{UserId: 3, CheckinDate: now.AddDate(0, 0, -1).Format("2006-01-02"), QuotaAwarded: 100, Streak: 4}).Error)
+
+	checkin, err := UserCheckin(3, "")
+	require.NoError(t, err)
+	assert.Equal(t, 5, checkin.Streak)
+
+	streak, err := GetUserCheckinStreak(3)
+	require.NoError(t, err)
+	assert.Equal(t, 5, streak)
+}
+
+func TestGetUserCheckinStreak_BrokenAfterMissedDay(t *testing.T) {
+	truncateTables(t)
+	require.NoError(t, DB.Create(&Checkin{UserId: 4, CheckinDate: time.Now().AddDate(0, 0, -2).Format("2006-01-02"), QuotaAwarded: 100, Streak: 7}).Error)
+
+	streak, err := GetUserCheckinStreak(4)
+	require.NoError(t, err)
+	assert.Equal(t, 0, streak)
+}
+
+func TestUserCheckin_InvertedQuotaRangeAwardsMinimum(t *testing.T) {
+	truncateTables(t)
+	enableCheckinForTest(t, 300, 100)
+	require.NoError(t, DB.Create(&User{Id: 5, Username: "checkin_inverted", Status: common.UserStatusEnabled}).Error)
+
+	checkin, err := UserCheckin(5, "")
+	require.NoError(t, err)
+	assert.Equal(t, 300, checkin.QuotaAwarded)
+}
+
+func TestUserCheckin_StreakMilestoneMultipliesReward(t *testing.T) {
+	truncateTables(t)
+	enableCheckinForTest(t, 100, 100)
+	operation_setting.GetCheckinSetting().StreakMilestones = []operation_setting.CheckinStreakMilestone{{Days: 3, Multiplier: 2.5}}
+	require.NoError(t, DB.Create(&User{Id: 6, Username: "checkin_bonus", Status: common.UserStatusEnabled}).Error)
+	require.NoError(t, DB.Create(&Checkin{UserId: 6, CheckinDate: time.Now().AddDate(0, 0, -1).Format("2006-01-02"), QuotaAwarded: 100, Streak: 2}).Error)
+
+	checkin, err := UserCheckin(6, "")
+	require.NoError(t, err)
+	assert.Equal(t, 3, checkin.Streak)
+	assert.Equal(t, 2.5, checkin.StreakMultiplier)
+	assert.Equal(t, 250, checkin.QuotaAwarded)
+
+	var user User
+	require.NoError(t, DB.Select("quota").Where("id = ?", 6).First(&user).Error)
+	assert.Equal(t, 250, user.Quota)
+}
+
+func TestUserMakeupCheckin(t *testing.T) {
+	truncateTables(t)
+	enableCheckinForTest(t, 100, 100)
+	setting := operation_setting.GetCheckinSetting()
+	setting.MakeupEnabled = true
+	setting.MakeupCost = 30
+	setting.MakeupDays = 7
+	setting.MakeupMonthlyLimit = 0
+	require.NoError(t, DB.Create(&User{Id: 7, Username: "checkin_makeup", Status: common.UserStatusEnabled, Quota: 50}).Error)
+
+	now := time.Now()
+	day := func(offset int) string { return now.AddDate(0, 0, offset).Format("2006-01-02") }
+	// 前天漏签，昨天和今天已签到
+	require.NoError(t, DB.Create(&Checkin{UserId: 7, CheckinDate: day(-3), QuotaAwarded: 100, Streak: 1}).Error)
+	require.NoError(t, DB.Create(&Checkin{UserId: 7, CheckinDate: day(-1), QuotaAwarded: 100, Streak: 1}).Error)
+	require.NoError(t, DB.Create(&Checkin{UserId: 7, CheckinDate: day(0), QuotaAwarded: 100, Streak: 2}).Error)
+
+	checkin, err := UserMakeupCheckin(7, day(-2))
+	require.NoError(t, err)
+	assert.True(t, checkin.IsMakeup)
+	assert.Equal(t, 2, checkin.Streak)
+
+	streak, err := GetUserCheckinStreak(7)
+	require.NoError(t, err)
+	assert.Equal(t, 4, streak)
+
+	var user User
+	require.NoError(t, DB.Select("quota").Where("id = ?", 7).First(&user).Error)
+	assert.Equal(t, 120, user.Quota)
+
+	_, err = UserMakeupCheckin(7, day(-2))
+	assert.Error(t, err)
+}
+
+func TestUserMakeupCheckin_Rejections(t *testing.T) {
+	truncateTables(t)
+	enableCheckinForTest(t, 100, 100)
+	setting := operation_setting.GetCheckinSetting()
+	setting.MakeupEnabled = true
+	setting.MakeupCost = 1000
+	setting.MakeupDays = 3
+	setting.MakeupMonthlyLimit = 1
+	require.NoError(t, DB.Create(&User{Id: 8, Username: "checkin_makeup_reject", Status: common.UserStatusEnabled, Quota: 10}).Error)
+
+	now := time.Now()
+	day := func(offset int) string { return now.AddDate(0, 0, offset).Format("2006-01-02") }
+
+	_, err := UserMakeupCheckin(8, day(0))
+	assert.Error(t, err, "today is not a make-up date")
+	_, err = UserMakeupCheckin(8, day(-4))
+	assert.Error(t, err, "outside the make-up window")
+	_, err = UserMakeupCheckin(8, "2025/01/01")
+	assert.Error(t, err, "malformed date")
+	_, err = UserMakeupCheckin(8, day(-1))
+	assert.ErrorIs(t, err, ErrCheckinMakeupQuota)
+
+	setting.MakeupCost = 0
+	_, err = UserMakeupCheckin(8, day(-1))
+	require.NoError(t, err)
+	_, err = UserMakeupCheckin(8, day(-2))
+	assert.ErrorIs(t, err, ErrCheckinMakeupLimit)
+}
+
+func TestGetCheckinAdminStats(t *testing.T) {
+	truncateTables(t)
+	require.NoError(t, DB.Create(&User{Id: 11, Username: "stats_a", AffCode: "stats_aff_a", Status: common.UserStatusEnabled, Group: "default"}).Error)
+	require.NoError(t, DB.Create(&User{Id: 12, Username: "stats_b", AffCode: "stats_aff_b", Status: common.UserStatusEnabled, Group: "default"}).Error)
+	require.NoError(t, DB.Create(&User{Id: 13, Username: "stats_c", AffCode: "stats_aff_c", Status: common.UserStatusEnabled, Group: "vip"}).Error)
+	for _, c := range []Checkin{
+		{UserId: 11, CheckinDate: "2026-03-01", QuotaAwarded: 100},
+		{UserId: 11, CheckinDate: "2026-03-02", QuotaAwarded: 200},
+		{UserId: 13, CheckinDate: "2026-03-02", QuotaAwarded: 50},
+		{UserId: 12, CheckinDate: "2026-02-20", QuotaAwarded: 999},
+	} {
+		require.NoError(t, DB.Create(&c).Error)
+	}
+
+	stats, err := GetCheckinAdminStats("2026-03-01", "2026-03-31", 10)
+	require.NoError(t, err)
+
+	assert.EqualValues(t, 3, stats.TotalCount)
+	assert.EqualValues(t, 350, stats.TotalQuota)
+	assert.EqualValues(t, 2, stats.Participants)
+	assert.Equal(t, []CheckinDailyStat{
+		{CheckinDate: "2026-03-01", Count: 1, Quota: 100},
+		{CheckinDate: "2026-03-02", Count: 2, Quota: 250},
+	}, stats.Daily)
+
+	groups := map[string]CheckinGroupStat{}
+	for _, g := range stats.Groups {
+		groups[g.Group] = g
+	}
+	assert.EqualValues(t, 2, groups["default"].Users)
+	assert.EqualValues(t, 1, groups["default"].Participants)
+	assert.Equal(t, 0.5, groups["default"].ParticipationRate)
+	assert.Equal(t, 1.0, groups["vip"].ParticipationRate)
+
+	require.Len(t, stats.TopUsers, 2)
+	assert.Equal(t, CheckinTopUser{UserId: 11, Username: "stats_a", Count: 2, Quota: 300}, stats.TopUsers[0])
+}
+
+func TestAdminGrantAndRevokeCheckin(t *testing.T) {
+	truncateTables(t)
+	require.NoError(t, DB.Create(&User{Id: 21, Username: "checkin_admin", Status: common.UserStatusEnabled, Quota: 1000}).Error)
+
+	now := time.Now()
+	day := func(offset int) string { return now.AddDate(0, 0, offset).Format("2006-01-02") }
+	require.NoError(t, DB.Create(&Checkin{UserId: 21, CheckinDate: day(-2), QuotaAwarded: 10, Streak: 1}).Error)
+	require.NoError(t, DB.Create(&Checkin{UserId: 21, CheckinDate: day(0), QuotaAwarded: 10, Streak: 1}).Error)
+
+	granted, err := AdminGrantCheckin(21, day(-1), 300)
+	require.NoError(t, err)
+	assert.Equal(t, 2, granted.Streak)
+	streak, err := GetUserCheckinStreak(21)
+	require.NoError(t, err)
+	assert.Equal(t, 3, streak)
+
+	_, err = AdminGrantCheckin(21, day(-1), 300)
+	assert.Error(t, err)
+	_, err = AdminGrantCheckin(21, day(1), 300)
+	assert.Error(t, err)
+
+	revoked, err := AdminRevokeCheckin(21, day(-1))
+	require.NoError(t, err)
+	assert.Equal(t, 300, revoked.QuotaAwarded)
+	streak, err = GetUserCheckinStreak(21)
+	require.NoError(t, err)
+	assert.Equal(t, 1, streak)
+
+	var user User
+	require.NoError(t, DB.Select("quota").Where("id = ?", 21).First(&user).Error)
+	assert.Equal(t, 1000, user.Quota)
+
+	_, err = AdminRevokeCheckin(21, day(-1))
+	assert.Error(t, err)
+}
+
+func TestUserCheckin_UsesConfiguredTimezone(t *testing.T) {
+	truncateTables(t)
+	enableCheckinForTest(t, 100, 100)
+	operation_setting.GetCheckinSetting().Timezone = "Pacific/Kiritimati"
+	require.NoError(t, DB.Create(&User{Id: 31, Username: "checkin_tz", Status: common.UserStatusEnabled}).Error)
+
+	loc, err := time.LoadLocation("Pacific/Kiritimati")
+	require.NoError(t, err)
+
+	checkin, err := UserCheckin(31, "")
+	require.NoError(t, err)
+	assert.Equal(t, time.Now().In(loc).Format("2006-01-02"), checkin.CheckinDate)
+
+	checked, err := HasCheckedInToday(31)
+	require.NoError(t, err)
+	assert.True(t, checked)
+}
+
+func TestUserCheckin_TotalMilestoneBonus(t *testing.T) {
+	truncateTables(t)
+	enableCheckinForTest(t, 100, 100)
+	operation_setting.GetCheckinSetting().TotalMilestones = []operation_setting.CheckinTotalMilestone{{Days: 3, Quota: 1000}, {Days: 10, Quota: 5000}}
+	require.NoError(t, DB.Create(&User{Id: 41, Username: "checkin_total", Status: common.UserStatusEnabled}).Error)
+	require.NoError(t, DB.Create(&Checkin{UserId: 41, CheckinDate: "2025-01-01", QuotaAwarded: 100, Streak: 1}).Error)
+	require.NoError(t, DB.Create(&Checkin{UserId: 41, CheckinDate: "2025-01-05", QuotaAwarded: 100, Streak: 1}).Error)
+
+	checkin, err := UserCheckin(41, "")
+	require.NoError(t, err)
+	assert.Equal(t, 1000, checkin.BonusQuota)
+	assert.Equal(t, 1100, checkin.QuotaAwarded)
+
+	stats, err := GetUserCheckinStats(41, time.Now().Format("2006-01"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"days": 10, "quota": 5000, "remaining": 7}, stats["next_milestone"])
+}
+
+func TestCheckinMilestones_PaidOnceAcrossGrantAndRevoke(t *testing.T) {
+	truncateTables(t)
+	enableCheckinForTest(t, 100, 100)
+	operation_setting.GetCheckinSetting().TotalMilestones = []operation_setting.CheckinTotalMilestone{{Days: 3, Quota: 1000}, {Days: 5, Quota: 2000}}
+	require.NoError(t, DB.Create(&User{Id: 42, Username: "checkin_milestone", Status: common.UserStatusEnabled}).Error)
+	day := func(offset int) string { return time.Now().AddDate(0, 0, offset).Format("2006-01-02") }
+
+	granted, err := AdminGrantCheckin(42, day(-10), 10)
+	require.NoError(t, err)
+	assert.Zero(t, granted.BonusQuota)
+	require.NoError(t, DB.Create(&Checkin{UserId: 42, CheckinDate: day(-9), QuotaAwarded: 10, Streak: 1}).Error)
+	granted, err = AdminGrantCheckin(42, day(-8), 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1000, granted.BonusQuota)
+
+	// 补录跨过第 5 天的里程碑时同样补发
+	require.NoError(t, DB.Create(&Checkin{UserId: 42, CheckinDate: day(-7), QuotaAwarded: 10, Streak: 1}).Error)
+	require.NoError(t, DB.Create(&Checkin{UserId: 42, CheckinDate: day(-6), QuotaAwarded: 10, Streak: 1}).Error)
+	jumped, err := AdminGrantCheckin(42, day(-5), 10)
+	require.NoError(t, err)
+	assert.Equal(t, 6, jumped.TotalCheckins)
+	assert.Equal(t, 2000, jumped.BonusQuota)
+	assert.Equal(t, 2010, jumped.QuotaAwarded)
+
+	// 撤销其他记录后再次达到同样的累计天数，不会重复发放
+	_, err = AdminRevokeCheckin(42, day(-7))
+	require.NoError(t, err)
+	checkin, err := UserCheckin(42, "")
+	require.NoError(t, err)
+	assert.Equal(t, 6, checkin.TotalCheckins)
+	assert.Zero(t, checkin.BonusQuota)
+
+	// 撤销发放里程碑的记录会扣回奖励，之后再次达到时重新发放
+	revoked, err := AdminRevokeCheckin(42, day(-5))
+	require.NoError(t, err)
+	assert.Equal(t, 2010, revoked.QuotaAwarded)
+	regranted, err := AdminGrantCheckin(42, day(-4), 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2000, regranted.BonusQuota)
+
+	var user User
+	require.NoError(t, DB.Select("quota").Where("id = ?", 42).First(&user).Error)
+	assert.Equal(t, 10+1010+2010-10+100-2010+2010, user.Quota)
+}
+
+func TestCheckinMilestones_LegacyUsersKeepEarlierMilestones(t *testing.T) {
+	truncateTables(t)
+	enableCheckinForTest(t, 100, 100)
+	operation_setting.GetCheckinSetting().TotalMilestones = []operation_setting.CheckinTotalMilestone{{Days: 3, Quota: 1000}, {Days: 5, Quota: 2000}}
+	require.NoError(t, DB.Create(&User{Id: 43, Username: "checkin_legacy", Status: common.UserStatusEnabled}).Error)
+	for i := 1; i <= 4; i++ {
+		require.NoError(t, DB.Create(&Checkin{UserId: 43, CheckinDate: fmt.Sprintf("2025-01-0%d", i), QuotaAwarded: 100, Streak: i}).Error)
+	}
+
+	// 升级前已经历过第 3 天的里程碑（按旧规则已发放），只发放本次达到的第 5 天
+	checkin, err := UserCheckin(43, "")
+	require.NoError(t, err)
+	assert.Equal(t, 2000, checkin.BonusQuota)
+}
+
+func TestUserCheckin_UsesGroupReward(t *testing.T) {
+	truncateTables(t)
+	enableCheckinForTest(t, 100, 100)
+	operation_setting.GetCheckinSetting().GroupRewards = map[string]operation_setting.CheckinGroupReward{
+		"vip": {MinQuota: 400, MaxQuota: 400, Multiplier: 2},
+	}
+	require.NoError(t, DB.Create(&User{Id: 51, Username: "checkin_vip", AffCode: "checkin_vip", Group: "vip", Status: common.UserStatusEnabled}).Error)
+	require.NoError(t, DB.Create(&User{Id: 52, Username: "checkin_default", AffCode: "checkin_default", Group: "default", Status: common.UserStatusEnabled}).Error)
+
+	vip, err := UserCheckin(51, "")
+	require.NoError(t, err)
+	assert.Equal(t, 800, vip.QuotaAwarded)
+
+	regular, err := UserCheckin(52, "")
+	require.NoError(t, err)
+	assert.Equal(t, 100, regular.QuotaAwarded)
+}
+
+func TestUserCheckin_IpAccountLimit(t *testing.T) {
+	truncateTables(t)
+	enableCheckinForTest(t, 100, 100)
+	setting := operation_setting.GetCheckinSetting()
+	setting.MaxAccountsPerIp = 2
+	for id := 71; id <= 74; id++ {
+		username := fmt.Sprintf("checkin_ip_%d", id)
+		require.NoError(t, DB.Create(&User{Id: id, Username: username, AffCode: username, Status: common.UserStatusEnabled}).Error)
+	}
+
+	_, err := UserCheckin(71, "10.0.0.1")
+	require.NoError(t, err)
+	_, err = UserCheckin(72, "10.0.0.1")
+	require.NoError(t, err)
+
+	setting.IpLimitAction = operation_setting.CheckinIpLimitActionDeny
+	_, err = UserCheckin(73, "10.0.0.1")
+	assert.ErrorIs(t, err, ErrCheckinIpLimited)
+
+	setting.IpLimitAction = operation_setting.CheckinIpLimitActionFlag
+	flagged, err := UserCheckin(74, "10.0.0.1")
+	require.NoError(t, err)
+	assert.True(t, flagged.Flagged)
+
+	today := flagged.CheckinDate
+	clusters, err := GetCheckinIpClusters(today, today, 2, 10)
+	require.NoError(t, err)
+	require.Len(t, clusters, 1)
+	assert.Equal(t, "10.0.0.1", clusters[0].Ip)
+	assert.EqualValues(t, 3, clusters[0].Accounts)
+	assert.EqualValues(t, 1, clusters[0].Flagged)
+	assert.Len(t, clusters[0].Users, 3)
+}
+
+func TestGetCheckinReminderCandidates(t *testing.T) {
+	truncateTables(t)
+	users := []User{
+		{Id: 81, Username: "remind_recent", AffCode: "remind_recent", Status: common.UserStatusEnabled},
+		{Id: 82, Username: "remind_done", AffCode: "remind_done", Status: common.UserStatusEnabled},
+		{Id: 83, Username: "remind_stale", AffCode: "remind_stale", Status: common.UserStatusEnabled},
+		{Id: 84, Username: "remind_disabled", AffCode: "remind_disabled", Status: common.UserStatusDisabled},
+		{Id: 85, Username: "remind_recent2", AffCode: "remind_recent2", Status: common.UserStatusEnabled},
+	}
+	require.NoError(t, DB.Create(&users).Error)
+	checkins := []Checkin{
+		{UserId: 81, CheckinDate: "2026-03-09", QuotaAwarded: 1},
+		{UserId: 82, CheckinDate: "2026-03-09", QuotaAwarded: 1},
+		{UserId: 82, CheckinDate: "2026-03-10", QuotaAwarded: 1},
+		{UserId: 83, CheckinDate: "2026-02-01", QuotaAwarded: 1},
+		{UserId: 84, CheckinDate: "2026-03-09", QuotaAwarded: 1},
+		{UserId: 85, CheckinDate: "2026-03-05", QuotaAwarded: 1},
+	}
+	require.NoError(t, DB.Create(&checkins).Error)
+
+	candidates, err := GetCheckinReminderCandidates("2026-03-10", "2026-03-03", 0, 10)
+	require.NoError(t, err)
+	ids := make([]int, 0, len(candidates))
+	for _, user := range candidates {
+		ids = append(ids, user.Id)
+	}
+	assert.Equal(t, []int{81, 85}, ids)
+
+	page, err := GetCheckinReminderCandidates("2026-03-10", "2026-03-03", 81, 1)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, 85, page[0].Id)
+}
+
+func TestUserCheckin_LotteryStockAndRedemption(t *testing.T) {
+	truncateTables(t)
+	enableCheckinForTest(t, 100, 100)
+	setting := operation_setting.GetCheckinSetting()
+	setting.LotteryEnabled = true
+	setting.Prizes = []operation_setting.CheckinPrize{
+		{Id: "jackpot", Name: "大奖", Type: operation_setting.CheckinPrizeTypeQuota, Quota: 5000, Weight: 1, DailyStock: 1},
+	}
+	for id := 91; id <= 93; id++ {
+		username := fmt.Sprintf("checkin_lottery_%d", id)
+		require.NoError(t, DB.Create(&User{Id: id, Username: username, AffCode: username, Status: common.UserStatusEnabled}).Error)
+	}
+
+	winner, err := UserCheckin(91, "")
+	require.NoError(t, err)
+	require.NotNil(t, winner.Prize)
+	assert.Equal(t, 0, winner.Prize.Index)
+	assert.Equal(t, 5000, winner.QuotaAwarded)
+	assert.Equal(t, "jackpot", winner.PrizeId)
+
+	// 库存用完后剔除该奖品，视为谢谢参与
+	loser, err := UserCheckin(92, "")
+	require.NoError(t, err)
+	require.NotNil(t, loser.Prize)
+	assert.Equal(t, -1, loser.Prize.Index)
+	assert.Equal(t, operation_setting.CheckinPrizeTypeNone, loser.Prize.Type)
+	assert.Equal(t, 0, loser.QuotaAwarded)
+
+	setting.Prizes = []operation_setting.CheckinPrize{
+		{Id: "code", Name: "兑换码", Type: operation_setting.CheckinPrizeTypeRedemption, Quota: 2000, Weight: 1},
+	}
+	coded, err := UserCheckin(93, "")
+	require.NoError(t, err)
+	require.NotEmpty(t, coded.Prize.RedeemCode)
+	assert.Equal(t, 0, coded.QuotaAwarded)
+	var redemption Redemption
+	require.NoError(t, DB.Where(&Redemption{Key: coded.Prize.RedeemCode}).First(&redemption).Error)
+	assert.Equal(t, 2000, redemption.Quota)
+}
+
+func TestExportCheckins_FiltersByUserAndDate(t *testing.T) {
+	truncateTables(t)
+	checkins := []Checkin{
+		{UserId: 1, CheckinDate: "2026-04-01", QuotaAwarded: 10},
+		{UserId: 1, CheckinDate: "2026-04-02", QuotaAwarded: 20},
+		{UserId: 1, CheckinDate: "2026-05-01", QuotaAwarded: 30},
+		{UserId: 2, CheckinDate: "2026-04-02", QuotaAwarded: 40},
+	}
+	require.NoError(t, DB.Create(&checkins).Error)
+
+	tests := []struct {
+		name   string
+		userId int
+		want   []int
+	}{
+		{name: "single user", userId: 1, want: []int{10, 20}},
+		{name: "all users", userId: 0, want: []int{10, 20, 40}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			err := ExportCheckins(tt.userId, "2026-04-01", "2026-04-30", func(batch []Checkin) error {
+				for _, checkin := range batch {
+					got = append(got, checkin.QuotaAwarded)
+				}
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestUserCheckin_AppliesActivePromotion(t *testing.T) {
+	truncateTables(t)
+	enableCheckinForTest(t, 100, 100)
+	today := operation_setting.CheckinNow().Format("2006-01-02")
+	operation_setting.GetCheckinSetting().Promotions = []operation_setting.CheckinPromotion{
+		{Name: "today", StartDate: today, EndDate: today, Multiplier: 3, BonusQuota: 50},
+	}
+	require.NoError(t, DB.Create(&User{Id: 95, Username: "checkin_promo", AffCode: "checkin_promo", Status: common.UserStatusEnabled}).Error)
+
+	checkin, err := UserCheckin(95, "")
+	require.NoError(t, err)
+	require.NotNil(t, checkin.Promotion)
+	assert.Equal(t, 350, checkin.QuotaAwarded)
+}
+
+func TestUserCheckin_RequiresDailyUsageTask(t *testing.T) {
+	truncateTables(t)
+	enableCheckinForTest(t, 100, 100)
+	setting := operation_setting.GetCheckinSetting()
+	setting.RequiredRequests = 2
+	setting.RequiredTokens = 150
+	require.NoError(t, DB.Create(&User{Id: 96, Username: "checkin_task", AffCode: "checkin_task", Status: common.UserStatusEnabled}).Error)
+
+	now := operation_setting.CheckinNow().Unix()
+	logs := []Log{
+		{UserId: 96, Type: LogTypeConsume, CreatedAt: now, PromptTokens: 50, CompletionTokens: 30},
+		{UserId: 96, Type: LogTypeError, CreatedAt: now, PromptTokens: 500},
+		{UserId: 96, Type: LogTypeConsume, CreatedAt: now - 3*24*3600, PromptTokens: 500},
+	}
+	require.NoError(t, LOG_DB.Create(&logs).Error)
+
+	progress, err := GetCheckinTaskProgress(96)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, progress.Requests)
+	assert.EqualValues(t, 80, progress.Tokens)
+	assert.False(t, progress.Completed)
+	_, err = UserCheckin(96, "")
+	assert.ErrorIs(t, err, ErrCheckinTaskIncomplete)
+
+	require.NoError(t, LOG_DB.Create(&Log{UserId: 96, Type: LogTypeConsume, CreatedAt: now, CompletionTokens: 70}).Error)
+	progress, err = GetCheckinTaskProgress(96)
+	require.NoError(t, err)
+	assert.True(t, progress.Completed)
+	_, err = UserCheckin(96, "")
+	require.NoError(t, err)
+}
+
+func TestUserCheckin_ReferralBoostAndInviterKickback(t *testing.T) {
+	truncateTables(t)
+	enableCheckinForTest(t, 100, 100)
+	setting := operation_setting.GetCheckinSetting()
+	setting.ReferralMinInvitees = 2
+	setting.ReferralMultiplier = 1.5
+	setting.InviterKickbackQuota = 20
+
+	users := []User{
+		{Id: 97, Username: "checkin_inviter", AffCode: "checkin_inviter", Status: common.UserStatusEnabled},
+		{Id: 98, Username: "checkin_invitee_a", AffCode: "checkin_invitee_a", Status: common.UserStatusEnabled, InviterId: 97, UsedQuota: 10},
+		{Id: 99, Username: "checkin_invitee_b", AffCode: "checkin_invitee_b", Status: common.UserStatusEnabled, InviterId: 97},
+	}
+	require.NoError(t, DB.Create(&users).Error)
+
+	boost, err := GetCheckinReferralBoost(97)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, boost.ActiveInvitees)
+	assert.False(t, boost.Active)
+	assert.Equal(t, 1.0, boost.Multiplier)
+
+	// 第二个被邀请用户产生消费后达到门槛
+	require.NoError(t, DB.Model(&User{}).Where("id = ?", 99).Update("used_quota", 5).Error)
+	boost, err = GetCheckinReferralBoost(97)
+	require.NoError(t, err)
+	assert.True(t, boost.Active)
+	assert.Equal(t, 1.5, boost.Multiplier)
+
+	checkin, err := UserCheckin(97, "")
+	require.NoError(t, err)
+	assert.Equal(t, 150, checkin.QuotaAwarded)
+	assert.Zero(t, checkin.InviterKickback)
+
+	checkin, err = UserCheckin(98, "")
+	require.NoError(t, err)
+	assert.Equal(t, 100, checkin.QuotaAwarded)
+	assert.Equal(t, 97, checkin.InviterId)
+	assert.Equal(t, 20, checkin.InviterKickback)
+
+	var inviter User
+	require.NoError(t, DB.Select("aff_quota", "aff_history").Where("id = ?", 97).First(&inviter).Error)
+	assert.Equal(t, 20, inviter.AffQuota)
+	assert.Equal(t, 20, inviter.AffHistoryQuota)
+
+	// 撤销被邀请用户的签到时一并扣回邀请人的邀请额度
+	_, err = AdminRevokeCheckin(98, checkin.CheckinDate)
+	require.NoError(t, err)
+	require.NoError(t, DB.Select("aff_quota", "aff_history").Where("id = ?", 97).First(&inviter).Error)
+	assert.Zero(t, inviter.AffQuota)
+	assert.Zero(t, inviter.AffHistoryQuota)
+
+	// 邀请人已划转的部分无法追回，aff_quota 不会扣成负数
+	checkin, err = UserCheckin(98, "")
+	require.NoError(t, err)
+	require.NoError(t, DB.Model(&User{}).Where("id = ?", 97).Update("aff_quota", 5).Error)
+	_, err = AdminRevokeCheckin(98, checkin.CheckinDate)
+	require.NoError(t, err)
+	require.NoError(t, DB.Select("aff_quota", "aff_history").Where("id = ?", 97).First(&inviter).Error)
+	assert.Zero(t, inviter.AffQuota)
+	assert.Zero(t, inviter.AffHistoryQuota)
+}
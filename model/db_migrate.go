@@ -0,0 +1,188 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const defaultDatabaseMigrationBatchSize = 1000
+
+// DatabaseMigrationOptions 跨数据库迁移选项
+type DatabaseMigrationOptions struct {
+	BatchSize int  // 每批读取与写入的行数，默认 1000
+	SkipLogs  bool // 跳过 logs 表，日志量大且允许丢弃历史时使用
+	// Progress 每写入一批后回调，copied 为该表已写入行数
+	Progress func(table string, copied int64, total int64)
+}
+
+// TableMigrationResult 单表迁移后的校验结果
+type TableMigrationResult struct {
+	Table       string `json:"table"`
+	SourceRows  int64  `json:"source_rows"`
+	TargetRows  int64  `json:"target_rows"`
+	SourceMaxId int64  `json:"source_max_id"`
+	TargetMaxId int64  `json:"target_max_id"`
+	Skipped     bool   `json:"skipped"`
+}
+
+// Consistent 行数与最大 ID 均一致时返回 true
+func (r TableMigrationResult) Consistent() bool {
+	return r.Skipped || (r.SourceRows == r.TargetRows && r.SourceMaxId == r.TargetMaxId)
+}
+
+// OpenDatabase 按与 SQL_DSN 相同的规则打开数据库，供迁移工具连接目标库
+func OpenDatabase(dsn string) (*gorm.DB, common.DatabaseType, error) {
+	if dsn == "" {
+		return nil, "", errors.New("dsn is empty")
+	}
+	return openDB(dsn, "target", false)
+}
+
+// MigrateDatabase 将 source 中的全部数据复制到 target，保留原有 ID。
+// 两端的表结构都会先按当前版本迁移，目标库中的表必须为空；完成后返回逐表校验结果
+func MigrateDatabase(source *gorm.DB, sourceType common.DatabaseType, target *gorm.DB, targetType common.DatabaseType, opts DatabaseMigrationOptions) ([]TableMigrationResult, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultDatabaseMigrationBatchSize
+	}
+	if targetType == common.DatabaseTypeMySQL {
+		if err := checkMySQLChineseSupport(target); err != nil {
+			return nil, err
+		}
+	}
+	if err := withMainDB(source, sourceType, migrateDB); err != nil {
+		return nil, fmt.Errorf("migrate source schema: %w", err)
+	}
+	if err := withMainDB(target, targetType, migrateDB); err != nil {
+		return nil, fmt.Errorf("migrate target schema: %w", err)
+	}
+
+	models := append(mainTableModels(), &SubscriptionPlan{})
+	var nonEmpty []string
+	for _, m := range models {
+		var count int64
+		if err := target.Model(m).Unscoped().Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			stmt := &gorm.Statement{DB: target}
+			_ = stmt.Parse(m)
+			nonEmpty = append(nonEmpty, stmt.Table)
+		}
+	}
+	if len(nonEmpty) > 0 {
+		return nil, fmt.Errorf("target database is not empty: %s", strings.Join(nonEmpty, ", "))
+	}
+
+	results := make([]TableMigrationResult, 0, len(models))
+	for _, m := range models {
+		_, skip := m.(*Log)
+		result, err := copyTable(source, target, targetType, m, skip && opts.SkipLogs, opts)
+		if err != nil {
+			return results, fmt.Errorf("copy %s: %w", result.Table, err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// withMainDB 临时将主库切换为 db 执行 fn，用于复用 migrateDB 中依赖全局 DB 的迁移逻辑
+func withMainDB(db *gorm.DB, dbType common.DatabaseType, fn func() error) error {
+	prevDB, prevType := DB, common.MainDatabaseType()
+	DB = db
+	common.SetMainDatabaseType(dbType)
+	initCol()
+	defer func() {
+		DB = prevDB
+		common.SetMainDatabaseType(prevType)
+		initCol()
+	}()
+	return fn()
+}
+
+func copyTable(source *gorm.DB, target *gorm.DB, targetType common.DatabaseType, model interface{}, skip bool, opts DatabaseMigrationOptions) (TableMigrationResult, error) {
+	stmt := &gorm.Statement{DB: source}
+	if err := stmt.Parse(model); err != nil {
+		return TableMigrationResult{}, err
+	}
+	sch := stmt.Schema
+	result := TableMigrationResult{Table: sch.Table, Skipped: skip}
+	if skip {
+		return result, nil
+	}
+	if err := source.Model(model).Unscoped().Count(&result.SourceRows).Error; err != nil {
+		return result, err
+	}
+
+	// 整数主键按 ID 分页，避免大表 OFFSET 越翻越慢；复合主键的表数据量小，按 OFFSET 分页
+	pk := sch.PrioritizedPrimaryField
+	keyset := pk != nil && (pk.DataType == schema.Int || pk.DataType == schema.Uint)
+
+	rows := reflect.New(reflect.SliceOf(reflect.TypeOf(model).Elem()))
+	writer := target.Session(&gorm.Session{SkipHooks: true})
+	var copied, lastId int64
+	for {
+		query := source.Model(model).Unscoped().Limit(opts.BatchSize)
+		if keyset {
+			query = query.Where(clause.Gt{Column: clause.Column{Name: pk.DBName}, Value: lastId})
+		} else {
+			query = query.Offset(int(copied))
+		}
+		for _, field := range sch.PrimaryFields {
+			query = query.Order(clause.OrderByColumn{Column: clause.Column{Name: field.DBName}})
+		}
+		rows.Elem().SetLen(0)
+		if err := query.Find(rows.Interface()).Error; err != nil {
+			return result, err
+		}
+		n := rows.Elem().Len()
+		if n == 0 {
+			break
+		}
+		// Select("*") 使零值字段也写入，避免被数据库默认值覆盖
+		if err := writer.Select("*").Omit(clause.Associations).Create(rows.Interface()).Error; err != nil {
+			return result, err
+		}
+		copied += int64(n)
+		if keyset {
+			value, _ := pk.ValueOf(stmt.Context, rows.Elem().Index(n-1))
+			lastId = reflect.ValueOf(value).Convert(reflect.TypeOf(lastId)).Int()
+		}
+		if opts.Progress != nil {
+			opts.Progress(sch.Table, copied, result.SourceRows)
+		}
+		if n < opts.BatchSize {
+			break
+		}
+	}
+
+	if keyset && targetType == common.DatabaseTypePostgreSQL && pk.AutoIncrement {
+		// 显式写入 ID 不会推进 PostgreSQL 序列，需要手动对齐，否则新插入的行会主键冲突
+		sql := fmt.Sprintf(`SELECT setval(pg_get_serial_sequence('%s', '%s'), COALESCE((SELECT MAX("%s") FROM "%s"), 0) + 1, false)`,
+			sch.Table, pk.DBName, pk.DBName, sch.Table)
+		if err := target.Exec(sql).Error; err != nil {
+			return result, fmt.Errorf("reset sequence: %w", err)
+		}
+	}
+
+	if err := target.Model(model).Unscoped().Count(&result.TargetRows).Error; err != nil {
+		return result, err
+	}
+	if keyset {
+		column := clause.Column{Name: pk.DBName}
+		if err := source.Model(model).Unscoped().Select("COALESCE(MAX(?), 0)", column).Scan(&result.SourceMaxId).Error; err != nil {
+			return result, err
+		}
+		if err := target.Model(model).Unscoped().Select("COALESCE(MAX(?), 0)", column).Scan(&result.TargetMaxId).Error; err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,88 @@
+package model
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func openMigrationTestDB(t *testing.T, name string) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), name)), &gorm.Config{})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = closeDB(db) })
+	return db
+}
+
+func TestMigrateDatabase(t *testing.T) {
+	source := openMigrationTestDB(t, "source.db")
+	target := openMigrationTestDB(t, "target.db")
+	require.NoError(t, withMainDB(source, common.DatabaseTypeSQLite, migrateDB))
+
+	// 不连续的 ID 与零值字段都应原样保留
+	users := []User{
+		{Id: 3, Username: "alice", Password: "x", DisplayName: "alice", Role: common.RoleCommonUser, Status: common.UserStatusEnabled, Quota: 100, Group: "default", AffCode: "a1"},
+		{Id: 9, Username: "bob", Password: "x", DisplayName: "bob", Role: common.RoleAdminUser, Status: common.UserStatusDisabled, Quota: 0, Group: "vip", AffCode: "b2"},
+	}
+	require.NoError(t, source.Session(&gorm.Session{SkipHooks: true}).Create(&users).Error)
+	for i := 1; i <= 5; i++ {
+		require.NoError(t, source.Create(&Log{Id: i * 2, UserId: 3, Type: LogTypeConsume, Content: "consume"}).Error)
+	}
+	require.NoError(t, source.Create(&Ability{Group: "default", Model: "gpt-4o", ChannelId: 1, Enabled: true}).Error)
+	require.NoError(t, source.Create(&Ability{Group: "vip", Model: "gpt-4o", ChannelId: 1, Enabled: false}).Error)
+	require.NoError(t, source.Create(&Option{Key: "SystemName", Value: "new-api"}).Error)
+
+	var progress []int64
+	results, err := MigrateDatabase(source, common.DatabaseTypeSQLite, target, common.DatabaseTypeSQLite, DatabaseMigrationOptions{
+		BatchSize: 2,
+		Progress: func(table string, copied int64, total int64) {
+			if table == "logs" {
+				progress = append(progress, copied)
+			}
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2, 4, 5}, progress)
+
+	byTable := make(map[string]TableMigrationResult)
+	for _, result := range results {
+		assert.True(t, result.Consistent(), result.Table)
+		byTable[result.Table] = result
+	}
+	assert.Equal(t, TableMigrationResult{Table: "logs", SourceRows: 5, TargetRows: 5, SourceMaxId: 10, TargetMaxId: 10}, byTable["logs"])
+	assert.Equal(t, int64(2), byTable["abilities"].TargetRows)
+
+	var migratedUsers []User
+	require.NoError(t, target.Order("id").Find(&migratedUsers).Error)
+	require.Len(t, migratedUsers, 2)
+	assert.Equal(t, 9, migratedUsers[1].Id)
+	assert.Equal(t, common.UserStatusDisabled, migratedUsers[1].Status)
+	assert.Equal(t, 0, migratedUsers[1].Quota)
+	assert.Equal(t, "vip", migratedUsers[1].Group)
+
+	var disabled Ability
+	require.NoError(t, target.Where("channel_id = ? AND model = ? AND "+commonGroupCol+" = ?", 1, "gpt-4o", "vip").First(&disabled).Error)
+	assert.False(t, disabled.Enabled)
+
+	// 目标库非空时拒绝迁移，避免重复写入
+	_, err = MigrateDatabase(source, common.DatabaseTypeSQLite, target, common.DatabaseTypeSQLite, DatabaseMigrationOptions{})
+	require.ErrorContains(t, err, "target database is not empty")
+
+	skipTarget := openMigrationTestDB(t, "skip.db")
+	results, err = MigrateDatabase(source, common.DatabaseTypeSQLite, skipTarget, common.DatabaseTypeSQLite, DatabaseMigrationOptions{SkipLogs: true})
+	require.NoError(t, err)
+	var logCount int64
+	require.NoError(t, skipTarget.Model(&Log{}).Count(&logCount).Error)
+	assert.Zero(t, logCount)
+	for _, result := range results {
+		if result.Table == "logs" {
+			assert.True(t, result.Skipped)
+		}
+	}
+}
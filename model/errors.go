@@ -22,6 +22,12 @@ var (
 	ErrTokenInvalid     = errors.New("token invalid")
 )
 
+// Quota errors
+var (
+	ErrInsufficientUserQuota = errors.New("user quota insufficient")
+	ErrUserQuotaSyncBusy     = errors.New("user quota sync is running on another node, retry later")
+)
+
 // Redemption errors
 var ErrRedeemFailed = errors.New("redeem.failed")
 
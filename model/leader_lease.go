@@ -0,0 +1,101 @@
+package model
+
+import (
+	"errors"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"gorm.io/gorm"
+)
+
+// LeaderLeaseNameBackground is the lease that gates cron-style background
+// jobs (subscription resets, credential refresh, system task scheduling) so
+// they run on exactly one master node at a time.
+const LeaderLeaseNameBackground = "background_jobs"
+
+// LeaderLease is a named, time-bounded leadership record. A node holds the
+// lease while LeaseUntil is in the future and it keeps renewing it; once the
+// holder stops renewing, any other node may take over after expiry.
+type LeaderLease struct {
+	Name       string `json:"name" gorm:"type:varchar(64);primaryKey"`
+	HolderID   string `json:"holder_id" gorm:"type:varchar(128);index"`
+	NodeName   string `json:"node_name" gorm:"type:varchar(128)"`
+	LeaseUntil int64  `json:"lease_until" gorm:"bigint;index"`
+	AcquiredAt int64  `json:"acquired_at" gorm:"bigint"`
+	UpdatedAt  int64  `json:"updated_at" gorm:"bigint"`
+}
+
+// TryAcquireLeaderLease acquires or renews the named lease for holderID.
+// It succeeds when no lease exists, the lease already belongs to holderID,
+// or the current holder's lease has expired.
+func TryAcquireLeaderLease(name string, holderID string, nodeName string, now int64, leaseUntil int64) (bool, error) {
+	lease := &LeaderLease{
+		Name:       name,
+		HolderID:   holderID,
+		NodeName:   nodeName,
+		LeaseUntil: leaseUntil,
+		AcquiredAt: now,
+		UpdatedAt:  now,
+	}
+	if err := DB.Create(lease).Error; err == nil {
+		return true, nil
+	}
+
+	renewed := DB.Model(&LeaderLease{}).
+		Where("name = ? AND holder_id = ?", name, holderID).
+		Updates(map[string]any{
+			"lease_until": leaseUntil,
+			"node_name":   nodeName,
+			"updated_at":  now,
+		})
+	if renewed.Error != nil {
+		return false, renewed.Error
+	}
+	if renewed.RowsAffected > 0 {
+		return true, nil
+	}
+
+	takenOver := DB.Model(&LeaderLease{}).
+		Where("name = ? AND lease_until < ?", name, now).
+		Updates(map[string]any{
+			"holder_id":   holderID,
+			"node_name":   nodeName,
+			"lease_until": leaseUntil,
+			"acquired_at": now,
+			"updated_at":  now,
+		})
+	if takenOver.Error != nil {
+		return false, takenOver.Error
+	}
+	return takenOver.RowsAffected > 0, nil
+}
+
+// ReleaseLeaderLease gives up the lease if holderID still owns it, letting
+// another node take over immediately instead of waiting for expiry.
+func ReleaseLeaderLease(name string, holderID string) error {
+	return DB.Where("name = ? AND holder_id = ?", name, holderID).Delete(&LeaderLease{}).Error
+}
+
+// GetLeaderLease returns the current lease row, or (nil, nil) when no node
+// has ever acquired it.
+func GetLeaderLease(name string) (*LeaderLease, error) {
+	var lease LeaderLease
+	if err := DB.Where("name = ?", name).First(&lease).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &lease, nil
+}
+
+func (lease *LeaderLease) IsActive(now int64) bool {
+	return lease != nil && lease.LeaseUntil >= now
+}
+
+func (lease *LeaderLease) BeforeCreate(_ *gorm.DB) error {
+	if lease.UpdatedAt == 0 {
+		lease.UpdatedAt = common.GetTimestamp()
+	}
+	return nil
+}
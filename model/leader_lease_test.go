@@ -0,0 +1,52 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeaderLeaseSingleHolderUntilExpiry(t *testing.T) {
+	truncateTables(t)
+
+	acquired, err := TryAcquireLeaderLease(LeaderLeaseNameBackground, "node-a", "a", 1000, 1030)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	acquired, err = TryAcquireLeaderLease(LeaderLeaseNameBackground, "node-b", "b", 1010, 1040)
+	require.NoError(t, err)
+	assert.False(t, acquired, "a live lease must not be taken over")
+
+	acquired, err = TryAcquireLeaderLease(LeaderLeaseNameBackground, "node-a", "a", 1020, 1050)
+	require.NoError(t, err)
+	assert.True(t, acquired, "the holder renews its own lease")
+
+	acquired, err = TryAcquireLeaderLease(LeaderLeaseNameBackground, "node-b", "b", 1051, 1081)
+	require.NoError(t, err)
+	assert.True(t, acquired, "an expired lease fails over")
+
+	lease, err := GetLeaderLease(LeaderLeaseNameBackground)
+	require.NoError(t, err)
+	require.NotNil(t, lease)
+	assert.Equal(t, "node-b", lease.HolderID)
+	assert.Equal(t, int64(1051), lease.AcquiredAt)
+}
+
+func TestLeaderLeaseReleaseOnlyByHolder(t *testing.T) {
+	truncateTables(t)
+
+	acquired, err := TryAcquireLeaderLease(LeaderLeaseNameBackground, "node-a", "a", 1000, 1030)
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	require.NoError(t, ReleaseLeaderLease(LeaderLeaseNameBackground, "node-b"))
+	lease, err := GetLeaderLease(LeaderLeaseNameBackground)
+	require.NoError(t, err)
+	require.NotNil(t, lease)
+
+	require.NoError(t, ReleaseLeaderLease(LeaderLeaseNameBackground, "node-a"))
+	acquired, err = TryAcquireLeaderLease(LeaderLeaseNameBackground, "node-b", "b", 1001, 1031)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+}
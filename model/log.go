@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/logger"
 	"github.com/QuantumNous/new-api/types"
 
@@ -100,7 +101,7 @@ func ensureLogRequestId(log *Log) {
 
 func createLog(log *Log) error {
 	ensureLogRequestId(log)
-	return LOG_DB.Create(log).Error
+	return logSink.Write(log)
 }
 
 func clickHouseLogOrder(prefix string) string {
@@ -226,6 +227,7 @@ func RecordLoginLog(userId int, username string, content string, ip string, acti
 // action+params 写入 Other.op，供前端本地化渲染（普通用户可见，不含敏感信息）。
 // adminInfo 存放操作者身份（写入 Other.admin_info，普通用户查询时剥离）；
 // auditInfo 存放路由/方法/结果等中间件兜底信息（写入 Other.audit_info，普通用户查询时剥离）。
+// 带有 adminInfo 的管理员操作同时写入独立的审计表（见 AuditLog）。
 func RecordOperationAuditLog(logUserId int, content string, ip string, action string, params map[string]interface{}, adminInfo map[string]interface{}, auditInfo map[string]interface{}) {
 	username, _ := GetUsernameById(logUserId, false)
 	other := map[string]interface{}{
@@ -249,6 +251,9 @@ func RecordOperationAuditLog(logUserId int, content string, ip string, action st
 	if err := createLog(log); err != nil {
 		common.SysLog("failed to record operation audit log: " + err.Error())
 	}
+	if len(adminInfo) > 0 {
+		recordAuditLog(ip, action, params, adminInfo, auditInfo)
+	}
 }
 
 func RecordTopupLog(userId int, content string, callerIp string, paymentMethod string, callbackPaymentMethod string) {
@@ -341,6 +346,9 @@ type RecordConsumeLogParams struct {
 }
 
 func RecordConsumeLog(c *gin.Context, userId int, params RecordConsumeLogParams) {
+	// 令牌 TPM 限流在请求结束后按此累计值记账，需在日志开关判断之前记录
+	common.SetContextKey(c, constant.ContextKeyTokenUsedTokens,
+		common.GetContextKeyInt(c, constant.ContextKeyTokenUsedTokens)+params.PromptTokens+params.CompletionTokens)
 	if !common.LogConsumeEnabled {
 		return
 	}
@@ -399,6 +407,9 @@ func RecordConsumeLog(c *gin.Context, userId int, params RecordConsumeLogParams)
 			TokenID:   params.TokenId,
 			ChannelID: params.ChannelId,
 			NodeName:  common.NodeName,
+
+			PromptTokens:     params.PromptTokens,
+			CompletionTokens: params.CompletionTokens,
 		})
 	}
 }
@@ -736,6 +747,82 @@ func DeleteOldLogBatch(ctx context.Context, targetTimestamp int64, limit int) (i
 	return result.RowsAffected, nil
 }
 
+// GetOldestLogCreatedAt 返回指定类型中早于 before 的最早日志时间，没有时返回 0
+func GetOldestLogCreatedAt(ctx context.Context, logType int, before int64) (int64, error) {
+	var oldest int64
+	err := LOG_DB.WithContext(ctx).Model(&Log{}).
+		Select("COALESCE(MIN(created_at), 0)").
+		Where("type = ? AND created_at < ?", logType, before).
+		Scan(&oldest).Error
+	return oldest, err
+}
+
+// GetLogsByTypeInRange 按时间顺序分页读取 [start, end) 区间内指定类型的日志，用于归档导出
+func GetLogsByTypeInRange(ctx context.Context, logType int, start int64, end int64, offset int, limit int) ([]*Log, error) {
+	order := "created_at asc, id asc"
+	if common.UsingLogDatabase(common.DatabaseTypeClickHouse) {
+		order = "created_at asc, request_id asc"
+	}
+	var logs []*Log
+	err := LOG_DB.WithContext(ctx).
+		Where("type = ? AND created_at >= ? AND created_at < ?", logType, start, end).
+		Order(order).Offset(offset).Limit(limit).
+		Find(&logs).Error
+	return logs, err
+}
+
+// ExportUserLogs 按时间顺序分批读取用户指定类型的日志并交给 fn 处理，用于用户数据导出；
+// 与用户自查日志一样剥离管理员可见的字段
+func ExportUserLogs(ctx context.Context, userId int, logType int, batchSize int, fn func(batch []*Log) error) error {
+	order := "created_at asc, id asc"
+	if common.UsingLogDatabase(common.DatabaseTypeClickHouse) {
+		order = "created_at asc, request_id asc"
+	}
+	for offset := 0; ; offset += batchSize {
+		var logs []*Log
+		err := LOG_DB.WithContext(ctx).
+			Where("user_id = ? AND type = ?", userId, logType).
+			Order(order).Offset(offset).Limit(batchSize).
+			Find(&logs).Error
+		if err != nil {
+			return err
+		}
+		if len(logs) == 0 {
+			return nil
+		}
+		formatUserLogs(logs, offset)
+		if err := fn(logs); err != nil {
+			return err
+		}
+		if len(logs) < batchSize {
+			return nil
+		}
+	}
+}
+
+// DeleteLogsByTypeInRange 删除 [start, end) 区间内指定类型的日志，ClickHouse 下一次性完成
+func DeleteLogsByTypeInRange(ctx context.Context, logType int, start int64, end int64, limit int) (int64, error) {
+	if common.UsingLogDatabase(common.DatabaseTypeClickHouse) {
+		var total int64
+		if err := LOG_DB.WithContext(ctx).Model(&Log{}).
+			Where("type = ? AND created_at >= ? AND created_at < ?", logType, start, end).
+			Count(&total).Error; err != nil || total == 0 {
+			return 0, err
+		}
+		if err := LOG_DB.WithContext(ctx).Exec(
+			"ALTER TABLE logs DELETE WHERE type = ? AND created_at >= ? AND created_at < ? SETTINGS mutations_sync = 1",
+			logType, start, end,
+		).Error; err != nil {
+			return 0, err
+		}
+		return total, nil
+	}
+	result := LOG_DB.WithContext(ctx).
+		Where("type = ? AND created_at >= ? AND created_at < ?", logType, start, end).
+		Limit(limit).Delete(&Log{})
+	return result.RowsAffected, result.Error
+}
+
 func DeleteOldLog(ctx context.Context, targetTimestamp int64, limit int) (int64, error) {
 	if limit <= 0 {
 		limit = 100
@@ -0,0 +1,151 @@
+package model
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+// 日志写入后端类型
+const (
+	LogSinkTypeDirect = "direct" // 每条日志同步写入 LOG_DB
+	LogSinkTypeBatch  = "batch"  // 内存缓冲后批量异步写入 LOG_DB
+)
+
+// LogSink 是日志写入后端。写入可替换为批量异步实现，以适配 ClickHouse 这类不适合逐行插入的存储；
+// 列表、统计等查询走 LOG_DB，需要实时结果的查询（如签到任务进度）通过 ConsumeStats 计入尚未落库的日志。
+type LogSink interface {
+	Write(log *Log) error
+	// Flush 将缓冲中的日志立即落库，停机时调用
+	Flush()
+	// ConsumeStats 统计用户在 [start, end) 内的消费日志条数与 token 数，包含尚未落库的日志
+	ConsumeStats(userId int, start int64, end int64) (requests int64, tokens int64, err error)
+}
+
+var logSink LogSink = directLogSink{}
+
+type directLogSink struct{}
+
+func (directLogSink) Write(log *Log) error {
+	return LOG_DB.Create(log).Error
+}
+
+func (directLogSink) Flush() {}
+
+func (directLogSink) ConsumeStats(userId int, start int64, end int64) (int64, int64, error) {
+	return queryConsumeStats(userId, start, end)
+}
+
+// queryConsumeStats 在 LOG_DB 中统计已落库的消费日志，语句同时兼容 ClickHouse
+func queryConsumeStats(userId int, start int64, end int64) (int64, int64, error) {
+	var stats struct {
+		Requests int64
+		Tokens   int64
+	}
+	err := LOG_DB.Table("logs").
+		Select("COUNT(*) AS requests, COALESCE(SUM(prompt_tokens), 0) + COALESCE(SUM(completion_tokens), 0) AS tokens").
+		Where("user_id = ? AND type = ? AND created_at >= ? AND created_at < ?", userId, LogTypeConsume, start, end).
+		Scan(&stats).Error
+	return stats.Requests, stats.Tokens, err
+}
+
+type batchLogSink struct {
+	mu        sync.Mutex
+	buffer    []*Log
+	batchSize int
+}
+
+func newBatchLogSink(batchSize int) *batchLogSink {
+	return &batchLogSink{
+		buffer:    make([]*Log, 0, batchSize),
+		batchSize: batchSize,
+	}
+}
+
+func (s *batchLogSink) Write(log *Log) error {
+	s.mu.Lock()
+	s.buffer = append(s.buffer, log)
+	full := len(s.buffer) >= s.batchSize
+	s.mu.Unlock()
+	if full {
+		gopool.Go(s.Flush)
+	}
+	return nil
+}
+
+func (s *batchLogSink) Flush() {
+	s.mu.Lock()
+	logs := s.buffer
+	s.buffer = make([]*Log, 0, s.batchSize)
+	s.mu.Unlock()
+	if len(logs) == 0 {
+		return
+	}
+	if err := LOG_DB.CreateInBatches(logs, s.batchSize).Error; err != nil {
+		common.SysError(fmt.Sprintf("failed to flush %d buffered logs: %v", len(logs), err))
+	}
+}
+
+func (s *batchLogSink) ConsumeStats(userId int, start int64, end int64) (int64, int64, error) {
+	// 先取缓冲再查库：期间被 Flush 落库的日志可能被计两次，但不会漏计
+	var requests, tokens int64
+	s.mu.Lock()
+	for _, log := range s.buffer {
+		if log.UserId == userId && log.Type == LogTypeConsume && log.CreatedAt >= start && log.CreatedAt < end {
+			requests++
+			tokens += int64(log.PromptTokens + log.CompletionTokens)
+		}
+	}
+	s.mu.Unlock()
+	storedRequests, storedTokens, err := queryConsumeStats(userId, start, end)
+	if err != nil {
+		return 0, 0, err
+	}
+	return requests + storedRequests, tokens + storedTokens, nil
+}
+
+// InitLogSink 根据 LOG_SINK 选择日志写入后端，需在 InitLogDB 之后调用。
+// 未配置时 ClickHouse 默认使用批量写入，其余数据库保持逐条同步写入。
+func InitLogSink() {
+	sinkType := common.GetEnvOrDefaultString("LOG_SINK", "")
+	if sinkType == "" {
+		sinkType = LogSinkTypeDirect
+		if common.UsingLogDatabase(common.DatabaseTypeClickHouse) {
+			sinkType = LogSinkTypeBatch
+		}
+	}
+	switch sinkType {
+	case LogSinkTypeBatch:
+		batchSize := common.GetEnvOrDefault("LOG_SINK_BATCH_SIZE", 500)
+		if batchSize <= 0 {
+			batchSize = 500
+		}
+		interval := common.GetEnvOrDefault("LOG_SINK_FLUSH_INTERVAL", 3)
+		if interval <= 0 {
+			interval = 3
+		}
+		sink := newBatchLogSink(batchSize)
+		logSink = sink
+		gopool.Go(func() {
+			for {
+				time.Sleep(time.Duration(interval) * time.Second)
+				sink.Flush()
+			}
+		})
+		common.SysLog(fmt.Sprintf("log sink: batch (size %d, flush interval %ds)", batchSize, interval))
+	case LogSinkTypeDirect:
+		logSink = directLogSink{}
+	default:
+		common.SysError(fmt.Sprintf("unknown LOG_SINK %q, falling back to %s", sinkType, LogSinkTypeDirect))
+		logSink = directLogSink{}
+	}
+}
+
+// FlushLogSink 将缓冲中的日志立即写入，停机时调用避免丢失
+func FlushLogSink() {
+	logSink.Flush()
+}
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchLogSinkBuffersUntilFlush(t *testing.T) {
+	truncateTables(t)
+	sink := newBatchLogSink(10)
+	overrideForTest[LogSink](t, &logSink, sink)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, createLog(&Log{UserId: 1, Type: LogTypeConsume, Content: "buffered"}))
+	}
+	var count int64
+	require.NoError(t, LOG_DB.Model(&Log{}).Count(&count).Error)
+	require.Zero(t, count)
+
+	FlushLogSink()
+	require.NoError(t, LOG_DB.Model(&Log{}).Count(&count).Error)
+	require.Equal(t, int64(3), count)
+
+	// 再次 Flush 空缓冲不应重复写入
+	FlushLogSink()
+	require.NoError(t, LOG_DB.Model(&Log{}).Count(&count).Error)
+	require.Equal(t, int64(3), count)
+}
+
+func TestBatchLogSinkConsumeStatsIncludesBuffered(t *testing.T) {
+	truncateTables(t)
+	sink := newBatchLogSink(10)
+	overrideForTest[LogSink](t, &logSink, sink)
+
+	require.NoError(t, LOG_DB.Create(&Log{UserId: 1, Type: LogTypeConsume, CreatedAt: 100, PromptTokens: 10}).Error)
+	require.NoError(t, createLog(&Log{UserId: 1, Type: LogTypeConsume, CreatedAt: 150, PromptTokens: 5, CompletionTokens: 7}))
+	require.NoError(t, createLog(&Log{UserId: 1, Type: LogTypeError, CreatedAt: 150, PromptTokens: 100}))
+	require.NoError(t, createLog(&Log{UserId: 2, Type: LogTypeConsume, CreatedAt: 150, PromptTokens: 100}))
+	require.NoError(t, createLog(&Log{UserId: 1, Type: LogTypeConsume, CreatedAt: 300, PromptTokens: 100}))
+
+	requests, tokens, err := sink.ConsumeStats(1, 100, 200)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), requests)
+	require.Equal(t, int64(22), tokens)
+
+	FlushLogSink()
+	requests, tokens, err = sink.ConsumeStats(1, 100, 200)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), requests)
+	require.Equal(t, int64(22), tokens)
+}
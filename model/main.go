@@ -125,7 +125,11 @@ func normalizeClickHouseDSN(dsn string) string {
 }
 
 func chooseDB(envName string, isLog bool) (*gorm.DB, common.DatabaseType, error) {
-	dsn := os.Getenv(envName)
+	return openDB(os.Getenv(envName), envName, isLog)
+}
+
+// openDB 按 DSN 格式打开数据库：postgres:// 为 PostgreSQL，空值或 local 为 SQLite，其余为 MySQL
+func openDB(dsn string, envName string, isLog bool) (*gorm.DB, common.DatabaseType, error) {
 	if dsn != "" {
 		if isClickHouseDSN(dsn) {
 			if !isLog {
@@ -260,27 +264,24 @@ func InitLogDB() (err error) {
 	return err
 }
 
-func migrateDB() error {
-	// Migrate price_amount column from float/double to decimal for existing tables
-	migrateSubscriptionPlanPriceAmount()
-	// Migrate model_limits column from varchar to text for existing tables
-	if err := migrateTokenModelLimitsToText(); err != nil {
-		return err
-	}
-
-	err := DB.AutoMigrate(
+// mainTableModels 返回主库中由 AutoMigrate 管理的表，SubscriptionPlan 因 SQLite 需要特殊处理不在其中
+func mainTableModels() []interface{} {
+	return []interface{}{
 		&Channel{},
 		&Token{},
 		&User{},
 		&PasskeyCredential{},
 		&Option{},
 		&Redemption{},
+		&RedemptionUse{},
 		&Ability{},
 		&Log{},
 		&Midjourney{},
 		&TopUp{},
 		&QuotaData{},
 		&Task{},
+		&BatchFile{},
+		&BatchJob{},
 		&Model{},
 		&Vendor{},
 		&PrefillGroup{},
@@ -288,18 +289,47 @@ func migrateDB() error {
 		&TwoFA{},
 		&TwoFABackupCode{},
 		&Checkin{},
+		&QuotaGrant{},
+		&CheckinPrizeStock{},
+		&CheckinMilestoneReward{},
+		&AuditLog{},
+		&ModerationHit{},
+		&UserSession{},
+		&NotificationTemplate{},
+		&AccountDeletionRequest{},
+		&QuotaTransfer{},
+		&PlaygroundSession{},
+		&PlaygroundMessage{},
+		&BodyLog{},
 		&SubscriptionOrder{},
 		&UserSubscription{},
 		&SubscriptionPreConsumeRecord{},
+		&SubscriptionGrant{},
 		&CustomOAuthProvider{},
 		&UserOAuthBinding{},
 		&PerfMetric{},
+		&ChannelHealth{},
+		&WebhookEndpoint{},
+		&WebhookDelivery{},
 		&SystemInstance{},
 		&SystemTask{},
 		&SystemTaskLock{},
+		&LeaderLease{},
 		&CasbinRule{},
 		&AuthzRole{},
-	)
+		&UserStatement{},
+	}
+}
+
+func migrateDB() error {
+	// Migrate price_amount column from float/double to decimal for existing tables
+	migrateSubscriptionPlanPriceAmount()
+	// Migrate model_limits column from varchar to text for existing tables
+	if err := migrateTokenModelLimitsToText(); err != nil {
+		return err
+	}
+
+	err := DB.AutoMigrate(mainTableModels()...)
 	if err != nil {
 		return err
 	}
@@ -329,12 +359,15 @@ func migrateDBFast() error {
 		{&PasskeyCredential{}, "PasskeyCredential"},
 		{&Option{}, "Option"},
 		{&Redemption{}, "Redemption"},
+		{&RedemptionUse{}, "RedemptionUse"},
 		{&Ability{}, "Ability"},
 		{&Log{}, "Log"},
 		{&Midjourney{}, "Midjourney"},
 		{&TopUp{}, "TopUp"},
 		{&QuotaData{}, "QuotaData"},
 		{&Task{}, "Task"},
+		{&BatchFile{}, "BatchFile"},
+		{&BatchJob{}, "BatchJob"},
 		{&Model{}, "Model"},
 		{&Vendor{}, "Vendor"},
 		{&PrefillGroup{}, "PrefillGroup"},
@@ -342,15 +375,33 @@ func migrateDBFast() error {
 		{&TwoFA{}, "TwoFA"},
 		{&TwoFABackupCode{}, "TwoFABackupCode"},
 		{&Checkin{}, "Checkin"},
+		{&QuotaGrant{}, "QuotaGrant"},
+		{&CheckinPrizeStock{}, "CheckinPrizeStock"},
+		{&CheckinMilestoneReward{}, "CheckinMilestoneReward"},
+		{&AuditLog{}, "AuditLog"},
+		{&ModerationHit{}, "ModerationHit"},
+		{&UserSession{}, "UserSession"},
+		{&NotificationTemplate{}, "NotificationTemplate"},
+		{&AccountDeletionRequest{}, "AccountDeletionRequest"},
+		{&QuotaTransfer{}, "QuotaTransfer"},
+		{&PlaygroundSession{}, "PlaygroundSession"},
+		{&PlaygroundMessage{}, "PlaygroundMessage"},
+		{&BodyLog{}, "BodyLog"},
 		{&SubscriptionOrder{}, "SubscriptionOrder"},
 		{&UserSubscription{}, "UserSubscription"},
 		{&SubscriptionPreConsumeRecord{}, "SubscriptionPreConsumeRecord"},
+		{&SubscriptionGrant{}, "SubscriptionGrant"},
 		{&CustomOAuthProvider{}, "CustomOAuthProvider"},
 		{&UserOAuthBinding{}, "UserOAuthBinding"},
 		{&PerfMetric{}, "PerfMetric"},
+		{&ChannelHealth{}, "ChannelHealth"},
+		{&WebhookEndpoint{}, "WebhookEndpoint"},
+		{&WebhookDelivery{}, "WebhookDelivery"},
 		{&SystemInstance{}, "SystemInstance"},
 		{&SystemTask{}, "SystemTask"},
 		{&SystemTaskLock{}, "SystemTaskLock"},
+		{&LeaderLease{}, "LeaderLease"},
+		{&UserStatement{}, "UserStatement"},
 	}
 	// 动态计算migration数量，确保errChan缓冲区足够大
 	errChan := make(chan error, len(migrations))
@@ -1,5 +1,7 @@
 package model
 
+import "gorm.io/gorm"
+
 type Midjourney struct {
 	Id          int    `json:"id"`
 	Code        int    `json:"code"`
@@ -231,3 +233,32 @@ func CountAllUserTask(userId int, queryParams TaskQueryParams) int64 {
 	_ = query.Count(&total).Error
 	return total
 }
+
+// runningMidjourneyTasks 已提交到上游且尚未完成的任务，提交失败的任务带有失败原因
+func runningMidjourneyTasks() *gorm.DB {
+	return DB.Model(&Midjourney{}).Where("progress != ? AND mj_id != ? AND fail_reason = ?", "100%", "", "")
+}
+
+// CountRunningMidjourneyTasks 按用户统计进行中的任务数
+func CountRunningMidjourneyTasks() (map[int]int, error) {
+	var rows []struct {
+		UserId int
+		Count  int
+	}
+	err := runningMidjourneyTasks().Select("user_id, count(*) as count").Group("user_id").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[int]int, len(rows))
+	for _, row := range rows {
+		counts[row.UserId] = row.Count
+	}
+	return counts, nil
+}
+
+// GetStuckMidjourneyTasks 获取提交时间早于 submittedBefore（毫秒）仍未完成的任务
+func GetStuckMidjourneyTasks(submittedBefore int64) ([]*Midjourney, error) {
+	var tasks []*Midjourney
+	err := runningMidjourneyTasks().Where("submit_time < ?", submittedBefore).Find(&tasks).Error
+	return tasks, err
+}
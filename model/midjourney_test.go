@@ -0,0 +1,39 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunningMidjourneyTasks(t *testing.T) {
+	t.Cleanup(func() {
+		DB.Exec("DELETE FROM midjourneys")
+	})
+	tasks := []*Midjourney{
+		{UserId: 1, MjId: "a", Progress: "50%", SubmitTime: 1000},
+		{UserId: 1, MjId: "b", Progress: "", SubmitTime: 5000},
+		{UserId: 2, MjId: "c", Progress: "10%", SubmitTime: 1000},
+		// 已完成
+		{UserId: 2, MjId: "d", Progress: "100%", SubmitTime: 1000},
+		// 提交失败
+		{UserId: 2, MjId: "", Progress: "", SubmitTime: 1000},
+		{UserId: 3, MjId: "e", Progress: "", FailReason: "upstream error", SubmitTime: 1000},
+	}
+	for _, task := range tasks {
+		require.NoError(t, task.Insert())
+	}
+
+	counts, err := CountRunningMidjourneyTasks()
+	require.NoError(t, err)
+	assert.Equal(t, map[int]int{1: 2, 2: 1}, counts)
+
+	stuck, err := GetStuckMidjourneyTasks(2000)
+	require.NoError(t, err)
+	mjIds := make([]string, 0, len(stuck))
+	for _, task := range stuck {
+		mjIds = append(mjIds, task.MjId)
+	}
+	assert.ElementsMatch(t, []string{"a", "c"}, mjIds)
+}
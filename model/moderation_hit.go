@@ -0,0 +1,147 @@
+package model
+
+import (
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"gorm.io/gorm"
+)
+
+// 内容审核命中来源
+const (
+	ModerationSourceWord  = "word"
+	ModerationSourceRegex = "regex"
+	ModerationSourceModel = "model"
+)
+
+// ModerationHit 请求内容审核的命中记录，供管理员查看审核报表；
+// 只记录命中的词/规则/类别，不保存完整请求内容
+type ModerationHit struct {
+	Id         int    `json:"id" gorm:"primaryKey;autoIncrement"`
+	CreatedAt  int64  `json:"created_at" gorm:"bigint;index"`
+	UserId     int    `json:"user_id" gorm:"index"`
+	Username   string `json:"username" gorm:"type:varchar(64)"`
+	TokenId    int    `json:"token_id"`
+	UsingGroup string `json:"group" gorm:"type:varchar(64);index"`
+	ModelName  string `json:"model_name" gorm:"type:varchar(255)"`
+	Source     string `json:"source" gorm:"type:varchar(16);index"`
+	Action     string `json:"action" gorm:"type:varchar(16);index"`
+	Matched    string `json:"matched" gorm:"type:text"` // 命中的违禁词、正则规则或审核类别，逗号分隔
+	RequestId  string `json:"request_id" gorm:"type:varchar(64)"`
+	Ip         string `json:"ip" gorm:"type:varchar(64)"`
+}
+
+func (ModerationHit) TableName() string {
+	return "moderation_hits"
+}
+
+// 单条命中记录中 Matched 的最大长度，避免超长正则或大量命中撑大表
+const moderationHitMatchedMaxLen = 1024
+
+func RecordModerationHit(hit *ModerationHit) error {
+	if hit.CreatedAt == 0 {
+		hit.CreatedAt = common.GetTimestamp()
+	}
+	if len(hit.Matched) > moderationHitMatchedMaxLen {
+		hit.Matched = strings.ToValidUTF8(hit.Matched[:moderationHitMatchedMaxLen], "")
+	}
+	return DB.Create(hit).Error
+}
+
+type ModerationHitQuery struct {
+	UserId         int
+	Group          string
+	Source         string
+	Action         string
+	StartTimestamp int64
+	EndTimestamp   int64
+}
+
+func (q ModerationHitQuery) apply(tx *gorm.DB) *gorm.DB {
+	if q.UserId != 0 {
+		tx = tx.Where("user_id = ?", q.UserId)
+	}
+	if q.Group != "" {
+		tx = tx.Where("using_group = ?", q.Group)
+	}
+	if q.Source != "" {
+		tx = tx.Where("source = ?", q.Source)
+	}
+	if q.Action != "" {
+		tx = tx.Where("action = ?", q.Action)
+	}
+	if q.StartTimestamp != 0 {
+		tx = tx.Where("created_at >= ?", q.StartTimestamp)
+	}
+	if q.EndTimestamp != 0 {
+		tx = tx.Where("created_at <= ?", q.EndTimestamp)
+	}
+	return tx
+}
+
+func GetModerationHits(query ModerationHitQuery, startIdx int, num int) (hits []*ModerationHit, total int64, err error) {
+	tx := query.apply(DB.Model(&ModerationHit{}))
+	if err = tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = tx.Order("id DESC").Limit(num).Offset(startIdx).Find(&hits).Error
+	return hits, total, err
+}
+
+type ModerationHitCount struct {
+	Source string `json:"source"`
+	Action string `json:"action"`
+	Count  int64  `json:"count"`
+}
+
+type ModerationHitUser struct {
+	UserId   int    `json:"user_id"`
+	Username string `json:"username"`
+	Count    int64  `json:"count"`
+}
+
+// ModerationHitStats 审核命中报表：按来源和动作汇总，以及命中最多的用户
+type ModerationHitStats struct {
+	Total    int64                `json:"total"`
+	Counts   []ModerationHitCount `json:"counts"`
+	TopUsers []ModerationHitUser  `json:"top_users"`
+}
+
+func GetModerationHitStats(query ModerationHitQuery, topN int) (*ModerationHitStats, error) {
+	stats := &ModerationHitStats{Counts: []ModerationHitCount{}, TopUsers: []ModerationHitUser{}}
+	err := query.apply(DB.Model(&ModerationHit{})).
+		Select("source, action, count(*) as count").
+		Group("source, action").
+		Order("count DESC").
+		Scan(&stats.Counts).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, count := range stats.Counts {
+		stats.Total += count.Count
+	}
+	err = query.apply(DB.Model(&ModerationHit{})).
+		Select("user_id, max(username) as username, count(*) as count").
+		Group("user_id").
+		Order("count DESC").
+		Limit(topN).
+		Scan(&stats.TopUsers).Error
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// DeleteModerationHitsBefore 删除一批 cutoff 之前的命中记录，返回删除条数
+func DeleteModerationHitsBefore(cutoff int64, limit int) (int64, error) {
+	var ids []int
+	if err := DB.Model(&ModerationHit{}).Where("created_at < ?", cutoff).Order("id").Limit(limit).Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	result := DB.Where("id IN ?", ids).Delete(&ModerationHit{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"errors"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NotificationTemplate 管理员自定义的邮件/通知模板，按模板键和语言区分，
+// 未自定义时使用 service 层内置的默认模板
+type NotificationTemplate struct {
+	Id          int    `json:"id"`
+	TemplateKey string `json:"key" gorm:"type:varchar(64);uniqueIndex:idx_notification_template_key_lang,priority:1"`
+	Language    string `json:"language" gorm:"type:varchar(16);uniqueIndex:idx_notification_template_key_lang,priority:2"`
+	Subject     string `json:"subject" gorm:"type:varchar(255)"`
+	Content     string `json:"content" gorm:"type:text"`
+	UpdatedAt   int64  `json:"updated_at" gorm:"bigint"`
+}
+
+func (NotificationTemplate) TableName() string {
+	return "notification_templates"
+}
+
+func GetAllNotificationTemplates() ([]*NotificationTemplate, error) {
+	var templates []*NotificationTemplate
+	err := DB.Order("template_key, language").Find(&templates).Error
+	return templates, err
+}
+
+// GetNotificationTemplate 获取指定模板键和语言的自定义模板，不存在时返回 nil
+func GetNotificationTemplate(key string, language string) (*NotificationTemplate, error) {
+	var template NotificationTemplate
+	err := DB.Where("template_key = ? AND language = ?", key, language).First(&template).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func GetNotificationTemplateById(id int) (*NotificationTemplate, error) {
+	var template NotificationTemplate
+	err := DB.First(&template, "id = ?", id).Error
+	return &template, err
+}
+
+// UpsertNotificationTemplate 保存自定义模板，同一模板键和语言已存在时覆盖
+func UpsertNotificationTemplate(template *NotificationTemplate) error {
+	template.Id = 0
+	template.UpdatedAt = common.GetTimestamp()
+	err := DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "template_key"}, {Name: "language"}},
+		DoUpdates: clause.AssignmentColumns([]string{"subject", "content", "updated_at"}),
+	}).Create(template).Error
+	if err != nil {
+		return err
+	}
+	// 冲突更新时 Create 不会回填原记录的 id
+	return DB.Select("id").Where("template_key = ? AND language = ?", template.TemplateKey, template.Language).First(template).Error
+}
+
+func DeleteNotificationTemplateById(id int) error {
+	return DB.Delete(&NotificationTemplate{}, "id = ?", id).Error
+}
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpsertNotificationTemplate(t *testing.T) {
+	t.Cleanup(func() { DB.Exec("DELETE FROM notification_templates") })
+
+	first := &NotificationTemplate{TemplateKey: "quota_low", Language: "en", Subject: "v1", Content: "c1"}
+	require.NoError(t, UpsertNotificationTemplate(first))
+	require.NotZero(t, first.Id)
+	other := &NotificationTemplate{TemplateKey: "quota_low", Language: "zh-CN", Subject: "v1", Content: "c1"}
+	require.NoError(t, UpsertNotificationTemplate(other))
+	assert.NotEqual(t, first.Id, other.Id)
+
+	// the same key and language overwrite the existing row and keep its id
+	second := &NotificationTemplate{TemplateKey: "quota_low", Language: "en", Subject: "v2", Content: "c2"}
+	require.NoError(t, UpsertNotificationTemplate(second))
+	assert.Equal(t, first.Id, second.Id)
+
+	template, err := GetNotificationTemplate("quota_low", "en")
+	require.NoError(t, err)
+	require.NotNil(t, template)
+	assert.Equal(t, "v2", template.Subject)
+	assert.Equal(t, "c2", template.Content)
+
+	require.NoError(t, DeleteNotificationTemplateById(first.Id))
+	template, err = GetNotificationTemplate("quota_low", "en")
+	require.NoError(t, err)
+	assert.Nil(t, template)
+
+	templates, err := GetAllNotificationTemplates()
+	require.NoError(t, err)
+	require.Len(t, templates, 1)
+	assert.Equal(t, "zh-CN", templates[0].Language)
+}
@@ -3,6 +3,8 @@ package model
 import (
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/QuantumNous/new-api/common"
@@ -87,6 +89,7 @@ func InitOptionMap() {
 	common.OptionMap["StripeApiSecret"] = setting.StripeApiSecret
 	common.OptionMap["StripeWebhookSecret"] = setting.StripeWebhookSecret
 	common.OptionMap["StripePriceId"] = setting.StripePriceId
+	common.OptionMap["StripeCurrency"] = setting.StripeCurrency
 	common.OptionMap["StripeUnitPrice"] = strconv.FormatFloat(setting.StripeUnitPrice, 'f', -1, 64)
 	common.OptionMap["StripePromotionCodesEnabled"] = strconv.FormatBool(setting.StripePromotionCodesEnabled)
 	common.OptionMap["CreemApiKey"] = setting.CreemApiKey
@@ -143,6 +146,7 @@ func InitOptionMap() {
 	common.OptionMap["ModelPrice"] = ratio_setting.ModelPrice2JSONString()
 	common.OptionMap["CacheRatio"] = ratio_setting.CacheRatio2JSONString()
 	common.OptionMap["CreateCacheRatio"] = ratio_setting.CreateCacheRatio2JSONString()
+	common.OptionMap["CreateCache1hRatio"] = ratio_setting.CreateCache1hRatio2JSONString()
 	common.OptionMap["GroupRatio"] = ratio_setting.GroupRatio2JSONString()
 	common.OptionMap["GroupGroupRatio"] = ratio_setting.GroupGroupRatio2JSONString()
 	common.OptionMap["UserUsableGroups"] = setting.UserUsableGroups2JSONString()
@@ -150,6 +154,8 @@ func InitOptionMap() {
 	common.OptionMap["ImageRatio"] = ratio_setting.ImageRatio2JSONString()
 	common.OptionMap["AudioRatio"] = ratio_setting.AudioRatio2JSONString()
 	common.OptionMap["AudioCompletionRatio"] = ratio_setting.AudioCompletionRatio2JSONString()
+	common.OptionMap["AudioSecondPrice"] = ratio_setting.AudioSecondPrice2JSONString()
+	common.OptionMap["AudioCharacterPrice"] = ratio_setting.AudioCharacterPrice2JSONString()
 	common.OptionMap["TopUpLink"] = common.TopUpLink
 	//common.OptionMap["ChatLink"] = common.ChatLink
 	//common.OptionMap["ChatLink2"] = common.ChatLink2
@@ -183,24 +189,84 @@ func InitOptionMap() {
 	}
 
 	common.OptionMapRWMutex.Unlock()
-	loadOptionsFromDatabase()
+	if err := ReloadOptions(); err != nil {
+		common.SysLog("failed to load options: " + err.Error())
+	}
+}
+
+var optionReloadLock sync.Mutex
+var optionReloadPending atomic.Bool
+
+func init() {
+	common.RegisterCacheInvalidationHandler(common.CacheInvalidationKindOptions, func(common.CacheInvalidationEvent) {
+		scheduleOptionsReload()
+	})
+}
+
+// scheduleOptionsReload 合并短时间内收到的多次配置变更广播，只重新加载一次
+func scheduleOptionsReload() {
+	if !optionReloadPending.CompareAndSwap(false, true) {
+		return
+	}
+	time.AfterFunc(500*time.Millisecond, func() {
+		optionReloadPending.Store(false)
+		if err := ReloadOptions(); err != nil {
+			common.SysLog("failed to reload options: " + err.Error())
+		}
+	})
 }
 
-func loadOptionsFromDatabase() {
-	options, _ := AllOption()
+// ReloadOptions 从数据库重新加载全部配置。同一个已注册配置的所有字段先写入副本再整体替换，
+// 其他请求不会读到新旧字段混杂的配置
+func ReloadOptions() error {
+	options, err := AllOption()
+	if err != nil {
+		return err
+	}
+	optionReloadLock.Lock()
+	defer optionReloadLock.Unlock()
+
+	configMaps := make(map[string]map[string]string)
 	for _, option := range options {
-		err := updateOptionMap(option.Key, option.Value)
-		if err != nil {
+		parts := strings.SplitN(option.Key, ".", 2)
+		if len(parts) == 2 && config.GlobalConfig.Get(parts[0]) != nil {
+			if configMaps[parts[0]] == nil {
+				configMaps[parts[0]] = make(map[string]string)
+			}
+			configMaps[parts[0]][parts[1]] = option.Value
+			continue
+		}
+		if err := updateOptionMap(option.Key, option.Value); err != nil {
 			common.SysLog("failed to update option map: " + err.Error())
 		}
 	}
+	for configName, configMap := range configMaps {
+		if err := config.GlobalConfig.Reload(configName, configMap); err != nil {
+			common.SysLog("failed to reload config " + configName + ": " + err.Error())
+			continue
+		}
+		common.OptionMapRWMutex.Lock()
+		for key, value := range configMap {
+			common.OptionMap[configName+"."+key] = value
+		}
+		afterConfigUpdate(configName)
+		common.OptionMapRWMutex.Unlock()
+	}
+	return nil
+}
+
+// BroadcastOptionsReload 通知其他节点从数据库重新加载配置
+func BroadcastOptionsReload() {
+	common.PublishCacheInvalidation(common.CacheInvalidationKindOptions, "")
 }
 
 func SyncOptions(frequency int) {
 	for {
 		time.Sleep(time.Duration(frequency) * time.Second)
 		common.SysLog("syncing options from database")
-		loadOptionsFromDatabase()
+		if err := ReloadOptions(); err != nil {
+			common.SysLog("failed to sync options: " + err.Error())
+		}
 	}
 }
 
@@ -217,7 +283,11 @@ func UpdateOption(key string, value string) error {
 	// otherwise it will execute Update (with all fields).
 	DB.Save(&option)
 	// Update OptionMap
-	return updateOptionMap(key, value)
+	if err := updateOptionMap(key, value); err != nil {
+		return err
+	}
+	BroadcastOptionsReload()
+	return nil
 }
 
 // UpdateOptionsBulk persists multiple key/value pairs in a single database
@@ -250,6 +320,7 @@ func UpdateOptionsBulk(values map[string]string) error {
 			return err
 		}
 	}
+	BroadcastOptionsReload()
 	return nil
 }
 
@@ -410,6 +481,8 @@ func updateOptionMap(key string, value string) (err error) {
 		setting.StripeWebhookSecret = value
 	case "StripePriceId":
 		setting.StripePriceId = value
+	case "StripeCurrency":
+		setting.StripeCurrency = strings.ToLower(strings.TrimSpace(value))
 	case "StripeUnitPrice":
 		setting.StripeUnitPrice, _ = strconv.ParseFloat(value, 64)
 	case "StripeMinTopUp":
@@ -540,12 +613,18 @@ func updateOptionMap(key string, value string) (err error) {
 		err = ratio_setting.UpdateCacheRatioByJSONString(value)
 	case "CreateCacheRatio":
 		err = ratio_setting.UpdateCreateCacheRatioByJSONString(value)
+	case "CreateCache1hRatio":
+		err = ratio_setting.UpdateCreateCache1hRatioByJSONString(value)
 	case "ImageRatio":
 		err = ratio_setting.UpdateImageRatioByJSONString(value)
 	case "AudioRatio":
 		err = ratio_setting.UpdateAudioRatioByJSONString(value)
 	case "AudioCompletionRatio":
 		err = ratio_setting.UpdateAudioCompletionRatioByJSONString(value)
+	case "AudioSecondPrice":
+		err = ratio_setting.UpdateAudioSecondPriceByJSONString(value)
+	case "AudioCharacterPrice":
+		err = ratio_setting.UpdateAudioCharacterPriceByJSONString(value)
 	case "TopUpLink":
 		common.TopUpLink = value
 	//case "ChatLink":
@@ -597,8 +676,13 @@ func handleConfigUpdate(key, value string) bool {
 		configKey: value,
 	}
 	config.UpdateConfigFromMap(cfg, configMap)
+	afterConfigUpdate(configName)
 
-	// 特定配置的后处理
+	return true // 已处理
+}
+
+// afterConfigUpdate 执行特定配置更新后的后处理
+func afterConfigUpdate(configName string) {
 	if configName == "performance_setting" {
 		performance_setting.UpdateAndSync()
 	} else if configName == "tool_price_setting" {
@@ -609,6 +693,4 @@ func handleConfigUpdate(key, value string) bool {
 	} else if configName == "theme" {
 		system_setting.UpdateAndSyncTheme()
 	}
-
-	return true // 已处理
 }
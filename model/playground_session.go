@@ -0,0 +1,129 @@
+package model
+
+import (
+	"errors"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"gorm.io/gorm"
+)
+
+// PlaygroundSession 操练场会话，保存在服务端，消息通过用户自己的令牌发送并计费
+type PlaygroundSession struct {
+	Id           int    `json:"id"`
+	UserId       int    `json:"user_id" gorm:"index"`
+	Title        string `json:"title" gorm:"type:varchar(128)"`
+	Model        string `json:"model" gorm:"type:varchar(255)"`
+	TokenId      int    `json:"token_id"` // 0 表示使用用户的默认令牌
+	SystemPrompt string `json:"system_prompt" gorm:"type:text"`
+	CreatedAt    int64  `json:"created_at" gorm:"bigint"`
+	UpdatedAt    int64  `json:"updated_at" gorm:"bigint;index"`
+}
+
+func (PlaygroundSession) TableName() string {
+	return "playground_sessions"
+}
+
+// PlaygroundMessage 操练场会话中的一条消息，助手消息记录本轮的 token 用量和按当前价格估算的额度
+type PlaygroundMessage struct {
+	Id               int    `json:"id"`
+	SessionId        int    `json:"session_id" gorm:"index"`
+	Role             string `json:"role" gorm:"type:varchar(16)"`
+	Content          string `json:"content" gorm:"type:text"`
+	Model            string `json:"model" gorm:"type:varchar(255)"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+	Quota            int    `json:"quota"`
+	CreatedAt        int64  `json:"created_at" gorm:"bigint"`
+}
+
+func (PlaygroundMessage) TableName() string {
+	return "playground_messages"
+}
+
+func CreatePlaygroundSession(session *PlaygroundSession) error {
+	now := common.GetTimestamp()
+	session.CreatedAt = now
+	session.UpdatedAt = now
+	return DB.Create(session).Error
+}
+
+// GetUserPlaygroundSessions 分页查询用户的操练场会话，最近更新的在前
+func GetUserPlaygroundSessions(userId int, startIdx int, num int) (sessions []*PlaygroundSession, total int64, err error) {
+	query := DB.Model(&PlaygroundSession{}).Where("user_id = ?", userId)
+	if err = query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = query.Order("updated_at desc, id desc").Limit(num).Offset(startIdx).Find(&sessions).Error
+	return sessions, total, err
+}
+
+// GetUserPlaygroundSession 获取属于该用户的会话
+func GetUserPlaygroundSession(id int, userId int) (*PlaygroundSession, error) {
+	var session PlaygroundSession
+	err := DB.Where("id = ? AND user_id = ?", id, userId).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("会话不存在")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func UpdatePlaygroundSession(session *PlaygroundSession) error {
+	session.UpdatedAt = common.GetTimestamp()
+	return DB.Model(session).Select("title", "model", "token_id", "system_prompt", "updated_at").Updates(session).Error
+}
+
+// DeletePlaygroundSession 删除会话及其全部消息
+func DeletePlaygroundSession(id int, userId int) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND user_id = ?", id, userId).Delete(&PlaygroundSession{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("会话不存在")
+		}
+		return tx.Where("session_id = ?", id).Delete(&PlaygroundMessage{}).Error
+	})
+}
+
+func GetPlaygroundMessages(sessionId int) ([]*PlaygroundMessage, error) {
+	var messages []*PlaygroundMessage
+	err := DB.Where("session_id = ?", sessionId).Order("id asc").Find(&messages).Error
+	return messages, err
+}
+
+// AddPlaygroundMessages 追加一轮对话的消息并刷新会话的更新时间
+func AddPlaygroundMessages(sessionId int, messages ...*PlaygroundMessage) error {
+	now := common.GetTimestamp()
+	return DB.Transaction(func(tx *gorm.DB) error {
+		for _, message := range messages {
+			message.SessionId = sessionId
+			message.CreatedAt = now
+		}
+		if err := tx.Create(&messages).Error; err != nil {
+			return err
+		}
+		return tx.Model(&PlaygroundSession{}).Where("id = ?", sessionId).Update("updated_at", now).Error
+	})
+}
+
+// GetUserDefaultToken 用户的默认令牌：id 最小的可用令牌
+func GetUserDefaultToken(userId int) (*Token, error) {
+	var token Token
+	now := common.GetTimestamp()
+	err := DB.Where("user_id = ? AND status = ?", userId, common.TokenStatusEnabled).
+		Where("expired_time = -1 OR expired_time > ?", now).
+		Where("unlimited_quota = ? OR remain_quota > 0", true).
+		Order("id asc").First(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("没有可用的令牌，请先创建令牌")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
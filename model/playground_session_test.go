@@ -0,0 +1,65 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlaygroundSessionLifecycle(t *testing.T) {
+	t.Cleanup(func() {
+		DB.Exec("DELETE FROM playground_sessions")
+		DB.Exec("DELETE FROM playground_messages")
+	})
+
+	session := &PlaygroundSession{UserId: 701, Title: "first", Model: "gpt-4o"}
+	require.NoError(t, CreatePlaygroundSession(session))
+	require.NoError(t, CreatePlaygroundSession(&PlaygroundSession{UserId: 702, Title: "other", Model: "gpt-4o"}))
+
+	_, err := GetUserPlaygroundSession(session.Id, 702)
+	assert.Error(t, err, "sessions are private to their owner")
+
+	require.NoError(t, AddPlaygroundMessages(session.Id,
+		&PlaygroundMessage{Role: "user", Content: "hi", PromptTokens: 10},
+		&PlaygroundMessage{Role: "assistant", Content: "hello", CompletionTokens: 2, Quota: 30},
+	))
+	messages, err := GetPlaygroundMessages(session.Id)
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	assert.Equal(t, "user", messages[0].Role)
+	assert.Equal(t, "assistant", messages[1].Role)
+	assert.Equal(t, 30, messages[1].Quota)
+
+	sessions, total, err := GetUserPlaygroundSessions(701, 0, 10)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, "first", sessions[0].Title)
+
+	assert.Error(t, DeletePlaygroundSession(session.Id, 702))
+	require.NoError(t, DeletePlaygroundSession(session.Id, 701))
+	messages, err = GetPlaygroundMessages(session.Id)
+	require.NoError(t, err)
+	assert.Empty(t, messages)
+}
+
+func TestGetUserDefaultToken(t *testing.T) {
+	t.Cleanup(func() { DB.Exec("DELETE FROM tokens") })
+
+	_, err := GetUserDefaultToken(711)
+	assert.Error(t, err)
+
+	now := common.GetTimestamp()
+	require.NoError(t, DB.Create(&Token{UserId: 711, Key: "pg-disabled", Status: common.TokenStatusDisabled, ExpiredTime: -1, UnlimitedQuota: true}).Error)
+	require.NoError(t, DB.Create(&Token{UserId: 711, Key: "pg-expired", Status: common.TokenStatusEnabled, ExpiredTime: now - 10, UnlimitedQuota: true}).Error)
+	require.NoError(t, DB.Create(&Token{UserId: 711, Key: "pg-empty", Status: common.TokenStatusEnabled, ExpiredTime: -1, RemainQuota: 0}).Error)
+	require.NoError(t, DB.Create(&Token{UserId: 711, Key: "pg-usable", Status: common.TokenStatusEnabled, ExpiredTime: -1, RemainQuota: 100}).Error)
+	require.NoError(t, DB.Create(&Token{UserId: 711, Key: "pg-later", Status: common.TokenStatusEnabled, ExpiredTime: -1, UnlimitedQuota: true}).Error)
+
+	token, err := GetUserDefaultToken(711)
+	require.NoError(t, err)
+	assert.Equal(t, "pg-usable", token.Key)
+}
@@ -28,6 +28,7 @@ type Pricing struct {
 	CompletionRatio        float64                 `json:"completion_ratio"`
 	CacheRatio             *float64                `json:"cache_ratio,omitempty"`
 	CreateCacheRatio       *float64                `json:"create_cache_ratio,omitempty"`
+	CreateCache1hRatio     *float64                `json:"create_cache_1h_ratio,omitempty"`
 	ImageRatio             *float64                `json:"image_ratio,omitempty"`
 	AudioRatio             *float64                `json:"audio_ratio,omitempty"`
 	AudioCompletionRatio   *float64                `json:"audio_completion_ratio,omitempty"`
@@ -36,6 +37,13 @@ type Pricing struct {
 	BillingMode            string                  `json:"billing_mode,omitempty"`
 	BillingExpr            string                  `json:"billing_expr,omitempty"`
 	PricingVersion         string                  `json:"pricing_version,omitempty"`
+	ChannelPriceOverrides  []PricingChannelPrice   `json:"channel_price_overrides,omitempty"` // 分组内部分渠道使用的覆盖价格
+}
+
+// PricingChannelPrice 某分组下渠道级价格覆盖，不暴露渠道信息
+type PricingChannelPrice struct {
+	Group string `json:"group"`
+	dto.ChannelModelPrice
 }
 
 type PricingVendor struct {
@@ -170,6 +178,34 @@ func loadPricingAdvancedCustomConfigs(enableAbilities []AbilityWithChannel) map[
 	return configs
 }
 
+// loadPricingChannelPrices 收集各模型在各分组下的渠道价格覆盖，相同分组下相同的价格只保留一份
+func loadPricingChannelPrices(enableAbilities []AbilityWithChannel) map[string][]PricingChannelPrice {
+	channelPrices := make(map[int]map[string]dto.ChannelModelPrice)
+	seen := make(map[string]struct{})
+	result := make(map[string][]PricingChannelPrice)
+	for _, ability := range enableAbilities {
+		prices, loaded := channelPrices[ability.ChannelId]
+		if !loaded {
+			channel, err := CacheGetChannel(ability.ChannelId)
+			if err == nil {
+				prices = channel.GetOtherSettings().ModelPrices
+			}
+			channelPrices[ability.ChannelId] = prices
+		}
+		price, ok := prices[ability.Model]
+		if !ok {
+			continue
+		}
+		key := ability.Model + "|" + ability.Group + "|" + common.GetJsonString(price)
+		if _, exists := seen[key]; exists {
+			continue
+		}
+		seen[key] = struct{}{}
+		result[ability.Model] = append(result[ability.Model], PricingChannelPrice{Group: ability.Group, ChannelModelPrice: price})
+	}
+	return result
+}
+
 func appendPricingEndpoint(endpoints []string, endpoint string) []string {
 	if endpoint == "" || common.StringsContains(endpoints, endpoint) {
 		return endpoints
@@ -354,12 +390,15 @@ func updatePricing() {
 		}
 	}
 
+	channelPriceOverrides := loadPricingChannelPrices(enableAbilities)
+
 	pricingMap = make([]Pricing, 0)
 	for model, groups := range modelGroupsMap {
 		pricing := Pricing{
 			ModelName:              model,
 			EnableGroup:            groups.Items(),
 			SupportedEndpointTypes: modelSupportEndpointTypes[model],
+			ChannelPriceOverrides:  channelPriceOverrides[model],
 		}
 
 		// 补充模型元数据（描述、标签、供应商、状态）
@@ -389,6 +428,9 @@ func updatePricing() {
 		if createCacheRatio, ok := ratio_setting.GetCreateCacheRatio(model); ok {
 			pricing.CreateCacheRatio = &createCacheRatio
 		}
+		if createCache1hRatio, ok := ratio_setting.GetCreateCache1hRatio(model); ok {
+			pricing.CreateCache1hRatio = &createCache1hRatio
+		}
 		if imageRatio, ok := ratio_setting.GetImageRatio(model); ok {
 			pricing.ImageRatio = &imageRatio
 		}
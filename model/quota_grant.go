@@ -0,0 +1,207 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+
+	"gorm.io/gorm"
+)
+
+// QuotaGrantSourceCheckin 签到奖励产生的限时额度
+const QuotaGrantSourceCheckin = "checkin"
+
+// QuotaGrantSourceAdmin 管理接口发放的限时额度
+const QuotaGrantSourceAdmin = "admin"
+
+// QuotaGrant 限时额度：发放时额度照常计入 users.quota，这里额外记录其中会过期的部分。
+// 消费时优先扣减最早过期的 Remaining；到期后仍未用完的 Remaining 从用户额度中扣回。
+type QuotaGrant struct {
+	Id           int    `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserId       int    `json:"user_id" gorm:"index:idx_quota_grant_user_expiry,priority:1"`
+	Source       string `json:"source" gorm:"type:varchar(32);index:idx_quota_grant_source,priority:1"`
+	SourceId     int    `json:"source_id" gorm:"index:idx_quota_grant_source,priority:2"`
+	Amount       int    `json:"amount"`
+	Remaining    int    `json:"remaining"`
+	ExpiredQuota int    `json:"expired_quota"`
+	ExpiresAt    int64  `json:"expires_at" gorm:"bigint;index:idx_quota_grant_user_expiry,priority:2"`
+	CreatedAt    int64  `json:"created_at" gorm:"bigint"`
+}
+
+// QuotaGrantSummary 用户限时额度概览
+type QuotaGrantSummary struct {
+	ExpiringQuota int          `json:"expiring_quota"`
+	NextExpiresAt int64        `json:"next_expires_at"`
+	Grants        []QuotaGrant `json:"grants"`
+}
+
+// quotaGrantsActive 标记是否存在未过期且未用完的限时额度。没有时消费路径完全跳过
+// 限时额度扣减，避免给每次请求增加一次查询；由后台任务定期刷新，新发放时立即置位。
+var quotaGrantsActive atomic.Bool
+
+func createQuotaGrant(tx *gorm.DB, userId int, source string, sourceId int, amount int, expiresAt int64) error {
+	if amount <= 0 {
+		return nil
+	}
+	grant := &QuotaGrant{
+		UserId:    userId,
+		Source:    source,
+		SourceId:  sourceId,
+		Amount:    amount,
+		Remaining: amount,
+		ExpiresAt: expiresAt,
+		CreatedAt: common.GetTimestamp(),
+	}
+	if err := tx.Create(grant).Error; err != nil {
+		return err
+	}
+	quotaGrantsActive.Store(true)
+	return nil
+}
+
+// GrantUserQuota 为用户增加额度，expiresAt 大于 0 时登记为限时额度，到期未用完的部分会被扣回
+func GrantUserQuota(userId int, quota int, expiresAt int64) error {
+	if quota <= 0 {
+		return errors.New("quota 必须为正数")
+	}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&User{}).Where("id = ?", userId).Update("quota", gorm.Expr("quota + ?", quota))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if expiresAt > 0 {
+			return createQuotaGrant(tx, userId, QuotaGrantSourceAdmin, 0, quota, expiresAt)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	_ = cacheIncrUserQuota(userId, int64(quota))
+	return nil
+}
+
+// deleteQuotaGrantsBySource 删除某个来源记录对应的限时额度，用于撤销发放
+func deleteQuotaGrantsBySource(tx *gorm.DB, source string, sourceId int) error {
+	return tx.Where("source = ? AND source_id = ?", source, sourceId).Delete(&QuotaGrant{}).Error
+}
+
+// drainQuotaGrants 消费 quota 额度时，按过期时间从早到晚扣减用户的限时额度余量
+func drainQuotaGrants(userId int, quota int) error {
+	if quota <= 0 {
+		return nil
+	}
+	now := common.GetTimestamp()
+	var grants []QuotaGrant
+	if err := DB.Select("id", "remaining").
+		Where("user_id = ? AND remaining > 0 AND expires_at > ?", userId, now).
+		Order("expires_at ASC, id ASC").
+		Find(&grants).Error; err != nil {
+		return err
+	}
+	for _, grant := range grants {
+		if quota <= 0 {
+			return nil
+		}
+		deduct := min(grant.Remaining, quota)
+		// 条件更新防止并发扣减把 remaining 扣成负数；失败时下一轮消费会继续扣减
+		result := DB.Model(&QuotaGrant{}).
+			Where("id = ? AND remaining >= ?", grant.Id, deduct).
+			Update("remaining", gorm.Expr("remaining - ?", deduct))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			quota -= deduct
+		}
+	}
+	return nil
+}
+
+// ExpireDueQuotaGrants 处理到期的限时额度，把未用完的部分从用户额度中扣回，返回处理的条数
+func ExpireDueQuotaGrants(limit int) (int, error) {
+	if limit <= 0 {
+		limit = 200
+	}
+	now := common.GetTimestamp()
+	var grants []QuotaGrant
+	if err := DB.Where("remaining > 0 AND expires_at <= ?", now).
+		Order("expires_at ASC, id ASC").
+		Limit(limit).
+		Find(&grants).Error; err != nil {
+		return 0, err
+	}
+	expired := 0
+	for _, grant := range grants {
+		var expiredQuota int
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			var user User
+			if err := lockForUpdate(tx).Select("id", "quota").Where("id = ?", grant.UserId).First(&user).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return tx.Model(&QuotaGrant{}).Where("id = ?", grant.Id).Update("remaining", 0).Error
+				}
+				return err
+			}
+			var current QuotaGrant
+			if err := lockForUpdate(tx).Where("id = ?", grant.Id).First(&current).Error; err != nil {
+				return err
+			}
+			// 用户额度可能已被其他途径扣减到低于余量，扣回时不让额度变为负数
+			expiredQuota = max(min(current.Remaining, user.Quota), 0)
+			if err := tx.Model(&QuotaGrant{}).Where("id = ?", current.Id).Updates(map[string]interface{}{
+				"remaining":     0,
+				"expired_quota": expiredQuota,
+			}).Error; err != nil {
+				return err
+			}
+			if expiredQuota == 0 {
+				return nil
+			}
+			return tx.Model(&User{}).Where("id = ?", grant.UserId).
+				Update("quota", gorm.Expr("quota - ?", expiredQuota)).Error
+		})
+		if err != nil {
+			return expired, err
+		}
+		expired++
+		if expiredQuota > 0 {
+			_ = cacheDecrUserQuota(grant.UserId, int64(expiredQuota))
+			RecordLog(grant.UserId, LogTypeSystem, fmt.Sprintf("限时额度到期，扣回未使用额度 %s", logger.LogQuota(expiredQuota)))
+		}
+	}
+	return expired, nil
+}
+
+// RefreshQuotaGrantsActive 根据数据库中是否还有有效的限时额度刷新本节点的消费扣减开关
+func RefreshQuotaGrantsActive() error {
+	var grant QuotaGrant
+	err := DB.Select("id").Where("remaining > 0 AND expires_at > ?", common.GetTimestamp()).Limit(1).Find(&grant).Error
+	if err != nil {
+		return err
+	}
+	quotaGrantsActive.Store(grant.Id != 0)
+	return nil
+}
+
+// GetUserQuotaGrantSummary 获取用户仍然有效的限时额度
+func GetUserQuotaGrantSummary(userId int) (*QuotaGrantSummary, error) {
+	summary := &QuotaGrantSummary{Grants: []QuotaGrant{}}
+	if err := DB.Where("user_id = ? AND remaining > 0 AND expires_at > ?", userId, common.GetTimestamp()).
+		Order("expires_at ASC, id ASC").
+		Find(&summary.Grants).Error; err != nil {
+		return nil, err
+	}
+	for _, grant := range summary.Grants {
+		summary.ExpiringQuota += grant.Remaining
+	}
+	if len(summary.Grants) > 0 {
+		summary.NextExpiresAt = summary.Grants[0].ExpiresAt
+	}
+	return summary, nil
+}
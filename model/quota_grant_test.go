@@ -0,0 +1,116 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestDrainQuotaGrants_OldestExpiryFirst(t *testing.T) {
+	truncateTables(t)
+	now := common.GetTimestamp()
+	require.NoError(t, DB.Create(&User{Id: 61, Username: "grant_drain", Status: common.UserStatusEnabled, Quota: 1000}).Error)
+	require.NoError(t, createQuotaGrant(DB, 61, QuotaGrantSourceCheckin, 1, 300, now+200))
+	require.NoError(t, createQuotaGrant(DB, 61, QuotaGrantSourceCheckin, 2, 300, now+100))
+
+	require.NoError(t, drainQuotaGrants(61, 400))
+
+	summary, err := GetUserQuotaGrantSummary(61)
+	require.NoError(t, err)
+	assert.Equal(t, 200, summary.ExpiringQuota)
+	assert.Equal(t, now+200, summary.NextExpiresAt)
+	require.Len(t, summary.Grants, 1)
+	assert.Equal(t, 1, summary.Grants[0].SourceId)
+}
+
+func TestExpireDueQuotaGrants(t *testing.T) {
+	truncateTables(t)
+	now := common.GetTimestamp()
+	require.NoError(t, DB.Create(&User{Id: 62, Username: "grant_expire", AffCode: "grant_expire", Status: common.UserStatusEnabled, Quota: 1000}).Error)
+	require.NoError(t, DB.Create(&User{Id: 63, Username: "grant_overdrawn", AffCode: "grant_overdrawn", Status: common.UserStatusEnabled, Quota: 50}).Error)
+	require.NoError(t, createQuotaGrant(DB, 62, QuotaGrantSourceCheckin, 1, 300, now-1))
+	require.NoError(t, createQuotaGrant(DB, 62, QuotaGrantSourceCheckin, 2, 300, now+3600))
+	require.NoError(t, createQuotaGrant(DB, 63, QuotaGrantSourceCheckin, 3, 300, now-1))
+
+	n, err := ExpireDueQuotaGrants(10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	var user User
+	require.NoError(t, DB.Select("quota").Where("id = ?", 62).First(&user).Error)
+	assert.Equal(t, 700, user.Quota)
+	require.NoError(t, DB.Select("quota").Where("id = ?", 63).First(&user).Error)
+	assert.Equal(t, 0, user.Quota)
+
+	require.NoError(t, RefreshQuotaGrantsActive())
+	assert.True(t, quotaGrantsActive.Load())
+}
+
+func TestUserCheckin_ExpiringRewardAndRevoke(t *testing.T) {
+	truncateTables(t)
+	enableCheckinForTest(t, 100, 100)
+	operation_setting.GetCheckinSetting().ExpireDays = 7
+	require.NoError(t, DB.Create(&User{Id: 64, Username: "grant_checkin", Status: common.UserStatusEnabled}).Error)
+
+	checkin, err := UserCheckin(64, "")
+	require.NoError(t, err)
+
+	summary, err := GetUserQuotaGrantSummary(64)
+	require.NoError(t, err)
+	assert.Equal(t, 100, summary.ExpiringQuota)
+
+	_, err = AdminRevokeCheckin(64, checkin.CheckinDate)
+	require.NoError(t, err)
+	summary, err = GetUserQuotaGrantSummary(64)
+	require.NoError(t, err)
+	assert.Equal(t, 0, summary.ExpiringQuota)
+}
+
+func TestAdminRevokeCheckin_AfterGrantExpired(t *testing.T) {
+	truncateTables(t)
+	enableCheckinForTest(t, 100, 100)
+	operation_setting.GetCheckinSetting().ExpireDays = 7
+	require.NoError(t, DB.Create(&User{Id: 65, Username: "grant_expired_revoke", Status: common.UserStatusEnabled, Quota: 1000}).Error)
+
+	checkin, err := UserCheckin(65, "")
+	require.NoError(t, err)
+	// spend 30 of the reward, then let the grant expire and take back the other 70
+	require.NoError(t, drainQuotaGrants(65, 30))
+	require.NoError(t, DB.Model(&User{}).Where("id = ?", 65).Update("quota", gorm.Expr("quota - ?", 30)).Error)
+	require.NoError(t, DB.Model(&QuotaGrant{}).Where("user_id = ?", 65).Update("expires_at", common.GetTimestamp()-1).Error)
+	_, err = ExpireDueQuotaGrants(10)
+	require.NoError(t, err)
+
+	var user User
+	require.NoError(t, DB.Select("quota").Where("id = ?", 65).First(&user).Error)
+	assert.Equal(t, 1000, user.Quota)
+
+	_, err = AdminRevokeCheckin(65, checkin.CheckinDate)
+	require.NoError(t, err)
+	require.NoError(t, DB.Select("quota").Where("id = ?", 65).First(&user).Error)
+	assert.Equal(t, 970, user.Quota, "only the spent part of the expired reward is taken back")
+}
+
+func TestGrantUserQuota(t *testing.T) {
+	truncateTables(t)
+	now := common.GetTimestamp()
+	require.NoError(t, DB.Create(&User{Id: 64, Username: "grant_admin", AffCode: "grant_admin", Status: common.UserStatusEnabled, Quota: 100}).Error)
+
+	require.NoError(t, GrantUserQuota(64, 500, 0))
+	require.NoError(t, GrantUserQuota(64, 300, now+3600))
+	assert.ErrorIs(t, GrantUserQuota(65, 300, 0), gorm.ErrRecordNotFound)
+	assert.Error(t, GrantUserQuota(64, 0, 0))
+
+	quota, err := GetUserQuota(64, true)
+	require.NoError(t, err)
+	assert.Equal(t, 900, quota)
+	summary, err := GetUserQuotaGrantSummary(64)
+	require.NoError(t, err)
+	require.Len(t, summary.Grants, 1)
+	assert.Equal(t, QuotaGrantSourceAdmin, summary.Grants[0].Source)
+	assert.Equal(t, 300, summary.Grants[0].Remaining)
+}
@@ -0,0 +1,186 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrQuotaTransferRecipientNotFound = errors.New("收款用户不存在或已被禁用")
+	ErrQuotaTransferToSelf            = errors.New("不能转账给自己")
+	ErrQuotaTransferDailyLimit        = errors.New("已超过今日转账限额")
+	ErrQuotaTransferDailyCount        = errors.New("已超过今日转账次数")
+	ErrQuotaTransferAmountTooSmall    = errors.New("转账额度扣除手续费后必须大于 0")
+)
+
+// QuotaTransfer 用户之间的额度转账记录，Amount 为转出方扣除的额度，
+// Fee 为其中的手续费，Received 为接收方实际到账的额度
+type QuotaTransfer struct {
+	Id         int    `json:"id"`
+	FromUserId int    `json:"from_user_id" gorm:"index"`
+	ToUserId   int    `json:"to_user_id" gorm:"index"`
+	Amount     int    `json:"amount"`
+	Fee        int    `json:"fee"`
+	Received   int    `json:"received"`
+	Message    string `json:"message" gorm:"type:varchar(255)"`
+	CreatedAt  int64  `json:"created_at" gorm:"bigint;index"`
+}
+
+func (QuotaTransfer) TableName() string {
+	return "quota_transfers"
+}
+
+// QuotaTransferFee 按配置的手续费百分比计算手续费，不足 1 的部分向上取整
+func QuotaTransferFee(amount int) int {
+	feePercent := operation_setting.GetQuotaTransferSetting().FeePercent
+	if feePercent <= 0 {
+		return 0
+	}
+	return int(decimal.NewFromInt(int64(amount)).
+		Mul(decimal.NewFromFloat(feePercent)).
+		Div(decimal.NewFromInt(100)).
+		Ceil().IntPart())
+}
+
+// TransferUserQuota 将 fromUserId 的 amount 额度转给用户名为 recipient 的用户，
+// 扣除手续费后到账；每日累计额度和次数按转出方、以服务器本地日期计算。
+// 转账成功后在双方各记一条系统日志，日志中带有转账记录 ID 便于对账
+func TransferUserQuota(fromUserId int, recipient string, amount int, message string) (*QuotaTransfer, error) {
+	if amount <= 0 {
+		return nil, errors.New("转账额度必须大于 0")
+	}
+	var toUser User
+	err := DB.Select("id", "username", "status").Where("username = ?", recipient).First(&toUser).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && toUser.Status != common.UserStatusEnabled) {
+		return nil, ErrQuotaTransferRecipientNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if toUser.Id == fromUserId {
+		return nil, ErrQuotaTransferToSelf
+	}
+	fee := QuotaTransferFee(amount)
+	transfer := &QuotaTransfer{
+		FromUserId: fromUserId,
+		ToUserId:   toUser.Id,
+		Amount:     amount,
+		Fee:        fee,
+		Received:   amount - fee,
+		Message:    message,
+		CreatedAt:  common.GetTimestamp(),
+	}
+	if transfer.Received <= 0 {
+		return nil, ErrQuotaTransferAmountTooSmall
+	}
+
+	// Redis 权威额度模式下额度以 Redis 为准，先原子扣减转出方，事务失败时退回
+	if common.RedisQuotaEnabled {
+		if err := adjustSyncedUserQuota(fromUserId, -amount, true); err != nil {
+			return nil, err
+		}
+	}
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		var sender User
+		if err := lockForUpdate(tx).Select("id").Where("id = ?", fromUserId).First(&sender).Error; err != nil {
+			return err
+		}
+		setting := operation_setting.GetQuotaTransferSetting()
+		if setting.DailyLimit > 0 || setting.DailyCount > 0 {
+			now := time.Now()
+			dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).Unix()
+			var today struct {
+				Count int64
+				Total int64
+			}
+			err := tx.Model(&QuotaTransfer{}).
+				Select("count(*) as count, COALESCE(sum(amount), 0) as total").
+				Where("from_user_id = ? AND created_at >= ?", fromUserId, dayStart).
+				Scan(&today).Error
+			if err != nil {
+				return err
+			}
+			if setting.DailyCount > 0 && today.Count >= int64(setting.DailyCount) {
+				return ErrQuotaTransferDailyCount
+			}
+			if setting.DailyLimit > 0 && today.Total+int64(amount) > int64(setting.DailyLimit) {
+				return ErrQuotaTransferDailyLimit
+			}
+		}
+		if !common.RedisQuotaEnabled {
+			result := tx.Model(&User{}).Where("id = ? AND quota >= ?", fromUserId, amount).
+				Update("quota", gorm.Expr("quota - ?", amount))
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrInsufficientUserQuota
+			}
+			if err := tx.Model(&User{}).Where("id = ?", toUser.Id).
+				Update("quota", gorm.Expr("quota + ?", transfer.Received)).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(transfer).Error
+	})
+	if err != nil {
+		if common.RedisQuotaEnabled {
+			if refundErr := adjustSyncedUserQuota(fromUserId, amount, false); refundErr != nil {
+				common.SysError(fmt.Sprintf("failed to refund quota transfer of user %d: %v", fromUserId, refundErr))
+			}
+		}
+		return nil, err
+	}
+
+	if common.RedisQuotaEnabled {
+		if err := adjustSyncedUserQuota(toUser.Id, transfer.Received, false); err != nil {
+			common.SysError(fmt.Sprintf("failed to credit quota transfer %d to user %d: %v", transfer.Id, toUser.Id, err))
+		}
+	} else {
+		gopool.Go(func() {
+			if err := cacheDecrUserQuota(fromUserId, int64(amount)); err != nil {
+				common.SysLog("failed to decrease user quota cache: " + err.Error())
+			}
+			if err := cacheIncrUserQuota(toUser.Id, int64(transfer.Received)); err != nil {
+				common.SysLog("failed to increase user quota cache: " + err.Error())
+			}
+		})
+	}
+	if quotaGrantsActive.Load() {
+		gopool.Go(func() {
+			if err := drainQuotaGrants(fromUserId, amount); err != nil {
+				common.SysLog("failed to drain quota grants: " + err.Error())
+			}
+		})
+	}
+
+	fromUsername, _ := GetUsernameById(fromUserId, false)
+	note := ""
+	if message != "" {
+		note = "，留言：" + message
+	}
+	RecordLog(fromUserId, LogTypeSystem, fmt.Sprintf("转账给用户 %s（ID %d）%s，手续费 %s，转账记录ID %d%s",
+		toUser.Username, toUser.Id, logger.LogQuota(amount), logger.LogQuota(fee), transfer.Id, note))
+	RecordLog(toUser.Id, LogTypeSystem, fmt.Sprintf("收到用户 %s（ID %d）转账 %s，转账记录ID %d%s",
+		fromUsername, fromUserId, logger.LogQuota(transfer.Received), transfer.Id, note))
+	return transfer, nil
+}
+
+// GetUserQuotaTransfers 分页查询用户转出和转入的转账记录
+func GetUserQuotaTransfers(userId int, startIdx int, num int) (transfers []*QuotaTransfer, total int64, err error) {
+	query := DB.Model(&QuotaTransfer{}).Where("from_user_id = ? OR to_user_id = ?", userId, userId)
+	if err = query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = query.Order("id desc").Limit(num).Offset(startIdx).Find(&transfers).Error
+	return transfers, total, err
+}
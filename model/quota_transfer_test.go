@@ -0,0 +1,101 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupQuotaTransferUsers(t *testing.T) {
+	t.Helper()
+	setting := operation_setting.GetQuotaTransferSetting()
+	overrideForTest(t, setting, *setting)
+	t.Cleanup(func() {
+		DB.Exec("DELETE FROM users")
+		DB.Exec("DELETE FROM quota_transfers")
+		DB.Exec("DELETE FROM logs")
+	})
+	require.NoError(t, DB.Create(&User{Id: 601, Username: "transfer_from", Password: "hashed", AffCode: "t601", Quota: 10000, Status: common.UserStatusEnabled}).Error)
+	require.NoError(t, DB.Create(&User{Id: 602, Username: "transfer_to", Password: "hashed", AffCode: "t602", Quota: 100, Status: common.UserStatusEnabled}).Error)
+	require.NoError(t, DB.Create(&User{Id: 603, Username: "transfer_banned", Password: "hashed", AffCode: "t603", Status: common.UserStatusDisabled}).Error)
+}
+
+func getTestUserQuota(t *testing.T, id int) int {
+	t.Helper()
+	var user User
+	require.NoError(t, DB.Select("quota").Where("id = ?", id).First(&user).Error)
+	return user.Quota
+}
+
+func TestTransferUserQuotaWithFee(t *testing.T) {
+	setupQuotaTransferUsers(t)
+	operation_setting.GetQuotaTransferSetting().FeePercent = 2.5
+
+	transfer, err := TransferUserQuota(601, "transfer_to", 1001, "thanks")
+	require.NoError(t, err)
+	assert.Equal(t, 26, transfer.Fee, "fee rounds up")
+	assert.Equal(t, 975, transfer.Received)
+	assert.Equal(t, 10000-1001, getTestUserQuota(t, 601))
+	assert.Equal(t, 100+975, getTestUserQuota(t, 602))
+
+	var logs []Log
+	require.NoError(t, DB.Where("type = ?", LogTypeSystem).Order("user_id").Find(&logs).Error)
+	require.Len(t, logs, 2)
+	assert.Equal(t, 601, logs[0].UserId)
+	assert.Equal(t, 602, logs[1].UserId)
+	for _, log := range logs {
+		assert.Contains(t, log.Content, "thanks")
+	}
+
+	transfers, total, err := GetUserQuotaTransfers(602, 0, 10)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+	require.Len(t, transfers, 1)
+	assert.Equal(t, transfer.Id, transfers[0].Id)
+}
+
+func TestTransferUserQuotaRejected(t *testing.T) {
+	setupQuotaTransferUsers(t)
+
+	tests := []struct {
+		name      string
+		recipient string
+		amount    int
+		wantErr   error
+	}{
+		{name: "insufficient quota", recipient: "transfer_to", amount: 10001, wantErr: ErrInsufficientUserQuota},
+		{name: "to self", recipient: "transfer_from", amount: 10, wantErr: ErrQuotaTransferToSelf},
+		{name: "disabled recipient", recipient: "transfer_banned", amount: 10, wantErr: ErrQuotaTransferRecipientNotFound},
+		{name: "unknown recipient", recipient: "nobody", amount: 10, wantErr: ErrQuotaTransferRecipientNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := TransferUserQuota(601, tt.recipient, tt.amount, "")
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+	assert.Equal(t, 10000, getTestUserQuota(t, 601))
+	assert.Equal(t, 100, getTestUserQuota(t, 602))
+}
+
+func TestTransferUserQuotaDailyLimits(t *testing.T) {
+	setupQuotaTransferUsers(t)
+	setting := operation_setting.GetQuotaTransferSetting()
+	setting.FeePercent = 0
+	setting.DailyLimit = 3000
+	setting.DailyCount = 2
+
+	_, err := TransferUserQuota(601, "transfer_to", 2000, "")
+	require.NoError(t, err)
+	_, err = TransferUserQuota(601, "transfer_to", 1001, "")
+	assert.ErrorIs(t, err, ErrQuotaTransferDailyLimit)
+	_, err = TransferUserQuota(601, "transfer_to", 1000, "")
+	require.NoError(t, err)
+	_, err = TransferUserQuota(601, "transfer_to", 1, "")
+	assert.ErrorIs(t, err, ErrQuotaTransferDailyCount)
+	assert.Equal(t, 7000, getTestUserQuota(t, 601))
+}
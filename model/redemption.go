@@ -23,9 +23,23 @@ type Redemption struct {
 	Count        int            `json:"count" gorm:"-:all"` // only for api request
 	UsedUserId   int            `json:"used_user_id"`
 	DeletedAt    gorm.DeletedAt `gorm:"index"`
-	ExpiredTime  int64          `json:"expired_time" gorm:"bigint"` // 过期时间，0 表示不过期
+	ExpiredTime  int64          `json:"expired_time" gorm:"bigint"`               // 过期时间，0 表示不过期
+	MaxUses      int            `json:"max_uses" gorm:"default:1"`                // 最多可兑换次数，每个用户只能兑换一次
+	UsedCount    int            `json:"used_count" gorm:"default:0"`              // 已兑换次数
+	Group        string         `json:"group" gorm:"type:varchar(64);default:''"` // 限定可兑换的用户分组，空表示不限
+	Prefix       string         `json:"prefix" gorm:"-:all"`                      // only for api request
 }
 
+// RedemptionUse 兑换记录，(redemption_id, user_id) 唯一，防止同一用户重复兑换多次可用的兑换码
+type RedemptionUse struct {
+	Id           int   `json:"id"`
+	RedemptionId int   `json:"redemption_id" gorm:"uniqueIndex:idx_redemption_use_user"`
+	UserId       int   `json:"user_id" gorm:"uniqueIndex:idx_redemption_use_user;index"`
+	CreatedTime  int64 `json:"created_time" gorm:"bigint"`
+}
+
+const redemptionExportBatchSize = 500
+
 func GetAllRedemptions(startIdx int, num int) (redemptions []*Redemption, total int64, err error) {
 	// 开始事务
 	tx := DB.Begin()
@@ -71,8 +85,31 @@ func SearchRedemptions(keyword string, status string, startIdx int, num int) (re
 		}
 	}()
 
-	query := tx.Model(&Redemption{})
+	query := applyRedemptionFilters(tx.Model(&Redemption{}), keyword, status)
+
+	// Get total count
+	err = query.Count(&total).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, 0, err
+	}
 
+	// Get paginated data
+	err = query.Order("id desc").Limit(num).Offset(startIdx).Find(&redemptions).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, 0, err
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		return nil, 0, err
+	}
+
+	return redemptions, total, nil
+}
+
+// applyRedemptionFilters 按名称前缀/ID 和状态过滤兑换码，搜索和导出共用
+func applyRedemptionFilters(query *gorm.DB, keyword string, status string) *gorm.DB {
 	if keyword != "" {
 		if id, err := strconv.Atoi(keyword); err == nil {
 			query = query.Where("id = ? OR name LIKE ?", id, keyword+"%")
@@ -86,7 +123,8 @@ func SearchRedemptions(keyword string, status string, startIdx int, num int) (re
 		switch status {
 		case "expired":
 			query = query.Where(
-				"status = ? AND expired_time != 0 AND expired_time < ?",
+				"(status = ? OR (status = ? AND expired_time != 0 AND expired_time < ?))",
+				common.RedemptionCodeStatusExpired,
 				common.RedemptionCodeStatusEnabled,
 				now,
 			)
@@ -102,26 +140,37 @@ func SearchRedemptions(keyword string, status string, startIdx int, num int) (re
 			query = query.Where("status = ?", common.RedemptionCodeStatusUsed)
 		}
 	}
+	return query
+}
 
-	// Get total count
-	err = query.Count(&total).Error
-	if err != nil {
-		tx.Rollback()
-		return nil, 0, err
-	}
-
-	// Get paginated data
-	err = query.Order("id desc").Limit(num).Offset(startIdx).Find(&redemptions).Error
-	if err != nil {
-		tx.Rollback()
-		return nil, 0, err
-	}
-
-	if err = tx.Commit().Error; err != nil {
-		return nil, 0, err
+// ExportRedemptions 按 id 升序分批读取符合条件的兑换码，逐批交给 fn 处理
+func ExportRedemptions(keyword string, status string, fn func(batch []Redemption) error) error {
+	lastId := 0
+	for {
+		var batch []Redemption
+		err := applyRedemptionFilters(DB.Where("id > ?", lastId), keyword, status).
+			Order("id asc").Limit(redemptionExportBatchSize).Find(&batch).Error
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := fn(batch); err != nil {
+			return err
+		}
+		if len(batch) < redemptionExportBatchSize {
+			return nil
+		}
+		lastId = batch[len(batch)-1].Id
 	}
+}
 
-	return redemptions, total, nil
+// CreateRedemptions 在同一事务中批量写入兑换码，任一失败则全部回滚
+func CreateRedemptions(redemptions []*Redemption) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		return tx.CreateInBatches(redemptions, 100).Error
+	})
 }
 
 func GetRedemptionById(id int) (*Redemption, error) {
@@ -142,6 +191,11 @@ func Redeem(key string, userId int) (quota int, err error) {
 		return 0, errors.New("无效的 user id")
 	}
 	redemption := &Redemption{}
+	userGroup, err := GetUserGroup(userId, false)
+	if err != nil {
+		common.SysError("redemption failed: " + err.Error())
+		return 0, ErrRedeemFailed
+	}
 
 	keyCol := "`key`"
 	if common.UsingMainDatabase(common.DatabaseTypePostgreSQL) {
@@ -159,28 +213,41 @@ func Redeem(key string, userId int) (quota int, err error) {
 		if redemption.ExpiredTime != 0 && redemption.ExpiredTime < common.GetTimestamp() {
 			return errors.New("该兑换码已过期")
 		}
-		// Compare-and-swap on status: only the transaction that flips
-		// enabled -> used may credit quota, so a concurrent redeem of the
-		// same code loses here even without a row lock (e.g. on SQLite).
+		if redemption.Group != "" && redemption.Group != userGroup {
+			return errors.New("该兑换码不适用于当前用户分组")
+		}
+		maxUses := max(redemption.MaxUses, 1)
+		updates := map[string]interface{}{
+			"redeemed_time": common.GetTimestamp(),
+			"used_user_id":  userId,
+			"used_count":    redemption.UsedCount + 1,
+		}
+		if redemption.UsedCount+1 >= maxUses {
+			updates["status"] = common.RedemptionCodeStatusUsed
+		}
+		// Compare-and-swap on status and used_count: only one transaction may
+		// consume each use of the code, so a concurrent redeem of the same
+		// code loses here even without a row lock (e.g. on SQLite).
 		result := tx.Model(&Redemption{}).
-			Where("id = ? AND status = ?", redemption.Id, common.RedemptionCodeStatusEnabled).
-			Updates(map[string]interface{}{
-				"redeemed_time": common.GetTimestamp(),
-				"status":        common.RedemptionCodeStatusUsed,
-				"used_user_id":  userId,
-			})
+			Where("id = ? AND status = ? AND used_count = ?", redemption.Id, common.RedemptionCodeStatusEnabled, redemption.UsedCount).
+			Updates(updates)
 		if result.Error != nil {
 			return result.Error
 		}
 		if result.RowsAffected == 0 {
 			return errors.New("该兑换码已被使用")
 		}
+		// 唯一索引保证同一用户对同一兑换码只能兑换一次
+		if err := tx.Create(&RedemptionUse{RedemptionId: redemption.Id, UserId: userId, CreatedTime: common.GetTimestamp()}).Error; err != nil {
+			return errors.New("已兑换过该兑换码")
+		}
 		return tx.Model(&User{}).Where("id = ?", userId).Update("quota", gorm.Expr("quota + ?", redemption.Quota)).Error
 	})
 	if err != nil {
 		common.SysError("redemption failed: " + err.Error())
 		return 0, ErrRedeemFailed
 	}
+	invalidateUserQuotaAfterCommit(userId)
 	RecordLog(userId, LogTypeTopup, fmt.Sprintf("通过兑换码充值 %s，兑换码ID %d", logger.LogQuota(redemption.Quota), redemption.Id))
 	return redemption.Quota, nil
 }
@@ -199,7 +266,7 @@ func (redemption *Redemption) SelectUpdate() error {
 // Update Make sure your token's fields is completed, because this will update non-zero values
 func (redemption *Redemption) Update() error {
 	var err error
-	err = DB.Model(redemption).Select("name", "status", "quota", "redeemed_time", "expired_time").Updates(redemption).Error
+	err = DB.Model(redemption).Select("name", "status", "quota", "redeemed_time", "expired_time", "max_uses", "group").Updates(redemption).Error
 	return err
 }
 
@@ -223,6 +290,21 @@ func DeleteRedemptionById(id int) (err error) {
 
 func DeleteInvalidRedemptions() (int64, error) {
 	now := common.GetTimestamp()
-	result := DB.Where("status IN ? OR (status = ? AND expired_time != 0 AND expired_time < ?)", []int{common.RedemptionCodeStatusUsed, common.RedemptionCodeStatusDisabled}, common.RedemptionCodeStatusEnabled, now).Delete(&Redemption{})
+	result := DB.Where("status IN ? OR (status = ? AND expired_time != 0 AND expired_time < ?)", []int{common.RedemptionCodeStatusUsed, common.RedemptionCodeStatusDisabled, common.RedemptionCodeStatusExpired}, common.RedemptionCodeStatusEnabled, now).Delete(&Redemption{})
+	return result.RowsAffected, result.Error
+}
+
+// ExpireRedemptions 将已过期但仍为启用状态的兑换码标记为已过期，每次最多处理 limit 条
+func ExpireRedemptions(now int64, limit int) (int64, error) {
+	var ids []int
+	err := DB.Model(&Redemption{}).
+		Where("status = ? AND expired_time != 0 AND expired_time < ?", common.RedemptionCodeStatusEnabled, now).
+		Order("id").Limit(limit).Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	result := DB.Model(&Redemption{}).
+		Where("id IN ? AND status = ?", ids, common.RedemptionCodeStatusEnabled).
+		Update("status", common.RedemptionCodeStatusExpired)
 	return result.RowsAffected, result.Error
 }
@@ -106,6 +106,7 @@ func setupRedeemFixture(t *testing.T, quota int) (userId int, key string) {
 	require.NoError(t, DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&Redemption{}).Error)
 	t.Cleanup(func() {
 		require.NoError(t, DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&Redemption{}).Error)
+		DB.Exec("DELETE FROM redemption_uses")
 		DB.Exec("DELETE FROM users")
 		DB.Exec("DELETE FROM logs")
 	})
@@ -148,6 +149,21 @@ func TestRedeemCreditsQuotaExactlyOnce(t *testing.T) {
 	assert.Equal(t, 500, user.Quota)
 }
 
+// With REDIS_QUOTA_ENABLED the authoritative quota loaded before the redeem
+// must be dropped, so the next read reloads the credited DB quota.
+func TestRedeemInvalidatesAuthoritativeQuota(t *testing.T) {
+	stub := useMiniRedis(t)
+	overrideForTest(t, &common.RedisQuotaEnabled, true)
+	userId, key := setupRedeemFixture(t, 500)
+	require.NoError(t, stub.Set(getUserQuotaSyncKey(userId), "0"))
+
+	_, err := Redeem(key, userId)
+	require.NoError(t, err)
+
+	assert.False(t, stub.Exists(getUserQuotaSyncKey(userId)))
+	assert.False(t, stub.Exists(getUserCacheKey(userId)))
+}
+
 // Exactly one of several concurrent redeems of the same code may win, and
 // quota must be credited exactly once.
 func TestRedeemConcurrentSingleSuccess(t *testing.T) {
@@ -179,3 +195,78 @@ func TestRedeemConcurrentSingleSuccess(t *testing.T) {
 	require.NoError(t, DB.First(&user, "id = ?", userId).Error)
 	assert.Equal(t, 300, user.Quota, "quota must be credited exactly once")
 }
+
+func TestRedeemMultiUseCodeWithGroupRestriction(t *testing.T) {
+	userId, key := setupRedeemFixture(t, 100)
+	require.NoError(t, DB.Model(&Redemption{}).Where("name = ?", "redeem-test").
+		Updates(map[string]interface{}{"max_uses": 2, "group": "vip"}).Error)
+	require.NoError(t, DB.Model(&User{}).Where("id = ?", userId).Update("group", "vip").Error)
+	vip2 := &User{Username: "redeem-vip2", Password: "password", Status: common.UserStatusEnabled, Group: "vip", AffCode: "vip2"}
+	vip3 := &User{Username: "redeem-vip3", Password: "password", Status: common.UserStatusEnabled, Group: "vip", AffCode: "vip3"}
+	other := &User{Username: "redeem-default", Password: "password", Status: common.UserStatusEnabled, Group: "default", AffCode: "default"}
+	for _, user := range []*User{vip2, vip3, other} {
+		require.NoError(t, DB.Create(user).Error)
+	}
+
+	steps := []struct {
+		name       string
+		userId     int
+		wantErr    bool
+		wantStatus int
+		wantUsed   int
+	}{
+		{name: "user outside the group is rejected", userId: other.Id, wantErr: true, wantStatus: common.RedemptionCodeStatusEnabled, wantUsed: 0},
+		{name: "first use keeps the code enabled", userId: userId, wantStatus: common.RedemptionCodeStatusEnabled, wantUsed: 1},
+		{name: "same user cannot redeem twice", userId: userId, wantErr: true, wantStatus: common.RedemptionCodeStatusEnabled, wantUsed: 1},
+		{name: "last use marks the code used", userId: vip2.Id, wantStatus: common.RedemptionCodeStatusUsed, wantUsed: 2},
+		{name: "exhausted code is rejected", userId: vip3.Id, wantErr: true, wantStatus: common.RedemptionCodeStatusUsed, wantUsed: 2},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			_, err := Redeem(key, step.userId)
+			if step.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			var redemption Redemption
+			require.NoError(t, DB.First(&redemption, "name = ?", "redeem-test").Error)
+			assert.Equal(t, step.wantStatus, redemption.Status)
+			assert.Equal(t, step.wantUsed, redemption.UsedCount)
+		})
+	}
+
+	var quotas []int
+	require.NoError(t, DB.Model(&User{}).Order("id").Pluck("quota", &quotas).Error)
+	assert.Equal(t, []int{100, 100, 0, 0}, quotas)
+}
+
+func TestExpireRedemptionsMarksOnlyEnabledExpiredCodes(t *testing.T) {
+	truncateTables(t)
+	now := common.GetTimestamp()
+	redemptions := []Redemption{
+		{Id: 1, Key: "20000000000000000000000000000001", Status: common.RedemptionCodeStatusEnabled, ExpiredTime: now - 10},
+		{Id: 2, Key: "20000000000000000000000000000002", Status: common.RedemptionCodeStatusEnabled, ExpiredTime: now + 3600},
+		{Id: 3, Key: "20000000000000000000000000000003", Status: common.RedemptionCodeStatusEnabled},
+		{Id: 4, Key: "20000000000000000000000000000004", Status: common.RedemptionCodeStatusUsed, ExpiredTime: now - 10},
+	}
+	require.NoError(t, DB.Create(&redemptions).Error)
+
+	expired, err := ExpireRedemptions(now, 10)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, expired)
+
+	var statuses []int
+	require.NoError(t, DB.Model(&Redemption{}).Order("id").Pluck("status", &statuses).Error)
+	assert.Equal(t, []int{
+		common.RedemptionCodeStatusExpired,
+		common.RedemptionCodeStatusEnabled,
+		common.RedemptionCodeStatusEnabled,
+		common.RedemptionCodeStatusUsed,
+	}, statuses)
+
+	rows, total, err := SearchRedemptions("", "expired", 0, 10)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, total)
+	assert.Equal(t, 1, rows[0].Id)
+}
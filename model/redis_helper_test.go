@@ -0,0 +1,50 @@
+package model
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+var (
+	miniRedisOnce     sync.Once
+	miniRedisInstance *miniredis.Miniredis
+)
+
+// overrideForTest sets *target to value for the duration of the test.
+func overrideForTest[T any](t *testing.T, target *T, value T) {
+	t.Helper()
+	original := *target
+	*target = value
+	t.Cleanup(func() {
+		*target = original
+	})
+}
+
+// useMiniRedis points common.RDB at a shared miniredis with empty data for the
+// duration of the test. The server itself lives until the test binary exits,
+// since the cache invalidation listener keeps its subscription open.
+func useMiniRedis(t *testing.T) *miniredis.Miniredis {
+	t.Helper()
+	miniRedisOnce.Do(func() {
+		server, err := miniredis.Run()
+		if err != nil {
+			t.Fatalf("failed to start miniredis: %v", err)
+		}
+		miniRedisInstance = server
+	})
+	server := miniRedisInstance
+	server.FlushAll()
+
+	// RDB is left pointing at miniredis afterwards: cache writes started in
+	// goroutines may still run after the test and must not hit a nil client.
+	common.RDB = redis.NewClient(&redis.Options{Addr: server.Addr()})
+	overrideForTest(t, &common.RedisEnabled, true)
+	// cached keys get a TTL of SyncFrequency; field updates skip keys without one
+	overrideForTest(t, &common.SyncFrequency, 60)
+	return server
+}
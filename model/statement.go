@@ -0,0 +1,264 @@
+package model
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// StatementPeriodLayout 账单周期格式，按服务器时区的自然月划分
+const StatementPeriodLayout = "2006-01"
+
+// StatementModelUsage 账单中单个模型的用量
+type StatementModelUsage struct {
+	ModelName        string `json:"model_name"`
+	Requests         int64  `json:"requests"`
+	PromptTokens     int64  `json:"prompt_tokens"`
+	CompletionTokens int64  `json:"completion_tokens"`
+	Quota            int64  `json:"quota"`
+}
+
+type StatementModelUsages []StatementModelUsage
+
+// Value implements driver.Valuer interface
+func (u StatementModelUsages) Value() (driver.Value, error) {
+	return common.Marshal(u)
+}
+
+// Scan implements sql.Scanner interface
+func (u *StatementModelUsages) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return common.Unmarshal(v, u)
+	case string:
+		return common.UnmarshalJsonStr(v, u)
+	}
+	return nil
+}
+
+// UserStatement 用户月度账单，生成后持久化，重新生成同一周期时覆盖原记录。
+// 用量来自消费/退款日志，因此需要开启消费日志记录（LogConsumeEnabled）
+type UserStatement struct {
+	Id               int                  `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserId           int                  `json:"user_id" gorm:"uniqueIndex:idx_user_statement_period,priority:1"`
+	Period           string               `json:"period" gorm:"type:varchar(7);uniqueIndex:idx_user_statement_period,priority:2;index"`
+	Username         string               `json:"username" gorm:"type:varchar(64);default:''"`
+	StartTime        int64                `json:"start_time" gorm:"bigint"`
+	EndTime          int64                `json:"end_time" gorm:"bigint"` // 不含
+	Requests         int64                `json:"requests"`
+	PromptTokens     int64                `json:"prompt_tokens"`
+	CompletionTokens int64                `json:"completion_tokens"`
+	QuotaConsumed    int64                `json:"quota_consumed"`
+	QuotaRefunded    int64                `json:"quota_refunded"`
+	TopUpCount       int64                `json:"topup_count"`
+	TopUpAmount      int64                `json:"topup_amount"`
+	TopUpMoney       float64              `json:"topup_money"`
+	CheckinCount     int64                `json:"checkin_count"`
+	CheckinQuota     int64                `json:"checkin_quota"`
+	Models           StatementModelUsages `json:"models" gorm:"type:text"`
+	CreatedAt        int64                `json:"created_at" gorm:"bigint"`
+}
+
+func (UserStatement) TableName() string {
+	return "user_statements"
+}
+
+// StatementPeriodRange 返回账单周期 [start, end) 的时间戳
+func StatementPeriodRange(period string) (int64, int64, error) {
+	start, err := time.ParseInLocation(StatementPeriodLayout, period, time.Local)
+	if err != nil {
+		return 0, 0, errors.New("账单周期格式应为 YYYY-MM")
+	}
+	return start.Unix(), start.AddDate(0, 1, 0).Unix(), nil
+}
+
+// GenerateUserStatement 汇总用户在账单周期内的用量、充值和签到奖励并保存
+func GenerateUserStatement(userId int, period string) (*UserStatement, error) {
+	start, end, err := StatementPeriodRange(period)
+	if err != nil {
+		return nil, err
+	}
+	statement := &UserStatement{
+		UserId:    userId,
+		Period:    period,
+		StartTime: start,
+		EndTime:   end,
+		Models:    StatementModelUsages{},
+		CreatedAt: common.GetTimestamp(),
+	}
+	statement.Username, _ = GetUsernameById(userId, false)
+
+	var models []StatementModelUsage
+	err = LOG_DB.Model(&Log{}).
+		Select("model_name, count(*) as requests, sum(prompt_tokens) as prompt_tokens, sum(completion_tokens) as completion_tokens, sum(quota) as quota").
+		Where("user_id = ? AND type = ? AND created_at >= ? AND created_at < ?", userId, LogTypeConsume, start, end).
+		Group("model_name").
+		Order("quota DESC").
+		Scan(&models).Error
+	if err != nil {
+		return nil, err
+	}
+	statement.Models = append(statement.Models, models...)
+	for _, usage := range statement.Models {
+		statement.Requests += usage.Requests
+		statement.PromptTokens += usage.PromptTokens
+		statement.CompletionTokens += usage.CompletionTokens
+		statement.QuotaConsumed += usage.Quota
+	}
+	err = LOG_DB.Model(&Log{}).
+		Select("coalesce(sum(quota), 0)").
+		Where("user_id = ? AND type = ? AND created_at >= ? AND created_at < ?", userId, LogTypeRefund, start, end).
+		Scan(&statement.QuotaRefunded).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var topUps struct {
+		Count  int64
+		Amount int64
+		Money  float64
+	}
+	err = DB.Model(&TopUp{}).
+		Select("count(*) as count, coalesce(sum(amount), 0) as amount, coalesce(sum(money), 0) as money").
+		Where("user_id = ? AND status IN ? AND complete_time >= ? AND complete_time < ?",
+			userId, []string{common.TopUpStatusSuccess, common.TopUpStatusRefunded}, start, end).
+		Scan(&topUps).Error
+	if err != nil {
+		return nil, err
+	}
+	statement.TopUpCount, statement.TopUpAmount, statement.TopUpMoney = topUps.Count, topUps.Amount, topUps.Money
+
+	var checkins struct {
+		Count int64
+		Quota int64
+	}
+	// 签到日期按签到时区记录，这里按日期字符串落在该月内统计
+	err = DB.Model(&Checkin{}).
+		Select("count(*) as count, coalesce(sum(quota_awarded), 0) as quota").
+		Where("user_id = ? AND checkin_date >= ? AND checkin_date < ?", userId, period+"-01", period+"-32").
+		Scan(&checkins).Error
+	if err != nil {
+		return nil, err
+	}
+	statement.CheckinCount, statement.CheckinQuota = checkins.Count, checkins.Quota
+
+	err = DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "period"}},
+		DoUpdates: clause.AssignmentColumns([]string{"username", "start_time", "end_time", "requests", "prompt_tokens", "completion_tokens", "quota_consumed", "quota_refunded", "top_up_count", "top_up_amount", "top_up_money", "checkin_count", "checkin_quota", "models", "created_at"}),
+	}).Create(statement).Error
+	if err != nil {
+		return nil, err
+	}
+	// 冲突更新时 Create 不会回填原记录的 id
+	if err := DB.Select("id").Where("user_id = ? AND period = ?", userId, period).First(statement).Error; err != nil {
+		return nil, err
+	}
+	return statement, nil
+}
+
+// GetStatementUserIds 返回账单周期内有消费、充值或签到记录的用户
+func GetStatementUserIds(period string) ([]int, error) {
+	start, end, err := StatementPeriodRange(period)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[int]bool)
+	userIds := make([]int, 0)
+	collect := func(ids []int) {
+		for _, id := range ids {
+			if id > 0 && !seen[id] {
+				seen[id] = true
+				userIds = append(userIds, id)
+			}
+		}
+	}
+	var ids []int
+	if err := LOG_DB.Model(&Log{}).Distinct("user_id").
+		Where("type IN ? AND created_at >= ? AND created_at < ?", []int{LogTypeConsume, LogTypeRefund}, start, end).
+		Pluck("user_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	collect(ids)
+	ids = nil
+	if err := DB.Model(&TopUp{}).Distinct("user_id").
+		Where("status IN ? AND complete_time >= ? AND complete_time < ?", []string{common.TopUpStatusSuccess, common.TopUpStatusRefunded}, start, end).
+		Pluck("user_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	collect(ids)
+	ids = nil
+	if err := DB.Model(&Checkin{}).Distinct("user_id").
+		Where("checkin_date >= ? AND checkin_date < ?", period+"-01", period+"-32").
+		Pluck("user_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	collect(ids)
+	return userIds, nil
+}
+
+// GenerateMissingStatements 为账单周期内有活动但尚未生成账单的用户生成账单，返回生成数量
+func GenerateMissingStatements(period string) (int, error) {
+	userIds, err := GetStatementUserIds(period)
+	if err != nil {
+		return 0, err
+	}
+	var existing []int
+	if err := DB.Model(&UserStatement{}).Where("period = ?", period).Pluck("user_id", &existing).Error; err != nil {
+		return 0, err
+	}
+	generated := make(map[int]bool, len(existing))
+	for _, userId := range existing {
+		generated[userId] = true
+	}
+	count := 0
+	for _, userId := range userIds {
+		if generated[userId] {
+			continue
+		}
+		if _, err := GenerateUserStatement(userId, period); err != nil {
+			return count, fmt.Errorf("failed to generate statement for user %d: %w", userId, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// GetUserStatements 分页获取用户的账单，按周期倒序，userId 为 0 时返回全部用户
+func GetUserStatements(userId int, period string, pageInfo *common.PageInfo) ([]*UserStatement, int64, error) {
+	var statements []*UserStatement
+	var total int64
+	query := DB.Model(&UserStatement{})
+	if userId != 0 {
+		query = query.Where("user_id = ?", userId)
+	}
+	if period != "" {
+		query = query.Where("period = ?", period)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("period desc").Order("id desc").Limit(pageInfo.GetPageSize()).Offset(pageInfo.GetStartIdx()).Find(&statements).Error
+	return statements, total, err
+}
+
+// GetUserStatementById 获取账单，userId 不为 0 时只返回该用户的账单
+func GetUserStatementById(id int, userId int) (*UserStatement, error) {
+	statement := &UserStatement{}
+	query := DB.Where("id = ?", id)
+	if userId != 0 {
+		query = query.Where("user_id = ?", userId)
+	}
+	if err := query.First(statement).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("账单不存在")
+		}
+		return nil, err
+	}
+	return statement, nil
+}
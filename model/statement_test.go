@@ -0,0 +1,112 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateUserStatement(t *testing.T) {
+	t.Cleanup(func() {
+		DB.Exec("DELETE FROM user_statements")
+		DB.Exec("DELETE FROM logs")
+		DB.Exec("DELETE FROM top_ups")
+		DB.Exec("DELETE FROM checkins")
+	})
+	const userId = 7001
+	start, end, err := StatementPeriodRange("2026-03")
+	require.NoError(t, err)
+	inPeriod := start + 3600
+	require.Equal(t, time.Unix(end, 0).Format(StatementPeriodLayout), "2026-04")
+
+	logs := []Log{
+		{UserId: userId, Type: LogTypeConsume, ModelName: "gpt-4o", PromptTokens: 100, CompletionTokens: 20, Quota: 500, CreatedAt: inPeriod},
+		{UserId: userId, Type: LogTypeConsume, ModelName: "gpt-4o", PromptTokens: 50, CompletionTokens: 10, Quota: 300, CreatedAt: inPeriod},
+		{UserId: userId, Type: LogTypeConsume, ModelName: "claude-3", PromptTokens: 10, CompletionTokens: 5, Quota: 100, CreatedAt: inPeriod},
+		{UserId: userId, Type: LogTypeRefund, ModelName: "gpt-4o", Quota: 40, CreatedAt: inPeriod},
+		// outside the period or another user
+		{UserId: userId, Type: LogTypeConsume, ModelName: "gpt-4o", Quota: 999, CreatedAt: end},
+		{UserId: userId + 1, Type: LogTypeConsume, ModelName: "gpt-4o", Quota: 999, CreatedAt: inPeriod},
+	}
+	require.NoError(t, LOG_DB.Create(&logs).Error)
+	topUps := []TopUp{
+		{UserId: userId, Amount: 10, Money: 7.5, TradeNo: "stmt-1", Status: common.TopUpStatusSuccess, CompleteTime: inPeriod},
+		{UserId: userId, Amount: 5, Money: 3, TradeNo: "stmt-2", Status: common.TopUpStatusPending, CompleteTime: inPeriod},
+	}
+	require.NoError(t, DB.Create(&topUps).Error)
+	checkins := []Checkin{
+		{UserId: userId, CheckinDate: "2026-03-01", QuotaAwarded: 20},
+		{UserId: userId, CheckinDate: "2026-03-31", QuotaAwarded: 30},
+		{UserId: userId, CheckinDate: "2026-04-01", QuotaAwarded: 99},
+	}
+	require.NoError(t, DB.Create(&checkins).Error)
+
+	statement, err := GenerateUserStatement(userId, "2026-03")
+	require.NoError(t, err)
+	assert.NotZero(t, statement.Id)
+	assert.EqualValues(t, 3, statement.Requests)
+	assert.EqualValues(t, 160, statement.PromptTokens)
+	assert.EqualValues(t, 35, statement.CompletionTokens)
+	assert.EqualValues(t, 900, statement.QuotaConsumed)
+	assert.EqualValues(t, 40, statement.QuotaRefunded)
+	assert.EqualValues(t, 1, statement.TopUpCount)
+	assert.EqualValues(t, 10, statement.TopUpAmount)
+	assert.InDelta(t, 7.5, statement.TopUpMoney, 0.001)
+	assert.EqualValues(t, 2, statement.CheckinCount)
+	assert.EqualValues(t, 50, statement.CheckinQuota)
+	assert.Equal(t, StatementModelUsages{
+		{ModelName: "gpt-4o", Requests: 2, PromptTokens: 150, CompletionTokens: 30, Quota: 800},
+		{ModelName: "claude-3", Requests: 1, PromptTokens: 10, CompletionTokens: 5, Quota: 100},
+	}, statement.Models)
+
+	// regenerating overwrites the same record
+	require.NoError(t, LOG_DB.Create(&Log{UserId: userId, Type: LogTypeConsume, ModelName: "claude-3", Quota: 100, CreatedAt: inPeriod}).Error)
+	regenerated, err := GenerateUserStatement(userId, "2026-03")
+	require.NoError(t, err)
+	assert.Equal(t, statement.Id, regenerated.Id)
+
+	stored, err := GetUserStatementById(statement.Id, userId)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1000, stored.QuotaConsumed)
+	assert.Len(t, stored.Models, 2)
+	_, err = GetUserStatementById(statement.Id, userId+1)
+	assert.Error(t, err, "statements of other users are not visible")
+
+	// only the other active user is still missing a statement
+	n, err := GenerateMissingStatements("2026-03")
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	statements, total, err := GetUserStatements(0, "2026-03", &common.PageInfo{Page: 1, PageSize: 10})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, total)
+	assert.Len(t, statements, 2)
+}
+
+func TestStatementPeriodRange(t *testing.T) {
+	tests := []struct {
+		period  string
+		wantErr bool
+	}{
+		{period: "2026-01"},
+		{period: "2026-12"},
+		{period: "2026-13", wantErr: true},
+		{period: "2026-1", wantErr: true},
+		{period: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.period, func(t *testing.T) {
+			start, end, err := StatementPeriodRange(tt.period)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.period, time.Unix(start, 0).Format(StatementPeriodLayout))
+			assert.Equal(t, time.Unix(start, 0).AddDate(0, 1, 0).Unix(), end)
+		})
+	}
+}
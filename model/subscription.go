@@ -185,6 +185,9 @@ type SubscriptionPlan struct {
 	QuotaResetPeriod        string `json:"quota_reset_period" gorm:"type:varchar(16);default:'never'"`
 	QuotaResetCustomSeconds int64  `json:"quota_reset_custom_seconds" gorm:"type:bigint;default:0"`
 
+	// Scale the quota of cycles shorter than a full reset period (calendar-aligned first cycle, cycle cut off by expiry)
+	ProrateCycles bool `json:"prorate_cycles"`
+
 	CreatedAt int64 `json:"created_at" gorm:"bigint"`
 	UpdatedAt int64 `json:"updated_at" gorm:"bigint"`
 }
@@ -258,6 +261,10 @@ type UserSubscription struct {
 	AmountTotal int64 `json:"amount_total" gorm:"type:bigint;not null;default:0"`
 	AmountUsed  int64 `json:"amount_used" gorm:"type:bigint;not null;default:0"`
 
+	// Full-cycle quota snapshot; AmountTotal is the (possibly prorated) quota of the current cycle
+	BaseAmount    int64 `json:"base_amount" gorm:"type:bigint;not null;default:0"`
+	ProrateCycles bool  `json:"prorate_cycles"`
+
 	StartTime int64  `json:"start_time" gorm:"bigint"`
 	EndTime   int64  `json:"end_time" gorm:"bigint;index;index:idx_user_sub_active,priority:3"`
 	Status    string `json:"status" gorm:"type:varchar(32);index;index:idx_user_sub_active,priority:2"` // active/expired/cancelled
@@ -538,6 +545,8 @@ func CreateUserSubscriptionFromPlanTx(tx *gorm.DB, userId int, plan *Subscriptio
 		PlanId:              plan.Id,
 		AmountTotal:         plan.TotalAmount,
 		AmountUsed:          0,
+		BaseAmount:          plan.TotalAmount,
+		ProrateCycles:       plan.ProrateCycles,
 		StartTime:           now.Unix(),
 		EndTime:             endUnix,
 		Status:              "active",
@@ -551,9 +560,13 @@ func CreateUserSubscriptionFromPlanTx(tx *gorm.DB, userId int, plan *Subscriptio
 		CreatedAt:           common.GetTimestamp(),
 		UpdatedAt:           common.GetTimestamp(),
 	}
+	prorated := startSubscriptionCycle(sub, plan)
 	if err := tx.Create(sub).Error; err != nil {
 		return nil, err
 	}
+	if err := recordSubscriptionGrantTx(tx, sub, SubscriptionGrantReasonInitial, 0, prorated); err != nil {
+		return nil, err
+	}
 	return sub, nil
 }
 
@@ -988,7 +1001,9 @@ func resetUserSubscriptionTx(tx *gorm.DB, sub *UserSubscription, plan *Subscript
 	if tx == nil || sub == nil || plan == nil {
 		return errors.New("invalid reset args")
 	}
+	prevAmountUsed := sub.AmountUsed
 	sub.AmountUsed = 0
+	prorated := false
 	if advanceResetTime {
 		nextReset := calcNextResetTime(time.Unix(now, 0), plan, sub.EndTime)
 		sub.NextResetTime = nextReset
@@ -997,8 +1012,12 @@ func resetUserSubscriptionTx(tx *gorm.DB, sub *UserSubscription, plan *Subscript
 		} else {
 			sub.LastResetTime = 0
 		}
+		prorated = startSubscriptionCycle(sub, plan)
 	}
-	return tx.Save(sub).Error
+	if err := tx.Save(sub).Error; err != nil {
+		return err
+	}
+	return recordSubscriptionGrantTx(tx, sub, SubscriptionGrantReasonAdminReset, prevAmountUsed, prorated)
 }
 
 func buildSubscriptionResetResult(plan *SubscriptionPlan, subs []UserSubscription, advanceResetTime bool) *SubscriptionResetResult {
@@ -1263,10 +1282,15 @@ func maybeResetUserSubscriptionWithPlanTx(tx *gorm.DB, sub *UserSubscription, pl
 		}
 		return nil
 	}
+	prevAmountUsed := sub.AmountUsed
 	sub.AmountUsed = 0
 	sub.LastResetTime = base.Unix()
 	sub.NextResetTime = next
-	return tx.Save(sub).Error
+	prorated := startSubscriptionCycle(sub, plan)
+	if err := tx.Save(sub).Error; err != nil {
+		return err
+	}
+	return recordSubscriptionGrantTx(tx, sub, SubscriptionGrantReasonCycle, prevAmountUsed, prorated)
 }
 
 // PreConsumeUserSubscription pre-consumes from any active subscription total quota.
@@ -0,0 +1,120 @@
+package model
+
+import (
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Subscription grant reasons
+const (
+	SubscriptionGrantReasonInitial    = "initial"
+	SubscriptionGrantReasonCycle      = "cycle"
+	SubscriptionGrantReasonAdminReset = "admin_reset"
+)
+
+// SubscriptionGrant records the quota granted to a user subscription for one
+// cycle: on purchase, on every scheduled reset and on admin resets.
+type SubscriptionGrant struct {
+	Id                 int    `json:"id"`
+	UserSubscriptionId int    `json:"user_subscription_id" gorm:"index"`
+	UserId             int    `json:"user_id" gorm:"index"`
+	PlanId             int    `json:"plan_id" gorm:"index"`
+	Reason             string `json:"reason" gorm:"type:varchar(32)"`
+	Amount             int64  `json:"amount" gorm:"type:bigint;not null;default:0"`
+	Prorated           bool   `json:"prorated"`
+	PrevAmountUsed     int64  `json:"prev_amount_used" gorm:"type:bigint;not null;default:0"`
+	CycleStart         int64  `json:"cycle_start" gorm:"bigint"`
+	CycleEnd           int64  `json:"cycle_end" gorm:"bigint"`
+	CreatedAt          int64  `json:"created_at" gorm:"bigint"`
+}
+
+// subscriptionResetPeriodSeconds returns the length of the full reset period
+// containing base, or 0 when the plan never resets.
+func subscriptionResetPeriodSeconds(base time.Time, plan *SubscriptionPlan) int64 {
+	switch NormalizeResetPeriod(plan.QuotaResetPeriod) {
+	case SubscriptionResetDaily:
+		return 24 * 3600
+	case SubscriptionResetWeekly:
+		return 7 * 24 * 3600
+	case SubscriptionResetMonthly:
+		monthStart := time.Date(base.Year(), base.Month(), 1, 0, 0, 0, 0, base.Location())
+		return monthStart.AddDate(0, 1, 0).Unix() - monthStart.Unix()
+	case SubscriptionResetCustom:
+		return plan.QuotaResetCustomSeconds
+	default:
+		return 0
+	}
+}
+
+// subscriptionCycleBounds returns the current cycle of the subscription.
+func subscriptionCycleBounds(sub *UserSubscription) (int64, int64) {
+	start := sub.LastResetTime
+	if start <= 0 {
+		start = sub.StartTime
+	}
+	end := sub.NextResetTime
+	if end <= 0 {
+		end = sub.EndTime
+	}
+	return start, end
+}
+
+// startSubscriptionCycle sets AmountTotal for the current cycle. Reset cycles
+// are calendar aligned, so the first cycle after purchase and the cycle cut off
+// by EndTime are usually shorter than a full period; when the subscription
+// prorates, those cycles get the full-cycle amount scaled by their length.
+// Returns whether the amount was prorated.
+func startSubscriptionCycle(sub *UserSubscription, plan *SubscriptionPlan) bool {
+	base := sub.BaseAmount
+	if base <= 0 {
+		// subscriptions created before BaseAmount existed keep their snapshot
+		base = sub.AmountTotal
+	}
+	sub.AmountTotal = base
+	if !sub.ProrateCycles || base <= 0 {
+		return false
+	}
+	start, end := subscriptionCycleBounds(sub)
+	period := subscriptionResetPeriodSeconds(time.Unix(start, 0), plan)
+	if period <= 0 || end <= start || end-start >= period {
+		return false
+	}
+	sub.AmountTotal = decimal.NewFromInt(base).
+		Mul(decimal.NewFromInt(end - start)).
+		Div(decimal.NewFromInt(period)).
+		IntPart()
+	return true
+}
+
+func recordSubscriptionGrantTx(tx *gorm.DB, sub *UserSubscription, reason string, prevAmountUsed int64, prorated bool) error {
+	start, end := subscriptionCycleBounds(sub)
+	return tx.Create(&SubscriptionGrant{
+		UserSubscriptionId: sub.Id,
+		UserId:             sub.UserId,
+		PlanId:             sub.PlanId,
+		Reason:             reason,
+		Amount:             sub.AmountTotal,
+		Prorated:           prorated,
+		PrevAmountUsed:     prevAmountUsed,
+		CycleStart:         start,
+		CycleEnd:           end,
+		CreatedAt:          common.GetTimestamp(),
+	}).Error
+}
+
+// GetSubscriptionGrants lists the grant history of a user subscription, newest
+// first. userId > 0 restricts the lookup to subscriptions owned by that user.
+func GetSubscriptionGrants(userSubscriptionId int, userId int, startIdx int, num int) (grants []*SubscriptionGrant, total int64, err error) {
+	query := DB.Model(&SubscriptionGrant{}).Where("user_subscription_id = ?", userSubscriptionId)
+	if userId > 0 {
+		query = query.Where("user_id = ?", userId)
+	}
+	if err = query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = query.Order("id desc").Limit(num).Offset(startIdx).Find(&grants).Error
+	return grants, total, err
+}
@@ -0,0 +1,127 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartSubscriptionCycleProration(t *testing.T) {
+	day := time.Date(2026, 2, 15, 0, 0, 0, 0, time.Local).Unix()
+	daily := &SubscriptionPlan{QuotaResetPeriod: SubscriptionResetDaily}
+	monthly := &SubscriptionPlan{QuotaResetPeriod: SubscriptionResetMonthly}
+	never := &SubscriptionPlan{QuotaResetPeriod: SubscriptionResetNever}
+
+	tests := []struct {
+		name         string
+		plan         *SubscriptionPlan
+		sub          UserSubscription
+		wantAmount   int64
+		wantProrated bool
+	}{
+		{
+			name:       "full cycle",
+			plan:       daily,
+			sub:        UserSubscription{BaseAmount: 2400, ProrateCycles: true, StartTime: day, LastResetTime: day, NextResetTime: day + 86400, EndTime: day + 10*86400},
+			wantAmount: 2400,
+		},
+		{
+			name:         "first cycle after purchase",
+			plan:         daily,
+			sub:          UserSubscription{BaseAmount: 2400, ProrateCycles: true, StartTime: day + 6*3600, LastResetTime: day + 6*3600, NextResetTime: day + 86400, EndTime: day + 10*86400},
+			wantAmount:   1800,
+			wantProrated: true,
+		},
+		{
+			name:         "last cycle cut off by expiry",
+			plan:         daily,
+			sub:          UserSubscription{BaseAmount: 2400, ProrateCycles: true, StartTime: day - 86400, LastResetTime: day, EndTime: day + 12*3600},
+			wantAmount:   1200,
+			wantProrated: true,
+		},
+		{
+			name:         "calendar month",
+			plan:         monthly,
+			sub:          UserSubscription{BaseAmount: 2800, ProrateCycles: true, StartTime: day, LastResetTime: day, NextResetTime: time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local).Unix(), EndTime: day + 60*86400},
+			wantAmount:   1400,
+			wantProrated: true,
+		},
+		{
+			name:       "proration disabled",
+			plan:       daily,
+			sub:        UserSubscription{BaseAmount: 2400, StartTime: day + 6*3600, LastResetTime: day + 6*3600, NextResetTime: day + 86400, EndTime: day + 10*86400},
+			wantAmount: 2400,
+		},
+		{
+			name:       "plan without resets",
+			plan:       never,
+			sub:        UserSubscription{BaseAmount: 2400, ProrateCycles: true, StartTime: day, EndTime: day + 3600},
+			wantAmount: 2400,
+		},
+		{
+			name:       "legacy subscription without base amount",
+			plan:       daily,
+			sub:        UserSubscription{AmountTotal: 500, StartTime: day + 6*3600, LastResetTime: day + 6*3600, NextResetTime: day + 86400},
+			wantAmount: 500,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := tt.sub
+			prorated := startSubscriptionCycle(&sub, tt.plan)
+			assert.Equal(t, tt.wantProrated, prorated)
+			assert.Equal(t, tt.wantAmount, sub.AmountTotal)
+		})
+	}
+}
+
+func TestResetDueSubscriptionsRecordsGrant(t *testing.T) {
+	truncateTables(t)
+
+	now := GetDBTimestamp()
+	plan := &SubscriptionPlan{
+		Id:                      9301,
+		Title:                   "Hourly",
+		DurationUnit:            SubscriptionDurationDay,
+		DurationValue:           1,
+		TotalAmount:             3600,
+		QuotaResetPeriod:        SubscriptionResetCustom,
+		QuotaResetCustomSeconds: 3600,
+		ProrateCycles:           true,
+	}
+	require.NoError(t, DB.Create(plan).Error)
+	// The previous cycle is due and the next one is cut off by expiry after half an hour.
+	sub := &UserSubscription{
+		Id: 9401, UserId: 301, PlanId: plan.Id,
+		AmountTotal: 3600, AmountUsed: 1000, BaseAmount: 3600, ProrateCycles: true,
+		StartTime: now - 3610, EndTime: now + 1790, Status: "active",
+		LastResetTime: now - 3610, NextResetTime: now - 10,
+	}
+	require.NoError(t, DB.Create(sub).Error)
+
+	count, err := ResetDueSubscriptions(10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	var reloaded UserSubscription
+	require.NoError(t, DB.First(&reloaded, sub.Id).Error)
+	assert.Zero(t, reloaded.AmountUsed)
+	assert.Zero(t, reloaded.NextResetTime)
+	assert.EqualValues(t, 1800, reloaded.AmountTotal)
+
+	grants, total, err := GetSubscriptionGrants(sub.Id, 301, 0, 10)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, total)
+	assert.Equal(t, SubscriptionGrantReasonCycle, grants[0].Reason)
+	assert.True(t, grants[0].Prorated)
+	assert.EqualValues(t, 1000, grants[0].PrevAmountUsed)
+	assert.EqualValues(t, 1800, grants[0].Amount)
+	assert.Equal(t, now-10, grants[0].CycleStart)
+	assert.Equal(t, now+1790, grants[0].CycleEnd)
+
+	_, total, err = GetSubscriptionGrants(sub.Id, 302, 0, 10)
+	require.NoError(t, err)
+	assert.Zero(t, total)
+}
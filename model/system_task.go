@@ -16,11 +16,21 @@ const (
 	SystemTaskStatusSucceeded SystemTaskStatus = "succeeded"
 	SystemTaskStatusFailed    SystemTaskStatus = "failed"
 
-	SystemTaskTypeLogCleanup     = "log_cleanup"
-	SystemTaskTypeChannelTest    = "channel_test"
-	SystemTaskTypeModelUpdate    = "model_update"
-	SystemTaskTypeMidjourneyPoll = "midjourney_poll"
-	SystemTaskTypeAsyncTaskPoll  = "async_task_poll"
+	SystemTaskTypeLogCleanup           = "log_cleanup"
+	SystemTaskTypeChannelTest          = "channel_test"
+	SystemTaskTypeModelUpdate          = "model_update"
+	SystemTaskTypeMidjourneyPoll       = "midjourney_poll"
+	SystemTaskTypeAsyncTaskPoll        = "async_task_poll"
+	SystemTaskTypeCheckinRemind        = "checkin_reminder"
+	SystemTaskTypeAuditCleanup         = "audit_log_cleanup"
+	SystemTaskTypeRedemptionExpire     = "redemption_expire"
+	SystemTaskTypeGroupUpgrade         = "group_upgrade"
+	SystemTaskTypeBatchPoll            = "batch_poll"
+	SystemTaskTypeLogRetention         = "log_retention"
+	SystemTaskTypeBodyLogCleanup       = "body_log_cleanup"
+	SystemTaskTypeModerationHitCleanup = "moderation_hit_cleanup"
+	SystemTaskTypeUserSessionCleanup   = "user_session_cleanup"
+	SystemTaskTypeMockLoadTest         = "mock_load_test"
 )
 
 var ErrSystemTaskLockLost = errors.New("system task lock lost")
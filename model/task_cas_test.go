@@ -49,11 +49,32 @@ func TestMain(m *testing.M) {
 		&SubscriptionPlan{},
 		&SubscriptionOrder{},
 		&UserSubscription{},
+		&SubscriptionGrant{},
 		&UserOAuthBinding{},
 		&PerfMetric{},
 		&SystemInstance{},
 		&SystemTask{},
 		&SystemTaskLock{},
+		&LeaderLease{},
+		&Checkin{},
+		&QuotaGrant{},
+		&CheckinPrizeStock{},
+		&CheckinMilestoneReward{},
+		&Redemption{},
+		&RedemptionUse{},
+		&AuditLog{},
+		&ChannelHealth{},
+		&WebhookEndpoint{},
+		&WebhookDelivery{},
+		&Midjourney{},
+		&UserStatement{},
+		&UserSession{},
+		&NotificationTemplate{},
+		&AccountDeletionRequest{},
+		&BodyLog{},
+		&QuotaTransfer{},
+		&PlaygroundSession{},
+		&PlaygroundMessage{},
 	); err != nil {
 		panic("failed to migrate: " + err.Error())
 	}
@@ -76,13 +97,26 @@ func truncateTables(t *testing.T) {
 		DB.Exec("DELETE FROM quota_data")
 		DB.Exec("DELETE FROM abilities")
 		DB.Exec("DELETE FROM top_ups")
+		DB.Exec("DELETE FROM redemption_uses")
 		DB.Exec("DELETE FROM subscription_orders")
 		DB.Exec("DELETE FROM subscription_plans")
 		DB.Exec("DELETE FROM user_subscriptions")
+		DB.Exec("DELETE FROM subscription_grants")
 		DB.Exec("DELETE FROM perf_metrics")
 		DB.Exec("DELETE FROM system_instances")
 		DB.Exec("DELETE FROM system_task_locks")
 		DB.Exec("DELETE FROM system_tasks")
+		DB.Exec("DELETE FROM leader_leases")
+		DB.Exec("DELETE FROM checkins")
+		DB.Exec("DELETE FROM quota_grants")
+		DB.Exec("DELETE FROM checkin_prize_stocks")
+		DB.Exec("DELETE FROM checkin_milestone_rewards")
+		DB.Exec("DELETE FROM redemptions")
+		DB.Exec("DELETE FROM audit_logs")
+		DB.Exec("DELETE FROM channel_health")
+		DB.Exec("DELETE FROM webhook_endpoints")
+		DB.Exec("DELETE FROM webhook_deliveries")
+		quotaGrantsActive.Store(false)
 	})
 }
 
@@ -8,27 +8,36 @@ import (
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/setting/operation_setting"
 	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/samber/hot"
 	"gorm.io/gorm"
 )
 
 type Token struct {
-	Id                 int            `json:"id"`
-	UserId             int            `json:"user_id" gorm:"index"`
-	Key                string         `json:"key" gorm:"type:varchar(128);uniqueIndex"`
-	Status             int            `json:"status" gorm:"default:1"`
-	Name               string         `json:"name" gorm:"index" `
-	CreatedTime        int64          `json:"created_time" gorm:"bigint"`
-	AccessedTime       int64          `json:"accessed_time" gorm:"bigint"`
-	ExpiredTime        int64          `json:"expired_time" gorm:"bigint;default:-1"` // -1 means never expired
-	RemainQuota        int            `json:"remain_quota" gorm:"default:0"`
-	UnlimitedQuota     bool           `json:"unlimited_quota"`
-	ModelLimitsEnabled bool           `json:"model_limits_enabled"`
-	ModelLimits        string         `json:"model_limits" gorm:"type:text"`
-	AllowIps           *string        `json:"allow_ips" gorm:"default:''"`
-	UsedQuota          int            `json:"used_quota" gorm:"default:0"` // used quota
-	Group              string         `json:"group" gorm:"default:''"`
-	CrossGroupRetry    bool           `json:"cross_group_retry"` // 跨分组重试，仅auto分组有效
-	DeletedAt          gorm.DeletedAt `gorm:"index"`
+	Id                 int     `json:"id"`
+	UserId             int     `json:"user_id" gorm:"index"`
+	Key                string  `json:"key" gorm:"type:varchar(128);uniqueIndex"`
+	Status             int     `json:"status" gorm:"default:1"`
+	Name               string  `json:"name" gorm:"index" `
+	CreatedTime        int64   `json:"created_time" gorm:"bigint"`
+	AccessedTime       int64   `json:"accessed_time" gorm:"bigint"`
+	ExpiredTime        int64   `json:"expired_time" gorm:"bigint;default:-1"` // -1 means never expired
+	RemainQuota        int     `json:"remain_quota" gorm:"default:0"`
+	UnlimitedQuota     bool    `json:"unlimited_quota"`
+	ModelLimitsEnabled bool    `json:"model_limits_enabled"`
+	ModelLimits        string  `json:"model_limits" gorm:"type:text"`
+	ModelMapping       string  `json:"model_mapping" gorm:"type:text"` // 令牌级模型别名，JSON 对象：请求模型 -> 实际模型
+	AllowIps           *string `json:"allow_ips" gorm:"default:''"`
+	UsedQuota          int     `json:"used_quota" gorm:"default:0"` // used quota
+	Group              string  `json:"group" gorm:"default:''"`
+	CrossGroupRetry    bool    `json:"cross_group_retry"`                // 跨分组重试，仅auto分组有效
+	RpmLimit           int     `json:"rpm_limit" gorm:"default:0"`       // 每分钟请求数上限，0 表示不限制
+	TpmLimit           int     `json:"tpm_limit" gorm:"default:0"`       // 每分钟 token 数上限，0 表示不限制
+	MaxConcurrency     int     `json:"max_concurrency" gorm:"default:0"` // 同时进行的流式请求数上限，0 表示不限制
+	BodyLogEnabled     bool    `json:"body_log_enabled"`                 // 记录该令牌的请求/响应体，需开启 body_log_setting.enabled
+	// PreviousKey 轮换前的旧密钥，在 PreviousKeyExpiresAt 之前仍可使用；不写入 Redis 缓存
+	PreviousKey          string         `json:"previous_key,omitempty" gorm:"type:varchar(128);index;default:''" redis:"-"`
+	PreviousKeyExpiresAt int64          `json:"previous_key_expires_at" gorm:"bigint;default:0"`
+	DeletedAt            gorm.DeletedAt `gorm:"index"`
 }
 
 func (token *Token) Clean() {
@@ -56,26 +65,40 @@ func (token *Token) GetMaskedKey() string {
 	return MaskTokenKey(token.Key)
 }
 
+// PreviousKeyActive 旧密钥是否仍处于轮换宽限期内
+func (token *Token) PreviousKeyActive() bool {
+	return token.PreviousKey != "" && token.PreviousKeyExpiresAt > common.GetTimestamp()
+}
+
+// GetIpLimits 返回令牌允许访问的 IP/CIDR 列表，条目以换行或逗号分隔
 func (token *Token) GetIpLimits() []string {
-	// delete empty spaces
-	//split with \n
-	ipLimits := make([]string, 0)
 	if token.AllowIps == nil {
-		return ipLimits
-	}
-	cleanIps := strings.ReplaceAll(*token.AllowIps, " ", "")
-	if cleanIps == "" {
-		return ipLimits
-	}
-	ips := strings.Split(cleanIps, "\n")
-	for _, ip := range ips {
-		ip = strings.TrimSpace(ip)
-		ip = strings.ReplaceAll(ip, ",", "")
-		if ip != "" {
-			ipLimits = append(ipLimits, ip)
-		}
+		return []string{}
+	}
+	return strings.FieldsFunc(*token.AllowIps, func(r rune) bool {
+		return r == '\n' || r == '\r' || r == ',' || r == ' ' || r == '\t'
+	})
+}
+
+// tokenIpAllowlistCache 按 AllowIps 原文缓存解析结果，避免每次请求重复解析 CIDR
+var tokenIpAllowlistCache = hot.NewHotCache[string, *common.IPAllowlist](hot.LRU, 10000).Build()
+
+// GetIpAllowlist 返回解析后的 IP 白名单，未配置时返回 nil。
+// 无法解析的条目被忽略，若全部无效则拒绝所有 IP。
+func (token *Token) GetIpAllowlist() *common.IPAllowlist {
+	if token.AllowIps == nil || *token.AllowIps == "" {
+		return nil
 	}
-	return ipLimits
+	raw := *token.AllowIps
+	if list, found, _ := tokenIpAllowlistCache.Get(raw); found {
+		return list
+	}
+	var list *common.IPAllowlist
+	if limits := token.GetIpLimits(); len(limits) > 0 {
+		list, _ = common.ParseIPAllowlist(limits)
+	}
+	tokenIpAllowlistCache.Set(raw, list)
+	return list
 }
 
 func GetAllUserTokens(userId int, startIdx int, num int) ([]*Token, error) {
@@ -260,6 +283,14 @@ func GetTokenById(id int) (*Token, error) {
 }
 
 func GetTokenByKey(key string, fromDB bool) (token *Token, err error) {
+	if newKey, ok := getTokenKeyAlias(key); ok {
+		// 宽限期内的旧密钥直接按新密钥查找；新密钥已失效时丢弃别名并走完整查询
+		aliased, aliasErr := GetTokenByKey(newKey, fromDB)
+		if aliasErr == nil && aliased.Key == newKey {
+			return aliased, nil
+		}
+		deleteTokenKeyAlias(key)
+	}
 	defer func() {
 		// Update Redis cache asynchronously on successful DB read
 		if shouldUpdateRedis(fromDB, err) && token != nil {
@@ -280,9 +311,57 @@ func GetTokenByKey(key string, fromDB bool) (token *Token, err error) {
 	}
 	fromDB = true
 	err = DB.Where(commonKeyCol+" = ?", key).First(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) && key != "" {
+		// 轮换宽限期内的旧密钥解析为同一令牌，缓存按新密钥写入
+		err = DB.Where("previous_key = ? AND previous_key_expires_at > ?", key, common.GetTimestamp()).First(&token).Error
+		if err == nil {
+			setTokenKeyAlias(key, token.Key, token.PreviousKeyExpiresAt)
+		}
+	}
 	return token, err
 }
 
+// RotateTokenKey 为令牌签发新密钥，旧密钥在 graceSeconds 秒内继续有效。
+// graceSeconds 为 0 时旧密钥立即失效；再次轮换会覆盖上一次保留的旧密钥。
+func RotateTokenKey(id int, userId int, newKey string, graceSeconds int64) (*Token, error) {
+	if id == 0 || userId == 0 {
+		return nil, errors.New("id 或 userId 为空！")
+	}
+	var token Token
+	var replacedKeys []string
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockForUpdate(tx).Where("id = ? AND user_id = ?", id, userId).First(&token).Error; err != nil {
+			return err
+		}
+		replacedKeys = []string{token.Key, token.PreviousKey}
+		token.PreviousKey = ""
+		token.PreviousKeyExpiresAt = 0
+		if graceSeconds > 0 {
+			token.PreviousKey = token.Key
+			token.PreviousKeyExpiresAt = common.GetTimestamp() + graceSeconds
+		}
+		token.Key = newKey
+		return tx.Model(&Token{}).Where("id = ?", token.Id).Updates(map[string]interface{}{
+			"key":                     token.Key,
+			"previous_key":            token.PreviousKey,
+			"previous_key_expires_at": token.PreviousKeyExpiresAt,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	deleteTokenKeyAlias(replacedKeys[1])
+	if common.RedisEnabled {
+		// 旧密钥的缓存条目必须清除，之后经数据库按 previous_key 解析
+		gopool.Go(func() {
+			if err := invalidateTokensCache([]Token{{Key: replacedKeys[0]}, {Key: replacedKeys[1]}}); err != nil {
+				common.SysLog("failed to invalidate rotated token cache: " + err.Error())
+			}
+		})
+	}
+	return &token, nil
+}
+
 func (token *Token) Insert() error {
 	var err error
 	err = DB.Create(token).Error
@@ -302,7 +381,7 @@ func (token *Token) Update() (err error) {
 		}
 	}()
 	err = DB.Model(token).Select("name", "status", "expired_time", "remain_quota", "unlimited_quota",
-		"model_limits_enabled", "model_limits", "allow_ips", "group", "cross_group_retry").Updates(token).Error
+		"model_limits_enabled", "model_limits", "model_mapping", "allow_ips", "group", "cross_group_retry", "rpm_limit", "tpm_limit", "max_concurrency", "body_log_enabled").Updates(token).Error
 	return err
 }
 
@@ -356,6 +435,18 @@ func (token *Token) GetModelLimitsMap() map[string]bool {
 	return limitsMap
 }
 
+// GetModelMapping 解析令牌级模型别名，格式错误时视为未配置
+func (token *Token) GetModelMapping() map[string]string {
+	if token.ModelMapping == "" || token.ModelMapping == "{}" {
+		return nil
+	}
+	mapping := make(map[string]string)
+	if err := common.UnmarshalJsonStr(token.ModelMapping, &mapping); err != nil {
+		return nil
+	}
+	return mapping
+}
+
 func DisableModelLimits(tokenId int) error {
 	token, err := GetTokenById(tokenId)
 	if err != nil {
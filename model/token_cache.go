@@ -2,12 +2,56 @@ package model
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/constant"
+
+	"github.com/samber/hot"
+)
+
+const tokenKeyAliasCapacity = 10000
+
+var (
+	tokenKeyAliasOnce  sync.Once
+	tokenKeyAliasCache *hot.HotCache[string, string]
 )
 
+// getTokenKeyAliasCache maps the HMAC of a rotated-out key to the current key
+// so that lookups during the grace window skip the previous_key query. It is
+// kept in process rather than in Redis because Redis only ever holds HMACs of
+// token keys.
+func getTokenKeyAliasCache() *hot.HotCache[string, string] {
+	tokenKeyAliasOnce.Do(func() {
+		tokenKeyAliasCache = hot.NewHotCache[string, string](hot.LRU, tokenKeyAliasCapacity).Build()
+	})
+	return tokenKeyAliasCache
+}
+
+func getTokenKeyAlias(previousKey string) (string, bool) {
+	if previousKey == "" {
+		return "", false
+	}
+	newKey, found, _ := getTokenKeyAliasCache().Get(common.GenerateHMAC(previousKey))
+	return newKey, found
+}
+
+func setTokenKeyAlias(previousKey string, newKey string, expiresAt int64) {
+	ttl := expiresAt - common.GetTimestamp()
+	if previousKey == "" || newKey == "" || ttl <= 0 {
+		return
+	}
+	getTokenKeyAliasCache().SetWithTTL(common.GenerateHMAC(previousKey), newKey, time.Duration(ttl)*time.Second)
+}
+
+func deleteTokenKeyAlias(previousKey string) {
+	if previousKey == "" {
+		return
+	}
+	getTokenKeyAliasCache().Delete(common.GenerateHMAC(previousKey))
+}
+
 func cacheSetToken(token Token) error {
 	key := common.GenerateHMAC(token.Key)
 	token.Clean()
@@ -15,6 +59,7 @@ func cacheSetToken(token Token) error {
 	if err != nil {
 		return err
 	}
+	invalidateLocalToken(key)
 	return nil
 }
 
@@ -24,6 +69,7 @@ func cacheDeleteToken(key string) error {
 	if err != nil {
 		return err
 	}
+	invalidateLocalToken(key)
 	return nil
 }
 
@@ -33,6 +79,7 @@ func cacheIncrTokenQuota(key string, increment int64) error {
 	if err != nil {
 		return err
 	}
+	localDropToken(key)
 	return nil
 }
 
@@ -46,6 +93,7 @@ func cacheSetTokenField(key string, field string, value string) error {
 	if err != nil {
 		return err
 	}
+	invalidateLocalToken(key)
 	return nil
 }
 
@@ -55,11 +103,15 @@ func cacheGetTokenByKey(key string) (*Token, error) {
 	if !common.RedisEnabled {
 		return nil, fmt.Errorf("redis is not enabled")
 	}
+	if token, ok := localGetToken(hmacKey); ok {
+		return token, nil
+	}
 	var token Token
 	err := common.RedisHGetObj(fmt.Sprintf("token:%s", hmacKey), &token)
 	if err != nil {
 		return nil, err
 	}
 	token.Key = key
+	localSetToken(hmacKey, token)
 	return &token, nil
 }
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTokenByKey_PreviousKeyAlias(t *testing.T) {
+	truncateTables(t)
+	t.Cleanup(func() { getTokenKeyAliasCache().Purge() })
+
+	token := &Token{UserId: 1, Key: "alias-old-key", Name: "alias", Status: common.TokenStatusEnabled, ExpiredTime: -1}
+	require.NoError(t, DB.Create(token).Error)
+
+	rotated, err := RotateTokenKey(token.Id, 1, "alias-new-key", 3600)
+	require.NoError(t, err)
+	_, found := getTokenKeyAlias("alias-old-key")
+	assert.False(t, found)
+
+	resolved, err := GetTokenByKey("alias-old-key", false)
+	require.NoError(t, err)
+	assert.Equal(t, rotated.Key, resolved.Key)
+	newKey, found := getTokenKeyAlias("alias-old-key")
+	require.True(t, found)
+	assert.Equal(t, "alias-new-key", newKey)
+
+	resolved, err = GetTokenByKey("alias-old-key", false)
+	require.NoError(t, err)
+	assert.Equal(t, token.Id, resolved.Id)
+
+	// 其他节点再次轮换且不保留宽限期：本节点的别名不会被主动清除，查询时须自行失效
+	require.NoError(t, DB.Model(&Token{}).Where("id = ?", token.Id).Updates(map[string]interface{}{
+		"key":                     "alias-third-key",
+		"previous_key":            "",
+		"previous_key_expires_at": 0,
+	}).Error)
+	_, err = GetTokenByKey("alias-old-key", false)
+	assert.Error(t, err)
+	_, found = getTokenKeyAlias("alias-old-key")
+	assert.False(t, found)
+}
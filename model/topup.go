@@ -22,6 +22,10 @@ type TopUp struct {
 	CreateTime      int64   `json:"create_time"`
 	CompleteTime    int64   `json:"complete_time"`
 	Status          string  `json:"status"`
+	// ProviderPaymentId 支付网关侧的支付单号（Stripe 为 PaymentIntent ID），用于匹配退款事件
+	ProviderPaymentId string `json:"provider_payment_id" gorm:"type:varchar(255);index;default:''"`
+	// RefundedAmount 网关累计退款金额（最小货币单位）
+	RefundedAmount int64 `json:"refunded_amount" gorm:"type:bigint;not null;default:0"`
 }
 
 const (
@@ -47,6 +51,16 @@ var (
 	ErrTopUpStatusInvalid    = errors.New("topup status invalid")
 )
 
+// TopUpCompletedHook 充值订单到账后调用，每笔订单只调用一次。
+// model 层无法引用 service，由 service 层注册用于推送 webhook 事件
+var TopUpCompletedHook func(userId int, tradeNo string, paymentMethod string, quota int, money float64)
+
+func notifyTopUpCompleted(userId int, tradeNo string, paymentMethod string, quota int, money float64) {
+	if TopUpCompletedHook != nil {
+		TopUpCompletedHook(userId, tradeNo, paymentMethod, quota, money)
+	}
+}
+
 func (topUp *TopUp) Insert() error {
 	var err error
 	err = DB.Create(topUp).Error
@@ -106,7 +120,7 @@ func UpdatePendingTopUpStatus(tradeNo string, expectedPaymentProvider string, ta
 	})
 }
 
-func Recharge(referenceId string, customerId string, callerIp string) (err error) {
+func Recharge(referenceId string, customerId string, paymentId string, callerIp string) (err error) {
 	if referenceId == "" {
 		return errors.New("未提供支付单号")
 	}
@@ -135,6 +149,7 @@ func Recharge(referenceId string, customerId string, callerIp string) (err error
 
 		topUp.CompleteTime = common.GetTimestamp()
 		topUp.Status = common.TopUpStatusSuccess
+		topUp.ProviderPaymentId = paymentId
 		err = tx.Save(topUp).Error
 		if err != nil {
 			return err
@@ -153,12 +168,71 @@ func Recharge(referenceId string, customerId string, callerIp string) (err error
 		common.SysError("topup failed: " + err.Error())
 		return errors.New("充值失败，请稍后重试")
 	}
+	invalidateUserQuotaAfterCommit(topUp.UserId)
 
 	RecordTopupLog(topUp.UserId, fmt.Sprintf("使用在线充值成功，充值金额: %v，支付金额：%d", logger.FormatQuota(int(quota)), topUp.Amount), callerIp, topUp.PaymentMethod, PaymentMethodStripe)
+	notifyTopUpCompleted(topUp.UserId, topUp.TradeNo, topUp.PaymentMethod, int(quota), topUp.Money)
 
 	return nil
 }
 
+// RefundStripeTopUp 按 Stripe 支付单的累计退款金额扣回对应比例的额度，返回本次扣回的额度。
+// Stripe 每次退款推送的都是累计值，只处理超出已记录退款金额的部分，重复推送不会重复扣减
+func RefundStripeTopUp(paymentId string, chargeAmount int64, amountRefunded int64, callerIp string) (int, error) {
+	if paymentId == "" || chargeAmount <= 0 || amountRefunded <= 0 {
+		return 0, errors.New("无效的退款参数")
+	}
+	if amountRefunded > chargeAmount {
+		amountRefunded = chargeAmount
+	}
+
+	var quotaToDeduct int
+	topUp := &TopUp{}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		err := lockForUpdate(tx).Where("provider_payment_id = ?", paymentId).First(topUp).Error
+		if err != nil {
+			return ErrTopUpNotFound
+		}
+		if topUp.PaymentProvider != PaymentProviderStripe {
+			return ErrPaymentMethodMismatch
+		}
+		if topUp.Status != common.TopUpStatusSuccess && topUp.Status != common.TopUpStatusRefunded {
+			return ErrTopUpStatusInvalid
+		}
+		if amountRefunded <= topUp.RefundedAmount {
+			return nil
+		}
+
+		// 按累计比例计算应扣额度再减去已扣部分，避免多次部分退款的舍入误差累积
+		credited := decimal.NewFromFloat(topUp.Money).Mul(decimal.NewFromFloat(common.QuotaPerUnit))
+		charge := decimal.NewFromInt(chargeAmount)
+		deductedBefore := credited.Mul(decimal.NewFromInt(topUp.RefundedAmount)).Div(charge).IntPart()
+		deductedAfter := credited.Mul(decimal.NewFromInt(amountRefunded)).Div(charge).IntPart()
+		quotaToDeduct = int(deductedAfter - deductedBefore)
+
+		topUp.RefundedAmount = amountRefunded
+		if amountRefunded >= chargeAmount {
+			topUp.Status = common.TopUpStatusRefunded
+		}
+		if err := tx.Save(topUp).Error; err != nil {
+			return err
+		}
+		if quotaToDeduct <= 0 {
+			return nil
+		}
+		return tx.Model(&User{}).Where("id = ?", topUp.UserId).Update("quota", gorm.Expr("quota - ?", quotaToDeduct)).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if quotaToDeduct > 0 {
+		invalidateUserQuotaAfterCommit(topUp.UserId)
+		RecordTopupLog(topUp.UserId, fmt.Sprintf("在线充值退款，扣除额度: %v，订单号：%s", logger.FormatQuota(quotaToDeduct), topUp.TradeNo), callerIp, topUp.PaymentMethod, PaymentMethodStripe)
+	}
+	return quotaToDeduct, nil
+}
+
 // topUpQueryWindowSeconds 限制充值记录查询的时间窗口（秒）。
 const topUpQueryWindowSeconds int64 = 30 * 24 * 60 * 60
 
@@ -384,9 +458,13 @@ func ManualCompleteTopUp(tradeNo string, callerIp string) error {
 	if err != nil {
 		return err
 	}
+	invalidateUserQuotaAfterCommit(userId)
 
 	// 事务外记录日志，避免阻塞
 	RecordTopupLog(userId, fmt.Sprintf("管理员补单成功，充值金额: %v，支付金额：%f", logger.FormatQuota(quotaToAdd), payMoney), callerIp, paymentMethod, "admin")
+	if quotaToAdd > 0 {
+		notifyTopUpCompleted(userId, tradeNo, paymentMethod, quotaToAdd, payMoney)
+	}
 	return nil
 }
 func RechargeCreem(referenceId string, customerEmail string, customerName string, callerIp string) (err error) {
@@ -458,8 +536,10 @@ func RechargeCreem(referenceId string, customerEmail string, customerName string
 		common.SysError("creem topup failed: " + err.Error())
 		return errors.New("充值失败，请稍后重试")
 	}
+	invalidateUserQuotaAfterCommit(topUp.UserId)
 
 	RecordTopupLog(topUp.UserId, fmt.Sprintf("使用Creem充值成功，充值额度: %v，支付金额：%.2f", quota, topUp.Money), callerIp, topUp.PaymentMethod, PaymentMethodCreem)
+	notifyTopUpCompleted(topUp.UserId, topUp.TradeNo, topUp.PaymentMethod, int(quota), topUp.Money)
 
 	return nil
 }
@@ -521,7 +601,9 @@ func RechargeWaffo(tradeNo string, callerIp string) (err error) {
 	}
 
 	if quotaToAdd > 0 {
+		invalidateUserQuotaAfterCommit(topUp.UserId)
 		RecordTopupLog(topUp.UserId, fmt.Sprintf("Waffo充值成功，充值额度: %v，支付金额: %.2f", logger.FormatQuota(quotaToAdd), topUp.Money), callerIp, topUp.PaymentMethod, PaymentMethodWaffo)
+		notifyTopUpCompleted(topUp.UserId, topUp.TradeNo, topUp.PaymentMethod, quotaToAdd, topUp.Money)
 	}
 
 	return nil
@@ -582,7 +664,9 @@ func RechargeWaffoPancake(tradeNo string) (err error) {
 	}
 
 	if quotaToAdd > 0 {
+		invalidateUserQuotaAfterCommit(topUp.UserId)
 		RecordLog(topUp.UserId, LogTypeTopup, fmt.Sprintf("Waffo Pancake充值成功，充值额度: %v，支付金额: %.2f", logger.FormatQuota(quotaToAdd), topUp.Money))
+		notifyTopUpCompleted(topUp.UserId, topUp.TradeNo, topUp.PaymentMethod, quotaToAdd, topUp.Money)
 	}
 
 	return nil
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefundStripeTopUp_DeductsCumulativeDelta(t *testing.T) {
+	truncateTables(t)
+	insertUserForPaymentGuardTest(t, 501, 0)
+	require.NoError(t, (&TopUp{
+		UserId:          501,
+		Amount:          10,
+		Money:           10,
+		TradeNo:         "stripe-refund",
+		PaymentMethod:   PaymentMethodStripe,
+		PaymentProvider: PaymentProviderStripe,
+		Status:          common.TopUpStatusPending,
+	}).Insert())
+	require.NoError(t, Recharge("stripe-refund", "cus_1", "pi_refund", "127.0.0.1"))
+
+	credited := int(10 * common.QuotaPerUnit)
+	tests := []struct {
+		name           string
+		amountRefunded int64
+		wantDeducted   int
+		wantStatus     string
+	}{
+		{name: "partial refund", amountRefunded: 300, wantDeducted: credited * 3 / 10, wantStatus: common.TopUpStatusSuccess},
+		{name: "duplicate event", amountRefunded: 300, wantDeducted: 0, wantStatus: common.TopUpStatusSuccess},
+		{name: "remaining refund", amountRefunded: 1000, wantDeducted: credited * 7 / 10, wantStatus: common.TopUpStatusRefunded},
+		{name: "already fully refunded", amountRefunded: 1000, wantDeducted: 0, wantStatus: common.TopUpStatusRefunded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deducted, err := RefundStripeTopUp("pi_refund", 1000, tt.amountRefunded, "127.0.0.1")
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantDeducted, deducted)
+			assert.Equal(t, tt.wantStatus, GetTopUpByTradeNo("stripe-refund").Status)
+		})
+	}
+
+	quota, err := GetUserQuota(501, true)
+	require.NoError(t, err)
+	assert.Zero(t, quota)
+
+	_, err = RefundStripeTopUp("pi_unknown", 1000, 100, "127.0.0.1")
+	assert.ErrorIs(t, err, ErrTopUpNotFound)
+}
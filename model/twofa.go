@@ -35,6 +35,39 @@ type TwoFABackupCode struct {
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// BeforeSave 配置了 CRYPTO_SECRET/SESSION_SECRET 时加密存储 TOTP 密钥，
+// 未配置时密钥每次重启都会变化，因此保持明文以免无法解密
+func (t *TwoFA) BeforeSave(tx *gorm.DB) error {
+	if t.Secret == "" || !common.CryptoSecretConfigured {
+		return nil
+	}
+	secret, err := common.EncryptString(t.Secret)
+	if err != nil {
+		return err
+	}
+	t.Secret = secret
+	return nil
+}
+
+// AfterSave 保存后恢复明文密钥，便于调用方继续使用
+func (t *TwoFA) AfterSave(tx *gorm.DB) error {
+	return t.decryptSecret()
+}
+
+// AfterFind 读取时解密密钥，兼容历史明文数据
+func (t *TwoFA) AfterFind(tx *gorm.DB) error {
+	return t.decryptSecret()
+}
+
+func (t *TwoFA) decryptSecret() error {
+	secret, err := common.DecryptString(t.Secret)
+	if err != nil {
+		return fmt.Errorf("解密2FA密钥失败: %w", err)
+	}
+	t.Secret = secret
+	return nil
+}
+
 // GetTwoFAByUserId 根据用户ID获取2FA设置
 func GetTwoFAByUserId(userId int) (*TwoFA, error) {
 	if userId == 0 {
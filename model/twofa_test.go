@@ -0,0 +1,52 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTwoFASecretEncryptedAtRest(t *testing.T) {
+	truncateTables(t)
+
+	overrideForTest(t, &common.CryptoSecretConfigured, true)
+
+	tests := []struct {
+		name      string
+		userId    int
+		legacy    bool
+		encrypted bool
+	}{
+		{name: "encrypted on save", userId: 501, encrypted: true},
+		{name: "legacy plaintext still readable", userId: 502, legacy: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			const secret = "JBSWY3DPEHPK3PXP"
+			if tt.legacy {
+				require.NoError(t, DB.Exec("INSERT INTO two_fas (user_id, secret, is_enabled) VALUES (?, ?, ?)", tt.userId, secret, true).Error)
+			} else {
+				twoFA := &TwoFA{UserId: tt.userId, Secret: secret}
+				require.NoError(t, DB.Create(twoFA).Error)
+				assert.Equal(t, secret, twoFA.Secret)
+			}
+
+			var stored string
+			require.NoError(t, DB.Model(&TwoFA{}).Where("user_id = ?", tt.userId).Pluck("secret", &stored).Error)
+			assert.Equal(t, tt.encrypted, common.IsEncryptedString(stored))
+
+			loaded, err := GetTwoFAByUserId(tt.userId)
+			require.NoError(t, err)
+			require.NotNil(t, loaded)
+			assert.Equal(t, secret, loaded.Secret)
+
+			// 旧数据再次保存时会被加密
+			require.NoError(t, loaded.Update())
+			require.NoError(t, DB.Model(&TwoFA{}).Where("user_id = ?", tt.userId).Pluck("secret", &stored).Error)
+			assert.True(t, common.IsEncryptedString(stored))
+			assert.Equal(t, secret, loaded.Secret)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
@@ -23,6 +24,10 @@ type QuotaData struct {
 	TokenUsed int    `json:"token_used" gorm:"default:0"`
 	Count     int    `json:"count" gorm:"default:0"`
 	Quota     int    `json:"quota" gorm:"default:0"`
+	// 以下字段供用量分析接口使用，Count 只统计成功请求，失败请求计入 ErrorCount
+	PromptTokens     int `json:"prompt_tokens" gorm:"default:0"`
+	CompletionTokens int `json:"completion_tokens" gorm:"default:0"`
+	ErrorCount       int `json:"error_count" gorm:"default:0"`
 }
 
 type QuotaDataLogParams struct {
@@ -36,18 +41,94 @@ type QuotaDataLogParams struct {
 	TokenID   int
 	ChannelID int
 	NodeName  string
+
+	PromptTokens     int
+	CompletionTokens int
+	// IsError 为 true 时记为一次失败请求，不计入 Count
+	IsError bool
 }
 
-func UpdateQuotaData() {
+// quotaDataHandoffKey 非主节点把本地缓存的看板数据推送到该 Redis 列表，由主节点合并写库
+const quotaDataHandoffKey = "quota_data:handoff"
+
+const quotaDataHandoffBatchSize = 1000
+
+// UpdateQuotaData 定时保存数据看板数据，只有后台任务主节点写库。
+// 启用 Redis 时其他节点把本地缓存推送到 Redis 由主节点合并；
+// 未启用 Redis 时没有共享通道，各节点仍各自写入本节点的数据
+func UpdateQuotaData(isLeader func() bool) {
 	for {
 		if common.DataExportEnabled {
-			common.SysLog("正在更新数据看板数据...")
-			SaveQuotaDataCache()
+			switch {
+			case isLeader():
+				common.SysLog("正在更新数据看板数据...")
+				if common.RedisEnabled {
+					if err := mergeQuotaDataHandoff(); err != nil {
+						common.SysError("failed to merge quota data handoff: " + err.Error())
+					}
+				}
+				SaveQuotaDataCache()
+			case common.RedisEnabled:
+				if err := handOffQuotaDataCache(); err != nil {
+					common.SysError("failed to hand off quota data: " + err.Error())
+				}
+			default:
+				SaveQuotaDataCache()
+			}
 		}
 		time.Sleep(time.Duration(common.DataExportInterval) * time.Minute)
 	}
 }
 
+// handOffQuotaDataCache 将本节点缓存的看板数据推送到 Redis，推送失败时保留在本地下次重试
+func handOffQuotaDataCache() error {
+	CacheQuotaDataLock.Lock()
+	defer CacheQuotaDataLock.Unlock()
+	if len(CacheQuotaData) == 0 {
+		return nil
+	}
+	values := make([]interface{}, 0, len(CacheQuotaData))
+	for _, quotaData := range CacheQuotaData {
+		data, err := common.Marshal(quotaData)
+		if err != nil {
+			return err
+		}
+		values = append(values, string(data))
+	}
+	if err := common.RDB.RPush(context.Background(), quotaDataHandoffKey, values...).Err(); err != nil {
+		return err
+	}
+	CacheQuotaData = make(map[string]*QuotaData)
+	return nil
+}
+
+// mergeQuotaDataHandoff 取出其他节点推送的看板数据并合并到本地缓存
+func mergeQuotaDataHandoff() error {
+	ctx := context.Background()
+	for {
+		pipe := common.RDB.TxPipeline()
+		rangeCmd := pipe.LRange(ctx, quotaDataHandoffKey, 0, quotaDataHandoffBatchSize-1)
+		pipe.LTrim(ctx, quotaDataHandoffKey, quotaDataHandoffBatchSize, -1)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return err
+		}
+		values := rangeCmd.Val()
+		CacheQuotaDataLock.Lock()
+		for _, value := range values {
+			var quotaData QuotaData
+			if err := common.UnmarshalJsonStr(value, &quotaData); err != nil {
+				common.SysError("failed to decode quota data handoff: " + err.Error())
+				continue
+			}
+			logQuotaDataCache(&quotaData)
+		}
+		CacheQuotaDataLock.Unlock()
+		if len(values) < quotaDataHandoffBatchSize {
+			return nil
+		}
+	}
+}
+
 var CacheQuotaData = make(map[string]*QuotaData)
 var CacheQuotaDataLock = sync.Mutex{}
 
@@ -62,14 +143,14 @@ func logQuotaDataCache(quotaData *QuotaData) {
 		quotaData.ChannelID,
 		quotaData.NodeName,
 	)
-	count := quotaData.Count
-	quota := quotaData.Quota
-	tokenUsed := quotaData.TokenUsed
 	cachedQuotaData, ok := CacheQuotaData[key]
 	if ok {
-		cachedQuotaData.Count += count
-		cachedQuotaData.Quota += quota
-		cachedQuotaData.TokenUsed += tokenUsed
+		cachedQuotaData.Count += quotaData.Count
+		cachedQuotaData.Quota += quotaData.Quota
+		cachedQuotaData.TokenUsed += quotaData.TokenUsed
+		cachedQuotaData.PromptTokens += quotaData.PromptTokens
+		cachedQuotaData.CompletionTokens += quotaData.CompletionTokens
+		cachedQuotaData.ErrorCount += quotaData.ErrorCount
 		quotaData = cachedQuotaData
 	}
 	CacheQuotaData[key] = quotaData
@@ -90,6 +171,13 @@ func LogQuotaData(params QuotaDataLogParams) {
 		Count:     1,
 		Quota:     params.Quota,
 		TokenUsed: params.TokenUsed,
+
+		PromptTokens:     params.PromptTokens,
+		CompletionTokens: params.CompletionTokens,
+	}
+	if params.IsError {
+		quotaData.Count = 0
+		quotaData.ErrorCount = 1
 	}
 
 	CacheQuotaDataLock.Lock()
@@ -132,6 +220,10 @@ func increaseQuotaData(quotaData *QuotaData) {
 			"count":      gorm.Expr("count + ?", quotaData.Count),
 			"quota":      gorm.Expr("quota + ?", quotaData.Quota),
 			"token_used": gorm.Expr("token_used + ?", quotaData.TokenUsed),
+
+			"prompt_tokens":     gorm.Expr("prompt_tokens + ?", quotaData.PromptTokens),
+			"completion_tokens": gorm.Expr("completion_tokens + ?", quotaData.CompletionTokens),
+			"error_count":       gorm.Expr("error_count + ?", quotaData.ErrorCount),
 		}).Error
 	if err != nil {
 		common.SysLog(fmt.Sprintf("increaseQuotaData error: %s", err))
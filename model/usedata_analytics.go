@@ -0,0 +1,99 @@
+package model
+
+import (
+	"fmt"
+)
+
+// 用量分析支持的分组维度
+const (
+	UsageAnalyticsGroupByModel   = "model"
+	UsageAnalyticsGroupByChannel = "channel"
+	UsageAnalyticsGroupByUser    = "user"
+	UsageAnalyticsGroupByGroup   = "group"
+)
+
+// 用量分析支持的时间粒度（秒）
+const (
+	UsageAnalyticsBucketHour int64 = 3600
+	UsageAnalyticsBucketDay  int64 = 86400
+)
+
+var usageAnalyticsGroupColumns = map[string]string{
+	UsageAnalyticsGroupByModel:   "model_name",
+	UsageAnalyticsGroupByChannel: "channel_id",
+	UsageAnalyticsGroupByUser:    "user_id, username",
+	UsageAnalyticsGroupByGroup:   "use_group",
+}
+
+type UsageAnalyticsRow struct {
+	Bucket           int64   `json:"bucket" gorm:"column:bucket"`
+	ModelName        string  `json:"model_name,omitempty" gorm:"column:model_name"`
+	ChannelID        int     `json:"channel_id,omitempty" gorm:"column:channel_id"`
+	UserID           int     `json:"user_id,omitempty" gorm:"column:user_id"`
+	Username         string  `json:"username,omitempty" gorm:"column:username"`
+	UseGroup         string  `json:"use_group,omitempty" gorm:"column:use_group"`
+	Requests         int64   `json:"requests" gorm:"column:requests"`
+	ErrorCount       int64   `json:"error_count" gorm:"column:error_count"`
+	ErrorRate        float64 `json:"error_rate" gorm:"-"`
+	PromptTokens     int64   `json:"prompt_tokens" gorm:"column:prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens" gorm:"column:completion_tokens"`
+	TotalTokens      int64   `json:"total_tokens" gorm:"column:total_tokens"`
+	Quota            int64   `json:"quota" gorm:"column:quota"`
+}
+
+// IsValidUsageAnalyticsGroupBy 判断分组维度是否受支持
+func IsValidUsageAnalyticsGroupBy(groupBy string) bool {
+	_, ok := usageAnalyticsGroupColumns[groupBy]
+	return ok
+}
+
+// GetUsageAnalytics 基于 quota_data 预聚合表按时间桶和维度汇总用量，
+// requests 包含成功与失败请求，error_rate = error_count / requests
+func GetUsageAnalytics(startTime int64, endTime int64, groupBy string, bucketSize int64) ([]*UsageAnalyticsRow, error) {
+	groupColumns, ok := usageAnalyticsGroupColumns[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("unsupported group_by: %s", groupBy)
+	}
+	bucketExpr := rankingBucketExpr(bucketSize)
+	rows := make([]*UsageAnalyticsRow, 0)
+	err := DB.Table("quota_data").
+		Select(fmt.Sprintf("%s as bucket, %s, sum(count) + sum(error_count) as requests, sum(error_count) as error_count, "+
+			"sum(prompt_tokens) as prompt_tokens, sum(completion_tokens) as completion_tokens, sum(token_used) as total_tokens, sum(quota) as quota",
+			bucketExpr, groupColumns)).
+		Where("created_at >= ? and created_at <= ?", startTime, endTime).
+		Group(fmt.Sprintf("%s, %s", bucketExpr, groupColumns)).
+		Order("bucket ASC").
+		Order("quota DESC").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		if row.Requests > 0 {
+			row.ErrorRate = float64(row.ErrorCount) / float64(row.Requests)
+		}
+	}
+	return rows, nil
+}
+
+// GetModelUsage 按模型汇总时间范围内的用量，userId 为 0 时统计全部用户
+func GetModelUsage(userId int, startTime int64, endTime int64) ([]*UsageAnalyticsRow, error) {
+	rows := make([]*UsageAnalyticsRow, 0)
+	query := DB.Table("quota_data").
+		Select("model_name, sum(count) + sum(error_count) as requests, sum(error_count) as error_count, "+
+			"sum(prompt_tokens) as prompt_tokens, sum(completion_tokens) as completion_tokens, sum(token_used) as total_tokens, sum(quota) as quota").
+		Where("created_at >= ? and created_at <= ?", startTime, endTime)
+	if userId != 0 {
+		query = query.Where("user_id = ?", userId)
+	}
+	err := query.Group("model_name").Order("quota DESC").Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		if row.Requests > 0 {
+			row.ErrorRate = float64(row.ErrorCount) / float64(row.Requests)
+		}
+	}
+	return rows, nil
+}
@@ -0,0 +1,95 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetUsageAnalyticsAggregatesBucketsAndErrors(t *testing.T) {
+	truncateTables(t)
+	CacheQuotaDataLock.Lock()
+	CacheQuotaData = make(map[string]*QuotaData)
+	CacheQuotaDataLock.Unlock()
+
+	// 两条成功请求落在第一天的不同小时，一条失败请求与其中一条同桶
+	LogQuotaData(QuotaDataLogParams{UserID: 1, Username: "alice", ModelName: "gpt-a", UseGroup: "default", ChannelID: 1,
+		CreatedAt: 3600, Quota: 100, TokenUsed: 30, PromptTokens: 20, CompletionTokens: 10})
+	LogQuotaData(QuotaDataLogParams{UserID: 1, Username: "alice", ModelName: "gpt-a", UseGroup: "default", ChannelID: 2,
+		CreatedAt: 7300, Quota: 50, TokenUsed: 15, PromptTokens: 10, CompletionTokens: 5})
+	LogQuotaData(QuotaDataLogParams{UserID: 1, Username: "alice", ModelName: "gpt-a", UseGroup: "default", ChannelID: 2,
+		CreatedAt: 7400, IsError: true})
+	LogQuotaData(QuotaDataLogParams{UserID: 2, Username: "bob", ModelName: "gpt-b", UseGroup: "vip", ChannelID: 1,
+		CreatedAt: 86400 + 10, Quota: 70, TokenUsed: 7, PromptTokens: 5, CompletionTokens: 2})
+	SaveQuotaDataCache()
+
+	tests := []struct {
+		name       string
+		groupBy    string
+		bucketSize int64
+		check      func(t *testing.T, rows []*UsageAnalyticsRow)
+	}{
+		{
+			name:       "by model per day",
+			groupBy:    UsageAnalyticsGroupByModel,
+			bucketSize: UsageAnalyticsBucketDay,
+			check: func(t *testing.T, rows []*UsageAnalyticsRow) {
+				require.Len(t, rows, 2)
+				require.Equal(t, int64(0), rows[0].Bucket)
+				require.Equal(t, "gpt-a", rows[0].ModelName)
+				require.Equal(t, int64(3), rows[0].Requests)
+				require.Equal(t, int64(1), rows[0].ErrorCount)
+				require.InDelta(t, 1.0/3, rows[0].ErrorRate, 1e-9)
+				require.Equal(t, int64(30), rows[0].PromptTokens)
+				require.Equal(t, int64(15), rows[0].CompletionTokens)
+				require.Equal(t, int64(45), rows[0].TotalTokens)
+				require.Equal(t, int64(150), rows[0].Quota)
+				require.Equal(t, int64(86400), rows[1].Bucket)
+				require.Equal(t, "gpt-b", rows[1].ModelName)
+				require.Zero(t, rows[1].ErrorRate)
+			},
+		},
+		{
+			name:       "by channel per hour",
+			groupBy:    UsageAnalyticsGroupByChannel,
+			bucketSize: UsageAnalyticsBucketHour,
+			check: func(t *testing.T, rows []*UsageAnalyticsRow) {
+				require.Len(t, rows, 3)
+				require.Equal(t, int64(7200), rows[1].Bucket)
+				require.Equal(t, 2, rows[1].ChannelID)
+				require.Equal(t, int64(2), rows[1].Requests)
+				require.InDelta(t, 0.5, rows[1].ErrorRate, 1e-9)
+			},
+		},
+		{
+			name:       "by user",
+			groupBy:    UsageAnalyticsGroupByUser,
+			bucketSize: UsageAnalyticsBucketDay,
+			check: func(t *testing.T, rows []*UsageAnalyticsRow) {
+				require.Len(t, rows, 2)
+				require.Equal(t, "alice", rows[0].Username)
+				require.Equal(t, 2, rows[1].UserID)
+			},
+		},
+		{
+			name:       "by group",
+			groupBy:    UsageAnalyticsGroupByGroup,
+			bucketSize: UsageAnalyticsBucketDay,
+			check: func(t *testing.T, rows []*UsageAnalyticsRow) {
+				require.Len(t, rows, 2)
+				require.Equal(t, "default", rows[0].UseGroup)
+				require.Equal(t, "vip", rows[1].UseGroup)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := GetUsageAnalytics(0, 200000, tt.groupBy, tt.bucketSize)
+			require.NoError(t, err)
+			tt.check(t, rows)
+		})
+	}
+
+	_, err := GetUsageAnalytics(0, 200000, "token", UsageAnalyticsBucketDay)
+	require.Error(t, err)
+}
@@ -114,6 +114,19 @@ func UpdateUserSetting(userId int, setting dto.UserSetting) error {
 	return updateUserSettingCache(userId, settingValue)
 }
 
+// SetUserQuotaWarningSent 更新额度预警发送标记。基于数据库中的最新设置修改，避免覆盖其它设置项
+func SetUserQuotaWarningSent(userId int, sent bool) error {
+	setting, err := GetUserSetting(userId, true)
+	if err != nil {
+		return err
+	}
+	if setting.QuotaWarningSent == sent {
+		return nil
+	}
+	setting.QuotaWarningSent = sent
+	return UpdateUserSetting(userId, setting)
+}
+
 // 根据用户角色生成默认的边栏配置
 func generateDefaultSidebarConfigForRole(userRole int) string {
 	defaultConfig := map[string]interface{}{}
@@ -984,6 +997,10 @@ func GetUserQuota(id int, fromDB bool) (quota int, err error) {
 			})
 		}
 	}()
+	if !fromDB && common.RedisQuotaEnabled {
+		// 权威额度由 Redis 维护，数据库可能尚未写回，不回退读库
+		return getSyncedUserQuota(id)
+	}
 	if !fromDB && common.RedisEnabled {
 		quota, err := getUserQuotaCache(id)
 		if err == nil {
@@ -1080,6 +1097,9 @@ func IncreaseUserQuota(id int, quota int, db bool) (err error) {
 	if quota < 0 {
 		return errors.New("quota 不能为负数！")
 	}
+	if !db && common.RedisQuotaEnabled {
+		return adjustSyncedUserQuota(id, quota, false)
+	}
 	gopool.Go(func() {
 		err := cacheIncrUserQuota(id, int64(quota))
 		if err != nil {
@@ -1105,6 +1125,16 @@ func DecreaseUserQuota(id int, quota int, db bool) (err error) {
 	if quota < 0 {
 		return errors.New("quota 不能为负数！")
 	}
+	if quotaGrantsActive.Load() {
+		gopool.Go(func() {
+			if err := drainQuotaGrants(id, quota); err != nil {
+				common.SysLog("failed to drain quota grants: " + err.Error())
+			}
+		})
+	}
+	if !db && common.RedisQuotaEnabled {
+		return adjustSyncedUserQuota(id, -quota, false)
+	}
 	gopool.Go(func() {
 		err := cacheDecrUserQuota(id, int64(quota))
 		if err != nil {
@@ -1153,6 +1183,36 @@ func UpdateUserLastLoginAt(id int) {
 	}
 }
 
+// UsedQuotaUpdatedHook 用户累计消费写入数据库后调用（批量更新模式下在落库时调用），
+// 由 service 层注册用于按累计消费调整用户分组
+var UsedQuotaUpdatedHook func(userId int)
+
+func notifyUsedQuotaUpdated(userId int) {
+	if UsedQuotaUpdatedHook != nil {
+		UsedQuotaUpdatedHook(userId)
+	}
+}
+
+// GetUsersInGroupsAfterId 按 id 升序分批读取指定分组内的用户，只加载 id、用户名、分组和累计消费
+func GetUsersInGroupsAfterId(groups []string, afterId int, limit int) (users []*User, err error) {
+	err = DB.Select("id", "username", "group", "used_quota").
+		Where("id > ? AND "+commonGroupCol+" IN ?", afterId, groups).
+		Order("id").Limit(limit).Find(&users).Error
+	return users, err
+}
+
+// UpdateUserGroupIfMatch 仅当用户当前分组仍为 fromGroup 时修改分组，避免覆盖并发的人工调整
+func UpdateUserGroupIfMatch(userId int, fromGroup string, toGroup string) (bool, error) {
+	result := DB.Model(&User{}).Where("id = ? AND "+commonGroupCol+" = ?", userId, fromGroup).Update("group", toGroup)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	if err := updateUserGroupCache(userId, toGroup); err != nil {
+		common.SysLog("failed to update user group cache: " + err.Error())
+	}
+	return true, nil
+}
+
 func UpdateUserUsedQuotaAndRequestCount(id int, quota int) {
 	if common.BatchUpdateEnabled {
 		addNewRecord(BatchUpdateTypeUsedQuota, id, quota)
@@ -1173,6 +1233,7 @@ func updateUserUsedQuotaAndRequestCount(id int, quota int, count int) {
 		common.SysLog("failed to update user used quota and request count: " + err.Error())
 		return
 	}
+	notifyUsedQuotaUpdated(id)
 
 	//// 更新缓存
 	//if err := invalidateUserCache(id); err != nil {
@@ -1194,6 +1255,10 @@ func updateUserQuotaUsedQuotaAndRequestCount(id int, quota int, usedQuota int, r
 	).Error
 	if err != nil {
 		common.SysLog("failed to batch update user quota, used quota and request count: " + err.Error())
+		return
+	}
+	if usedQuota != 0 {
+		notifyUsedQuotaUpdated(id)
 	}
 }
 
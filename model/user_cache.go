@@ -54,7 +54,16 @@ func invalidateUserCache(userId int) error {
 	if !common.RedisEnabled {
 		return nil
 	}
-	return common.RedisDelKey(getUserCacheKey(userId))
+	if err := common.RedisDelKey(getUserCacheKey(userId)); err != nil {
+		return err
+	}
+	if common.RedisQuotaEnabled {
+		if err := common.RedisDelKey(getUserQuotaSyncKey(userId)); err != nil {
+			return err
+		}
+	}
+	invalidateLocalUser(userId)
+	return nil
 }
 
 // InvalidateUserCache is the exported version of invalidateUserCache.
@@ -68,11 +77,15 @@ func populateUserCache(user User) error {
 		return nil
 	}
 
-	return common.RedisHSetObj(
+	if err := common.RedisHSetObj(
 		getUserCacheKey(user.Id),
 		user.ToBaseUser(),
 		time.Duration(common.RedisKeyCacheSeconds())*time.Second,
-	)
+	); err != nil {
+		return err
+	}
+	invalidateLocalUser(user.Id)
+	return nil
 }
 
 // updateUserCache refreshes non-quota user cache fields.
@@ -94,7 +107,11 @@ func updateUserCache(user User) error {
 	if err := updateUserNameCache(user.Id, user.Username); err != nil {
 		return err
 	}
-	return updateUserSettingCache(user.Id, user.Setting)
+	if err := updateUserSettingCache(user.Id, user.Setting); err != nil {
+		return err
+	}
+	invalidateLocalUser(user.Id)
+	return nil
 }
 
 // GetUserCache gets complete user cache from hash
@@ -143,21 +160,35 @@ func cacheGetUserBase(userId int) (*UserBase, error) {
 	if !common.RedisEnabled {
 		return nil, fmt.Errorf("redis is not enabled")
 	}
+	if userCache, ok := localGetUser(userId); ok {
+		return userCache, nil
+	}
 	var userCache UserBase
 	// Try getting from Redis first
 	err := common.RedisHGetObj(getUserCacheKey(userId), &userCache)
 	if err != nil {
 		return nil, err
 	}
+	localSetUser(userCache)
 	return &userCache, nil
 }
 
 // Add atomic quota operations using hash fields
+// 调用方已直接修改数据库额度；Redis 权威额度模式下同步调整已加载的权威额度（不记录待写回增量）
 func cacheIncrUserQuota(userId int, delta int64) error {
 	if !common.RedisEnabled {
 		return nil
 	}
-	return common.RedisHIncrBy(getUserCacheKey(userId), "Quota", delta)
+	if common.RedisQuotaEnabled {
+		if err := common.RedisIncr(getUserQuotaSyncKey(userId), delta); err != nil {
+			return err
+		}
+	}
+	if err := common.RedisHIncrBy(getUserCacheKey(userId), "Quota", delta); err != nil {
+		return err
+	}
+	localDropUser(userId)
+	return nil
 }
 
 func cacheDecrUserQuota(userId int, delta int64) error {
@@ -214,21 +245,33 @@ func updateUserStatusCache(userId int, status bool) error {
 	if !status {
 		statusInt = common.UserStatusDisabled
 	}
-	return common.RedisHSetField(getUserCacheKey(userId), "Status", fmt.Sprintf("%d", statusInt))
+	if err := common.RedisHSetField(getUserCacheKey(userId), "Status", fmt.Sprintf("%d", statusInt)); err != nil {
+		return err
+	}
+	invalidateLocalUser(userId)
+	return nil
 }
 
 func updateUserQuotaCache(userId int, quota int) error {
 	if !common.RedisEnabled {
 		return nil
 	}
-	return common.RedisHSetField(getUserCacheKey(userId), "Quota", fmt.Sprintf("%d", quota))
+	if err := common.RedisHSetField(getUserCacheKey(userId), "Quota", fmt.Sprintf("%d", quota)); err != nil {
+		return err
+	}
+	localDropUser(userId)
+	return nil
 }
 
 func updateUserGroupCache(userId int, group string) error {
 	if !common.RedisEnabled {
 		return nil
 	}
-	return common.RedisHSetField(getUserCacheKey(userId), "Group", group)
+	if err := common.RedisHSetField(getUserCacheKey(userId), "Group", group); err != nil {
+		return err
+	}
+	invalidateLocalUser(userId)
+	return nil
 }
 
 func UpdateUserGroupCache(userId int, group string) error {
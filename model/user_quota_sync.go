@@ -0,0 +1,306 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/go-redis/redis/v8"
+)
+
+// Redis 权威额度模式（REDIS_QUOTA_ENABLED=true）下：
+//   - user_quota:<id> 是用户额度的权威值，所有节点在此原子扣减，避免多节点各自批量落库导致的超扣；
+//   - user_quota_pending 哈希记录尚未写回数据库的增量，由任一节点在 user_quota_flush_lock 保护下定期写回；
+//   - 始终保持 user_quota:<id> == 数据库额度 + 待写回增量，键过期后按此式重新加载。
+const (
+	userQuotaPendingKey   = "user_quota_pending"
+	userQuotaFlushLockKey = "user_quota_flush_lock"
+	userQuotaFlushLockTTL = 60 * time.Second
+	userQuotaScanBatch    = 200
+)
+
+// userQuotaLoadScript 以数据库额度加上待写回增量初始化权威额度，已存在时保持不变
+var userQuotaLoadScript = redis.NewScript(`
+local pending = tonumber(redis.call('HGET', KEYS[2], ARGV[2]) or '0')
+redis.call('SET', KEYS[1], tonumber(ARGV[1]) + pending, 'EX', ARGV[3], 'NX')
+return tonumber(redis.call('GET', KEYS[1]))
+`)
+
+// userQuotaAdjustScript 原子调整权威额度并记录待写回增量。返回 {状态, 额度}：
+// 0 表示键不存在需先加载，1 表示成功，2 表示 ARGV[3] 要求余额充足但额度不足
+var userQuotaAdjustScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if not current then
+	return {0, 0}
+end
+current = tonumber(current)
+local delta = tonumber(ARGV[1])
+if ARGV[3] == '1' and current + delta < 0 then
+	return {2, current}
+end
+local after = redis.call('INCRBY', KEYS[1], delta)
+redis.call('HINCRBY', KEYS[2], ARGV[2], delta)
+return {1, after}
+`)
+
+// userQuotaSettleScript 在增量写回数据库后将其从待写回哈希中扣除，归零时删除字段
+var userQuotaSettleScript = redis.NewScript(`
+local left = redis.call('HINCRBY', KEYS[1], ARGV[1], -tonumber(ARGV[2]))
+if left == 0 then
+	redis.call('HDEL', KEYS[1], ARGV[1])
+end
+return left
+`)
+
+// userQuotaUnlockScript 仅释放自己持有的写回锁
+var userQuotaUnlockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// UserQuotaDrift 描述一个权威额度与数据库不一致的用户
+type UserQuotaDrift struct {
+	UserId       int `json:"user_id"`
+	RedisQuota   int `json:"redis_quota"`
+	DBQuota      int `json:"db_quota"`
+	PendingDelta int `json:"pending_delta"`
+	// Drift = RedisQuota - (DBQuota + PendingDelta)
+	Drift int `json:"drift"`
+}
+
+func getUserQuotaSyncKey(userId int) string {
+	return fmt.Sprintf("user_quota:%d", userId)
+}
+
+// InitRedisQuotaSync 启动时先写回其他节点遗留的待写回增量并校正不一致的权威额度，
+// 之后按 REDIS_QUOTA_SYNC_INTERVAL（秒，默认 5）定期写回
+func InitRedisQuotaSync() {
+	FlushUserQuotaWriteBehind()
+	if drifts, err := CheckUserQuotaConsistency(true); err != nil {
+		common.SysError("failed to reconcile redis user quota: " + err.Error())
+	} else if len(drifts) > 0 {
+		common.SysLog(fmt.Sprintf("reconciled %d drifted redis user quotas", len(drifts)))
+	}
+	interval := common.GetEnvOrDefault("REDIS_QUOTA_SYNC_INTERVAL", 5)
+	if interval <= 0 {
+		interval = 5
+	}
+	gopool.Go(func() {
+		for {
+			time.Sleep(time.Duration(interval) * time.Second)
+			FlushUserQuotaWriteBehind()
+		}
+	})
+	common.SysLog(fmt.Sprintf("redis authoritative user quota enabled with write-behind interval %ds", interval))
+}
+
+func loadSyncedUserQuota(ctx context.Context, userId int) (int, error) {
+	var quota int
+	if err := DB.Model(&User{}).Where("id = ?", userId).Select("quota").Find(&quota).Error; err != nil {
+		return 0, err
+	}
+	return userQuotaLoadScript.Run(ctx, common.RDB, []string{getUserQuotaSyncKey(userId), userQuotaPendingKey},
+		quota, userId, max(common.RedisKeyCacheSeconds(), 60)).Int()
+}
+
+// getSyncedUserQuota 读取权威额度，不存在时从数据库加载
+func getSyncedUserQuota(userId int) (int, error) {
+	ctx := context.Background()
+	quota, err := common.RDB.Get(ctx, getUserQuotaSyncKey(userId)).Int()
+	if errors.Is(err, redis.Nil) {
+		return loadSyncedUserQuota(ctx, userId)
+	}
+	return quota, err
+}
+
+// invalidateUserQuotaAfterCommit 在事务内直接修改数据库额度并提交后调用：清除用户缓存与权威额度，
+// 下次访问时按“数据库额度 + 待写回增量”重新加载，使 Redis 权威额度与数据库保持一致
+func invalidateUserQuotaAfterCommit(userId int) {
+	if err := invalidateUserCache(userId); err != nil {
+		common.SysError(fmt.Sprintf("failed to invalidate quota cache for user %d: %s", userId, err.Error()))
+	}
+}
+
+// adjustSyncedUserQuota 在权威额度上原子加减 delta 并记录待写回增量；
+// requireBalance 为 true 时额度不足返回 ErrInsufficientUserQuota 且不做修改
+func adjustSyncedUserQuota(userId int, delta int, requireBalance bool) error {
+	ctx := context.Background()
+	keys := []string{getUserQuotaSyncKey(userId), userQuotaPendingKey}
+	flag := "0"
+	if requireBalance {
+		flag = "1"
+	}
+	for attempt := 0; attempt < 2; attempt++ {
+		result, err := userQuotaAdjustScript.Run(ctx, common.RDB, keys, delta, userId, flag).Int64Slice()
+		if err != nil {
+			return err
+		}
+		switch result[0] {
+		case 1:
+			gopool.Go(func() {
+				if err := common.RedisHIncrBy(getUserCacheKey(userId), "Quota", int64(delta)); err != nil {
+					common.SysLog("failed to update user quota cache: " + err.Error())
+				}
+				localDropUser(userId)
+			})
+			return nil
+		case 2:
+			return ErrInsufficientUserQuota
+		}
+		if _, err := loadSyncedUserQuota(ctx, userId); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("failed to load redis quota of user %d", userId)
+}
+
+// PreConsumeUserQuota 预扣用户额度。Redis 权威额度模式下检查与扣减在同一原子操作中完成，
+// 多节点并发预扣不会超扣；其余模式等同 DecreaseUserQuota
+func PreConsumeUserQuota(id int, quota int) error {
+	if !common.RedisQuotaEnabled {
+		return DecreaseUserQuota(id, quota, false)
+	}
+	if quota < 0 {
+		return errors.New("quota 不能为负数！")
+	}
+	if err := adjustSyncedUserQuota(id, -quota, true); err != nil {
+		return err
+	}
+	if quotaGrantsActive.Load() {
+		gopool.Go(func() {
+			if err := drainQuotaGrants(id, quota); err != nil {
+				common.SysLog("failed to drain quota grants: " + err.Error())
+			}
+		})
+	}
+	return nil
+}
+
+func lockUserQuotaSync(ctx context.Context) (string, bool, error) {
+	owner := fmt.Sprintf("%s-%d", common.NodeName, time.Now().UnixNano())
+	ok, err := common.RDB.SetNX(ctx, userQuotaFlushLockKey, owner, userQuotaFlushLockTTL).Result()
+	return owner, ok, err
+}
+
+func unlockUserQuotaSync(ctx context.Context, owner string) {
+	if err := userQuotaUnlockScript.Run(ctx, common.RDB, []string{userQuotaFlushLockKey}, owner).Err(); err != nil {
+		common.SysLog("failed to release user quota sync lock: " + err.Error())
+	}
+}
+
+// FlushUserQuotaWriteBehind 将待写回增量写入数据库。同一时刻只有一个节点执行；
+// 增量先落库再从待写回哈希扣除，中途失败时宁可少算余额也不会多算
+func FlushUserQuotaWriteBehind() {
+	if !common.RedisQuotaEnabled {
+		return
+	}
+	ctx := context.Background()
+	owner, ok, err := lockUserQuotaSync(ctx)
+	if err != nil {
+		common.SysError("failed to acquire user quota sync lock: " + err.Error())
+		return
+	}
+	if !ok {
+		return
+	}
+	defer unlockUserQuotaSync(ctx, owner)
+
+	pending, err := common.RDB.HGetAll(ctx, userQuotaPendingKey).Result()
+	if err != nil {
+		common.SysError("failed to read pending user quota: " + err.Error())
+		return
+	}
+	for field, value := range pending {
+		userId, err := strconv.Atoi(field)
+		if err != nil {
+			continue
+		}
+		delta, err := strconv.Atoi(value)
+		if err != nil {
+			continue
+		}
+		if delta != 0 {
+			if err := increaseUserQuota(userId, delta); err != nil {
+				common.SysError(fmt.Sprintf("failed to write back quota of user %d: %v", userId, err))
+				continue
+			}
+		}
+		if err := userQuotaSettleScript.Run(ctx, common.RDB, []string{userQuotaPendingKey}, field, delta).Err(); err != nil {
+			common.SysError(fmt.Sprintf("failed to settle pending quota of user %d: %v", userId, err))
+		}
+	}
+}
+
+// CheckUserQuotaConsistency 对比所有权威额度与“数据库额度 + 待写回增量”，返回不一致的用户。
+// fix 为 true 时删除不一致的权威额度，下次访问时按数据库重新加载
+func CheckUserQuotaConsistency(fix bool) ([]*UserQuotaDrift, error) {
+	drifts := make([]*UserQuotaDrift, 0)
+	if !common.RedisQuotaEnabled {
+		return drifts, nil
+	}
+	ctx := context.Background()
+	owner, ok, err := lockUserQuotaSync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrUserQuotaSyncBusy
+	}
+	defer unlockUserQuotaSync(ctx, owner)
+
+	var cursor uint64
+	for {
+		keys, next, err := common.RDB.Scan(ctx, cursor, "user_quota:*", userQuotaScanBatch).Result()
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			userId, err := strconv.Atoi(key[len("user_quota:"):])
+			if err != nil {
+				continue
+			}
+			redisQuota, err := common.RDB.Get(ctx, key).Int()
+			if errors.Is(err, redis.Nil) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			pending, err := common.RDB.HGet(ctx, userQuotaPendingKey, strconv.Itoa(userId)).Int()
+			if err != nil && !errors.Is(err, redis.Nil) {
+				return nil, err
+			}
+			var dbQuota int
+			if err := DB.Model(&User{}).Where("id = ?", userId).Select("quota").Find(&dbQuota).Error; err != nil {
+				return nil, err
+			}
+			if redisQuota == dbQuota+pending {
+				continue
+			}
+			drifts = append(drifts, &UserQuotaDrift{
+				UserId:       userId,
+				RedisQuota:   redisQuota,
+				DBQuota:      dbQuota,
+				PendingDelta: pending,
+				Drift:        redisQuota - dbQuota - pending,
+			})
+			if fix {
+				if err := common.RDB.Del(ctx, key).Err(); err != nil {
+					return nil, err
+				}
+			}
+		}
+		cursor = next
+		if cursor == 0 {
+			break
+		}
+	}
+	return drifts, nil
+}
@@ -0,0 +1,87 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreConsumeUserQuotaWithoutRedisQuota(t *testing.T) {
+	setupUserUpdateTestState(t)
+	overrideForTest(t, &common.RedisQuotaEnabled, false)
+
+	tests := []struct {
+		name      string
+		quota     int
+		amount    int
+		wantQuota int
+		wantErr   bool
+	}{
+		{name: "decreases db quota", quota: 1000, amount: 300, wantQuota: 700},
+		{name: "zero amount", quota: 1000, amount: 0, wantQuota: 1000},
+		{name: "negative amount", quota: 1000, amount: -1, wantQuota: 1000, wantErr: true},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := User{Id: i + 1, Username: tt.name, Password: "password", Status: common.UserStatusEnabled, Quota: tt.quota, AffCode: tt.name}
+			require.NoError(t, DB.Create(&user).Error)
+
+			err := PreConsumeUserQuota(user.Id, tt.amount)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			quota, err := GetUserQuota(user.Id, true)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantQuota, quota)
+		})
+	}
+
+	drifts, err := CheckUserQuotaConsistency(true)
+	require.NoError(t, err)
+	assert.Empty(t, drifts)
+}
+
+func TestPreConsumeUserQuotaWithRedisQuota(t *testing.T) {
+	setupUserUpdateTestState(t)
+	server := useMiniRedis(t)
+	overrideForTest(t, &common.RedisQuotaEnabled, true)
+	user := User{Id: 1, Username: "redis-quota", Password: "password", Status: common.UserStatusEnabled, Quota: 1000, AffCode: "redis-quota"}
+	require.NoError(t, DB.Create(&user).Error)
+	key := getUserQuotaSyncKey(user.Id)
+	dbQuota := func() int {
+		var quota int
+		require.NoError(t, DB.Model(&User{}).Where("id = ?", user.Id).Select("quota").Find(&quota).Error)
+		return quota
+	}
+
+	// 首次扣减从数据库加载权威额度，增量只记入待写回哈希
+	require.NoError(t, PreConsumeUserQuota(user.Id, 300))
+	server.CheckGet(t, key, "700")
+	assert.Equal(t, "-300", server.HGet(userQuotaPendingKey, "1"))
+	assert.Equal(t, 1000, dbQuota())
+
+	require.ErrorIs(t, PreConsumeUserQuota(user.Id, 800), ErrInsufficientUserQuota)
+	server.CheckGet(t, key, "700")
+
+	FlushUserQuotaWriteBehind()
+	assert.Equal(t, 700, dbQuota())
+	assert.Empty(t, server.HGet(userQuotaPendingKey, "1"))
+	drifts, err := CheckUserQuotaConsistency(false)
+	require.NoError(t, err)
+	assert.Empty(t, drifts)
+
+	require.NoError(t, server.Set(key, "50"))
+	drifts, err = CheckUserQuotaConsistency(true)
+	require.NoError(t, err)
+	require.Len(t, drifts, 1)
+	assert.Equal(t, -650, drifts[0].Drift)
+	assert.False(t, server.Exists(key))
+	quota, err := getSyncedUserQuota(user.Id)
+	require.NoError(t, err)
+	assert.Equal(t, 700, quota)
+}
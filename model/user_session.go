@@ -0,0 +1,166 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"gorm.io/gorm"
+)
+
+// 登录会话的最长有效期，与 session cookie 的 MaxAge 一致
+const UserSessionMaxAgeSeconds = 30 * 24 * 3600
+
+// 最近活跃时间的更新间隔，避免每个请求都写库
+const userSessionTouchIntervalSeconds = 60
+
+// UserSession 登录会话登记表。会话数据仍保存在签名 cookie 中，cookie 只携带会话 id，
+// 鉴权时校验该 id 仍在表中，删除记录即可让对应设备下线
+type UserSession struct {
+	Id           string `json:"id" gorm:"type:varchar(64);primaryKey"`
+	UserId       int    `json:"user_id" gorm:"index"`
+	Ip           string `json:"ip" gorm:"type:varchar(64)"`
+	UserAgent    string `json:"user_agent" gorm:"type:varchar(512)"`
+	CreatedAt    int64  `json:"created_at" gorm:"bigint"`
+	LastActiveAt int64  `json:"last_active_at" gorm:"bigint"`
+	ExpiresAt    int64  `json:"expires_at" gorm:"bigint;index"`
+}
+
+func (UserSession) TableName() string {
+	return "user_sessions"
+}
+
+func getUserSessionCacheKey(sessionId string) string {
+	return fmt.Sprintf("user_session:%s", sessionId)
+}
+
+func truncateSessionField(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	return strings.ToValidUTF8(s[:maxLen], "")
+}
+
+// CreateUserSession 登记新的登录会话
+func CreateUserSession(userId int, ip string, userAgent string) (*UserSession, error) {
+	sessionId, err := common.GenerateRandomCharsKey(32)
+	if err != nil {
+		return nil, err
+	}
+	now := common.GetTimestamp()
+	session := &UserSession{
+		Id:           sessionId,
+		UserId:       userId,
+		Ip:           truncateSessionField(ip, 64),
+		UserAgent:    truncateSessionField(userAgent, 512),
+		CreatedAt:    now,
+		LastActiveAt: now,
+		ExpiresAt:    now + UserSessionMaxAgeSeconds,
+	}
+	if err := DB.Create(session).Error; err != nil {
+		return nil, err
+	}
+	if common.RedisEnabled {
+		_ = common.RedisSet(getUserSessionCacheKey(sessionId), strconv.Itoa(userId), time.Duration(UserSessionMaxAgeSeconds)*time.Second)
+	}
+	return session, nil
+}
+
+// IsUserSessionValid 判断会话是否仍有效（未被注销且属于该用户）
+func IsUserSessionValid(sessionId string, userId int) (bool, error) {
+	if common.RedisEnabled {
+		if cached, err := common.RedisGet(getUserSessionCacheKey(sessionId)); err == nil {
+			return cached == strconv.Itoa(userId), nil
+		}
+	}
+	var session UserSession
+	err := DB.Where("id = ? AND expires_at > ?", sessionId, common.GetTimestamp()).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if common.RedisEnabled {
+		ttl := time.Duration(session.ExpiresAt-common.GetTimestamp()) * time.Second
+		_ = common.RedisSet(getUserSessionCacheKey(sessionId), strconv.Itoa(session.UserId), ttl)
+	}
+	return session.UserId == userId, nil
+}
+
+// TouchUserSession 更新会话的最近活跃时间和 IP，同一会话每分钟最多写一次
+func TouchUserSession(sessionId string, ip string) error {
+	now := common.GetTimestamp()
+	return DB.Model(&UserSession{}).
+		Where("id = ? AND last_active_at < ?", sessionId, now-userSessionTouchIntervalSeconds).
+		Updates(map[string]interface{}{"last_active_at": now, "ip": truncateSessionField(ip, 64)}).Error
+}
+
+// GetUserSessions 获取用户未过期的会话，按最近活跃时间倒序
+func GetUserSessions(userId int) ([]*UserSession, error) {
+	var sessions []*UserSession
+	err := DB.Where("user_id = ? AND expires_at > ?", userId, common.GetTimestamp()).
+		Order("last_active_at desc").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+func deleteUserSessionCache(sessionIds []string) {
+	if !common.RedisEnabled {
+		return
+	}
+	for _, sessionId := range sessionIds {
+		_ = common.RedisDel(getUserSessionCacheKey(sessionId))
+	}
+}
+
+// RevokeUserSession 注销用户的单个会话
+func RevokeUserSession(userId int, sessionId string) error {
+	result := DB.Where("id = ? AND user_id = ?", sessionId, userId).Delete(&UserSession{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("会话不存在")
+	}
+	deleteUserSessionCache([]string{sessionId})
+	return nil
+}
+
+// RevokeUserSessions 注销用户的全部会话，exceptSessionId 不为空时保留该会话，返回注销数量
+func RevokeUserSessions(userId int, exceptSessionId string) (int64, error) {
+	var sessionIds []string
+	query := DB.Model(&UserSession{}).Where("user_id = ?", userId)
+	if exceptSessionId != "" {
+		query = query.Where("id <> ?", exceptSessionId)
+	}
+	if err := query.Pluck("id", &sessionIds).Error; err != nil {
+		return 0, err
+	}
+	if len(sessionIds) == 0 {
+		return 0, nil
+	}
+	result := DB.Where("id IN ?", sessionIds).Delete(&UserSession{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	deleteUserSessionCache(sessionIds)
+	return result.RowsAffected, nil
+}
+
+// DeleteExpiredUserSessions 删除一批已过期的会话，返回删除条数
+func DeleteExpiredUserSessions(limit int) (int64, error) {
+	var ids []string
+	if err := DB.Model(&UserSession{}).Where("expires_at <= ?", common.GetTimestamp()).Order("expires_at").Limit(limit).Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	result := DB.Where("id IN ?", ids).Delete(&UserSession{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserSessionLifecycle(t *testing.T) {
+	t.Cleanup(func() { DB.Exec("DELETE FROM user_sessions") })
+
+	first, err := CreateUserSession(1, "10.0.0.1", "browser-a")
+	require.NoError(t, err)
+	second, err := CreateUserSession(1, "10.0.0.2", "browser-b")
+	require.NoError(t, err)
+	other, err := CreateUserSession(2, "10.0.0.3", "browser-c")
+	require.NoError(t, err)
+	assert.NotEqual(t, first.Id, second.Id)
+
+	valid, err := IsUserSessionValid(first.Id, 1)
+	require.NoError(t, err)
+	assert.True(t, valid)
+	valid, err = IsUserSessionValid(first.Id, 2)
+	require.NoError(t, err)
+	assert.False(t, valid, "a session id is bound to its user")
+
+	// activity is only written once the touch interval has passed
+	require.NoError(t, DB.Model(&UserSession{}).Where("id = ?", second.Id).Update("last_active_at", first.LastActiveAt-userSessionTouchIntervalSeconds-1).Error)
+	require.NoError(t, TouchUserSession(second.Id, "10.0.0.9"))
+	require.NoError(t, TouchUserSession(first.Id, "10.0.0.9"))
+	sessions, err := GetUserSessions(1)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	ips := map[string]string{sessions[0].Id: sessions[0].Ip, sessions[1].Id: sessions[1].Ip}
+	assert.Equal(t, "10.0.0.1", ips[first.Id])
+	assert.Equal(t, "10.0.0.9", ips[second.Id])
+
+	assert.Error(t, RevokeUserSession(2, first.Id), "users cannot revoke sessions of others")
+	require.NoError(t, RevokeUserSession(1, first.Id))
+	valid, err = IsUserSessionValid(first.Id, 1)
+	require.NoError(t, err)
+	assert.False(t, valid)
+
+	count, err := RevokeUserSessions(1, "")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, count)
+	valid, err = IsUserSessionValid(other.Id, 2)
+	require.NoError(t, err)
+	assert.True(t, valid, "other users keep their sessions")
+
+	require.NoError(t, DB.Model(&UserSession{}).Where("id = ?", other.Id).Update("expires_at", common.GetTimestamp()-1).Error)
+	valid, err = IsUserSessionValid(other.Id, 2)
+	require.NoError(t, err)
+	assert.False(t, valid, "expired sessions are invalid")
+	deleted, err := DeleteExpiredUserSessions(100)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, deleted)
+}
@@ -218,3 +218,20 @@ func TestResetUserPasswordByEmailRequiresSingleActiveMatch(t *testing.T) {
 	err = ResetUserPasswordByEmail("missing@example.com", "NewPassword123")
 	require.True(t, errors.Is(err, ErrEmailNotFound))
 }
+
+func TestSetUserQuotaWarningSentKeepsOtherSettings(t *testing.T) {
+	setupUserUpdateTestState(t)
+
+	user := User{Id: 1, Username: "quota-warning-user", Password: "password", Status: common.UserStatusEnabled}
+	user.SetSetting(dto.UserSetting{NotifyType: dto.NotifyTypeWebhook, WebhookUrl: "https://example.com/hook", QuotaWarningThreshold: 5000})
+	require.NoError(t, DB.Create(&user).Error)
+
+	for _, sent := range []bool{true, true, false} {
+		require.NoError(t, SetUserQuotaWarningSent(user.Id, sent))
+		setting, err := GetUserSetting(user.Id, true)
+		require.NoError(t, err)
+		assert.Equal(t, sent, setting.QuotaWarningSent)
+		assert.Equal(t, "https://example.com/hook", setting.WebhookUrl)
+		assert.EqualValues(t, 5000, setting.QuotaWarningThreshold)
+	}
+}
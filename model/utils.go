@@ -39,6 +39,12 @@ func InitBatchUpdater() {
 	})
 }
 
+// FlushBatchUpdates writes every pending batched quota delta immediately. It
+// is called during shutdown so deltas buffered since the last tick are not lost.
+func FlushBatchUpdates() {
+	batchUpdate()
+}
+
 func addNewRecord(type_ int, id int, value int) {
 	batchUpdateLocks[type_].Lock()
 	defer batchUpdateLocks[type_].Unlock()
@@ -0,0 +1,147 @@
+package model
+
+import (
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"gorm.io/gorm"
+)
+
+const (
+	WebhookEndpointStatusEnabled  = 1
+	WebhookEndpointStatusDisabled = 2
+)
+
+const webhookDeliveryErrorMaxLength = 512
+
+// WebhookEndpoint 管理员配置的运维事件推送地址
+type WebhookEndpoint struct {
+	Id        int    `json:"id"`
+	Name      string `json:"name" gorm:"type:varchar(64)"`
+	Url       string `json:"url" gorm:"type:varchar(1024)"`
+	Secret    string `json:"secret,omitempty" gorm:"type:varchar(255)"` // 签名密钥，配置后请求头带 X-Webhook-Signature
+	Events    string `json:"events" gorm:"type:text"`                   // 订阅的事件，逗号分隔，为空表示订阅全部事件
+	Status    int    `json:"status" gorm:"default:1"`
+	CreatedAt int64  `json:"created_at" gorm:"bigint"`
+	UpdatedAt int64  `json:"updated_at" gorm:"bigint"`
+}
+
+// WebhookDelivery 单次事件推送（含重试）的结果记录
+type WebhookDelivery struct {
+	Id         int    `json:"id"`
+	EndpointId int    `json:"endpoint_id" gorm:"index:idx_webhook_delivery_endpoint,priority:1"`
+	EventId    string `json:"event_id" gorm:"type:varchar(64)"`
+	Event      string `json:"event" gorm:"type:varchar(64);index"`
+	Payload    string `json:"payload" gorm:"type:text"`
+	Success    bool   `json:"success"`
+	Attempts   int    `json:"attempts"`
+	Error      string `json:"error" gorm:"type:varchar(512)"`
+	DurationMs int64  `json:"duration_ms"`
+	CreatedAt  int64  `json:"created_at" gorm:"bigint;index:idx_webhook_delivery_endpoint,priority:2"`
+}
+
+// EventList 返回订阅的事件列表
+func (endpoint *WebhookEndpoint) EventList() []string {
+	events := make([]string, 0)
+	for _, event := range strings.Split(endpoint.Events, ",") {
+		if event = strings.TrimSpace(event); event != "" {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// Subscribes 判断该地址是否订阅了指定事件
+func (endpoint *WebhookEndpoint) Subscribes(event string) bool {
+	events := endpoint.EventList()
+	if len(events) == 0 {
+		return true
+	}
+	for _, subscribed := range events {
+		if subscribed == event {
+			return true
+		}
+	}
+	return false
+}
+
+func (endpoint *WebhookEndpoint) Insert() error {
+	now := common.GetTimestamp()
+	endpoint.CreatedAt = now
+	endpoint.UpdatedAt = now
+	return DB.Create(endpoint).Error
+}
+
+// Update 更新可编辑字段；Secret 为空时保留原密钥
+func (endpoint *WebhookEndpoint) Update() error {
+	endpoint.UpdatedAt = common.GetTimestamp()
+	columns := []string{"name", "url", "events", "status", "updated_at"}
+	if endpoint.Secret != "" {
+		columns = append(columns, "secret")
+	}
+	return DB.Model(endpoint).Select(columns).Updates(endpoint).Error
+}
+
+// DeleteWebhookEndpointById 删除推送地址及其推送记录
+func DeleteWebhookEndpointById(id int) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("endpoint_id = ?", id).Delete(&WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&WebhookEndpoint{}, id).Error
+	})
+}
+
+func GetWebhookEndpointById(id int) (*WebhookEndpoint, error) {
+	endpoint := &WebhookEndpoint{}
+	err := DB.First(endpoint, "id = ?", id).Error
+	return endpoint, err
+}
+
+func GetAllWebhookEndpoints() ([]*WebhookEndpoint, error) {
+	var endpoints []*WebhookEndpoint
+	err := DB.Order("id").Find(&endpoints).Error
+	return endpoints, err
+}
+
+// GetWebhookEndpointsForEvent 返回已启用且订阅了指定事件的推送地址
+func GetWebhookEndpointsForEvent(event string) ([]*WebhookEndpoint, error) {
+	var endpoints []*WebhookEndpoint
+	if err := DB.Where("status = ?", WebhookEndpointStatusEnabled).Order("id").Find(&endpoints).Error; err != nil {
+		return nil, err
+	}
+	subscribed := make([]*WebhookEndpoint, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		if endpoint.Subscribes(event) {
+			subscribed = append(subscribed, endpoint)
+		}
+	}
+	return subscribed, nil
+}
+
+func RecordWebhookDelivery(delivery *WebhookDelivery) error {
+	if delivery.CreatedAt == 0 {
+		delivery.CreatedAt = common.GetTimestamp()
+	}
+	if runes := []rune(delivery.Error); len(runes) > webhookDeliveryErrorMaxLength {
+		delivery.Error = string(runes[:webhookDeliveryErrorMaxLength])
+	}
+	return DB.Create(delivery).Error
+}
+
+// GetWebhookDeliveries 分页查询推送记录，endpointId 为 0 时查询全部地址
+func GetWebhookDeliveries(endpointId int, event string, startIdx int, num int) (deliveries []*WebhookDelivery, total int64, err error) {
+	query := DB.Model(&WebhookDelivery{})
+	if endpointId > 0 {
+		query = query.Where("endpoint_id = ?", endpointId)
+	}
+	if event != "" {
+		query = query.Where("event = ?", event)
+	}
+	if err = query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = query.Order("id desc").Limit(num).Offset(startIdx).Find(&deliveries).Error
+	return deliveries, total, err
+}
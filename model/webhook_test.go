@@ -0,0 +1,62 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookEndpoint_EventFilterAndUpdate(t *testing.T) {
+	truncateTables(t)
+
+	all := &WebhookEndpoint{Name: "all", Url: "https://example.com/all", Secret: "s1"}
+	topup := &WebhookEndpoint{Name: "topup", Url: "https://example.com/topup", Events: "topup.completed, redemption.used", Status: WebhookEndpointStatusEnabled}
+	disabled := &WebhookEndpoint{Name: "disabled", Url: "https://example.com/off", Status: WebhookEndpointStatusDisabled}
+	for _, endpoint := range []*WebhookEndpoint{all, topup, disabled} {
+		require.NoError(t, endpoint.Insert())
+	}
+
+	tests := []struct {
+		event string
+		want  []string
+	}{
+		{event: "topup.completed", want: []string{"all", "topup"}},
+		{event: "channel.auto_disabled", want: []string{"all"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.event, func(t *testing.T) {
+			endpoints, err := GetWebhookEndpointsForEvent(tt.event)
+			require.NoError(t, err)
+			names := make([]string, 0, len(endpoints))
+			for _, endpoint := range endpoints {
+				names = append(names, endpoint.Name)
+			}
+			assert.Equal(t, tt.want, names)
+		})
+	}
+
+	// secret 留空时保留原密钥
+	update := &WebhookEndpoint{Id: all.Id, Name: "renamed", Url: all.Url, Status: WebhookEndpointStatusEnabled}
+	require.NoError(t, update.Update())
+	reloaded, err := GetWebhookEndpointById(all.Id)
+	require.NoError(t, err)
+	assert.Equal(t, "renamed", reloaded.Name)
+	assert.Equal(t, "s1", reloaded.Secret)
+
+	// 删除地址时一并删除推送记录
+	require.NoError(t, RecordWebhookDelivery(&WebhookDelivery{EndpointId: all.Id, Event: "topup.completed", Success: true, Attempts: 1}))
+	require.NoError(t, RecordWebhookDelivery(&WebhookDelivery{EndpointId: topup.Id, Event: "topup.completed", Attempts: 4, Error: "timeout"}))
+	deliveries, total, err := GetWebhookDeliveries(0, "topup.completed", 0, 10)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, total)
+	assert.Len(t, deliveries, 2)
+
+	require.NoError(t, DeleteWebhookEndpointById(all.Id))
+	_, total, err = GetWebhookDeliveries(all.Id, "", 0, 10)
+	require.NoError(t, err)
+	assert.Zero(t, total)
+	_, total, err = GetWebhookDeliveries(topup.Id, "", 0, 10)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+}
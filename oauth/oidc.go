@@ -1,19 +1,39 @@
 package oauth
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/i18n"
 	"github.com/QuantumNous/new-api/logger"
 	"github.com/QuantumNous/new-api/model"
 	"github.com/QuantumNous/new-api/setting/system_setting"
 	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+// oidcDiscoveryTTL is how long a fetched discovery document is reused.
+const oidcDiscoveryTTL = time.Hour
+
+type oidcDiscovery struct {
+	TokenEndpoint    string `json:"token_endpoint"`
+	UserInfoEndpoint string `json:"userinfo_endpoint"`
+}
+
+var (
+	oidcDiscoveryMu        sync.Mutex
+	oidcDiscoveryURL       string
+	oidcDiscoveryFetchedAt time.Time
+	oidcDiscoveryCached    oidcDiscovery
 )
 
 func init() {
@@ -40,6 +60,45 @@ type oidcUser struct {
 	Picture           string `json:"picture"`
 }
 
+// resolveOIDCEndpoints returns the token and userinfo endpoints. Endpoints left
+// empty in the settings are taken from the discovery document at WellKnown.
+func resolveOIDCEndpoints(ctx context.Context) (string, string, error) {
+	settings := system_setting.GetOIDCSettings()
+	if (settings.TokenEndpoint != "" && settings.UserInfoEndpoint != "") || settings.WellKnown == "" {
+		return settings.TokenEndpoint, settings.UserInfoEndpoint, nil
+	}
+
+	oidcDiscoveryMu.Lock()
+	defer oidcDiscoveryMu.Unlock()
+	if oidcDiscoveryURL != settings.WellKnown || time.Since(oidcDiscoveryFetchedAt) > oidcDiscoveryTTL {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, settings.WellKnown, nil)
+		if err != nil {
+			return "", "", err
+		}
+		req.Header.Set("Accept", "application/json")
+		client := http.Client{
+			Timeout: 5 * time.Second,
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			return "", "", err
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return "", "", fmt.Errorf("discovery request failed: status=%d", res.StatusCode)
+		}
+		var discovery oidcDiscovery
+		if err := common.DecodeJson(res.Body, &discovery); err != nil {
+			return "", "", err
+		}
+		oidcDiscoveryURL = settings.WellKnown
+		oidcDiscoveryFetchedAt = time.Now()
+		oidcDiscoveryCached = discovery
+	}
+	return cmp.Or(settings.TokenEndpoint, oidcDiscoveryCached.TokenEndpoint),
+		cmp.Or(settings.UserInfoEndpoint, oidcDiscoveryCached.UserInfoEndpoint), nil
+}
+
 func (p *OIDCProvider) GetName() string {
 	return "OIDC"
 }
@@ -56,6 +115,11 @@ func (p *OIDCProvider) ExchangeToken(ctx context.Context, code string, c *gin.Co
 	logger.LogDebug(ctx, "[OAuth-OIDC] ExchangeToken: code=%s...", code[:min(len(code), 10)])
 
 	settings := system_setting.GetOIDCSettings()
+	tokenEndpoint, _, err := resolveOIDCEndpoints(ctx)
+	if err != nil {
+		logger.LogError(ctx, fmt.Sprintf("[OAuth-OIDC] ExchangeToken discovery error: %s", err.Error()))
+		return nil, NewOAuthErrorWithRaw(i18n.MsgOAuthConnectFailed, map[string]any{"Provider": "OIDC"}, err.Error())
+	}
 	redirectUri := fmt.Sprintf("%s/oauth/oidc", system_setting.ServerAddress)
 	values := url.Values{}
 	values.Set("client_id", settings.ClientId)
@@ -64,9 +128,9 @@ func (p *OIDCProvider) ExchangeToken(ctx context.Context, code string, c *gin.Co
 	values.Set("grant_type", "authorization_code")
 	values.Set("redirect_uri", redirectUri)
 
-	logger.LogDebug(ctx, "[OAuth-OIDC] ExchangeToken: token_endpoint=%s, redirect_uri=%s", settings.TokenEndpoint, redirectUri)
+	logger.LogDebug(ctx, "[OAuth-OIDC] ExchangeToken: token_endpoint=%s, redirect_uri=%s", tokenEndpoint, redirectUri)
 
-	req, err := http.NewRequestWithContext(ctx, "POST", settings.TokenEndpoint, strings.NewReader(values.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", tokenEndpoint, strings.NewReader(values.Encode()))
 	if err != nil {
 		return nil, err
 	}
@@ -111,10 +175,15 @@ func (p *OIDCProvider) ExchangeToken(ctx context.Context, code string, c *gin.Co
 
 func (p *OIDCProvider) GetUserInfo(ctx context.Context, token *OAuthToken) (*OAuthUser, error) {
 	settings := system_setting.GetOIDCSettings()
+	_, userInfoEndpoint, err := resolveOIDCEndpoints(ctx)
+	if err != nil {
+		logger.LogError(ctx, fmt.Sprintf("[OAuth-OIDC] GetUserInfo discovery error: %s", err.Error()))
+		return nil, NewOAuthErrorWithRaw(i18n.MsgOAuthConnectFailed, map[string]any{"Provider": "OIDC"}, err.Error())
+	}
 
-	logger.LogDebug(ctx, "[OAuth-OIDC] GetUserInfo: userinfo_endpoint=%s", settings.UserInfoEndpoint)
+	logger.LogDebug(ctx, "[OAuth-OIDC] GetUserInfo: userinfo_endpoint=%s", userInfoEndpoint)
 
-	req, err := http.NewRequestWithContext(ctx, "GET", settings.UserInfoEndpoint, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", userInfoEndpoint, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -137,25 +206,37 @@ func (p *OIDCProvider) GetUserInfo(ctx context.Context, token *OAuthToken) (*OAu
 		return nil, NewOAuthError(i18n.MsgOAuthGetUserErr, nil)
 	}
 
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
 	var oidcUser oidcUser
-	err = json.NewDecoder(res.Body).Decode(&oidcUser)
+	err = common.Unmarshal(body, &oidcUser)
 	if err != nil {
 		logger.LogError(ctx, fmt.Sprintf("[OAuth-OIDC] GetUserInfo decode error: %s", err.Error()))
 		return nil, err
 	}
+	username := oidcUser.PreferredUsername
+	if claim := strings.TrimSpace(settings.UsernameClaim); claim != "" {
+		username = gjson.GetBytes(body, claim).String()
+	}
 
 	if oidcUser.OpenID == "" || oidcUser.Email == "" {
 		logger.LogError(ctx, fmt.Sprintf("[OAuth-OIDC] GetUserInfo failed: empty fields (sub=%s, email=%s)", oidcUser.OpenID, oidcUser.Email))
 		return nil, NewOAuthError(i18n.MsgOAuthUserInfoEmpty, map[string]any{"Provider": "OIDC"})
 	}
 
-	logger.LogDebug(ctx, "[OAuth-OIDC] GetUserInfo success: sub=%s, username=%s, name=%s, email=%s", oidcUser.OpenID, oidcUser.PreferredUsername, oidcUser.Name, oidcUser.Email)
+	logger.LogDebug(ctx, "[OAuth-OIDC] GetUserInfo success: sub=%s, username=%s, name=%s, email=%s", oidcUser.OpenID, username, oidcUser.Name, oidcUser.Email)
 
 	return &OAuthUser{
 		ProviderUserID: oidcUser.OpenID,
-		Username:       oidcUser.PreferredUsername,
+		Username:       username,
 		DisplayName:    oidcUser.Name,
 		Email:          oidcUser.Email,
+		Extra: map[string]any{
+			// some providers send email_verified as the string "true"
+			"email_verified": gjson.GetBytes(body, "email_verified").Bool(),
+		},
 	}, nil
 }
 
@@ -175,3 +256,25 @@ func (p *OIDCProvider) SetProviderUserID(user *model.User, providerUserID string
 func (p *OIDCProvider) GetProviderPrefix() string {
 	return "oidc_"
 }
+
+func (p *OIDCProvider) LinkableUser(oauthUser *OAuthUser) *model.User {
+	if !system_setting.GetOIDCSettings().LinkByVerifiedEmail {
+		return nil
+	}
+	if verified, _ := oauthUser.Extra["email_verified"].(bool); !verified {
+		return nil
+	}
+	user, err := model.GetUniqueUserByEmail(oauthUser.Email)
+	if err != nil || user.OidcId != "" {
+		return nil
+	}
+	return user
+}
+
+func (p *OIDCProvider) AutoProvisionEnabled() bool {
+	return system_setting.GetOIDCSettings().AutoProvision
+}
+
+func (p *OIDCProvider) DefaultGroup() string {
+	return strings.TrimSpace(system_setting.GetOIDCSettings().DefaultGroup)
+}
@@ -0,0 +1,49 @@
+package oauth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/setting/system_setting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOIDCGetUserInfoUsesDiscoveryAndUsernameClaim(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"token_endpoint":"` + server.URL + `/token","userinfo_endpoint":"` + server.URL + `/userinfo"}`))
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer access", r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"sub":"u-1","email":"a@example.com","email_verified":"true","preferred_username":"alice","attrs":{"login":"corp-alice"}}`))
+	})
+
+	settings := system_setting.GetOIDCSettings()
+	original := *settings
+	t.Cleanup(func() { *settings = original })
+
+	tests := []struct {
+		name         string
+		claim        string
+		wantUsername string
+	}{
+		{name: "default preferred_username", wantUsername: "alice"},
+		{name: "nested claim", claim: "attrs.login", wantUsername: "corp-alice"},
+		{name: "missing claim", claim: "upn", wantUsername: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*settings = system_setting.OIDCSettings{WellKnown: server.URL + "/.well-known/openid-configuration", UsernameClaim: tt.claim}
+			user, err := (&OIDCProvider{}).GetUserInfo(context.Background(), &OAuthToken{AccessToken: "access"})
+			require.NoError(t, err)
+			assert.Equal(t, "u-1", user.ProviderUserID)
+			assert.Equal(t, tt.wantUsername, user.Username)
+			assert.Equal(t, true, user.Extra["email_verified"])
+		})
+	}
+}
@@ -34,3 +34,17 @@ type Provider interface {
 	// GetProviderPrefix returns the prefix for auto-generated usernames (e.g., "github_")
 	GetProviderPrefix() string
 }
+
+// ProvisioningProvider is implemented by providers that control how a first
+// login is mapped to an account: linking an existing user or provisioning a
+// new one with provider-specific rules.
+type ProvisioningProvider interface {
+	// LinkableUser returns an existing unbound user the login should be linked to, or nil
+	LinkableUser(oauthUser *OAuthUser) *model.User
+
+	// AutoProvisionEnabled reports whether accounts may be created while registration is disabled
+	AutoProvisionEnabled() bool
+
+	// DefaultGroup returns the group of provisioned users, empty for the system default
+	DefaultGroup() string
+}
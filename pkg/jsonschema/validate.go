@@ -0,0 +1,249 @@
+// Package jsonschema validates decoded JSON values against the subset of JSON
+// Schema used by structured outputs: type, enum, const, properties, required,
+// additionalProperties, items, anyOf, oneOf, allOf, local $ref, and string,
+// number and array bounds. Unknown keywords are ignored.
+package jsonschema
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Validate reports the first violation of schema by value. Both are expected
+// as decoded into any by a JSON decoder: objects as map[string]any, arrays as
+// []any and numbers as float64.
+func Validate(schema any, value any) error {
+	root, _ := schema.(map[string]any)
+	return validate(root, schema, value, "$", 0)
+}
+
+// maxDepth bounds nested validation so that self-referencing schemas cannot
+// recurse forever.
+const maxDepth = 128
+
+func validate(root map[string]any, schema any, value any, path string, depth int) error {
+	if depth > maxDepth {
+		return fmt.Errorf("%s: schema nesting exceeds %d levels", path, maxDepth)
+	}
+	switch s := schema.(type) {
+	case nil:
+		return nil
+	case bool:
+		if !s {
+			return fmt.Errorf("%s: no value is allowed here", path)
+		}
+		return nil
+	case map[string]any:
+		return validateObjectSchema(root, s, value, path, depth)
+	default:
+		return fmt.Errorf("%s: invalid schema of type %T", path, schema)
+	}
+}
+
+func validateObjectSchema(root map[string]any, schema map[string]any, value any, path string, depth int) error {
+	if ref, ok := schema["$ref"].(string); ok {
+		resolved, err := resolveRef(root, ref)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		return validate(root, resolved, value, path, depth+1)
+	}
+	if t, ok := schema["type"]; ok && !matchesType(t, value) {
+		return fmt.Errorf("%s: expected %v, got %s", path, t, typeName(value))
+	}
+	if enum, ok := schema["enum"].([]any); ok {
+		if !slices.ContainsFunc(enum, func(item any) bool { return reflect.DeepEqual(item, value) }) {
+			return fmt.Errorf("%s: value is not one of %v", path, enum)
+		}
+	}
+	if constant, ok := schema["const"]; ok && !reflect.DeepEqual(constant, value) {
+		return fmt.Errorf("%s: value must be %v", path, constant)
+	}
+	if subschemas, ok := schema["allOf"].([]any); ok {
+		for _, subschema := range subschemas {
+			if err := validate(root, subschema, value, path, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	for _, keyword := range []string{"anyOf", "oneOf"} {
+		subschemas, ok := schema[keyword].([]any)
+		if !ok {
+			continue
+		}
+		matched := 0
+		for _, subschema := range subschemas {
+			if validate(root, subschema, value, path, depth+1) == nil {
+				matched++
+			}
+		}
+		if matched == 0 || (keyword == "oneOf" && matched > 1) {
+			return fmt.Errorf("%s: value does not match %s (%d of %d subschemas matched)", path, keyword, matched, len(subschemas))
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		properties, _ := schema["properties"].(map[string]any)
+		if required, ok := schema["required"].([]any); ok {
+			for _, name := range required {
+				if key, ok := name.(string); ok {
+					if _, present := v[key]; !present {
+						return fmt.Errorf("%s: missing required property %q", path, key)
+					}
+				}
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		// sorted for a deterministic first error
+		slices.Sort(keys)
+		for _, key := range keys {
+			propertyPath := path + "." + key
+			if subschema, ok := properties[key]; ok {
+				if err := validate(root, subschema, v[key], propertyPath, depth+1); err != nil {
+					return err
+				}
+				continue
+			}
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					return fmt.Errorf("%s: additional property is not allowed", propertyPath)
+				}
+			case map[string]any:
+				if err := validate(root, additional, v[key], propertyPath, depth+1); err != nil {
+					return err
+				}
+			}
+		}
+	case []any:
+		if minItems, ok := schema["minItems"].(float64); ok && float64(len(v)) < minItems {
+			return fmt.Errorf("%s: expected at least %v items, got %d", path, minItems, len(v))
+		}
+		if maxItems, ok := schema["maxItems"].(float64); ok && float64(len(v)) > maxItems {
+			return fmt.Errorf("%s: expected at most %v items, got %d", path, maxItems, len(v))
+		}
+		if items, ok := schema["items"]; ok {
+			for i, item := range v {
+				if err := validate(root, items, item, fmt.Sprintf("%s[%d]", path, i), depth+1); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		length := float64(utf8.RuneCountInString(v))
+		if minLength, ok := schema["minLength"].(float64); ok && length < minLength {
+			return fmt.Errorf("%s: expected at least %v characters", path, minLength)
+		}
+		if maxLength, ok := schema["maxLength"].(float64); ok && length > maxLength {
+			return fmt.Errorf("%s: expected at most %v characters", path, maxLength)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			re, err := regexp.Compile(pattern)
+			if err == nil && !re.MatchString(v) {
+				return fmt.Errorf("%s: value does not match pattern %q", path, pattern)
+			}
+		}
+	case float64:
+		if minimum, ok := schema["minimum"].(float64); ok && v < minimum {
+			return fmt.Errorf("%s: value must be >= %v", path, minimum)
+		}
+		if maximum, ok := schema["maximum"].(float64); ok && v > maximum {
+			return fmt.Errorf("%s: value must be <= %v", path, maximum)
+		}
+		if minimum, ok := schema["exclusiveMinimum"].(float64); ok && v <= minimum {
+			return fmt.Errorf("%s: value must be > %v", path, minimum)
+		}
+		if maximum, ok := schema["exclusiveMaximum"].(float64); ok && v >= maximum {
+			return fmt.Errorf("%s: value must be < %v", path, maximum)
+		}
+	}
+	return nil
+}
+
+func matchesType(schemaType any, value any) bool {
+	switch t := schemaType.(type) {
+	case string:
+		switch t {
+		case "object":
+			_, ok := value.(map[string]any)
+			return ok
+		case "array":
+			_, ok := value.([]any)
+			return ok
+		case "string":
+			_, ok := value.(string)
+			return ok
+		case "boolean":
+			_, ok := value.(bool)
+			return ok
+		case "null":
+			return value == nil
+		case "number":
+			_, ok := value.(float64)
+			return ok
+		case "integer":
+			number, ok := value.(float64)
+			return ok && number == math.Trunc(number)
+		}
+		return true
+	case []any:
+		for _, item := range t {
+			if matchesType(item, value) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func typeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// resolveRef resolves a JSON pointer into the root schema such as
+// "#/$defs/step". Remote references are not supported.
+func resolveRef(root map[string]any, ref string) (any, error) {
+	pointer, ok := strings.CutPrefix(ref, "#")
+	if !ok {
+		return nil, fmt.Errorf("unsupported $ref %q", ref)
+	}
+	var current any = root
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		if token == "" {
+			continue
+		}
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		object, ok := current.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+		if current, ok = object[token]; !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+	}
+	return current, nil
+}
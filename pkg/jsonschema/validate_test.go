@@ -0,0 +1,85 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	const schema = `{
+		"type": "object",
+		"properties": {
+			"name": {"type": "string", "minLength": 1, "pattern": "^[a-z]+$"},
+			"age": {"type": "integer", "minimum": 0},
+			"role": {"enum": ["admin", "user"]},
+			"tags": {"type": "array", "items": {"type": "string"}, "maxItems": 2},
+			"steps": {"type": "array", "items": {"$ref": "#/$defs/step"}},
+			"note": {"type": ["string", "null"]}
+		},
+		"required": ["name", "age"],
+		"additionalProperties": false,
+		"$defs": {
+			"step": {"type": "object", "properties": {"n": {"type": "number"}}, "required": ["n"]}
+		}
+	}`
+	tests := []struct {
+		name    string
+		value   string
+		wantErr string
+	}{
+		{name: "valid", value: `{"name":"ann","age":3,"role":"user","tags":["a"],"steps":[{"n":1.5}],"note":null}`},
+		{name: "missing required", value: `{"name":"ann"}`, wantErr: `$: missing required property "age"`},
+		{name: "wrong type", value: `{"name":"ann","age":"3"}`, wantErr: "$.age: expected integer, got string"},
+		{name: "not an integer", value: `{"name":"ann","age":1.5}`, wantErr: "$.age: expected integer, got number"},
+		{name: "below minimum", value: `{"name":"ann","age":-1}`, wantErr: "$.age: value must be >= 0"},
+		{name: "enum", value: `{"name":"ann","age":1,"role":"root"}`, wantErr: "$.role: value is not one of [admin user]"},
+		{name: "pattern", value: `{"name":"Ann","age":1}`, wantErr: `$.name: value does not match pattern "^[a-z]+$"`},
+		{name: "too many items", value: `{"name":"ann","age":1,"tags":["a","b","c"]}`, wantErr: "$.tags: expected at most 2 items, got 3"},
+		{name: "ref", value: `{"name":"ann","age":1,"steps":[{"n":1},{}]}`, wantErr: `$.steps[1]: missing required property "n"`},
+		{name: "additional property", value: `{"name":"ann","age":1,"extra":true}`, wantErr: "$.extra: additional property is not allowed"},
+		{name: "not an object", value: `[]`, wantErr: "$: expected object, got array"},
+	}
+	var decodedSchema any
+	require.NoError(t, json.Unmarshal([]byte(schema), &decodedSchema))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var value any
+			require.NoError(t, json.Unmarshal([]byte(tt.value), &value))
+			err := Validate(decodedSchema, value)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestValidateCombinators(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  string
+		value   string
+		wantErr bool
+	}{
+		{name: "anyOf matches one", schema: `{"anyOf":[{"type":"string"},{"type":"number"}]}`, value: `1`},
+		{name: "anyOf matches none", schema: `{"anyOf":[{"type":"string"},{"type":"number"}]}`, value: `true`, wantErr: true},
+		{name: "oneOf matches two", schema: `{"oneOf":[{"type":"number"},{"type":"integer"}]}`, value: `1`, wantErr: true},
+		{name: "allOf", schema: `{"allOf":[{"type":"string"},{"maxLength":2}]}`, value: `"abc"`, wantErr: true},
+		{name: "const", schema: `{"const":"x"}`, value: `"y"`, wantErr: true},
+		{name: "false schema", schema: `false`, value: `1`, wantErr: true},
+		{name: "self reference", schema: `{"$ref":"#"}`, value: `1`, wantErr: true},
+		{name: "unresolvable ref", schema: `{"$ref":"#/$defs/missing"}`, value: `1`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var schema, value any
+			require.NoError(t, json.Unmarshal([]byte(tt.schema), &schema))
+			require.NoError(t, json.Unmarshal([]byte(tt.value), &value))
+			assert.Equal(t, tt.wantErr, Validate(schema, value) != nil)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package perfmetrics
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 	"time"
 
@@ -24,10 +25,23 @@ func flushLoop() {
 }
 
 func flushCompletedBuckets() {
-	currentBucket := bucketStart(time.Now().Unix())
+	flushBucketsBefore(bucketStart(time.Now().Unix()))
+}
+
+// FlushAll persists every hot bucket, including the one still being filled.
+// It is called during shutdown so the final partial bucket is not lost; the
+// upsert is additive, so another node writing the same bucket stays correct.
+func FlushAll() {
+	if !perf_metrics_setting.GetSetting().Enabled {
+		return
+	}
+	flushBucketsBefore(math.MaxInt64)
+}
+
+func flushBucketsBefore(cutoff int64) {
 	hotBuckets.Range(func(key, value any) bool {
 		k := key.(bucketKey)
-		if k.bucketTs >= currentBucket {
+		if k.bucketTs >= cutoff {
 			return true
 		}
 
@@ -42,6 +42,14 @@ func RecordRelaySample(info *relaycommon.RelayInfo, success bool, outputTokens i
 	if generationMs <= 0 {
 		generationMs = latencyMs
 	}
+	if success && info.ChannelMeta != nil {
+		// least-latency channel selection ranks streams by time to first token
+		channelLatencyMs := latencyMs
+		if hasTtft {
+			channelLatencyMs = ttftMs
+		}
+		model.RecordChannelLatency(info.ChannelId, channelLatencyMs)
+	}
 	Record(Sample{
 		Model:        info.OriginModelName,
 		Group:        info.UsingGroup,
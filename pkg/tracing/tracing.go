@@ -0,0 +1,104 @@
+// Package tracing wires OpenTelemetry distributed tracing into the relay path.
+//
+// Tracing is off unless TRACING_ENABLED=true. Spans are exported over OTLP/HTTP;
+// the endpoint and headers come from the standard OTEL_EXPORTER_OTLP_* env
+// vars. While disabled every helper degrades to the global no-op tracer, so
+// instrumented call sites cost next to nothing.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/QuantumNous/new-api"
+
+var provider *sdktrace.TracerProvider
+
+// Init installs the OTLP exporter and global tracer provider. It is a no-op
+// when tracing is disabled.
+func Init() error {
+	if !common.GetEnvOrDefaultBool("TRACING_ENABLED", false) {
+		return nil
+	}
+	exporter, err := otlptracehttp.New(context.Background())
+	if err != nil {
+		return fmt.Errorf("create otlp exporter: %w", err)
+	}
+	serviceName := common.GetEnvOrDefaultString("OTEL_SERVICE_NAME", "new-api")
+	res := resource.NewSchemaless(
+		semconv.ServiceName(serviceName),
+		semconv.ServiceVersion(common.Version),
+		semconv.ServiceInstanceID(common.NodeName),
+	)
+	sampleRatio := 1.0
+	if value := common.GetEnvOrDefaultString("TRACING_SAMPLE_RATIO", ""); value != "" {
+		ratio, err := strconv.ParseFloat(value, 64)
+		if err != nil || ratio < 0 || ratio > 1 {
+			return fmt.Errorf("invalid TRACING_SAMPLE_RATIO %q: must be between 0 and 1", value)
+		}
+		sampleRatio = ratio
+	}
+	provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	common.SysLog(fmt.Sprintf("OpenTelemetry tracing enabled: service=%s sample_ratio=%g", serviceName, sampleRatio))
+	return nil
+}
+
+// Shutdown flushes buffered spans. It is called during graceful shutdown.
+func Shutdown(ctx context.Context) {
+	if provider == nil {
+		return
+	}
+	if err := provider.Shutdown(ctx); err != nil {
+		common.SysError(fmt.Sprintf("failed to shutdown tracer provider: %v", err))
+	}
+}
+
+// Start begins a child span of whatever span ctx carries.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err on the span, if any, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// StartSpan begins a child span of the request span carried by c. The
+// request context is not replaced, so sibling spans started later still nest
+// under the request span rather than under this one.
+func StartSpan(c *gin.Context, name string, attrs ...attribute.KeyValue) trace.Span {
+	ctx := context.Background()
+	if c != nil && c.Request != nil {
+		ctx = c.Request.Context()
+	}
+	_, span := Start(ctx, name, attrs...)
+	return span
+}
@@ -373,3 +373,59 @@ func TestOpenAIChatRequestToClaudeMessages_ClaudeOpus48ThinkingUsesAdaptiveHighE
 	require.Nil(t, claudeRequest.TopP)
 	require.Nil(t, claudeRequest.TopK)
 }
+
+func TestOpenAIChatRequestToClaudeMessages_ToolSchemas(t *testing.T) {
+	request := dto.GeneralOpenAIRequest{
+		Model:    "claude-sonnet-4",
+		Messages: []dto.Message{{Role: "user", Content: "hello"}},
+		Tools: []dto.ToolCallRequest{
+			{Type: "function", Function: dto.FunctionRequest{Name: "get_time"}},
+			{Type: "function", Function: dto.FunctionRequest{
+				Name: "lookup",
+				Parameters: map[string]any{
+					"type":                 "object",
+					"properties":           map[string]any{"q": map[string]any{"type": "string"}},
+					"required":             []any{"q"},
+					"additionalProperties": false,
+				},
+			}},
+		},
+	}
+
+	claudeRequest, err := relayconvert.OpenAIChatRequestToClaudeMessages(nil, request)
+	require.NoError(t, err)
+	require.Len(t, claudeRequest.Tools, 2)
+	noParams := claudeRequest.Tools.([]any)[0].(*dto.Tool)
+	assert.Equal(t, "get_time", noParams.Name)
+	assert.Equal(t, map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}, noParams.InputSchema)
+	lookup := claudeRequest.Tools.([]any)[1].(*dto.Tool)
+	assert.Equal(t, []any{"q"}, lookup.InputSchema["required"])
+	assert.Equal(t, false, lookup.InputSchema["additionalProperties"])
+}
+
+func TestStreamResponseClaude2OpenAI_ToolCallIndexesCountToolCallsOnly(t *testing.T) {
+	text := "let me check"
+	events := []*dto.ClaudeResponse{
+		{Type: "content_block_start", Index: commonPointer(0), ContentBlock: &dto.ClaudeMediaMessage{Type: "text", Text: commonPointer("")}},
+		{Type: "content_block_delta", Index: commonPointer(0), Delta: &dto.ClaudeMediaMessage{Type: "text_delta", Text: &text}},
+		{Type: "content_block_start", Index: commonPointer(1), ContentBlock: &dto.ClaudeMediaMessage{Type: "tool_use", Id: "toolu_1", Name: "lookup"}},
+		{Type: "content_block_delta", Index: commonPointer(1), Delta: &dto.ClaudeMediaMessage{Type: "input_json_delta", PartialJson: commonPointer(`{"q":"a"}`)}},
+		{Type: "content_block_start", Index: commonPointer(2), ContentBlock: &dto.ClaudeMediaMessage{Type: "tool_use", Id: "toolu_2", Name: "lookup"}},
+		{Type: "content_block_delta", Index: commonPointer(2), Delta: &dto.ClaudeMediaMessage{Type: "input_json_delta", PartialJson: commonPointer(`{"q":"b"}`)}},
+	}
+	claudeInfo := &ClaudeResponseInfo{Usage: &dto.Usage{}}
+
+	var indexes []int
+	var ids []string
+	for _, event := range events {
+		response := StreamResponseClaude2OpenAI(event)
+		require.NotNil(t, response)
+		require.True(t, FormatClaudeResponseInfo(event, response, claudeInfo))
+		for _, toolCall := range response.Choices[0].Delta.ToolCalls {
+			indexes = append(indexes, *toolCall.Index)
+			ids = append(ids, toolCall.ID)
+		}
+	}
+	assert.Equal(t, []int{0, 0, 1, 1}, indexes)
+	assert.Equal(t, []string{"toolu_1", "", "toolu_2", ""}, ids)
+}
@@ -0,0 +1,133 @@
+package mock
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	relayconstant "github.com/QuantumNous/new-api/relay/constant"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMockRelayInfo(config *dto.MockChannelConfig) *relaycommon.RelayInfo {
+	return &relaycommon.RelayInfo{
+		RequestId: "req-1",
+		RelayMode: relayconstant.RelayModeChatCompletions,
+		ChannelMeta: &relaycommon.ChannelMeta{
+			UpstreamModelName:    "mock-gpt",
+			ChannelOtherSettings: dto.ChannelOtherSettings{Mock: config},
+		},
+	}
+}
+
+func TestNewResponsePlan(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       *dto.MockChannelConfig
+		maxTokens    int
+		wantTokens   int
+		wantFirst    time.Duration
+		wantInterval time.Duration
+	}{
+		{name: "defaults", wantTokens: defaultCompletionTokens, wantFirst: defaultFirstTokenLatencyMs * time.Millisecond, wantInterval: time.Second / defaultTokensPerSecond},
+		{name: "configured", config: &dto.MockChannelConfig{FirstTokenLatencyMs: 10, TokensPerSecond: 1000, CompletionTokens: 5}, wantTokens: 5, wantFirst: 10 * time.Millisecond, wantInterval: time.Millisecond},
+		{name: "bounded by max tokens", config: &dto.MockChannelConfig{CompletionTokens: 100}, maxTokens: 7, wantTokens: 7, wantFirst: defaultFirstTokenLatencyMs * time.Millisecond, wantInterval: time.Second / defaultTokensPerSecond},
+		{name: "capped", config: &dto.MockChannelConfig{CompletionTokens: maxCompletionTokens * 2}, wantTokens: maxCompletionTokens, wantFirst: defaultFirstTokenLatencyMs * time.Millisecond, wantInterval: time.Second / defaultTokensPerSecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := newResponsePlan(newMockRelayInfo(tt.config), tt.maxTokens)
+
+			assert.Equal(t, "chatcmpl-mock-req-1", plan.id)
+			assert.Equal(t, "mock-gpt", plan.model)
+			assert.Equal(t, tt.wantTokens, plan.completionTokens)
+			assert.Equal(t, tt.wantFirst, plan.firstTokenLatency)
+			assert.Equal(t, tt.wantInterval, plan.tokenInterval)
+		})
+	}
+}
+
+func TestConvertOpenAIRequestCapturesMaxTokens(t *testing.T) {
+	info := newMockRelayInfo(nil)
+	maxTokens := uint(12)
+	maxCompletionTokens := uint(3)
+
+	adaptor := &Adaptor{}
+	_, err := adaptor.ConvertOpenAIRequest(nil, info, &dto.GeneralOpenAIRequest{MaxTokens: &maxTokens})
+	require.NoError(t, err)
+	assert.Equal(t, 12, adaptor.maxTokens)
+
+	adaptor = &Adaptor{}
+	_, err = adaptor.ConvertOpenAIRequest(nil, info, &dto.GeneralOpenAIRequest{MaxTokens: &maxTokens, MaxCompletionTokens: &maxCompletionTokens})
+	require.NoError(t, err)
+	assert.Equal(t, 3, adaptor.maxTokens)
+
+	info.RelayMode = relayconstant.RelayModeEmbeddings
+	_, err = (&Adaptor{}).ConvertOpenAIRequest(nil, info, &dto.GeneralOpenAIRequest{})
+	require.ErrorContains(t, err, "only supports chat completions")
+}
+
+func TestResponsePlanJSONResponse(t *testing.T) {
+	plan := newResponsePlan(newMockRelayInfo(&dto.MockChannelConfig{FirstTokenLatencyMs: 1, TokensPerSecond: 10000, CompletionTokens: 12}), 0)
+
+	resp, err := plan.jsonResponse(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	var response dto.OpenAITextResponse
+	require.NoError(t, common.Unmarshal(body, &response))
+	require.Len(t, response.Choices, 1)
+	assert.Equal(t, "The quick brown fox jumps over the lazy dog.The quick", response.Choices[0].Message.StringContent())
+	assert.Equal(t, 12, response.Usage.CompletionTokens)
+	assert.Equal(t, response.Usage.PromptTokens+12, response.Usage.TotalTokens)
+}
+
+func TestResponsePlanStreamResponse(t *testing.T) {
+	plan := newResponsePlan(newMockRelayInfo(&dto.MockChannelConfig{FirstTokenLatencyMs: 1, TokensPerSecond: 10000, CompletionTokens: 3}), 0)
+
+	resp := plan.streamResponse(context.Background())
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	events := strings.Split(strings.TrimSuffix(string(body), "\n\n"), "\n\n")
+	// 3 token chunks, the finish chunk, the usage chunk and [DONE]
+	require.Len(t, events, 6)
+	assert.Equal(t, "data: [DONE]", events[5])
+
+	var content strings.Builder
+	for _, event := range events[:3] {
+		var chunk dto.ChatCompletionsStreamResponse
+		require.NoError(t, common.UnmarshalJsonStr(strings.TrimPrefix(event, "data: "), &chunk))
+		require.Len(t, chunk.Choices, 1)
+		content.WriteString(chunk.Choices[0].Delta.GetContentString())
+	}
+	assert.Equal(t, "The quick brown", content.String())
+
+	var usageChunk dto.ChatCompletionsStreamResponse
+	require.NoError(t, common.UnmarshalJsonStr(strings.TrimPrefix(events[4], "data: "), &usageChunk))
+	require.NotNil(t, usageChunk.Usage)
+	assert.Equal(t, 3, usageChunk.Usage.CompletionTokens)
+}
+
+func TestResponsePlanStopsWhenClientGoesAway(t *testing.T) {
+	plan := newResponsePlan(newMockRelayInfo(&dto.MockChannelConfig{FirstTokenLatencyMs: 60000}), 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := plan.jsonResponse(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	resp := plan.streamResponse(ctx)
+	_, err = io.ReadAll(resp.Body)
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,24 @@
+package mock
+
+var ModelList = []string{
+	"mock-gpt",
+	"mock-gpt-fast",
+}
+
+var ChannelName = "mock"
+
+const (
+	defaultFirstTokenLatencyMs = 200
+	defaultTokensPerSecond     = 50
+	defaultCompletionTokens    = 64
+
+	// maxCompletionTokens bounds a single synthetic response so a misconfigured
+	// benchmark cannot hold a connection open indefinitely.
+	maxCompletionTokens = 8192
+)
+
+// mockVocabulary is cycled to build deterministic output: the same config and
+// request always produce byte-identical completions.
+var mockVocabulary = []string{
+	"The", " quick", " brown", " fox", " jumps", " over", " the", " lazy", " dog", ".",
+}
@@ -0,0 +1,181 @@
+package mock
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+)
+
+type responsePlan struct {
+	id                string
+	model             string
+	created           int64
+	promptTokens      int
+	completionTokens  int
+	firstTokenLatency time.Duration
+	tokenInterval     time.Duration
+}
+
+func newResponsePlan(info *relaycommon.RelayInfo, maxTokens int) responsePlan {
+	config := dto.MockChannelConfig{}
+	if info.ChannelOtherSettings.Mock != nil {
+		config = *info.ChannelOtherSettings.Mock
+	}
+	if config.FirstTokenLatencyMs <= 0 {
+		config.FirstTokenLatencyMs = defaultFirstTokenLatencyMs
+	}
+	if config.TokensPerSecond <= 0 {
+		config.TokensPerSecond = defaultTokensPerSecond
+	}
+	if config.CompletionTokens <= 0 {
+		config.CompletionTokens = defaultCompletionTokens
+	}
+	completionTokens := min(config.CompletionTokens, maxCompletionTokens)
+	if maxTokens > 0 {
+		completionTokens = min(completionTokens, maxTokens)
+	}
+	return responsePlan{
+		id:                "chatcmpl-mock-" + info.RequestId,
+		model:             info.UpstreamModelName,
+		created:           time.Now().Unix(),
+		promptTokens:      info.GetEstimatePromptTokens(),
+		completionTokens:  completionTokens,
+		firstTokenLatency: time.Duration(config.FirstTokenLatencyMs) * time.Millisecond,
+		tokenInterval:     time.Second / time.Duration(config.TokensPerSecond),
+	}
+}
+
+func (p responsePlan) usage() *dto.Usage {
+	return &dto.Usage{
+		PromptTokens:     p.promptTokens,
+		CompletionTokens: p.completionTokens,
+		TotalTokens:      p.promptTokens + p.completionTokens,
+	}
+}
+
+func (p responsePlan) tokenText(i int) string {
+	return mockVocabulary[i%len(mockVocabulary)]
+}
+
+func (p responsePlan) jsonResponse(ctx context.Context) (*http.Response, error) {
+	total := p.firstTokenLatency + p.tokenInterval*time.Duration(p.completionTokens)
+	if err := sleepContext(ctx, total); err != nil {
+		return nil, err
+	}
+	var content strings.Builder
+	for i := 0; i < p.completionTokens; i++ {
+		content.WriteString(p.tokenText(i))
+	}
+	response := dto.OpenAITextResponse{
+		Id:      p.id,
+		Model:   p.model,
+		Object:  "chat.completion",
+		Created: p.created,
+		Choices: []dto.OpenAITextResponseChoice{{
+			Index:        0,
+			Message:      dto.Message{Role: "assistant", Content: content.String()},
+			FinishReason: "stop",
+		}},
+		Usage: *p.usage(),
+	}
+	body, err := common.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+	return newMockHTTPResponse("application/json", io.NopCloser(bytes.NewReader(body))), nil
+}
+
+// streamResponse emits one SSE chunk per token at the configured rate. The
+// writer stops as soon as the client goes away so abandoned benchmark
+// requests do not keep generating.
+func (p responsePlan) streamResponse(ctx context.Context) *http.Response {
+	reader, writer := io.Pipe()
+	go func() {
+		err := p.writeStream(ctx, writer)
+		_ = writer.CloseWithError(err)
+	}()
+	return newMockHTTPResponse("text/event-stream", reader)
+}
+
+func (p responsePlan) writeStream(ctx context.Context, w io.Writer) error {
+	if err := sleepContext(ctx, p.firstTokenLatency); err != nil {
+		return err
+	}
+	for i := 0; i < p.completionTokens; i++ {
+		if i > 0 {
+			if err := sleepContext(ctx, p.tokenInterval); err != nil {
+				return err
+			}
+		}
+		delta := dto.ChatCompletionsStreamResponseChoiceDelta{}
+		if i == 0 {
+			delta.Role = "assistant"
+		}
+		delta.SetContentString(p.tokenText(i))
+		if err := p.writeChunk(w, []dto.ChatCompletionsStreamResponseChoice{{Delta: delta}}, nil); err != nil {
+			return err
+		}
+	}
+	finishReason := "stop"
+	if err := p.writeChunk(w, []dto.ChatCompletionsStreamResponseChoice{{FinishReason: &finishReason}}, nil); err != nil {
+		return err
+	}
+	if err := p.writeChunk(w, []dto.ChatCompletionsStreamResponseChoice{}, p.usage()); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "data: [DONE]\n\n")
+	return err
+}
+
+func (p responsePlan) writeChunk(w io.Writer, choices []dto.ChatCompletionsStreamResponseChoice, usage *dto.Usage) error {
+	chunk := dto.ChatCompletionsStreamResponse{
+		Id:      p.id,
+		Object:  "chat.completion.chunk",
+		Created: p.created,
+		Model:   p.model,
+		Choices: choices,
+		Usage:   usage,
+	}
+	data, err := common.Marshal(chunk)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+	return err
+}
+
+func newMockHTTPResponse(contentType string, body io.ReadCloser) *http.Response {
+	header := make(http.Header)
+	header.Set("Content-Type", contentType)
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     header,
+		Body:       body,
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
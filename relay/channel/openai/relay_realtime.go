@@ -2,6 +2,7 @@ package openai
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/dto"
@@ -9,6 +10,7 @@ import (
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
 	"github.com/QuantumNous/new-api/relay/helper"
 	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
 	"github.com/QuantumNous/new-api/types"
 
 	"github.com/bytedance/gopkg/util/gopool"
@@ -201,13 +203,37 @@ func OpenaiRealtimeHandler(c *gin.Context, info *relaycommon.RelayInfo) (*types.
 		}
 	})
 
-	select {
-	case <-clientClosed:
-	case <-targetClosed:
-	case err := <-errChan:
-		//return service.OpenAIErrorWrapper(err, "realtime_error", http.StatusInternalServerError), nil
-		logger.LogError(c, "realtime error: "+err.Error())
-	case <-c.Done():
+	// sendChan/receiveChan 记录双向消息活动，超过空闲时长无消息时结束会话
+	var idleTimer *time.Timer
+	var idleTimeout <-chan time.Time
+	idleDuration := time.Duration(operation_setting.GetRealtimeSetting().IdleTimeoutSeconds) * time.Second
+	if idleDuration > 0 {
+		idleTimer = time.NewTimer(idleDuration)
+		defer idleTimer.Stop()
+		idleTimeout = idleTimer.C
+	}
+
+waitLoop:
+	for {
+		select {
+		case <-clientClosed:
+			break waitLoop
+		case <-targetClosed:
+			break waitLoop
+		case err := <-errChan:
+			//return service.OpenAIErrorWrapper(err, "realtime_error", http.StatusInternalServerError), nil
+			logger.LogError(c, "realtime error: "+err.Error())
+			break waitLoop
+		case <-c.Done():
+			break waitLoop
+		case <-sendChan:
+			resetRealtimeIdleTimer(idleTimer, idleDuration)
+		case <-receiveChan:
+			resetRealtimeIdleTimer(idleTimer, idleDuration)
+		case <-idleTimeout:
+			logger.LogInfo(c, "realtime session closed after idle timeout")
+			break waitLoop
+		}
 	}
 
 	if usage.TotalTokens != 0 {
@@ -223,6 +249,19 @@ func OpenaiRealtimeHandler(c *gin.Context, info *relaycommon.RelayInfo) (*types.
 	return nil, sumUsage
 }
 
+func resetRealtimeIdleTimer(timer *time.Timer, d time.Duration) {
+	if timer == nil {
+		return
+	}
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+	timer.Reset(d)
+}
+
 func preConsumeUsage(ctx *gin.Context, info *relaycommon.RelayInfo, usage *dto.RealtimeUsage, totalUsage *dto.RealtimeUsage) error {
 	if usage == nil || totalUsage == nil {
 		return fmt.Errorf("invalid usage pointer")
@@ -18,6 +18,9 @@ import (
 
 var negativeIndexRegexp = regexp.MustCompile(`\.(-\d+)`)
 
+// overrideTemplatePlaceholderRegexp matches {{path}} placeholders of set_template values.
+var overrideTemplatePlaceholderRegexp = regexp.MustCompile(`\{\{\s*([^{}]+?)\s*\}\}`)
+
 const (
 	paramOverrideContextRequestHeaders = "request_headers"
 	paramOverrideContextHeaderOverride = "header_override"
@@ -56,7 +59,7 @@ type ConditionOperation struct {
 
 type ParamOperation struct {
 	Path       string               `json:"path"`
-	Mode       string               `json:"mode"` // delete, set, move, copy, prepend, append, trim_prefix, trim_suffix, ensure_prefix, ensure_suffix, trim_space, to_lower, to_upper, replace, regex_replace, return_error, prune_objects, set_header, delete_header, copy_header, move_header, pass_headers, sync_fields
+	Mode       string               `json:"mode"` // delete, set, move, copy, prepend, append, trim_prefix, trim_suffix, ensure_prefix, ensure_suffix, trim_space, to_lower, to_upper, replace, regex_replace, clamp, set_template, return_error, prune_objects, set_header, delete_header, copy_header, move_header, pass_headers, sync_fields
 	Value      interface{}          `json:"value"`
 	KeepOrigin bool                 `json:"keep_origin"`
 	From       string               `json:"from,omitempty"`
@@ -349,6 +352,11 @@ func buildParamOverrideAuditLine(mode, path, from, to string, value interface{})
 			return ""
 		}
 		return fmt.Sprintf("%s %s from %s to %s", mode, path, from, to)
+	case "clamp", "set_template":
+		if path == "" {
+			return ""
+		}
+		return fmt.Sprintf("%s %s = %s", mode, path, formatParamOverrideAuditValue(value))
 	case "set_header":
 		if path == "" {
 			return ""
@@ -878,6 +886,37 @@ func applyOperations(jsonData []byte, operations []ParamOperation, conditionCont
 				}
 				auditRecorder.recordOperation("regex_replace", path, op.From, op.To, nil)
 			}
+		case "clamp":
+			minValue, maxValue, parseErr := parseClampBounds(op.Value)
+			if parseErr != nil {
+				return nil, parseErr
+			}
+			for _, path := range opPaths {
+				var clamped bool
+				result, clamped, err = clampNumberValue(result, path, minValue, maxValue)
+				if err != nil {
+					break
+				}
+				if clamped {
+					auditRecorder.recordOperation("clamp", path, "", "", gjson.GetBytes(result, path).Value())
+				}
+			}
+		case "set_template":
+			template, ok := op.Value.(string)
+			if !ok {
+				return nil, fmt.Errorf("set_template value must be a string")
+			}
+			rendered := renderOverrideTemplate(template, result, contextJSON)
+			for _, path := range opPaths {
+				if op.KeepOrigin && gjson.GetBytes(result, path).Exists() {
+					continue
+				}
+				result, err = sjson.SetBytes(result, path, rendered)
+				if err != nil {
+					break
+				}
+				auditRecorder.recordOperation("set_template", path, "", "", rendered)
+			}
 		case "return_error":
 			auditRecorder.recordOperation("return_error", op.Path, "", "", op.Value)
 			returnErr, parseErr := parseParamOverrideReturnError(op.Value)
@@ -1564,7 +1603,7 @@ func copyValue(data []byte, fromPath, toPath string) ([]byte, error) {
 
 func isPathBasedOperation(mode string) bool {
 	switch mode {
-	case "delete", "set", "prepend", "append", "trim_prefix", "trim_suffix", "ensure_prefix", "ensure_suffix", "trim_space", "to_lower", "to_upper", "replace", "regex_replace", "prune_objects":
+	case "delete", "set", "prepend", "append", "trim_prefix", "trim_suffix", "ensure_prefix", "ensure_suffix", "trim_space", "to_lower", "to_upper", "replace", "regex_replace", "clamp", "set_template", "prune_objects":
 		return true
 	default:
 		return false
@@ -1783,6 +1822,85 @@ func regexReplaceStringValue(data []byte, path, pattern, replacement string) ([]
 	return sjson.SetBytes(data, path, re.ReplaceAllString(current.String(), replacement))
 }
 
+// parseClampBounds accepts either a number (the upper bound) or an object
+// with optional "min" and "max" numbers.
+func parseClampBounds(value interface{}) (*float64, *float64, error) {
+	toFloat := func(raw interface{}) (*float64, error) {
+		switch typed := raw.(type) {
+		case nil:
+			return nil, nil
+		case float64:
+			return &typed, nil
+		case int:
+			f := float64(typed)
+			return &f, nil
+		case int64:
+			f := float64(typed)
+			return &f, nil
+		default:
+			return nil, fmt.Errorf("clamp bound must be a number, got %v", raw)
+		}
+	}
+	var minValue, maxValue *float64
+	var err error
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		if minValue, err = toFloat(typed["min"]); err != nil {
+			return nil, nil, err
+		}
+		if maxValue, err = toFloat(typed["max"]); err != nil {
+			return nil, nil, err
+		}
+	default:
+		if maxValue, err = toFloat(typed); err != nil {
+			return nil, nil, err
+		}
+	}
+	if minValue == nil && maxValue == nil {
+		return nil, nil, fmt.Errorf("clamp requires min or max")
+	}
+	if minValue != nil && maxValue != nil && *minValue > *maxValue {
+		return nil, nil, fmt.Errorf("clamp min is greater than max")
+	}
+	return minValue, maxValue, nil
+}
+
+// clampNumberValue limits the number at path to [min, max]. Missing or
+// non-numeric fields are left alone so a cap never injects a parameter the
+// client did not send.
+func clampNumberValue(data []byte, path string, minValue, maxValue *float64) ([]byte, bool, error) {
+	current := gjson.GetBytes(data, path)
+	if current.Type != gjson.Number {
+		return data, false, nil
+	}
+	number := current.Float()
+	switch {
+	case maxValue != nil && number > *maxValue:
+		number = *maxValue
+	case minValue != nil && number < *minValue:
+		number = *minValue
+	default:
+		return data, false, nil
+	}
+	result, err := sjson.SetBytes(data, path, number)
+	return result, err == nil, err
+}
+
+// renderOverrideTemplate replaces {{path}} placeholders with values read from
+// the request body, falling back to the override context (model,
+// original_model, request_path, ...) like conditions do. Unknown
+// placeholders render as empty strings.
+func renderOverrideTemplate(template string, data []byte, contextJSON string) string {
+	return overrideTemplatePlaceholderRegexp.ReplaceAllStringFunc(template, func(placeholder string) string {
+		path := overrideTemplatePlaceholderRegexp.FindStringSubmatch(placeholder)[1]
+		value := gjson.GetBytes(data, path)
+		if !value.Exists() && contextJSON != "" {
+			value = gjson.Get(contextJSON, path)
+		}
+		return value.String()
+	})
+}
+
 type pruneObjectsOptions struct {
 	conditions []ConditionOperation
 	logic      string
@@ -183,6 +183,95 @@ func TestApplyParamOverrideRegexReplace(t *testing.T) {
 	assertJSONEqual(t, `{"model":"openai/gpt-4o-mini","temperature":0.7}`, string(out))
 }
 
+func TestApplyParamOverrideClamp(t *testing.T) {
+	// clamp example:
+	// {"operations":[{"path":"temperature","mode":"clamp","value":1},{"path":"top_p","mode":"clamp","value":{"min":0.1,"max":0.9}}]}
+	input := []byte(`{"model":"gpt-4","temperature":1.8,"top_p":0.01,"presence_penalty":0.5}`)
+	override := map[string]interface{}{
+		"operations": []interface{}{
+			map[string]interface{}{
+				"path":  "temperature",
+				"mode":  "clamp",
+				"value": 1.0,
+			},
+			map[string]interface{}{
+				"path":  "top_p",
+				"mode":  "clamp",
+				"value": map[string]interface{}{"min": 0.1, "max": 0.9},
+			},
+			map[string]interface{}{
+				"path":  "presence_penalty",
+				"mode":  "clamp",
+				"value": map[string]interface{}{"max": 1.0},
+			},
+			map[string]interface{}{
+				"path":  "max_tokens",
+				"mode":  "clamp",
+				"value": 4096.0,
+			},
+		},
+	}
+
+	out, err := ApplyParamOverride(input, override, nil)
+	if err != nil {
+		t.Fatalf("ApplyParamOverride returned error: %v", err)
+	}
+	assertJSONEqual(t, `{"model":"gpt-4","temperature":1,"top_p":0.1,"presence_penalty":0.5}`, string(out))
+}
+
+func TestApplyParamOverrideClampInvalidBounds(t *testing.T) {
+	for _, value := range []interface{}{
+		"1",
+		map[string]interface{}{},
+		map[string]interface{}{"min": 2.0, "max": 1.0},
+	} {
+		override := map[string]interface{}{
+			"operations": []interface{}{
+				map[string]interface{}{
+					"path":  "temperature",
+					"mode":  "clamp",
+					"value": value,
+				},
+			},
+		}
+		_, err := ApplyParamOverride([]byte(`{"temperature":0.7}`), override, nil)
+		if err == nil {
+			t.Fatalf("expected error for clamp value %v", value)
+		}
+	}
+}
+
+func TestApplyParamOverrideSetTemplate(t *testing.T) {
+	// set_template example:
+	// {"operations":[{"path":"model","mode":"set_template","value":"hosted/{{original_model}}-{{user}}"}]}
+	input := []byte(`{"model":"gpt-4","user":"alice"}`)
+	override := map[string]interface{}{
+		"operations": []interface{}{
+			map[string]interface{}{
+				"path":  "model",
+				"mode":  "set_template",
+				"value": "hosted/{{ original_model }}-{{user}}{{missing}}",
+			},
+			map[string]interface{}{
+				"path":        "user",
+				"mode":        "set_template",
+				"value":       "{{model}}",
+				"keep_origin": true,
+			},
+		},
+	}
+	ctx := map[string]interface{}{
+		"model":          "upstream-gpt-4",
+		"original_model": "gpt-4",
+	}
+
+	out, err := ApplyParamOverride(input, override, ctx)
+	if err != nil {
+		t.Fatalf("ApplyParamOverride returned error: %v", err)
+	}
+	assertJSONEqual(t, `{"model":"hosted/gpt-4-alice","user":"alice"}`, string(out))
+}
+
 func TestApplyParamOverrideReplaceRequiresFrom(t *testing.T) {
 	// replace requires from example:
 	// {"operations":[{"path":"model","mode":"replace"}]}
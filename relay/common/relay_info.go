@@ -99,6 +99,8 @@ type RelayInfo struct {
 	//SendLastReasoningResponse bool
 	IsStream               bool
 	IsGeminiBatchEmbedding bool
+	EmbeddingCacheHit      bool // 嵌入请求命中响应缓存，未请求上游
+	ChatCacheHit           bool // 对话请求命中响应缓存，未请求上游
 	IsPlayground           bool
 	UsePrice               bool
 	RelayMode              int
@@ -233,9 +235,7 @@ func (info *RelayInfo) InitChannelMeta(c *gin.Context) {
 		channelMeta.ChannelOtherSettings = channelOtherSettings
 	}
 
-	if streamSupportedChannels[channelMeta.ChannelType] {
-		channelMeta.SupportStreamOptions = true
-	}
+	channelMeta.SupportStreamOptions = supportStreamOptions(channelMeta.ChannelType, channelMeta.ChannelOtherSettings.StreamOptionsMode)
 
 	info.ChannelMeta = channelMeta
 
@@ -342,6 +342,18 @@ var streamSupportedChannels = map[int]bool{
 	constant.ChannelTypeMiniMax:        true,
 	constant.ChannelTypeSiliconFlow:    true,
 	constant.ChannelTypeAdvancedCustom: true,
+	constant.ChannelTypeMock:           true,
+}
+
+// supportStreamOptions 渠道设置优先，未设置时按渠道类型判断
+func supportStreamOptions(channelType int, mode dto.StreamOptionsMode) bool {
+	switch mode {
+	case dto.StreamOptionsModeEnabled:
+		return true
+	case dto.StreamOptionsModeDisabled:
+		return false
+	}
+	return streamSupportedChannels[channelType]
 }
 
 func GenRelayInfoWs(c *gin.Context, ws *websocket.Conn) *RelayInfo {
@@ -3,6 +3,8 @@ package common
 import (
 	"testing"
 
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
 	"github.com/QuantumNous/new-api/types"
 	"github.com/stretchr/testify/require"
 )
@@ -38,3 +40,22 @@ func TestRelayInfoGetFinalRequestRelayFormatNilReceiver(t *testing.T) {
 	var info *RelayInfo
 	require.Equal(t, types.RelayFormat(""), info.GetFinalRequestRelayFormat())
 }
+
+func TestSupportStreamOptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		channelType int
+		mode        dto.StreamOptionsMode
+		want        bool
+	}{
+		{"openai default", constant.ChannelTypeOpenAI, "", true},
+		{"unsupported type default", constant.ChannelTypePerplexity, "", false},
+		{"forced on unsupported type", constant.ChannelTypePerplexity, dto.StreamOptionsModeEnabled, true},
+		{"disabled on openai", constant.ChannelTypeOpenAI, dto.StreamOptionsModeDisabled, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, supportStreamOptions(tt.channelType, tt.mode))
+		})
+	}
+}
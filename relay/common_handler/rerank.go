@@ -65,6 +65,10 @@ func RerankHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Respo
 		if err != nil {
 			return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
 		}
+		// vLLM 等上游可能不返回 usage，按估算的输入 token 计费
+		if jinaResp.Usage.TotalTokens == 0 {
+			jinaResp.Usage.TotalTokens = info.GetEstimatePromptTokens()
+		}
 		jinaResp.Usage.PromptTokens = jinaResp.Usage.TotalTokens
 	}
 
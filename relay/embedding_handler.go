@@ -24,6 +24,21 @@ func EmbeddingHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *
 		return types.NewErrorWithStatusCode(fmt.Errorf("invalid request type, expected *dto.EmbeddingRequest, got %T", info.Request), types.ErrorCodeInvalidRequest, http.StatusBadRequest, types.ErrOptionWithSkipRetry())
 	}
 
+	// 命中缓存时直接返回缓存的响应，不请求上游
+	var cacheKey string
+	if service.ShouldUseEmbeddingCache(c) {
+		if key, err := service.EmbeddingCacheKey(*embeddingReq); err == nil {
+			cacheKey = key
+			if entry, ok := service.GetEmbeddingCacheEntry(cacheKey); ok {
+				info.EmbeddingCacheHit = true
+				c.Header(service.EmbeddingCacheHeader, "hit")
+				c.Data(http.StatusOK, "application/json", []byte(entry.Body))
+				service.PostTextConsumeQuota(c, info, &entry.Usage, nil)
+				return nil
+			}
+		}
+	}
+
 	request, err := common.DeepCopy(embeddingReq)
 	if err != nil {
 		return types.NewError(fmt.Errorf("failed to copy request to EmbeddingRequest: %w", err), types.ErrorCodeInvalidRequest, types.ErrOptionWithSkipRetry())
@@ -83,12 +98,27 @@ func EmbeddingHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *
 		}
 	}
 
+	var captureWriter *service.EmbeddingCaptureWriter
+	if cacheKey != "" {
+		c.Header(service.EmbeddingCacheHeader, "miss")
+		captureWriter = service.NewEmbeddingCaptureWriter(c.Writer)
+		c.Writer = captureWriter
+	}
 	usage, newAPIError := adaptor.DoResponse(c, httpResp, info)
+	if captureWriter != nil {
+		c.Writer = captureWriter.ResponseWriter
+	}
 	if newAPIError != nil {
 		// reset status code 重置状态码
 		service.ResetStatusCode(newAPIError, statusCodeMappingStr)
 		return newAPIError
 	}
+	if captureWriter != nil {
+		// 没有用量的响应无法在命中时计费，不缓存
+		if body, ok := captureWriter.Body(); ok && body != "" && usage.(*dto.Usage) != nil {
+			service.SetEmbeddingCacheEntry(cacheKey, service.EmbeddingCacheEntry{Body: body, Usage: *usage.(*dto.Usage)})
+		}
+	}
 	service.PostTextConsumeQuota(c, info, usage.(*dto.Usage), nil)
 	return nil
 }
@@ -3,12 +3,16 @@ package helper
 import (
 	"fmt"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
 	"github.com/QuantumNous/new-api/logger"
 	"github.com/QuantumNous/new-api/model"
 	"github.com/QuantumNous/new-api/pkg/billingexpr"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	relayconstant "github.com/QuantumNous/new-api/relay/constant"
 	"github.com/QuantumNous/new-api/setting/billing_setting"
 	"github.com/QuantumNous/new-api/setting/operation_setting"
 	"github.com/QuantumNous/new-api/setting/ratio_setting"
@@ -32,6 +36,12 @@ func modelPriceNotConfiguredError(modelName string, userId int) error {
 	)
 }
 
+// 音频按秒、按字符计价时，单价作为按次价格，用量作为额外倍率记录
+const (
+	AudioSecondsRatio    = "seconds"
+	AudioCharactersRatio = "characters"
+)
+
 // https://docs.claude.com/en/docs/build-with-claude/prompt-caching#1-hour-cache-duration
 const claudeCacheCreation1hMultiplier = 6 / 3.75
 
@@ -69,13 +79,50 @@ func HandleGroupRatio(ctx *gin.Context, relayInfo *relaycommon.RelayInfo) types.
 	return groupRatioInfo
 }
 
+// ChannelModelPrice 返回当前渠道对该模型的价格覆盖
+func ChannelModelPrice(c *gin.Context, modelName string) (dto.ChannelModelPrice, bool) {
+	otherSettings, ok := common.GetContextKeyType[dto.ChannelOtherSettings](c, constant.ContextKeyChannelOtherSetting)
+	if !ok {
+		return dto.ChannelModelPrice{}, false
+	}
+	price, ok := otherSettings.ModelPrices[modelName]
+	return price, ok
+}
+
 func ModelPriceHelper(c *gin.Context, info *relaycommon.RelayInfo, promptTokens int, meta *types.TokenCountMeta) (types.PriceData, error) {
 	modelPrice, usePrice := ratio_setting.GetModelPrice(info.OriginModelName, false)
+	channelPrice, hasChannelPrice := ChannelModelPrice(c, info.OriginModelName)
+	if hasChannelPrice {
+		if channelPrice.ModelPrice != nil {
+			modelPrice, usePrice = *channelPrice.ModelPrice, true
+		} else if channelPrice.ModelRatio != nil {
+			usePrice = false
+		}
+	}
+
+	// 配置了音频按秒、按字符价格时优先使用，渠道价格覆盖仍然优先
+	var audioUnitName string
+	var audioUnits float64
+	if !hasChannelPrice {
+		switch info.RelayMode {
+		case relayconstant.RelayModeAudioTranscription, relayconstant.RelayModeAudioTranslation:
+			if price, ok := ratio_setting.GetAudioSecondPrice(info.OriginModelName); ok {
+				modelPrice, usePrice = price, true
+				audioUnitName, audioUnits = AudioSecondsRatio, meta.AudioSeconds
+			}
+		case relayconstant.RelayModeAudioSpeech:
+			if price, ok := ratio_setting.GetAudioCharacterPrice(info.OriginModelName); ok {
+				modelPrice, usePrice = price, true
+				audioUnitName, audioUnits = AudioCharactersRatio, float64(utf8.RuneCountInString(meta.CombineText))
+			}
+		}
+	}
 
 	groupRatioInfo := HandleGroupRatio(c, info)
 
 	// Check if this model uses tiered_expr billing
-	if billing_setting.GetBillingMode(info.OriginModelName) == billing_setting.BillingModeTieredExpr {
+	// 渠道价格覆盖优先于阶梯计费
+	if !hasChannelPrice && audioUnitName == "" && billing_setting.GetBillingMode(info.OriginModelName) == billing_setting.BillingModeTieredExpr {
 		return modelPriceHelperTiered(c, info, promptTokens, meta, groupRatioInfo)
 	}
 
@@ -98,6 +145,9 @@ func ModelPriceHelper(c *gin.Context, info *relaycommon.RelayInfo, promptTokens
 		var success bool
 		var matchName string
 		modelRatio, success, matchName = ratio_setting.GetModelRatio(info.OriginModelName)
+		if hasChannelPrice && channelPrice.ModelRatio != nil {
+			modelRatio, success = *channelPrice.ModelRatio, true
+		}
 		if !success {
 			acceptUnsetRatio := false
 			if info.UserSetting.AcceptUnsetRatioModel {
@@ -108,11 +158,18 @@ func ModelPriceHelper(c *gin.Context, info *relaycommon.RelayInfo, promptTokens
 			}
 		}
 		completionRatio = ratio_setting.GetCompletionRatio(info.OriginModelName)
+		if hasChannelPrice && channelPrice.CompletionRatio != nil {
+			completionRatio = *channelPrice.CompletionRatio
+		}
 		cacheRatio, _ = ratio_setting.GetCacheRatio(info.OriginModelName)
 		cacheCreationRatio, _ = ratio_setting.GetCreateCacheRatio(info.OriginModelName)
 		cacheCreationRatio5m = cacheCreationRatio
-		// 固定1h和5min缓存写入价格的比例
-		cacheCreationRatio1h = cacheCreationRatio * claudeCacheCreation1hMultiplier
+		// 未单独配置时固定1h和5min缓存写入价格的比例
+		if ratio, ok := ratio_setting.GetCreateCache1hRatio(info.OriginModelName); ok {
+			cacheCreationRatio1h = ratio
+		} else {
+			cacheCreationRatio1h = cacheCreationRatio * claudeCacheCreation1hMultiplier
+		}
 		imageRatio, _ = ratio_setting.GetImageRatio(info.OriginModelName)
 		audioRatio = ratio_setting.GetAudioRatio(info.OriginModelName)
 		audioCompletionRatio = ratio_setting.GetAudioCompletionRatio(info.OriginModelName)
@@ -154,6 +211,7 @@ func ModelPriceHelper(c *gin.Context, info *relaycommon.RelayInfo, promptTokens
 		CompletionRatio:      completionRatio,
 		GroupRatioInfo:       groupRatioInfo,
 		UsePrice:             usePrice,
+		ChannelPriceOverride: hasChannelPrice,
 		CacheRatio:           cacheRatio,
 		ImageRatio:           imageRatio,
 		AudioRatio:           audioRatio,
@@ -167,6 +225,10 @@ func ModelPriceHelper(c *gin.Context, info *relaycommon.RelayInfo, promptTokens
 		for name, ratio := range meta.BillingRatios {
 			priceData.AddOtherRatio(name, ratio)
 		}
+		if audioUnitName != "" {
+			// 不足一秒或一个字符按一计
+			priceData.AddOtherRatio(audioUnitName, max(audioUnits, 1))
+		}
 		quotaToPreConsume := priceData.ApplyOtherRatiosToFloat(modelPrice * common.QuotaPerUnit * groupRatioInfo.GroupRatio)
 		quota, err := common.QuotaFromFloatStrict(quotaToPreConsume)
 		if err != nil {
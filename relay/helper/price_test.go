@@ -6,8 +6,11 @@ import (
 	"testing"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
 	"github.com/QuantumNous/new-api/pkg/billingexpr"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	relayconstant "github.com/QuantumNous/new-api/relay/constant"
 	"github.com/QuantumNous/new-api/setting/billing_setting"
 	"github.com/QuantumNous/new-api/setting/config"
 	"github.com/QuantumNous/new-api/setting/ratio_setting"
@@ -272,3 +275,195 @@ func TestModelPriceHelperRequestBillingRatiosOnlyApplyToFixedPrice(t *testing.T)
 	require.Equal(t, common.QuotaClampOverflow, clamp.Kind)
 	require.Nil(t, info.Billing)
 }
+
+func TestModelPriceHelperCacheCreation1hRatio(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	savedModelRatios := ratio_setting.ModelRatio2JSONString()
+	savedCreateCacheRatios := ratio_setting.CreateCacheRatio2JSONString()
+	savedCreateCache1hRatios := ratio_setting.CreateCache1hRatio2JSONString()
+	t.Cleanup(func() {
+		require.NoError(t, ratio_setting.UpdateModelRatioByJSONString(savedModelRatios))
+		require.NoError(t, ratio_setting.UpdateCreateCacheRatioByJSONString(savedCreateCacheRatios))
+		require.NoError(t, ratio_setting.UpdateCreateCache1hRatioByJSONString(savedCreateCache1hRatios))
+	})
+
+	require.NoError(t, ratio_setting.UpdateModelRatioByJSONString(`{"cache-default-model": 1, "cache-custom-model": 1}`))
+	require.NoError(t, ratio_setting.UpdateCreateCacheRatioByJSONString(`{"cache-default-model": 1.25, "cache-custom-model": 1.25}`))
+	require.NoError(t, ratio_setting.UpdateCreateCache1hRatioByJSONString(`{"cache-custom-model": 3}`))
+
+	tests := []struct {
+		name   string
+		model  string
+		want5m float64
+		want1h float64
+	}{
+		{name: "falls back to fixed multiplier", model: "cache-default-model", want5m: 1.25, want1h: 2},
+		{name: "uses configured 1h ratio", model: "cache-custom-model", want5m: 1.25, want1h: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Set("group", "default")
+			info := &relaycommon.RelayInfo{
+				OriginModelName: tt.model,
+				UserGroup:       "default",
+				UsingGroup:      "default",
+			}
+			priceData, err := ModelPriceHelper(ctx, info, 1000, &types.TokenCountMeta{})
+			require.NoError(t, err)
+			require.InDelta(t, tt.want5m, priceData.CacheCreation5mRatio, 1e-9)
+			require.InDelta(t, tt.want1h, priceData.CacheCreation1hRatio, 1e-9)
+		})
+	}
+}
+
+func TestModelPriceHelperChannelPriceOverride(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	savedModelPrices := ratio_setting.ModelPrice2JSONString()
+	savedModelRatios := ratio_setting.ModelRatio2JSONString()
+	t.Cleanup(func() {
+		require.NoError(t, ratio_setting.UpdateModelPriceByJSONString(savedModelPrices))
+		require.NoError(t, ratio_setting.UpdateModelRatioByJSONString(savedModelRatios))
+	})
+	require.NoError(t, ratio_setting.UpdateModelPriceByJSONString(`{"override-price-model": 0.1}`))
+	require.NoError(t, ratio_setting.UpdateModelRatioByJSONString(`{"override-ratio-model": 10}`))
+
+	tests := []struct {
+		name         string
+		model        string
+		prices       map[string]dto.ChannelModelPrice
+		wantUsePrice bool
+		wantPrice    float64
+		wantRatio    float64
+		wantOverride bool
+	}{
+		{
+			name:         "no override uses global ratio",
+			model:        "override-ratio-model",
+			wantRatio:    10,
+			wantOverride: false,
+		},
+		{
+			name:         "ratio override",
+			model:        "override-ratio-model",
+			prices:       map[string]dto.ChannelModelPrice{"override-ratio-model": {ModelRatio: common.GetPointer(2.0)}},
+			wantRatio:    2,
+			wantOverride: true,
+		},
+		{
+			name:         "price override switches ratio model to per call",
+			model:        "override-ratio-model",
+			prices:       map[string]dto.ChannelModelPrice{"override-ratio-model": {ModelPrice: common.GetPointer(0.01)}},
+			wantUsePrice: true,
+			wantPrice:    0.01,
+			wantOverride: true,
+		},
+		{
+			name:         "ratio override switches per call model to ratio",
+			model:        "override-price-model",
+			prices:       map[string]dto.ChannelModelPrice{"override-price-model": {ModelRatio: common.GetPointer(1.5)}},
+			wantRatio:    1.5,
+			wantOverride: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Set("group", "default")
+			common.SetContextKey(ctx, constant.ContextKeyChannelOtherSetting, dto.ChannelOtherSettings{ModelPrices: tt.prices})
+			info := &relaycommon.RelayInfo{
+				OriginModelName: tt.model,
+				UserGroup:       "default",
+				UsingGroup:      "default",
+			}
+			priceData, err := ModelPriceHelper(ctx, info, 1000, &types.TokenCountMeta{})
+			require.NoError(t, err)
+			require.Equal(t, tt.wantUsePrice, priceData.UsePrice)
+			require.Equal(t, tt.wantOverride, priceData.ChannelPriceOverride)
+			if tt.wantUsePrice {
+				require.InDelta(t, tt.wantPrice, priceData.ModelPrice, 1e-9)
+			} else {
+				require.InDelta(t, tt.wantRatio, priceData.ModelRatio, 1e-9)
+			}
+		})
+	}
+}
+
+func TestModelPriceHelperAudioUnitPrice(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	savedModelRatios := ratio_setting.ModelRatio2JSONString()
+	savedSecondPrices := ratio_setting.AudioSecondPrice2JSONString()
+	savedCharacterPrices := ratio_setting.AudioCharacterPrice2JSONString()
+	t.Cleanup(func() {
+		require.NoError(t, ratio_setting.UpdateModelRatioByJSONString(savedModelRatios))
+		require.NoError(t, ratio_setting.UpdateAudioSecondPriceByJSONString(savedSecondPrices))
+		require.NoError(t, ratio_setting.UpdateAudioCharacterPriceByJSONString(savedCharacterPrices))
+	})
+	require.NoError(t, ratio_setting.UpdateModelRatioByJSONString(`{"audio-ratio-model": 15}`))
+	require.NoError(t, ratio_setting.UpdateAudioSecondPriceByJSONString(`{"audio-second-model": 0.0001}`))
+	require.NoError(t, ratio_setting.UpdateAudioCharacterPriceByJSONString(`{"audio-character-model": 0.00003}`))
+
+	tests := []struct {
+		name      string
+		model     string
+		relayMode int
+		meta      *types.TokenCountMeta
+		wantRatio string
+		wantUnits float64
+		wantQuota int
+	}{
+		{
+			name:      "transcription billed per second",
+			model:     "audio-second-model",
+			relayMode: relayconstant.RelayModeAudioTranscription,
+			meta:      &types.TokenCountMeta{AudioSeconds: 90},
+			wantRatio: AudioSecondsRatio,
+			wantUnits: 90,
+			wantQuota: 4500, // 0.0001 * 500000 * 90
+		},
+		{
+			name:      "speech billed per character",
+			model:     "audio-character-model",
+			relayMode: relayconstant.RelayModeAudioSpeech,
+			meta:      &types.TokenCountMeta{CombineText: "你好，world"},
+			wantRatio: AudioCharactersRatio,
+			wantUnits: 8,
+			wantQuota: 120, // 0.00003 * 500000 * 8
+		},
+		{
+			name:      "empty audio still billed one second",
+			model:     "audio-second-model",
+			relayMode: relayconstant.RelayModeAudioTranslation,
+			meta:      &types.TokenCountMeta{},
+			wantRatio: AudioSecondsRatio,
+			wantUnits: 1,
+			wantQuota: 50,
+		},
+		{
+			name:      "no unit price keeps ratio billing",
+			model:     "audio-ratio-model",
+			relayMode: relayconstant.RelayModeAudioTranscription,
+			meta:      &types.TokenCountMeta{AudioSeconds: 90},
+			wantQuota: 22500, // 1500 tokens * 15
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Set("group", "default")
+			info := &relaycommon.RelayInfo{
+				OriginModelName: tt.model,
+				RelayMode:       tt.relayMode,
+				UserGroup:       "default",
+				UsingGroup:      "default",
+			}
+			priceData, err := ModelPriceHelper(ctx, info, 1500, tt.meta)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantQuota, priceData.QuotaToPreConsume)
+			require.Equal(t, tt.wantRatio != "", priceData.UsePrice)
+			if tt.wantRatio != "" {
+				require.Equal(t, map[string]float64{tt.wantRatio: tt.wantUnits}, priceData.OtherRatios())
+			}
+		})
+	}
+}
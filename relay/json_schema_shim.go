@@ -0,0 +1,253 @@
+package relay
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/pkg/jsonschema"
+	"github.com/QuantumNous/new-api/relay/channel"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/types"
+	"github.com/samber/lo"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JsonSchemaShimHeader reports whether the shimmed structured output
+// validated against the requested schema: "valid" or "invalid".
+const JsonSchemaShimHeader = "X-Json-Schema-Shim"
+
+// jsonSchemaShim emulates response_format json_schema on upstreams without
+// native structured outputs: the schema is turned into a system instruction
+// and non-stream replies are validated against it, with an optional retry.
+type jsonSchemaShim struct {
+	name   string
+	schema any
+}
+
+// newJsonSchemaShim returns nil unless the request asks for a json_schema
+// response and the channel needs the shim. Unless the channel forces the
+// mode, it is used for Claude upstreams, which have no response_format.
+func newJsonSchemaShim(info *relaycommon.RelayInfo, request *dto.GeneralOpenAIRequest) *jsonSchemaShim {
+	if request.ResponseFormat == nil || request.ResponseFormat.Type != "json_schema" {
+		return nil
+	}
+	switch info.ChannelOtherSettings.JsonSchemaShimMode {
+	case dto.JsonSchemaShimModeDisabled:
+		return nil
+	case dto.JsonSchemaShimModeEnabled:
+	default:
+		switch info.ApiType {
+		case constant.APITypeAnthropic, constant.APITypeAws:
+		case constant.APITypeVertexAi:
+			if !strings.HasPrefix(info.UpstreamModelName, "claude") {
+				return nil
+			}
+		default:
+			return nil
+		}
+	}
+	var format dto.FormatJsonSchema
+	if err := common.Unmarshal(request.ResponseFormat.JsonSchema, &format); err != nil || format.Schema == nil {
+		return nil
+	}
+	return &jsonSchemaShim{name: format.Name, schema: format.Schema}
+}
+
+func (s *jsonSchemaShim) instruction() (string, error) {
+	schema, err := common.Marshal(s.schema)
+	if err != nil {
+		return "", err
+	}
+	name := ""
+	if s.name != "" {
+		name = fmt.Sprintf(" named %q", s.name)
+	}
+	return fmt.Sprintf("Respond with a single JSON value that conforms to the following JSON schema%s. "+
+		"Output only the JSON itself, without Markdown code fences or any other text.\n%s", name, schema), nil
+}
+
+// apply replaces response_format with the schema instruction, appended to the
+// first system message or sent as a new one.
+func (s *jsonSchemaShim) apply(c *gin.Context, info *relaycommon.RelayInfo, request *dto.GeneralOpenAIRequest) error {
+	instruction, err := s.instruction()
+	if err != nil {
+		return err
+	}
+	request.ResponseFormat = nil
+	// the channel system prompt is only added when no system message exists,
+	// so add it before the instruction creates one; overrides still apply later
+	if !info.ChannelSetting.SystemPromptOverride {
+		applySystemPromptIfNeeded(c, info, request)
+	}
+	systemRole := request.GetSystemRoleName()
+	if len(request.Messages) > 0 && request.Messages[0].Role == systemRole {
+		message := &request.Messages[0]
+		if message.IsStringContent() {
+			message.SetStringContent(message.StringContent() + "\n\n" + instruction)
+		} else {
+			message.SetMediaContent(append(message.ParseContent(), dto.MediaContent{Type: dto.ContentTypeText, Text: instruction}))
+		}
+		return nil
+	}
+	request.Messages = append([]dto.Message{{Role: systemRole, Content: instruction}}, request.Messages...)
+	return nil
+}
+
+// validate strips Markdown fences around content and checks it against the
+// schema, returning the bare JSON.
+func (s *jsonSchemaShim) validate(content string) (string, error) {
+	content = strings.TrimSpace(content)
+	if fenced, ok := strings.CutPrefix(content, "```"); ok {
+		// drop the language tag on the opening fence, e.g. ```json
+		if newline := strings.IndexByte(fenced, '\n'); newline >= 0 {
+			fenced = fenced[newline+1:]
+		}
+		content = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(fenced), "```"))
+	}
+	var value any
+	if err := common.UnmarshalJsonStr(content, &value); err != nil {
+		return content, fmt.Errorf("reply is not valid JSON: %w", err)
+	}
+	if err := jsonschema.Validate(s.schema, value); err != nil {
+		return content, err
+	}
+	return content, nil
+}
+
+// relay sends the request with the schema instruction. Stream replies are
+// passed through as they arrive; non-stream replies are held back until
+// validated, and an invalid one is retried once when the channel allows it.
+func (s *jsonSchemaShim) relay(c *gin.Context, info *relaycommon.RelayInfo, adaptor channel.Adaptor, request *dto.GeneralOpenAIRequest) (*dto.Usage, *types.NewAPIError) {
+	if err := s.apply(c, info, request); err != nil {
+		return nil, types.NewError(err, types.ErrorCodeConvertRequestFailed, types.ErrOptionWithSkipRetry())
+	}
+	if lo.FromPtrOr(request.Stream, false) {
+		return sendTextRequest(c, info, adaptor, request)
+	}
+
+	usage, writer, newAPIError := sendBufferedTextRequest(c, info, adaptor, request)
+	if newAPIError != nil {
+		return nil, newAPIError
+	}
+	if info.IsStream {
+		// the upstream streamed anyway, there is no single reply to validate
+		writer.flush(nil)
+		return usage, nil
+	}
+	content := gjson.GetBytes(writer.body.Bytes(), "choices.0.message.content").String()
+	cleaned, err := s.validate(content)
+	if err != nil && info.ChannelOtherSettings.JsonSchemaShimRetry {
+		logger.LogWarn(c, fmt.Sprintf("json schema shim: retrying invalid reply: %v", err))
+		request.Messages = append(request.Messages,
+			dto.Message{Role: "assistant", Content: content},
+			dto.Message{Role: "user", Content: fmt.Sprintf("Your reply does not conform to the JSON schema: %v. Reply again with only the corrected JSON.", err)},
+		)
+		retryUsage, retryWriter, retryErr := sendBufferedTextRequest(c, info, adaptor, request)
+		if retryErr != nil {
+			// the first reply was already paid for, return it rather than the retry error
+			logger.LogError(c, fmt.Sprintf("json schema shim: retry failed: %v", retryErr))
+		} else {
+			usage = addShimUsage(usage, retryUsage)
+			writer = retryWriter
+			content = gjson.GetBytes(writer.body.Bytes(), "choices.0.message.content").String()
+			cleaned, err = s.validate(content)
+		}
+	}
+	if err != nil {
+		logger.LogWarn(c, fmt.Sprintf("json schema shim: reply does not conform to the schema: %v", err))
+		c.Header(JsonSchemaShimHeader, "invalid")
+		writer.flush(nil)
+		return usage, nil
+	}
+	c.Header(JsonSchemaShimHeader, "valid")
+	body, setErr := sjson.SetBytes(writer.body.Bytes(), "choices.0.message.content", cleaned)
+	if setErr != nil {
+		body = nil
+	}
+	writer.flush(body)
+	return usage, nil
+}
+
+func sendBufferedTextRequest(c *gin.Context, info *relaycommon.RelayInfo, adaptor channel.Adaptor, request *dto.GeneralOpenAIRequest) (*dto.Usage, *bufferedResponseWriter, *types.NewAPIError) {
+	writer := &bufferedResponseWriter{ResponseWriter: c.Writer}
+	c.Writer = writer
+	defer func() {
+		c.Writer = writer.ResponseWriter
+	}()
+	usage, newAPIError := sendTextRequest(c, info, adaptor, request)
+	return usage, writer, newAPIError
+}
+
+// addShimUsage bills both attempts. The upstream-native billing usage only
+// describes one of them, so it is dropped in favour of the summed counts.
+func addShimUsage(first *dto.Usage, second *dto.Usage) *dto.Usage {
+	total := *second
+	total.BillingUsage = nil
+	total.PromptTokens += first.PromptTokens
+	total.CompletionTokens += first.CompletionTokens
+	total.TotalTokens += first.TotalTokens
+	total.PromptTokensDetails.CachedTokens += first.PromptTokensDetails.CachedTokens
+	total.PromptTokensDetails.CachedCreationTokens += first.PromptTokensDetails.CachedCreationTokens
+	return &total
+}
+
+// bufferedResponseWriter holds a response back from the client until flush.
+// Headers still go to the underlying writer.
+type bufferedResponseWriter struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedResponseWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *bufferedResponseWriter) WriteHeaderNow() {}
+
+func (w *bufferedResponseWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+func (w *bufferedResponseWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedResponseWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func (w *bufferedResponseWriter) Size() int {
+	return w.body.Len()
+}
+
+func (w *bufferedResponseWriter) Written() bool {
+	return w.status != 0 || w.body.Len() > 0
+}
+
+func (w *bufferedResponseWriter) Flush() {}
+
+// flush writes body, or the buffered body when nil, to the underlying writer.
+func (w *bufferedResponseWriter) flush(body []byte) {
+	if body == nil {
+		body = w.body.Bytes()
+	}
+	w.ResponseWriter.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.ResponseWriter.WriteHeader(w.Status())
+	_, _ = w.ResponseWriter.Write(body)
+}
@@ -0,0 +1,110 @@
+package relay
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const shimTestFormat = `{"name":"person","schema":{"type":"object","properties":{"name":{"type":"string"}},"required":["name"]}}`
+
+func TestNewJsonSchemaShim(t *testing.T) {
+	tests := []struct {
+		name       string
+		apiType    int
+		model      string
+		mode       dto.JsonSchemaShimMode
+		formatType string
+		want       bool
+	}{
+		{name: "anthropic", apiType: constant.APITypeAnthropic, formatType: "json_schema", want: true},
+		{name: "aws", apiType: constant.APITypeAws, formatType: "json_schema", want: true},
+		{name: "vertex claude", apiType: constant.APITypeVertexAi, model: "claude-sonnet-4", formatType: "json_schema", want: true},
+		{name: "vertex gemini", apiType: constant.APITypeVertexAi, model: "gemini-2.5-pro", formatType: "json_schema"},
+		{name: "openai", apiType: constant.APITypeOpenAI, formatType: "json_schema"},
+		{name: "forced on", apiType: constant.APITypeOpenAI, mode: dto.JsonSchemaShimModeEnabled, formatType: "json_schema", want: true},
+		{name: "disabled", apiType: constant.APITypeAnthropic, mode: dto.JsonSchemaShimModeDisabled, formatType: "json_schema"},
+		{name: "json object", apiType: constant.APITypeAnthropic, formatType: "json_object"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := &relaycommon.RelayInfo{ChannelMeta: &relaycommon.ChannelMeta{
+				ApiType:              tt.apiType,
+				UpstreamModelName:    tt.model,
+				ChannelOtherSettings: dto.ChannelOtherSettings{JsonSchemaShimMode: tt.mode},
+			}}
+			request := &dto.GeneralOpenAIRequest{ResponseFormat: &dto.ResponseFormat{Type: tt.formatType, JsonSchema: []byte(shimTestFormat)}}
+			assert.Equal(t, tt.want, newJsonSchemaShim(info, request) != nil)
+		})
+	}
+}
+
+func TestJsonSchemaShimApply(t *testing.T) {
+	info := &relaycommon.RelayInfo{ChannelMeta: &relaycommon.ChannelMeta{ApiType: constant.APITypeAnthropic}}
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	request := &dto.GeneralOpenAIRequest{
+		ResponseFormat: &dto.ResponseFormat{Type: "json_schema", JsonSchema: []byte(shimTestFormat)},
+		Messages:       []dto.Message{{Role: "system", Content: "Be brief."}, {Role: "user", Content: "Who?"}},
+	}
+	shim := newJsonSchemaShim(info, request)
+	require.NotNil(t, shim)
+	require.NoError(t, shim.apply(c, info, request))
+	assert.Nil(t, request.ResponseFormat)
+	require.Len(t, request.Messages, 2)
+	assert.Contains(t, request.Messages[0].StringContent(), "Be brief.\n\nRespond with a single JSON value")
+	assert.Contains(t, request.Messages[0].StringContent(), `"required":["name"]`)
+
+	info.ChannelSetting.SystemPrompt = "You are a bot."
+	request = &dto.GeneralOpenAIRequest{Messages: []dto.Message{{Role: "user", Content: "Who?"}}}
+	require.NoError(t, shim.apply(c, info, request))
+	require.Len(t, request.Messages, 2)
+	assert.Contains(t, request.Messages[0].StringContent(), "You are a bot.\n\nRespond with a single JSON value")
+}
+
+func TestJsonSchemaShimValidate(t *testing.T) {
+	shim := &jsonSchemaShim{schema: map[string]any{
+		"type":     "object",
+		"required": []any{"name"},
+	}}
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr bool
+	}{
+		{name: "bare", content: `{"name":"ann"}`, want: `{"name":"ann"}`},
+		{name: "fenced", content: "```json\n{\"name\":\"ann\"}\n```", want: `{"name":"ann"}`},
+		{name: "missing property", content: `{}`, want: `{}`, wantErr: true},
+		{name: "prose", content: `Sure! Here it is.`, want: `Sure! Here it is.`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := shim.validate(tt.content)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
+
+func TestBufferedResponseWriter(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	writer := &bufferedResponseWriter{ResponseWriter: c.Writer}
+	c.Writer = writer
+
+	c.JSON(201, gin.H{"a": 1})
+	assert.Zero(t, recorder.Body.Len())
+	assert.Equal(t, 201, writer.Status())
+
+	writer.flush([]byte(`{"a":2}`))
+	assert.Equal(t, 201, recorder.Code)
+	assert.Equal(t, `{"a":2}`, recorder.Body.String())
+	assert.Equal(t, "7", recorder.Header().Get("Content-Length"))
+}
@@ -533,6 +533,12 @@ func RelayMidjourneySubmit(c *gin.Context, relayInfo *relaycommon.RelayInfo) *dt
 		}
 	}
 
+	release, queueErr := service.AcquireMidjourneySlot(c, relayInfo.UserId, midjRequest.Action)
+	if queueErr != nil {
+		return queueErr
+	}
+	defer release()
+
 	midjResponseWithStatus, responseBody, err := service.DoMidjourneyHttpRequest(c, time.Second*60, fullRequestURL)
 	if err != nil {
 		return &midjResponseWithStatus.Response
@@ -35,6 +35,11 @@ func RerankHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *typ
 		return types.NewError(err, types.ErrorCodeChannelModelMappedError, types.ErrOptionWithSkipRetry())
 	}
 
+	// 按次计费的模型可配置为按文档数计费
+	if info.PriceData.UsePrice && model_setting.IsPerDocumentPriceModel(info.OriginModelName) {
+		info.PriceData.AddOtherRatio("documents", float64(len(request.Documents)))
+	}
+
 	adaptor := GetAdaptor(info.ApiType)
 	if adaptor == nil {
 		return types.NewError(fmt.Errorf("invalid api type: %d", info.ApiType), types.ErrorCodeInvalidApiType, types.ErrOptionWithSkipRetry())
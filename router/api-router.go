@@ -25,6 +25,7 @@ func SetApiRouter(router *gin.Engine) {
 		apiRouter.GET("/uptime/status", controller.GetUptimeKumaStatus)
 		apiRouter.GET("/models", middleware.UserAuth(), controller.DashboardListModels)
 		apiRouter.GET("/status/test", middleware.AdminAuth(), controller.TestStatus)
+		apiRouter.GET("/status/models", middleware.ModelStatusAuth(), controller.GetModelStatuses)
 		apiRouter.GET("/notice", controller.GetNotice)
 		apiRouter.GET("/user-agreement", controller.GetUserAgreement)
 		apiRouter.GET("/privacy-policy", controller.GetPrivacyPolicy)
@@ -32,6 +33,7 @@ func SetApiRouter(router *gin.Engine) {
 		//apiRouter.GET("/midjourney", controller.GetMidjourney)
 		apiRouter.GET("/home_page_content", controller.GetHomePageContent)
 		apiRouter.GET("/pricing", middleware.HeaderNavModuleAuth("pricing"), controller.GetPricing)
+		apiRouter.GET("/pricing/models", middleware.CORS(), middleware.TokenAuth(), controller.GetTokenModelPricing)
 		perfMetricsRoute := apiRouter.Group("/perf-metrics")
 		perfMetricsRoute.Use(middleware.HeaderNavModulePublicOrUserAuth("pricing"))
 		{
@@ -85,6 +87,10 @@ func SetApiRouter(router *gin.Engine) {
 				selfRoute.GET("/models", controller.GetUserModels)
 				selfRoute.PUT("/self", middleware.CriticalRateLimit(), controller.UpdateSelf)
 				selfRoute.DELETE("/self", controller.DeleteSelf)
+				selfRoute.GET("/self/export", middleware.CriticalRateLimit(), controller.ExportSelfData)
+				selfRoute.GET("/self/deletion", controller.GetSelfDeletionRequest)
+				selfRoute.POST("/self/deletion", middleware.CriticalRateLimit(), controller.RequestSelfDeletion)
+				selfRoute.DELETE("/self/deletion", controller.CancelSelfDeletion)
 				selfRoute.GET("/token", controller.GenerateAccessToken)
 				selfRoute.GET("/passkey", controller.PasskeyStatus)
 				selfRoute.POST("/passkey/register/begin", controller.PasskeyRegisterBegin)
@@ -106,8 +112,15 @@ func SetApiRouter(router *gin.Engine) {
 				selfRoute.POST("/waffo-pancake/amount", controller.RequestWaffoPancakeAmount)
 				selfRoute.POST("/waffo-pancake/pay", middleware.CriticalRateLimit(), controller.RequestWaffoPancakePay)
 				selfRoute.POST("/aff_transfer", controller.TransferAffQuota)
+				selfRoute.GET("/transfer", controller.GetSelfQuotaTransfers)
+				selfRoute.POST("/transfer", middleware.CriticalRateLimit(), controller.TransferUserQuota)
 				selfRoute.PUT("/setting", controller.UpdateUserSetting)
 
+				// Session / device management
+				selfRoute.GET("/sessions", controller.GetSelfSessions)
+				selfRoute.DELETE("/sessions", controller.RevokeOtherSelfSessions)
+				selfRoute.DELETE("/sessions/:id", controller.RevokeSelfSession)
+
 				// 2FA routes
 				selfRoute.GET("/2fa/status", controller.Get2FAStatus)
 				selfRoute.POST("/2fa/setup", controller.Setup2FA)
@@ -117,7 +130,13 @@ func SetApiRouter(router *gin.Engine) {
 
 				// Check-in routes
 				selfRoute.GET("/checkin", controller.GetCheckinStatus)
-				selfRoute.POST("/checkin", middleware.TurnstileCheck(), controller.DoCheckin)
+				selfRoute.GET("/checkin/list", controller.GetCheckinCalendar)
+				selfRoute.GET("/checkin/export", controller.ExportSelfCheckins)
+				selfRoute.GET("/statements", controller.GetSelfStatements)
+				selfRoute.GET("/statements/:id/export", controller.ExportSelfStatement)
+				selfRoute.GET("/quota_grants", controller.GetSelfQuotaGrants)
+				selfRoute.POST("/checkin", middleware.CheckinTurnstileCheck(), controller.DoCheckin)
+				selfRoute.POST("/checkin/makeup", middleware.CheckinTurnstileCheck(), controller.DoCheckinMakeup)
 
 				// Custom OAuth bindings
 				selfRoute.GET("/oauth/bindings", controller.GetUserOAuthBindings)
@@ -140,6 +159,7 @@ func SetApiRouter(router *gin.Engine) {
 				adminRoute.PUT("/", controller.UpdateUser)
 				adminRoute.DELETE("/:id", controller.DeleteUser)
 				adminRoute.DELETE("/:id/reset_passkey", controller.AdminResetPasskey)
+				adminRoute.GET("/:id/sessions", controller.GetUserSessionsByAdmin)
 
 				// Admin 2FA routes
 				adminRoute.GET("/2fa/stats", controller.Admin2FAStats)
@@ -154,6 +174,7 @@ func SetApiRouter(router *gin.Engine) {
 			subscriptionRoute.GET("/plans", controller.GetSubscriptionPlans)
 			subscriptionRoute.GET("/self", controller.GetSubscriptionSelf)
 			subscriptionRoute.PUT("/self/preference", controller.UpdateSubscriptionPreference)
+			subscriptionRoute.GET("/self/:id/grants", controller.GetSubscriptionSelfGrants)
 			subscriptionRoute.POST("/balance/pay", middleware.CriticalRateLimit(), controller.SubscriptionRequestBalancePay)
 			subscriptionRoute.POST("/epay/pay", middleware.CriticalRateLimit(), controller.SubscriptionRequestEpay)
 			subscriptionRoute.POST("/stripe/pay", middleware.CriticalRateLimit(), controller.SubscriptionRequestStripePay)
@@ -176,6 +197,7 @@ func SetApiRouter(router *gin.Engine) {
 			subscriptionAdminRoute.POST("/users/:id/subscriptions/reset", controller.AdminResetUserSubscriptionsByPlan)
 			subscriptionAdminRoute.POST("/user_subscriptions/:id/invalidate", controller.AdminInvalidateUserSubscription)
 			subscriptionAdminRoute.DELETE("/user_subscriptions/:id", controller.AdminDeleteUserSubscription)
+			subscriptionAdminRoute.GET("/user_subscriptions/:id/grants", controller.AdminListSubscriptionGrants)
 		}
 
 		// Subscription payment callbacks (no auth)
@@ -188,6 +210,7 @@ func SetApiRouter(router *gin.Engine) {
 		{
 			optionRoute.GET("/", controller.GetOptions)
 			optionRoute.PUT("/", controller.UpdateOption)
+			optionRoute.POST("/reload", controller.ReloadOptions)
 			optionRoute.POST("/payment_compliance", controller.ConfirmPaymentCompliance)
 			optionRoute.GET("/channel_affinity_cache", controller.GetChannelAffinityCacheStats)
 			optionRoute.DELETE("/channel_affinity_cache", controller.ClearChannelAffinityCache)
@@ -236,6 +259,7 @@ func SetApiRouter(router *gin.Engine) {
 			tokenRoute.GET("/search", middleware.SearchRateLimit(), controller.SearchTokens)
 			tokenRoute.GET("/:id", controller.GetToken)
 			tokenRoute.POST("/:id/key", middleware.CriticalRateLimit(), middleware.DisableCache(), controller.GetTokenKey)
+			tokenRoute.POST("/:id/rotate", middleware.CriticalRateLimit(), middleware.DisableCache(), controller.RotateToken)
 			tokenRoute.POST("/", controller.AddToken)
 			tokenRoute.PUT("/", controller.UpdateToken)
 			tokenRoute.DELETE("/:id", controller.DeleteToken)
@@ -258,12 +282,35 @@ func SetApiRouter(router *gin.Engine) {
 		{
 			redemptionRoute.GET("/", controller.GetAllRedemptions)
 			redemptionRoute.GET("/search", controller.SearchRedemptions)
+			redemptionRoute.GET("/export", controller.ExportRedemptions)
 			redemptionRoute.GET("/:id", controller.GetRedemption)
 			redemptionRoute.POST("/", controller.AddRedemption)
 			redemptionRoute.PUT("/", controller.UpdateRedemption)
 			redemptionRoute.DELETE("/invalid", controller.DeleteInvalidRedemption)
 			redemptionRoute.DELETE("/:id", controller.DeleteRedemption)
 		}
+		checkinRoute := apiRouter.Group("/checkin")
+		checkinRoute.Use(middleware.AdminAuth())
+		{
+			checkinRoute.GET("/stats", controller.GetCheckinAdminStats)
+			checkinRoute.GET("/ip_clusters", controller.GetCheckinIpClusters)
+			checkinRoute.GET("/export", controller.AdminExportCheckins)
+			checkinRoute.POST("/admin/grant", controller.AdminGrantCheckin)
+			checkinRoute.DELETE("/admin/revoke", controller.AdminRevokeCheckin)
+		}
+		moderationRoute := apiRouter.Group("/moderation")
+		moderationRoute.Use(middleware.AdminAuth())
+		{
+			moderationRoute.GET("/hits", controller.GetModerationHits)
+			moderationRoute.GET("/stats", controller.GetModerationHitStats)
+		}
+		statementRoute := apiRouter.Group("/statement")
+		statementRoute.Use(middleware.AdminAuth())
+		{
+			statementRoute.GET("/", controller.GetAllStatements)
+			statementRoute.GET("/:id/export", controller.AdminExportStatement)
+			statementRoute.POST("/generate", controller.AdminGenerateStatements)
+		}
 		logRoute := apiRouter.Group("/log")
 		logRoute.GET("/", middleware.AdminAuth(), controller.GetAllLogs)
 		// Legacy synchronous direct-delete route used only by the classic frontend.
@@ -276,10 +323,53 @@ func SetApiRouter(router *gin.Engine) {
 		logRoute.GET("/self", middleware.UserAuth(), controller.GetUserLogs)
 		logRoute.GET("/self/search", middleware.UserAuth(), middleware.SearchRateLimit(), controller.SearchUserLogs)
 
+		apiRouter.GET("/audit", middleware.RootAuth(), controller.GetAuditLogs)
+		bodyLogRoute := apiRouter.Group("/body_log")
+		bodyLogRoute.Use(middleware.RootAuth())
+		{
+			bodyLogRoute.GET("/", controller.GetBodyLogs)
+			bodyLogRoute.GET("/:id", controller.GetBodyLog)
+		}
+
+		webhookRoute := apiRouter.Group("/webhook")
+		webhookRoute.Use(middleware.RootAuth())
+		{
+			webhookRoute.GET("/", controller.GetWebhookEndpoints)
+			webhookRoute.POST("/", controller.CreateWebhookEndpoint)
+			webhookRoute.PUT("/", controller.UpdateWebhookEndpoint)
+			webhookRoute.DELETE("/:id", controller.DeleteWebhookEndpoint)
+			webhookRoute.POST("/:id/test", controller.TestWebhookEndpoint)
+			webhookRoute.GET("/deliveries", controller.GetWebhookDeliveries)
+		}
+
+		playgroundRoute := apiRouter.Group("/playground")
+		playgroundRoute.Use(middleware.UserAuth())
+		{
+			playgroundRoute.GET("/sessions", controller.GetPlaygroundSessions)
+			playgroundRoute.POST("/sessions", controller.CreatePlaygroundSession)
+			playgroundRoute.GET("/sessions/:id", controller.GetPlaygroundSession)
+			playgroundRoute.PUT("/sessions/:id", controller.UpdatePlaygroundSession)
+			playgroundRoute.DELETE("/sessions/:id", controller.DeletePlaygroundSession)
+			playgroundRoute.GET("/sessions/:id/export", controller.ExportPlaygroundSession)
+			playgroundRoute.POST("/sessions/:id/preview", controller.PreviewPlaygroundMessage)
+		}
+
+		notificationTemplateRoute := apiRouter.Group("/notification_template")
+		notificationTemplateRoute.Use(middleware.RootAuth())
+		{
+			notificationTemplateRoute.GET("/", controller.GetNotificationTemplates)
+			notificationTemplateRoute.PUT("/", controller.UpdateNotificationTemplate)
+			notificationTemplateRoute.DELETE("/:id", controller.DeleteNotificationTemplate)
+			notificationTemplateRoute.POST("/test", controller.TestNotificationTemplate)
+		}
+
+		apiRouter.POST("/group_upgrade/preview", middleware.RootAuth(), controller.PreviewGroupUpgrades)
+
 		systemTaskRoute := apiRouter.Group("/system-task")
 		systemTaskRoute.Use(middleware.RootAuth())
 		{
 			systemTaskRoute.POST("/log-cleanup", controller.CreateLogCleanupSystemTask)
+			systemTaskRoute.POST("/log-retention", controller.CreateLogRetentionSystemTask)
 			systemTaskRoute.GET("/list", controller.ListSystemTasks)
 			systemTaskRoute.GET("/current", controller.GetCurrentSystemTask)
 			systemTaskRoute.GET("/:task_id", controller.GetSystemTask)
@@ -288,6 +378,7 @@ func SetApiRouter(router *gin.Engine) {
 		systemInfoRoute.Use(middleware.RootAuth())
 		{
 			systemInfoRoute.GET("/instances", controller.ListSystemInstances)
+			systemInfoRoute.GET("/leader", controller.GetSystemLeader)
 			systemInfoRoute.DELETE("/stale-instances", controller.DeleteStaleSystemInstances)
 			systemInfoRoute.DELETE("/instances/:node_name", controller.DeleteStaleSystemInstance)
 		}
@@ -299,6 +390,16 @@ func SetApiRouter(router *gin.Engine) {
 		dataRoute.GET("/flow", middleware.AdminAuth(), controller.GetAllFlowQuotaDates)
 		dataRoute.GET("/flow/self", middleware.UserAuth(), controller.GetUserFlowQuotaDates)
 
+		analyticsRoute := apiRouter.Group("/analytics")
+		analyticsRoute.GET("/usage", middleware.AdminAuth(), controller.GetUsageAnalytics)
+
+		quotaSyncRoute := apiRouter.Group("/quota_sync")
+		quotaSyncRoute.Use(middleware.RootAuth())
+		{
+			quotaSyncRoute.GET("/check", controller.GetQuotaSyncConsistency)
+			quotaSyncRoute.POST("/reconcile", controller.ReconcileQuotaSync)
+		}
+
 		logRoute.Use(middleware.CORS(), middleware.CriticalRateLimit())
 		{
 			logRoute.GET("/token", middleware.TokenAuthReadOnly(), controller.GetLogByKey)
@@ -321,6 +422,10 @@ func SetApiRouter(router *gin.Engine) {
 		mjRoute := apiRouter.Group("/mj")
 		mjRoute.GET("/self", middleware.UserAuth(), controller.GetUserMidjourney)
 		mjRoute.GET("/", middleware.AdminAuth(), controller.GetAllMidjourney)
+		mjRoute.GET("/queue", middleware.AdminAuth(), controller.GetMidjourneyQueue)
+		mjRoute.GET("/queue/self", middleware.UserAuth(), controller.GetUserMidjourneyQueue)
+		mjRoute.DELETE("/queue/:id", middleware.AdminAuth(), controller.CancelMidjourneyQueueEntry)
+		mjRoute.POST("/:id/cancel", middleware.AdminAuth(), controller.CancelMidjourney)
 
 		taskRoute := apiRouter.Group("/task")
 		{
@@ -328,6 +433,9 @@ func SetApiRouter(router *gin.Engine) {
 			taskRoute.GET("/", middleware.AdminAuth(), controller.GetAllTask)
 		}
 
+		batchRoute := apiRouter.Group("/batch")
+		batchRoute.GET("/self", middleware.UserAuth(), controller.GetUserBatchJobs)
+
 		vendorRoute := apiRouter.Group("/vendors")
 		vendorRoute.Use(middleware.AdminAuth())
 		{
@@ -27,6 +27,17 @@ func registerChannelRoutes(apiRouter *gin.RouterGroup) {
 		middleware.SecureVerificationRequired(),
 		controller.GetChannelKey,
 	)
+	channelRoute.POST("/export/keys",
+		middleware.RootAuth(),
+		middleware.CriticalRateLimit(),
+		middleware.DisableCache(),
+		middleware.SecureVerificationRequired(),
+		controller.ExportChannelsWithKeys,
+	)
+	channelRoute.POST("/mock/load_test",
+		middleware.RootAuth(),
+		controller.RunMockLoadTest,
+	)
 
 	for _, route := range channelPermissionRoutes {
 		channelRoute.Handle(route.method, route.path,
@@ -42,6 +53,16 @@ var channelPermissionRoutes = []permissionRoute{
 	{method: http.MethodGet, path: "/models", permission: authz.ChannelRead, handler: controller.ChannelListModels},
 	{method: http.MethodGet, path: "/models_enabled", permission: authz.ChannelRead, handler: controller.EnabledListModels},
 	{method: http.MethodGet, path: "/ops", permission: authz.ChannelRead, handler: controller.GetChannelOps},
+	{method: http.MethodGet, path: "/health", permission: authz.ChannelRead, handler: controller.GetChannelHealthSummaries},
+	{method: http.MethodGet, path: "/:id/health", permission: authz.ChannelRead, handler: controller.GetChannelHealthHistory},
+	{method: http.MethodGet, path: "/export", permission: authz.ChannelRead, handler: controller.ExportChannels},
+	{method: http.MethodPost, path: "/export", permission: authz.ChannelRead, handler: controller.ExportChannels},
+	{method: http.MethodPost, path: "/import", permission: authz.ChannelSensitiveWrite, handler: controller.ImportChannels},
+	{method: http.MethodGet, path: "/tags", permission: authz.ChannelRead, handler: controller.GetChannelTags},
+	{method: http.MethodGet, path: "/tags/channels", permission: authz.ChannelRead, handler: controller.GetChannelsByTags},
+	{method: http.MethodPost, path: "/tags/status", permission: authz.ChannelOperate, handler: controller.UpdateChannelsStatusByTags},
+	{method: http.MethodPut, path: "/tags", permission: authz.ChannelWrite, handler: controller.EditChannelsByTags},
+	{method: http.MethodPost, path: "/batch/tags", permission: authz.ChannelWrite, handler: controller.BatchUpdateChannelTags},
 	{method: http.MethodGet, path: "/:id", permission: authz.ChannelRead, handler: controller.GetChannel},
 	{method: http.MethodGet, path: "/test", permission: authz.ChannelOperate, handler: controller.TestAllChannels},
 	{method: http.MethodGet, path: "/test/:id", permission: authz.ChannelOperate, handler: controller.TestChannel},
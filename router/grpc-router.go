@@ -0,0 +1,109 @@
+package router
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/controller"
+	"github.com/QuantumNous/new-api/pkg/adminpb"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// StartAdminGrpcServer 在 GRPC_ADMIN_ADDR 上启动 gRPC 管理接口，未配置地址时返回 nil。
+// 接口强制 mTLS：只接受 GRPC_ADMIN_CLIENT_CA 签发的客户端证书，
+// 设置 GRPC_ADMIN_ALLOWED_CLIENTS（逗号分隔的证书 CN）后只放行其中的证书
+func StartAdminGrpcServer() (*grpc.Server, error) {
+	addr := os.Getenv("GRPC_ADMIN_ADDR")
+	if addr == "" {
+		return nil, nil
+	}
+	tlsConfig, err := adminGrpcTLSConfig(os.Getenv("GRPC_ADMIN_TLS_CERT"), os.Getenv("GRPC_ADMIN_TLS_KEY"), os.Getenv("GRPC_ADMIN_CLIENT_CA"))
+	if err != nil {
+		return nil, err
+	}
+	allowed := map[string]bool{}
+	for _, name := range strings.Split(os.Getenv("GRPC_ADMIN_ALLOWED_CLIENTS"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			allowed[name] = true
+		}
+	}
+	server := grpc.NewServer(
+		grpc.Creds(credentials.NewTLS(tlsConfig)),
+		grpc.UnaryInterceptor(adminGrpcAuth(allowed)),
+	)
+	adminpb.RegisterAdminServiceServer(server, &controller.GrpcAdminServer{})
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	gopool.Go(func() {
+		if err := server.Serve(listener); err != nil {
+			common.SysError("gRPC admin server stopped: " + err.Error())
+		}
+	})
+	common.SysLog("gRPC admin server listening on " + addr)
+	return server, nil
+}
+
+// adminGrpcTLSConfig 构建要求并校验客户端证书的 TLS 配置
+func adminGrpcTLSConfig(certFile string, keyFile string, clientCAFile string) (*tls.Config, error) {
+	if certFile == "" || keyFile == "" || clientCAFile == "" {
+		return nil, errors.New("GRPC_ADMIN_ADDR requires GRPC_ADMIN_TLS_CERT, GRPC_ADMIN_TLS_KEY and GRPC_ADMIN_CLIENT_CA")
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load gRPC admin server certificate: %w", err)
+	}
+	caPEM, err := os.ReadFile(clientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gRPC admin client CA: %w", err)
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(caPEM) {
+		return nil, errors.New("no certificates found in GRPC_ADMIN_CLIENT_CA")
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// adminGrpcAuth 以已校验的客户端证书 CN 作为调用方身份，并兜底 handler 中的 panic
+func adminGrpcAuth(allowed map[string]bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		p, ok := peer.FromContext(ctx)
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "client certificate required")
+		}
+		tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+		if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+			return nil, status.Error(codes.Unauthenticated, "client certificate required")
+		}
+		name := tlsInfo.State.VerifiedChains[0][0].Subject.CommonName
+		if len(allowed) > 0 && !allowed[name] {
+			return nil, status.Errorf(codes.PermissionDenied, "client %q is not allowed", name)
+		}
+		defer func() {
+			if r := recover(); r != nil {
+				common.SysError(fmt.Sprintf("gRPC admin panic in %s: %v", info.FullMethod, r))
+				err = status.Error(codes.Internal, "internal error")
+			}
+		}()
+		return handler(controller.WithGrpcOperator(ctx, name), req)
+	}
+}
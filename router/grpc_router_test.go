@@ -0,0 +1,133 @@
+package router
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/pkg/adminpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+func issueTestCert(t *testing.T, commonName string, parent *testCert, server bool) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if server {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		template.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.ExtKeyUsage = nil
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+func (c *testCert) writePEM(t *testing.T, dir string, name string) (string, string) {
+	t.Helper()
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0o600))
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.der}, PrivateKey: c.key}
+}
+
+func TestAdminGrpcMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := issueTestCert(t, "test-ca", nil, false)
+	caFile, _ := ca.writePEM(t, dir, "ca")
+	serverCertFile, serverKeyFile := issueTestCert(t, "localhost", ca, true).writePEM(t, dir, "server")
+
+	tlsConfig, err := adminGrpcTLSConfig(serverCertFile, serverKeyFile, caFile)
+	require.NoError(t, err)
+	server := grpc.NewServer(
+		grpc.Creds(credentials.NewTLS(tlsConfig)),
+		grpc.UnaryInterceptor(adminGrpcAuth(map[string]bool{"provisioner": true})),
+	)
+	// handlers are not under test, the unimplemented stubs show that auth passed
+	adminpb.RegisterAdminServiceServer(server, &adminpb.UnimplementedAdminServiceServer{})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	otherCA := issueTestCert(t, "other-ca", nil, false)
+	tests := []struct {
+		name         string
+		clientCert   *testCert
+		expectedCode codes.Code
+	}{
+		{name: "allowed client", clientCert: issueTestCert(t, "provisioner", ca, false), expectedCode: codes.Unimplemented},
+		{name: "client not in allow list", clientCert: issueTestCert(t, "intruder", ca, false), expectedCode: codes.PermissionDenied},
+		{name: "certificate from another CA", clientCert: issueTestCert(t, "provisioner", otherCA, false), expectedCode: codes.Unavailable},
+		{name: "no client certificate", expectedCode: codes.Unavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientTLS := &tls.Config{RootCAs: roots, ServerName: "127.0.0.1"}
+			if tt.clientCert != nil {
+				clientTLS.Certificates = []tls.Certificate{tt.clientCert.tlsCertificate()}
+			}
+			conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(credentials.NewTLS(clientTLS)))
+			require.NoError(t, err)
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_, err = adminpb.NewAdminServiceClient(conn).GetUser(ctx, &adminpb.GetUserRequest{Id: 1})
+			assert.Equal(t, tt.expectedCode, status.Code(err), err)
+		})
+	}
+}
+
+func TestAdminGrpcTLSConfigRequiresClientCA(t *testing.T) {
+	_, err := adminGrpcTLSConfig("server.crt", "server.key", "")
+	assert.Error(t, err)
+}
@@ -1,6 +1,8 @@
 package router
 
 import (
+	"strings"
+
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/controller"
 	"github.com/QuantumNous/new-api/middleware"
@@ -15,6 +17,7 @@ func SetRelayRouter(router *gin.Engine) {
 	router.Use(middleware.DecompressRequestMiddleware())
 	router.Use(middleware.BodyStorageCleanup()) // 清理请求体存储
 	router.Use(middleware.StatsMiddleware())
+	router.Use(middleware.Tracing())
 	// https://platform.openai.com/docs/api-reference/introduction
 	modelsRouter := router.Group("/v1/models")
 	modelsRouter.Use(middleware.RouteTag("relay"))
@@ -66,10 +69,26 @@ func SetRelayRouter(router *gin.Engine) {
 	{
 		playgroundRouter.POST("/chat/completions", controller.Playground)
 	}
+	// 服务端会话：改写为会话令牌的 /v1/chat/completions 请求后按正常 API 请求鉴权、限流和计费
+	playgroundSessionRouter := router.Group("/pg/sessions")
+	playgroundSessionRouter.Use(middleware.RouteTag("relay"))
+	playgroundSessionRouter.Use(middleware.SystemPerformanceCheck())
+	playgroundSessionRouter.Use(middleware.UserAuth())
+	{
+		playgroundSessionRouter.POST("/:id/messages",
+			controller.PreparePlaygroundSessionChat,
+			middleware.TokenAuth(),
+			middleware.TokenRateLimit(),
+			middleware.ModelRequestRateLimit(),
+			middleware.Distribute(),
+			controller.PlaygroundSessionChat,
+		)
+	}
 	relayV1Router := router.Group("/v1")
 	relayV1Router.Use(middleware.RouteTag("relay"))
 	relayV1Router.Use(middleware.SystemPerformanceCheck())
 	relayV1Router.Use(middleware.TokenAuth())
+	relayV1Router.Use(middleware.TokenRateLimit())
 	relayV1Router.Use(middleware.ModelRequestRateLimit())
 	{
 		// WebSocket 路由（统一到 Relay）
@@ -79,10 +98,27 @@ func SetRelayRouter(router *gin.Engine) {
 			controller.Relay(c, types.RelayFormatOpenAIRealtime)
 		})
 	}
+	{
+		// 本地计算的接口，无需选择渠道
+		relayV1Router.POST("/messages/count_tokens", controller.CountClaudeTokens)
+	}
+	{
+		// Batch / Files 接口按文件和任务记录的渠道转发，无需选择渠道
+		relayV1Router.POST("/files", controller.UploadBatchFile)
+		relayV1Router.GET("/files", controller.ListBatchFiles)
+		relayV1Router.GET("/files/:id", controller.RetrieveBatchFile)
+		relayV1Router.GET("/files/:id/content", controller.RetrieveBatchFileContent)
+		relayV1Router.DELETE("/files/:id", controller.DeleteBatchFile)
+		relayV1Router.POST("/batches", controller.CreateBatch)
+		relayV1Router.GET("/batches", controller.ListBatches)
+		relayV1Router.GET("/batches/:id", controller.RetrieveBatch)
+		relayV1Router.POST("/batches/:id/cancel", controller.CancelBatch)
+	}
 	{
 		//http router
 		httpRouter := relayV1Router.Group("")
 		httpRouter.Use(middleware.Distribute())
+		httpRouter.Use(middleware.BodyLog())
 
 		// claude related routes
 		httpRouter.POST("/messages", func(c *gin.Context) {
@@ -152,11 +188,6 @@ func SetRelayRouter(router *gin.Engine) {
 
 		// not implemented
 		httpRouter.POST("/images/variations", controller.RelayNotImplemented)
-		httpRouter.GET("/files", controller.RelayNotImplemented)
-		httpRouter.POST("/files", controller.RelayNotImplemented)
-		httpRouter.DELETE("/files/:id", controller.RelayNotImplemented)
-		httpRouter.GET("/files/:id", controller.RelayNotImplemented)
-		httpRouter.GET("/files/:id/content", controller.RelayNotImplemented)
 		httpRouter.POST("/fine-tunes", controller.RelayNotImplemented)
 		httpRouter.GET("/fine-tunes", controller.RelayNotImplemented)
 		httpRouter.GET("/fine-tunes/:id", controller.RelayNotImplemented)
@@ -190,11 +221,18 @@ func SetRelayRouter(router *gin.Engine) {
 	relayGeminiRouter.Use(middleware.RouteTag("relay"))
 	relayGeminiRouter.Use(middleware.SystemPerformanceCheck())
 	relayGeminiRouter.Use(middleware.TokenAuth())
+	relayGeminiRouter.Use(middleware.TokenRateLimit())
 	relayGeminiRouter.Use(middleware.ModelRequestRateLimit())
 	relayGeminiRouter.Use(middleware.Distribute())
+	relayGeminiRouter.Use(middleware.BodyLog())
 	{
 		// Gemini API 路径格式: /v1beta/models/{model_name}:{action}
 		relayGeminiRouter.POST("/models/*path", func(c *gin.Context) {
+			// countTokens 在本地估算，不能当作 generateContent 转发和计费
+			if strings.HasSuffix(c.Param("path"), ":countTokens") {
+				controller.CountGeminiTokens(c)
+				return
+			}
 			controller.Relay(c, types.RelayFormatGemini)
 		})
 	}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+)
+
+const auditLogCleanupBatchSize = 500
+
+type auditLogCleanupPayload struct {
+	Cutoff int64 `json:"cutoff"`
+}
+
+type auditLogCleanupResult struct {
+	Cutoff  int64 `json:"cutoff"`
+	Deleted int64 `json:"deleted"`
+}
+
+// auditLogCleanupHandler prunes audit logs older than the configured retention
+// window. The cutoff is fixed in the payload when the task is scheduled, so a
+// retried run deletes exactly the same range.
+type auditLogCleanupHandler struct{}
+
+func init() {
+	RegisterSystemTaskHandler(auditLogCleanupHandler{})
+}
+
+func (auditLogCleanupHandler) Type() string { return model.SystemTaskTypeAuditCleanup }
+
+func (auditLogCleanupHandler) Enabled() bool {
+	return operation_setting.GetAuditSetting().RetentionDays > 0
+}
+
+func (auditLogCleanupHandler) Interval() time.Duration { return 6 * time.Hour }
+
+func (auditLogCleanupHandler) NewPayload() any {
+	days := operation_setting.GetAuditSetting().RetentionDays
+	return auditLogCleanupPayload{Cutoff: common.GetTimestamp() - int64(days)*24*3600}
+}
+
+func (auditLogCleanupHandler) Run(ctx context.Context, task *model.SystemTask, runnerID string) {
+	payload := auditLogCleanupPayload{}
+	if err := task.DecodePayload(&payload); err != nil {
+		failSystemTask(task, runnerID, err)
+		return
+	}
+	result := &auditLogCleanupResult{Cutoff: payload.Cutoff}
+	for {
+		if err := ctx.Err(); err != nil {
+			failSystemTask(task, runnerID, err)
+			return
+		}
+		deleted, err := model.DeleteAuditLogsBefore(payload.Cutoff, auditLogCleanupBatchSize)
+		if err != nil {
+			failSystemTask(task, runnerID, err)
+			return
+		}
+		result.Deleted += deleted
+		if deleted < auditLogCleanupBatchSize {
+			break
+		}
+	}
+	if err := model.FinishSystemTask(task.TaskID, runnerID, model.SystemTaskStatusSucceeded, result, ""); err != nil {
+		logSystemTaskLockError(ctx, task, err)
+	}
+}
@@ -0,0 +1,297 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+
+	"github.com/shopspring/decimal"
+	"github.com/tidwall/gjson"
+)
+
+// batchPollLimit caps how many unsettled batch jobs one polling pass refreshes.
+const batchPollLimit = 200
+
+// BatchPollSummary is the result recorded on each batch_poll system task row.
+type BatchPollSummary struct {
+	UnsettledJobs int `json:"unsettled_jobs"`
+	Refreshed     int `json:"refreshed"`
+	Settled       int `json:"settled"`
+	Failed        int `json:"failed"`
+}
+
+// batchUsage is the token usage of a finished batch, either taken from the
+// batch object itself or summed from the output file.
+type batchUsage struct {
+	InputTokens    int
+	OutputTokens   int
+	CompletedCalls int
+}
+
+// SelectBatchChannel picks an OpenAI channel able to serve modelName for the
+// given group. Files and batches live on the upstream account they were created
+// on, so the chosen channel and key index must be stored and reused for every
+// follow-up request.
+func SelectBatchChannel(group string, modelName string) (*model.Channel, int, error) {
+	channel, err := model.GetRandomSatisfiedChannel(group, modelName, 0, "/v1/batches")
+	if err != nil {
+		return nil, 0, err
+	}
+	if channel == nil {
+		return nil, 0, fmt.Errorf("no available channel for model %s under group %s", modelName, group)
+	}
+	if channel.Type != constant.ChannelTypeOpenAI {
+		return nil, 0, fmt.Errorf("channel #%d does not support the batch API", channel.Id)
+	}
+	_, keyIndex, apiErr := channel.GetNextEnabledKey()
+	if apiErr != nil {
+		return nil, 0, apiErr
+	}
+	return channel, keyIndex, nil
+}
+
+// DoBatchUpstreamRequest sends a Files/Batches API request to the channel and
+// key a file or batch was created on. The caller owns the response body.
+func DoBatchUpstreamRequest(ctx context.Context, channelId int, keyIndex int, method string, path string, body io.Reader, contentType string) (*http.Response, error) {
+	channel, err := model.CacheGetChannel(channelId)
+	if err != nil {
+		return nil, err
+	}
+	key := channel.Key
+	if channel.ChannelInfo.IsMultiKey {
+		keys := channel.GetKeys()
+		if keyIndex < 0 || keyIndex >= len(keys) {
+			return nil, fmt.Errorf("channel #%d key index %d out of range", channelId, keyIndex)
+		}
+		key = keys[keyIndex]
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(channel.GetBaseURL(), "/")+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(key))
+	if channel.OpenAIOrganization != nil && *channel.OpenAIOrganization != "" {
+		req.Header.Set("OpenAI-Organization", *channel.OpenAIOrganization)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	client, err := NewProxyHttpClient(channel.GetSetting().Proxy)
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(req)
+}
+
+// ApplyUpstreamBatch copies the fields the tracker cares about from an
+// upstream batch object into job.
+func ApplyUpstreamBatch(job *model.BatchJob, body []byte) {
+	result := gjson.ParseBytes(body)
+	if status := result.Get("status").String(); status != "" {
+		job.Status = status
+	}
+	job.OutputFileId = result.Get("output_file_id").String()
+	job.ErrorFileId = result.Get("error_file_id").String()
+	job.Data = string(body)
+}
+
+// RefreshBatchJob fetches the latest batch object from upstream, stores it and
+// settles the job once it reaches a terminal status. It returns the raw batch
+// object so callers can hand it back to the client unchanged.
+func RefreshBatchJob(ctx context.Context, job *model.BatchJob) ([]byte, error) {
+	resp, err := DoBatchUpstreamRequest(ctx, job.ChannelId, job.KeyIndex, http.MethodGet, "/v1/batches/"+job.BatchId, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return body, fmt.Errorf("upstream returned status %d: %s", resp.StatusCode, string(body))
+	}
+	ApplyUpstreamBatch(job, body)
+	if err := job.UpdateFromUpstream(); err != nil {
+		return body, err
+	}
+	if !job.Settled && model.IsBatchTerminalStatus(job.Status) {
+		if err := SettleBatchJob(ctx, job); err != nil {
+			return body, err
+		}
+	}
+	return body, nil
+}
+
+// SettleBatchJob charges the user for a finished batch at the configured
+// discount. Settlement is guarded by a CAS on the settled flag so concurrent
+// pollers and client refreshes charge at most once.
+func SettleBatchJob(ctx context.Context, job *model.BatchJob) error {
+	usage, err := collectBatchUsage(ctx, job)
+	if err != nil {
+		return err
+	}
+	quota, other := calculateBatchQuota(job, usage)
+	won, err := model.MarkBatchJobSettled(job.Id, quota)
+	if err != nil || !won {
+		return err
+	}
+	job.Settled = true
+	job.Quota = quota
+	if quota <= 0 {
+		return nil
+	}
+
+	if err := model.DecreaseUserQuota(job.UserId, quota, false); err != nil {
+		logger.LogWarn(ctx, fmt.Sprintf("batch %s 扣除用户额度失败: %s", job.BatchId, err.Error()))
+	}
+	if job.TokenId > 0 {
+		if tokenKey := resolveTokenKey(ctx, job.TokenId, job.BatchId); tokenKey != "" {
+			if err := model.DecreaseTokenQuota(job.TokenId, tokenKey, quota); err != nil {
+				logger.LogWarn(ctx, fmt.Sprintf("batch %s 扣除令牌额度失败: %s", job.BatchId, err.Error()))
+			}
+		}
+	}
+	model.RecordTaskBillingLog(model.RecordTaskBillingLogParams{
+		UserId:    job.UserId,
+		LogType:   model.LogTypeConsume,
+		Content:   fmt.Sprintf("Batch %s 结算，请求数 %d", job.BatchId, usage.CompletedCalls),
+		ChannelId: job.ChannelId,
+		ModelName: job.Model,
+		Quota:     quota,
+		TokenId:   job.TokenId,
+		Group:     job.Group,
+		Other:     other,
+	})
+	model.UpdateUserUsedQuotaAndRequestCount(job.UserId, quota)
+	model.UpdateChannelUsedQuota(job.ChannelId, quota)
+	return nil
+}
+
+// collectBatchUsage prefers the usage block on the batch object and falls
+// back to summing per-request usage from the output file, which is what the
+// upstream reports for endpoints whose batch object carries no usage.
+func collectBatchUsage(ctx context.Context, job *model.BatchJob) (batchUsage, error) {
+	batch := gjson.Parse(job.Data)
+	usage := batchUsage{
+		CompletedCalls: int(batch.Get("request_counts.completed").Int()),
+	}
+	if u := batch.Get("usage"); u.Exists() {
+		usage.InputTokens = int(u.Get("input_tokens").Int())
+		usage.OutputTokens = int(u.Get("output_tokens").Int())
+		return usage, nil
+	}
+	if job.OutputFileId == "" {
+		return usage, nil
+	}
+
+	resp, err := DoBatchUpstreamRequest(ctx, job.ChannelId, job.KeyIndex, http.MethodGet, "/v1/files/"+job.OutputFileId+"/content", nil, "")
+	if err != nil {
+		return usage, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return usage, fmt.Errorf("download output file %s: upstream returned status %d", job.OutputFileId, resp.StatusCode)
+	}
+	usage.CompletedCalls = 0
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := gjson.ParseBytes(scanner.Bytes())
+		if line.Get("response.status_code").Int() != http.StatusOK {
+			continue
+		}
+		usage.CompletedCalls++
+		u := line.Get("response.body.usage")
+		if v := u.Get("prompt_tokens"); v.Exists() {
+			usage.InputTokens += int(v.Int())
+		} else {
+			usage.InputTokens += int(u.Get("input_tokens").Int())
+		}
+		if v := u.Get("completion_tokens"); v.Exists() {
+			usage.OutputTokens += int(v.Int())
+		} else {
+			usage.OutputTokens += int(u.Get("output_tokens").Int())
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return usage, err
+	}
+	return usage, nil
+}
+
+// calculateBatchQuota prices the batch with the regular model/group ratios
+// (or per-call price for fixed-price models) and applies the batch discount.
+func calculateBatchQuota(job *model.BatchJob, usage batchUsage) (int, map[string]interface{}) {
+	discount := decimal.NewFromInt(int64(operation_setting.GetBatchSetting().DiscountPercent)).Div(decimal.NewFromInt(100))
+	groupRatio := ratio_setting.GetGroupRatio(job.Group)
+	other := map[string]interface{}{
+		"is_batch":       true,
+		"batch_id":       job.BatchId,
+		"group_ratio":    groupRatio,
+		"batch_discount": discount.InexactFloat64(),
+		"input_tokens":   usage.InputTokens,
+		"output_tokens":  usage.OutputTokens,
+	}
+
+	var quota decimal.Decimal
+	if modelPrice, usePrice := ratio_setting.GetModelPrice(job.Model, false); usePrice {
+		other["model_price"] = modelPrice
+		quota = decimal.NewFromFloat(modelPrice).
+			Mul(decimal.NewFromFloat(common.QuotaPerUnit)).
+			Mul(decimal.NewFromInt(int64(usage.CompletedCalls)))
+	} else {
+		modelRatio, _, _ := ratio_setting.GetModelRatio(job.Model)
+		completionRatio := ratio_setting.GetCompletionRatio(job.Model)
+		other["model_ratio"] = modelRatio
+		other["completion_ratio"] = completionRatio
+		quota = decimal.NewFromInt(int64(usage.InputTokens)).
+			Add(decimal.NewFromInt(int64(usage.OutputTokens)).Mul(decimal.NewFromFloat(completionRatio))).
+			Mul(decimal.NewFromFloat(modelRatio))
+	}
+	quota = quota.Mul(decimal.NewFromFloat(groupRatio)).Mul(discount).Round(0)
+	return int(quota.IntPart()), other
+}
+
+// RunBatchPollingOnce refreshes every unsettled batch job once, settling the
+// ones that reached a terminal status. It honors ctx cancellation between jobs.
+func RunBatchPollingOnce(ctx context.Context, report func(processed, total int)) BatchPollSummary {
+	summary := BatchPollSummary{}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	jobs, err := model.GetUnsettledBatchJobs(batchPollLimit)
+	if err != nil {
+		common.SysLog(fmt.Sprintf("batch polling: failed to load jobs: %v", err))
+		return summary
+	}
+	summary.UnsettledJobs = len(jobs)
+	for i, job := range jobs {
+		if ctx.Err() != nil {
+			break
+		}
+		if report != nil {
+			report(i, len(jobs))
+		}
+		if _, err := RefreshBatchJob(ctx, job); err != nil {
+			summary.Failed++
+			logger.LogWarn(ctx, fmt.Sprintf("batch %s 刷新失败: %s", job.BatchId, err.Error()))
+			continue
+		}
+		summary.Refreshed++
+		if job.Settled {
+			summary.Settled++
+		}
+	}
+	return summary
+}
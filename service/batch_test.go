@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setBatchModelRatioForTest(t *testing.T) {
+	t.Helper()
+	oldRatios := ratio_setting.ModelRatio2JSONString()
+	require.NoError(t, ratio_setting.UpdateModelRatioByJSONString(`{"batch-test-model": 2}`))
+	t.Cleanup(func() {
+		_ = ratio_setting.UpdateModelRatioByJSONString(oldRatios)
+	})
+}
+
+func TestCalculateBatchQuotaAppliesDiscount(t *testing.T) {
+	setBatchModelRatioForTest(t)
+	setting := operation_setting.GetBatchSetting()
+	oldDiscount := setting.DiscountPercent
+	t.Cleanup(func() { setting.DiscountPercent = oldDiscount })
+
+	job := &model.BatchJob{BatchId: "batch_calc", Model: "batch-test-model", Group: "default"}
+	usage := batchUsage{InputTokens: 1000, OutputTokens: 500, CompletedCalls: 3}
+
+	setting.DiscountPercent = 100
+	fullQuota, _ := calculateBatchQuota(job, usage)
+	setting.DiscountPercent = 50
+	discountedQuota, other := calculateBatchQuota(job, usage)
+
+	completionRatio := ratio_setting.GetCompletionRatio("batch-test-model")
+	groupRatio := ratio_setting.GetGroupRatio("default")
+	assert.InDelta(t, (1000+500*completionRatio)*2*groupRatio, float64(fullQuota), 1)
+	assert.InDelta(t, float64(fullQuota)/2, float64(discountedQuota), 1)
+	assert.Equal(t, true, other["is_batch"])
+	assert.Equal(t, 0.5, other["batch_discount"])
+}
+
+func TestSettleBatchJobChargesOnce(t *testing.T) {
+	truncate(t)
+	setBatchModelRatioForTest(t)
+	seedUser(t, 1, 100000)
+
+	job := &model.BatchJob{
+		BatchId: "batch_settle",
+		UserId:  1,
+		Group:   "default",
+		Model:   "batch-test-model",
+		Status:  model.BatchStatusCompleted,
+		Data:    `{"id":"batch_settle","status":"completed","request_counts":{"completed":2},"usage":{"input_tokens":1000,"output_tokens":0}}`,
+	}
+	require.NoError(t, job.Insert())
+
+	require.NoError(t, SettleBatchJob(context.Background(), job))
+	assert.True(t, job.Settled)
+	assert.Positive(t, job.Quota)
+
+	// a concurrent refresh holding a stale copy must not charge again
+	stale := *job
+	stale.Settled = false
+	require.NoError(t, SettleBatchJob(context.Background(), &stale))
+
+	assert.Equal(t, 100000-job.Quota, getUserQuota(t, 1))
+	stored, err := model.GetUserBatchJob(1, "batch_settle")
+	require.NoError(t, err)
+	assert.True(t, stored.Settled)
+	assert.Equal(t, job.Quota, stored.Quota)
+}
@@ -0,0 +1,294 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+)
+
+const (
+	MaxLoadTestRequests    = 10000
+	MaxLoadTestConcurrency = 256
+	loadTestRequestTimeout = 5 * time.Minute
+)
+
+type LoadTestRequest struct {
+	TokenId     int    `json:"token_id"`
+	Model       string `json:"model"`
+	Requests    int    `json:"requests"`
+	Concurrency int    `json:"concurrency"`
+	Stream      bool   `json:"stream"`
+	MaxTokens   int    `json:"max_tokens"`
+}
+
+type LoadTestResult struct {
+	Requests     int            `json:"requests"`
+	Succeeded    int            `json:"succeeded"`
+	Failed       int            `json:"failed"`
+	StatusCodes  map[string]int `json:"status_codes"`
+	DurationMs   int64          `json:"duration_ms"`
+	RequestsPerS float64        `json:"requests_per_second"`
+	LatencyMs    LatencySummary `json:"latency_ms"`
+	// FirstByteMs is the time until the first response byte, which for streams
+	// approximates time-to-first-token including gateway overhead.
+	FirstByteMs LatencySummary `json:"first_byte_ms"`
+	Errors      []string       `json:"errors,omitempty"`
+}
+
+type LatencySummary struct {
+	Min int64 `json:"min"`
+	Avg int64 `json:"avg"`
+	P50 int64 `json:"p50"`
+	P95 int64 `json:"p95"`
+	P99 int64 `json:"p99"`
+	Max int64 `json:"max"`
+}
+
+type loadTestSample struct {
+	statusCode  int
+	latency     time.Duration
+	firstByte   time.Duration
+	errorString string
+}
+
+// ValidateLoadTestRequest bounds the run and makes sure every enabled channel
+// serving the model is a mock channel, so a load test can never spend real
+// upstream credits.
+func ValidateLoadTestRequest(req *LoadTestRequest) error {
+	if req.Model == "" {
+		return errors.New("model is required")
+	}
+	if req.Requests <= 0 || req.Requests > MaxLoadTestRequests {
+		return fmt.Errorf("requests must be between 1 and %d", MaxLoadTestRequests)
+	}
+	if req.Concurrency <= 0 {
+		req.Concurrency = 1
+	}
+	if req.Concurrency > MaxLoadTestConcurrency {
+		return fmt.Errorf("concurrency must be at most %d", MaxLoadTestConcurrency)
+	}
+	if req.Concurrency > req.Requests {
+		req.Concurrency = req.Requests
+	}
+	channelTypes, err := model.GetModelEnabledChannelTypes(req.Model)
+	if err != nil {
+		return err
+	}
+	if len(channelTypes) == 0 {
+		return fmt.Errorf("no enabled channel serves model %s", req.Model)
+	}
+	for _, channelType := range channelTypes {
+		if channelType != constant.ChannelTypeMock {
+			return fmt.Errorf("model %s is served by non-mock channels; load tests only run against mock channels", req.Model)
+		}
+	}
+	return nil
+}
+
+// loadTestTaskPayload is stored on the mock_load_test task row. It keeps the
+// token id and owner rather than the key, which is resolved when the task runs.
+type loadTestTaskPayload struct {
+	LoadTestRequest
+	UserId int `json:"user_id"`
+}
+
+// mockLoadTestHandler runs an on-demand load test on whichever master node
+// claims the task; progress is reported as completed requests.
+type mockLoadTestHandler struct{}
+
+func init() {
+	RegisterSystemTaskHandler(mockLoadTestHandler{})
+}
+
+func (mockLoadTestHandler) Type() string { return model.SystemTaskTypeMockLoadTest }
+
+func (mockLoadTestHandler) Run(ctx context.Context, task *model.SystemTask, runnerID string) {
+	payload := loadTestTaskPayload{}
+	if err := task.DecodePayload(&payload); err != nil {
+		failSystemTask(task, runnerID, err)
+		return
+	}
+	token, err := model.GetTokenByIds(payload.TokenId, payload.UserId)
+	if err != nil {
+		failSystemTask(task, runnerID, fmt.Errorf("load test token not found: %w", err))
+		return
+	}
+	result, err := RunLoadTest(ctx, payload.LoadTestRequest, token.Key, NewSystemTaskProgressReporter(task, runnerID))
+	if err != nil {
+		failSystemTask(task, runnerID, err)
+		return
+	}
+	if err := model.FinishSystemTask(task.TaskID, runnerID, model.SystemTaskStatusSucceeded, result, ""); err != nil {
+		logSystemTaskLockError(ctx, task, err)
+	}
+}
+
+// StartLoadTest enqueues a validated load test owned by userId. As with other
+// on-demand tasks, created is false when a load test is already active and
+// that task is returned instead.
+func StartLoadTest(req LoadTestRequest, userId int) (task *model.SystemTask, created bool, err error) {
+	return EnqueueSystemTask(model.SystemTaskTypeMockLoadTest, loadTestTaskPayload{LoadTestRequest: req, UserId: userId})
+}
+
+// RunLoadTest replays chat completions against this node's own HTTP listener
+// with the given token, so every request passes through the real middleware
+// chain: token auth, rate limits, channel selection, billing and logging.
+// progress, when set, is called from the calling goroutine after each request.
+func RunLoadTest(ctx context.Context, req LoadTestRequest, tokenKey string, progress func(processed, total int)) (*LoadTestResult, error) {
+	body, err := common.Marshal(map[string]any{
+		"model":          req.Model,
+		"stream":         req.Stream,
+		"max_tokens":     req.MaxTokens,
+		"stream_options": map[string]any{"include_usage": req.Stream},
+		"messages": []map[string]string{
+			{"role": "user", "content": "benchmark"},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("http://127.0.0.1:%s/v1/chat/completions", loadTestListenPort())
+	client := &http.Client{Timeout: loadTestRequestTimeout}
+
+	samples := make([]loadTestSample, req.Requests)
+	jobs := make(chan int)
+	completed := make(chan struct{}, req.Concurrency)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := 0; w < req.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				samples[i] = runLoadTestRequest(ctx, client, url, tokenKey, body)
+				completed <- struct{}{}
+			}
+		}()
+	}
+	go func() {
+		for i := 0; i < req.Requests; i++ {
+			if ctx.Err() != nil {
+				samples[i] = loadTestSample{errorString: ctx.Err().Error()}
+				completed <- struct{}{}
+				continue
+			}
+			jobs <- i
+		}
+		close(jobs)
+	}()
+	for processed := 1; processed <= req.Requests; processed++ {
+		<-completed
+		if progress != nil {
+			progress(processed, req.Requests)
+		}
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	return summarizeLoadTest(samples, elapsed), nil
+}
+
+func runLoadTestRequest(ctx context.Context, client *http.Client, url string, tokenKey string, body []byte) loadTestSample {
+	start := time.Now()
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return loadTestSample{errorString: err.Error()}
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer sk-"+tokenKey)
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return loadTestSample{latency: time.Since(start), errorString: err.Error()}
+	}
+	defer resp.Body.Close()
+
+	sample := loadTestSample{statusCode: resp.StatusCode}
+	reader := bufio.NewReader(resp.Body)
+	if _, err := reader.Peek(1); err == nil {
+		sample.firstByte = time.Since(start)
+	}
+	payload, err := io.ReadAll(reader)
+	sample.latency = time.Since(start)
+	if err != nil {
+		sample.errorString = err.Error()
+	} else if resp.StatusCode != http.StatusOK {
+		sample.errorString = string(payload)
+	}
+	return sample
+}
+
+func summarizeLoadTest(samples []loadTestSample, elapsed time.Duration) *LoadTestResult {
+	result := &LoadTestResult{
+		Requests:    len(samples),
+		StatusCodes: map[string]int{},
+		DurationMs:  elapsed.Milliseconds(),
+	}
+	latencies := make([]int64, 0, len(samples))
+	firstBytes := make([]int64, 0, len(samples))
+	for _, sample := range samples {
+		result.StatusCodes[strconv.Itoa(sample.statusCode)]++
+		if sample.statusCode == http.StatusOK && sample.errorString == "" {
+			result.Succeeded++
+			latencies = append(latencies, sample.latency.Milliseconds())
+			firstBytes = append(firstBytes, sample.firstByte.Milliseconds())
+			continue
+		}
+		result.Failed++
+		// Keep a handful of distinct messages; the status histogram carries the rest.
+		if len(result.Errors) < 10 && sample.errorString != "" {
+			result.Errors = append(result.Errors, sample.errorString)
+		}
+	}
+	if elapsed > 0 {
+		result.RequestsPerS = float64(len(samples)) / elapsed.Seconds()
+	}
+	result.LatencyMs = summarizeLatencies(latencies)
+	result.FirstByteMs = summarizeLatencies(firstBytes)
+	return result
+}
+
+func summarizeLatencies(values []int64) LatencySummary {
+	if len(values) == 0 {
+		return LatencySummary{}
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	var sum int64
+	for _, v := range values {
+		sum += v
+	}
+	percentile := func(p int) int64 {
+		idx := (len(values)*p + 99) / 100
+		if idx <= 0 {
+			idx = 1
+		}
+		return values[idx-1]
+	}
+	return LatencySummary{
+		Min: values[0],
+		Avg: sum / int64(len(values)),
+		P50: percentile(50),
+		P95: percentile(95),
+		P99: percentile(99),
+		Max: values[len(values)-1],
+	}
+}
+
+func loadTestListenPort() string {
+	if port := os.Getenv("PORT"); port != "" {
+		return port
+	}
+	return strconv.Itoa(*common.Port)
+}
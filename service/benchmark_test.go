@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateLoadTestRequest(t *testing.T) {
+	truncate(t)
+	mockChannel := &model.Channel{Name: "bench", Type: constant.ChannelTypeMock, Key: "mock", Status: common.ChannelStatusEnabled, Models: "mock-gpt,shared-model", Group: "default"}
+	require.NoError(t, mockChannel.Insert())
+	realChannel := &model.Channel{Name: "real", Type: constant.ChannelTypeOpenAI, Key: "sk-real", Status: common.ChannelStatusEnabled, Models: "shared-model", Group: "default"}
+	require.NoError(t, realChannel.Insert())
+
+	tests := []struct {
+		name            string
+		req             LoadTestRequest
+		wantConcurrency int
+		wantErr         string
+	}{
+		{name: "mock only model", req: LoadTestRequest{Model: "mock-gpt", Requests: 10, Concurrency: 4}, wantConcurrency: 4},
+		{name: "default concurrency", req: LoadTestRequest{Model: "mock-gpt", Requests: 10}, wantConcurrency: 1},
+		{name: "concurrency capped by requests", req: LoadTestRequest{Model: "mock-gpt", Requests: 2, Concurrency: 8}, wantConcurrency: 2},
+		{name: "missing model", req: LoadTestRequest{Requests: 1}, wantErr: "model is required"},
+		{name: "no requests", req: LoadTestRequest{Model: "mock-gpt"}, wantErr: "requests must be between"},
+		{name: "too many requests", req: LoadTestRequest{Model: "mock-gpt", Requests: MaxLoadTestRequests + 1}, wantErr: "requests must be between"},
+		{name: "too much concurrency", req: LoadTestRequest{Model: "mock-gpt", Requests: 10, Concurrency: MaxLoadTestConcurrency + 1}, wantErr: "concurrency must be at most"},
+		{name: "model served by real channel", req: LoadTestRequest{Model: "shared-model", Requests: 1}, wantErr: "non-mock channels"},
+		{name: "unknown model", req: LoadTestRequest{Model: "missing-model", Requests: 1}, wantErr: "no enabled channel"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			err := ValidateLoadTestRequest(&req)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantConcurrency, req.Concurrency)
+		})
+	}
+}
+
+func TestSummarizeLoadTest(t *testing.T) {
+	samples := []loadTestSample{
+		{statusCode: http.StatusOK, latency: 30 * time.Millisecond, firstByte: 10 * time.Millisecond},
+		{statusCode: http.StatusOK, latency: 10 * time.Millisecond, firstByte: 5 * time.Millisecond},
+		{statusCode: http.StatusOK, latency: 20 * time.Millisecond, firstByte: 6 * time.Millisecond},
+		{statusCode: http.StatusTooManyRequests, latency: time.Millisecond, errorString: "rate limited"},
+		{errorString: "connection refused"},
+	}
+
+	result := summarizeLoadTest(samples, time.Second)
+
+	assert.Equal(t, 5, result.Requests)
+	assert.Equal(t, 3, result.Succeeded)
+	assert.Equal(t, 2, result.Failed)
+	assert.Equal(t, map[string]int{"200": 3, "429": 1, "0": 1}, result.StatusCodes)
+	assert.Equal(t, []string{"rate limited", "connection refused"}, result.Errors)
+	assert.InDelta(t, 5.0, result.RequestsPerS, 0.001)
+	assert.Equal(t, LatencySummary{Min: 10, Avg: 20, P50: 20, P95: 30, P99: 30, Max: 30}, result.LatencyMs)
+	assert.Equal(t, int64(5), result.FirstByteMs.Min)
+	assert.Equal(t, int64(10), result.FirstByteMs.Max)
+}
+
+func TestSummarizeLatencies(t *testing.T) {
+	values := make([]int64, 0, 100)
+	for i := int64(100); i >= 1; i-- {
+		values = append(values, i)
+	}
+
+	summary := summarizeLatencies(values)
+
+	assert.Equal(t, LatencySummary{Min: 1, Avg: 50, P50: 50, P95: 95, P99: 99, Max: 100}, summary)
+	assert.Equal(t, LatencySummary{}, summarizeLatencies(nil))
+}
+
+func TestRunLoadTest(t *testing.T) {
+	var served atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer sk-bench-token", r.Header.Get("Authorization"))
+		if served.Add(1)%2 == 0 {
+			http.Error(w, "busy", http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"ok"}`))
+	}))
+	defer server.Close()
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+	t.Setenv("PORT", port)
+
+	var reported []int
+	result, err := RunLoadTest(context.Background(), LoadTestRequest{Model: "mock-gpt", Requests: 6, Concurrency: 3}, "bench-token", func(processed, total int) {
+		assert.Equal(t, 6, total)
+		reported = append(reported, processed)
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6}, reported)
+	assert.Equal(t, int32(6), served.Load())
+	assert.Equal(t, 6, result.Requests)
+	assert.Equal(t, 3, result.Succeeded)
+	assert.Equal(t, 3, result.Failed)
+	assert.Equal(t, map[string]int{"200": 3, "429": 3}, result.StatusCodes)
+}
+
+func TestMockLoadTestHandlerRunsTaskWithProgress(t *testing.T) {
+	truncate(t)
+	token := &model.Token{UserId: 7, Key: "bench-task-token", Name: "bench", Status: common.TokenStatusEnabled}
+	require.NoError(t, model.DB.Create(token).Error)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer sk-bench-task-token", r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"id":"ok"}`))
+	}))
+	defer server.Close()
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+	t.Setenv("PORT", port)
+
+	task, created, err := StartLoadTest(LoadTestRequest{TokenId: token.Id, Model: "mock-gpt", Requests: 4, Concurrency: 2}, 7)
+	require.NoError(t, err)
+	require.True(t, created)
+	_, created, err = StartLoadTest(LoadTestRequest{TokenId: token.Id, Model: "mock-gpt", Requests: 1}, 7)
+	require.NoError(t, err)
+	assert.False(t, created)
+
+	claimed, ok, err := model.ClaimSystemTask(task.ID, task.Type, "runner-a", common.GetTimestamp()+60)
+	require.NoError(t, err)
+	require.True(t, ok)
+	mockLoadTestHandler{}.Run(context.Background(), claimed, "runner-a")
+
+	finished, err := model.GetSystemTaskByTaskID(task.TaskID)
+	require.NoError(t, err)
+	assert.Equal(t, model.SystemTaskStatusSucceeded, finished.Status)
+	var progress SystemTaskProgress
+	require.NoError(t, finished.DecodeState(&progress))
+	assert.Equal(t, SystemTaskProgress{Total: 4, Processed: 4, Progress: 100}, progress)
+	var result LoadTestResult
+	require.NoError(t, common.UnmarshalJsonStr(finished.Result, &result))
+	assert.Equal(t, 4, result.Succeeded)
+}
+
+func TestRunLoadTestStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	t.Setenv("PORT", "1")
+
+	result, err := RunLoadTest(ctx, LoadTestRequest{Model: "mock-gpt", Requests: 3, Concurrency: 1}, "bench-token", nil)
+
+	require.NoError(t, err)
+	assert.Zero(t, result.Succeeded)
+	assert.Equal(t, 3, result.Failed)
+	assert.Contains(t, result.Errors, context.Canceled.Error())
+}
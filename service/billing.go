@@ -5,9 +5,11 @@ import (
 	"net/http"
 
 	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/pkg/tracing"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
 	"github.com/QuantumNous/new-api/types"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const (
@@ -34,10 +36,13 @@ func PreConsumeBilling(c *gin.Context, preConsumedQuota int, relayInfo *relaycom
 			types.ErrOptionWithSkipRetry(),
 		)
 	}
+	span := tracing.StartSpan(c, "quota.pre_consume", attribute.Int("quota.amount", preConsumedQuota))
 	session, apiErr := NewBillingSession(c, relayInfo, preConsumedQuota)
 	if apiErr != nil {
+		tracing.End(span, apiErr)
 		return apiErr
 	}
+	span.End()
 	relayInfo.Billing = session
 	return nil
 }
@@ -71,7 +76,10 @@ func SettleBilling(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, actualQuo
 			))
 		}
 
-		if err := relayInfo.Billing.Settle(actualQuota); err != nil {
+		span := tracing.StartSpan(ctx, "quota.settle", attribute.Int("quota.amount", actualQuota), attribute.Int("quota.delta", delta))
+		err := relayInfo.Billing.Settle(actualQuota)
+		tracing.End(span, err)
+		if err != nil {
 			return err
 		}
 
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+)
+
+const bodyLogCleanupBatchSize = 500
+
+var (
+	// bodyLogDataURLPattern matches inline base64 data URLs (images, audio,
+	// files) in OpenAI-style payloads.
+	bodyLogDataURLPattern = regexp.MustCompile(`data:[a-zA-Z0-9.+-]+/[a-zA-Z0-9.+-]+;base64,[A-Za-z0-9+/=]+`)
+	// bodyLogBase64FieldPattern matches long base64 strings carried in plain
+	// JSON fields, e.g. Claude image sources, Gemini inline_data and image
+	// generation b64_json results.
+	bodyLogBase64FieldPattern = regexp.MustCompile(`"(data|b64_json|image|audio)"\s*:\s*"[A-Za-z0-9+/=]{256,}"`)
+
+	bodyLogMaskPatterns sync.Map // pattern -> *regexp.Regexp
+)
+
+// RedactBody applies the configured body log redaction rules: inline base64
+// payloads are replaced with a placeholder when StripImages is on, and every
+// match of a mask pattern is replaced with ***. Invalid patterns are skipped;
+// they are rejected when the option is saved.
+func RedactBody(body []byte, setting *operation_setting.BodyLogSetting) []byte {
+	if setting.StripImages {
+		body = bodyLogDataURLPattern.ReplaceAll(body, []byte("[base64 stripped]"))
+		body = bodyLogBase64FieldPattern.ReplaceAll(body, []byte(`"$1":"[base64 stripped]"`))
+	}
+	for _, pattern := range setting.MaskPatterns {
+		var re *regexp.Regexp
+		if cached, ok := bodyLogMaskPatterns.Load(pattern); ok {
+			re = cached.(*regexp.Regexp)
+		} else {
+			compiled, err := regexp.Compile(pattern)
+			if err != nil {
+				continue
+			}
+			bodyLogMaskPatterns.Store(pattern, compiled)
+			re = compiled
+		}
+		body = re.ReplaceAll(body, []byte("***"))
+	}
+	return body
+}
+
+// CompressBody gzips a redacted body for storage.
+func CompressBody(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(body); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DecompressBody reverses CompressBody for the root-only viewer.
+func DecompressBody(body []byte) ([]byte, error) {
+	if len(body) == 0 {
+		return nil, nil
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	return io.ReadAll(gz)
+}
+
+// RecordBodyLog redacts, compresses and stores one captured request/response
+// pair. Bodies are expected to be already cut to MaxBodyBytes.
+func RecordBodyLog(log *model.BodyLog, requestBody []byte, responseBody []byte) {
+	setting := operation_setting.GetBodyLogSetting()
+	var err error
+	if log.RequestBody, err = CompressBody(RedactBody(requestBody, setting)); err != nil {
+		common.SysError(fmt.Sprintf("failed to compress request body for %s: %v", log.RequestId, err))
+		return
+	}
+	if log.ResponseBody, err = CompressBody(RedactBody(responseBody, setting)); err != nil {
+		common.SysError(fmt.Sprintf("failed to compress response body for %s: %v", log.RequestId, err))
+		return
+	}
+	if err := model.CreateBodyLog(log); err != nil {
+		common.SysError(fmt.Sprintf("failed to record body log for %s: %v", log.RequestId, err))
+	}
+}
+
+type bodyLogCleanupPayload struct {
+	Cutoff int64 `json:"cutoff"`
+}
+
+type bodyLogCleanupResult struct {
+	Cutoff  int64 `json:"cutoff"`
+	Deleted int64 `json:"deleted"`
+}
+
+// bodyLogCleanupHandler prunes captured bodies older than their own retention
+// window, independent of the regular log retention.
+type bodyLogCleanupHandler struct{}
+
+func init() {
+	RegisterSystemTaskHandler(bodyLogCleanupHandler{})
+}
+
+func (bodyLogCleanupHandler) Type() string { return model.SystemTaskTypeBodyLogCleanup }
+
+func (bodyLogCleanupHandler) Enabled() bool {
+	return operation_setting.GetBodyLogSetting().RetentionDays > 0
+}
+
+func (bodyLogCleanupHandler) Interval() time.Duration { return time.Hour }
+
+func (bodyLogCleanupHandler) NewPayload() any {
+	days := operation_setting.GetBodyLogSetting().RetentionDays
+	return bodyLogCleanupPayload{Cutoff: common.GetTimestamp() - int64(days)*24*3600}
+}
+
+func (bodyLogCleanupHandler) Run(ctx context.Context, task *model.SystemTask, runnerID string) {
+	payload := bodyLogCleanupPayload{}
+	if err := task.DecodePayload(&payload); err != nil {
+		failSystemTask(task, runnerID, err)
+		return
+	}
+	result := &bodyLogCleanupResult{Cutoff: payload.Cutoff}
+	for {
+		if err := ctx.Err(); err != nil {
+			failSystemTask(task, runnerID, err)
+			return
+		}
+		deleted, err := model.DeleteBodyLogsBefore(payload.Cutoff, bodyLogCleanupBatchSize)
+		if err != nil {
+			failSystemTask(task, runnerID, err)
+			return
+		}
+		result.Deleted += deleted
+		if deleted < bodyLogCleanupBatchSize {
+			break
+		}
+	}
+	if err := model.FinishSystemTask(task.TaskID, runnerID, model.SystemTaskStatusSucceeded, result, ""); err != nil {
+		logSystemTaskLockError(ctx, task, err)
+	}
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactBody(t *testing.T) {
+	longBase64 := strings.Repeat("QUJD", 100)
+	tests := []struct {
+		name    string
+		setting operation_setting.BodyLogSetting
+		body    string
+		want    string
+	}{
+		{
+			name:    "strip data url",
+			setting: operation_setting.BodyLogSetting{StripImages: true},
+			body:    `{"image_url":{"url":"data:image/png;base64,iVBORw0KGgo="}}`,
+			want:    `{"image_url":{"url":"[base64 stripped]"}}`,
+		},
+		{
+			name:    "strip long base64 field",
+			setting: operation_setting.BodyLogSetting{StripImages: true},
+			body:    `{"source":{"type":"base64","data":"` + longBase64 + `"}}`,
+			want:    `{"source":{"type":"base64","data":"[base64 stripped]"}}`,
+		},
+		{
+			name:    "short data field kept",
+			setting: operation_setting.BodyLogSetting{StripImages: true},
+			body:    `{"data":"abc"}`,
+			want:    `{"data":"abc"}`,
+		},
+		{
+			name:    "images kept when disabled",
+			setting: operation_setting.BodyLogSetting{},
+			body:    `{"url":"data:image/png;base64,iVBORw0KGgo="}`,
+			want:    `{"url":"data:image/png;base64,iVBORw0KGgo="}`,
+		},
+		{
+			name:    "mask patterns",
+			setting: operation_setting.BodyLogSetting{MaskPatterns: []string{`\d{11}`, `[\w.]+@[\w.]+`, `(`}},
+			body:    `call 13800138000 or mail a.b@example.com`,
+			want:    `call *** or mail ***`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, string(RedactBody([]byte(tt.body), &tt.setting)))
+		})
+	}
+}
+
+func TestCompressBodyRoundTrip(t *testing.T) {
+	body := []byte(strings.Repeat(`{"role":"user","content":"hello"}`, 50))
+	compressed, err := CompressBody(body)
+	require.NoError(t, err)
+	require.Less(t, len(compressed), len(body))
+	decompressed, err := DecompressBody(compressed)
+	require.NoError(t, err)
+	require.Equal(t, body, decompressed)
+
+	empty, err := DecompressBody(nil)
+	require.NoError(t, err)
+	require.Nil(t, empty)
+}
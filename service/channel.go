@@ -30,6 +30,12 @@ func DisableChannel(channelError types.ChannelError, reason string) {
 		subject := fmt.Sprintf("通道「%s」（#%d）已被禁用", channelError.ChannelName, channelError.ChannelId)
 		content := fmt.Sprintf("通道「%s」（#%d）已被禁用，原因：%s", channelError.ChannelName, channelError.ChannelId, reason)
 		NotifyRootUser(formatNotifyType(channelError.ChannelId, common.ChannelStatusAutoDisabled), subject, content)
+		PublishWebhookEvent(WebhookEventChannelAutoDisabled, map[string]any{
+			"channel_id":   channelError.ChannelId,
+			"channel_name": channelError.ChannelName,
+			"channel_type": channelError.ChannelType,
+			"reason":       reason,
+		})
 	}
 }
 
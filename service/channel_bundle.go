@@ -0,0 +1,406 @@
+package service
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+
+	"gopkg.in/yaml.v3"
+)
+
+const ChannelBundleVersion = 1
+
+var ErrChannelBundlePassphrase = errors.New("the bundle keys are encrypted, a passphrase is required")
+
+// ErrChannelBundleExportPassphrase is returned when keys are exported without a
+// passphrase: bundles leave the instance, so keys are never written in plaintext.
+var ErrChannelBundleExportPassphrase = errors.New("exporting channel keys requires a passphrase")
+
+// ChannelBundle is the portable form of a set of channels, used to replicate
+// channel setups between instances. Runtime state such as balance, used quota
+// and test results is not part of it.
+type ChannelBundle struct {
+	Version    int   `json:"version" yaml:"version"`
+	ExportedAt int64 `json:"exported_at" yaml:"exported_at"`
+	// KeySalt is set when the keys are encrypted with a passphrase; each key is
+	// then base64(nonce|ciphertext) under the scrypt-derived key.
+	KeySalt  string              `json:"key_salt,omitempty" yaml:"key_salt,omitempty"`
+	Channels []ChannelBundleItem `json:"channels" yaml:"channels"`
+}
+
+// ChannelBundleItem is one channel of a bundle. An empty key leaves the key
+// of an existing channel unchanged on import, so bundles can be kept in a
+// repository without secrets.
+type ChannelBundleItem struct {
+	Id                int     `json:"id,omitempty" yaml:"id,omitempty"`
+	Name              string  `json:"name" yaml:"name"`
+	Type              int     `json:"type" yaml:"type"`
+	Key               string  `json:"key,omitempty" yaml:"key,omitempty"`
+	Status            int     `json:"status,omitempty" yaml:"status,omitempty"`
+	MultiKey          bool    `json:"multi_key,omitempty" yaml:"multi_key,omitempty"`
+	MultiKeyMode      string  `json:"multi_key_mode,omitempty" yaml:"multi_key_mode,omitempty"`
+	BaseURL           string  `json:"base_url" yaml:"base_url,omitempty"`
+	Models            string  `json:"models" yaml:"models"`
+	Group             string  `json:"group" yaml:"group"`
+	Tag               string  `json:"tag" yaml:"tag,omitempty"`
+	Tags              string  `json:"tags" yaml:"tags,omitempty"`
+	Priority          int64   `json:"priority" yaml:"priority"`
+	Weight            uint    `json:"weight" yaml:"weight"`
+	AutoBan           int     `json:"auto_ban" yaml:"auto_ban"`
+	TestModel         string  `json:"test_model" yaml:"test_model,omitempty"`
+	ModelMapping      string  `json:"model_mapping" yaml:"model_mapping,omitempty"`
+	StatusCodeMapping string  `json:"status_code_mapping" yaml:"status_code_mapping,omitempty"`
+	ParamOverride     string  `json:"param_override" yaml:"param_override,omitempty"`
+	HeaderOverride    string  `json:"header_override" yaml:"header_override,omitempty"`
+	Setting           string  `json:"setting" yaml:"setting,omitempty"`
+	Settings          string  `json:"settings" yaml:"settings,omitempty"`
+	Other             string  `json:"other" yaml:"other,omitempty"`
+	Remark            string  `json:"remark" yaml:"remark,omitempty"`
+	OpenAIOrg         *string `json:"openai_organization,omitempty" yaml:"openai_organization,omitempty"`
+}
+
+func NewChannelBundleItem(channel *model.Channel) ChannelBundleItem {
+	deref := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	}
+	item := ChannelBundleItem{
+		Id:                channel.Id,
+		Name:              channel.Name,
+		Type:              channel.Type,
+		Key:               channel.Key,
+		Status:            channel.Status,
+		MultiKey:          channel.ChannelInfo.IsMultiKey,
+		BaseURL:           deref(channel.BaseURL),
+		Models:            channel.Models,
+		Group:             channel.Group,
+		Tag:               deref(channel.Tag),
+		Tags:              deref(channel.Tags),
+		Priority:          channel.GetPriority(),
+		Weight:            uint(channel.GetWeight()),
+		AutoBan:           1,
+		TestModel:         deref(channel.TestModel),
+		ModelMapping:      deref(channel.ModelMapping),
+		StatusCodeMapping: deref(channel.StatusCodeMapping),
+		ParamOverride:     deref(channel.ParamOverride),
+		HeaderOverride:    deref(channel.HeaderOverride),
+		Setting:           deref(channel.Setting),
+		Settings:          channel.OtherSettings,
+		Other:             channel.Other,
+		Remark:            deref(channel.Remark),
+		OpenAIOrg:         channel.OpenAIOrganization,
+	}
+	if channel.ChannelInfo.IsMultiKey {
+		item.MultiKeyMode = string(channel.ChannelInfo.MultiKeyMode)
+	}
+	if channel.AutoBan != nil {
+		item.AutoBan = *channel.AutoBan
+	}
+	return item
+}
+
+// ApplyTo copies the item onto channel. The key and status are only replaced
+// when the item carries them.
+func (item ChannelBundleItem) ApplyTo(channel *model.Channel) {
+	channel.Name = item.Name
+	channel.Type = item.Type
+	if item.Key != "" {
+		channel.Key = item.Key
+	}
+	if item.Status != 0 {
+		channel.Status = item.Status
+	}
+	if item.MultiKey {
+		channel.ChannelInfo.IsMultiKey = true
+		if item.MultiKeyMode != "" {
+			channel.ChannelInfo.MultiKeyMode = constant.MultiKeyMode(item.MultiKeyMode)
+		}
+		if item.Key != "" {
+			channel.ChannelInfo.MultiKeySize = len(strings.Split(strings.Trim(item.Key, "\n"), "\n"))
+		}
+	}
+	channel.BaseURL = &item.BaseURL
+	channel.Models = item.Models
+	channel.Group = item.Group
+	channel.Tag = &item.Tag
+	channel.SetTags(strings.Split(item.Tags, ","))
+	channel.Priority = &item.Priority
+	channel.Weight = &item.Weight
+	channel.AutoBan = &item.AutoBan
+	channel.TestModel = &item.TestModel
+	channel.ModelMapping = &item.ModelMapping
+	channel.StatusCodeMapping = &item.StatusCodeMapping
+	channel.ParamOverride = &item.ParamOverride
+	channel.HeaderOverride = &item.HeaderOverride
+	channel.Setting = &item.Setting
+	channel.OtherSettings = item.Settings
+	channel.Other = item.Other
+	channel.Remark = &item.Remark
+	channel.OpenAIOrganization = item.OpenAIOrg
+}
+
+// ExportChannelBundle bundles all channels. Keys are left out unless
+// includeKeys is set, in which case they are encrypted with the passphrase.
+func ExportChannelBundle(includeKeys bool, passphrase string) (*ChannelBundle, error) {
+	if includeKeys && passphrase == "" {
+		return nil, ErrChannelBundleExportPassphrase
+	}
+	channels, err := model.GetAllChannels(0, 0, true, true)
+	if err != nil {
+		return nil, err
+	}
+	bundle := &ChannelBundle{
+		Version:    ChannelBundleVersion,
+		ExportedAt: common.GetTimestamp(),
+		Channels:   make([]ChannelBundleItem, 0, len(channels)),
+	}
+	var aead cipher.AEAD
+	if includeKeys {
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		if aead, err = common.NewPassphraseAEAD(passphrase, salt); err != nil {
+			return nil, err
+		}
+		bundle.KeySalt = base64.StdEncoding.EncodeToString(salt)
+	}
+	for _, channel := range channels {
+		item := NewChannelBundleItem(channel)
+		if aead == nil {
+			item.Key = ""
+		} else if item.Key, err = common.SealString(aead, item.Key); err != nil {
+			return nil, err
+		}
+		bundle.Channels = append(bundle.Channels, item)
+	}
+	return bundle, nil
+}
+
+// MarshalChannelBundle encodes the bundle as "json" or "yaml".
+func MarshalChannelBundle(bundle *ChannelBundle, format string) ([]byte, error) {
+	switch format {
+	case "", "json":
+		return common.Marshal(bundle)
+	case "yaml", "yml":
+		return yaml.Marshal(bundle)
+	default:
+		return nil, fmt.Errorf("unsupported bundle format %q", format)
+	}
+}
+
+// ParseChannelBundle decodes a JSON or YAML bundle. YAML is a superset of
+// JSON, so the YAML decoder handles both; unknown fields are rejected to
+// catch typos early.
+func ParseChannelBundle(data []byte) (*ChannelBundle, error) {
+	var bundle ChannelBundle
+	decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&bundle); err != nil {
+		return nil, fmt.Errorf("invalid channel bundle: %w", err)
+	}
+	if bundle.Version > ChannelBundleVersion {
+		return nil, fmt.Errorf("unsupported channel bundle version %d", bundle.Version)
+	}
+	names := make(map[string]bool, len(bundle.Channels))
+	for i, item := range bundle.Channels {
+		if strings.TrimSpace(item.Name) == "" {
+			return nil, fmt.Errorf("channel #%d: name is required", i+1)
+		}
+		if names[item.Name] {
+			return nil, fmt.Errorf("channel %q appears more than once", item.Name)
+		}
+		names[item.Name] = true
+	}
+	return &bundle, nil
+}
+
+// DecryptChannelBundleKeys replaces the encrypted keys of the bundle with
+// their plaintext.
+func DecryptChannelBundleKeys(bundle *ChannelBundle, passphrase string) error {
+	if bundle.KeySalt == "" {
+		return nil
+	}
+	if passphrase == "" {
+		return ErrChannelBundlePassphrase
+	}
+	salt, err := base64.StdEncoding.DecodeString(bundle.KeySalt)
+	if err != nil {
+		return fmt.Errorf("invalid key salt: %w", err)
+	}
+	aead, err := common.NewPassphraseAEAD(passphrase, salt)
+	if err != nil {
+		return err
+	}
+	for i := range bundle.Channels {
+		if bundle.Channels[i].Key == "" {
+			continue
+		}
+		key, err := common.OpenString(aead, bundle.Channels[i].Key)
+		if err != nil {
+			return fmt.Errorf("channel %q: failed to decrypt key, wrong passphrase?", bundle.Channels[i].Name)
+		}
+		bundle.Channels[i].Key = key
+	}
+	bundle.KeySalt = ""
+	return nil
+}
+
+const (
+	ChannelImportCreate    = "create"
+	ChannelImportUpdate    = "update"
+	ChannelImportUnchanged = "unchanged"
+)
+
+type ChannelImportChange struct {
+	Name      string `json:"name"`
+	ChannelId int    `json:"channel_id,omitempty"`
+	Action    string `json:"action"`
+	// Fields lists the changed fields of an update; key values are never
+	// included in the diff.
+	Fields []ChannelFieldChange `json:"fields,omitempty"`
+}
+
+type ChannelFieldChange struct {
+	Field  string `json:"field"`
+	Before any    `json:"before,omitempty"`
+	After  any    `json:"after,omitempty"`
+}
+
+// ImportChannelBundle matches the bundle channels with existing channels by
+// name, since ids differ between instances, and creates or updates them.
+// With dryRun it only reports the changes.
+func ImportChannelBundle(bundle *ChannelBundle, dryRun bool) ([]ChannelImportChange, error) {
+	existing, err := model.GetAllChannels(0, 0, true, true)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]*model.Channel, len(existing))
+	duplicated := make(map[string]bool)
+	for _, channel := range existing {
+		if byName[channel.Name] != nil {
+			duplicated[channel.Name] = true
+		}
+		byName[channel.Name] = channel
+	}
+
+	names := make(map[string]bool, len(bundle.Channels))
+	for _, item := range bundle.Channels {
+		if strings.TrimSpace(item.Name) == "" {
+			return nil, errors.New("channel name is required")
+		}
+		if names[item.Name] {
+			return nil, fmt.Errorf("channel %q appears more than once", item.Name)
+		}
+		names[item.Name] = true
+	}
+
+	changes := make([]ChannelImportChange, 0, len(bundle.Channels))
+	for _, item := range bundle.Channels {
+		if duplicated[item.Name] {
+			return nil, fmt.Errorf("channel %q matches several existing channels, rename them before importing", item.Name)
+		}
+		channel := byName[item.Name]
+		if channel == nil {
+			if strings.TrimSpace(item.Key) == "" {
+				return nil, fmt.Errorf("channel %q: key is required to create a channel", item.Name)
+			}
+			changes = append(changes, ChannelImportChange{Name: item.Name, Action: ChannelImportCreate})
+			continue
+		}
+		fields := diffChannelBundleItem(NewChannelBundleItem(channel), item)
+		change := ChannelImportChange{Name: item.Name, ChannelId: channel.Id, Action: ChannelImportUnchanged}
+		if len(fields) > 0 {
+			change.Action = ChannelImportUpdate
+			change.Fields = fields
+		}
+		changes = append(changes, change)
+	}
+	if dryRun {
+		return changes, nil
+	}
+
+	creates := make([]*model.Channel, 0)
+	createIndexes := make([]int, 0)
+	updates := make([]*model.Channel, 0)
+	for i, item := range bundle.Channels {
+		switch changes[i].Action {
+		case ChannelImportCreate:
+			channel := &model.Channel{Status: common.ChannelStatusEnabled, CreatedTime: common.GetTimestamp()}
+			item.ApplyTo(channel)
+			creates = append(creates, channel)
+			createIndexes = append(createIndexes, i)
+		case ChannelImportUpdate:
+			channel := byName[item.Name]
+			item.ApplyTo(channel)
+			updates = append(updates, channel)
+		}
+	}
+	// All channels are written in one transaction, so a failed import leaves
+	// both the database and the channel cache untouched.
+	if err := model.SaveImportedChannels(creates, updates); err != nil {
+		return nil, err
+	}
+	model.RefreshChannelCache()
+	ResetProxyClientCache()
+	for i, channel := range creates {
+		changes[createIndexes[i]].ChannelId = channel.Id
+	}
+	return changes, nil
+}
+
+// diffChannelBundleItem compares the fields an import would write. Fields the
+// incoming item leaves unset (key, status) keep their current value.
+func diffChannelBundleItem(current ChannelBundleItem, incoming ChannelBundleItem) []ChannelFieldChange {
+	if incoming.Key == "" {
+		incoming.Key = current.Key
+	}
+	if incoming.Status == 0 {
+		incoming.Status = current.Status
+	}
+	incoming.Id = current.Id
+	if !incoming.MultiKey {
+		incoming.MultiKey = current.MultiKey
+		incoming.MultiKeyMode = current.MultiKeyMode
+	} else if incoming.MultiKeyMode == "" {
+		incoming.MultiKeyMode = current.MultiKeyMode
+	}
+
+	changes := make([]ChannelFieldChange, 0)
+	currentValue := reflect.ValueOf(current)
+	incomingValue := reflect.ValueOf(incoming)
+	itemType := currentValue.Type()
+	value := func(v reflect.Value) any {
+		if v.Kind() != reflect.Pointer {
+			return v.Interface()
+		}
+		if v.IsNil() {
+			return nil
+		}
+		return v.Elem().Interface()
+	}
+	for i := 0; i < itemType.NumField(); i++ {
+		before := value(currentValue.Field(i))
+		after := value(incomingValue.Field(i))
+		if reflect.DeepEqual(before, after) {
+			continue
+		}
+		field := strings.Split(itemType.Field(i).Tag.Get("json"), ",")[0]
+		if field == "key" {
+			changes = append(changes, ChannelFieldChange{Field: field})
+			continue
+		}
+		changes = append(changes, ChannelFieldChange{Field: field, Before: before, After: after})
+	}
+	return changes
+}
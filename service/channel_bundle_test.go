@@ -0,0 +1,151 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseChannelBundle(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		wantCount int
+		wantErr   string
+	}{
+		{name: "yaml", data: "version: 1\nchannels:\n  - name: a\n    type: 1\n    models: gpt-4o\n", wantCount: 1},
+		{name: "json", data: `{"version":1,"channels":[{"name":"a","type":1},{"name":"b","type":14}]}`, wantCount: 2},
+		{name: "unknown field", data: "channels:\n  - name: a\n    modles: gpt-4o\n", wantErr: "modles"},
+		{name: "missing name", data: "channels:\n  - type: 1\n", wantErr: "name is required"},
+		{name: "duplicated name", data: "channels:\n  - name: a\n  - name: a\n", wantErr: "more than once"},
+		{name: "newer version", data: "version: 99\nchannels: []\n", wantErr: "unsupported"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bundle, err := ParseChannelBundle([]byte(tt.data))
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, bundle.Channels, tt.wantCount)
+		})
+	}
+}
+
+func TestChannelBundleExportImport(t *testing.T) {
+	truncate(t)
+	source := &model.Channel{Name: "openai-main", Type: 1, Key: "sk-secret", Status: common.ChannelStatusEnabled, Models: "gpt-4o", Group: "default"}
+	require.NoError(t, source.Insert())
+
+	tests := []struct {
+		name        string
+		includeKeys bool
+		passphrase  string
+		importPass  string
+		wantKey     string
+		exportErr   error
+		wantErr     error
+	}{
+		{name: "without keys keeps stored key", wantKey: "sk-secret"},
+		{name: "keys without passphrase", includeKeys: true, exportErr: ErrChannelBundleExportPassphrase},
+		{name: "encrypted keys", includeKeys: true, passphrase: "p@ss", importPass: "p@ss", wantKey: "sk-secret"},
+		{name: "encrypted keys without passphrase", includeKeys: true, passphrase: "p@ss", wantErr: ErrChannelBundlePassphrase},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bundle, err := ExportChannelBundle(tt.includeKeys, tt.passphrase)
+			if tt.exportErr != nil {
+				require.ErrorIs(t, err, tt.exportErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, bundle.Channels, 1)
+			if tt.passphrase != "" {
+				assert.NotEqual(t, "sk-secret", bundle.Channels[0].Key)
+			}
+
+			data, err := MarshalChannelBundle(bundle, "yaml")
+			require.NoError(t, err)
+			parsed, err := ParseChannelBundle(data)
+			require.NoError(t, err)
+			err = DecryptChannelBundleKeys(parsed, tt.importPass)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			parsed.Channels[0].Models = "gpt-4o,gpt-4o-mini"
+			changes, err := ImportChannelBundle(parsed, true)
+			require.NoError(t, err)
+			require.Len(t, changes, 1)
+			assert.Equal(t, ChannelImportUpdate, changes[0].Action)
+			assert.Equal(t, []ChannelFieldChange{{Field: "models", Before: "gpt-4o", After: "gpt-4o,gpt-4o-mini"}}, changes[0].Fields)
+
+			stored, err := model.GetChannelById(source.Id, true)
+			require.NoError(t, err)
+			assert.Equal(t, "gpt-4o", stored.Models, "dry run must not write")
+			assert.Equal(t, tt.wantKey, stored.Key)
+		})
+	}
+}
+
+func TestImportChannelBundleCreatesMissing(t *testing.T) {
+	truncate(t)
+	bundle := &ChannelBundle{Channels: []ChannelBundleItem{
+		{Name: "created", Type: 1, Key: "sk-new", Models: "gpt-4o", Group: "default", AutoBan: 1},
+		{Name: "no-key", Type: 1, Models: "gpt-4o", Group: "default"},
+	}}
+	_, err := ImportChannelBundle(bundle, false)
+	require.ErrorContains(t, err, "key is required")
+
+	bundle.Channels = bundle.Channels[:1]
+	changes, err := ImportChannelBundle(bundle, false)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, ChannelImportCreate, changes[0].Action)
+	require.NotZero(t, changes[0].ChannelId)
+
+	changes, err = ImportChannelBundle(bundle, true)
+	require.NoError(t, err)
+	assert.Equal(t, ChannelImportUnchanged, changes[0].Action)
+}
+
+func TestImportChannelBundleRejectsDuplicateNames(t *testing.T) {
+	truncate(t)
+	bundle := &ChannelBundle{Channels: []ChannelBundleItem{
+		{Name: "twice", Type: 1, Key: "sk-a", Models: "gpt-4o", Group: "default"},
+		{Name: "twice", Type: 1, Key: "sk-b", Models: "gpt-4o", Group: "default"},
+	}}
+	_, err := ImportChannelBundle(bundle, false)
+	require.ErrorContains(t, err, "more than once")
+
+	channels, err := model.GetAllChannels(0, 0, true, false)
+	require.NoError(t, err)
+	assert.Empty(t, channels)
+}
+
+func TestSaveImportedChannelsRollsBack(t *testing.T) {
+	truncate(t)
+	existing := &model.Channel{Name: "existing", Type: 1, Key: "sk-old", Status: common.ChannelStatusEnabled, Models: "gpt-4o", Group: "default"}
+	require.NoError(t, existing.Insert())
+
+	existing.Models = "gpt-4o,gpt-4o-mini"
+	creates := []*model.Channel{
+		{Name: "fresh", Type: 1, Key: "sk-new", Status: common.ChannelStatusEnabled, Models: "gpt-4o", Group: "default"},
+		{Id: existing.Id, Name: "conflicting", Type: 1, Key: "sk-x", Status: common.ChannelStatusEnabled, Models: "gpt-4o", Group: "default"},
+	}
+	err := model.SaveImportedChannels(creates, []*model.Channel{existing})
+	require.ErrorContains(t, err, "conflicting")
+
+	channels, err := model.GetAllChannels(0, 0, true, false)
+	require.NoError(t, err)
+	require.Len(t, channels, 1)
+	assert.Equal(t, "existing", channels[0].Name)
+	assert.Equal(t, "gpt-4o", channels[0].Models)
+}
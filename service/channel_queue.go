@@ -0,0 +1,209 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/pkg/tracing"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type channelQueueEntry struct {
+	priority int
+	// closed once the entry has been handed an in-flight slot
+	admitted chan struct{}
+}
+
+// channelQueue holds requests waiting for a slot of a saturated channel.
+// Waiters are admitted by group priority, then by arrival, as slots free up.
+// Like the in-flight counts it is local to the node.
+type channelQueue struct {
+	mu      sync.Mutex
+	waiting map[int][]*channelQueueEntry
+
+	queued   atomic.Int64
+	admitted atomic.Int64
+	timedOut atomic.Int64
+	rejected atomic.Int64
+}
+
+var chQueue = &channelQueue{waiting: map[int][]*channelQueueEntry{}}
+
+// enqueueLocked inserts entry after every waiter of equal or higher priority.
+func (q *channelQueue) enqueueLocked(channelId int, entry *channelQueueEntry) {
+	waiting := q.waiting[channelId]
+	index := sort.Search(len(waiting), func(i int) bool { return waiting[i].priority < entry.priority })
+	q.waiting[channelId] = slices.Insert(waiting, index, entry)
+}
+
+func (q *channelQueue) removeLocked(channelId int, entry *channelQueueEntry) bool {
+	waiting := q.waiting[channelId]
+	index := slices.Index(waiting, entry)
+	if index < 0 {
+		return false
+	}
+	if len(waiting) == 1 {
+		delete(q.waiting, channelId)
+	} else {
+		q.waiting[channelId] = slices.Delete(waiting, index, index+1)
+	}
+	return true
+}
+
+// admitLocked hands free slots of the channel to the head of its queue.
+func (q *channelQueue) admitLocked(channelId int, limit int) {
+	for len(q.waiting[channelId]) > 0 && model.TryAcquireChannelSlot(channelId, limit) {
+		head := q.waiting[channelId][0]
+		q.removeLocked(channelId, head)
+		q.admitted.Add(1)
+		close(head.admitted)
+	}
+}
+
+// releaseFunc returns the release callback of one acquired slot. It is safe
+// to call more than once; only the first call gives the slot back.
+func (q *channelQueue) releaseFunc(channelId int, limit int) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			model.ReleaseChannelSlot(channelId)
+			q.admitLocked(channelId, limit)
+		})
+	}
+}
+
+// AcquireChannelSlot takes an in-flight slot of the selected channel when it
+// has a max_concurrency cap. Channel selection already routes around
+// saturated channels, so a full channel here means every candidate is full:
+// with the channel queue enabled the request waits for a slot up to its
+// group's max wait, otherwise it is rejected with a retryable 429. The
+// returned release func must be called once the attempt finishes.
+func AcquireChannelSlot(c *gin.Context, info *relaycommon.RelayInfo, channelId int) (func(), *types.NewAPIError) {
+	otherSettings, _ := common.GetContextKeyType[dto.ChannelOtherSettings](c, constant.ContextKeyChannelOtherSetting)
+	limit := otherSettings.MaxConcurrency
+	if limit <= 0 {
+		return func() {}, nil
+	}
+	q := chQueue
+	q.mu.Lock()
+	// queued requests go first, a newcomer may not take a slot past them
+	if len(q.waiting[channelId]) == 0 && model.TryAcquireChannelSlot(channelId, limit) {
+		q.mu.Unlock()
+		return q.releaseFunc(channelId, limit), nil
+	}
+	setting := operation_setting.GetChannelQueueSetting()
+	maxWait := time.Duration(operation_setting.GetChannelQueueMaxWaitSeconds(info.UserGroup)) * time.Second
+	if !setting.Enabled || maxWait <= 0 || (setting.MaxQueueLength > 0 && len(q.waiting[channelId]) >= setting.MaxQueueLength) {
+		q.mu.Unlock()
+		q.rejected.Add(1)
+		return nil, types.NewErrorWithStatusCode(
+			fmt.Errorf("channel #%d is at its concurrency limit of %d", channelId, limit),
+			types.ErrorCodeRateLimitExceeded, http.StatusTooManyRequests)
+	}
+	entry := &channelQueueEntry{
+		priority: operation_setting.GetChannelQueuePriority(info.UserGroup),
+		admitted: make(chan struct{}),
+	}
+	q.enqueueLocked(channelId, entry)
+	q.mu.Unlock()
+	q.queued.Add(1)
+
+	span := tracing.StartSpan(c, "channel.queue",
+		attribute.Int("channel.id", channelId),
+		attribute.Int("queue.priority", entry.priority),
+	)
+	defer span.End()
+	timer := time.NewTimer(maxWait)
+	defer timer.Stop()
+	var reason error
+	select {
+	case <-entry.admitted:
+		return q.releaseFunc(channelId, limit), nil
+	case <-timer.C:
+		reason = fmt.Errorf("no free slot on channel #%d within %s", channelId, maxWait)
+	case <-c.Request.Context().Done():
+		reason = c.Request.Context().Err()
+	}
+
+	q.mu.Lock()
+	removed := q.removeLocked(channelId, entry)
+	q.mu.Unlock()
+	if !removed {
+		// admitted while giving up: keep the slot
+		return q.releaseFunc(channelId, limit), nil
+	}
+	q.timedOut.Add(1)
+	return nil, types.NewErrorWithStatusCode(reason, types.ErrorCodeRateLimitExceeded, http.StatusTooManyRequests, types.ErrOptionWithSkipRetry())
+}
+
+// ChannelQueueDepth is the load of one capped channel on this node.
+type ChannelQueueDepth struct {
+	ChannelId int `json:"channel_id"`
+	InFlight  int `json:"in_flight"`
+	Waiting   int `json:"waiting"`
+}
+
+// ChannelQueueStats reports the channel admission queue of this node. The
+// counters are cumulative since start or the last reset.
+type ChannelQueueStats struct {
+	Enabled  bool                `json:"enabled"`
+	Waiting  int                 `json:"waiting"`
+	Queued   int64               `json:"queued"`
+	Admitted int64               `json:"admitted"`
+	TimedOut int64               `json:"timed_out"`
+	Rejected int64               `json:"rejected"`
+	Channels []ChannelQueueDepth `json:"channels"`
+}
+
+func GetChannelQueueStats() ChannelQueueStats {
+	q := chQueue
+	inflight := model.GetChannelInflight()
+	stats := ChannelQueueStats{
+		Enabled:  operation_setting.GetChannelQueueSetting().Enabled,
+		Queued:   q.queued.Load(),
+		Admitted: q.admitted.Load(),
+		TimedOut: q.timedOut.Load(),
+		Rejected: q.rejected.Load(),
+		Channels: make([]ChannelQueueDepth, 0, len(inflight)),
+	}
+	q.mu.Lock()
+	for channelId, waiting := range q.waiting {
+		if _, ok := inflight[channelId]; !ok {
+			inflight[channelId] = 0
+		}
+		stats.Waiting += len(waiting)
+	}
+	for channelId, count := range inflight {
+		stats.Channels = append(stats.Channels, ChannelQueueDepth{
+			ChannelId: channelId,
+			InFlight:  count,
+			Waiting:   len(q.waiting[channelId]),
+		})
+	}
+	q.mu.Unlock()
+	slices.SortFunc(stats.Channels, func(a, b ChannelQueueDepth) int { return a.ChannelId - b.ChannelId })
+	return stats
+}
+
+func ResetChannelQueueStats() {
+	chQueue.queued.Store(0)
+	chQueue.admitted.Store(0)
+	chQueue.timedOut.Store(0)
+	chQueue.rejected.Store(0)
+}
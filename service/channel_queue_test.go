@@ -0,0 +1,176 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newChannelQueueContext(maxConcurrency int) *gin.Context {
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	common.SetContextKey(ctx, constant.ContextKeyChannelOtherSetting, dto.ChannelOtherSettings{MaxConcurrency: maxConcurrency})
+	return ctx
+}
+
+func TestAcquireChannelSlot(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	original := *operation_setting.GetChannelQueueSetting()
+	t.Cleanup(func() {
+		*operation_setting.GetChannelQueueSetting() = original
+	})
+	setting := operation_setting.GetChannelQueueSetting()
+
+	tests := []struct {
+		name           string
+		queueEnabled   bool
+		maxWaitSeconds int
+		maxConcurrency int
+		expectedAllow  int
+	}{
+		{name: "uncapped channel", maxConcurrency: 0, expectedAllow: 3},
+		{name: "cap without queue rejects retryably", maxConcurrency: 2, expectedAllow: 2},
+		{name: "queue with no wait time rejects", queueEnabled: true, maxConcurrency: 1, expectedAllow: 1},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setting.Enabled = tt.queueEnabled
+			setting.MaxWaitSeconds = tt.maxWaitSeconds
+			channelId := 3000 + i
+			info := &relaycommon.RelayInfo{UserGroup: "default"}
+
+			releases := make([]func(), 0, 3)
+			var rejected *types.NewAPIError
+			for range 3 {
+				release, err := AcquireChannelSlot(newChannelQueueContext(tt.maxConcurrency), info, channelId)
+				if err != nil {
+					rejected = err
+					continue
+				}
+				releases = append(releases, release)
+			}
+			assert.Len(t, releases, tt.expectedAllow)
+			if tt.expectedAllow < 3 {
+				require.NotNil(t, rejected)
+				assert.Equal(t, http.StatusTooManyRequests, rejected.StatusCode)
+				assert.False(t, types.IsSkipRetryError(rejected))
+			}
+			for _, release := range releases {
+				release()
+			}
+			release, err := AcquireChannelSlot(newChannelQueueContext(tt.maxConcurrency), info, channelId)
+			require.Nil(t, err, "slots are free again")
+			release()
+		})
+	}
+}
+
+func TestAcquireChannelSlotReleaseIsIdempotent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	original := *operation_setting.GetChannelQueueSetting()
+	t.Cleanup(func() {
+		*operation_setting.GetChannelQueueSetting() = original
+	})
+	operation_setting.GetChannelQueueSetting().Enabled = false
+	const channelId = 3100
+	info := &relaycommon.RelayInfo{UserGroup: "default"}
+
+	first, err := AcquireChannelSlot(newChannelQueueContext(1), info, channelId)
+	require.Nil(t, err)
+	first()
+	second, err := AcquireChannelSlot(newChannelQueueContext(1), info, channelId)
+	require.Nil(t, err)
+	t.Cleanup(second)
+
+	// a repeated release of the first attempt must not free the slot now held by the second
+	first()
+	_, err = AcquireChannelSlot(newChannelQueueContext(1), info, channelId)
+	require.NotNil(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, err.StatusCode)
+}
+
+func TestAcquireChannelSlotQueuesByPriority(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	original := *operation_setting.GetChannelQueueSetting()
+	t.Cleanup(func() {
+		*operation_setting.GetChannelQueueSetting() = original
+	})
+	setting := operation_setting.GetChannelQueueSetting()
+	setting.Enabled = true
+	setting.MaxWaitSeconds = 5
+	setting.MaxQueueLength = 0
+	setting.GroupPriorities = map[string]int{"vip": 10}
+	setting.GroupMaxWaitSeconds = map[string]int{}
+	const channelId = 3100
+
+	holder, err := AcquireChannelSlot(newChannelQueueContext(1), &relaycommon.RelayInfo{UserGroup: "default"}, channelId)
+	require.Nil(t, err)
+
+	admitted := make(chan string, 2)
+	for i, group := range []string{"slow", "vip"} {
+		go func() {
+			release, err := AcquireChannelSlot(newChannelQueueContext(1), &relaycommon.RelayInfo{UserGroup: group}, channelId)
+			if err != nil {
+				admitted <- "error: " + err.Error()
+				return
+			}
+			admitted <- group
+			time.Sleep(10 * time.Millisecond)
+			release()
+		}()
+		// make the arrival order deterministic
+		require.Eventually(t, func() bool {
+			return channelQueueWaiting(channelId) == i+1
+		}, time.Second, time.Millisecond)
+	}
+
+	holder()
+	assert.Equal(t, "vip", <-admitted, "higher priority group is admitted first despite arriving later")
+	assert.Equal(t, "slow", <-admitted)
+	assert.Equal(t, 0, channelQueueWaiting(channelId))
+}
+
+func TestAcquireChannelSlotTimesOut(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	original := *operation_setting.GetChannelQueueSetting()
+	t.Cleanup(func() {
+		*operation_setting.GetChannelQueueSetting() = original
+	})
+	setting := operation_setting.GetChannelQueueSetting()
+	setting.Enabled = true
+	setting.GroupMaxWaitSeconds = map[string]int{"default": 1}
+	const channelId = 3200
+
+	holder, err := AcquireChannelSlot(newChannelQueueContext(1), &relaycommon.RelayInfo{UserGroup: "default"}, channelId)
+	require.Nil(t, err)
+	defer holder()
+
+	start := time.Now()
+	_, err = AcquireChannelSlot(newChannelQueueContext(1), &relaycommon.RelayInfo{UserGroup: "default"}, channelId)
+	require.NotNil(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+	assert.Equal(t, http.StatusTooManyRequests, err.StatusCode)
+	assert.True(t, types.IsSkipRetryError(err), "every candidate is saturated, retrying would not help")
+	assert.Equal(t, 0, channelQueueWaiting(channelId))
+}
+
+func channelQueueWaiting(channelId int) int {
+	for _, depth := range GetChannelQueueStats().Channels {
+		if depth.ChannelId == channelId {
+			return depth.Waiting
+		}
+	}
+	return 0
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/types"
+	"github.com/gin-gonic/gin"
+)
+
+// getChannelRetryPolicy returns the retry policy of the channel currently
+// selected for the request, or nil when the channel uses the global settings.
+func getChannelRetryPolicy(c *gin.Context) *dto.ChannelRetryPolicy {
+	otherSettings, ok := common.GetContextKeyType[dto.ChannelOtherSettings](c, constant.ContextKeyChannelOtherSetting)
+	if !ok {
+		return nil
+	}
+	return otherSettings.RetryPolicy
+}
+
+// GetChannelRetryTimes returns how many retries are allowed after a failure on
+// the current channel.
+func GetChannelRetryTimes(c *gin.Context) int {
+	if policy := getChannelRetryPolicy(c); policy != nil && policy.RetryTimes != nil {
+		return *policy.RetryTimes
+	}
+	return common.RetryTimes
+}
+
+// ShouldRetryChannelStatusCode checks an upstream status code against the
+// current channel's retryable ranges, falling back to the global ranges.
+func ShouldRetryChannelStatusCode(c *gin.Context, code int) bool {
+	if policy := getChannelRetryPolicy(c); policy != nil && policy.StatusCodes != "" {
+		ranges, err := operation_setting.ParseHTTPStatusCodeRanges(policy.StatusCodes)
+		if err == nil {
+			return operation_setting.ShouldRetryByStatusCodeRanges(ranges, code)
+		}
+	}
+	return operation_setting.ShouldRetryByStatusCode(code)
+}
+
+// WaitChannelRetryBackoff sleeps before retry number attempt (starting at 1)
+// using the current channel's exponential backoff. It returns false when the
+// client went away while waiting.
+func WaitChannelRetryBackoff(c *gin.Context, attempt int) bool {
+	policy := getChannelRetryPolicy(c)
+	if policy == nil || policy.BackoffMs <= 0 || attempt <= 0 {
+		return true
+	}
+	maxBackoffMs := policy.MaxBackoffMs
+	if maxBackoffMs <= 0 {
+		maxBackoffMs = operation_setting.MaxChannelRetryBackoffMs
+	}
+	backoffMs := policy.BackoffMs
+	for i := 1; i < attempt && backoffMs < maxBackoffMs; i++ {
+		backoffMs *= 2
+	}
+	backoffMs = min(backoffMs, maxBackoffMs)
+
+	timer := time.NewTimer(time.Duration(backoffMs) * time.Millisecond)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-c.Request.Context().Done():
+		return false
+	}
+}
+
+// IsChannelBreakerFailure reports whether a relay error counts against the
+// channel's circuit breaker. Only upstream-side failures do; client errors and
+// local errors such as quota checks are not the channel's fault.
+func IsChannelBreakerFailure(err *types.NewAPIError) bool {
+	if err == nil {
+		return false
+	}
+	if types.IsChannelError(err) {
+		return true
+	}
+	if types.IsSkipRetryError(err) {
+		return false
+	}
+	code := err.StatusCode
+	return code == http.StatusTooManyRequests || code >= 500 || code < 100
+}
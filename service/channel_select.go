@@ -7,8 +7,10 @@ import (
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/logger"
 	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/pkg/tracing"
 	"github.com/QuantumNous/new-api/setting"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type RetryParam struct {
@@ -81,10 +83,19 @@ func (p *RetryParam) ResetRetryNextTry() {
 //
 //	Retry=3: GroupB, priority1 (startRetryIndex=2, priorityRetry=1)
 //	         分组B, 优先级1
-func CacheGetRandomSatisfiedChannel(param *RetryParam) (*model.Channel, string, error) {
-	var channel *model.Channel
-	var err error
-	selectGroup := param.TokenGroup
+func CacheGetRandomSatisfiedChannel(param *RetryParam) (channel *model.Channel, selectGroup string, err error) {
+	span := tracing.StartSpan(param.Ctx, "channel.select",
+		attribute.String("model", param.ModelName),
+		attribute.String("group", param.TokenGroup),
+		attribute.Int("retry", param.GetRetry()),
+	)
+	defer func() {
+		if channel != nil {
+			span.SetAttributes(attribute.Int("channel.id", channel.Id), attribute.String("channel.group", selectGroup))
+		}
+		tracing.End(span, err)
+	}()
+	selectGroup = param.TokenGroup
 	userGroup := common.GetContextKeyString(param.Ctx, constant.ContextKeyUserGroup)
 
 	if param.TokenGroup == "auto" {
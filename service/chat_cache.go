@@ -0,0 +1,94 @@
+package service
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/pkg/cachex"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	relayconstant "github.com/QuantumNous/new-api/relay/constant"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+)
+
+const chatCacheNamespace = "new-api:chat_cache:v1"
+
+// ChatCacheHeader is both the opt-out request header ("no-cache" skips lookup
+// and store for that request) and the response header reporting "hit" or
+// "miss". A standard "Cache-Control: no-cache" or "no-store" also bypasses.
+const ChatCacheHeader = "X-Chat-Cache"
+
+var (
+	chatCacheOnce sync.Once
+	chatCache     *cachex.HybridCache[ResponseCacheEntry]
+)
+
+func getChatCache() *cachex.HybridCache[ResponseCacheEntry] {
+	chatCacheOnce.Do(func() {
+		setting := operation_setting.GetChatCacheSetting()
+		chatCache = newResponseCache(chatCacheNamespace, setting.MaxEntries, time.Duration(setting.TTLSeconds)*time.Second)
+	})
+	return chatCache
+}
+
+// ShouldUseChatCache reports whether a chat completion may be served from and
+// stored into the cache. Only non-streaming requests of enabled groups are
+// cached.
+func ShouldUseChatCache(c *gin.Context, info *relaycommon.RelayInfo, request *dto.GeneralOpenAIRequest) bool {
+	setting := operation_setting.GetChatCacheSetting()
+	if !setting.Enabled || info.RelayMode != relayconstant.RelayModeChatCompletions || lo.FromPtrOr(request.Stream, false) {
+		return false
+	}
+	if !setting.IsGroupEnabled(info.UsingGroup) {
+		return false
+	}
+	if strings.EqualFold(strings.TrimSpace(c.GetHeader(ChatCacheHeader)), "no-cache") {
+		return false
+	}
+	cacheControl := strings.ToLower(c.GetHeader("Cache-Control"))
+	return !strings.Contains(cacheControl, "no-cache") && !strings.Contains(cacheControl, "no-store")
+}
+
+// chatCacheScope namespaces a cached request by the caller's group and, unless
+// the cache is shared across users, by the user.
+type chatCacheScope struct {
+	Group   string                   `json:"group"`
+	UserId  int                      `json:"user_id,omitempty"`
+	Request dto.GeneralOpenAIRequest `json:"request"`
+}
+
+// ChatCacheKey hashes the request after dropping fields that only identify the
+// end user or affect transport. Entries are scoped to the caller's group and,
+// by default, to the calling user, so one tenant's responses are never served
+// to another.
+func ChatCacheKey(info *relaycommon.RelayInfo, request dto.GeneralOpenAIRequest) (string, error) {
+	request.User = nil
+	request.SafetyIdentifier = nil
+	request.Metadata = nil
+	request.Store = nil
+	request.PromptCacheKey = ""
+	request.Stream = nil
+	request.StreamOptions = nil
+	scope := chatCacheScope{Group: info.UsingGroup, Request: request}
+	if !operation_setting.GetChatCacheSetting().SharedAcrossUsers {
+		scope.UserId = info.UserId
+	}
+	return hashResponseCacheKey(scope)
+}
+
+func GetChatCacheEntry(key string) (*ResponseCacheEntry, bool) {
+	return getResponseCacheEntry(getChatCache(), key)
+}
+
+func SetChatCacheEntry(key string, entry ResponseCacheEntry) {
+	setting := operation_setting.GetChatCacheSetting()
+	setResponseCacheEntry(getChatCache(), key, entry, setting.TTLSeconds, setting.MaxEntryKB)
+}
+
+func NewChatCaptureWriter(w gin.ResponseWriter) *ResponseCaptureWriter {
+	return NewResponseCaptureWriter(w, operation_setting.GetChatCacheSetting().MaxEntryKB*1024)
+}
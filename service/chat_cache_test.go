@@ -0,0 +1,106 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/dto"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	relayconstant "github.com/QuantumNous/new-api/relay/constant"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatCacheKey(t *testing.T) {
+	setting := operation_setting.GetChatCacheSetting()
+	original := *setting
+	t.Cleanup(func() {
+		*setting = original
+	})
+
+	baseInfo := relaycommon.RelayInfo{UserId: 1, UsingGroup: "default"}
+	base := dto.GeneralOpenAIRequest{
+		Model:    "gpt-4o-mini",
+		Messages: []dto.Message{{Role: "user", Content: "hello"}},
+	}
+
+	tests := []struct {
+		name     string
+		shared   bool
+		mutate   func(info *relaycommon.RelayInfo, r *dto.GeneralOpenAIRequest)
+		wantSame bool
+	}{
+		{name: "user ignored", mutate: func(_ *relaycommon.RelayInfo, r *dto.GeneralOpenAIRequest) { r.User = []byte(`"end-user-1"`) }, wantSame: true},
+		{name: "stream flag ignored", mutate: func(_ *relaycommon.RelayInfo, r *dto.GeneralOpenAIRequest) { r.Stream = lo.ToPtr(false) }, wantSame: true},
+		{name: "model differs", mutate: func(_ *relaycommon.RelayInfo, r *dto.GeneralOpenAIRequest) { r.Model = "gpt-4o" }},
+		{name: "messages differ", mutate: func(_ *relaycommon.RelayInfo, r *dto.GeneralOpenAIRequest) {
+			r.Messages = []dto.Message{{Role: "user", Content: "hi"}}
+		}},
+		{name: "temperature differs", mutate: func(_ *relaycommon.RelayInfo, r *dto.GeneralOpenAIRequest) { r.Temperature = lo.ToPtr(0.2) }},
+		{name: "group differs", mutate: func(info *relaycommon.RelayInfo, _ *dto.GeneralOpenAIRequest) { info.UsingGroup = "vip" }},
+		{name: "caller differs", mutate: func(info *relaycommon.RelayInfo, _ *dto.GeneralOpenAIRequest) { info.UserId = 2 }},
+		{name: "caller shared across users", shared: true, mutate: func(info *relaycommon.RelayInfo, _ *dto.GeneralOpenAIRequest) { info.UserId = 2 }, wantSame: true},
+		{name: "group differs when shared", shared: true, mutate: func(info *relaycommon.RelayInfo, _ *dto.GeneralOpenAIRequest) { info.UsingGroup = "vip" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setting.SharedAcrossUsers = tt.shared
+			baseKey, err := ChatCacheKey(&baseInfo, base)
+			require.NoError(t, err)
+
+			info := baseInfo
+			request := base
+			tt.mutate(&info, &request)
+			key, err := ChatCacheKey(&info, request)
+			require.NoError(t, err)
+			if tt.wantSame {
+				assert.Equal(t, baseKey, key)
+			} else {
+				assert.NotEqual(t, baseKey, key)
+			}
+		})
+	}
+}
+
+func TestShouldUseChatCache(t *testing.T) {
+	setting := operation_setting.GetChatCacheSetting()
+	original := *setting
+	setting.Enabled = true
+	setting.Groups = []string{"vip"}
+	t.Cleanup(func() {
+		*setting = original
+	})
+
+	tests := []struct {
+		name      string
+		group     string
+		relayMode int
+		stream    bool
+		headers   map[string]string
+		want      bool
+	}{
+		{name: "enabled group", group: "vip", relayMode: relayconstant.RelayModeChatCompletions, want: true},
+		{name: "other group", group: "default", relayMode: relayconstant.RelayModeChatCompletions},
+		{name: "stream request", group: "vip", relayMode: relayconstant.RelayModeChatCompletions, stream: true},
+		{name: "completions mode", group: "vip", relayMode: relayconstant.RelayModeCompletions},
+		{name: "bypass header", group: "vip", relayMode: relayconstant.RelayModeChatCompletions, headers: map[string]string{ChatCacheHeader: "no-cache"}},
+		{name: "cache control no-store", group: "vip", relayMode: relayconstant.RelayModeChatCompletions, headers: map[string]string{"Cache-Control": "no-store"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+			for k, v := range tt.headers {
+				c.Request.Header.Set(k, v)
+			}
+			info := &relaycommon.RelayInfo{RelayMode: tt.relayMode, UsingGroup: tt.group}
+			request := &dto.GeneralOpenAIRequest{Stream: lo.ToPtr(tt.stream)}
+			assert.Equal(t, tt.want, ShouldUseChatCache(c, info, request))
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+const (
+	CheckinEventCompleted = "checkin.completed" // 每次签到（含补签）成功
+	CheckinEventMilestone = "checkin.milestone" // 签到达到连签或累计里程碑
+
+	CheckinMilestoneStreak = "streak"
+	CheckinMilestoneTotal  = "total"
+)
+
+// checkinWebhookMaxAttempts 签到事件推送的最大尝试次数，重试间隔按 1s、2s、4s… 递增
+const checkinWebhookMaxAttempts = 4
+
+// CheckinEvent 签到事件推送负载
+type CheckinEvent struct {
+	Event            string  `json:"event"`
+	UserId           int     `json:"user_id"`
+	CheckinDate      string  `json:"checkin_date"`
+	QuotaAwarded     int     `json:"quota_awarded"`
+	Streak           int     `json:"streak"`
+	StreakMultiplier float64 `json:"streak_multiplier,omitempty"`
+	BonusQuota       int     `json:"bonus_quota,omitempty"`
+	IsMakeup         bool    `json:"is_makeup,omitempty"`
+	Flagged          bool    `json:"flagged,omitempty"`
+	Milestone        string  `json:"milestone,omitempty"`      // 里程碑类型：streak 连签 / total 累计
+	MilestoneDays    int     `json:"milestone_days,omitempty"` // 达到的里程碑天数
+	Timestamp        int64   `json:"timestamp"`
+}
+
+// buildCheckinEvents 根据签到结果生成需要推送的事件：一条签到完成事件，以及达到的里程碑事件
+func buildCheckinEvents(checkin *model.Checkin) []CheckinEvent {
+	base := CheckinEvent{
+		Event:            CheckinEventCompleted,
+		UserId:           checkin.UserId,
+		CheckinDate:      checkin.CheckinDate,
+		QuotaAwarded:     checkin.QuotaAwarded,
+		Streak:           checkin.Streak,
+		StreakMultiplier: checkin.StreakMultiplier,
+		BonusQuota:       checkin.BonusQuota,
+		IsMakeup:         checkin.IsMakeup,
+		Flagged:          checkin.Flagged,
+		Timestamp:        time.Now().Unix(),
+	}
+	events := []CheckinEvent{base}
+	if operation_setting.IsCheckinStreakMilestone(checkin.Streak) {
+		event := base
+		event.Event = CheckinEventMilestone
+		event.Milestone = CheckinMilestoneStreak
+		event.MilestoneDays = checkin.Streak
+		events = append(events, event)
+	}
+	if checkin.BonusQuota > 0 {
+		event := base
+		event.Event = CheckinEventMilestone
+		event.Milestone = CheckinMilestoneTotal
+		event.MilestoneDays = checkin.TotalCheckins
+		events = append(events, event)
+	}
+	return events
+}
+
+// PublishCheckinEvents 异步推送签到事件：发送到管理员配置的 webhook（失败时重试），
+// 并在开启时通过用户自己的通知方式告知本次签到结果。不影响签到本身的结果
+func PublishCheckinEvents(checkin *model.Checkin) {
+	setting := operation_setting.GetCheckinSetting()
+	webhookURL, secret := setting.WebhookUrl, setting.WebhookSecret
+	notifyUser := setting.NotifyUser
+	if webhookURL == "" && !notifyUser {
+		return
+	}
+	events := buildCheckinEvents(checkin)
+	gopool.Go(func() {
+		if webhookURL != "" {
+			for _, event := range events {
+				deliverCheckinEvent(webhookURL, secret, event)
+			}
+		}
+		if notifyUser {
+			notifyCheckinUser(checkin, events)
+		}
+	})
+}
+
+func deliverCheckinEvent(webhookURL string, secret string, event CheckinEvent) {
+	payload, err := common.Marshal(event)
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to marshal checkin event: %v", err))
+		return
+	}
+	if attempts, err := postWebhookWithRetry(webhookURL, secret, payload, checkinWebhookMaxAttempts); err != nil {
+		common.SysError(fmt.Sprintf("failed to deliver checkin event %s for user %d after %d attempts: %v",
+			event.Event, event.UserId, attempts, err))
+	}
+}
+
+func notifyCheckinUser(checkin *model.Checkin, events []CheckinEvent) {
+	user, err := model.GetUserCache(checkin.UserId)
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to load user %d for checkin notification: %v", checkin.UserId, err))
+		return
+	}
+	vars := map[string]string{
+		"date":   checkin.CheckinDate,
+		"quota":  logger.LogQuota(checkin.QuotaAwarded),
+		"streak": strconv.Itoa(checkin.Streak),
+	}
+	for _, event := range events {
+		switch event.Milestone {
+		case CheckinMilestoneStreak:
+			vars["streak_milestone"] = strconv.Itoa(event.MilestoneDays)
+		case CheckinMilestoneTotal:
+			vars["total_milestone"] = strconv.Itoa(event.MilestoneDays)
+			vars["bonus_quota"] = logger.LogQuota(event.BonusQuota)
+		}
+	}
+	if err := NotifyUserWithTemplate(user.Id, user.Email, user.GetSetting(), dto.NotifyTypeCheckin, NotificationTemplateCheckinSuccess, vars); err != nil {
+		common.SysLog(fmt.Sprintf("failed to send checkin notification to user %d: %s", user.Id, err.Error()))
+	}
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildCheckinEvents(t *testing.T) {
+	setting := operation_setting.GetCheckinSetting()
+	saved := setting.StreakMilestones
+	setting.StreakMilestones = []operation_setting.CheckinStreakMilestone{{Days: 7, Multiplier: 2}}
+	t.Cleanup(func() { setting.StreakMilestones = saved })
+
+	tests := []struct {
+		name       string
+		checkin    model.Checkin
+		milestones []string
+	}{
+		{name: "plain", checkin: model.Checkin{UserId: 1, Streak: 3, QuotaAwarded: 100}},
+		{name: "streak milestone", checkin: model.Checkin{UserId: 1, Streak: 7, QuotaAwarded: 200}, milestones: []string{CheckinMilestoneStreak}},
+		{name: "past streak milestone", checkin: model.Checkin{UserId: 1, Streak: 8, QuotaAwarded: 200}},
+		{name: "total milestone", checkin: model.Checkin{UserId: 1, Streak: 2, QuotaAwarded: 600, BonusQuota: 500, TotalCheckins: 10}, milestones: []string{CheckinMilestoneTotal}},
+		{name: "both milestones", checkin: model.Checkin{UserId: 1, Streak: 7, QuotaAwarded: 700, BonusQuota: 500, TotalCheckins: 30}, milestones: []string{CheckinMilestoneStreak, CheckinMilestoneTotal}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := buildCheckinEvents(&tt.checkin)
+			require.Len(t, events, 1+len(tt.milestones))
+			assert.Equal(t, CheckinEventCompleted, events[0].Event)
+			assert.Equal(t, tt.checkin.QuotaAwarded, events[0].QuotaAwarded)
+			for i, milestone := range tt.milestones {
+				event := events[i+1]
+				assert.Equal(t, CheckinEventMilestone, event.Event)
+				assert.Equal(t, milestone, event.Milestone)
+				if milestone == CheckinMilestoneStreak {
+					assert.Equal(t, tt.checkin.Streak, event.MilestoneDays)
+				} else {
+					assert.Equal(t, tt.checkin.TotalCheckins, event.MilestoneDays)
+				}
+			}
+		})
+	}
+}
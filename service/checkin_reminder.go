@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+)
+
+const (
+	// checkinReminderLookbackDays limits reminders to users who checked in
+	// recently, so dormant accounts are never mailed.
+	checkinReminderLookbackDays = 7
+	checkinReminderBatchPause   = 10 * time.Second
+	checkinReminderDefaultBatch = 50
+)
+
+type checkinReminderPayload struct {
+	Date string `json:"date"`
+}
+
+type checkinReminderResult struct {
+	Date    string `json:"date"`
+	Sent    int    `json:"sent"`
+	OptOut  int    `json:"opt_out"`
+	Failed  int    `json:"failed"`
+	Batches int    `json:"batches"`
+}
+
+// checkinReminderHandler sends the daily check-in reminder once per check-in
+// day, after the configured hour in the check-in timezone. The payload carries
+// the day it was scheduled for so a succeeded run suppresses the rest of that
+// day while a failed one is retried after Interval.
+type checkinReminderHandler struct{}
+
+func init() {
+	RegisterSystemTaskHandler(checkinReminderHandler{})
+}
+
+func (checkinReminderHandler) Type() string { return model.SystemTaskTypeCheckinRemind }
+
+func (checkinReminderHandler) Enabled() bool {
+	setting := operation_setting.GetCheckinSetting()
+	if !setting.Enabled || !setting.ReminderEnabled {
+		return false
+	}
+	now := operation_setting.CheckinNow()
+	if now.Hour() < setting.ReminderHour {
+		return false
+	}
+	latest, err := model.GetLatestSystemTask(model.SystemTaskTypeCheckinRemind)
+	if err != nil {
+		return false
+	}
+	if latest == nil || latest.Status != model.SystemTaskStatusSucceeded {
+		return true
+	}
+	payload := checkinReminderPayload{}
+	if err := latest.DecodePayload(&payload); err != nil {
+		return true
+	}
+	return payload.Date != now.Format("2006-01-02")
+}
+
+func (checkinReminderHandler) Interval() time.Duration { return time.Hour }
+
+func (checkinReminderHandler) NewPayload() any {
+	return checkinReminderPayload{Date: operation_setting.CheckinNow().Format("2006-01-02")}
+}
+
+func (checkinReminderHandler) Run(ctx context.Context, task *model.SystemTask, runnerID string) {
+	payload := checkinReminderPayload{}
+	if err := task.DecodePayload(&payload); err != nil {
+		failSystemTask(task, runnerID, err)
+		return
+	}
+	result, err := sendCheckinReminders(ctx, payload.Date)
+	if err != nil {
+		failSystemTask(task, runnerID, err)
+		return
+	}
+	if err := model.FinishSystemTask(task.TaskID, runnerID, model.SystemTaskStatusSucceeded, result, ""); err != nil {
+		logSystemTaskLockError(ctx, task, err)
+	}
+}
+
+// sendCheckinReminders notifies, batch by batch, every user who checked in
+// during the lookback window but not yet on date and has not opted out. The
+// pause between batches keeps a large user base from flooding the mail server.
+func sendCheckinReminders(ctx context.Context, date string) (*checkinReminderResult, error) {
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return nil, errors.New("invalid checkin reminder date")
+	}
+	sinceDate := day.AddDate(0, 0, -checkinReminderLookbackDays).Format("2006-01-02")
+	batchSize := operation_setting.GetCheckinSetting().ReminderBatchSize
+	if batchSize <= 0 {
+		batchSize = checkinReminderDefaultBatch
+	}
+	// the reminder text only depends on the language, render it once per language
+	messages := make(map[string]NotificationMessage)
+
+	result := &checkinReminderResult{Date: date}
+	afterId := 0
+	for {
+		users, err := model.GetCheckinReminderCandidates(date, sinceDate, afterId, batchSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(users) == 0 {
+			return result, nil
+		}
+		result.Batches++
+		for _, user := range users {
+			afterId = user.Id
+			userSetting := user.GetSetting()
+			if userSetting.CheckinReminderDisabled {
+				result.OptOut++
+				continue
+			}
+			lang := NormalizeNotificationLanguage(userSetting.Language)
+			message, ok := messages[lang]
+			if !ok {
+				message = RenderNotification(NotificationTemplateCheckinReminder, lang, map[string]string{"date": date})
+				messages[lang] = message
+			}
+			if err := NotifyUser(user.Id, user.Email, userSetting, newTemplateNotify(dto.NotifyTypeCheckin, message, userSetting)); err != nil {
+				result.Failed++
+				continue
+			}
+			result.Sent++
+		}
+		if len(users) < batchSize {
+			return result, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(checkinReminderBatchPause):
+		}
+	}
+}
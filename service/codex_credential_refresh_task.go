@@ -53,6 +53,9 @@ func StartCodexCredentialAutoRefreshTask() {
 }
 
 func runCodexCredentialAutoRefreshOnce() {
+	if !IsBackgroundLeader() {
+		return
+	}
 	if !codexCredentialRefreshRunning.CompareAndSwap(false, true) {
 		return
 	}
@@ -137,7 +140,7 @@ func runCodexCredentialAutoRefreshOnce() {
 					logger.LogWarn(ctx, fmt.Sprintf("codex credential auto-refresh: InitChannelCache panic: %v", r))
 				}
 			}()
-			model.InitChannelCache()
+			model.RefreshChannelCache()
 		}()
 		ResetProxyClientCache()
 	}
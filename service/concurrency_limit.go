@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/pkg/tracing"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// concurrencyLeaseTTL bounds how long a Redis slot outlives a crashed node.
+	// Holders renew their lease every third of the TTL while the stream runs.
+	concurrencyLeaseTTL     = 10 * time.Minute
+	concurrencyPollInterval = 200 * time.Millisecond
+)
+
+// concurrencyAcquireScript drops expired leases, then adds the caller's lease
+// (scored by its expiry) only when the set is still below the limit.
+var concurrencyAcquireScript = redis.NewScript(`
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+if redis.call('ZCARD', KEYS[1]) >= tonumber(ARGV[2]) then
+	return 0
+end
+redis.call('ZADD', KEYS[1], ARGV[3], ARGV[4])
+redis.call('PEXPIRE', KEYS[1], ARGV[5])
+return 1
+`)
+
+var (
+	concurrencySlotsMu sync.Mutex
+	concurrencySlots   = map[string]int{}
+)
+
+type concurrencySlot struct {
+	key   string
+	scope string
+	limit int
+}
+
+func tryAcquireConcurrencySlot(ctx context.Context, slot concurrencySlot, leaseId string) (bool, error) {
+	if common.RedisEnabled {
+		now := time.Now()
+		acquired, err := concurrencyAcquireScript.Run(ctx, common.RDB, []string{slot.key},
+			now.UnixMilli(), slot.limit, now.Add(concurrencyLeaseTTL).UnixMilli(), leaseId, concurrencyLeaseTTL.Milliseconds()).Int()
+		return acquired == 1, err
+	}
+	concurrencySlotsMu.Lock()
+	defer concurrencySlotsMu.Unlock()
+	if concurrencySlots[slot.key] >= slot.limit {
+		return false, nil
+	}
+	concurrencySlots[slot.key]++
+	return true, nil
+}
+
+func releaseConcurrencySlot(key string, leaseId string) {
+	if common.RedisEnabled {
+		if err := common.RDB.ZRem(context.Background(), key, leaseId).Err(); err != nil {
+			common.SysLog(fmt.Sprintf("failed to release concurrency slot %s: %v", key, err))
+		}
+		return
+	}
+	concurrencySlotsMu.Lock()
+	defer concurrencySlotsMu.Unlock()
+	concurrencySlots[key]--
+	if concurrencySlots[key] <= 0 {
+		delete(concurrencySlots, key)
+	}
+}
+
+// renewConcurrencyLeases keeps the Redis leases of a long-running stream from
+// expiring until done is closed.
+func renewConcurrencyLeases(keys []string, leaseId string, done <-chan struct{}) {
+	ticker := time.NewTicker(concurrencyLeaseTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			ctx := context.Background()
+			expiry := float64(time.Now().Add(concurrencyLeaseTTL).UnixMilli())
+			for _, key := range keys {
+				common.RDB.ZAddXX(ctx, key, &redis.Z{Score: expiry, Member: leaseId})
+				common.RDB.PExpire(ctx, key, concurrencyLeaseTTL)
+			}
+		}
+	}
+}
+
+// AcquireStreamConcurrency takes one slot of the user's group limit and one of
+// the token's limit for a streaming request. Depending on the configured mode
+// it rejects immediately or waits for a slot until the queue timeout. The
+// returned release func must be called once the request finishes.
+func AcquireStreamConcurrency(c *gin.Context, info *relaycommon.RelayInfo) (func(), *types.NewAPIError) {
+	setting := operation_setting.GetConcurrencySetting()
+	if !setting.Enabled || !info.IsStream {
+		return func() {}, nil
+	}
+	slots := make([]concurrencySlot, 0, 2)
+	if limit := operation_setting.GetUserConcurrencyLimit(info.UserGroup); limit > 0 {
+		slots = append(slots, concurrencySlot{key: fmt.Sprintf("concurrency:user:%d", info.UserId), scope: "user", limit: limit})
+	}
+	if limit := common.GetContextKeyInt(c, constant.ContextKeyTokenMaxConcurrency); limit > 0 && info.TokenId > 0 {
+		slots = append(slots, concurrencySlot{key: fmt.Sprintf("concurrency:token:%d", info.TokenId), scope: "token", limit: limit})
+	}
+	if len(slots) == 0 {
+		return func() {}, nil
+	}
+	span := tracing.StartSpan(c, "concurrency.acquire", attribute.String("concurrency.mode", setting.Mode))
+	defer span.End()
+
+	var deadline time.Time
+	if setting.Mode == operation_setting.ConcurrencyModeQueue {
+		deadline = time.Now().Add(time.Duration(max(setting.QueueTimeoutSeconds, 0)) * time.Second)
+	}
+	leaseId := common.GetUUID()
+	acquired := make([]string, 0, len(slots))
+	release := func() {
+		for _, key := range acquired {
+			releaseConcurrencySlot(key, leaseId)
+		}
+	}
+	ctx := c.Request.Context()
+	for _, slot := range slots {
+		for {
+			ok, err := tryAcquireConcurrencySlot(ctx, slot, leaseId)
+			if err != nil {
+				release()
+				return nil, types.NewError(err, types.ErrorCodeQueryDataError, types.ErrOptionWithSkipRetry())
+			}
+			if ok {
+				acquired = append(acquired, slot.key)
+				break
+			}
+			if deadline.IsZero() || time.Now().After(deadline) {
+				release()
+				return nil, types.NewErrorWithStatusCode(
+					fmt.Errorf("too many concurrent streaming requests for this %s: limit %d", slot.scope, slot.limit),
+					types.ErrorCodeRateLimitExceeded, http.StatusTooManyRequests, types.ErrOptionWithSkipRetry())
+			}
+			select {
+			case <-ctx.Done():
+				release()
+				return nil, types.NewError(ctx.Err(), types.ErrorCodeRateLimitExceeded, types.ErrOptionWithSkipRetry())
+			case <-time.After(concurrencyPollInterval):
+			}
+		}
+	}
+
+	if !common.RedisEnabled {
+		return release, nil
+	}
+	done := make(chan struct{})
+	gopool.Go(func() {
+		renewConcurrencyLeases(acquired, leaseId, done)
+	})
+	return func() {
+		close(done)
+		release()
+	}, nil
+}
+
+// AcquireRealtimeSession takes one of the user's realtime session slots for a
+// /v1/realtime WebSocket. Sessions are long-lived, so the limit always rejects
+// instead of queueing. The returned release func must be called once the
+// session ends.
+func AcquireRealtimeSession(c *gin.Context, info *relaycommon.RelayInfo) (func(), *types.NewAPIError) {
+	limit := operation_setting.GetRealtimeSetting().MaxSessionsPerUser
+	if info.RelayFormat != types.RelayFormatOpenAIRealtime || limit <= 0 {
+		return func() {}, nil
+	}
+	key := fmt.Sprintf("concurrency:realtime:user:%d", info.UserId)
+	leaseId := common.GetUUID()
+	ok, err := tryAcquireConcurrencySlot(c.Request.Context(), concurrencySlot{key: key, scope: "user", limit: limit}, leaseId)
+	if err != nil {
+		return nil, types.NewError(err, types.ErrorCodeQueryDataError, types.ErrOptionWithSkipRetry())
+	}
+	if !ok {
+		return nil, types.NewErrorWithStatusCode(
+			fmt.Errorf("too many concurrent realtime sessions for this user: limit %d", limit),
+			types.ErrorCodeRateLimitExceeded, http.StatusTooManyRequests, types.ErrOptionWithSkipRetry())
+	}
+
+	if !common.RedisEnabled {
+		return func() { releaseConcurrencySlot(key, leaseId) }, nil
+	}
+	done := make(chan struct{})
+	gopool.Go(func() {
+		renewConcurrencyLeases([]string{key}, leaseId, done)
+	})
+	return func() {
+		close(done)
+		releaseConcurrencySlot(key, leaseId)
+	}, nil
+}
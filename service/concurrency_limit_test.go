@@ -0,0 +1,131 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquireStreamConcurrency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	original := *operation_setting.GetConcurrencySetting()
+	originalRedis := common.RedisEnabled
+	t.Cleanup(func() {
+		*operation_setting.GetConcurrencySetting() = original
+		common.RedisEnabled = originalRedis
+	})
+	common.RedisEnabled = false
+
+	newContext := func(tokenLimit int) *gin.Context {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+		common.SetContextKey(ctx, constant.ContextKeyTokenMaxConcurrency, tokenLimit)
+		return ctx
+	}
+
+	tests := []struct {
+		name       string
+		userLimit  int
+		groupLimit map[string]int
+		tokenLimit int
+		stream     bool
+		allowed    int
+	}{
+		{name: "non-stream requests are not limited", userLimit: 1, stream: false, allowed: 3},
+		{name: "user limit", userLimit: 2, stream: true, allowed: 2},
+		{name: "group overrides default", userLimit: 5, groupLimit: map[string]int{"vip": 1}, stream: true, allowed: 1},
+		{name: "token limit is stricter", userLimit: 3, tokenLimit: 1, stream: true, allowed: 1},
+		{name: "unlimited", stream: true, allowed: 3},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setting := operation_setting.GetConcurrencySetting()
+			setting.Enabled = true
+			setting.Mode = operation_setting.ConcurrencyModeReject
+			setting.DefaultUserLimit = tt.userLimit
+			setting.GroupLimits = tt.groupLimit
+
+			info := &relaycommon.RelayInfo{UserId: 1000 + i, TokenId: 2000 + i, UserGroup: "vip", IsStream: tt.stream}
+			releases := make([]func(), 0, 3)
+			var rejected *types.NewAPIError
+			for range 3 {
+				release, apiErr := AcquireStreamConcurrency(newContext(tt.tokenLimit), info)
+				if apiErr != nil {
+					rejected = apiErr
+					break
+				}
+				releases = append(releases, release)
+			}
+			require.Len(t, releases, tt.allowed)
+			if tt.allowed < 3 {
+				require.NotNil(t, rejected)
+				require.Equal(t, http.StatusTooManyRequests, rejected.StatusCode)
+				require.Equal(t, types.ErrorCodeRateLimitExceeded, rejected.GetErrorCode())
+			}
+
+			for _, release := range releases {
+				release()
+			}
+			release, apiErr := AcquireStreamConcurrency(newContext(tt.tokenLimit), info)
+			require.Nil(t, apiErr, "slots should be reusable after release")
+			release()
+		})
+	}
+}
+
+func TestAcquireRealtimeSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	original := *operation_setting.GetRealtimeSetting()
+	originalRedis := common.RedisEnabled
+	t.Cleanup(func() {
+		*operation_setting.GetRealtimeSetting() = original
+		common.RedisEnabled = originalRedis
+	})
+	common.RedisEnabled = false
+
+	tests := []struct {
+		name    string
+		limit   int
+		format  types.RelayFormat
+		allowed int
+	}{
+		{name: "limit reached", limit: 2, format: types.RelayFormatOpenAIRealtime, allowed: 2},
+		{name: "unlimited", limit: 0, format: types.RelayFormatOpenAIRealtime, allowed: 3},
+		{name: "non-realtime requests are not limited", limit: 1, format: types.RelayFormatOpenAI, allowed: 3},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			operation_setting.GetRealtimeSetting().MaxSessionsPerUser = tt.limit
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest(http.MethodGet, "/v1/realtime", nil)
+			info := &relaycommon.RelayInfo{UserId: 3000 + i, RelayFormat: tt.format}
+
+			releases := make([]func(), 0, 3)
+			for range 3 {
+				release, apiErr := AcquireRealtimeSession(ctx, info)
+				if apiErr != nil {
+					require.Equal(t, http.StatusTooManyRequests, apiErr.StatusCode)
+					break
+				}
+				releases = append(releases, release)
+			}
+			require.Len(t, releases, tt.allowed)
+
+			for _, release := range releases {
+				release()
+			}
+			release, apiErr := AcquireRealtimeSession(ctx, info)
+			require.Nil(t, apiErr, "sessions should be reusable after release")
+			release()
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/pkg/cachex"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+const embeddingCacheNamespace = "new-api:embedding_cache:v1"
+
+// EmbeddingCacheHeader is both the opt-out request header ("no-cache" skips
+// lookup and store for that request) and the response header reporting
+// "hit" or "miss".
+const EmbeddingCacheHeader = "X-Embedding-Cache"
+
+// EmbeddingCacheEntry is the cached embedding response and its usage.
+type EmbeddingCacheEntry = ResponseCacheEntry
+
+// EmbeddingCaptureWriter copies the embedding response for the cache.
+type EmbeddingCaptureWriter = ResponseCaptureWriter
+
+var (
+	embeddingCacheOnce sync.Once
+	embeddingCache     *cachex.HybridCache[ResponseCacheEntry]
+)
+
+func getEmbeddingCache() *cachex.HybridCache[ResponseCacheEntry] {
+	embeddingCacheOnce.Do(func() {
+		setting := operation_setting.GetEmbeddingCacheSetting()
+		embeddingCache = newResponseCache(embeddingCacheNamespace, setting.MaxEntries, time.Duration(setting.TTLSeconds)*time.Second)
+	})
+	return embeddingCache
+}
+
+// ShouldUseEmbeddingCache reports whether the cache is enabled and the client
+// did not opt out for this request.
+func ShouldUseEmbeddingCache(c *gin.Context) bool {
+	return operation_setting.GetEmbeddingCacheSetting().Enabled &&
+		!strings.EqualFold(strings.TrimSpace(c.GetHeader(EmbeddingCacheHeader)), "no-cache")
+}
+
+// EmbeddingCacheKey hashes the requested model and every field that affects
+// the returned vectors. The end-user identifier does not, so it is ignored.
+func EmbeddingCacheKey(request dto.EmbeddingRequest) (string, error) {
+	request.User = ""
+	return hashResponseCacheKey(request)
+}
+
+func GetEmbeddingCacheEntry(key string) (*EmbeddingCacheEntry, bool) {
+	return getResponseCacheEntry(getEmbeddingCache(), key)
+}
+
+func SetEmbeddingCacheEntry(key string, entry EmbeddingCacheEntry) {
+	setting := operation_setting.GetEmbeddingCacheSetting()
+	setResponseCacheEntry(getEmbeddingCache(), key, entry, setting.TTLSeconds, setting.MaxEntryKB)
+}
+
+func NewEmbeddingCaptureWriter(w gin.ResponseWriter) *EmbeddingCaptureWriter {
+	return NewResponseCaptureWriter(w, operation_setting.GetEmbeddingCacheSetting().MaxEntryKB*1024)
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddingCacheKey(t *testing.T) {
+	dimensions := 256
+	base := dto.EmbeddingRequest{Model: "text-embedding-3-small", Input: []string{"hello", "world"}}
+	baseKey, err := EmbeddingCacheKey(base)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		mutate   func(r *dto.EmbeddingRequest)
+		wantSame bool
+	}{
+		{name: "user ignored", mutate: func(r *dto.EmbeddingRequest) { r.User = "end-user-1" }, wantSame: true},
+		{name: "model differs", mutate: func(r *dto.EmbeddingRequest) { r.Model = "text-embedding-3-large" }},
+		{name: "input differs", mutate: func(r *dto.EmbeddingRequest) { r.Input = []string{"hello"} }},
+		{name: "dimensions differ", mutate: func(r *dto.EmbeddingRequest) { r.Dimensions = &dimensions }},
+		{name: "encoding differs", mutate: func(r *dto.EmbeddingRequest) { r.EncodingFormat = "base64" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := base
+			tt.mutate(&request)
+			key, err := EmbeddingCacheKey(request)
+			require.NoError(t, err)
+			if tt.wantSame {
+				assert.Equal(t, baseKey, key)
+			} else {
+				assert.NotEqual(t, baseKey, key)
+			}
+		})
+	}
+}
+
+func TestEmbeddingCacheEntryRoundTrip(t *testing.T) {
+	originalRedis := common.RedisEnabled
+	setting := operation_setting.GetEmbeddingCacheSetting()
+	original := *setting
+	common.RedisEnabled = false
+	setting.MaxEntryKB = 1
+	setting.TTLSeconds = 60
+	t.Cleanup(func() {
+		common.RedisEnabled = originalRedis
+		*setting = original
+	})
+
+	tests := []struct {
+		name      string
+		body      string
+		wantFound bool
+	}{
+		{name: "small response cached", body: `{"data":[]}`, wantFound: true},
+		{name: "oversized response skipped", body: strings.Repeat("x", 2048)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := "round-trip-" + tt.name
+			SetEmbeddingCacheEntry(key, EmbeddingCacheEntry{Body: tt.body, Usage: dto.Usage{PromptTokens: 3, TotalTokens: 3}})
+			entry, found := GetEmbeddingCacheEntry(key)
+			require.Equal(t, tt.wantFound, found)
+			if tt.wantFound {
+				assert.Equal(t, tt.body, entry.Body)
+				assert.Equal(t, 3, entry.Usage.PromptTokens)
+			}
+		})
+	}
+}
+
+func TestEmbeddingCaptureWriter(t *testing.T) {
+	setting := operation_setting.GetEmbeddingCacheSetting()
+	original := *setting
+	setting.MaxEntryKB = 1
+	t.Cleanup(func() {
+		*setting = original
+	})
+
+	tests := []struct {
+		name     string
+		writes   []string
+		wantBody bool
+	}{
+		{name: "captured", writes: []string{`{"data":`, `[]}`}, wantBody: true},
+		{name: "overflow", writes: []string{strings.Repeat("x", 600), strings.Repeat("y", 600)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			writer := NewEmbeddingCaptureWriter(c.Writer)
+			writer.WriteHeader(http.StatusOK)
+			for _, chunk := range tt.writes {
+				_, err := writer.WriteString(chunk)
+				require.NoError(t, err)
+			}
+
+			body, ok := writer.Body()
+			assert.Equal(t, tt.wantBody, ok)
+			if tt.wantBody {
+				assert.Equal(t, strings.Join(tt.writes, ""), body)
+			}
+			assert.Equal(t, strings.Join(tt.writes, ""), recorder.Body.String())
+		})
+	}
+}
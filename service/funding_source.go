@@ -37,7 +37,7 @@ func (w *WalletFunding) PreConsume(amount int) error {
 	if amount <= 0 {
 		return nil
 	}
-	if err := model.DecreaseUserQuota(w.userId, amount, false); err != nil {
+	if err := model.PreConsumeUserQuota(w.userId, amount); err != nil {
 		return err
 	}
 	w.consumed = amount
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/samber/hot"
+)
+
+const (
+	groupUpgradeBatchSize = 500
+	// groupUpgradeEvalInterval throttles the re-evaluation triggered by
+	// consumption to once per user per interval on each node.
+	groupUpgradeEvalInterval = 60 * time.Second
+	// groupUpgradeEvalCapacity bounds the throttle cache; a user evicted early
+	// is simply re-evaluated on the next consumption.
+	groupUpgradeEvalCapacity = 100000
+)
+
+// groupUpgradeRecentEvals holds the users evaluated within the last
+// groupUpgradeEvalInterval on this node.
+var groupUpgradeRecentEvals = hot.NewHotCache[int, struct{}](hot.LRU, groupUpgradeEvalCapacity).
+	WithTTL(groupUpgradeEvalInterval).
+	Build()
+
+// GroupUpgradeChange is a group change of one user under the upgrade rules.
+type GroupUpgradeChange struct {
+	UserId    int    `json:"user_id"`
+	Username  string `json:"username"`
+	UsedQuota int    `json:"used_quota"`
+	FromGroup string `json:"from_group"`
+	ToGroup   string `json:"to_group"`
+}
+
+type groupUpgradeResult struct {
+	Changed int `json:"changed"`
+}
+
+// groupUpgradeHandler periodically applies the upgrade rules to every managed
+// user, which also picks up rule changes and consumption missed by the hook.
+type groupUpgradeHandler struct{}
+
+func init() {
+	model.UsedQuotaUpdatedHook = scheduleUserGroupUpgrade
+	RegisterSystemTaskHandler(groupUpgradeHandler{})
+}
+
+func (groupUpgradeHandler) Type() string { return model.SystemTaskTypeGroupUpgrade }
+
+func (groupUpgradeHandler) Enabled() bool {
+	setting := operation_setting.GetGroupUpgradeSetting()
+	return setting.Enabled && len(setting.Rules) > 0
+}
+
+func (groupUpgradeHandler) Interval() time.Duration { return 1 * time.Hour }
+
+func (groupUpgradeHandler) NewPayload() any { return nil }
+
+func (groupUpgradeHandler) Run(ctx context.Context, task *model.SystemTask, runnerID string) {
+	setting := operation_setting.GetGroupUpgradeSetting()
+	result := &groupUpgradeResult{}
+	err := scanGroupUpgrades(ctx, setting, func(change GroupUpgradeChange) bool {
+		if applyGroupUpgrade(setting, change) {
+			result.Changed++
+		}
+		return true
+	})
+	if err != nil {
+		failSystemTask(task, runnerID, err)
+		return
+	}
+	if err := model.FinishSystemTask(task.TaskID, runnerID, model.SystemTaskStatusSucceeded, result, ""); err != nil {
+		logSystemTaskLockError(ctx, task, err)
+	}
+}
+
+// groupUpgradeTarget returns the group a user currently in currentGroup with
+// usedQuota cumulative spend belongs to, and whether the user should move
+// there. Users outside the base group and the rule groups are never touched,
+// and downgrades only happen when the setting allows them.
+func groupUpgradeTarget(setting *operation_setting.GroupUpgradeSetting, currentGroup string, usedQuota int) (string, bool) {
+	currentThreshold := -1.0
+	if currentGroup == setting.BaseGroup {
+		currentThreshold = 0
+	}
+	target, targetThreshold := setting.BaseGroup, 0.0
+	for _, rule := range setting.Rules {
+		if rule.Group == currentGroup {
+			currentThreshold = rule.MinSpend
+		}
+		if float64(usedQuota) >= rule.MinSpend*common.QuotaPerUnit && rule.MinSpend >= targetThreshold {
+			target, targetThreshold = rule.Group, rule.MinSpend
+		}
+	}
+	if currentThreshold < 0 || target == "" || target == currentGroup {
+		return "", false
+	}
+	if targetThreshold < currentThreshold && !setting.AllowDowngrade {
+		return "", false
+	}
+	return target, true
+}
+
+// scanGroupUpgrades walks every user in a managed group and calls fn for each
+// pending change until fn returns false.
+func scanGroupUpgrades(ctx context.Context, setting *operation_setting.GroupUpgradeSetting, fn func(change GroupUpgradeChange) bool) error {
+	groups := make([]string, 0, len(setting.Rules)+1)
+	if setting.BaseGroup != "" {
+		groups = append(groups, setting.BaseGroup)
+	}
+	for _, rule := range setting.Rules {
+		groups = append(groups, rule.Group)
+	}
+	if len(groups) == 0 {
+		return nil
+	}
+	lastId := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		users, err := model.GetUsersInGroupsAfterId(groups, lastId, groupUpgradeBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			target, ok := groupUpgradeTarget(setting, user.Group, user.UsedQuota)
+			if !ok {
+				continue
+			}
+			if !fn(GroupUpgradeChange{UserId: user.Id, Username: user.Username, UsedQuota: user.UsedQuota, FromGroup: user.Group, ToGroup: target}) {
+				return nil
+			}
+		}
+		if len(users) < groupUpgradeBatchSize {
+			return nil
+		}
+		lastId = users[len(users)-1].Id
+	}
+}
+
+// PreviewGroupUpgrades lists up to limit users whose group would change under
+// setting, without applying anything.
+func PreviewGroupUpgrades(ctx context.Context, setting *operation_setting.GroupUpgradeSetting, limit int) ([]GroupUpgradeChange, error) {
+	changes := make([]GroupUpgradeChange, 0)
+	err := scanGroupUpgrades(ctx, setting, func(change GroupUpgradeChange) bool {
+		changes = append(changes, change)
+		return len(changes) < limit
+	})
+	return changes, err
+}
+
+// scheduleUserGroupUpgrade re-evaluates a user's group after consumption has
+// been written to the database.
+func scheduleUserGroupUpgrade(userId int) {
+	setting := operation_setting.GetGroupUpgradeSetting()
+	if !setting.Enabled || len(setting.Rules) == 0 {
+		return
+	}
+	if groupUpgradeRecentEvals.Has(userId) {
+		return
+	}
+	groupUpgradeRecentEvals.Set(userId, struct{}{})
+	gopool.Go(func() {
+		user, err := model.GetUserById(userId, false)
+		if err != nil {
+			common.SysError(fmt.Sprintf("failed to load user %d for group upgrade: %v", userId, err))
+			return
+		}
+		if target, ok := groupUpgradeTarget(setting, user.Group, user.UsedQuota); ok {
+			applyGroupUpgrade(setting, GroupUpgradeChange{UserId: user.Id, Username: user.Username, UsedQuota: user.UsedQuota, FromGroup: user.Group, ToGroup: target})
+		}
+	})
+}
+
+// applyGroupUpgrade moves the user unless the group was changed concurrently,
+// then records and announces the change. Returns whether the user was moved.
+func applyGroupUpgrade(setting *operation_setting.GroupUpgradeSetting, change GroupUpgradeChange) bool {
+	changed, err := model.UpdateUserGroupIfMatch(change.UserId, change.FromGroup, change.ToGroup)
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to change group of user %d: %v", change.UserId, err))
+		return false
+	}
+	if !changed {
+		return false
+	}
+	content := fmt.Sprintf("累计消费 %s，用户分组由 %s 调整为 %s", logger.LogQuota(change.UsedQuota), change.FromGroup, change.ToGroup)
+	model.RecordLog(change.UserId, model.LogTypeSystem, content)
+	PublishWebhookEvent(WebhookEventUserGroupChanged, map[string]any{
+		"user_id":    change.UserId,
+		"used_quota": change.UsedQuota,
+		"from_group": change.FromGroup,
+		"to_group":   change.ToGroup,
+	})
+	if setting.NotifyUser {
+		user, err := model.GetUserCache(change.UserId)
+		if err != nil {
+			common.SysError(fmt.Sprintf("failed to load user %d for group change notification: %v", change.UserId, err))
+			return true
+		}
+		err = NotifyUserWithTemplate(user.Id, user.Email, user.GetSetting(), dto.NotifyTypeGroupChange, NotificationTemplateGroupChange, map[string]string{
+			"used_quota": logger.LogQuota(change.UsedQuota),
+			"from_group": change.FromGroup,
+			"to_group":   change.ToGroup,
+		})
+		if err != nil {
+			common.SysLog(fmt.Sprintf("failed to send group change notification to user %d: %s", user.Id, err.Error()))
+		}
+	}
+	return true
+}
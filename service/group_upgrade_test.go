@@ -0,0 +1,69 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupUpgradeTarget(t *testing.T) {
+	unit := int(common.QuotaPerUnit)
+	setting := operation_setting.GroupUpgradeSetting{
+		BaseGroup: "default",
+		Rules: []operation_setting.GroupUpgradeRule{
+			{MinSpend: 500, Group: "svip"},
+			{MinSpend: 100, Group: "vip"},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		group          string
+		usedQuota      int
+		allowDowngrade bool
+		wantGroup      string
+		wantChange     bool
+	}{
+		{name: "below every threshold", group: "default", usedQuota: 99 * unit},
+		{name: "reaches first threshold", group: "default", usedQuota: 100 * unit, wantGroup: "vip", wantChange: true},
+		{name: "skips to highest reached threshold", group: "default", usedQuota: 800 * unit, wantGroup: "svip", wantChange: true},
+		{name: "upgrades between rule groups", group: "vip", usedQuota: 500 * unit, wantGroup: "svip", wantChange: true},
+		{name: "already in target group", group: "vip", usedQuota: 200 * unit},
+		{name: "unmanaged group is left alone", group: "partner", usedQuota: 800 * unit},
+		{name: "downgrade disabled", group: "svip", usedQuota: 200 * unit},
+		{name: "downgrade to lower rule", group: "svip", usedQuota: 200 * unit, allowDowngrade: true, wantGroup: "vip", wantChange: true},
+		{name: "downgrade to base group", group: "vip", usedQuota: 0, allowDowngrade: true, wantGroup: "default", wantChange: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setting.AllowDowngrade = tt.allowDowngrade
+			group, changed := groupUpgradeTarget(&setting, tt.group, tt.usedQuota)
+			assert.Equal(t, tt.wantChange, changed)
+			assert.Equal(t, tt.wantGroup, group)
+		})
+	}
+}
+
+func TestScheduleUserGroupUpgradeRecordsEvaluation(t *testing.T) {
+	setting := operation_setting.GetGroupUpgradeSetting()
+	original := *setting
+	t.Cleanup(func() {
+		*setting = original
+		groupUpgradeRecentEvals.Delete(9201)
+		groupUpgradeRecentEvals.Delete(9202)
+	})
+	setting.Rules = []operation_setting.GroupUpgradeRule{{MinSpend: 100, Group: "vip"}}
+
+	setting.Enabled = false
+	scheduleUserGroupUpgrade(9201)
+	assert.False(t, groupUpgradeRecentEvals.Has(9201))
+
+	// 评估过的用户在间隔内由有界的 LRU 记录，重复消费不会再次触发评估
+	setting.Enabled = true
+	scheduleUserGroupUpgrade(9202)
+	assert.True(t, groupUpgradeRecentEvals.Has(9202))
+	capacity, _ := groupUpgradeRecentEvals.Capacity()
+	assert.Equal(t, groupUpgradeEvalCapacity, capacity)
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+const (
+	// leaderLeaseTTL is how long a silent leader keeps the lease before another
+	// master may take over; leaderRenewInterval must stay well below it.
+	leaderLeaseTTL      = 30 * time.Second
+	leaderRenewInterval = 10 * time.Second
+)
+
+var (
+	leaderElectionOnce sync.Once
+	leaderHolderID     string
+	isLeader           atomic.Bool
+)
+
+// LeaderStatus is the admin-facing view of background job leadership.
+type LeaderStatus struct {
+	LeaseName      string `json:"lease_name"`
+	LeaderNodeName string `json:"leader_node_name"`
+	LeaseUntil     int64  `json:"lease_until"`
+	AcquiredAt     int64  `json:"acquired_at"`
+	Active         bool   `json:"active"`
+	CurrentNode    string `json:"current_node"`
+	IsCurrentNode  bool   `json:"is_current_node"`
+}
+
+// StartLeaderElection joins the background job leader election. Only master
+// nodes participate; slave nodes never become leader. The loop keeps renewing
+// the lease while held and retries acquisition otherwise, so a crashed leader
+// is replaced within one TTL.
+func StartLeaderElection() {
+	leaderElectionOnce.Do(func() {
+		if !common.IsMasterNode {
+			return
+		}
+		leaderHolderID = fmt.Sprintf("%s-%s", common.NodeName, common.GetRandomString(8))
+		runLeaderElectionOnce()
+		gopool.Go(func() {
+			ticker := time.NewTicker(leaderRenewInterval)
+			defer ticker.Stop()
+			for range ticker.C {
+				runLeaderElectionOnce()
+			}
+		})
+	})
+}
+
+func runLeaderElectionOnce() {
+	now := common.GetTimestamp()
+	acquired, err := model.TryAcquireLeaderLease(model.LeaderLeaseNameBackground, leaderHolderID, common.NodeName, now, now+int64(leaderLeaseTTL.Seconds()))
+	if err != nil {
+		// Step down on errors: a leader that cannot reach the database cannot
+		// prove it still holds the lease.
+		if isLeader.Swap(false) {
+			logger.LogWarn(context.Background(), fmt.Sprintf("leader election: stepping down after renew failure: %v", err))
+		}
+		return
+	}
+	if isLeader.Swap(acquired) != acquired {
+		if acquired {
+			logger.LogInfo(context.Background(), fmt.Sprintf("leader election: %s became background job leader", leaderHolderID))
+		} else {
+			logger.LogWarn(context.Background(), fmt.Sprintf("leader election: %s lost background job leadership", leaderHolderID))
+		}
+	}
+}
+
+// IsBackgroundLeader reports whether this node currently holds the background
+// job lease. Cron-style jobs that must run on exactly one node check it on
+// every tick, so leadership changes take effect without restarting the jobs.
+func IsBackgroundLeader() bool {
+	return isLeader.Load()
+}
+
+// ResignLeadership releases the lease during shutdown so another master can
+// take over immediately.
+func ResignLeadership() {
+	if !isLeader.Swap(false) {
+		return
+	}
+	if err := model.ReleaseLeaderLease(model.LeaderLeaseNameBackground, leaderHolderID); err != nil {
+		logger.LogWarn(context.Background(), fmt.Sprintf("leader election: release lease failed: %v", err))
+	}
+}
+
+func GetLeaderStatus() (*LeaderStatus, error) {
+	lease, err := model.GetLeaderLease(model.LeaderLeaseNameBackground)
+	if err != nil {
+		return nil, err
+	}
+	status := &LeaderStatus{
+		LeaseName:     model.LeaderLeaseNameBackground,
+		CurrentNode:   common.NodeName,
+		IsCurrentNode: IsBackgroundLeader(),
+	}
+	if lease == nil {
+		return status, nil
+	}
+	status.LeaderNodeName = lease.NodeName
+	status.LeaseUntil = lease.LeaseUntil
+	status.AcquiredAt = lease.AcquiredAt
+	status.Active = lease.IsActive(common.GetTimestamp())
+	return status, nil
+}
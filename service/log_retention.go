@@ -0,0 +1,273 @@
+package service
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+const (
+	logRetentionDeleteBatchSize = 1000
+	logRetentionExportPageSize  = 1000
+	logRetentionDaySeconds      = 24 * 3600
+)
+
+// LogRetentionPayload fixes the per-type cutoffs when the run is created, so
+// a retried run purges exactly the same range. A zero cutoff skips the type.
+type LogRetentionPayload struct {
+	ConsumeCutoff int64 `json:"consume_cutoff"`
+	ErrorCutoff   int64 `json:"error_cutoff"`
+}
+
+// LogRetentionState is persisted after every processed day so admins can
+// follow a long run through the system task API.
+type LogRetentionState struct {
+	CurrentType    string   `json:"current_type"`
+	CurrentDay     string   `json:"current_day"`
+	ConsumeDeleted int64    `json:"consume_deleted"`
+	ErrorDeleted   int64    `json:"error_deleted"`
+	ArchivedFiles  []string `json:"archived_files"`
+}
+
+// logRetentionHandler purges consume and error logs older than their
+// configured retention, one UTC day at a time. With archival enabled each day
+// is exported to S3-compatible storage as gzip JSONL and only deleted after
+// the upload succeeded.
+type logRetentionHandler struct{}
+
+func init() {
+	RegisterSystemTaskHandler(logRetentionHandler{})
+}
+
+func (logRetentionHandler) Type() string { return model.SystemTaskTypeLogRetention }
+
+func (logRetentionHandler) Enabled() bool {
+	setting := operation_setting.GetLogRetentionSetting()
+	return setting.ConsumeRetentionDays > 0 || setting.ErrorRetentionDays > 0
+}
+
+func (logRetentionHandler) Interval() time.Duration { return 6 * time.Hour }
+
+func (logRetentionHandler) NewPayload() any {
+	return NewLogRetentionPayload()
+}
+
+// NewLogRetentionPayload computes day-aligned cutoffs from the current
+// settings. Aligning to UTC midnight keeps every archived object a full day,
+// so a later run never overwrites a partially exported day.
+func NewLogRetentionPayload() LogRetentionPayload {
+	setting := operation_setting.GetLogRetentionSetting()
+	now := common.GetTimestamp()
+	today := now - now%logRetentionDaySeconds
+	payload := LogRetentionPayload{}
+	if setting.ConsumeRetentionDays > 0 {
+		payload.ConsumeCutoff = today - int64(setting.ConsumeRetentionDays)*logRetentionDaySeconds
+	}
+	if setting.ErrorRetentionDays > 0 {
+		payload.ErrorCutoff = today - int64(setting.ErrorRetentionDays)*logRetentionDaySeconds
+	}
+	return payload
+}
+
+func (logRetentionHandler) Run(ctx context.Context, task *model.SystemTask, runnerID string) {
+	payload := LogRetentionPayload{}
+	if err := task.DecodePayload(&payload); err != nil {
+		failSystemTask(task, runnerID, err)
+		return
+	}
+	state := LogRetentionState{}
+	if err := task.DecodeState(&state); err != nil {
+		failSystemTask(task, runnerID, err)
+		return
+	}
+	targets := []struct {
+		name    string
+		logType int
+		cutoff  int64
+		deleted *int64
+	}{
+		{"consume", model.LogTypeConsume, payload.ConsumeCutoff, &state.ConsumeDeleted},
+		{"error", model.LogTypeError, payload.ErrorCutoff, &state.ErrorDeleted},
+	}
+	for _, target := range targets {
+		if target.cutoff <= 0 {
+			continue
+		}
+		for {
+			if err := ctx.Err(); err != nil {
+				failSystemTask(task, runnerID, err)
+				return
+			}
+			oldest, err := model.GetOldestLogCreatedAt(ctx, target.logType, target.cutoff)
+			if err != nil {
+				failSystemTask(task, runnerID, err)
+				return
+			}
+			if oldest <= 0 {
+				break
+			}
+			dayStart := oldest - oldest%logRetentionDaySeconds
+			dayEnd := min(dayStart+logRetentionDaySeconds, target.cutoff)
+			day := time.Unix(dayStart, 0).UTC().Format("2006-01-02")
+			state.CurrentType = target.name
+			state.CurrentDay = day
+
+			if operation_setting.GetLogRetentionSetting().ArchiveEnabled {
+				objectKey, err := archiveLogDay(ctx, target.name, target.logType, dayStart, dayEnd, day)
+				if err != nil {
+					failSystemTask(task, runnerID, fmt.Errorf("archive %s logs of %s: %w", target.name, day, err))
+					return
+				}
+				state.ArchivedFiles = append(state.ArchivedFiles, objectKey)
+			}
+
+			progressed := false
+			for {
+				deleted, err := model.DeleteLogsByTypeInRange(ctx, target.logType, dayStart, dayEnd, logRetentionDeleteBatchSize)
+				if err != nil {
+					failSystemTask(task, runnerID, err)
+					return
+				}
+				if deleted == 0 {
+					break
+				}
+				progressed = true
+				*target.deleted += deleted
+			}
+			if !progressed {
+				failSystemTask(task, runnerID, fmt.Errorf("no %s log rows were deleted for %s", target.name, day))
+				return
+			}
+			if err := model.UpdateSystemTaskState(task.TaskID, runnerID, state); err != nil {
+				logSystemTaskLockError(ctx, task, err)
+				return
+			}
+		}
+	}
+	state.CurrentType = ""
+	state.CurrentDay = ""
+	if err := model.FinishSystemTask(task.TaskID, runnerID, model.SystemTaskStatusSucceeded, state, ""); err != nil {
+		logSystemTaskLockError(ctx, task, err)
+	}
+}
+
+// archiveLogDay exports [start, end) of one log type into a temporary gzip
+// JSONL file and uploads it, returning the object key.
+func archiveLogDay(ctx context.Context, typeName string, logType int, start int64, end int64, day string) (string, error) {
+	file, err := os.CreateTemp("", "new-api-log-archive-*.jsonl.gz")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+	}()
+
+	gz := gzip.NewWriter(file)
+	for offset := 0; ; offset += logRetentionExportPageSize {
+		logs, err := model.GetLogsByTypeInRange(ctx, logType, start, end, offset, logRetentionExportPageSize)
+		if err != nil {
+			return "", err
+		}
+		for _, log := range logs {
+			line, err := common.Marshal(log)
+			if err != nil {
+				return "", err
+			}
+			if _, err := gz.Write(append(line, '\n')); err != nil {
+				return "", err
+			}
+		}
+		if len(logs) < logRetentionExportPageSize {
+			break
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return "", err
+	}
+
+	setting := operation_setting.GetLogRetentionSetting()
+	objectKey := fmt.Sprintf("%s/%s.jsonl.gz", typeName, day)
+	if prefix := strings.Trim(setting.S3Prefix, "/"); prefix != "" {
+		objectKey = prefix + "/" + objectKey
+	}
+	if err := uploadLogArchive(ctx, setting, objectKey, file); err != nil {
+		return "", err
+	}
+	return objectKey, nil
+}
+
+// uploadLogArchive PUTs the file to an S3-compatible bucket using SigV4.
+func uploadLogArchive(ctx context.Context, setting *operation_setting.LogRetentionSetting, objectKey string, file *os.File) error {
+	if setting.S3Endpoint == "" || setting.S3Bucket == "" {
+		return errors.New("s3 endpoint and bucket are required for log archival")
+	}
+	endpoint, err := url.Parse(strings.TrimSuffix(setting.S3Endpoint, "/"))
+	if err != nil {
+		return err
+	}
+	if setting.S3PathStyle {
+		endpoint.Path += "/" + setting.S3Bucket + "/" + objectKey
+	} else {
+		endpoint.Host = setting.S3Bucket + "." + endpoint.Host
+		endpoint.Path += "/" + objectKey
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	hasher := sha256.New()
+	size, err := io.Copy(hasher, file)
+	if err != nil {
+		return err
+	}
+	payloadHash := hex.EncodeToString(hasher.Sum(nil))
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint.String(), file)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/gzip")
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	credentials := aws.Credentials{
+		AccessKeyID:     setting.S3AccessKeyId,
+		SecretAccessKey: setting.S3AccessSecret,
+	}
+	region := setting.S3Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	if err := v4.NewSigner().SignHTTP(ctx, credentials, req, payloadHash, "s3", region, time.Now()); err != nil {
+		return err
+	}
+	resp, err := GetHttpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("upload %s: status %d: %s", objectKey, resp.StatusCode, string(body))
+	}
+	return nil
+}
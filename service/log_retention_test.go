@@ -0,0 +1,137 @@
+package service
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/stretchr/testify/require"
+)
+
+func runLogRetentionTask(t *testing.T, payload LogRetentionPayload) *model.SystemTask {
+	t.Helper()
+	task, err := model.CreateSystemTask(model.SystemTaskTypeLogRetention, payload, nil)
+	require.NoError(t, err)
+	claimed, ok, err := model.ClaimSystemTask(task.ID, model.SystemTaskTypeLogRetention, "runner-a", common.GetTimestamp()+60)
+	require.NoError(t, err)
+	require.True(t, ok)
+	logRetentionHandler{}.Run(context.Background(), claimed, "runner-a")
+	finished, err := model.GetSystemTaskByTaskID(task.TaskID)
+	require.NoError(t, err)
+	return finished
+}
+
+func TestLogRetentionPurgesAndArchivesExpiredDays(t *testing.T) {
+	const day = int64(logRetentionDaySeconds)
+	tests := []struct {
+		name         string
+		archive      bool
+		wantArchived []string
+	}{
+		{name: "purge only", archive: false},
+		{name: "archive before purge", archive: true, wantArchived: []string{"logs/consume/1970-01-02.jsonl.gz", "logs/consume/1970-01-03.jsonl.gz"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			truncate(t)
+			var mu sync.Mutex
+			uploads := map[string]string{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, http.MethodPut, r.Method)
+				require.Contains(t, r.Header.Get("Authorization"), "AWS4-HMAC-SHA256")
+				gz, err := gzip.NewReader(r.Body)
+				require.NoError(t, err)
+				body, err := io.ReadAll(gz)
+				require.NoError(t, err)
+				mu.Lock()
+				uploads[strings.TrimPrefix(r.URL.Path, "/archive/")] = string(body)
+				mu.Unlock()
+			}))
+			defer server.Close()
+			originalClient := httpClient
+			httpClient = server.Client()
+			setting := operation_setting.GetLogRetentionSetting()
+			saved := *setting
+			setting.ArchiveEnabled = tt.archive
+			setting.S3Endpoint = server.URL
+			setting.S3Bucket = "archive"
+			setting.S3Prefix = "logs"
+			setting.S3PathStyle = true
+			setting.S3AccessKeyId = "ak"
+			setting.S3AccessSecret = "sk"
+			t.Cleanup(func() {
+				httpClient = originalClient
+				*setting = saved
+			})
+
+			for _, log := range []*model.Log{
+				{UserId: 1, Type: model.LogTypeConsume, CreatedAt: day + 10, Content: "day1-a"},
+				{UserId: 1, Type: model.LogTypeConsume, CreatedAt: day + 20, Content: "day1-b"},
+				{UserId: 1, Type: model.LogTypeConsume, CreatedAt: 2*day + 5, Content: "day2"},
+				{UserId: 1, Type: model.LogTypeConsume, CreatedAt: 3*day + 5, Content: "kept"},
+				{UserId: 1, Type: model.LogTypeError, CreatedAt: day + 30, Content: "error-kept"},
+				{UserId: 1, Type: model.LogTypeTopup, CreatedAt: day + 40, Content: "topup-kept"},
+			} {
+				require.NoError(t, model.LOG_DB.Create(log).Error)
+			}
+
+			task := runLogRetentionTask(t, LogRetentionPayload{ConsumeCutoff: 3 * day})
+			require.Equal(t, model.SystemTaskStatusSucceeded, task.Status, task.Error)
+
+			var remaining []string
+			require.NoError(t, model.LOG_DB.Model(&model.Log{}).Order("created_at").Pluck("content", &remaining).Error)
+			require.ElementsMatch(t, []string{"kept", "error-kept", "topup-kept"}, remaining)
+
+			state := LogRetentionState{}
+			require.NoError(t, common.UnmarshalJsonStr(task.Result, &state))
+			require.Equal(t, int64(3), state.ConsumeDeleted)
+			require.Zero(t, state.ErrorDeleted)
+			require.Equal(t, tt.wantArchived, state.ArchivedFiles)
+			if tt.archive {
+				require.Len(t, uploads, 2)
+				lines := strings.Split(strings.TrimSpace(uploads["logs/consume/1970-01-02.jsonl.gz"]), "\n")
+				require.Len(t, lines, 2)
+				require.Contains(t, lines[0], "day1-a")
+			} else {
+				require.Empty(t, uploads)
+			}
+		})
+	}
+}
+
+func TestLogRetentionKeepsLogsWhenUploadFails(t *testing.T) {
+	truncate(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+	originalClient := httpClient
+	httpClient = server.Client()
+	setting := operation_setting.GetLogRetentionSetting()
+	saved := *setting
+	setting.ArchiveEnabled = true
+	setting.S3Endpoint = server.URL
+	setting.S3Bucket = "archive"
+	setting.S3PathStyle = true
+	t.Cleanup(func() {
+		httpClient = originalClient
+		*setting = saved
+	})
+
+	require.NoError(t, model.LOG_DB.Create(&model.Log{UserId: 1, Type: model.LogTypeError, CreatedAt: 100}).Error)
+	task := runLogRetentionTask(t, LogRetentionPayload{ErrorCutoff: logRetentionDaySeconds})
+	require.Equal(t, model.SystemTaskStatusFailed, task.Status)
+
+	var count int64
+	require.NoError(t, model.LOG_DB.Model(&model.Log{}).Count(&count).Error)
+	require.Equal(t, int64(1), count)
+}
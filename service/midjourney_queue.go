@@ -0,0 +1,268 @@
+package service
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+)
+
+const midjourneyQueueDispatchInterval = time.Second
+
+var errMidjourneyQueueCancelled = errors.New("cancelled by administrator")
+
+// MidjourneyQueueEntry is a Midjourney submission waiting for a free slot.
+// Position is 1-based in the current admission order.
+type MidjourneyQueueEntry struct {
+	Id         string `json:"id"`
+	UserId     int    `json:"user_id"`
+	Action     string `json:"action"`
+	EnqueuedAt int64  `json:"enqueued_at"`
+	Position   int    `json:"position"`
+
+	// done receives nil once the entry is admitted, or the rejection
+	done chan error
+}
+
+// midjourneyQueue admits submissions while the per-user and global running
+// task caps allow. Waiting submissions are admitted fairly: the user with the
+// fewest running tasks goes first, ties go to the earliest arrival. Running
+// tasks are counted from the database so caps hold across nodes; the waiting
+// queue itself is local to the node.
+type midjourneyQueue struct {
+	mu      sync.Mutex
+	waiting []*MidjourneyQueueEntry
+	// admitted submissions whose task is not recorded in the database yet
+	inFlight map[int]int
+	started  bool
+}
+
+var mjQueue = &midjourneyQueue{inFlight: map[int]int{}}
+
+// fairOrder sorts entries by the running tasks of their user, then by arrival.
+func fairOrder(entries []*MidjourneyQueueEntry, running map[int]int) []*MidjourneyQueueEntry {
+	ordered := slices.Clone(entries)
+	slices.SortStableFunc(ordered, func(a, b *MidjourneyQueueEntry) int {
+		if running[a.UserId] != running[b.UserId] {
+			return cmp.Compare(running[a.UserId], running[b.UserId])
+		}
+		return cmp.Compare(a.EnqueuedAt, b.EnqueuedAt)
+	})
+	return ordered
+}
+
+// admitLocked admits waiting entries until a cap is reached. running holds
+// the database counts and is updated with the admitted entries.
+func (q *midjourneyQueue) admitLocked(running map[int]int, setting *operation_setting.MidjourneyQueueSetting) {
+	total := 0
+	for userId, count := range q.inFlight {
+		running[userId] += count
+	}
+	for _, count := range running {
+		total += count
+	}
+	for len(q.waiting) > 0 {
+		if setting.MaxRunningTasks > 0 && total >= setting.MaxRunningTasks {
+			return
+		}
+		// re-rank after every admission so one user's backlog cannot jump ahead
+		var next *MidjourneyQueueEntry
+		for _, entry := range fairOrder(q.waiting, running) {
+			if setting.MaxUserRunningTasks <= 0 || running[entry.UserId] < setting.MaxUserRunningTasks {
+				next = entry
+				break
+			}
+		}
+		if next == nil {
+			return
+		}
+		q.removeLocked(next.Id)
+		q.inFlight[next.UserId]++
+		running[next.UserId]++
+		total++
+		next.done <- nil
+	}
+}
+
+func (q *midjourneyQueue) removeLocked(id string) *MidjourneyQueueEntry {
+	index := slices.IndexFunc(q.waiting, func(entry *MidjourneyQueueEntry) bool { return entry.Id == id })
+	if index < 0 {
+		return nil
+	}
+	entry := q.waiting[index]
+	q.waiting = slices.Delete(q.waiting, index, index+1)
+	return entry
+}
+
+func (q *midjourneyQueue) dispatch() {
+	running, err := model.CountRunningMidjourneyTasks()
+	if err != nil {
+		common.SysError("failed to count running midjourney tasks: " + err.Error())
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.admitLocked(running, operation_setting.GetMidjourneyQueueSetting())
+}
+
+// startDispatcherLocked keeps admitting waiting entries as running tasks
+// finish. It stops once the queue is empty.
+func (q *midjourneyQueue) startDispatcherLocked() {
+	if q.started {
+		return
+	}
+	q.started = true
+	gopool.Go(func() {
+		for {
+			time.Sleep(midjourneyQueueDispatchInterval)
+			q.mu.Lock()
+			if len(q.waiting) == 0 {
+				q.started = false
+				q.mu.Unlock()
+				return
+			}
+			q.mu.Unlock()
+			q.dispatch()
+		}
+	})
+}
+
+// positionLocked returns the 1-based position of id in the admission order.
+func (q *midjourneyQueue) positionLocked(id string, running map[int]int) int {
+	return slices.IndexFunc(fairOrder(q.waiting, running), func(entry *MidjourneyQueueEntry) bool { return entry.Id == id }) + 1
+}
+
+// AcquireMidjourneySlot holds a Midjourney submission until the user's and the
+// site's running task caps leave room for it, waiting in the fair queue up to
+// the configured timeout. The returned release func must be called once the
+// submission has been recorded or has failed.
+func AcquireMidjourneySlot(c *gin.Context, userId int, action string) (func(), *dto.MidjourneyResponse) {
+	setting := operation_setting.GetMidjourneyQueueSetting()
+	if !setting.Enabled || (setting.MaxUserRunningTasks <= 0 && setting.MaxRunningTasks <= 0) {
+		return func() {}, nil
+	}
+	entry := &MidjourneyQueueEntry{
+		Id:         common.GetUUID(),
+		UserId:     userId,
+		Action:     action,
+		EnqueuedAt: time.Now().UnixMilli(),
+		done:       make(chan error, 1),
+	}
+	release := func() {
+		mjQueue.mu.Lock()
+		defer mjQueue.mu.Unlock()
+		mjQueue.inFlight[userId]--
+		if mjQueue.inFlight[userId] <= 0 {
+			delete(mjQueue.inFlight, userId)
+		}
+	}
+
+	mjQueue.mu.Lock()
+	queued := 0
+	for _, waiting := range mjQueue.waiting {
+		if waiting.UserId == userId {
+			queued++
+		}
+	}
+	if queued > 0 && queued >= setting.MaxUserQueuedTasks {
+		mjQueue.mu.Unlock()
+		return nil, MidjourneyErrorWrapper(constant.MjQueueFull, fmt.Sprintf("queue_full: %d submissions are already waiting", queued))
+	}
+	mjQueue.waiting = append(mjQueue.waiting, entry)
+	mjQueue.mu.Unlock()
+	mjQueue.dispatch()
+
+	var timeout <-chan time.Time
+	if setting.MaxUserQueuedTasks > 0 && setting.QueueTimeoutSeconds > 0 {
+		timer := time.NewTimer(time.Duration(setting.QueueTimeoutSeconds) * time.Second)
+		defer timer.Stop()
+		timeout = timer.C
+		mjQueue.mu.Lock()
+		mjQueue.startDispatcherLocked()
+		mjQueue.mu.Unlock()
+	}
+	select {
+	case err := <-entry.done:
+		if err == nil {
+			return release, nil
+		}
+		return nil, MidjourneyErrorWrapper(constant.MjQueueFull, "queue_cancelled: "+err.Error())
+	default:
+	}
+	if timeout == nil {
+		return nil, leaveMidjourneyQueue(entry, release, "no free slot")
+	}
+	select {
+	case err := <-entry.done:
+		if err == nil {
+			return release, nil
+		}
+		return nil, MidjourneyErrorWrapper(constant.MjQueueFull, "queue_cancelled: "+err.Error())
+	case <-timeout:
+		return nil, leaveMidjourneyQueue(entry, release, "queue timeout")
+	case <-c.Request.Context().Done():
+		return nil, leaveMidjourneyQueue(entry, release, "request cancelled")
+	}
+}
+
+// leaveMidjourneyQueue removes an entry that gave up waiting and reports its
+// position. An entry admitted in the meantime gives its slot back.
+func leaveMidjourneyQueue(entry *MidjourneyQueueEntry, release func(), reason string) *dto.MidjourneyResponse {
+	running, _ := model.CountRunningMidjourneyTasks()
+	mjQueue.mu.Lock()
+	position := mjQueue.positionLocked(entry.Id, running)
+	removed := mjQueue.removeLocked(entry.Id) != nil
+	mjQueue.mu.Unlock()
+	if !removed {
+		if err := <-entry.done; err == nil {
+			release()
+		}
+	}
+	return &dto.MidjourneyResponse{
+		Code:        constant.MjQueueFull,
+		Description: "queue_full: " + reason,
+		Result:      fmt.Sprintf("前面还有 %d 个任务，请稍后再试", max(position-1, 0)),
+	}
+}
+
+// GetMidjourneyQueue lists the waiting submissions in admission order.
+func GetMidjourneyQueue() ([]MidjourneyQueueEntry, error) {
+	running, err := model.CountRunningMidjourneyTasks()
+	if err != nil {
+		return nil, err
+	}
+	mjQueue.mu.Lock()
+	defer mjQueue.mu.Unlock()
+	entries := make([]MidjourneyQueueEntry, 0, len(mjQueue.waiting))
+	for i, entry := range fairOrder(mjQueue.waiting, running) {
+		item := *entry
+		item.Position = i + 1
+		item.done = nil
+		entries = append(entries, item)
+	}
+	return entries, nil
+}
+
+// CancelMidjourneyQueueEntry rejects a waiting submission. It reports false
+// when the entry is no longer waiting.
+func CancelMidjourneyQueueEntry(id string) bool {
+	mjQueue.mu.Lock()
+	defer mjQueue.mu.Unlock()
+	entry := mjQueue.removeLocked(id)
+	if entry == nil {
+		return false
+	}
+	entry.done <- errMidjourneyQueueCancelled
+	return true
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMidjourneyQueueAdmit(t *testing.T) {
+	tests := []struct {
+		name         string
+		setting      operation_setting.MidjourneyQueueSetting
+		running      map[int]int
+		inFlight     map[int]int
+		waitingUsers []int
+		wantAdmitted []string
+	}{
+		{
+			name:         "fewest running tasks first",
+			setting:      operation_setting.MidjourneyQueueSetting{MaxRunningTasks: 2},
+			running:      map[int]int{1: 0},
+			waitingUsers: []int{1, 1, 1, 2},
+			wantAdmitted: []string{"0", "3"},
+		},
+		{
+			name:         "per user cap skips to next user",
+			setting:      operation_setting.MidjourneyQueueSetting{MaxUserRunningTasks: 2},
+			running:      map[int]int{1: 1},
+			waitingUsers: []int{1, 1, 2},
+			wantAdmitted: []string{"2", "0"},
+		},
+		{
+			name:         "in flight submissions count against the cap",
+			setting:      operation_setting.MidjourneyQueueSetting{MaxUserRunningTasks: 1},
+			running:      map[int]int{},
+			inFlight:     map[int]int{1: 1},
+			waitingUsers: []int{1, 2},
+			wantAdmitted: []string{"1"},
+		},
+		{
+			name:         "global cap reached",
+			setting:      operation_setting.MidjourneyQueueSetting{MaxRunningTasks: 3},
+			running:      map[int]int{1: 2, 2: 1},
+			waitingUsers: []int{3},
+			wantAdmitted: nil,
+		},
+		{
+			name:         "round robin across users",
+			setting:      operation_setting.MidjourneyQueueSetting{MaxRunningTasks: 4},
+			running:      map[int]int{},
+			waitingUsers: []int{1, 1, 1, 2, 2},
+			wantAdmitted: []string{"0", "3", "1", "4"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue := &midjourneyQueue{inFlight: map[int]int{}}
+			for userId, count := range tt.inFlight {
+				queue.inFlight[userId] = count
+			}
+			entries := make([]*MidjourneyQueueEntry, 0, len(tt.waitingUsers))
+			for i, userId := range tt.waitingUsers {
+				entry := &MidjourneyQueueEntry{
+					Id:         string(rune('0' + i)),
+					UserId:     userId,
+					EnqueuedAt: int64(i),
+					done:       make(chan error, 1),
+				}
+				entries = append(entries, entry)
+				queue.waiting = append(queue.waiting, entry)
+			}
+
+			queue.admitLocked(tt.running, &tt.setting)
+
+			var admitted []string
+			for _, entry := range entries {
+				select {
+				case err := <-entry.done:
+					assert.NoError(t, err)
+					admitted = append(admitted, entry.Id)
+				default:
+				}
+			}
+			assert.ElementsMatch(t, tt.wantAdmitted, admitted)
+			assert.Len(t, queue.waiting, len(tt.waitingUsers)-len(tt.wantAdmitted))
+		})
+	}
+}
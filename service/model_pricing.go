@@ -0,0 +1,48 @@
+package service
+
+import (
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/billing_setting"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+
+	"github.com/shopspring/decimal"
+)
+
+// GetModelEffectivePrice converts the model ratios of pricing into USD prices
+// for a request billed under group by a user of userGroup, applying the same
+// group ratio resolution as the relay. Models billed by tiered expression
+// have no single token price, so only the ratios are reported for them.
+func GetModelEffectivePrice(pricing model.Pricing, userGroup string, group string) dto.ModelEffectivePrice {
+	groupRatio, ok := ratio_setting.GetGroupGroupRatio(userGroup, group)
+	if !ok {
+		groupRatio = ratio_setting.GetGroupRatio(group)
+	}
+	price := dto.ModelEffectivePrice{
+		Group:       group,
+		GroupRatio:  groupRatio,
+		QuotaType:   pricing.QuotaType,
+		BillingMode: pricing.BillingMode,
+	}
+	ratio := decimal.NewFromFloat(groupRatio)
+	if pricing.QuotaType == 1 {
+		requestPrice := decimal.NewFromFloat(pricing.ModelPrice).Mul(ratio).Round(6).InexactFloat64()
+		price.RequestPrice = &requestPrice
+		return price
+	}
+	if pricing.BillingMode == billing_setting.BillingModeTieredExpr {
+		return price
+	}
+	// a model ratio of 1 costs QuotaPerUnit quota, i.e. 1 USD, per QuotaPerUnit tokens
+	input := decimal.NewFromFloat(pricing.ModelRatio).Mul(ratio).
+		Mul(decimal.NewFromInt(1_000_000)).Div(decimal.NewFromFloat(common.QuotaPerUnit))
+	inputPrice := input.Round(6).InexactFloat64()
+	outputPrice := input.Mul(decimal.NewFromFloat(pricing.CompletionRatio)).Round(6).InexactFloat64()
+	price.InputPrice, price.OutputPrice = &inputPrice, &outputPrice
+	if pricing.CacheRatio != nil {
+		cacheReadPrice := input.Mul(decimal.NewFromFloat(*pricing.CacheRatio)).Round(6).InexactFloat64()
+		price.CacheReadPrice = &cacheReadPrice
+	}
+	return price
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetModelEffectivePrice(t *testing.T) {
+	oldGroupRatio := ratio_setting.GroupRatio2JSONString()
+	oldGroupGroupRatio := ratio_setting.GroupGroupRatio2JSONString()
+	require.NoError(t, ratio_setting.UpdateGroupRatioByJSONString(`{"default": 1, "vip": 0.5}`))
+	require.NoError(t, ratio_setting.UpdateGroupGroupRatioByJSONString(`{"partner": {"vip": 0.25}}`))
+	t.Cleanup(func() {
+		_ = ratio_setting.UpdateGroupRatioByJSONString(oldGroupRatio)
+		_ = ratio_setting.UpdateGroupGroupRatioByJSONString(oldGroupGroupRatio)
+	})
+	ptr := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name      string
+		pricing   model.Pricing
+		userGroup string
+		group     string
+		want      dto.ModelEffectivePrice
+	}{
+		{
+			name:      "token pricing",
+			pricing:   model.Pricing{ModelRatio: 1.25, CompletionRatio: 4, CacheRatio: ptr(0.1)},
+			userGroup: "default",
+			group:     "default",
+			want:      dto.ModelEffectivePrice{Group: "default", GroupRatio: 1, InputPrice: ptr(2.5), OutputPrice: ptr(10), CacheReadPrice: ptr(0.25)},
+		},
+		{
+			name:      "group ratio applies",
+			pricing:   model.Pricing{ModelRatio: 1.25, CompletionRatio: 4},
+			userGroup: "default",
+			group:     "vip",
+			want:      dto.ModelEffectivePrice{Group: "vip", GroupRatio: 0.5, InputPrice: ptr(1.25), OutputPrice: ptr(5)},
+		},
+		{
+			name:      "user group special ratio wins",
+			pricing:   model.Pricing{QuotaType: 1, ModelPrice: 0.04},
+			userGroup: "partner",
+			group:     "vip",
+			want:      dto.ModelEffectivePrice{Group: "vip", GroupRatio: 0.25, QuotaType: 1, RequestPrice: ptr(0.01)},
+		},
+		{
+			name:      "tiered expression has no single price",
+			pricing:   model.Pricing{ModelRatio: 1, CompletionRatio: 1, BillingMode: "tiered_expr"},
+			userGroup: "default",
+			group:     "default",
+			want:      dto.ModelEffectivePrice{Group: "default", GroupRatio: 1, BillingMode: "tiered_expr"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, GetModelEffectivePrice(tt.pricing, tt.userGroup, tt.group))
+		})
+	}
+}
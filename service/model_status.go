@@ -0,0 +1,123 @@
+package service
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/model"
+)
+
+const (
+	ModelStatusOperational = "operational"
+	ModelStatusDegraded    = "degraded"
+	ModelStatusOutage      = "outage"
+	ModelStatusUnknown     = "unknown"
+
+	modelStatusOperationalRate = 0.95
+	modelStatusDegradedRate    = 0.5
+	modelStatusCacheTTL        = time.Minute
+)
+
+// ModelStatus is the public availability of one model over a window. It is
+// aggregated across every channel serving the model and carries no channel
+// details.
+type ModelStatus struct {
+	Model        string  `json:"model"`
+	Status       string  `json:"status"`
+	SuccessRate  float64 `json:"success_rate"`
+	P95LatencyMs int64   `json:"p95_latency_ms"`
+	Probes       int     `json:"probes"`
+	LastProbeAt  int64   `json:"last_probe_at"`
+}
+
+type modelStatusCacheEntry struct {
+	statuses  []ModelStatus
+	expiresAt time.Time
+}
+
+var (
+	modelStatusCache     = make(map[int]modelStatusCacheEntry)
+	modelStatusCacheLock sync.RWMutex
+)
+
+// GetModelStatuses returns the availability of every advertised model over
+// the last hours, derived from the channel health probes of the channels
+// that serve it. Results are cached for a minute per window since the
+// endpoint may be public; the snapshot is built without holding the lock and
+// swapped in afterwards, so a slow query never blocks readers.
+func GetModelStatuses(hours int) ([]ModelStatus, error) {
+	modelStatusCacheLock.RLock()
+	entry, ok := modelStatusCache[hours]
+	modelStatusCacheLock.RUnlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.statuses, nil
+	}
+
+	buckets, err := model.GetModelHealthBuckets(time.Now().Add(-time.Duration(hours) * time.Hour).Unix())
+	if err != nil {
+		return nil, err
+	}
+	pricing := model.GetPricing()
+	models := make([]string, 0, len(pricing))
+	for _, item := range pricing {
+		models = append(models, item.ModelName)
+	}
+	statuses := summarizeModelStatuses(models, buckets)
+
+	modelStatusCacheLock.Lock()
+	modelStatusCache[hours] = modelStatusCacheEntry{statuses: statuses, expiresAt: time.Now().Add(modelStatusCacheTTL)}
+	modelStatusCacheLock.Unlock()
+	return statuses, nil
+}
+
+// summarizeModelStatuses turns the per-model probe buckets into statuses. The
+// p95 latency only counts successful probes, as failed ones usually end early
+// or time out.
+func summarizeModelStatuses(models []string, buckets []model.ModelHealthBucket) []ModelStatus {
+	modelBuckets := make(map[string][]model.ModelHealthBucket)
+	for _, bucket := range buckets {
+		modelBuckets[bucket.Model] = append(modelBuckets[bucket.Model], bucket)
+	}
+	statuses := make([]ModelStatus, 0, len(models))
+	for _, modelName := range models {
+		status := ModelStatus{Model: modelName, Status: ModelStatusUnknown}
+		var succeeded int
+		var latencies []model.ModelHealthBucket
+		for _, bucket := range modelBuckets[modelName] {
+			status.Probes += int(bucket.Probes)
+			status.LastProbeAt = max(status.LastProbeAt, bucket.LastProbeAt)
+			if bucket.Success {
+				succeeded += int(bucket.Probes)
+				latencies = append(latencies, bucket)
+			}
+		}
+		if status.Probes > 0 {
+			status.SuccessRate = math.Round(float64(succeeded)/float64(status.Probes)*10000) / 10000
+			switch {
+			case status.SuccessRate >= modelStatusOperationalRate:
+				status.Status = ModelStatusOperational
+			case status.SuccessRate >= modelStatusDegradedRate:
+				status.Status = ModelStatusDegraded
+			default:
+				status.Status = ModelStatusOutage
+			}
+		}
+		if succeeded > 0 {
+			sort.Slice(latencies, func(i, j int) bool { return latencies[i].LatencyMs < latencies[j].LatencyMs })
+			rank := int64(math.Ceil(0.95 * float64(succeeded)))
+			var seen int64
+			for _, bucket := range latencies {
+				seen += bucket.Probes
+				if seen >= rank {
+					status.P95LatencyMs = bucket.LatencyMs
+					break
+				}
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Model < statuses[j].Model })
+	return statuses
+}
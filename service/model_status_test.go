@@ -0,0 +1,47 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarizeModelStatuses(t *testing.T) {
+	var buckets []model.ModelHealthBucket
+	for i := 1; i <= 20; i++ {
+		buckets = append(buckets, model.ModelHealthBucket{Model: "healthy", Success: true, LatencyMs: int64(i * 10), Probes: 1, LastProbeAt: int64(100 + i)})
+	}
+	buckets = append(buckets,
+		model.ModelHealthBucket{Model: "shared", Success: true, LatencyMs: 10, Probes: 19, LastProbeAt: 120},
+		model.ModelHealthBucket{Model: "shared", Success: true, LatencyMs: 1000, Probes: 11, LastProbeAt: 50},
+		model.ModelHealthBucket{Model: "shared", Success: false, Probes: 10, LastProbeAt: 50},
+		model.ModelHealthBucket{Model: "broken", Success: false, Probes: 1, LastProbeAt: 10},
+		model.ModelHealthBucket{Model: "unlisted", Success: true, LatencyMs: 1, Probes: 1, LastProbeAt: 999},
+	)
+
+	statuses := summarizeModelStatuses([]string{"unprobed", "shared", "healthy", "broken"}, buckets)
+	require.Len(t, statuses, 4)
+	byModel := make(map[string]ModelStatus)
+	for _, status := range statuses {
+		byModel[status.Model] = status
+	}
+	assert.Equal(t, "broken", statuses[0].Model, "sorted by model name")
+
+	tests := []struct {
+		model string
+		want  ModelStatus
+	}{
+		{model: "healthy", want: ModelStatus{Model: "healthy", Status: ModelStatusOperational, SuccessRate: 1, P95LatencyMs: 190, Probes: 20, LastProbeAt: 120}},
+		{model: "shared", want: ModelStatus{Model: "shared", Status: ModelStatusDegraded, SuccessRate: 0.75, P95LatencyMs: 1000, Probes: 40, LastProbeAt: 120}},
+		{model: "broken", want: ModelStatus{Model: "broken", Status: ModelStatusOutage, Probes: 1, LastProbeAt: 10}},
+		{model: "unprobed", want: ModelStatus{Model: "unprobed", Status: ModelStatusUnknown}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			assert.Equal(t, tt.want, byModel[tt.model])
+		})
+	}
+}
@@ -0,0 +1,321 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+)
+
+// moderationModelMaxInput caps the text sent to the moderation model; the
+// prompt head is enough to classify it and keeps the call cheap.
+const moderationModelMaxInput = 32 * 1024
+
+// moderationRedactSkipKeys are JSON fields never redacted because they carry
+// identifiers or payloads rather than prompt text.
+var moderationRedactSkipKeys = map[string]bool{
+	"model": true, "role": true, "type": true, "id": true, "name": true,
+	"tool_call_id": true, "call_id": true, "url": true, "image_url": true,
+	"data": true, "file_id": true, "mime_type": true, "media_type": true,
+}
+
+type moderationMatch struct {
+	source  string
+	matched []string
+}
+
+// ModerateRequest runs the pre-flight moderation stage for the prompt text
+// according to the group's action:
+//   - block rejects the request on any hit;
+//   - log records the hits and lets the request through;
+//   - redact rewrites banned-word and regex hits in the request body, still
+//     rejecting requests flagged by the moderation model.
+//
+// It reports whether the body was rewritten, in which case the caller must
+// parse the request again.
+func ModerateRequest(c *gin.Context, info *relaycommon.RelayInfo, action string, text string) (bool, *types.NewAPIError) {
+	if text == "" {
+		return false, nil
+	}
+	setting := operation_setting.GetModerationSetting()
+	matches := matchModerationRules(text, setting.BannedWords, operation_setting.GetModerationRegexps())
+	if len(matches) == 0 || action != operation_setting.ModerationActionBlock {
+		if match, err := checkModerationModel(c, setting, text); err != nil {
+			// fail open: an unavailable moderation model must not take the relay down
+			logger.LogWarn(c, fmt.Sprintf("moderation model check failed: %s", err.Error()))
+		} else if match != nil {
+			matches = append(matches, *match)
+		}
+	}
+	if len(matches) == 0 {
+		return false, nil
+	}
+
+	flaggedByModel := false
+	for _, match := range matches {
+		if match.source == model.ModerationSourceModel {
+			flaggedByModel = true
+		}
+	}
+	effective := action
+	if action == operation_setting.ModerationActionRedact && flaggedByModel {
+		effective = operation_setting.ModerationActionBlock
+	}
+	recordModerationHits(c, info, effective, matches)
+
+	switch effective {
+	case operation_setting.ModerationActionLog:
+		return false, nil
+	case operation_setting.ModerationActionRedact:
+		err := redactRequestBody(c, setting.BannedWords, operation_setting.GetModerationRegexps(), setting.Replacement)
+		if err == nil {
+			return true, nil
+		}
+		// e.g. multipart bodies cannot be rewritten, fall back to blocking
+		logger.LogWarn(c, fmt.Sprintf("moderation redaction failed, blocking instead: %s", err.Error()))
+	}
+	return false, types.NewErrorWithStatusCode(errors.New("request blocked by content moderation"),
+		types.ErrorCodePromptBlocked, http.StatusBadRequest, types.ErrOptionWithSkipRetry())
+}
+
+func matchModerationRules(text string, words []string, regexps []*regexp.Regexp) []moderationMatch {
+	var matches []moderationMatch
+	if ok, hits := AcSearch(strings.ToLower(text), words, false); ok {
+		slices.Sort(hits)
+		matches = append(matches, moderationMatch{source: model.ModerationSourceWord, matched: slices.Compact(hits)})
+	}
+	var rules []string
+	for _, re := range regexps {
+		if re.MatchString(text) {
+			rules = append(rules, re.String())
+		}
+	}
+	if len(rules) > 0 {
+		matches = append(matches, moderationMatch{source: model.ModerationSourceRegex, matched: rules})
+	}
+	return matches
+}
+
+type moderationModelResponse struct {
+	Results []struct {
+		Flagged    bool            `json:"flagged"`
+		Categories map[string]bool `json:"categories"`
+	} `json:"results"`
+}
+
+// checkModerationModel calls an OpenAI-compatible /v1/moderations endpoint.
+func checkModerationModel(c *gin.Context, setting *operation_setting.ModerationSetting, text string) (*moderationMatch, error) {
+	if !setting.ModelEnabled || setting.ModelBaseURL == "" {
+		return nil, nil
+	}
+	if runes := []rune(text); len(runes) > moderationModelMaxInput {
+		text = string(runes[:moderationModelMaxInput])
+	}
+	body, err := common.Marshal(map[string]any{
+		"model": setting.ModelName,
+		"input": text,
+	})
+	if err != nil {
+		return nil, err
+	}
+	timeout := time.Duration(setting.ModelTimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+	url := strings.TrimSuffix(setting.ModelBaseURL, "/") + "/v1/moderations"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if setting.ModelAPIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+setting.ModelAPIKey)
+	}
+	resp, err := GetHttpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("moderation endpoint returned status %d", resp.StatusCode)
+	}
+	var result moderationModelResponse
+	if err := common.Unmarshal(respBody, &result); err != nil {
+		return nil, err
+	}
+	var categories []string
+	flagged := false
+	for _, r := range result.Results {
+		if !r.Flagged {
+			continue
+		}
+		flagged = true
+		for category, hit := range r.Categories {
+			if hit {
+				categories = append(categories, category)
+			}
+		}
+	}
+	if !flagged {
+		return nil, nil
+	}
+	slices.Sort(categories)
+	return &moderationMatch{source: model.ModerationSourceModel, matched: slices.Compact(categories)}, nil
+}
+
+func recordModerationHits(c *gin.Context, info *relaycommon.RelayInfo, action string, matches []moderationMatch) {
+	hits := make([]*model.ModerationHit, 0, len(matches))
+	for _, match := range matches {
+		hits = append(hits, &model.ModerationHit{
+			CreatedAt:  common.GetTimestamp(),
+			UserId:     info.UserId,
+			Username:   c.GetString("username"),
+			TokenId:    info.TokenId,
+			UsingGroup: info.UsingGroup,
+			ModelName:  info.OriginModelName,
+			Source:     match.source,
+			Action:     action,
+			Matched:    strings.Join(match.matched, ","),
+			RequestId:  info.RequestId,
+			Ip:         c.ClientIP(),
+		})
+		logger.LogWarn(c, fmt.Sprintf("content moderation %s hit (%s): %s", match.source, action, strings.Join(match.matched, ", ")))
+	}
+	gopool.Go(func() {
+		for _, hit := range hits {
+			if err := model.RecordModerationHit(hit); err != nil {
+				common.SysError("failed to record moderation hit: " + err.Error())
+			}
+		}
+	})
+}
+
+var moderationWordRegexCache sync.Map
+
+// moderationWordsRegexp builds a case-insensitive alternation of the banned
+// words, longest first so overlapping words are replaced as a whole.
+func moderationWordsRegexp(words []string) *regexp.Regexp {
+	key := acKey(words)
+	if key == "" {
+		return nil
+	}
+	if cached, ok := moderationWordRegexCache.Load(key); ok {
+		return cached.(*regexp.Regexp)
+	}
+	quoted := make([]string, 0, len(words))
+	for _, word := range words {
+		if word = strings.TrimSpace(word); word != "" {
+			quoted = append(quoted, regexp.QuoteMeta(word))
+		}
+	}
+	sort.Slice(quoted, func(i, j int) bool { return len(quoted[i]) > len(quoted[j]) })
+	re := regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))
+	moderationWordRegexCache.Store(key, re)
+	return re
+}
+
+// redactModerationText replaces banned words and regex matches in text.
+func redactModerationText(text string, words []string, regexps []*regexp.Regexp, replacement string) string {
+	if re := moderationWordsRegexp(words); re != nil {
+		text = re.ReplaceAllLiteralString(text, replacement)
+	}
+	for _, re := range regexps {
+		text = re.ReplaceAllLiteralString(text, replacement)
+	}
+	return text
+}
+
+// redactRequestBody rewrites the prompt text inside the JSON request body and
+// swaps the cached body storage so later reads see the redacted request.
+func redactRequestBody(c *gin.Context, words []string, regexps []*regexp.Regexp, replacement string) error {
+	storage, err := common.GetBodyStorage(c)
+	if err != nil {
+		return err
+	}
+	raw, err := storage.Bytes()
+	if err != nil {
+		return err
+	}
+	redacted, err := redactModerationJSON(raw, words, regexps, replacement)
+	if err != nil {
+		return fmt.Errorf("redaction needs a JSON request body: %w", err)
+	}
+	newStorage, err := common.CreateBodyStorage(redacted)
+	if err != nil {
+		return err
+	}
+	storage.Close()
+	c.Set(common.KeyBodyStorage, newStorage)
+	c.Request.Body = io.NopCloser(newStorage)
+	c.Request.ContentLength = int64(len(redacted))
+	return nil
+}
+
+// redactModerationJSON redacts every string value of a JSON document except
+// identifier fields. Non-string values are kept verbatim so numbers such as
+// seeds keep their exact precision.
+func redactModerationJSON(raw json.RawMessage, words []string, regexps []*regexp.Regexp, replacement string) (json.RawMessage, error) {
+	switch common.GetJsonType(raw) {
+	case "string":
+		var text string
+		if err := common.Unmarshal(raw, &text); err != nil {
+			return nil, err
+		}
+		return common.Marshal(redactModerationText(text, words, regexps, replacement))
+	case "array":
+		var items []json.RawMessage
+		if err := common.Unmarshal(raw, &items); err != nil {
+			return nil, err
+		}
+		for i, item := range items {
+			redacted, err := redactModerationJSON(item, words, regexps, replacement)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = redacted
+		}
+		return common.Marshal(items)
+	case "object":
+		var fields map[string]json.RawMessage
+		if err := common.Unmarshal(raw, &fields); err != nil {
+			return nil, err
+		}
+		for key, item := range fields {
+			if moderationRedactSkipKeys[key] {
+				continue
+			}
+			redacted, err := redactModerationJSON(item, words, regexps, replacement)
+			if err != nil {
+				return nil, err
+			}
+			fields[key] = redacted
+		}
+		return common.Marshal(fields)
+	}
+	return raw, nil
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+)
+
+const moderationHitCleanupBatchSize = 500
+
+type moderationHitCleanupPayload struct {
+	Cutoff int64 `json:"cutoff"`
+}
+
+type moderationHitCleanupResult struct {
+	Cutoff  int64 `json:"cutoff"`
+	Deleted int64 `json:"deleted"`
+}
+
+// moderationHitCleanupHandler prunes moderation hits older than the
+// configured retention window, like the audit log cleanup.
+type moderationHitCleanupHandler struct{}
+
+func init() {
+	RegisterSystemTaskHandler(moderationHitCleanupHandler{})
+}
+
+func (moderationHitCleanupHandler) Type() string { return model.SystemTaskTypeModerationHitCleanup }
+
+func (moderationHitCleanupHandler) Enabled() bool {
+	return operation_setting.GetModerationSetting().HitRetentionDays > 0
+}
+
+func (moderationHitCleanupHandler) Interval() time.Duration { return 6 * time.Hour }
+
+func (moderationHitCleanupHandler) NewPayload() any {
+	days := operation_setting.GetModerationSetting().HitRetentionDays
+	return moderationHitCleanupPayload{Cutoff: common.GetTimestamp() - int64(days)*24*3600}
+}
+
+func (moderationHitCleanupHandler) Run(ctx context.Context, task *model.SystemTask, runnerID string) {
+	payload := moderationHitCleanupPayload{}
+	if err := task.DecodePayload(&payload); err != nil {
+		failSystemTask(task, runnerID, err)
+		return
+	}
+	result := &moderationHitCleanupResult{Cutoff: payload.Cutoff}
+	for {
+		if err := ctx.Err(); err != nil {
+			failSystemTask(task, runnerID, err)
+			return
+		}
+		deleted, err := model.DeleteModerationHitsBefore(payload.Cutoff, moderationHitCleanupBatchSize)
+		if err != nil {
+			failSystemTask(task, runnerID, err)
+			return
+		}
+		result.Deleted += deleted
+		if deleted < moderationHitCleanupBatchSize {
+			break
+		}
+	}
+	if err := model.FinishSystemTask(task.TaskID, runnerID, model.SystemTaskStatusSucceeded, result, ""); err != nil {
+		logSystemTaskLockError(ctx, task, err)
+	}
+}
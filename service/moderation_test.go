@@ -0,0 +1,142 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newModerationContext(t *testing.T, body string) *gin.Context {
+	t.Helper()
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+	_, err := common.GetBodyStorage(ctx)
+	require.NoError(t, err)
+	return ctx
+}
+
+func setModerationSettingForTest(t *testing.T) *operation_setting.ModerationSetting {
+	t.Helper()
+	setting := operation_setting.GetModerationSetting()
+	original := *setting
+	t.Cleanup(func() {
+		*setting = original
+		model.DB.Exec("DELETE FROM moderation_hits")
+	})
+	setting.Enabled = true
+	setting.BannedWords = []string{"Forbidden"}
+	setting.RegexRules = []string{`\b\d{3}-\d{4}\b`}
+	setting.Replacement = "***"
+	setting.ModelEnabled = false
+	return setting
+}
+
+func TestModerateRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setModerationSettingForTest(t)
+	const body = `{"model":"forbidden-model","seed":12345678901234567,"messages":[{"role":"user","content":"a FORBIDDEN call to 555-1234"}]}`
+	const text = "a FORBIDDEN call to 555-1234"
+
+	tests := []struct {
+		name         string
+		action       string
+		text         string
+		wantBlocked  bool
+		wantRedacted bool
+		wantBody     string
+	}{
+		{name: "clean text passes", action: operation_setting.ModerationActionBlock, text: "hello"},
+		{name: "block rejects", action: operation_setting.ModerationActionBlock, text: text, wantBlocked: true},
+		{name: "log lets the request through", action: operation_setting.ModerationActionLog, text: text},
+		{
+			name:         "redact rewrites prompt text only",
+			action:       operation_setting.ModerationActionRedact,
+			text:         text,
+			wantRedacted: true,
+			wantBody:     `{"messages":[{"content":"a *** call to ***","role":"user"}],"model":"forbidden-model","seed":12345678901234567}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := newModerationContext(t, body)
+			info := &relaycommon.RelayInfo{UserId: 1, UsingGroup: "default", RequestId: tt.name}
+			redacted, err := ModerateRequest(ctx, info, tt.action, tt.text)
+			assert.Equal(t, tt.wantRedacted, redacted)
+			if tt.wantBlocked {
+				require.NotNil(t, err)
+				assert.Equal(t, http.StatusBadRequest, err.StatusCode)
+				assert.Equal(t, types.ErrorCodePromptBlocked, err.GetErrorCode())
+				assert.True(t, types.IsSkipRetryError(err))
+			} else {
+				assert.Nil(t, err)
+			}
+			if tt.wantBody != "" {
+				storage, storageErr := common.GetBodyStorage(ctx)
+				require.NoError(t, storageErr)
+				got, readErr := storage.Bytes()
+				require.NoError(t, readErr)
+				assert.JSONEq(t, tt.wantBody, string(got))
+				assert.Contains(t, string(got), "12345678901234567", "numbers keep their precision")
+			}
+		})
+	}
+
+	// hits are written in the background, one per source
+	assert.Eventually(t, func() bool {
+		hits, total, err := model.GetModerationHits(model.ModerationHitQuery{Action: operation_setting.ModerationActionLog}, 0, 10)
+		return err == nil && total == 2 && len(hits) == 2
+	}, time.Second, 10*time.Millisecond)
+	stats, err := model.GetModerationHitStats(model.ModerationHitQuery{}, 10)
+	require.NoError(t, err)
+	assert.EqualValues(t, 6, stats.Total)
+}
+
+func TestModerateRequestWithModel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setting := setModerationSettingForTest(t)
+	setting.BannedWords = []string{}
+	setting.RegexRules = []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/moderations", r.URL.Path)
+		assert.Equal(t, "Bearer sk-test", r.Header.Get("Authorization"))
+		var req struct {
+			Input string `json:"input"`
+		}
+		_ = common.DecodeJson(r.Body, &req)
+		flagged := strconv.FormatBool(strings.Contains(req.Input, "violent"))
+		_, _ = w.Write([]byte(`{"results":[{"flagged":` + flagged + `,"categories":{"violence":` + flagged + `,"hate":false}}]}`))
+	}))
+	defer server.Close()
+	InitHttpClient()
+	setting.ModelEnabled = true
+	setting.ModelBaseURL = server.URL
+	setting.ModelAPIKey = "sk-test"
+
+	info := &relaycommon.RelayInfo{UserId: 1, UsingGroup: "default"}
+	_, err := ModerateRequest(newModerationContext(t, `{}`), info, operation_setting.ModerationActionBlock, "a calm prompt")
+	assert.Nil(t, err)
+	// redaction cannot fix a model verdict, the request is blocked
+	redacted, err := ModerateRequest(newModerationContext(t, `{}`), info, operation_setting.ModerationActionRedact, "a violent prompt")
+	assert.False(t, redacted)
+	require.NotNil(t, err)
+	assert.Equal(t, types.ErrorCodePromptBlocked, err.GetErrorCode())
+
+	// an unreachable moderation model fails open
+	setting.ModelBaseURL = "http://127.0.0.1:1"
+	_, err = ModerateRequest(newModerationContext(t, `{}`), info, operation_setting.ModerationActionBlock, "a violent prompt")
+	assert.Nil(t, err)
+}
@@ -0,0 +1,317 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	htmltemplate "html/template"
+	"regexp"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/i18n"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+)
+
+// Template keys of the outgoing emails and user notifications.
+const (
+	NotificationTemplateEmailVerification    = "email_verification"
+	NotificationTemplatePasswordReset        = "password_reset"
+	NotificationTemplateQuotaLow             = "quota_low"
+	NotificationTemplateSubscriptionQuotaLow = "subscription_quota_low"
+	NotificationTemplateCheckinReminder      = "checkin_reminder"
+	NotificationTemplateCheckinSuccess       = "checkin_success"
+	NotificationTemplateGroupChange          = "group_change"
+	NotificationTemplateTopUpReceipt         = "topup_receipt"
+)
+
+// notificationFallbackLanguage is used when neither the recipient's language
+// nor a custom template for it exists; it matches the historical messages.
+const notificationFallbackLanguage = i18n.LangZhCN
+
+// NotificationCommonVariables are available to every template.
+var NotificationCommonVariables = []string{"system_name", "server_address"}
+
+// NotificationMessage is a rendered (or raw) template.
+type NotificationMessage struct {
+	Subject string `json:"subject"`
+	Content string `json:"content"`
+}
+
+// NotificationTemplateDefinition describes a template: its variables, sample
+// values used for test sends and the built-in text per language.
+type NotificationTemplateDefinition struct {
+	Key       string                         `json:"key"`
+	Variables []string                       `json:"variables"`
+	Sample    map[string]string              `json:"sample"`
+	Defaults  map[string]NotificationMessage `json:"defaults"`
+}
+
+// NotificationTemplateDefinitions lists every customizable template. Subjects
+// are Go text/template and contents Go html/template strings, so variables
+// are written as {{.name}} and values are HTML-escaped in the content.
+var NotificationTemplateDefinitions = []NotificationTemplateDefinition{
+	{
+		Key:       NotificationTemplateEmailVerification,
+		Variables: []string{"code", "valid_minutes"},
+		Sample:    map[string]string{"code": "123456", "valid_minutes": "10"},
+		Defaults: map[string]NotificationMessage{
+			i18n.LangZhCN: {
+				Subject: "{{.system_name}}邮箱验证邮件",
+				Content: "<p>您好，你正在进行{{.system_name}}邮箱验证。</p><p>您的验证码为: <strong>{{.code}}</strong></p><p>验证码 {{.valid_minutes}} 分钟内有效，如果不是本人操作，请忽略。</p>",
+			},
+			i18n.LangEn: {
+				Subject: "{{.system_name}} email verification",
+				Content: "<p>Hello, you are verifying your email address for {{.system_name}}.</p><p>Your verification code is: <strong>{{.code}}</strong></p><p>The code is valid for {{.valid_minutes}} minutes. If you did not request it, please ignore this email.</p>",
+			},
+		},
+	},
+	{
+		Key:       NotificationTemplatePasswordReset,
+		Variables: []string{"link", "valid_minutes"},
+		Sample:    map[string]string{"link": "https://example.com/user/reset?email=user@example.com&token=sample", "valid_minutes": "10"},
+		Defaults: map[string]NotificationMessage{
+			i18n.LangZhCN: {
+				Subject: "{{.system_name}}密码重置",
+				Content: "<p>您好，你正在进行{{.system_name}}密码重置。</p><p>点击 <a href=\"{{.link}}\">此处</a> 进行密码重置。</p><p>如果链接无法点击，请尝试点击下面的链接或将其复制到浏览器中打开：<br> {{.link}} </p><p>重置链接 {{.valid_minutes}} 分钟内有效，如果不是本人操作，请忽略。</p>",
+			},
+			i18n.LangEn: {
+				Subject: "{{.system_name}} password reset",
+				Content: "<p>Hello, you are resetting your {{.system_name}} password.</p><p>Click <a href=\"{{.link}}\">here</a> to reset it.</p><p>If the link does not work, copy it into your browser:<br> {{.link}} </p><p>The link is valid for {{.valid_minutes}} minutes. If you did not request it, please ignore this email.</p>",
+			},
+		},
+	},
+	{
+		Key:       NotificationTemplateQuotaLow,
+		Variables: []string{"remaining_quota", "topup_link"},
+		Sample:    map[string]string{"remaining_quota": "＄0.500000", "topup_link": "https://example.com/console/topup"},
+		Defaults: map[string]NotificationMessage{
+			i18n.LangZhCN: {
+				Subject: "您的额度即将用尽",
+				Content: "您的额度即将用尽，当前剩余额度为 {{.remaining_quota}}，为了不影响您的使用，请及时充值。<br/>充值链接：<a href=\"{{.topup_link}}\">{{.topup_link}}</a>",
+			},
+			i18n.LangEn: {
+				Subject: "Your quota is running low",
+				Content: "Your quota is running low. The remaining quota is {{.remaining_quota}}, please top up in time to avoid interruption.<br/>Top-up link: <a href=\"{{.topup_link}}\">{{.topup_link}}</a>",
+			},
+		},
+	},
+	{
+		Key:       NotificationTemplateSubscriptionQuotaLow,
+		Variables: []string{"remaining_quota", "topup_link"},
+		Sample:    map[string]string{"remaining_quota": "＄0.500000", "topup_link": "https://example.com/console/topup"},
+		Defaults: map[string]NotificationMessage{
+			i18n.LangZhCN: {
+				Subject: "您的订阅额度即将用尽",
+				Content: "您的订阅额度即将用尽，当前剩余额度为 {{.remaining_quota}}，为了不影响您的使用，请及时充值。<br/>充值链接：<a href=\"{{.topup_link}}\">{{.topup_link}}</a>",
+			},
+			i18n.LangEn: {
+				Subject: "Your subscription quota is running low",
+				Content: "Your subscription quota is running low. The remaining quota is {{.remaining_quota}}, please top up in time to avoid interruption.<br/>Top-up link: <a href=\"{{.topup_link}}\">{{.topup_link}}</a>",
+			},
+		},
+	},
+	{
+		Key:       NotificationTemplateCheckinReminder,
+		Variables: []string{"date"},
+		Sample:    map[string]string{"date": "2026-01-01"},
+		Defaults: map[string]NotificationMessage{
+			i18n.LangZhCN: {
+				Subject: "签到提醒",
+				Content: "您 {{.date}} 还没有签到，记得签到领取今日额度，保持连续签到哦",
+			},
+			i18n.LangEn: {
+				Subject: "Check-in reminder",
+				Content: "You have not checked in on {{.date}} yet. Check in to claim today's quota and keep your streak going.",
+			},
+		},
+	},
+	{
+		Key:       NotificationTemplateCheckinSuccess,
+		Variables: []string{"date", "quota", "streak", "streak_milestone", "total_milestone", "bonus_quota"},
+		Sample:    map[string]string{"date": "2026-01-01", "quota": "＄0.100000", "streak": "7", "streak_milestone": "7", "total_milestone": "", "bonus_quota": ""},
+		Defaults: map[string]NotificationMessage{
+			i18n.LangZhCN: {
+				Subject: "签到成功",
+				Content: "{{.date}} 签到成功，获得额度 {{.quota}}，连续签到 {{.streak}} 天{{if .streak_milestone}}，达成连续签到 {{.streak_milestone}} 天里程碑{{end}}{{if .total_milestone}}，达成累计签到 {{.total_milestone}} 天里程碑，额外奖励 {{.bonus_quota}}{{end}}",
+			},
+			i18n.LangEn: {
+				Subject: "Check-in succeeded",
+				Content: "Checked in on {{.date}} and received {{.quota}}, {{.streak}}-day streak{{if .streak_milestone}}, reached the {{.streak_milestone}}-day streak milestone{{end}}{{if .total_milestone}}, reached the {{.total_milestone}}-day total milestone with a bonus of {{.bonus_quota}}{{end}}",
+			},
+		},
+	},
+	{
+		Key:       NotificationTemplateGroupChange,
+		Variables: []string{"used_quota", "from_group", "to_group"},
+		Sample:    map[string]string{"used_quota": "＄100.000000", "from_group": "default", "to_group": "vip"},
+		Defaults: map[string]NotificationMessage{
+			i18n.LangZhCN: {
+				Subject: "分组变更",
+				Content: "累计消费 {{.used_quota}}，用户分组由 {{.from_group}} 调整为 {{.to_group}}",
+			},
+			i18n.LangEn: {
+				Subject: "Group changed",
+				Content: "Your total spending reached {{.used_quota}}, your group has been changed from {{.from_group}} to {{.to_group}}",
+			},
+		},
+	},
+	{
+		Key:       NotificationTemplateTopUpReceipt,
+		Variables: []string{"trade_no", "payment_method", "quota", "money", "time"},
+		Sample:    map[string]string{"trade_no": "USR1NO1234567890", "payment_method": "stripe", "quota": "＄10.000000", "money": "10.00", "time": "2026-01-01 12:00:00"},
+		Defaults: map[string]NotificationMessage{
+			i18n.LangZhCN: {
+				Subject: "{{.system_name}}充值到账通知",
+				Content: "<p>您的充值已到账。</p><p>订单号：{{.trade_no}}<br/>支付方式：{{.payment_method}}<br/>支付金额：{{.money}}<br/>到账额度：{{.quota}}<br/>到账时间：{{.time}}</p>",
+			},
+			i18n.LangEn: {
+				Subject: "{{.system_name}} top-up receipt",
+				Content: "<p>Your top-up has been credited.</p><p>Order: {{.trade_no}}<br/>Payment method: {{.payment_method}}<br/>Amount paid: {{.money}}<br/>Quota credited: {{.quota}}<br/>Time: {{.time}}</p>",
+			},
+		},
+	},
+}
+
+// GetNotificationTemplateDefinition returns the definition of a template key.
+func GetNotificationTemplateDefinition(key string) (*NotificationTemplateDefinition, bool) {
+	for i := range NotificationTemplateDefinitions {
+		if NotificationTemplateDefinitions[i].Key == key {
+			return &NotificationTemplateDefinitions[i], true
+		}
+	}
+	return nil, false
+}
+
+// NormalizeNotificationLanguage maps a user or request language to a
+// supported language code; an unset language keeps the historical default.
+func NormalizeNotificationLanguage(lang string) string {
+	if strings.TrimSpace(lang) == "" {
+		return notificationFallbackLanguage
+	}
+	return i18n.ParseAcceptLanguage(lang)
+}
+
+// RenderNotificationTemplate executes a subject and content template with the
+// given variables plus the common ones. Missing variables render empty.
+func RenderNotificationTemplate(subject string, content string, vars map[string]string) (*NotificationMessage, error) {
+	data := map[string]string{
+		"system_name":    common.SystemName,
+		"server_address": system_setting.ServerAddress,
+	}
+	for name, value := range vars {
+		data[name] = value
+	}
+	subjectTmpl, err := texttemplate.New("subject").Option("missingkey=zero").Parse(subject)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subject template: %w", err)
+	}
+	contentTmpl, err := htmltemplate.New("content").Option("missingkey=zero").Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid content template: %w", err)
+	}
+	var subjectBuf, contentBuf bytes.Buffer
+	if err := subjectTmpl.Execute(&subjectBuf, data); err != nil {
+		return nil, fmt.Errorf("failed to render subject: %w", err)
+	}
+	if err := contentTmpl.Execute(&contentBuf, data); err != nil {
+		return nil, fmt.Errorf("failed to render content: %w", err)
+	}
+	return &NotificationMessage{
+		Subject: strings.TrimSpace(subjectBuf.String()),
+		Content: contentBuf.String(),
+	}, nil
+}
+
+// builtinNotificationTemplate returns the built-in template for key, falling
+// back to the default language when lang has no built-in translation.
+func builtinNotificationTemplate(definition *NotificationTemplateDefinition, lang string) NotificationMessage {
+	if builtin, ok := definition.Defaults[lang]; ok {
+		return builtin
+	}
+	return definition.Defaults[notificationFallbackLanguage]
+}
+
+// ResolveNotificationTemplate returns the raw template used for key and lang:
+// a custom template for lang, the built-in one for lang, then the same for
+// the default language. It reports whether the result is a custom template.
+func ResolveNotificationTemplate(key string, lang string) (NotificationMessage, bool, error) {
+	definition, ok := GetNotificationTemplateDefinition(key)
+	if !ok {
+		return NotificationMessage{}, false, fmt.Errorf("unknown notification template %q", key)
+	}
+	lang = NormalizeNotificationLanguage(lang)
+	languages := []string{lang}
+	if lang != notificationFallbackLanguage {
+		languages = append(languages, notificationFallbackLanguage)
+	}
+	for _, language := range languages {
+		custom, err := model.GetNotificationTemplate(key, language)
+		if err != nil {
+			return builtinNotificationTemplate(definition, lang), false, err
+		}
+		if custom != nil {
+			return NotificationMessage{Subject: custom.Subject, Content: custom.Content}, true, nil
+		}
+		if builtin, ok := definition.Defaults[language]; ok {
+			return builtin, false, nil
+		}
+	}
+	return builtinNotificationTemplate(definition, lang), false, nil
+}
+
+// RenderNotification renders the template for key in lang. A custom template
+// that cannot be loaded or rendered is logged and replaced by the built-in
+// text so that verification codes and alerts are still delivered.
+func RenderNotification(key string, lang string, vars map[string]string) NotificationMessage {
+	raw, custom, err := ResolveNotificationTemplate(key, lang)
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to load notification template %s: %v", key, err))
+	}
+	message, err := RenderNotificationTemplate(raw.Subject, raw.Content, vars)
+	if err == nil {
+		return *message
+	}
+	common.SysError(fmt.Sprintf("failed to render notification template %s: %v", key, err))
+	if custom {
+		definition, _ := GetNotificationTemplateDefinition(key)
+		raw = builtinNotificationTemplate(definition, NormalizeNotificationLanguage(lang))
+		if message, err := RenderNotificationTemplate(raw.Subject, raw.Content, vars); err == nil {
+			return *message
+		}
+	}
+	return raw
+}
+
+var (
+	notificationLineBreakRegex = regexp.MustCompile(`(?i)<br\s*/?>|</p>`)
+	notificationTagRegex       = regexp.MustCompile(`<[^>]*>`)
+)
+
+// notificationPlainText converts rendered HTML content for channels that do
+// not support markup, such as Bark and Gotify.
+func notificationPlainText(content string) string {
+	content = notificationLineBreakRegex.ReplaceAllString(content, "\n")
+	content = notificationTagRegex.ReplaceAllString(content, "")
+	return strings.TrimSpace(html.UnescapeString(content))
+}
+
+// newTemplateNotify builds the notification of a rendered template for the
+// user's channel, dropping the markup for channels that cannot display it.
+func newTemplateNotify(notifyType string, message NotificationMessage, userSetting dto.UserSetting) dto.Notify {
+	content := message.Content
+	if userSetting.NotifyType == dto.NotifyTypeBark || userSetting.NotifyType == dto.NotifyTypeGotify {
+		content = notificationPlainText(content)
+	}
+	return dto.NewNotify(notifyType, message.Subject, content, nil)
+}
+
+// NotifyUserWithTemplate renders the template in the user's language and
+// sends it through the user's notification channel.
+func NotifyUserWithTemplate(userId int, userEmail string, userSetting dto.UserSetting, notifyType string, key string, vars map[string]string) error {
+	message := RenderNotification(key, userSetting.Language, vars)
+	return NotifyUser(userId, userEmail, userSetting, newTemplateNotify(notifyType, message, userSetting))
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/i18n"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func saveNotificationTemplateForTest(t *testing.T, key string, language string, subject string, content string) {
+	t.Helper()
+	t.Cleanup(func() {
+		model.DB.Exec("DELETE FROM notification_templates")
+	})
+	require.NoError(t, model.UpsertNotificationTemplate(&model.NotificationTemplate{
+		TemplateKey: key,
+		Language:    language,
+		Subject:     subject,
+		Content:     content,
+	}))
+}
+
+func TestBuiltinNotificationTemplatesRender(t *testing.T) {
+	for _, definition := range NotificationTemplateDefinitions {
+		for _, lang := range []string{i18n.LangZhCN, i18n.LangEn} {
+			builtin, ok := definition.Defaults[lang]
+			require.True(t, ok, "%s has no %s default", definition.Key, lang)
+			message, err := RenderNotificationTemplate(builtin.Subject, builtin.Content, definition.Sample)
+			require.NoError(t, err, "%s/%s", definition.Key, lang)
+			assert.NotEmpty(t, message.Subject)
+			assert.NotContains(t, message.Content, "{{")
+		}
+	}
+}
+
+func TestRenderNotificationTemplate(t *testing.T) {
+	tests := []struct {
+		name        string
+		subject     string
+		content     string
+		vars        map[string]string
+		wantSubject string
+		wantContent string
+		wantErr     bool
+	}{
+		{
+			name:        "interpolates variables",
+			subject:     "Code {{.code}}",
+			content:     "<p>{{.code}} valid for {{.valid_minutes}} minutes</p>",
+			vars:        map[string]string{"code": "123456", "valid_minutes": "10"},
+			wantSubject: "Code 123456",
+			wantContent: "<p>123456 valid for 10 minutes</p>",
+		},
+		{
+			name:        "missing variables render empty",
+			subject:     "Hi{{.nobody}}",
+			content:     "[{{.nobody}}]",
+			wantSubject: "Hi",
+			wantContent: "[]",
+		},
+		{
+			name:        "escapes values in content only",
+			subject:     "{{.from_group}}",
+			content:     "{{.from_group}}",
+			vars:        map[string]string{"from_group": "<b>vip</b>"},
+			wantSubject: "<b>vip</b>",
+			wantContent: "&lt;b&gt;vip&lt;/b&gt;",
+		},
+		{
+			name:        "supports conditionals",
+			subject:     "s",
+			content:     "a{{if .bonus}} +{{.bonus}}{{end}}",
+			vars:        map[string]string{"bonus": ""},
+			wantSubject: "s",
+			wantContent: "a",
+		},
+		{
+			name:    "rejects invalid syntax",
+			subject: "{{.code",
+			content: "x",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message, err := RenderNotificationTemplate(tt.subject, tt.content, tt.vars)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantSubject, message.Subject)
+			assert.Equal(t, tt.wantContent, message.Content)
+		})
+	}
+}
+
+func TestRenderNotificationAddsCommonVariables(t *testing.T) {
+	original := common.SystemName
+	common.SystemName = "TestAPI"
+	t.Cleanup(func() { common.SystemName = original })
+
+	message := RenderNotification(NotificationTemplateEmailVerification, i18n.LangEn, map[string]string{"code": "654321", "valid_minutes": "5"})
+	assert.Equal(t, "TestAPI email verification", message.Subject)
+	assert.Contains(t, message.Content, "<strong>654321</strong>")
+}
+
+func TestResolveNotificationTemplateFallback(t *testing.T) {
+	saveNotificationTemplateForTest(t, NotificationTemplateCheckinReminder, i18n.LangZhCN, "自定义提醒", "记得签到 {{.date}}")
+
+	tests := []struct {
+		name        string
+		lang        string
+		wantSubject string
+		wantCustom  bool
+	}{
+		{name: "custom template for language", lang: i18n.LangZhCN, wantSubject: "自定义提醒", wantCustom: true},
+		{name: "unset language uses default language", lang: "", wantSubject: "自定义提醒", wantCustom: true},
+		{name: "built-in template for language", lang: i18n.LangEn, wantSubject: "Check-in reminder"},
+		{name: "language without built-in falls back", lang: i18n.LangZhTW, wantSubject: "自定义提醒", wantCustom: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, custom, err := ResolveNotificationTemplate(NotificationTemplateCheckinReminder, tt.lang)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantSubject, raw.Subject)
+			assert.Equal(t, tt.wantCustom, custom)
+		})
+	}
+
+	_, _, err := ResolveNotificationTemplate("unknown", i18n.LangEn)
+	assert.Error(t, err)
+}
+
+func TestRenderNotificationBrokenCustomTemplateFallsBack(t *testing.T) {
+	saveNotificationTemplateForTest(t, NotificationTemplateGroupChange, i18n.LangEn, "Group", "{{.used_quota | missingfunc}}")
+
+	message := RenderNotification(NotificationTemplateGroupChange, i18n.LangEn, map[string]string{
+		"used_quota": "$1", "from_group": "default", "to_group": "vip",
+	})
+	assert.Equal(t, "Group changed", message.Subject)
+	assert.Contains(t, message.Content, "from default to vip")
+}
+
+func TestNewTemplateNotifyPlainTextChannels(t *testing.T) {
+	message := NotificationMessage{
+		Subject: "Low quota",
+		Content: "Remaining 1 &amp; 2<br/>Link: <a href=\"https://example.com\">https://example.com</a>",
+	}
+	tests := []struct {
+		notifyType  string
+		wantContent string
+	}{
+		{notifyType: dto.NotifyTypeEmail, wantContent: message.Content},
+		{notifyType: dto.NotifyTypeWebhook, wantContent: message.Content},
+		{notifyType: dto.NotifyTypeBark, wantContent: "Remaining 1 & 2\nLink: https://example.com"},
+		{notifyType: dto.NotifyTypeGotify, wantContent: "Remaining 1 & 2\nLink: https://example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.notifyType, func(t *testing.T) {
+			notify := newTemplateNotify(dto.NotifyTypeQuotaExceed, message, dto.UserSetting{NotifyType: tt.notifyType})
+			assert.Equal(t, "Low quota", notify.Title)
+			assert.Equal(t, tt.wantContent, notify.Content)
+			assert.Nil(t, notify.Values)
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+
+	"github.com/shopspring/decimal"
+)
+
+// EstimatePlaygroundQuota estimates the quota of a chat turn from the base
+// model price or ratio and the group ratio. Channel price overrides and tiered
+// billing are not applied, so the figure is a preview; the consume log holds
+// the quota actually charged.
+func EstimatePlaygroundQuota(modelName string, userGroup string, usingGroup string, promptTokens int, completionTokens int) int {
+	groupRatio, ok := ratio_setting.GetGroupGroupRatio(userGroup, usingGroup)
+	if !ok {
+		groupRatio = ratio_setting.GetGroupRatio(usingGroup)
+	}
+	if price, usePrice := ratio_setting.GetModelPrice(modelName, false); usePrice {
+		return int(decimal.NewFromFloat(price).
+			Mul(decimal.NewFromFloat(common.QuotaPerUnit)).
+			Mul(decimal.NewFromFloat(groupRatio)).
+			Round(0).IntPart())
+	}
+	modelRatio, _, _ := ratio_setting.GetModelRatio(modelName)
+	completionRatio := ratio_setting.GetCompletionRatio(modelName)
+	tokens := decimal.NewFromInt(int64(promptTokens)).
+		Add(decimal.NewFromInt(int64(completionTokens)).Mul(decimal.NewFromFloat(completionRatio)))
+	return int(tokens.
+		Mul(decimal.NewFromFloat(modelRatio)).
+		Mul(decimal.NewFromFloat(groupRatio)).
+		Round(0).IntPart())
+}
+
+// BuildPlaygroundMessages converts a stored playground session into an
+// OpenAI-format messages array, with the system prompt first.
+func BuildPlaygroundMessages(session *model.PlaygroundSession, history []*model.PlaygroundMessage) []dto.Message {
+	messages := make([]dto.Message, 0, len(history)+1)
+	if session.SystemPrompt != "" {
+		messages = append(messages, dto.Message{Role: "system", Content: session.SystemPrompt})
+	}
+	for _, message := range history {
+		messages = append(messages, dto.Message{Role: message.Role, Content: message.Content})
+	}
+	return messages
+}
+
+// ParsePlaygroundStream collects the assistant text and the usage chunk from
+// a captured OpenAI chat completions event stream. Usage is nil when the
+// stream did not report it.
+func ParsePlaygroundStream(body string) (string, *dto.Usage) {
+	var content strings.Builder
+	var usage *dto.Usage
+	for _, line := range strings.Split(body, "\n") {
+		data, ok := strings.CutPrefix(strings.TrimSpace(line), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "" || data == "[DONE]" {
+			continue
+		}
+		var chunk dto.ChatCompletionsStreamResponse
+		if err := common.UnmarshalJsonStr(data, &chunk); err != nil {
+			continue
+		}
+		for _, choice := range chunk.Choices {
+			content.WriteString(choice.Delta.GetContentString())
+		}
+		if chunk.Usage != nil && chunk.Usage.TotalTokens > 0 {
+			usage = chunk.Usage
+		}
+	}
+	return content.String(), usage
+}
+
+// CountPlaygroundPromptTokens estimates the prompt tokens of a messages
+// array, counting the usual 3 tokens of per-message framing plus 3 for the
+// reply priming.
+func CountPlaygroundPromptTokens(messages []dto.Message, modelName string) int {
+	tokens := 3
+	for _, message := range messages {
+		tokens += 3 + CountTextToken(message.StringContent(), modelName)
+	}
+	return tokens
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimatePlaygroundQuota(t *testing.T) {
+	oldModelRatio := ratio_setting.ModelRatio2JSONString()
+	oldModelPrice := ratio_setting.ModelPrice2JSONString()
+	oldCompletionRatio := ratio_setting.CompletionRatio2JSONString()
+	oldGroupRatio := ratio_setting.GroupRatio2JSONString()
+	oldGroupGroupRatio := ratio_setting.GroupGroupRatio2JSONString()
+	require.NoError(t, ratio_setting.UpdateModelRatioByJSONString(`{"pg-ratio-model": 2}`))
+	require.NoError(t, ratio_setting.UpdateModelPriceByJSONString(`{"pg-price-model": 0.01}`))
+	require.NoError(t, ratio_setting.UpdateCompletionRatioByJSONString(`{"pg-ratio-model": 4}`))
+	require.NoError(t, ratio_setting.UpdateGroupRatioByJSONString(`{"default": 1, "vip": 0.5}`))
+	require.NoError(t, ratio_setting.UpdateGroupGroupRatioByJSONString(`{"partner": {"vip": 0.25}}`))
+	t.Cleanup(func() {
+		_ = ratio_setting.UpdateModelRatioByJSONString(oldModelRatio)
+		_ = ratio_setting.UpdateModelPriceByJSONString(oldModelPrice)
+		_ = ratio_setting.UpdateCompletionRatioByJSONString(oldCompletionRatio)
+		_ = ratio_setting.UpdateGroupRatioByJSONString(oldGroupRatio)
+		_ = ratio_setting.UpdateGroupGroupRatioByJSONString(oldGroupGroupRatio)
+	})
+
+	tests := []struct {
+		name       string
+		model      string
+		userGroup  string
+		usingGroup string
+		prompt     int
+		completion int
+		want       int
+	}{
+		{name: "ratio model", model: "pg-ratio-model", userGroup: "default", usingGroup: "default", prompt: 100, completion: 50, want: 600},
+		{name: "group ratio", model: "pg-ratio-model", userGroup: "default", usingGroup: "vip", prompt: 100, completion: 50, want: 300},
+		{name: "user group special ratio", model: "pg-ratio-model", userGroup: "partner", usingGroup: "vip", prompt: 100, completion: 50, want: 150},
+		{name: "fixed price model", model: "pg-price-model", userGroup: "default", usingGroup: "default", prompt: 100, completion: 50, want: 5000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, EstimatePlaygroundQuota(tt.model, tt.userGroup, tt.usingGroup, tt.prompt, tt.completion))
+		})
+	}
+}
+
+func TestBuildPlaygroundMessages(t *testing.T) {
+	session := &model.PlaygroundSession{Model: "gpt-4o", SystemPrompt: "be brief"}
+	history := []*model.PlaygroundMessage{
+		{Role: "user", Content: "hi"},
+		{Role: "assistant", Content: "hello"},
+	}
+	assert.Equal(t, []dto.Message{
+		{Role: "system", Content: "be brief"},
+		{Role: "user", Content: "hi"},
+		{Role: "assistant", Content: "hello"},
+	}, BuildPlaygroundMessages(session, history))
+
+	session.SystemPrompt = ""
+	assert.Len(t, BuildPlaygroundMessages(session, history), 2)
+}
+
+func TestParsePlaygroundStream(t *testing.T) {
+	body := "data: {\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\",\"content\":\"Hel\"}}]}\n\n" +
+		": PING\n\n" +
+		"data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"lo\"}}]}\n\n" +
+		"data: {\"choices\":[],\"usage\":{\"prompt_tokens\":12,\"completion_tokens\":2,\"total_tokens\":14}}\n\n" +
+		"data: [DONE]\n\n"
+
+	content, usage := ParsePlaygroundStream(body)
+	assert.Equal(t, "Hello", content)
+	require.NotNil(t, usage)
+	assert.Equal(t, 12, usage.PromptTokens)
+	assert.Equal(t, 2, usage.CompletionTokens)
+
+	content, usage = ParsePlaygroundStream("data: {\"choices\":[{\"delta\":{\"content\":\"x\"}}]}\n\ndata: [DONE]\n\n")
+	assert.Equal(t, "x", content)
+	assert.Nil(t, usage)
+}
@@ -379,7 +379,7 @@ func PostAudioConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, u
 		Group:            relayInfo.UsingGroup,
 		Other:            other,
 	})
-	gopool.Go(func() {
+	common.GoPendingWrite(func() {
 		perfmetrics.RecordRelaySample(relayInfo, true, int64(usage.CompletionTokens))
 	})
 }
@@ -468,36 +468,34 @@ func checkAndSendQuotaNotify(relayInfo *relaycommon.RelayInfo, quota int, preCon
 		if relayInfo.UserQuota-consumeQuota < threshold {
 			quotaTooLow = true
 		}
-		if quotaTooLow {
-			prompt := "您的额度即将用尽"
-			topUpLink := PaymentReturnURL("/console/topup")
-
-			// 根据通知方式生成不同的内容格式
-			var content string
-			var values []interface{}
-
-			notifyType := userSetting.NotifyType
-			if notifyType == "" {
-				notifyType = dto.NotifyTypeEmail
-			}
-
-			if notifyType == dto.NotifyTypeBark {
-				// Bark推送使用简短文本，不支持HTML
-				content = "{{value}}，剩余额度：{{value}}，请及时充值"
-				values = []interface{}{prompt, logger.FormatQuota(relayInfo.UserQuota)}
-			} else if notifyType == dto.NotifyTypeGotify {
-				content = "{{value}}，当前剩余额度为 {{value}}，请及时充值。"
-				values = []interface{}{prompt, logger.FormatQuota(relayInfo.UserQuota)}
-			} else {
-				// 默认内容格式，适用于Email和Webhook（支持HTML）
-				content = "{{value}}，当前剩余额度为 {{value}}，为了不影响您的使用，请及时充值。<br/>充值链接：<a href='{{value}}'>{{value}}</a>"
-				values = []interface{}{prompt, logger.FormatQuota(relayInfo.UserQuota), topUpLink, topUpLink}
-			}
-
-			err := NotifyUser(relayInfo.UserId, relayInfo.UserEmail, relayInfo.UserSetting, dto.NewNotify(dto.NotifyTypeQuotaExceed, prompt, content, values))
-			if err != nil {
-				common.SysError(fmt.Sprintf("failed to send quota notify to user %d: %s", relayInfo.UserId, err.Error()))
+		// 预警只在额度跌破阈值时发送一次，额度回升到阈值以上（充值、兑换等）后重新启用
+		if !quotaTooLow {
+			if userSetting.QuotaWarningSent {
+				if err := model.SetUserQuotaWarningSent(relayInfo.UserId, false); err != nil {
+					common.SysError(fmt.Sprintf("failed to reset quota warning for user %d: %s", relayInfo.UserId, err.Error()))
+				}
 			}
+			return
+		}
+		if userSetting.QuotaWarningSent {
+			return
+		}
+		// 先标记已发送，发送失败也不在后续请求中重复推送
+		if err := model.SetUserQuotaWarningSent(relayInfo.UserId, true); err != nil {
+			common.SysError(fmt.Sprintf("failed to mark quota warning for user %d: %s", relayInfo.UserId, err.Error()))
+			return
+		}
+		PublishWebhookEvent(WebhookEventUserQuotaLow, map[string]any{
+			"user_id":         relayInfo.UserId,
+			"remaining_quota": relayInfo.UserQuota - consumeQuota,
+			"threshold":       threshold,
+		})
+		err := NotifyUserWithTemplate(relayInfo.UserId, relayInfo.UserEmail, relayInfo.UserSetting, dto.NotifyTypeQuotaExceed, NotificationTemplateQuotaLow, map[string]string{
+			"remaining_quota": logger.FormatQuota(relayInfo.UserQuota),
+			"topup_link":      PaymentReturnURL("/console/topup"),
+		})
+		if err != nil {
+			common.SysError(fmt.Sprintf("failed to send quota notify to user %d: %s", relayInfo.UserId, err.Error()))
 		}
 	})
 }
@@ -523,28 +521,11 @@ func checkAndSendSubscriptionQuotaNotify(relayInfo *relaycommon.RelayInfo) {
 			return
 		}
 
-		prompt := "您的订阅额度即将用尽"
-		topUpLink := PaymentReturnURL("/console/topup")
-
-		var content string
-		var values []interface{}
-		notifyType := userSetting.NotifyType
-		if notifyType == "" {
-			notifyType = dto.NotifyTypeEmail
-		}
-
-		if notifyType == dto.NotifyTypeBark {
-			content = "{{value}}，剩余额度：{{value}}，请及时充值"
-			values = []interface{}{prompt, logger.FormatQuota(int(remaining))}
-		} else if notifyType == dto.NotifyTypeGotify {
-			content = "{{value}}，当前剩余额度为 {{value}}，请及时充值。"
-			values = []interface{}{prompt, logger.FormatQuota(int(remaining))}
-		} else {
-			content = "{{value}}，当前剩余额度为 {{value}}，为了不影响您的使用，请及时充值。<br/>充值链接：<a href='{{value}}'>{{value}}</a>"
-			values = []interface{}{prompt, logger.FormatQuota(int(remaining)), topUpLink, topUpLink}
-		}
-
-		if err := NotifyUser(relayInfo.UserId, relayInfo.UserEmail, relayInfo.UserSetting, dto.NewNotify(dto.NotifyTypeQuotaExceed, prompt, content, values)); err != nil {
+		err := NotifyUserWithTemplate(relayInfo.UserId, relayInfo.UserEmail, relayInfo.UserSetting, dto.NotifyTypeQuotaExceed, NotificationTemplateSubscriptionQuotaLow, map[string]string{
+			"remaining_quota": logger.FormatQuota(int(remaining)),
+			"topup_link":      PaymentReturnURL("/console/topup"),
+		})
+		if err != nil {
 			common.SysError(fmt.Sprintf("failed to send subscription quota notify to user %d: %s", relayInfo.UserId, err.Error()))
 		}
 	})
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+const (
+	quotaGrantTickInterval = 1 * time.Minute
+	quotaGrantBatchSize    = 300
+)
+
+var (
+	quotaGrantTaskOnce    sync.Once
+	quotaGrantTaskRunning atomic.Bool
+)
+
+// StartQuotaGrantTask runs on every node: each node refreshes its own switch
+// that decides whether quota consumption drains expiring grants, while only
+// the background leader claws back expired grants.
+func StartQuotaGrantTask() {
+	quotaGrantTaskOnce.Do(func() {
+		gopool.Go(func() {
+			ticker := time.NewTicker(quotaGrantTickInterval)
+			defer ticker.Stop()
+
+			runQuotaGrantTaskOnce()
+			for range ticker.C {
+				runQuotaGrantTaskOnce()
+			}
+		})
+	})
+}
+
+func runQuotaGrantTaskOnce() {
+	if !quotaGrantTaskRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer quotaGrantTaskRunning.Store(false)
+
+	ctx := context.Background()
+	if IsBackgroundLeader() {
+		for {
+			n, err := model.ExpireDueQuotaGrants(quotaGrantBatchSize)
+			if err != nil {
+				logger.LogWarn(ctx, fmt.Sprintf("quota grant expire task failed: %v", err))
+				break
+			}
+			if n < quotaGrantBatchSize {
+				break
+			}
+		}
+	}
+	if err := model.RefreshQuotaGrantsActive(); err != nil {
+		logger.LogWarn(ctx, fmt.Sprintf("quota grant refresh failed: %v", err))
+	}
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+)
+
+const redemptionExpireBatchSize = 500
+
+type redemptionExpirePayload struct {
+	Now int64 `json:"now"`
+}
+
+type redemptionExpireResult struct {
+	Now     int64 `json:"now"`
+	Expired int64 `json:"expired"`
+}
+
+// redemptionExpireHandler marks enabled redemption codes whose expired_time has
+// passed as expired, so they show up as invalid and can be purged in bulk.
+type redemptionExpireHandler struct{}
+
+func init() {
+	RegisterSystemTaskHandler(redemptionExpireHandler{})
+}
+
+func (redemptionExpireHandler) Type() string { return model.SystemTaskTypeRedemptionExpire }
+
+func (redemptionExpireHandler) Enabled() bool { return true }
+
+func (redemptionExpireHandler) Interval() time.Duration { return 1 * time.Hour }
+
+func (redemptionExpireHandler) NewPayload() any {
+	return redemptionExpirePayload{Now: common.GetTimestamp()}
+}
+
+func (redemptionExpireHandler) Run(ctx context.Context, task *model.SystemTask, runnerID string) {
+	payload := redemptionExpirePayload{}
+	if err := task.DecodePayload(&payload); err != nil {
+		failSystemTask(task, runnerID, err)
+		return
+	}
+	result := &redemptionExpireResult{Now: payload.Now}
+	for {
+		if err := ctx.Err(); err != nil {
+			failSystemTask(task, runnerID, err)
+			return
+		}
+		expired, err := model.ExpireRedemptions(payload.Now, redemptionExpireBatchSize)
+		if err != nil {
+			failSystemTask(task, runnerID, err)
+			return
+		}
+		result.Expired += expired
+		if expired < redemptionExpireBatchSize {
+			break
+		}
+	}
+	if err := model.FinishSystemTask(task.TaskID, runnerID, model.SystemTaskStatusSucceeded, result, ""); err != nil {
+		logSystemTaskLockError(ctx, task, err)
+	}
+}
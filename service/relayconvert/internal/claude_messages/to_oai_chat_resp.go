@@ -19,6 +19,9 @@ type ClaudeResponseInfo struct {
 	ResponseText strings.Builder
 	Usage        *dto.Usage
 	Done         bool
+	// toolCallIndexes maps Claude content block indexes to OpenAI tool call
+	// indexes of the stream
+	toolCallIndexes map[int]int
 }
 
 func StopReasonClaudeToOpenAI(reason string) string {
@@ -395,6 +398,27 @@ func FormatClaudeResponseInfo(claudeResponse *dto.ClaudeResponse, oaiResponse *d
 		oaiResponse.Id = claudeInfo.ResponseId
 		oaiResponse.Created = claudeInfo.Created
 		oaiResponse.Model = claudeInfo.Model
+		// OpenAI clients accumulate tool call deltas by index, which counts
+		// tool calls only, while Claude numbers every content block including
+		// text and thinking
+		for i := range oaiResponse.Choices {
+			for j := range oaiResponse.Choices[i].Delta.ToolCalls {
+				toolCall := &oaiResponse.Choices[i].Delta.ToolCalls[j]
+				blockIdx := 0
+				if toolCall.Index != nil {
+					blockIdx = *toolCall.Index
+				}
+				if claudeInfo.toolCallIndexes == nil {
+					claudeInfo.toolCallIndexes = make(map[int]int)
+				}
+				idx, ok := claudeInfo.toolCallIndexes[blockIdx]
+				if !ok {
+					idx = len(claudeInfo.toolCallIndexes)
+					claudeInfo.toolCallIndexes[blockIdx] = idx
+				}
+				toolCall.SetIndex(idx)
+			}
+		}
 	}
 	return true
 }
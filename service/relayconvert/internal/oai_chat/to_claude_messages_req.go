@@ -31,25 +31,24 @@ func OpenAIChatRequestToClaudeMessages(c *gin.Context, textRequest dto.GeneralOp
 	claudeTools := make([]any, 0, len(textRequest.Tools))
 
 	for _, tool := range textRequest.Tools {
+		claudeTool := dto.Tool{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+		}
+		// Claude requires an object schema even for functions without
+		// parameters, and rejects a null "required"
+		claudeTool.InputSchema = map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{},
+		}
 		if params, ok := tool.Function.Parameters.(map[string]any); ok {
-			claudeTool := dto.Tool{
-				Name:        tool.Function.Name,
-				Description: tool.Function.Description,
-			}
-			claudeTool.InputSchema = make(map[string]interface{})
-			if params["type"] != nil {
-				claudeTool.InputSchema["type"] = params["type"].(string)
-			}
-			claudeTool.InputSchema["properties"] = params["properties"]
-			claudeTool.InputSchema["required"] = params["required"]
 			for key, value := range params {
-				if key == "type" || key == "properties" || key == "required" {
-					continue
+				if value != nil {
+					claudeTool.InputSchema[key] = value
 				}
-				claudeTool.InputSchema[key] = value
 			}
-			claudeTools = append(claudeTools, &claudeTool)
 		}
+		claudeTools = append(claudeTools, &claudeTool)
 	}
 
 	if textRequest.WebSearchOptions != nil {
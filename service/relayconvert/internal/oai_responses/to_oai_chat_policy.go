@@ -0,0 +1,22 @@
+package oairesponses
+
+import (
+	"github.com/QuantumNous/new-api/service/relayconvert/internal/matcher"
+	"github.com/QuantumNous/new-api/setting/model_setting"
+)
+
+func ShouldResponsesUseChatCompletionsPolicy(policy model_setting.ResponsesToChatCompletionsPolicy, channelID int, channelType int, model string) bool {
+	if !policy.IsChannelEnabled(channelID, channelType) {
+		return false
+	}
+	return matcher.MatchAnyRegex(policy.ModelPatterns, model)
+}
+
+func ShouldResponsesUseChatCompletionsGlobal(channelID int, channelType int, model string) bool {
+	return ShouldResponsesUseChatCompletionsPolicy(
+		model_setting.GetGlobalSettings().ResponsesToChatCompletionsPolicy,
+		channelID,
+		channelType,
+		model,
+	)
+}
@@ -0,0 +1,59 @@
+package relayconvert
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/setting/model_setting"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldResponsesUseChatCompletionsPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      model_setting.ResponsesToChatCompletionsPolicy
+		channelID   int
+		channelType int
+		model       string
+		want        bool
+	}{
+		{
+			name:   "disabled",
+			policy: model_setting.ResponsesToChatCompletionsPolicy{AllChannels: true, ModelPatterns: []string{".*"}},
+			model:  "deepseek-chat",
+			want:   false,
+		},
+		{
+			name:   "all channels with matching model",
+			policy: model_setting.ResponsesToChatCompletionsPolicy{Enabled: true, AllChannels: true, ModelPatterns: []string{"^deepseek-"}},
+			model:  "deepseek-chat",
+			want:   true,
+		},
+		{
+			name:   "model not matched",
+			policy: model_setting.ResponsesToChatCompletionsPolicy{Enabled: true, AllChannels: true, ModelPatterns: []string{"^deepseek-"}},
+			model:  "gpt-5",
+			want:   false,
+		},
+		{
+			name:      "channel id listed",
+			policy:    model_setting.ResponsesToChatCompletionsPolicy{Enabled: true, ChannelIDs: []int{7}, ModelPatterns: []string{".*"}},
+			channelID: 7,
+			model:     "qwen-max",
+			want:      true,
+		},
+		{
+			name:        "channel not listed",
+			policy:      model_setting.ResponsesToChatCompletionsPolicy{Enabled: true, ChannelIDs: []int{7}, ModelPatterns: []string{".*"}},
+			channelID:   8,
+			channelType: 1,
+			model:       "qwen-max",
+			want:        false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ShouldResponsesUseChatCompletionsPolicy(tt.policy, tt.channelID, tt.channelType, tt.model))
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/pkg/cachex"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samber/hot"
+)
+
+// ResponseCacheEntry is a cached client-facing response body together with
+// the usage it was billed for, so a hit can be billed without the upstream.
+type ResponseCacheEntry struct {
+	Body  string    `json:"body"`
+	Usage dto.Usage `json:"usage"`
+}
+
+// newResponseCache uses Redis when enabled so every node shares hits, and an
+// LRU bounded by capacity otherwise.
+func newResponseCache(namespace string, capacity int, ttl time.Duration) *cachex.HybridCache[ResponseCacheEntry] {
+	if capacity <= 0 {
+		capacity = 10000
+	}
+	ttl = max(ttl, time.Second)
+	return cachex.NewHybridCache[ResponseCacheEntry](cachex.HybridCacheConfig[ResponseCacheEntry]{
+		Namespace: cachex.Namespace(namespace),
+		Redis:     common.RDB,
+		RedisEnabled: func() bool {
+			return common.RedisEnabled && common.RDB != nil
+		},
+		RedisCodec: cachex.JSONCodec[ResponseCacheEntry]{},
+		Memory: func() *hot.HotCache[string, ResponseCacheEntry] {
+			return hot.NewHotCache[string, ResponseCacheEntry](hot.LRU, capacity).
+				WithTTL(ttl).
+				WithJanitor().
+				Build()
+		},
+	})
+}
+
+func hashResponseCacheKey(request any) (string, error) {
+	data, err := common.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func getResponseCacheEntry(cache *cachex.HybridCache[ResponseCacheEntry], key string) (*ResponseCacheEntry, bool) {
+	entry, found, err := cache.Get(key)
+	if err != nil {
+		common.SysLog("failed to read response cache: " + err.Error())
+		return nil, false
+	}
+	if !found {
+		return nil, false
+	}
+	return &entry, true
+}
+
+func setResponseCacheEntry(cache *cachex.HybridCache[ResponseCacheEntry], key string, entry ResponseCacheEntry, ttlSeconds int, maxEntryKB int) {
+	if maxEntryKB > 0 && len(entry.Body) > maxEntryKB*1024 {
+		return
+	}
+	if err := cache.SetWithTTL(key, entry, time.Duration(ttlSeconds)*time.Second); err != nil {
+		common.SysLog("failed to write response cache: " + err.Error())
+	}
+}
+
+// ResponseCaptureWriter copies the response written to the client, giving up
+// once it exceeds limit so oversized responses are never cached.
+type ResponseCaptureWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+func NewResponseCaptureWriter(w gin.ResponseWriter, limit int) *ResponseCaptureWriter {
+	return &ResponseCaptureWriter{ResponseWriter: w, limit: limit}
+}
+
+func (w *ResponseCaptureWriter) Write(b []byte) (int, error) {
+	if !w.overflow {
+		if w.limit > 0 && w.body.Len()+len(b) > w.limit {
+			w.overflow = true
+			w.body.Reset()
+		} else {
+			w.body.Write(b)
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *ResponseCaptureWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Body returns the captured response, or false when it was too large.
+func (w *ResponseCaptureWriter) Body() (string, bool) {
+	if w.overflow {
+		return "", false
+	}
+	return w.body.String(), true
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/go-pdf/fpdf"
+)
+
+const statementTimeLayout = "2006-01-02 15:04:05"
+
+// WriteStatementCSV writes the statement as a summary block followed by the
+// per-model usage table. Quota columns are raw quota units next to their
+// display amount so resellers can re-price them.
+func WriteStatementCSV(w io.Writer, statement *model.UserStatement) error {
+	writer := csv.NewWriter(w)
+	rows := [][]string{
+		{"period", statement.Period},
+		{"user_id", strconv.Itoa(statement.UserId)},
+		{"username", statement.Username},
+		{"start_time", time.Unix(statement.StartTime, 0).Format(statementTimeLayout)},
+		{"end_time", time.Unix(statement.EndTime, 0).Format(statementTimeLayout)},
+		{"requests", strconv.FormatInt(statement.Requests, 10)},
+		{"prompt_tokens", strconv.FormatInt(statement.PromptTokens, 10)},
+		{"completion_tokens", strconv.FormatInt(statement.CompletionTokens, 10)},
+		{"quota_consumed", strconv.FormatInt(statement.QuotaConsumed, 10), logger.FormatQuota(int(statement.QuotaConsumed))},
+		{"quota_refunded", strconv.FormatInt(statement.QuotaRefunded, 10), logger.FormatQuota(int(statement.QuotaRefunded))},
+		{"topup_count", strconv.FormatInt(statement.TopUpCount, 10)},
+		{"topup_amount", strconv.FormatInt(statement.TopUpAmount, 10)},
+		{"topup_money", strconv.FormatFloat(statement.TopUpMoney, 'f', 2, 64)},
+		{"checkin_count", strconv.FormatInt(statement.CheckinCount, 10)},
+		{"checkin_quota", strconv.FormatInt(statement.CheckinQuota, 10), logger.FormatQuota(int(statement.CheckinQuota))},
+		{},
+		{"model_name", "requests", "prompt_tokens", "completion_tokens", "quota", "amount"},
+	}
+	for _, usage := range statement.Models {
+		rows = append(rows, []string{
+			usage.ModelName,
+			strconv.FormatInt(usage.Requests, 10),
+			strconv.FormatInt(usage.PromptTokens, 10),
+			strconv.FormatInt(usage.CompletionTokens, 10),
+			strconv.FormatInt(usage.Quota, 10),
+			logger.FormatQuota(int(usage.Quota)),
+		})
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+// WriteStatementPDF renders the statement as a single A4 document. It uses
+// the core Helvetica font so no font files need to ship with the binary;
+// text outside cp1252 (e.g. CJK usernames) is replaced by the translator.
+func WriteStatementPDF(w io.Writer, statement *model.UserStatement) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle(fmt.Sprintf("%s statement %s", common.SystemName, statement.Period), true)
+	pdf.SetCreator(common.SystemName, true)
+	pdf.SetCreationDate(time.Unix(statement.CreatedAt, 0))
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	// the fullwidth dollar sign of the USD display has no cp1252 glyph
+	amount := func(quota int64) string {
+		return tr(strings.ReplaceAll(logger.FormatQuota(int(quota)), "＄", "$"))
+	}
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(0, 10, tr(fmt.Sprintf("%s - Usage Statement %s", common.SystemName, statement.Period)), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(0, 6, tr(fmt.Sprintf("User: %s (#%d)", statement.Username, statement.UserId)), "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 6, fmt.Sprintf("Period: %s - %s",
+		time.Unix(statement.StartTime, 0).Format(statementTimeLayout),
+		time.Unix(statement.EndTime, 0).Format(statementTimeLayout)), "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 6, "Generated: "+time.Unix(statement.CreatedAt, 0).Format(statementTimeLayout), "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	pdf.SetFont("Helvetica", "B", 12)
+	pdf.CellFormat(0, 8, "Summary", "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	summary := [][2]string{
+		{"Requests", strconv.FormatInt(statement.Requests, 10)},
+		{"Prompt tokens", strconv.FormatInt(statement.PromptTokens, 10)},
+		{"Completion tokens", strconv.FormatInt(statement.CompletionTokens, 10)},
+		{"Quota consumed", amount(statement.QuotaConsumed)},
+		{"Quota refunded", amount(statement.QuotaRefunded)},
+		{"Top-ups", fmt.Sprintf("%d (%.2f paid)", statement.TopUpCount, statement.TopUpMoney)},
+		{"Check-in credits", fmt.Sprintf("%d (%s)", statement.CheckinCount, amount(statement.CheckinQuota))},
+	}
+	for _, row := range summary {
+		pdf.CellFormat(60, 6, row[0], "B", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 6, row[1], "B", 1, "R", false, 0, "")
+	}
+	pdf.Ln(4)
+
+	pdf.SetFont("Helvetica", "B", 12)
+	pdf.CellFormat(0, 8, "Usage by model", "", 1, "L", false, 0, "")
+	widths := []float64{62, 22, 30, 30, 46}
+	pdf.SetFont("Helvetica", "B", 9)
+	pdf.SetFillColor(235, 235, 235)
+	for i, title := range []string{"Model", "Requests", "Prompt", "Completion", "Amount"} {
+		align := "R"
+		if i == 0 {
+			align = "L"
+		}
+		pdf.CellFormat(widths[i], 7, title, "1", 0, align, true, 0, "")
+	}
+	pdf.Ln(-1)
+	pdf.SetFont("Helvetica", "", 9)
+	for _, usage := range statement.Models {
+		pdf.CellFormat(widths[0], 6, tr(usage.ModelName), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[1], 6, strconv.FormatInt(usage.Requests, 10), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[2], 6, strconv.FormatInt(usage.PromptTokens, 10), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[3], 6, strconv.FormatInt(usage.CompletionTokens, 10), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[4], 6, amount(usage.Quota), "1", 1, "R", false, 0, "")
+	}
+	if len(statement.Models) == 0 {
+		pdf.CellFormat(0, 6, "No usage in this period", "1", 1, "C", false, 0, "")
+	}
+	return pdf.Output(w)
+}
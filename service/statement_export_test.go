@@ -0,0 +1,39 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteStatement(t *testing.T) {
+	statement := &model.UserStatement{
+		UserId:        1,
+		Username:      "用户",
+		Period:        "2026-03",
+		Requests:      2,
+		QuotaConsumed: 1000,
+		Models: model.StatementModelUsages{
+			{ModelName: "gpt-4o", Requests: 2, PromptTokens: 10, CompletionTokens: 5, Quota: 1000},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteStatementCSV(&buf, statement))
+	reader := csv.NewReader(&buf)
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"period", "2026-03"}, rows[0])
+	assert.Equal(t, "gpt-4o", rows[len(rows)-1][0])
+	assert.Equal(t, "1000", rows[len(rows)-1][4])
+
+	buf.Reset()
+	require.NoError(t, WriteStatementPDF(&buf, statement))
+	assert.True(t, bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")))
+}
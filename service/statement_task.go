@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+const statementTickInterval = 1 * time.Hour
+
+var (
+	statementTaskOnce    sync.Once
+	statementTaskRunning atomic.Bool
+)
+
+// StartStatementTask generates the previous month's statements on the
+// background leader. Users who already have a statement for that month are
+// skipped, so the hourly tick only does work right after a month closes or
+// when new users show up in late-arriving logs.
+func StartStatementTask() {
+	statementTaskOnce.Do(func() {
+		gopool.Go(func() {
+			ticker := time.NewTicker(statementTickInterval)
+			defer ticker.Stop()
+
+			runStatementTaskOnce()
+			for range ticker.C {
+				runStatementTaskOnce()
+			}
+		})
+	})
+}
+
+func runStatementTaskOnce() {
+	if !operation_setting.GetStatementSetting().AutoGenerateEnabled || !IsBackgroundLeader() {
+		return
+	}
+	if !statementTaskRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer statementTaskRunning.Store(false)
+
+	now := time.Now()
+	period := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local).AddDate(0, -1, 0).Format(model.StatementPeriodLayout)
+	n, err := model.GenerateMissingStatements(period)
+	if err != nil {
+		logger.LogWarn(context.Background(), fmt.Sprintf("statement task failed for %s: %v", period, err))
+	}
+	if n > 0 {
+		logger.LogInfo(context.Background(), fmt.Sprintf("generated %d statements for %s", n, period))
+	}
+}
@@ -45,6 +45,9 @@ func StartSubscriptionQuotaResetTask() {
 }
 
 func runSubscriptionQuotaResetOnce() {
+	if !IsBackgroundLeader() {
+		return
+	}
 	if !subscriptionResetRunning.CompareAndSwap(false, true) {
 		return
 	}
@@ -32,6 +32,7 @@ type SystemInstanceInfo struct {
 
 type SystemInstanceRoleInfo struct {
 	IsMaster bool `json:"is_master"`
+	IsLeader bool `json:"is_leader"`
 }
 
 type SystemInstanceRuntimeInfo struct {
@@ -95,6 +96,7 @@ func ReportCurrentSystemInstance() error {
 		Node:          identity,
 		Role: SystemInstanceRoleInfo{
 			IsMaster: common.IsMasterNode,
+			IsLeader: IsBackgroundLeader(),
 		},
 		Runtime: SystemInstanceRuntimeInfo{
 			Version:   common.Version,
@@ -146,7 +146,9 @@ func StartSystemTaskRunner() {
 						logger.LogWarn(context.Background(), fmt.Sprintf("system task stale lock cleanup failed: %v", err))
 					}
 				}
-				if now.Sub(lastScheduler) >= systemTaskSchedulerInterval {
+				// Only the elected leader creates scheduled rows; every master
+				// still claims pending rows so on-demand tasks are not delayed.
+				if IsBackgroundLeader() && now.Sub(lastScheduler) >= systemTaskSchedulerInterval {
 					lastScheduler = now
 					runSystemTaskScheduler()
 				}
@@ -16,7 +16,6 @@ import (
 	"github.com/QuantumNous/new-api/setting/operation_setting"
 	"github.com/QuantumNous/new-api/types"
 
-	"github.com/bytedance/gopkg/util/gopool"
 	"github.com/gin-gonic/gin"
 	"github.com/shopspring/decimal"
 )
@@ -372,6 +371,20 @@ func PostTextConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, us
 		}
 	}
 
+	var embeddingCacheRatio float64
+	if relayInfo.EmbeddingCacheHit {
+		embeddingCacheRatio = operation_setting.GetEmbeddingCacheSetting().HitPriceRatio
+		summary.Quota = int(decimal.NewFromInt(int64(summary.Quota)).Mul(decimal.NewFromFloat(embeddingCacheRatio)).Round(0).IntPart())
+		extraContent = append(extraContent, fmt.Sprintf("嵌入缓存命中，按原价 %s 倍计费", decimal.NewFromFloat(embeddingCacheRatio).String()))
+	}
+	var chatCacheRatio float64
+	if relayInfo.ChatCacheHit {
+		chatCacheRatio = operation_setting.GetChatCacheSetting().HitPriceRatio
+		summary.Quota = int(decimal.NewFromInt(int64(summary.Quota)).Mul(decimal.NewFromFloat(chatCacheRatio)).Round(0).IntPart())
+		extraContent = append(extraContent, fmt.Sprintf("对话缓存命中，按原价 %s 倍计费", decimal.NewFromFloat(chatCacheRatio).String()))
+	}
+	responseCacheHit := relayInfo.EmbeddingCacheHit || relayInfo.ChatCacheHit
+
 	if summary.WebSearchCallCount > 0 {
 		extraContent = append(extraContent, fmt.Sprintf("Web Search 调用 %d 次，调用花费 %s", summary.WebSearchCallCount, decimal.NewFromFloat(summary.WebSearchPrice).Mul(decimal.NewFromInt(int64(summary.WebSearchCallCount))).Div(decimal.NewFromInt(1000)).Mul(decimal.NewFromFloat(summary.GroupRatio)).Mul(decimal.NewFromFloat(common.QuotaPerUnit)).String()))
 	}
@@ -393,7 +406,9 @@ func PostTextConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, us
 		logger.LogError(ctx, fmt.Sprintf("total tokens is 0, cannot consume quota, userId %d, channelId %d, tokenId %d, model %s， pre-consumed quota %d", relayInfo.UserId, relayInfo.ChannelId, relayInfo.TokenId, summary.ModelName, relayInfo.FinalPreConsumedQuota))
 	} else {
 		model.UpdateUserUsedQuotaAndRequestCount(relayInfo.UserId, summary.Quota)
-		model.UpdateChannelUsedQuota(relayInfo.ChannelId, summary.Quota)
+		if !responseCacheHit {
+			model.UpdateChannelUsedQuota(relayInfo.ChannelId, summary.Quota)
+		}
 	}
 
 	if err := SettleBilling(ctx, relayInfo, summary.Quota); err != nil {
@@ -485,6 +500,14 @@ func PostTextConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, us
 	if tieredBillingApplied {
 		InjectTieredBillingInfo(other, relayInfo, tieredResult)
 	}
+	if relayInfo.EmbeddingCacheHit {
+		other["embedding_cache_hit"] = true
+		other["embedding_cache_price_ratio"] = embeddingCacheRatio
+	}
+	if relayInfo.ChatCacheHit {
+		other["chat_cache_hit"] = true
+		other["chat_cache_price_ratio"] = chatCacheRatio
+	}
 
 	attachQuotaSaturation(ctx, relayInfo, other)
 
@@ -502,7 +525,10 @@ func PostTextConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, us
 		Group:            relayInfo.UsingGroup,
 		Other:            other,
 	})
-	gopool.Go(func() {
+	if responseCacheHit {
+		return
+	}
+	common.GoPendingWrite(func() {
 		perfmetrics.RecordRelaySample(relayInfo, true, int64(summary.CompletionTokens))
 	})
 }
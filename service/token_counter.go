@@ -14,6 +14,7 @@ import (
 	"github.com/QuantumNous/new-api/logger"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
 	constant2 "github.com/QuantumNous/new-api/relay/constant"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
 	"github.com/QuantumNous/new-api/types"
 
 	"github.com/gin-gonic/gin"
@@ -177,8 +178,10 @@ func getImageToken(c *gin.Context, fileMeta *types.FileMeta, model string, strea
 }
 
 func EstimateRequestToken(c *gin.Context, meta *types.TokenCountMeta, info *relaycommon.RelayInfo) (int, error) {
-	// 是否统计token
-	if !constant.CountToken {
+	isTranscription := info.RelayMode == constant2.RelayModeAudioTranscription || info.RelayMode == constant2.RelayModeAudioTranslation
+	_, hasSecondPrice := ratio_setting.GetAudioSecondPrice(info.OriginModelName)
+	// 是否统计token，按秒计价的语音转写仍需解析音频时长
+	if !constant.CountToken && !(isTranscription && hasSecondPrice) {
 		return 0, nil
 	}
 
@@ -189,7 +192,7 @@ func EstimateRequestToken(c *gin.Context, meta *types.TokenCountMeta, info *rela
 	if info.RelayFormat == types.RelayFormatOpenAIRealtime {
 		return 0, nil
 	}
-	if info.RelayMode == constant2.RelayModeAudioTranscription || info.RelayMode == constant2.RelayModeAudioTranslation {
+	if isTranscription {
 		multiForm, err := common.ParseMultipartFormReusable(c)
 		if err != nil {
 			return 0, fmt.Errorf("error parsing multipart form: %v", err)
@@ -213,6 +216,7 @@ func EstimateRequestToken(c *gin.Context, meta *types.TokenCountMeta, info *rela
 			if duration < 0 {
 				duration = 0
 			}
+			meta.AudioSeconds += math.Ceil(duration)
 			// 一分钟 1000 token，与 $price / minute 对齐。
 			totalAudioToken += common.QuotaRound(math.Ceil(duration) / 60.0 * 1000)
 		}
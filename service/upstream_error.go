@@ -0,0 +1,143 @@
+package service
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// upstreamErrorClass is a stable OpenAI-style error type and code with the
+// generic message used when upstream messages are hidden.
+type upstreamErrorClass struct {
+	Type    string
+	Code    string
+	Message string
+}
+
+var (
+	upstreamErrorContextLength = upstreamErrorClass{"invalid_request_error", "context_length_exceeded", "the request exceeds the context length of the model"}
+	upstreamErrorContentPolicy = upstreamErrorClass{"invalid_request_error", "content_policy_violation", "the request was rejected by the upstream content policy"}
+	upstreamErrorInvalid       = upstreamErrorClass{"invalid_request_error", "invalid_request", "the request was rejected by the upstream service"}
+	upstreamErrorModelNotFound = upstreamErrorClass{"invalid_request_error", "model_not_found", "the model is not available on the upstream service"}
+	upstreamErrorTooLarge      = upstreamErrorClass{"invalid_request_error", "request_too_large", "the request is too large for the upstream service"}
+	upstreamErrorRateLimit     = upstreamErrorClass{"rate_limit_error", "rate_limit_exceeded", "the upstream service is rate limited, please retry later"}
+	upstreamErrorAuth          = upstreamErrorClass{"server_error", "upstream_auth_failed", "the upstream service rejected the channel credentials"}
+	upstreamErrorTimeout       = upstreamErrorClass{"server_error", "upstream_timeout", "the upstream service timed out"}
+	upstreamErrorServer        = upstreamErrorClass{"server_error", "upstream_error", "the upstream service returned an error"}
+)
+
+var (
+	contextLengthMarkers = []string{"context_length_exceeded", "context length", "context window", "prompt is too long", "too many tokens", "input is too long"}
+	contentPolicyMarkers = []string{"content_policy", "content policy", "content management policy", "content_filter", "safety system"}
+)
+
+// classifyUpstreamError maps an upstream status code and message to a
+// stable error class. Well-known message markers take precedence over the
+// status code since providers disagree on the status for these cases.
+func classifyUpstreamError(statusCode int, message string) upstreamErrorClass {
+	lower := strings.ToLower(message)
+	switch {
+	case containsAny(lower, contextLengthMarkers):
+		return upstreamErrorContextLength
+	case containsAny(lower, contentPolicyMarkers):
+		return upstreamErrorContentPolicy
+	}
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return upstreamErrorAuth
+	case statusCode == http.StatusNotFound:
+		return upstreamErrorModelNotFound
+	case statusCode == http.StatusRequestEntityTooLarge:
+		return upstreamErrorTooLarge
+	case statusCode == http.StatusTooManyRequests:
+		return upstreamErrorRateLimit
+	case statusCode == http.StatusRequestTimeout || statusCode == http.StatusGatewayTimeout:
+		return upstreamErrorTimeout
+	case statusCode >= 400 && statusCode < 500:
+		return upstreamErrorInvalid
+	default:
+		return upstreamErrorServer
+	}
+}
+
+func containsAny(s string, markers []string) bool {
+	for _, marker := range markers {
+		if strings.Contains(s, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeUpstreamError prepares an upstream error for the client. The
+// first matching error override of the channel wins; otherwise the channel
+// name and base URL are removed from the message and, when enabled, the
+// error is mapped to a stable type and code. Errors raised locally are left
+// alone, and error logs keep the raw message since they are written before
+// this runs.
+func NormalizeUpstreamError(c *gin.Context, err *types.NewAPIError) {
+	if !types.IsUpstreamError(err) {
+		return
+	}
+	rawMessage := err.Error()
+	if otherSettings, ok := common.GetContextKeyType[dto.ChannelOtherSettings](c, constant.ContextKeyChannelOtherSetting); ok {
+		for _, override := range otherSettings.ErrorOverrides {
+			re, compileErr := regexp.Compile(override.Pattern)
+			if compileErr != nil {
+				continue
+			}
+			match := re.FindStringSubmatchIndex(rawMessage)
+			if match == nil {
+				continue
+			}
+			if override.StatusCode != 0 {
+				err.StatusCode = override.StatusCode
+			}
+			class := classifyUpstreamError(err.StatusCode, rawMessage)
+			if override.Code != "" {
+				class.Code = override.Code
+			}
+			err.Rewrite(string(re.ExpandString(nil, override.Message, rawMessage, match)), class.Type, class.Code)
+			return
+		}
+	}
+
+	strip := func(message string) string {
+		return stripChannelInfo(c, message)
+	}
+	setting := operation_setting.GetUpstreamErrorSetting()
+	if !setting.NormalizeEnabled {
+		err.MapMessage(strip)
+		return
+	}
+	class := classifyUpstreamError(err.StatusCode, rawMessage)
+	message := class.Message
+	// auth failures tend to echo (part of) the channel key
+	if !setting.HideMessage && class != upstreamErrorAuth {
+		message = strip(err.ToOpenAIError().Message)
+	}
+	err.Rewrite(message, class.Type, class.Code)
+}
+
+// stripChannelInfo removes the channel name and upstream host from message.
+// Very short names are kept to avoid mangling unrelated words.
+func stripChannelInfo(c *gin.Context, message string) string {
+	if name := common.GetContextKeyString(c, constant.ContextKeyChannelName); len([]rune(name)) >= 3 {
+		message = strings.ReplaceAll(message, name, "***")
+	}
+	if baseURL := common.GetContextKeyString(c, constant.ContextKeyChannelBaseUrl); baseURL != "" {
+		if u, err := url.Parse(baseURL); err == nil && u.Hostname() != "" {
+			message = strings.ReplaceAll(message, u.Hostname(), "***")
+		}
+	}
+	return message
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeUpstreamError(t *testing.T) {
+	upstream := func(message string, code string, status int) *types.NewAPIError {
+		return types.WithOpenAIError(types.OpenAIError{Message: message, Type: "upstream_type", Code: code}, status, types.ErrOptionWithUpstream())
+	}
+	tests := []struct {
+		name        string
+		err         *types.NewAPIError
+		overrides   []dto.ChannelErrorOverride
+		setting     operation_setting.UpstreamErrorSetting
+		wantMessage string
+		wantCode    any
+		wantType    string
+		wantStatus  int
+	}{
+		{
+			name:        "local error is untouched",
+			err:         types.NewOpenAIError(errors.New("acme-relay is down"), types.ErrorCodeConvertRequestFailed, http.StatusBadRequest),
+			setting:     operation_setting.UpstreamErrorSetting{NormalizeEnabled: true},
+			wantMessage: "acme-relay is down",
+			wantCode:    types.ErrorCodeConvertRequestFailed,
+			wantType:    string(types.ErrorCodeConvertRequestFailed),
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "channel info is stripped without normalization",
+			err:         upstream("acme-relay: quota of proxy.acme.example exhausted", "quota", http.StatusTooManyRequests),
+			wantMessage: "***: quota of *** exhausted",
+			wantCode:    "quota",
+			wantType:    "upstream_type",
+			wantStatus:  http.StatusTooManyRequests,
+		},
+		{
+			name:        "status code maps to stable code",
+			err:         upstream("acme-relay: slow down", "1302", http.StatusTooManyRequests),
+			setting:     operation_setting.UpstreamErrorSetting{NormalizeEnabled: true},
+			wantMessage: "***: slow down",
+			wantCode:    "rate_limit_exceeded",
+			wantType:    "rate_limit_error",
+			wantStatus:  http.StatusTooManyRequests,
+		},
+		{
+			name:        "message marker wins over status code",
+			err:         upstream("This model's maximum context length is 8192 tokens", "", http.StatusInternalServerError),
+			setting:     operation_setting.UpstreamErrorSetting{NormalizeEnabled: true},
+			wantMessage: "This model's maximum context length is 8192 tokens",
+			wantCode:    "context_length_exceeded",
+			wantType:    "invalid_request_error",
+			wantStatus:  http.StatusInternalServerError,
+		},
+		{
+			name:        "auth failures never echo the upstream message",
+			err:         upstream("Incorrect API key provided: sk-abc***xyz", "invalid_api_key", http.StatusUnauthorized),
+			setting:     operation_setting.UpstreamErrorSetting{NormalizeEnabled: true},
+			wantMessage: upstreamErrorAuth.Message,
+			wantCode:    "upstream_auth_failed",
+			wantType:    "server_error",
+			wantStatus:  http.StatusUnauthorized,
+		},
+		{
+			name:        "hidden message",
+			err:         upstream("upstream exploded", "", http.StatusBadGateway),
+			setting:     operation_setting.UpstreamErrorSetting{NormalizeEnabled: true, HideMessage: true},
+			wantMessage: upstreamErrorServer.Message,
+			wantCode:    "upstream_error",
+			wantType:    "server_error",
+			wantStatus:  http.StatusBadGateway,
+		},
+		{
+			name: "first matching override wins",
+			err:  upstream("Arrearage: account 42 is overdue", "Arrearage", http.StatusBadRequest),
+			overrides: []dto.ChannelErrorOverride{
+				{Pattern: `^never`, Message: "unused"},
+				{Pattern: `account (\d+) is overdue`, Message: "service unavailable (ref $1)", StatusCode: http.StatusServiceUnavailable},
+				{Pattern: `overdue`, Message: "unused"},
+			},
+			wantMessage: "service unavailable (ref 42)",
+			wantCode:    "upstream_error",
+			wantType:    "server_error",
+			wantStatus:  http.StatusServiceUnavailable,
+		},
+		{
+			name:        "override code",
+			err:         upstream("Arrearage", "Arrearage", http.StatusBadRequest),
+			overrides:   []dto.ChannelErrorOverride{{Pattern: `Arrearage`, Message: "try again later", Code: "temporarily_unavailable"}},
+			wantMessage: "try again later",
+			wantCode:    "temporarily_unavailable",
+			wantType:    "invalid_request_error",
+			wantStatus:  http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setting := operation_setting.GetUpstreamErrorSetting()
+			saved := *setting
+			*setting = tt.setting
+			t.Cleanup(func() { *setting = saved })
+
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			common.SetContextKey(c, constant.ContextKeyChannelName, "acme-relay")
+			common.SetContextKey(c, constant.ContextKeyChannelBaseUrl, "https://proxy.acme.example/v1")
+			common.SetContextKey(c, constant.ContextKeyChannelOtherSetting, dto.ChannelOtherSettings{ErrorOverrides: tt.overrides})
+
+			NormalizeUpstreamError(c, tt.err)
+			oaiError := tt.err.ToOpenAIError()
+			assert.Equal(t, tt.wantMessage, oaiError.Message)
+			assert.Equal(t, tt.wantCode, oaiError.Code)
+			assert.Equal(t, tt.wantType, oaiError.Type)
+			assert.Equal(t, tt.wantStatus, tt.err.StatusCode)
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+// usageCapCheckScript rejects the request when the window counter has reached
+// either cap, otherwise counts it. It returns {allowed, requests, tokens}.
+var usageCapCheckScript = redis.NewScript(`
+local requests = tonumber(redis.call('HGET', KEYS[1], 'requests') or '0')
+local tokens = tonumber(redis.call('HGET', KEYS[1], 'tokens') or '0')
+local maxRequests = tonumber(ARGV[1])
+local maxTokens = tonumber(ARGV[2])
+if (maxRequests > 0 and requests >= maxRequests) or (maxTokens > 0 and tokens >= maxTokens) then
+	return {0, requests, tokens}
+end
+requests = redis.call('HINCRBY', KEYS[1], 'requests', 1)
+redis.call('PEXPIREAT', KEYS[1], ARGV[3])
+return {1, requests, tokens}
+`)
+
+type usageCapCounter struct {
+	requests  int64
+	tokens    int64
+	expiresAt time.Time
+}
+
+var (
+	usageCapCountersMu sync.Mutex
+	usageCapCounters   = map[string]*usageCapCounter{}
+)
+
+// usageCapSlot is a usage cap rule resolved to the counter of its current
+// window, with the counter values seen when the request was checked.
+type usageCapSlot struct {
+	rule     operation_setting.UsageCapRule
+	key      string
+	reset    time.Time
+	requests int64
+	tokens   int64
+}
+
+func tryCountUsageCap(ctx context.Context, slot *usageCapSlot) (bool, error) {
+	if common.RedisEnabled {
+		result, err := usageCapCheckScript.Run(ctx, common.RDB, []string{slot.key},
+			slot.rule.MaxRequests, slot.rule.MaxTokens, slot.reset.UnixMilli()).Int64Slice()
+		if err != nil {
+			return false, err
+		}
+		slot.requests, slot.tokens = result[1], result[2]
+		return result[0] == 1, nil
+	}
+	usageCapCountersMu.Lock()
+	defer usageCapCountersMu.Unlock()
+	counter, ok := usageCapCounters[slot.key]
+	if !ok {
+		// drop the counters of past windows whenever a new one starts
+		now := time.Now()
+		for key, old := range usageCapCounters {
+			if now.After(old.expiresAt) {
+				delete(usageCapCounters, key)
+			}
+		}
+		counter = &usageCapCounter{expiresAt: slot.reset}
+		usageCapCounters[slot.key] = counter
+	}
+	slot.requests, slot.tokens = counter.requests, counter.tokens
+	if (slot.rule.MaxRequests > 0 && counter.requests >= slot.rule.MaxRequests) ||
+		(slot.rule.MaxTokens > 0 && counter.tokens >= slot.rule.MaxTokens) {
+		return false, nil
+	}
+	counter.requests++
+	slot.requests++
+	return true, nil
+}
+
+func addUsageCap(slot usageCapSlot, requests int64, tokens int64) {
+	if common.RedisEnabled {
+		ctx := context.Background()
+		pipe := common.RDB.TxPipeline()
+		pipe.HIncrBy(ctx, slot.key, "requests", requests)
+		pipe.HIncrBy(ctx, slot.key, "tokens", tokens)
+		pipe.ExpireAt(ctx, slot.key, slot.reset)
+		if _, err := pipe.Exec(ctx); err != nil {
+			common.SysError(fmt.Sprintf("failed to update usage cap %s: %v", slot.key, err))
+		}
+		return
+	}
+	usageCapCountersMu.Lock()
+	defer usageCapCountersMu.Unlock()
+	if counter, ok := usageCapCounters[slot.key]; ok {
+		counter.requests += requests
+		counter.tokens += tokens
+	}
+}
+
+// setUsageCapHeaders reports the tightest of the given caps: the fewest
+// requests and tokens left and the earliest reset.
+func setUsageCapHeaders(c *gin.Context, slots []usageCapSlot) {
+	remainingRequests, remainingTokens := int64(-1), int64(-1)
+	var reset time.Time
+	for _, slot := range slots {
+		if slot.rule.MaxRequests > 0 {
+			remaining := max(slot.rule.MaxRequests-slot.requests, 0)
+			if remainingRequests < 0 || remaining < remainingRequests {
+				remainingRequests = remaining
+			}
+		}
+		if slot.rule.MaxTokens > 0 {
+			remaining := max(slot.rule.MaxTokens-slot.tokens, 0)
+			if remainingTokens < 0 || remaining < remainingTokens {
+				remainingTokens = remaining
+			}
+		}
+		if reset.IsZero() || slot.reset.Before(reset) {
+			reset = slot.reset
+		}
+	}
+	if remainingRequests >= 0 {
+		c.Header("X-Usage-Cap-Remaining-Requests", strconv.FormatInt(remainingRequests, 10))
+	}
+	if remainingTokens >= 0 {
+		c.Header("X-Usage-Cap-Remaining-Tokens", strconv.FormatInt(remainingTokens, 10))
+	}
+	c.Header("X-Usage-Cap-Reset", strconv.FormatInt(reset.Unix(), 10))
+}
+
+// CheckUsageCaps counts the request against every daily or weekly cap that
+// matches the user's group and the requested model, and rejects it with a 429
+// once any cap is used up. Tokens are only known afterwards, so a token cap
+// is checked against earlier requests and may be overshot by the last one.
+// The returned settle func must be called with the final relay error: failed
+// requests that consumed nothing give their request back, the others add the
+// tokens recorded by RecordConsumeLog.
+func CheckUsageCaps(c *gin.Context, info *relaycommon.RelayInfo) (func(*types.NewAPIError), *types.NewAPIError) {
+	rules := operation_setting.GetUsageCapRules(info.UserGroup, info.OriginModelName)
+	if len(rules) == 0 {
+		return func(*types.NewAPIError) {}, nil
+	}
+	now := time.Now()
+	slots := make([]usageCapSlot, 0, len(rules))
+	refund := func() {
+		for _, slot := range slots {
+			addUsageCap(slot, -1, 0)
+		}
+	}
+	for _, rule := range rules {
+		start, reset := operation_setting.UsageCapWindow(rule.Period, now)
+		slot := usageCapSlot{
+			rule:  rule,
+			key:   fmt.Sprintf("usage_cap:%d:%s:%s:%s:%d", info.UserId, rule.Group, rule.Model, rule.Period, start.Unix()),
+			reset: reset,
+		}
+		ok, err := tryCountUsageCap(c.Request.Context(), &slot)
+		if err != nil {
+			refund()
+			return nil, types.NewError(err, types.ErrorCodeQueryDataError, types.ErrOptionWithSkipRetry())
+		}
+		if ok {
+			slots = append(slots, slot)
+			continue
+		}
+		refund()
+		setUsageCapHeaders(c, []usageCapSlot{slot})
+		c.Header("Retry-After", strconv.FormatInt(max(int64(time.Until(reset).Seconds()), 1), 10))
+		limitType, limit := "request", rule.MaxRequests
+		if rule.MaxRequests == 0 || slot.requests < rule.MaxRequests {
+			limitType, limit = "token", rule.MaxTokens
+		}
+		period := "daily"
+		if rule.Period == operation_setting.UsageCapPeriodWeek {
+			period = "weekly"
+		}
+		return nil, types.NewErrorWithStatusCode(
+			fmt.Errorf("%s %s cap of %d reached for model %s, resets at %s", period, limitType, limit, rule.Model, reset.Format(time.RFC3339)),
+			types.ErrorCodeUsageCapExceeded, http.StatusTooManyRequests, types.ErrOptionWithSkipRetry())
+	}
+	setUsageCapHeaders(c, slots)
+
+	return func(relayErr *types.NewAPIError) {
+		used := int64(common.GetContextKeyInt(c, constant.ContextKeyTokenUsedTokens))
+		switch {
+		case used > 0:
+			for _, slot := range slots {
+				addUsageCap(slot, 0, used)
+			}
+		case relayErr != nil:
+			refund()
+		}
+	}, nil
+}
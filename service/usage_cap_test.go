@@ -0,0 +1,101 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckUsageCaps(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	original := *operation_setting.GetUsageCapSetting()
+	originalRedis := common.RedisEnabled
+	t.Cleanup(func() {
+		*operation_setting.GetUsageCapSetting() = original
+		common.RedisEnabled = originalRedis
+	})
+	common.RedisEnabled = false
+	*operation_setting.GetUsageCapSetting() = operation_setting.UsageCapSetting{
+		Enabled: true,
+		Rules: []operation_setting.UsageCapRule{
+			{Group: "free", Model: "gpt-4o", Period: operation_setting.UsageCapPeriodDay, MaxRequests: 2},
+			{Group: "free", Model: "claude-*", Period: operation_setting.UsageCapPeriodWeek, MaxTokens: 100},
+		},
+	}
+
+	// request sends one request and reports usedTokens (or a failure) back
+	request := func(info *relaycommon.RelayInfo, usedTokens int, failed bool) (*httptest.ResponseRecorder, *types.NewAPIError) {
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+		settle, apiErr := CheckUsageCaps(c, info)
+		if apiErr != nil {
+			return recorder, apiErr
+		}
+		common.SetContextKey(c, constant.ContextKeyTokenUsedTokens, usedTokens)
+		var relayErr *types.NewAPIError
+		if failed {
+			relayErr = types.NewError(errors.New("upstream failed"), types.ErrorCodeBadResponse)
+		}
+		settle(relayErr)
+		return recorder, nil
+	}
+
+	t.Run("request cap", func(t *testing.T) {
+		info := &relaycommon.RelayInfo{UserId: 3101, UserGroup: "free", OriginModelName: "gpt-4o"}
+		recorder, apiErr := request(info, 10, false)
+		require.Nil(t, apiErr)
+		assert.Equal(t, "1", recorder.Header().Get("X-Usage-Cap-Remaining-Requests"))
+		_, apiErr = request(info, 0, true)
+		require.Nil(t, apiErr, "failed requests give their request back")
+		_, apiErr = request(info, 10, false)
+		require.Nil(t, apiErr)
+
+		recorder, apiErr = request(info, 10, false)
+		require.NotNil(t, apiErr)
+		assert.Equal(t, http.StatusTooManyRequests, apiErr.StatusCode)
+		assert.Equal(t, types.ErrorCodeUsageCapExceeded, apiErr.GetErrorCode())
+		assert.Contains(t, apiErr.Error(), "daily request cap of 2 reached for model gpt-4o")
+		assert.Equal(t, "0", recorder.Header().Get("X-Usage-Cap-Remaining-Requests"))
+		assert.NotEmpty(t, recorder.Header().Get("X-Usage-Cap-Reset"))
+		assert.NotEmpty(t, recorder.Header().Get("Retry-After"))
+	})
+
+	t.Run("token cap shared by prefix", func(t *testing.T) {
+		info := &relaycommon.RelayInfo{UserId: 3102, UserGroup: "free", OriginModelName: "claude-sonnet-4"}
+		_, apiErr := request(info, 60, false)
+		require.Nil(t, apiErr)
+		info.OriginModelName = "claude-haiku-4"
+		recorder, apiErr := request(info, 60, false)
+		require.Nil(t, apiErr, "the last request may overshoot the token cap")
+		assert.Equal(t, "40", recorder.Header().Get("X-Usage-Cap-Remaining-Tokens"))
+
+		_, apiErr = request(info, 1, false)
+		require.NotNil(t, apiErr)
+		assert.Contains(t, apiErr.Error(), "weekly token cap of 100 reached for model claude-*")
+	})
+
+	t.Run("other groups and models are not capped", func(t *testing.T) {
+		for _, info := range []*relaycommon.RelayInfo{
+			{UserId: 3103, UserGroup: "vip", OriginModelName: "gpt-4o"},
+			{UserId: 3103, UserGroup: "free", OriginModelName: "gpt-4o-mini"},
+		} {
+			for range 3 {
+				recorder, apiErr := request(info, 10, false)
+				require.Nil(t, apiErr)
+				assert.Empty(t, recorder.Header().Get("X-Usage-Cap-Reset"))
+			}
+		}
+	})
+}
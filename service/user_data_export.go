@@ -0,0 +1,196 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"io"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+)
+
+const userDataExportBatchSize = 500
+
+// userDataExportProfile is the account data of the export. Credentials,
+// admin-only fields and notification secrets are left out.
+type userDataExportProfile struct {
+	Id              int             `json:"id"`
+	Username        string          `json:"username"`
+	DisplayName     string          `json:"display_name"`
+	Email           string          `json:"email"`
+	Role            int             `json:"role"`
+	Status          int             `json:"status"`
+	Group           string          `json:"group"`
+	Quota           int             `json:"quota"`
+	UsedQuota       int             `json:"used_quota"`
+	RequestCount    int             `json:"request_count"`
+	AffCode         string          `json:"aff_code"`
+	AffCount        int             `json:"aff_count"`
+	AffQuota        int             `json:"aff_quota"`
+	AffHistoryQuota int             `json:"aff_history_quota"`
+	InviterId       int             `json:"inviter_id"`
+	GitHubId        string          `json:"github_id"`
+	DiscordId       string          `json:"discord_id"`
+	OidcId          string          `json:"oidc_id"`
+	WeChatId        string          `json:"wechat_id"`
+	TelegramId      string          `json:"telegram_id"`
+	LinuxDOId       string          `json:"linux_do_id"`
+	CreatedAt       int64           `json:"created_at"`
+	LastLoginAt     int64           `json:"last_login_at"`
+	Setting         dto.UserSetting `json:"setting"`
+}
+
+// userDataExportCheckin drops the anti-abuse fields of a check-in record.
+type userDataExportCheckin struct {
+	CheckinDate  string `json:"checkin_date"`
+	QuotaAwarded int    `json:"quota_awarded"`
+	BonusQuota   int    `json:"bonus_quota"`
+	Streak       int    `json:"streak"`
+	IsMakeup     bool   `json:"is_makeup"`
+	PrizeId      string `json:"prize_id"`
+	CreatedAt    int64  `json:"created_at"`
+}
+
+// WriteUserDataExport writes a zip archive with everything stored about the
+// user: JSON documents for the profile, tokens (keys masked), top-ups,
+// check-ins and active login sessions, and JSON Lines for the consumption
+// and sign-in logs, which are streamed in batches.
+func WriteUserDataExport(ctx context.Context, w io.Writer, user *model.User) error {
+	archive := zip.NewWriter(w)
+
+	setting := user.GetSetting()
+	setting.WebhookSecret = ""
+	setting.GotifyToken = ""
+	profile := userDataExportProfile{
+		Id:              user.Id,
+		Username:        user.Username,
+		DisplayName:     user.DisplayName,
+		Email:           user.Email,
+		Role:            user.Role,
+		Status:          user.Status,
+		Group:           user.Group,
+		Quota:           user.Quota,
+		UsedQuota:       user.UsedQuota,
+		RequestCount:    user.RequestCount,
+		AffCode:         user.AffCode,
+		AffCount:        user.AffCount,
+		AffQuota:        user.AffQuota,
+		AffHistoryQuota: user.AffHistoryQuota,
+		InviterId:       user.InviterId,
+		GitHubId:        user.GitHubId,
+		DiscordId:       user.DiscordId,
+		OidcId:          user.OidcId,
+		WeChatId:        user.WeChatId,
+		TelegramId:      user.TelegramId,
+		LinuxDOId:       user.LinuxDOId,
+		CreatedAt:       user.CreatedAt,
+		LastLoginAt:     user.LastLoginAt,
+		Setting:         setting,
+	}
+	if err := writeUserDataExportJSON(archive, "export.json", map[string]any{
+		"system_name": common.SystemName,
+		"user_id":     user.Id,
+		"exported_at": time.Now().Unix(),
+		"files": []string{"profile.json", "tokens.json", "topups.json", "checkins.json", "sessions.json",
+			"consumption_logs.jsonl", "sign_in_logs.jsonl"},
+	}); err != nil {
+		return err
+	}
+	if err := writeUserDataExportJSON(archive, "profile.json", profile); err != nil {
+		return err
+	}
+
+	tokens, err := model.GetAllUserTokens(user.Id, 0, -1)
+	if err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		token.Key = token.GetMaskedKey()
+		token.PreviousKey = ""
+	}
+	if err := writeUserDataExportJSON(archive, "tokens.json", tokens); err != nil {
+		return err
+	}
+
+	var topUps []*model.TopUp
+	if err := model.DB.Where("user_id = ?", user.Id).Order("id asc").Find(&topUps).Error; err != nil {
+		return err
+	}
+	if err := writeUserDataExportJSON(archive, "topups.json", topUps); err != nil {
+		return err
+	}
+
+	checkins := make([]userDataExportCheckin, 0)
+	err = model.ExportCheckins(user.Id, "", "9999-12-31", func(batch []model.Checkin) error {
+		for _, checkin := range batch {
+			checkins = append(checkins, userDataExportCheckin{
+				CheckinDate:  checkin.CheckinDate,
+				QuotaAwarded: checkin.QuotaAwarded,
+				BonusQuota:   checkin.BonusQuota,
+				Streak:       checkin.Streak,
+				IsMakeup:     checkin.IsMakeup,
+				PrizeId:      checkin.PrizeId,
+				CreatedAt:    checkin.CreatedAt,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := writeUserDataExportJSON(archive, "checkins.json", checkins); err != nil {
+		return err
+	}
+
+	sessions, err := model.GetUserSessions(user.Id)
+	if err != nil {
+		return err
+	}
+	if err := writeUserDataExportJSON(archive, "sessions.json", sessions); err != nil {
+		return err
+	}
+
+	for _, logFile := range []struct {
+		name    string
+		logType int
+	}{
+		{name: "consumption_logs.jsonl", logType: model.LogTypeConsume},
+		{name: "sign_in_logs.jsonl", logType: model.LogTypeLogin},
+	} {
+		file, err := archive.Create(logFile.name)
+		if err != nil {
+			return err
+		}
+		err = model.ExportUserLogs(ctx, user.Id, logFile.logType, userDataExportBatchSize, func(batch []*model.Log) error {
+			for _, log := range batch {
+				line, err := common.Marshal(log)
+				if err != nil {
+					return err
+				}
+				if _, err := file.Write(append(line, '\n')); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+func writeUserDataExportJSON(archive *zip.Writer, name string, value any) error {
+	data, err := common.Marshal(value)
+	if err != nil {
+		return err
+	}
+	file, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	return err
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteUserDataExport(t *testing.T) {
+	truncate(t)
+	t.Cleanup(func() {
+		model.DB.Exec("DELETE FROM checkins")
+		model.DB.Exec("DELETE FROM user_sessions")
+	})
+
+	user := &model.User{Id: 801, Username: "export_user", Email: "export@example.com", AffCode: "e801", Status: common.UserStatusEnabled}
+	user.SetSetting(dto.UserSetting{NotifyType: dto.NotifyTypeWebhook, WebhookUrl: "https://hook.example.com", WebhookSecret: "hook-secret"})
+	require.NoError(t, model.DB.Create(user).Error)
+	require.NoError(t, model.DB.Create(&model.Token{UserId: user.Id, Key: "abcdefghijklmnopqrstuvwxyz123456", Name: "main"}).Error)
+	require.NoError(t, model.DB.Create(&model.TopUp{UserId: user.Id, TradeNo: "EXPORT-1", Amount: 10, Money: 10, Status: common.TopUpStatusSuccess}).Error)
+	require.NoError(t, model.DB.Create(&model.Checkin{UserId: user.Id, CheckinDate: "2026-01-01", QuotaAwarded: 100, Ip: "10.0.0.1"}).Error)
+	for i := 0; i < userDataExportBatchSize+3; i++ {
+		require.NoError(t, model.DB.Create(&model.Log{UserId: user.Id, Type: model.LogTypeConsume, Quota: i, Other: `{"admin_info":{"x":1}}`}).Error)
+	}
+	require.NoError(t, model.DB.Create(&model.Log{UserId: user.Id, Type: model.LogTypeLogin, Ip: "10.0.0.1"}).Error)
+	require.NoError(t, model.DB.Create(&model.Log{UserId: user.Id + 1, Type: model.LogTypeConsume}).Error)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteUserDataExport(context.Background(), &buf, user))
+
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	files := make(map[string]string)
+	for _, file := range reader.File {
+		rc, err := file.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+		files[file.Name] = string(data)
+	}
+
+	assert.Contains(t, files["profile.json"], `"username":"export_user"`)
+	assert.Contains(t, files["profile.json"], "https://hook.example.com")
+	assert.NotContains(t, files["profile.json"], "hook-secret")
+	assert.NotContains(t, files["tokens.json"], "abcdefghijklmnopqrstuvwxyz123456")
+	assert.Contains(t, files["tokens.json"], `"name":"main"`)
+	assert.Contains(t, files["topups.json"], "EXPORT-1")
+	assert.Contains(t, files["checkins.json"], "2026-01-01")
+	assert.NotContains(t, files["checkins.json"], "10.0.0.1")
+	assert.Contains(t, files, "sessions.json")
+
+	consumeLines := strings.Split(strings.TrimSpace(files["consumption_logs.jsonl"]), "\n")
+	assert.Len(t, consumeLines, userDataExportBatchSize+3)
+	assert.NotContains(t, files["consumption_logs.jsonl"], "admin_info")
+	assert.Len(t, strings.Split(strings.TrimSpace(files["sign_in_logs.jsonl"]), "\n"), 1)
+}
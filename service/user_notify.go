@@ -5,12 +5,17 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
 	"github.com/QuantumNous/new-api/model"
 	"github.com/QuantumNous/new-api/setting/system_setting"
+
+	"github.com/bytedance/gopkg/util/gopool"
 )
 
 func NotifyRootUser(t string, subject string, content string) {
@@ -47,6 +52,27 @@ func NotifyUpstreamModelUpdateWatchers(subject string, content string) {
 	common.SysLog(fmt.Sprintf("upstream model update notifications sent: %d", sentCount))
 }
 
+// SendTopUpReceipt asynchronously sends the top-up receipt to the user.
+func SendTopUpReceipt(userId int, tradeNo string, paymentMethod string, quota int, money float64) {
+	gopool.Go(func() {
+		user, err := model.GetUserById(userId, false)
+		if err != nil {
+			common.SysError(fmt.Sprintf("failed to load user %d for topup receipt: %v", userId, err))
+			return
+		}
+		err = NotifyUserWithTemplate(user.Id, user.Email, user.GetSetting(), dto.NotifyTypeTopUp, NotificationTemplateTopUpReceipt, map[string]string{
+			"trade_no":       tradeNo,
+			"payment_method": paymentMethod,
+			"quota":          logger.LogQuota(quota),
+			"money":          strconv.FormatFloat(money, 'f', 2, 64),
+			"time":           time.Now().Format("2006-01-02 15:04:05"),
+		})
+		if err != nil {
+			common.SysLog(fmt.Sprintf("failed to send topup receipt to user %d: %s", userId, err.Error()))
+		}
+	})
+}
+
 func NotifyUser(userId int, userEmail string, userSetting dto.UserSetting, data dto.Notify) error {
 	notifyType := userSetting.NotifyType
 	if notifyType == "" {
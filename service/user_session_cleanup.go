@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/QuantumNous/new-api/model"
+)
+
+const userSessionCleanupBatchSize = 500
+
+type userSessionCleanupResult struct {
+	Deleted int64 `json:"deleted"`
+}
+
+// userSessionCleanupHandler prunes expired login sessions. Expired sessions
+// are already rejected by the auth middleware, this only keeps the table small.
+type userSessionCleanupHandler struct{}
+
+func init() {
+	RegisterSystemTaskHandler(userSessionCleanupHandler{})
+}
+
+func (userSessionCleanupHandler) Type() string { return model.SystemTaskTypeUserSessionCleanup }
+
+func (userSessionCleanupHandler) Enabled() bool { return true }
+
+func (userSessionCleanupHandler) Interval() time.Duration { return 6 * time.Hour }
+
+func (userSessionCleanupHandler) NewPayload() any { return nil }
+
+func (userSessionCleanupHandler) Run(ctx context.Context, task *model.SystemTask, runnerID string) {
+	result := &userSessionCleanupResult{}
+	for {
+		if err := ctx.Err(); err != nil {
+			failSystemTask(task, runnerID, err)
+			return
+		}
+		deleted, err := model.DeleteExpiredUserSessions(userSessionCleanupBatchSize)
+		if err != nil {
+			failSystemTask(task, runnerID, err)
+			return
+		}
+		result.Deleted += deleted
+		if deleted < userSessionCleanupBatchSize {
+			break
+		}
+	}
+	if err := model.FinishSystemTask(task.TaskID, runnerID, model.SystemTaskStatusSucceeded, result, ""); err != nil {
+		logSystemTaskLockError(ctx, task, err)
+	}
+}
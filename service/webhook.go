@@ -53,9 +53,14 @@ func SendWebhookNotify(webhookURL string, secret string, data dto.Notify) error
 		return fmt.Errorf("failed to marshal webhook payload: %v", err)
 	}
 
-	// 创建 HTTP 请求
+	return postWebhook(webhookURL, secret, payloadBytes)
+}
+
+// postWebhook 以 JSON 格式 POST 负载到 webhook 地址，配置了 secret 时附带签名
+func postWebhook(webhookURL string, secret string, payloadBytes []byte) error {
 	var req *http.Request
 	var resp *http.Response
+	var err error
 
 	if system_setting.EnableWorker() {
 		// 构建worker请求数据
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+const (
+	WebhookEventChannelAutoDisabled = "channel.auto_disabled" // 通道被自动禁用
+	WebhookEventUserQuotaLow        = "user.quota_low"        // 用户剩余额度低于提醒阈值
+	WebhookEventRedemptionUsed      = "redemption.used"       // 兑换码被使用
+	WebhookEventTopUpCompleted      = "topup.completed"       // 充值订单到账
+	WebhookEventUserGroupChanged    = "user.group_changed"    // 按累计消费自动调整了用户分组
+	WebhookEventTest                = "webhook.test"          // 管理员手动发送的测试事件
+)
+
+// WebhookEvents 可订阅的运维事件
+var WebhookEvents = []string{
+	WebhookEventChannelAutoDisabled,
+	WebhookEventUserQuotaLow,
+	WebhookEventRedemptionUsed,
+	WebhookEventTopUpCompleted,
+	WebhookEventUserGroupChanged,
+}
+
+// webhookMaxAttempts 事件推送的最大尝试次数，重试间隔按 1s、2s、4s… 递增
+const webhookMaxAttempts = 4
+
+// WebhookEvent 运维事件推送负载
+type WebhookEvent struct {
+	Id        string         `json:"id"`
+	Event     string         `json:"event"`
+	Timestamp int64          `json:"timestamp"`
+	Data      map[string]any `json:"data"`
+}
+
+func init() {
+	model.TopUpCompletedHook = func(userId int, tradeNo string, paymentMethod string, quota int, money float64) {
+		PublishTopUpCompletedEvent(userId, tradeNo, paymentMethod, quota, money)
+		SendTopUpReceipt(userId, tradeNo, paymentMethod, quota, money)
+	}
+}
+
+// postWebhookWithRetry 推送负载，失败时按指数退避重试，返回实际尝试次数和最后一次错误
+func postWebhookWithRetry(webhookURL string, secret string, payload []byte, maxAttempts int) (int, error) {
+	for attempt := 1; ; attempt++ {
+		err := postWebhook(webhookURL, secret, payload)
+		if err == nil || attempt >= maxAttempts {
+			return attempt, err
+		}
+		time.Sleep(time.Duration(1<<(attempt-1)) * time.Second)
+	}
+}
+
+// PublishWebhookEvent 异步推送事件到所有订阅了该事件的已启用地址，每个地址的推送结果写入推送记录
+func PublishWebhookEvent(event string, data map[string]any) {
+	gopool.Go(func() {
+		endpoints, err := model.GetWebhookEndpointsForEvent(event)
+		if err != nil {
+			common.SysError(fmt.Sprintf("failed to load webhook endpoints for event %s: %v", event, err))
+			return
+		}
+		if len(endpoints) == 0 {
+			return
+		}
+		payload := WebhookEvent{
+			Id:        common.GetUUID(),
+			Event:     event,
+			Timestamp: time.Now().Unix(),
+			Data:      data,
+		}
+		for _, endpoint := range endpoints {
+			endpoint := endpoint
+			gopool.Go(func() {
+				DeliverWebhookEvent(endpoint, payload, webhookMaxAttempts)
+			})
+		}
+	})
+}
+
+// DeliverWebhookEvent 同步推送单个事件并记录结果
+func DeliverWebhookEvent(endpoint *model.WebhookEndpoint, event WebhookEvent, maxAttempts int) *model.WebhookDelivery {
+	delivery := &model.WebhookDelivery{
+		EndpointId: endpoint.Id,
+		EventId:    event.Id,
+		Event:      event.Event,
+	}
+	payload, err := common.Marshal(event)
+	if err != nil {
+		delivery.Error = fmt.Sprintf("failed to marshal webhook event: %v", err)
+	} else {
+		delivery.Payload = string(payload)
+		start := time.Now()
+		delivery.Attempts, err = postWebhookWithRetry(endpoint.Url, endpoint.Secret, payload, maxAttempts)
+		delivery.DurationMs = time.Since(start).Milliseconds()
+		delivery.Success = err == nil
+		if err != nil {
+			delivery.Error = err.Error()
+		}
+	}
+	if !delivery.Success {
+		common.SysError(fmt.Sprintf("failed to deliver webhook event %s to endpoint %d after %d attempts: %s",
+			event.Event, endpoint.Id, delivery.Attempts, delivery.Error))
+	}
+	if err := model.RecordWebhookDelivery(delivery); err != nil {
+		common.SysError(fmt.Sprintf("failed to record webhook delivery: %v", err))
+	}
+	return delivery
+}
+
+// PublishTopUpCompletedEvent 推送充值到账事件
+func PublishTopUpCompletedEvent(userId int, tradeNo string, paymentMethod string, quota int, money float64) {
+	PublishWebhookEvent(WebhookEventTopUpCompleted, map[string]any{
+		"user_id":        userId,
+		"trade_no":       tradeNo,
+		"payment_method": paymentMethod,
+		"quota":          quota,
+		"money":          money,
+	})
+}
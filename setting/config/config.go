@@ -67,6 +67,24 @@ func (cm *ConfigManager) LoadFromDB(options map[string]string) error {
 	return nil
 }
 
+// Reload 将 configMap 应用到配置副本后整体替换原配置，读者不会看到只更新了一半的配置
+func (cm *ConfigManager) Reload(name string, configMap map[string]string) error {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	val := reflect.ValueOf(cm.configs[name])
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	staged := reflect.New(val.Elem().Type())
+	staged.Elem().Set(val.Elem())
+	if err := updateConfigFromMap(staged.Interface(), configMap); err != nil {
+		return err
+	}
+	val.Elem().Set(staged.Elem())
+	return nil
+}
+
 // SaveToDB 将配置保存到数据库
 func (cm *ConfigManager) SaveToDB(updateFunc func(key, value string) error) error {
 	cm.mutex.RLock()
@@ -261,7 +279,14 @@ func updateConfigFromMap(config interface{}, configMap map[string]string) error
 				continue
 			}
 			field.Set(fresh.Elem())
-		case reflect.Slice, reflect.Struct:
+		case reflect.Slice:
+			// 与 map 相同，分配新切片，避免复用底层数组修改到旧配置副本
+			fresh := reflect.New(field.Type())
+			if err := json.Unmarshal([]byte(strValue), fresh.Interface()); err != nil {
+				continue
+			}
+			field.Set(fresh.Elem())
+		case reflect.Struct:
 			err := json.Unmarshal([]byte(strValue), field.Addr().Interface())
 			if err != nil {
 				continue
@@ -94,3 +94,33 @@ func TestUpdateConfigFromMap_ScalarFieldsUnchanged(t *testing.T) {
 		t.Errorf("Modes should be unchanged, got %v", cfg.Modes)
 	}
 }
+
+type testConfigWithSlice struct {
+	Patterns []string `json:"patterns"`
+	Enabled  bool     `json:"enabled"`
+}
+
+func TestConfigManagerReload_ReplacesWithoutTouchingOldSlice(t *testing.T) {
+	cfg := &testConfigWithSlice{Patterns: []string{"a", "b"}}
+	manager := NewConfigManager()
+	manager.Register("test", cfg)
+	oldPatterns := cfg.Patterns
+
+	err := manager.Reload("test", map[string]string{
+		"patterns": `["c", "d"]`,
+		"enabled":  "true",
+	})
+	if err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	if !cfg.Enabled || len(cfg.Patterns) != 2 || cfg.Patterns[0] != "c" || cfg.Patterns[1] != "d" {
+		t.Errorf("config not reloaded; got %+v", cfg)
+	}
+	if oldPatterns[0] != "a" || oldPatterns[1] != "b" {
+		t.Errorf("Reload mutated the slice held by existing readers; got %v", oldPatterns)
+	}
+	if manager.Get("test") != cfg {
+		t.Errorf("Reload must keep the registered pointer so GetXxx accessors see new values")
+	}
+}
@@ -32,10 +32,15 @@ func (p ChatCompletionsToResponsesPolicy) IsChannelEnabled(channelID int, channe
 	return false
 }
 
+// ResponsesToChatCompletionsPolicy 指定哪些渠道/模型将 /v1/responses 请求转换为 chat completions 发送给上游，
+// 用于只支持 chat completions 的渠道
+type ResponsesToChatCompletionsPolicy = ChatCompletionsToResponsesPolicy
+
 type GlobalSettings struct {
 	PassThroughRequestEnabled        bool                             `json:"pass_through_request_enabled"`
 	ThinkingModelBlacklist           []string                         `json:"thinking_model_blacklist"`
 	ChatCompletionsToResponsesPolicy ChatCompletionsToResponsesPolicy `json:"chat_completions_to_responses_policy"`
+	ResponsesToChatCompletionsPolicy ResponsesToChatCompletionsPolicy `json:"responses_to_chat_completions_policy"`
 }
 
 // 默认配置
@@ -49,6 +54,10 @@ var defaultOpenaiSettings = GlobalSettings{
 		Enabled:     false,
 		AllChannels: true,
 	},
+	ResponsesToChatCompletionsPolicy: ResponsesToChatCompletionsPolicy{
+		Enabled:     false,
+		AllChannels: true,
+	},
 }
 
 // 全局实例
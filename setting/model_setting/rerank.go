@@ -0,0 +1,41 @@
+package model_setting
+
+import (
+	"strings"
+
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// RerankSettings 重排序模型配置
+type RerankSettings struct {
+	// PerDocumentPriceModels 按次计费时改为按文档数计费的模型，模型价格即为单个文档的价格
+	PerDocumentPriceModels []string `json:"per_document_price_models"`
+}
+
+// 默认配置
+var defaultRerankSettings = RerankSettings{
+	PerDocumentPriceModels: []string{},
+}
+
+// 全局实例
+var rerankSettings = defaultRerankSettings
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("rerank", &rerankSettings)
+}
+
+// GetRerankSettings
+func GetRerankSettings() *RerankSettings {
+	return &rerankSettings
+}
+
+// IsPerDocumentPriceModel
+func IsPerDocumentPriceModel(model string) bool {
+	for _, m := range rerankSettings.PerDocumentPriceModels {
+		if strings.TrimSpace(m) == model {
+			return true
+		}
+	}
+	return false
+}
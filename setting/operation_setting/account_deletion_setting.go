@@ -0,0 +1,25 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// 注销冷静期的最大天数
+const MaxAccountDeletionCoolOffDays = 365
+
+type AccountDeletionSetting struct {
+	// 用户申请注销后需等待的天数，期间可撤销，冷静期结束后确认才会注销；0 表示可立即确认
+	CoolOffDays int `json:"cool_off_days"`
+}
+
+// 默认配置
+var accountDeletionSetting = AccountDeletionSetting{
+	CoolOffDays: 7,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("account_deletion_setting", &accountDeletionSetting)
+}
+
+func GetAccountDeletionSetting() *AccountDeletionSetting {
+	return &accountDeletionSetting
+}
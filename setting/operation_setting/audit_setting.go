@@ -0,0 +1,23 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+type AuditSetting struct {
+	Enabled       bool `json:"enabled"`        // 是否将管理操作写入独立的审计日志表
+	RetentionDays int  `json:"retention_days"` // 审计日志保留天数，0 表示永久保留
+}
+
+// 默认配置
+var auditSetting = AuditSetting{
+	Enabled:       true,
+	RetentionDays: 0,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("audit_setting", &auditSetting)
+}
+
+func GetAuditSetting() *AuditSetting {
+	return &auditSetting
+}
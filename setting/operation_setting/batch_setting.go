@@ -0,0 +1,26 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// BatchSetting OpenAI Batch API 配置
+type BatchSetting struct {
+	Enabled             bool `json:"enabled"`
+	DiscountPercent     int  `json:"discount_percent"`      // 批量任务按普通价格的百分比计费，OpenAI 官方为 50
+	PollIntervalSeconds int  `json:"poll_interval_seconds"` // 轮询上游任务状态的间隔
+}
+
+// 默认配置
+var batchSetting = BatchSetting{
+	Enabled:             false,
+	DiscountPercent:     50,
+	PollIntervalSeconds: 60,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("batch_setting", &batchSetting)
+}
+
+func GetBatchSetting() *BatchSetting {
+	return &batchSetting
+}
@@ -0,0 +1,29 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+type BodyLogSetting struct {
+	Enabled       bool     `json:"enabled"`        // 总开关，关闭时渠道/令牌的开关均不生效
+	RetentionDays int      `json:"retention_days"` // 请求/响应体保留天数，0 表示永久保留
+	MaxBodyBytes  int      `json:"max_body_bytes"` // 单个请求体或响应体最多保存的字节数（脱敏前）
+	StripImages   bool     `json:"strip_images"`   // 去除 base64 图片等大段二进制内容
+	MaskPatterns  []string `json:"mask_patterns"`  // 正则表达式，命中内容替换为 ***
+}
+
+// 默认配置
+var bodyLogSetting = BodyLogSetting{
+	Enabled:       false,
+	RetentionDays: 7,
+	MaxBodyBytes:  256 * 1024,
+	StripImages:   true,
+	MaskPatterns:  []string{},
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("body_log_setting", &bodyLogSetting)
+}
+
+func GetBodyLogSetting() *BodyLogSetting {
+	return &bodyLogSetting
+}
@@ -0,0 +1,36 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// 渠道级重试策略（dto.ChannelRetryPolicy）的取值上限
+const (
+	MaxChannelRetryTimes     = 10
+	MaxChannelRetryBackoffMs = 60000
+)
+
+// ChannelBreakerSetting 渠道熔断配置，统计窗口内上游错误率超过阈值后暂停向该渠道分发请求
+type ChannelBreakerSetting struct {
+	Enabled          bool `json:"enabled"`
+	WindowSeconds    int  `json:"window_seconds"`     // 错误率统计窗口
+	MinRequests      int  `json:"min_requests"`       // 窗口内请求数达到该值才会判断是否熔断
+	ErrorRatePercent int  `json:"error_rate_percent"` // 错误率达到该百分比时熔断
+	OpenSeconds      int  `json:"open_seconds"`       // 熔断持续时间，到期后放行一个探测请求（半开）
+}
+
+// 默认配置
+var channelBreakerSetting = ChannelBreakerSetting{
+	Enabled:          false,
+	WindowSeconds:    60,
+	MinRequests:      10,
+	ErrorRatePercent: 50,
+	OpenSeconds:      30,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("channel_breaker_setting", &channelBreakerSetting)
+}
+
+func GetChannelBreakerSetting() *ChannelBreakerSetting {
+	return &channelBreakerSetting
+}
@@ -0,0 +1,43 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// ChannelQueueSetting 渠道达到并发上限（渠道设置 max_concurrency）时的排队准入
+type ChannelQueueSetting struct {
+	Enabled             bool           `json:"enabled"`                // 关闭时渠道满载直接返回 429，由重试逻辑切换渠道
+	MaxWaitSeconds      int            `json:"max_wait_seconds"`       // 默认最长排队时间
+	MaxQueueLength      int            `json:"max_queue_length"`       // 单个渠道最多排队的请求数，0 表示不限制
+	GroupPriorities     map[string]int `json:"group_priorities"`       // 按用户分组设置优先级，数值越大越先获得空闲位置，未配置为 0
+	GroupMaxWaitSeconds map[string]int `json:"group_max_wait_seconds"` // 按用户分组覆盖 MaxWaitSeconds
+}
+
+// 默认配置
+var channelQueueSetting = ChannelQueueSetting{
+	Enabled:             false,
+	MaxWaitSeconds:      30,
+	MaxQueueLength:      100,
+	GroupPriorities:     map[string]int{},
+	GroupMaxWaitSeconds: map[string]int{},
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("channel_queue_setting", &channelQueueSetting)
+}
+
+func GetChannelQueueSetting() *ChannelQueueSetting {
+	return &channelQueueSetting
+}
+
+// GetChannelQueuePriority 获取用户分组的排队优先级
+func GetChannelQueuePriority(group string) int {
+	return channelQueueSetting.GroupPriorities[group]
+}
+
+// GetChannelQueueMaxWaitSeconds 获取用户分组的最长排队时间
+func GetChannelQueueMaxWaitSeconds(group string) int {
+	if seconds, ok := channelQueueSetting.GroupMaxWaitSeconds[group]; ok {
+		return max(seconds, 0)
+	}
+	return max(channelQueueSetting.MaxWaitSeconds, 0)
+}
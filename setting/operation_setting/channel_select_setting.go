@@ -0,0 +1,61 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// 同一优先级内的渠道选择策略，优先级之间始终按重试次数逐级回退
+const (
+	ChannelSelectStrategyWeighted     = "weighted"      // 按渠道权重随机
+	ChannelSelectStrategyRandom       = "random"        // 忽略权重，均匀随机
+	ChannelSelectStrategyLeastLatency = "least_latency" // 优先选择近期响应最快的渠道
+)
+
+// ChannelSelectRule 按分组和模型指定选择策略，Group/Model 为空表示匹配任意值
+type ChannelSelectRule struct {
+	Group    string `json:"group"`
+	Model    string `json:"model"`
+	Strategy string `json:"strategy"`
+}
+
+// ChannelSelectSetting 渠道负载均衡配置
+type ChannelSelectSetting struct {
+	DefaultStrategy string              `json:"default_strategy"`
+	Rules           []ChannelSelectRule `json:"rules"`           // 按顺序匹配，先匹配者生效
+	ExplorePercent  int                 `json:"explore_percent"` // least_latency 下按权重随机选择的请求比例，用于刷新其他渠道的延迟统计
+}
+
+// 默认配置
+var channelSelectSetting = ChannelSelectSetting{
+	DefaultStrategy: ChannelSelectStrategyWeighted,
+	Rules:           []ChannelSelectRule{},
+	ExplorePercent:  5,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("channel_select_setting", &channelSelectSetting)
+}
+
+func GetChannelSelectSetting() *ChannelSelectSetting {
+	return &channelSelectSetting
+}
+
+func IsValidChannelSelectStrategy(strategy string) bool {
+	switch strategy {
+	case ChannelSelectStrategyWeighted, ChannelSelectStrategyRandom, ChannelSelectStrategyLeastLatency:
+		return true
+	}
+	return false
+}
+
+// GetChannelSelectStrategy 返回分组和模型对应的渠道选择策略
+func GetChannelSelectStrategy(group string, model string) string {
+	for _, rule := range channelSelectSetting.Rules {
+		if (rule.Group == "" || rule.Group == group) && (rule.Model == "" || rule.Model == model) {
+			return rule.Strategy
+		}
+	}
+	if IsValidChannelSelectStrategy(channelSelectSetting.DefaultStrategy) {
+		return channelSelectSetting.DefaultStrategy
+	}
+	return ChannelSelectStrategyWeighted
+}
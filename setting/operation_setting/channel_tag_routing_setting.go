@@ -0,0 +1,33 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// ChannelTagRoutingRule 按渠道路由标签调整某个分组的渠道选择
+type ChannelTagRoutingRule struct {
+	Prefer  []string `json:"prefer"`  // 优先选择带有其中任一标签的渠道，这类渠道都不可用时再选择其他渠道
+	Exclude []string `json:"exclude"` // 不选择带有其中任一标签的渠道
+}
+
+type ChannelTagRoutingSetting struct {
+	Groups map[string]ChannelTagRoutingRule `json:"groups"` // 键为分组名
+}
+
+// 默认配置
+var channelTagRoutingSetting = ChannelTagRoutingSetting{
+	Groups: map[string]ChannelTagRoutingRule{},
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("channel_tag_routing_setting", &channelTagRoutingSetting)
+}
+
+func GetChannelTagRoutingSetting() *ChannelTagRoutingSetting {
+	return &channelTagRoutingSetting
+}
+
+// GetChannelTagRoutingRule 返回分组的标签路由规则，未配置或规则为空时 ok 为 false
+func GetChannelTagRoutingRule(group string) (rule ChannelTagRoutingRule, ok bool) {
+	rule, ok = channelTagRoutingSetting.Groups[group]
+	return rule, ok && (len(rule.Prefer) > 0 || len(rule.Exclude) > 0)
+}
@@ -0,0 +1,44 @@
+package operation_setting
+
+import (
+	"slices"
+
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+type ChatCacheSetting struct {
+	Enabled       bool     `json:"enabled"`
+	Groups        []string `json:"groups"`          // 启用缓存的分组，为空表示所有分组
+	TTLSeconds    int      `json:"ttl_seconds"`     // 缓存有效期（秒）
+	MaxEntries    int      `json:"max_entries"`     // 未启用 Redis 时内存缓存的最大条目数，超出按 LRU 淘汰，修改后重启生效
+	MaxEntryKB    int      `json:"max_entry_kb"`    // 单个响应超过该大小（KB）时不缓存
+	HitPriceRatio float64  `json:"hit_price_ratio"` // 命中缓存时按原价的倍率计费，0 表示免费
+	// 缓存始终按分组隔离；默认再按用户隔离，开启后同一分组的用户共享缓存，相同提示词可能读到其他用户的响应
+	SharedAcrossUsers bool `json:"shared_across_users"`
+}
+
+// 默认配置
+var chatCacheSetting = ChatCacheSetting{
+	Enabled:       false,
+	Groups:        []string{},
+	TTLSeconds:    3600,
+	MaxEntries:    10000,
+	MaxEntryKB:    512,
+	HitPriceRatio: 0,
+
+	SharedAcrossUsers: false,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("chat_cache_setting", &chatCacheSetting)
+}
+
+func GetChatCacheSetting() *ChatCacheSetting {
+	return &chatCacheSetting
+}
+
+// IsGroupEnabled 判断分组是否启用对话缓存
+func (s *ChatCacheSetting) IsGroupEnabled(group string) bool {
+	return len(s.Groups) == 0 || slices.Contains(s.Groups, group)
+}
@@ -1,21 +1,136 @@
 package operation_setting
 
-import "github.com/QuantumNous/new-api/setting/config"
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// maxCheckinMultiplier 签到倍率（连签、分组）上限，防止配置错误导致一次签到发放过量额度
+const maxCheckinMultiplier = 100
+
+const (
+	CheckinIpLimitActionDeny = "deny"
+	CheckinIpLimitActionFlag = "flag"
+)
+
+const (
+	CheckinPrizeTypeQuota      = "quota"      // 直接发放额度
+	CheckinPrizeTypeRedemption = "redemption" // 发放一张兑换码
+	CheckinPrizeTypeNone       = "none"       // 谢谢参与
+)
+
+// CheckinStreakMilestone 连续签到里程碑：连签达到 Days 天后，当天奖励乘以 Multiplier
+type CheckinStreakMilestone struct {
+	Days       int     `json:"days"`
+	Multiplier float64 `json:"multiplier"`
+}
+
+// CheckinTotalMilestone 累计签到里程碑：累计签到达到 Days 天时额外发放 Quota 额度
+type CheckinTotalMilestone struct {
+	Days  int `json:"days"`
+	Quota int `json:"quota"`
+}
+
+// CheckinGroupReward 分组签到奖励：配置了区间时覆盖全局区间，Multiplier 为 0 时视为 1
+type CheckinGroupReward struct {
+	MinQuota   int     `json:"min_quota"`
+	MaxQuota   int     `json:"max_quota"`
+	Multiplier float64 `json:"multiplier"`
+}
+
+// CheckinPromotion 签到促销活动：StartDate 至 EndDate（含，YYYY-MM-DD）期间，
+// 签到奖励乘以 Multiplier（0 视为 1）并额外发放 BonusQuota
+type CheckinPromotion struct {
+	Name       string  `json:"name"`
+	StartDate  string  `json:"start_date"`
+	EndDate    string  `json:"end_date"`
+	Multiplier float64 `json:"multiplier"`
+	BonusQuota int     `json:"bonus_quota"`
+}
+
+// CheckinPrize 抽奖模式的奖品：按 Weight 加权抽取，DailyStock 为每日库存，0 表示不限
+type CheckinPrize struct {
+	Id         string `json:"id"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Quota      int    `json:"quota"` // quota 类型发放的额度，redemption 类型为兑换码面额
+	Weight     int    `json:"weight"`
+	DailyStock int    `json:"daily_stock"`
+}
 
 // CheckinSetting 签到功能配置
 type CheckinSetting struct {
-	Enabled  bool `json:"enabled"`   // 是否启用签到功能
-	MinQuota int  `json:"min_quota"` // 签到最小额度奖励
-	MaxQuota int  `json:"max_quota"` // 签到最大额度奖励
+	Enabled          bool                     `json:"enabled"`           // 是否启用签到功能
+	MinQuota         int                      `json:"min_quota"`         // 签到最小额度奖励
+	MaxQuota         int                      `json:"max_quota"`         // 签到最大额度奖励
+	StreakMilestones []CheckinStreakMilestone `json:"streak_milestones"` // 连签倍率，例如 7 天 2 倍、30 天 5 倍
+	TotalMilestones  []CheckinTotalMilestone  `json:"total_milestones"`  // 累计签到奖励，例如累计 10 天额外奖励
+
+	GroupRewards map[string]CheckinGroupReward `json:"group_rewards"` // 按用户分组覆盖签到奖励
+
+	MakeupEnabled      bool `json:"makeup_enabled"`       // 是否允许补签
+	MakeupCost         int  `json:"makeup_cost"`          // 每次补签扣除的额度
+	MakeupDays         int  `json:"makeup_days"`          // 可补签的最近天数（不含今天）
+	MakeupMonthlyLimit int  `json:"makeup_monthly_limit"` // 每月补签次数上限，0 表示不限
+
+	Timezone string `json:"timezone"` // 签到日期分界使用的 IANA 时区，如 Asia/Shanghai；留空使用服务器本地时区
+
+	ExpireDays int `json:"expire_days"` // 签到奖励额度的有效天数，0 表示永久有效
+
+	RequireCaptcha bool `json:"require_captcha"` // 每次签到都要求新的 Turnstile 校验，而不是复用会话内的校验结果
+
+	MaxAccountsPerIp int    `json:"max_accounts_per_ip"` // 同一 IP 每天允许签到的账号数，0 表示不限制
+	IpLimitAction    string `json:"ip_limit_action"`     // 超限处理方式：deny 拒绝签到，flag 照常发放但标记待复核
+
+	WebhookUrl    string `json:"webhook_url"`    // 签到事件推送地址，留空表示不推送
+	WebhookSecret string `json:"webhook_secret"` // 签到事件签名密钥，用于 X-Webhook-Signature
+	NotifyUser    bool   `json:"notify_user"`    // 是否通过用户自己配置的通知方式推送签到结果
+
+	ReminderEnabled   bool `json:"reminder_enabled"`    // 是否每天提醒近期签到过但今天还未签到的用户
+	ReminderHour      int  `json:"reminder_hour"`       // 提醒发送的整点（签到时区，0-23）
+	ReminderBatchSize int  `json:"reminder_batch_size"` // 每批提醒的用户数，批次之间会暂停，避免压垮邮件服务
+
+	LotteryEnabled bool           `json:"lottery_enabled"` // 开启后每日签到从奖品表抽奖，代替固定区间的随机额度
+	Prizes         []CheckinPrize `json:"prizes"`          // 抽奖奖品表
+
+	Promotions []CheckinPromotion `json:"promotions"` // 节假日等促销活动，日期范围不能重叠
+
+	RequiredRequests int `json:"required_requests"` // 当天至少成功调用多少次才能签到，0 表示不要求
+	RequiredTokens   int `json:"required_tokens"`   // 当天至少消耗多少 token 才能签到，0 表示不要求
+
+	ReferralMinInvitees  int     `json:"referral_min_invitees"`  // 邀请的活跃用户达到该人数后享受签到加成，0 表示关闭
+	ReferralMultiplier   float64 `json:"referral_multiplier"`    // 邀请加成倍率
+	InviterKickbackQuota int     `json:"inviter_kickback_quota"` // 被邀请用户签到时给邀请人的邀请额度奖励，0 表示关闭
 }
 
 // 默认配置
 var checkinSetting = CheckinSetting{
-	Enabled:  false, // 默认关闭
-	MinQuota: 1000,  // 默认最小额度 1000 (约 0.002 USD)
-	MaxQuota: 10000, // 默认最大额度 10000 (约 0.02 USD)
+	Enabled:            false, // 默认关闭
+	MinQuota:           1000,  // 默认最小额度 1000 (约 0.002 USD)
+	MaxQuota:           10000, // 默认最大额度 10000 (约 0.02 USD)
+	StreakMilestones:   []CheckinStreakMilestone{},
+	TotalMilestones:    []CheckinTotalMilestone{},
+	GroupRewards:       map[string]CheckinGroupReward{},
+	Prizes:             []CheckinPrize{},
+	Promotions:         []CheckinPromotion{},
+	MakeupEnabled:      false,
+	MakeupCost:         0,
+	MakeupDays:         7,
+	MakeupMonthlyLimit: 3,
+	IpLimitAction:      CheckinIpLimitActionDeny,
+	ReminderHour:       20,
+	ReminderBatchSize:  50,
 }
 
+// checkinLocation 缓存最近一次解析的签到时区，避免每次签到都重新加载时区数据
+var checkinLocation atomic.Pointer[time.Location]
+
 func init() {
 	// 注册到全局配置管理器
 	config.GlobalConfig.Register("checkin_setting", &checkinSetting)
@@ -31,7 +146,153 @@ func IsCheckinEnabled() bool {
 	return checkinSetting.Enabled
 }
 
-// GetCheckinQuotaRange 获取签到额度范围
-func GetCheckinQuotaRange() (min, max int) {
-	return checkinSetting.MinQuota, checkinSetting.MaxQuota
+// GetCheckinQuotaRange 获取指定用户分组生效的签到额度范围
+// 分组配置了区间时覆盖全局区间，再乘以分组倍率（限制在 (0, 100]）；
+// 负数按 0 处理，最大值小于最小值时视为固定额度，避免配置错误导致奖励异常
+func GetCheckinQuotaRange(group string) (min, max int) {
+	min, max = checkinSetting.MinQuota, checkinSetting.MaxQuota
+	multiplier := 1.0
+	if reward, ok := checkinSetting.GroupRewards[group]; ok {
+		if reward.MinQuota > 0 || reward.MaxQuota > 0 {
+			min, max = reward.MinQuota, reward.MaxQuota
+		}
+		if reward.Multiplier > 0 {
+			multiplier = reward.Multiplier
+		}
+	}
+	if min < 0 {
+		min = 0
+	}
+	if max < min {
+		max = min
+	}
+	if multiplier != 1 {
+		multiplier = math.Min(multiplier, maxCheckinMultiplier)
+		min = common.QuotaFromFloat(float64(min) * multiplier)
+		max = common.QuotaFromFloat(float64(max) * multiplier)
+	}
+	return min, max
+}
+
+// GetCheckinStreakMultiplier 获取连签天数对应的奖励倍率
+// 取已达到的最高里程碑；未配置或未达到任何里程碑时为 1，倍率限制在 [1, 100]
+func GetCheckinStreakMultiplier(streak int) float64 {
+	multiplier := 1.0
+	reachedDays := 0
+	for _, milestone := range checkinSetting.StreakMilestones {
+		if milestone.Days <= 0 || streak < milestone.Days || milestone.Days < reachedDays {
+			continue
+		}
+		reachedDays = milestone.Days
+		multiplier = milestone.Multiplier
+	}
+	if multiplier < 1 {
+		return 1
+	}
+	if multiplier > maxCheckinMultiplier {
+		return maxCheckinMultiplier
+	}
+	return multiplier
+}
+
+// IsCheckinStreakMilestone 连签天数是否恰好达到某个已配置的连签里程碑
+func IsCheckinStreakMilestone(streak int) bool {
+	for _, milestone := range checkinSetting.StreakMilestones {
+		if milestone.Days > 0 && milestone.Days == streak {
+			return true
+		}
+	}
+	return false
+}
+
+// GetCheckinReferralMultiplier 根据邀请的活跃用户数获取签到加成倍率，未开启或未达标时为 1，倍率限制在 [1, 100]
+func GetCheckinReferralMultiplier(activeInvitees int) float64 {
+	if checkinSetting.ReferralMinInvitees <= 0 || activeInvitees < checkinSetting.ReferralMinInvitees {
+		return 1
+	}
+	return math.Min(math.Max(checkinSetting.ReferralMultiplier, 1), maxCheckinMultiplier)
+}
+
+// GetNextCheckinTotalMilestone 获取累计签到 total 天之后的下一个里程碑，没有时返回 nil
+func GetNextCheckinTotalMilestone(total int) *CheckinTotalMilestone {
+	var next *CheckinTotalMilestone
+	for i := range checkinSetting.TotalMilestones {
+		milestone := &checkinSetting.TotalMilestones[i]
+		if milestone.Days <= total || milestone.Quota <= 0 {
+			continue
+		}
+		if next == nil || milestone.Days < next.Days {
+			next = milestone
+		}
+	}
+	if next == nil {
+		return nil
+	}
+	result := *next
+	return &result
+}
+
+// GetActiveCheckinPromotion 获取 date（YYYY-MM-DD）当天生效的促销活动，没有时返回 nil
+// 返回的倍率已限制在 [1, 100]
+func GetActiveCheckinPromotion(date string) *CheckinPromotion {
+	for _, promotion := range checkinSetting.Promotions {
+		if promotion.StartDate <= date && date <= promotion.EndDate {
+			result := promotion
+			result.Multiplier = math.Min(math.Max(result.Multiplier, 1), maxCheckinMultiplier)
+			result.BonusQuota = max(result.BonusQuota, 0)
+			return &result
+		}
+	}
+	return nil
+}
+
+// ValidateCheckinPromotions 校验促销活动配置：日期格式、起止顺序、倍率和奖励范围，以及日期范围互不重叠
+func ValidateCheckinPromotions(promotions []CheckinPromotion) error {
+	sorted := make([]CheckinPromotion, len(promotions))
+	copy(sorted, promotions)
+	for _, promotion := range sorted {
+		start, startErr := time.Parse("2006-01-02", promotion.StartDate)
+		end, endErr := time.Parse("2006-01-02", promotion.EndDate)
+		if startErr != nil || endErr != nil {
+			return fmt.Errorf("促销活动 %q 的日期格式应为 YYYY-MM-DD", promotion.Name)
+		}
+		if end.Before(start) {
+			return fmt.Errorf("促销活动 %q 的结束日期早于开始日期", promotion.Name)
+		}
+		if promotion.Multiplier < 0 || promotion.Multiplier > maxCheckinMultiplier || promotion.BonusQuota < 0 {
+			return fmt.Errorf("促销活动 %q 的倍率应在 0-%d 之间，额外奖励不能为负数", promotion.Name, maxCheckinMultiplier)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].StartDate < sorted[j].StartDate
+	})
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].StartDate <= sorted[i-1].EndDate {
+			return fmt.Errorf("促销活动 %q 与 %q 的日期范围重叠", sorted[i-1].Name, sorted[i].Name)
+		}
+	}
+	return nil
+}
+
+// GetCheckinLocation 获取签到使用的时区，未配置或配置无效时回退到服务器本地时区
+func GetCheckinLocation() *time.Location {
+	name := checkinSetting.Timezone
+	if name == "" {
+		return time.Local
+	}
+	if cached := checkinLocation.Load(); cached != nil && cached.String() == name {
+		return cached
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.Local
+	}
+	checkinLocation.Store(loc)
+	return loc
+}
+
+// CheckinNow 返回签到时区下的当前时间
+// 签到日期、连签和补签窗口都以此为准，使不同时区部署的节点对“今天”的判断一致
+func CheckinNow() time.Time {
+	return time.Now().In(GetCheckinLocation())
 }
@@ -0,0 +1,163 @@
+package operation_setting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetCheckinStreakMultiplier(t *testing.T) {
+	orig := checkinSetting
+	t.Cleanup(func() { checkinSetting = orig })
+
+	checkinSetting.StreakMilestones = []CheckinStreakMilestone{
+		{Days: 30, Multiplier: 5},
+		{Days: 7, Multiplier: 2},
+		{Days: 100, Multiplier: 1000},
+		{Days: 3, Multiplier: 0.5},
+	}
+
+	tests := []struct {
+		name   string
+		streak int
+		want   float64
+	}{
+		{name: "below every milestone", streak: 1, want: 1},
+		{name: "multiplier below one is ignored", streak: 3, want: 1},
+		{name: "reached first real milestone", streak: 7, want: 2},
+		{name: "highest reached milestone wins regardless of order", streak: 45, want: 5},
+		{name: "multiplier is capped", streak: 100, want: maxCheckinMultiplier},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, GetCheckinStreakMultiplier(tt.streak))
+		})
+	}
+}
+
+func TestGetCheckinLocation(t *testing.T) {
+	orig := checkinSetting
+	t.Cleanup(func() { checkinSetting = orig })
+
+	tests := []struct {
+		name     string
+		timezone string
+		want     string
+	}{
+		{name: "empty uses server local time", timezone: "", want: time.Local.String()},
+		{name: "valid IANA name", timezone: "Asia/Shanghai", want: "Asia/Shanghai"},
+		{name: "invalid name falls back to local", timezone: "Mars/Olympus", want: time.Local.String()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkinSetting.Timezone = tt.timezone
+			assert.Equal(t, tt.want, GetCheckinLocation().String())
+			assert.Equal(t, tt.want, CheckinNow().Location().String())
+		})
+	}
+}
+
+func TestCheckinTotalMilestones(t *testing.T) {
+	orig := checkinSetting
+	t.Cleanup(func() { checkinSetting = orig })
+
+	checkinSetting.TotalMilestones = []CheckinTotalMilestone{
+		{Days: 30, Quota: 3000},
+		{Days: 10, Quota: 1000},
+		{Days: 20, Quota: 0},
+	}
+
+	assert.Equal(t, &CheckinTotalMilestone{Days: 10, Quota: 1000}, GetNextCheckinTotalMilestone(0))
+	assert.Equal(t, &CheckinTotalMilestone{Days: 30, Quota: 3000}, GetNextCheckinTotalMilestone(10))
+	assert.Nil(t, GetNextCheckinTotalMilestone(30))
+}
+
+func TestGetCheckinQuotaRange(t *testing.T) {
+	orig := checkinSetting
+	t.Cleanup(func() { checkinSetting = orig })
+
+	checkinSetting.MinQuota = 100
+	checkinSetting.MaxQuota = 200
+	checkinSetting.GroupRewards = map[string]CheckinGroupReward{
+		"vip":      {MinQuota: 500, MaxQuota: 800},
+		"svip":     {MinQuota: 500, MaxQuota: 800, Multiplier: 1.5},
+		"boosted":  {Multiplier: 3},
+		"inverted": {MinQuota: 900, MaxQuota: 100},
+		"huge":     {Multiplier: 1e6},
+	}
+
+	tests := []struct {
+		group   string
+		wantMin int
+		wantMax int
+	}{
+		{group: "default", wantMin: 100, wantMax: 200},
+		{group: "vip", wantMin: 500, wantMax: 800},
+		{group: "svip", wantMin: 750, wantMax: 1200},
+		{group: "boosted", wantMin: 300, wantMax: 600},
+		{group: "inverted", wantMin: 900, wantMax: 900},
+		{group: "huge", wantMin: 100 * maxCheckinMultiplier, wantMax: 200 * maxCheckinMultiplier},
+	}
+	for _, tt := range tests {
+		t.Run(tt.group, func(t *testing.T) {
+			gotMin, gotMax := GetCheckinQuotaRange(tt.group)
+			assert.Equal(t, tt.wantMin, gotMin)
+			assert.Equal(t, tt.wantMax, gotMax)
+		})
+	}
+}
+
+func TestValidateCheckinPromotions(t *testing.T) {
+	tests := []struct {
+		name       string
+		promotions []CheckinPromotion
+		wantErr    bool
+	}{
+		{name: "empty", promotions: nil},
+		{name: "disjoint ranges in any order", promotions: []CheckinPromotion{
+			{Name: "national day", StartDate: "2026-10-01", EndDate: "2026-10-07", Multiplier: 2},
+			{Name: "new year", StartDate: "2026-02-16", EndDate: "2026-02-22", BonusQuota: 1000},
+		}},
+		{name: "bad date", promotions: []CheckinPromotion{{Name: "x", StartDate: "2026-2-1", EndDate: "2026-02-03"}}, wantErr: true},
+		{name: "end before start", promotions: []CheckinPromotion{{Name: "x", StartDate: "2026-02-03", EndDate: "2026-02-01"}}, wantErr: true},
+		{name: "multiplier too large", promotions: []CheckinPromotion{{Name: "x", StartDate: "2026-02-01", EndDate: "2026-02-01", Multiplier: 1000}}, wantErr: true},
+		{name: "negative bonus", promotions: []CheckinPromotion{{Name: "x", StartDate: "2026-02-01", EndDate: "2026-02-01", BonusQuota: -1}}, wantErr: true},
+		{name: "overlap on boundary day", promotions: []CheckinPromotion{
+			{Name: "a", StartDate: "2026-02-01", EndDate: "2026-02-10"},
+			{Name: "b", StartDate: "2026-02-10", EndDate: "2026-02-12"},
+		}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCheckinPromotions(tt.promotions)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestGetActiveCheckinPromotion(t *testing.T) {
+	orig := checkinSetting
+	t.Cleanup(func() { checkinSetting = orig })
+
+	checkinSetting.Promotions = []CheckinPromotion{
+		{Name: "bonus only", StartDate: "2026-02-16", EndDate: "2026-02-22", BonusQuota: 500},
+		{Name: "double", StartDate: "2026-10-01", EndDate: "2026-10-07", Multiplier: 2},
+	}
+
+	assert.Nil(t, GetActiveCheckinPromotion("2026-02-15"))
+	promotion := GetActiveCheckinPromotion("2026-02-22")
+	if assert.NotNil(t, promotion) {
+		assert.Equal(t, "bonus only", promotion.Name)
+		assert.Equal(t, 1.0, promotion.Multiplier)
+		assert.Equal(t, 500, promotion.BonusQuota)
+	}
+	promotion = GetActiveCheckinPromotion("2026-10-01")
+	if assert.NotNil(t, promotion) {
+		assert.Equal(t, 2.0, promotion.Multiplier)
+	}
+}
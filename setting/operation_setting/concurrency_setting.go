@@ -0,0 +1,43 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+const (
+	ConcurrencyModeReject = "reject"
+	ConcurrencyModeQueue  = "queue"
+)
+
+// ConcurrencySetting 流式请求并发上限，令牌上限在令牌上单独配置
+type ConcurrencySetting struct {
+	Enabled             bool           `json:"enabled"`
+	DefaultUserLimit    int            `json:"default_user_limit"`    // 单个用户同时进行的流式请求数上限，0 表示不限制
+	GroupLimits         map[string]int `json:"group_limits"`          // 按用户分组覆盖 DefaultUserLimit
+	Mode                string         `json:"mode"`                  // 达到上限时拒绝（reject）或排队等待（queue）
+	QueueTimeoutSeconds int            `json:"queue_timeout_seconds"` // 排队模式下最长等待时间
+}
+
+// 默认配置
+var concurrencySetting = ConcurrencySetting{
+	Enabled:             false,
+	DefaultUserLimit:    0,
+	GroupLimits:         map[string]int{},
+	Mode:                ConcurrencyModeReject,
+	QueueTimeoutSeconds: 30,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("concurrency_setting", &concurrencySetting)
+}
+
+func GetConcurrencySetting() *ConcurrencySetting {
+	return &concurrencySetting
+}
+
+// GetUserConcurrencyLimit 获取指定用户分组的流式并发上限，0 表示不限制
+func GetUserConcurrencyLimit(group string) int {
+	if limit, ok := concurrencySetting.GroupLimits[group]; ok {
+		return max(limit, 0)
+	}
+	return max(concurrencySetting.DefaultUserLimit, 0)
+}
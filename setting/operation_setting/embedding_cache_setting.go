@@ -0,0 +1,29 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+type EmbeddingCacheSetting struct {
+	Enabled       bool    `json:"enabled"`
+	TTLSeconds    int     `json:"ttl_seconds"`     // 缓存有效期（秒）
+	MaxEntries    int     `json:"max_entries"`     // 未启用 Redis 时内存缓存的最大条目数，超出按 LRU 淘汰，修改后重启生效
+	MaxEntryKB    int     `json:"max_entry_kb"`    // 单个响应超过该大小（KB）时不缓存
+	HitPriceRatio float64 `json:"hit_price_ratio"` // 命中缓存时按原价的倍率计费，0 表示免费
+}
+
+// 默认配置
+var embeddingCacheSetting = EmbeddingCacheSetting{
+	Enabled:       false,
+	TTLSeconds:    24 * 3600,
+	MaxEntries:    10000,
+	MaxEntryKB:    1024,
+	HitPriceRatio: 0,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("embedding_cache_setting", &embeddingCacheSetting)
+}
+
+func GetEmbeddingCacheSetting() *EmbeddingCacheSetting {
+	return &embeddingCacheSetting
+}
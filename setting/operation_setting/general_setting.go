@@ -1,6 +1,9 @@
 package operation_setting
 
-import "github.com/QuantumNous/new-api/setting/config"
+import (
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/config"
+)
 
 // 额度展示类型
 const (
@@ -20,6 +23,8 @@ type GeneralSetting struct {
 	CustomCurrencySymbol string `json:"custom_currency_symbol"`
 	// 自定义货币与美元汇率（1 USD = X Custom）
 	CustomCurrencyExchangeRate float64 `json:"custom_currency_exchange_rate"`
+	// 货币金额展示的小数位数，作用于日志、签到提示、价格接口和看板（TOKENS 展示类型不适用）
+	CurrencyDecimalPlaces int `json:"currency_decimal_places"`
 }
 
 // 默认配置
@@ -30,8 +35,12 @@ var generalSetting = GeneralSetting{
 	QuotaDisplayType:           QuotaDisplayTypeUSD,
 	CustomCurrencySymbol:       "¤",
 	CustomCurrencyExchangeRate: 1.0,
+	CurrencyDecimalPlaces:      6,
 }
 
+// 小数位数上限，超过后浮点误差会直接显示出来
+const MaxCurrencyDecimalPlaces = 10
+
 func init() {
 	// 注册到全局配置管理器
 	config.GlobalConfig.Register("general_setting", &generalSetting)
@@ -89,3 +98,19 @@ func GetUsdToCurrencyRate(usdToCny float64) float64 {
 		return 1
 	}
 }
+
+// GetCurrencyDecimalPlaces 返回货币金额展示的小数位数
+func GetCurrencyDecimalPlaces() int {
+	if generalSetting.CurrencyDecimalPlaces < 0 {
+		return 0
+	}
+	return min(generalSetting.CurrencyDecimalPlaces, MaxCurrencyDecimalPlaces)
+}
+
+// QuotaToDisplayAmount 将额度换算为当前展示类型下的金额，TOKENS 展示类型返回额度本身
+func QuotaToDisplayAmount(quota float64) float64 {
+	if generalSetting.QuotaDisplayType == QuotaDisplayTypeTokens {
+		return quota
+	}
+	return quota / common.QuotaPerUnit * GetUsdToCurrencyRate(USDExchangeRate)
+}
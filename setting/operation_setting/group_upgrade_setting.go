@@ -0,0 +1,37 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// GroupUpgradeRule 累计消费达到 MinSpend（美元）时升级到 Group
+type GroupUpgradeRule struct {
+	MinSpend float64 `json:"min_spend"`
+	Group    string  `json:"group"`
+}
+
+// GroupUpgradeSetting 按累计消费自动调整用户分组，只处理当前位于 BaseGroup 或规则分组内的用户，
+// 手动设置或订阅授予的其他分组不受影响
+type GroupUpgradeSetting struct {
+	Enabled        bool               `json:"enabled"`
+	BaseGroup      string             `json:"base_group"`      // 未达到任何门槛时所在的分组
+	Rules          []GroupUpgradeRule `json:"rules"`           // 消费门槛与目标分组
+	AllowDowngrade bool               `json:"allow_downgrade"` // 门槛调高或累计消费被重置后是否降级
+	NotifyUser     bool               `json:"notify_user"`     // 分组变更后通知用户
+}
+
+// 默认配置
+var groupUpgradeSetting = GroupUpgradeSetting{
+	Enabled:        false,
+	BaseGroup:      "default",
+	Rules:          []GroupUpgradeRule{},
+	AllowDowngrade: false,
+	NotifyUser:     true,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("group_upgrade_setting", &groupUpgradeSetting)
+}
+
+func GetGroupUpgradeSetting() *GroupUpgradeSetting {
+	return &groupUpgradeSetting
+}
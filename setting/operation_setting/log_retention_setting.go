@@ -0,0 +1,35 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+type LogRetentionSetting struct {
+	ConsumeRetentionDays int `json:"consume_retention_days"` // 消费日志保留天数，0 表示永久保留
+	ErrorRetentionDays   int `json:"error_retention_days"`   // 错误日志保留天数，0 表示永久保留
+	// 开启后过期日志按天导出为 gzip JSONL 上传到 S3 兼容存储，上传成功后才删除
+	ArchiveEnabled bool   `json:"archive_enabled"`
+	S3Endpoint     string `json:"s3_endpoint"` // 如 https://s3.amazonaws.com 或 MinIO 地址
+	S3Region       string `json:"s3_region"`
+	S3Bucket       string `json:"s3_bucket"`
+	S3Prefix       string `json:"s3_prefix"`
+	S3AccessKeyId  string `json:"s3_access_key_id"`
+	S3AccessSecret string `json:"s3_access_secret"`
+	S3PathStyle    bool   `json:"s3_path_style"` // MinIO 等自建存储通常需要 path-style 访问
+}
+
+// 默认配置
+var logRetentionSetting = LogRetentionSetting{
+	ConsumeRetentionDays: 0,
+	ErrorRetentionDays:   0,
+	ArchiveEnabled:       false,
+	S3Region:             "us-east-1",
+	S3Prefix:             "new-api-logs",
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("log_retention_setting", &logRetentionSetting)
+}
+
+func GetLogRetentionSetting() *LogRetentionSetting {
+	return &logRetentionSetting
+}
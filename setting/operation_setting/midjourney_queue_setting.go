@@ -0,0 +1,32 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// MidjourneyQueueSetting Midjourney 任务的用户并发上限与公平排队
+type MidjourneyQueueSetting struct {
+	Enabled                 bool `json:"enabled"`                    // 同时控制排队与卡住任务回收
+	MaxUserRunningTasks     int  `json:"max_user_running_tasks"`     // 单个用户同时进行中的任务数上限，0 表示不限制
+	MaxRunningTasks         int  `json:"max_running_tasks"`          // 全站同时进行中的任务数上限，0 表示不限制
+	MaxUserQueuedTasks      int  `json:"max_user_queued_tasks"`      // 单个用户最多排队等待的提交数，0 表示不排队直接拒绝
+	QueueTimeoutSeconds     int  `json:"queue_timeout_seconds"`      // 排队最长等待时间
+	StuckTaskTimeoutMinutes int  `json:"stuck_task_timeout_minutes"` // 任务提交后超过该时长仍未完成则判定失败并退款，0 表示不回收
+}
+
+// 默认配置
+var midjourneyQueueSetting = MidjourneyQueueSetting{
+	Enabled:                 false,
+	MaxUserRunningTasks:     3,
+	MaxRunningTasks:         0,
+	MaxUserQueuedTasks:      5,
+	QueueTimeoutSeconds:     60,
+	StuckTaskTimeoutMinutes: 60,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("midjourney_queue_setting", &midjourneyQueueSetting)
+}
+
+func GetMidjourneyQueueSetting() *MidjourneyQueueSetting {
+	return &midjourneyQueueSetting
+}
@@ -0,0 +1,117 @@
+package operation_setting
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sync"
+
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// 内容审核命中后的处理动作
+const (
+	ModerationActionOff    = "off"    // 不审核
+	ModerationActionBlock  = "block"  // 拒绝请求
+	ModerationActionLog    = "log"    // 仅记录命中，放行请求
+	ModerationActionRedact = "redact" // 将命中的违禁词/正则片段替换后放行，审核模型命中时仍拒绝
+)
+
+type ModerationSetting struct {
+	Enabled bool `json:"enabled"`
+	// 默认处理动作，GroupActions 可按分组覆盖，值为 off 时该分组不审核
+	Action       string            `json:"action"`
+	GroupActions map[string]string `json:"group_actions"`
+	BannedWords  []string          `json:"banned_words"` // 不区分大小写
+	RegexRules   []string          `json:"regex_rules"`  // Go 正则语法，可用 (?i) 忽略大小写
+	Replacement  string            `json:"replacement"`  // redact 动作的替换文本
+	// 调用 OpenAI 兼容的 /v1/moderations 接口进行审核，调用失败时放行
+	ModelEnabled        bool   `json:"model_enabled"`
+	ModelBaseURL        string `json:"model_base_url"`
+	ModelAPIKey         string `json:"model_api_key"`
+	ModelName           string `json:"model_name"`
+	ModelTimeoutSeconds int    `json:"model_timeout_seconds"`
+	HitRetentionDays    int    `json:"hit_retention_days"` // 命中记录保留天数，0 表示永久保留
+}
+
+// 默认配置
+var moderationSetting = ModerationSetting{
+	Enabled:             false,
+	Action:              ModerationActionBlock,
+	GroupActions:        map[string]string{},
+	BannedWords:         []string{},
+	RegexRules:          []string{},
+	Replacement:         "***",
+	ModelEnabled:        false,
+	ModelBaseURL:        "https://api.openai.com",
+	ModelName:           "omni-moderation-latest",
+	ModelTimeoutSeconds: 5,
+	HitRetentionDays:    90,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("moderation_setting", &moderationSetting)
+}
+
+func GetModerationSetting() *ModerationSetting {
+	return &moderationSetting
+}
+
+// IsValidModerationAction 判断处理动作是否合法
+func IsValidModerationAction(action string) bool {
+	switch action {
+	case ModerationActionOff, ModerationActionBlock, ModerationActionLog, ModerationActionRedact:
+		return true
+	}
+	return false
+}
+
+// GetModerationAction 返回分组的处理动作，未开启审核时返回 off
+func GetModerationAction(group string) string {
+	if !moderationSetting.Enabled {
+		return ModerationActionOff
+	}
+	if action, ok := moderationSetting.GroupActions[group]; ok && IsValidModerationAction(action) {
+		return action
+	}
+	if IsValidModerationAction(moderationSetting.Action) {
+		return moderationSetting.Action
+	}
+	return ModerationActionBlock
+}
+
+var (
+	moderationRegexMu    sync.Mutex
+	moderationRegexRules []string
+	moderationRegexCache []*regexp.Regexp
+)
+
+// GetModerationRegexps 返回编译后的正则规则，规则变更后重新编译，无法编译的规则被忽略
+func GetModerationRegexps() []*regexp.Regexp {
+	moderationRegexMu.Lock()
+	defer moderationRegexMu.Unlock()
+	rules := moderationSetting.RegexRules
+	if slices.Equal(rules, moderationRegexRules) && moderationRegexCache != nil {
+		return moderationRegexCache
+	}
+	compiled := make([]*regexp.Regexp, 0, len(rules))
+	for _, rule := range rules {
+		if re, err := regexp.Compile(rule); err == nil {
+			compiled = append(compiled, re)
+		}
+	}
+	moderationRegexRules = append([]string(nil), rules...)
+	moderationRegexCache = compiled
+	return compiled
+}
+
+// ValidateModerationRegexRules 校验正则规则是否都能编译
+func ValidateModerationRegexRules(rules []string) error {
+	for _, rule := range rules {
+		if _, err := regexp.Compile(rule); err != nil {
+			return fmt.Errorf("正则规则 %q 无效: %v", rule, err)
+		}
+	}
+	return nil
+}
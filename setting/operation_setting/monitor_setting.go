@@ -11,6 +11,9 @@ type MonitorSetting struct {
 	AutoTestChannelEnabled bool    `json:"auto_test_channel_enabled"`
 	AutoTestChannelMinutes float64 `json:"auto_test_channel_minutes"`
 	ChannelTestMode        string  `json:"channel_test_mode"`
+	FailureThreshold       int     `json:"failure_threshold"`     // consecutive failed probes before auto-disable; below 1 disables on the first failure
+	HealthRetentionDays    int     `json:"health_retention_days"` // days of channel health history to keep; 0 keeps it forever
+	ModelStatusPublic      bool    `json:"model_status_public"`   // serve /api/status/models without a token
 }
 
 const (
@@ -23,6 +26,8 @@ var monitorSetting = MonitorSetting{
 	AutoTestChannelEnabled: false,
 	AutoTestChannelMinutes: 10,
 	ChannelTestMode:        ChannelTestModeScheduledAll,
+	FailureThreshold:       1,
+	HealthRetentionDays:    7,
 }
 
 func init() {
@@ -0,0 +1,32 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// QuotaTransferSetting 用户之间转账额度的配置，额度均为内部额度单位
+type QuotaTransferSetting struct {
+	Enabled    bool    `json:"enabled"`
+	MinAmount  int     `json:"min_amount"`  // 单笔最少转出额度
+	MaxAmount  int     `json:"max_amount"`  // 单笔最多转出额度，0 表示不限制
+	DailyLimit int     `json:"daily_limit"` // 每人每日累计转出额度上限，0 表示不限制
+	DailyCount int     `json:"daily_count"` // 每人每日转出次数上限，0 表示不限制
+	FeePercent float64 `json:"fee_percent"` // 手续费百分比（0-100），从转出额度中扣除，接收方实收转出额度减手续费
+}
+
+// 默认配置
+var quotaTransferSetting = QuotaTransferSetting{
+	Enabled:    false,
+	MinAmount:  500000,
+	MaxAmount:  0,
+	DailyLimit: 0,
+	DailyCount: 10,
+	FeePercent: 0,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("quota_transfer_setting", &quotaTransferSetting)
+}
+
+func GetQuotaTransferSetting() *QuotaTransferSetting {
+	return &quotaTransferSetting
+}
@@ -0,0 +1,24 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// RealtimeSetting Realtime（WebSocket）会话配置
+type RealtimeSetting struct {
+	MaxSessionsPerUser int `json:"max_sessions_per_user"` // 单个用户同时保持的 Realtime 会话数上限，0 表示不限制
+	IdleTimeoutSeconds int `json:"idle_timeout_seconds"`  // 双向均无消息超过该时长后关闭会话，0 表示不限制
+}
+
+// 默认配置
+var realtimeSetting = RealtimeSetting{
+	MaxSessionsPerUser: 0,
+	IdleTimeoutSeconds: 300,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("realtime_setting", &realtimeSetting)
+}
+
+func GetRealtimeSetting() *RealtimeSetting {
+	return &realtimeSetting
+}
@@ -0,0 +1,22 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+type StatementSetting struct {
+	// 开启后每月初由主节点自动为上月有活动的用户生成月度账单
+	AutoGenerateEnabled bool `json:"auto_generate_enabled"`
+}
+
+// 默认配置
+var statementSetting = StatementSetting{
+	AutoGenerateEnabled: true,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("statement_setting", &statementSetting)
+}
+
+func GetStatementSetting() *StatementSetting {
+	return &statementSetting
+}
@@ -78,10 +78,15 @@ func IsAlwaysSkipRetryCode(errorCode types.ErrorCode) bool {
 }
 
 func ShouldRetryByStatusCode(code int) bool {
+	return ShouldRetryByStatusCodeRanges(AutomaticRetryStatusCodeRanges, code)
+}
+
+// ShouldRetryByStatusCodeRanges 按指定范围判断状态码是否可重试，始终不重试的状态码优先
+func ShouldRetryByStatusCodeRanges(ranges []StatusCodeRange, code int) bool {
 	if IsAlwaysSkipRetryStatusCode(code) {
 		return false
 	}
-	return shouldMatchStatusCodeRanges(AutomaticRetryStatusCodeRanges, code)
+	return shouldMatchStatusCodeRanges(ranges, code)
 }
 
 func statusCodeRangesToString(ranges []StatusCodeRange) string {
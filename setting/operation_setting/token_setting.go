@@ -4,12 +4,17 @@ import "github.com/QuantumNous/new-api/setting/config"
 
 // TokenSetting 令牌相关配置
 type TokenSetting struct {
-	MaxUserTokens int `json:"max_user_tokens"` // 每用户最大令牌数量
+	MaxUserTokens        int `json:"max_user_tokens"`        // 每用户最大令牌数量
+	RotationGraceSeconds int `json:"rotation_grace_seconds"` // 轮换密钥时旧密钥默认保留的秒数
 }
 
+// MaxTokenRotationGraceSeconds 轮换宽限期上限（30 天）
+const MaxTokenRotationGraceSeconds = 30 * 24 * 3600
+
 // 默认配置
 var tokenSetting = TokenSetting{
-	MaxUserTokens: 1000, // 默认每用户最多 1000 个令牌
+	MaxUserTokens:        1000,  // 默认每用户最多 1000 个令牌
+	RotationGraceSeconds: 86400, // 默认旧密钥保留 24 小时
 }
 
 func init() {
@@ -0,0 +1,23 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+type UpstreamErrorSetting struct {
+	NormalizeEnabled bool `json:"normalize_enabled"` // 将上游错误归一化为稳定的 OpenAI 风格错误类型与错误码
+	HideMessage      bool `json:"hide_message"`      // 归一化时不返回上游原始错误信息，统一替换为通用提示
+}
+
+// 默认配置
+var upstreamErrorSetting = UpstreamErrorSetting{
+	NormalizeEnabled: false,
+	HideMessage:      false,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("upstream_error_setting", &upstreamErrorSetting)
+}
+
+func GetUpstreamErrorSetting() *UpstreamErrorSetting {
+	return &upstreamErrorSetting
+}
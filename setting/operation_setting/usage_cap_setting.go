@@ -0,0 +1,117 @@
+package operation_setting
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+const (
+	UsageCapPeriodDay  = "day"
+	UsageCapPeriodWeek = "week"
+)
+
+// UsageCapRule 某个用户分组在一个周期内对某个模型的用量上限
+type UsageCapRule struct {
+	Group       string `json:"group"`        // 用户分组
+	Model       string `json:"model"`        // 模型名，以 * 结尾时按前缀匹配，匹配到的模型共享同一计数
+	Period      string `json:"period"`       // day 或 week，周从周一开始
+	MaxRequests int64  `json:"max_requests"` // 周期内最多请求次数，0 表示不限制
+	MaxTokens   int64  `json:"max_tokens"`   // 周期内最多消耗的 token 数，0 表示不限制
+}
+
+// UsageCapSetting 按分组和模型的日/周用量上限，与余额额度相互独立
+type UsageCapSetting struct {
+	Enabled  bool           `json:"enabled"`
+	Timezone string         `json:"timezone"` // 周期分界使用的 IANA 时区，如 Asia/Shanghai；留空使用服务器本地时区
+	Rules    []UsageCapRule `json:"rules"`
+}
+
+// 默认配置
+var usageCapSetting = UsageCapSetting{
+	Enabled:  false,
+	Timezone: "",
+	Rules:    []UsageCapRule{},
+}
+
+var usageCapLocation atomic.Pointer[time.Location]
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("usage_cap_setting", &usageCapSetting)
+}
+
+func GetUsageCapSetting() *UsageCapSetting {
+	return &usageCapSetting
+}
+
+// GetUsageCapRules 返回适用于指定分组和模型的用量上限规则
+func GetUsageCapRules(group string, model string) []UsageCapRule {
+	if !usageCapSetting.Enabled {
+		return nil
+	}
+	var rules []UsageCapRule
+	for _, rule := range usageCapSetting.Rules {
+		if rule.Group != group {
+			continue
+		}
+		if prefix, ok := strings.CutSuffix(rule.Model, "*"); ok && strings.HasPrefix(model, prefix) || rule.Model == model {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// ValidateUsageCapRules 校验用量上限规则，同一分组、模型与周期只能配置一条
+func ValidateUsageCapRules(rules []UsageCapRule) error {
+	seen := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		if rule.Group == "" || rule.Model == "" {
+			return fmt.Errorf("用量上限规则的分组和模型不能为空")
+		}
+		if rule.Period != UsageCapPeriodDay && rule.Period != UsageCapPeriodWeek {
+			return fmt.Errorf("分组 %s 模型 %s 的周期只能是 day 或 week", rule.Group, rule.Model)
+		}
+		if rule.MaxRequests < 0 || rule.MaxTokens < 0 || (rule.MaxRequests == 0 && rule.MaxTokens == 0) {
+			return fmt.Errorf("分组 %s 模型 %s 的请求次数和 token 上限不能为负数，且至少设置一项", rule.Group, rule.Model)
+		}
+		key := rule.Group + "|" + rule.Model + "|" + rule.Period
+		if seen[key] {
+			return fmt.Errorf("分组 %s 模型 %s 的 %s 上限重复配置", rule.Group, rule.Model, rule.Period)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// GetUsageCapLocation 获取用量上限周期使用的时区，未配置或配置无效时回退到服务器本地时区
+func GetUsageCapLocation() *time.Location {
+	name := usageCapSetting.Timezone
+	if name == "" {
+		return time.Local
+	}
+	if cached := usageCapLocation.Load(); cached != nil && cached.String() == name {
+		return cached
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.Local
+	}
+	usageCapLocation.Store(loc)
+	return loc
+}
+
+// UsageCapWindow 返回 now 所在周期的起止时间
+func UsageCapWindow(period string, now time.Time) (time.Time, time.Time) {
+	now = now.In(GetUsageCapLocation())
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if period == UsageCapPeriodWeek {
+		// 周一为一周的第一天
+		start = start.AddDate(0, 0, -(int(start.Weekday())+6)%7)
+		return start, start.AddDate(0, 0, 7)
+	}
+	return start, start.AddDate(0, 0, 1)
+}
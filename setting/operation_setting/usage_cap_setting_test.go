@@ -0,0 +1,61 @@
+package operation_setting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsageCapWindow(t *testing.T) {
+	orig := usageCapSetting
+	t.Cleanup(func() { usageCapSetting = orig })
+	usageCapSetting.Timezone = "Asia/Shanghai"
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		period    string
+		now       time.Time
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{
+			name:      "day boundary follows the configured timezone",
+			period:    UsageCapPeriodDay,
+			now:       time.Date(2026, 10, 14, 17, 30, 0, 0, time.UTC),
+			wantStart: time.Date(2026, 10, 15, 0, 0, 0, 0, loc),
+			wantEnd:   time.Date(2026, 10, 16, 0, 0, 0, 0, loc),
+		},
+		{
+			name:      "week starts on monday",
+			period:    UsageCapPeriodWeek,
+			now:       time.Date(2026, 10, 18, 23, 0, 0, 0, loc),
+			wantStart: time.Date(2026, 10, 12, 0, 0, 0, 0, loc),
+			wantEnd:   time.Date(2026, 10, 19, 0, 0, 0, 0, loc),
+		},
+		{
+			name:      "monday is the first day of its week",
+			period:    UsageCapPeriodWeek,
+			now:       time.Date(2026, 10, 19, 0, 0, 0, 0, loc),
+			wantStart: time.Date(2026, 10, 19, 0, 0, 0, 0, loc),
+			wantEnd:   time.Date(2026, 10, 26, 0, 0, 0, 0, loc),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := UsageCapWindow(tt.period, tt.now)
+			assert.True(t, tt.wantStart.Equal(start), "start %s", start)
+			assert.True(t, tt.wantEnd.Equal(end), "end %s", end)
+		})
+	}
+}
+
+func TestValidateUsageCapRules(t *testing.T) {
+	valid := UsageCapRule{Group: "free", Model: "gpt-4o", Period: UsageCapPeriodDay, MaxRequests: 50}
+	assert.NoError(t, ValidateUsageCapRules([]UsageCapRule{valid, {Group: "free", Model: "gpt-4o", Period: UsageCapPeriodWeek, MaxTokens: 1000}}))
+	assert.Error(t, ValidateUsageCapRules([]UsageCapRule{valid, valid}), "duplicate rule")
+	assert.Error(t, ValidateUsageCapRules([]UsageCapRule{{Group: "free", Model: "gpt-4o", Period: "month", MaxRequests: 1}}), "unknown period")
+	assert.Error(t, ValidateUsageCapRules([]UsageCapRule{{Group: "free", Model: "gpt-4o", Period: UsageCapPeriodDay}}), "no cap")
+}
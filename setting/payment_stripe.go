@@ -6,3 +6,6 @@ var StripePriceId = ""
 var StripeUnitPrice = 8.0
 var StripeMinTopUp = 1
 var StripePromotionCodesEnabled = false
+
+// StripeCurrency 未配置 StripePriceId 时按实际支付金额动态生成价格所使用的币种
+var StripeCurrency = "usd"
@@ -0,0 +1,45 @@
+package ratio_setting
+
+import (
+	"github.com/QuantumNous/new-api/types"
+)
+
+// audioSecondPriceMap 语音转写/翻译按输入音频时长计价，单位：美元/秒
+var audioSecondPriceMap = types.NewRWMap[string, float64]()
+
+// audioCharacterPriceMap 语音合成按输入文本字符数计价，单位：美元/字符
+var audioCharacterPriceMap = types.NewRWMap[string, float64]()
+
+func AudioSecondPrice2JSONString() string {
+	return audioSecondPriceMap.MarshalJSONString()
+}
+
+func UpdateAudioSecondPriceByJSONString(jsonStr string) error {
+	return types.LoadFromJsonStringWithCallback(audioSecondPriceMap, jsonStr, InvalidateExposedDataCache)
+}
+
+func AudioCharacterPrice2JSONString() string {
+	return audioCharacterPriceMap.MarshalJSONString()
+}
+
+func UpdateAudioCharacterPriceByJSONString(jsonStr string) error {
+	return types.LoadFromJsonStringWithCallback(audioCharacterPriceMap, jsonStr, InvalidateExposedDataCache)
+}
+
+// GetAudioSecondPrice 获取模型每秒音频的价格，未配置时返回 false
+func GetAudioSecondPrice(name string) (float64, bool) {
+	return audioSecondPriceMap.Get(FormatMatchingModelName(name))
+}
+
+// GetAudioCharacterPrice 获取模型每个字符的价格，未配置时返回 false
+func GetAudioCharacterPrice(name string) (float64, bool) {
+	return audioCharacterPriceMap.Get(FormatMatchingModelName(name))
+}
+
+func GetAudioSecondPriceCopy() map[string]float64 {
+	return audioSecondPriceMap.ReadAll()
+}
+
+func GetAudioCharacterPriceCopy() map[string]float64 {
+	return audioCharacterPriceMap.ReadAll()
+}
@@ -130,6 +130,9 @@ var defaultCreateCacheRatio = map[string]float64{
 var cacheRatioMap = types.NewRWMap[string, float64]()
 var createCacheRatioMap = types.NewRWMap[string, float64]()
 
+// createCache1hRatioMap 1 小时缓存写入倍率，未配置的模型按 5 分钟缓存写入倍率的固定比例计算
+var createCache1hRatioMap = types.NewRWMap[string, float64]()
+
 // GetCacheRatioMap returns a copy of the cache ratio map
 func GetCacheRatioMap() map[string]float64 {
 	return cacheRatioMap.ReadAll()
@@ -145,6 +148,11 @@ func CreateCacheRatio2JSONString() string {
 	return createCacheRatioMap.MarshalJSONString()
 }
 
+// CreateCache1hRatio2JSONString converts the 1h create cache ratio map to a JSON string
+func CreateCache1hRatio2JSONString() string {
+	return createCache1hRatioMap.MarshalJSONString()
+}
+
 // UpdateCacheRatioByJSONString updates the cache ratio map from a JSON string
 func UpdateCacheRatioByJSONString(jsonStr string) error {
 	return types.LoadFromJsonStringWithCallback(cacheRatioMap, jsonStr, InvalidateExposedDataCache)
@@ -155,6 +163,11 @@ func UpdateCreateCacheRatioByJSONString(jsonStr string) error {
 	return types.LoadFromJsonStringWithCallback(createCacheRatioMap, jsonStr, InvalidateExposedDataCache)
 }
 
+// UpdateCreateCache1hRatioByJSONString updates the 1h create cache ratio map from a JSON string
+func UpdateCreateCache1hRatioByJSONString(jsonStr string) error {
+	return types.LoadFromJsonStringWithCallback(createCache1hRatioMap, jsonStr, InvalidateExposedDataCache)
+}
+
 // GetCacheRatio returns the cache ratio for a model
 func GetCacheRatio(name string) (float64, bool) {
 	ratio, ok := cacheRatioMap.Get(name)
@@ -172,6 +185,10 @@ func GetCreateCacheRatio(name string) (float64, bool) {
 	return ratio, true
 }
 
+func GetCreateCache1hRatio(name string) (float64, bool) {
+	return createCache1hRatioMap.Get(name)
+}
+
 func GetCacheRatioCopy() map[string]float64 {
 	return cacheRatioMap.ReadAll()
 }
@@ -179,3 +196,7 @@ func GetCacheRatioCopy() map[string]float64 {
 func GetCreateCacheRatioCopy() map[string]float64 {
 	return createCacheRatioMap.ReadAll()
 }
+
+func GetCreateCache1hRatioCopy() map[string]float64 {
+	return createCache1hRatioMap.ReadAll()
+}
@@ -42,11 +42,12 @@ func GetExposedData() gin.H {
 		return cloneGinH(c.data)
 	}
 	newData := gin.H{
-		"model_ratio":        GetModelRatioCopy(),
-		"completion_ratio":   GetCompletionRatioCopy(),
-		"cache_ratio":        GetCacheRatioCopy(),
-		"create_cache_ratio": GetCreateCacheRatioCopy(),
-		"model_price":        GetModelPriceCopy(),
+		"model_ratio":           GetModelRatioCopy(),
+		"completion_ratio":      GetCompletionRatioCopy(),
+		"cache_ratio":           GetCacheRatioCopy(),
+		"create_cache_ratio":    GetCreateCacheRatioCopy(),
+		"create_cache_1h_ratio": GetCreateCache1hRatioCopy(),
+		"model_price":           GetModelPriceCopy(),
 	}
 	exposedData.Store(&exposedCache{
 		data:      newData,
@@ -10,6 +10,14 @@ type OIDCSettings struct {
 	AuthorizationEndpoint string `json:"authorization_endpoint"`
 	TokenEndpoint         string `json:"token_endpoint"`
 	UserInfoEndpoint      string `json:"user_info_endpoint"`
+	// UsernameClaim 用作用户名的 claim，支持 a.b 形式的嵌套路径，留空使用 preferred_username
+	UsernameClaim string `json:"username_claim"`
+	// DefaultGroup 首次登录自动创建的用户所属分组，留空使用系统默认分组
+	DefaultGroup string `json:"default_group"`
+	// AutoProvision 关闭注册时仍允许通过 OIDC 首次登录自动创建账号
+	AutoProvision bool `json:"auto_provision"`
+	// LinkByVerifiedEmail 首次登录时将 OIDC 账号关联到邮箱相同（且已验证）的已有用户
+	LinkByVerifiedEmail bool `json:"link_by_verified_email"`
 }
 
 // 默认配置
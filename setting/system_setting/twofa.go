@@ -0,0 +1,18 @@
+package system_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+type TwoFASettings struct {
+	// RequireForAdmin 管理员和超级管理员必须启用 2FA，未启用时登录后只能访问 2FA 设置接口
+	RequireForAdmin bool `json:"require_for_admin"`
+}
+
+var defaultTwoFASettings = TwoFASettings{}
+
+func init() {
+	config.GlobalConfig.Register("two_fa", &defaultTwoFASettings)
+}
+
+func GetTwoFASettings() *TwoFASettings {
+	return &defaultTwoFASettings
+}
@@ -64,6 +64,7 @@ const (
 	ErrorCodeReadRequestBodyFailed ErrorCode = "read_request_body_failed"
 	ErrorCodeConvertRequestFailed  ErrorCode = "convert_request_failed"
 	ErrorCodeAccessDenied          ErrorCode = "access_denied"
+	ErrorCodeRateLimitExceeded     ErrorCode = "rate_limit_exceeded"
 
 	// request error
 	ErrorCodeBadRequestBody ErrorCode = "bad_request_body"
@@ -85,6 +86,7 @@ const (
 	// quota error
 	ErrorCodeInsufficientUserQuota      ErrorCode = "insufficient_user_quota"
 	ErrorCodePreConsumeTokenQuotaFailed ErrorCode = "pre_consume_token_quota_failed"
+	ErrorCodeUsageCapExceeded           ErrorCode = "usage_cap_exceeded"
 )
 
 type NewAPIError struct {
@@ -92,6 +94,7 @@ type NewAPIError struct {
 	RelayError     any
 	skipRetry      bool
 	recordErrorLog *bool
+	upstream       bool
 	errorType      ErrorType
 	errorCode      ErrorCode
 	StatusCode     int
@@ -177,6 +180,34 @@ func (e *NewAPIError) SetMessage(message string) {
 	e.Err = errors.New(message)
 }
 
+// MapMessage applies fn to the error message and to the message of the
+// wrapped upstream error.
+func (e *NewAPIError) MapMessage(fn func(string) string) {
+	e.Err = errors.New(fn(e.Error()))
+	switch relayError := e.RelayError.(type) {
+	case OpenAIError:
+		relayError.Message = fn(relayError.Message)
+		e.RelayError = relayError
+	case ClaudeError:
+		relayError.Message = fn(relayError.Message)
+		e.RelayError = relayError
+	}
+}
+
+// Rewrite replaces the client-facing message, type and code with an
+// OpenAI-style error. Status code and retry/logging flags are kept.
+func (e *NewAPIError) Rewrite(message string, errorType string, code string) {
+	e.Err = errors.New(message)
+	e.errorType = ErrorTypeOpenAIError
+	e.errorCode = ErrorCode(code)
+	e.Metadata = nil
+	e.RelayError = OpenAIError{
+		Message: message,
+		Type:    errorType,
+		Code:    code,
+	}
+}
+
 func (e *NewAPIError) ToOpenAIError() OpenAIError {
 	var result OpenAIError
 	switch e.errorType {
@@ -370,6 +401,15 @@ func IsChannelError(err *NewAPIError) bool {
 	return strings.HasPrefix(string(err.errorCode), "channel:")
 }
 
+// IsUpstreamError reports whether the error was built from an upstream
+// response rather than raised locally.
+func IsUpstreamError(err *NewAPIError) bool {
+	if err == nil {
+		return false
+	}
+	return err.upstream
+}
+
 func IsSkipRetryError(err *NewAPIError) bool {
 	if err == nil {
 		return false
@@ -384,6 +424,12 @@ func ErrOptionWithSkipRetry() NewAPIErrorOptions {
 	}
 }
 
+func ErrOptionWithUpstream() NewAPIErrorOptions {
+	return func(e *NewAPIError) {
+		e.upstream = true
+	}
+}
+
 func ErrOptionWithNoRecordErrorLog() NewAPIErrorOptions {
 	return func(e *NewAPIError) {
 		e.recordErrorLog = common.GetPointer(false)